@@ -30,7 +30,10 @@ func main() {
 	router := gin.Default()
 
 	// Setup API routes with configuration
-	httpapi.SetupRoutes(router, cfg)
+	handler, err := httpapi.SetupRoutes(router, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize services: %v", err)
+	}
 
 	// Create HTTP server
 	serverAddr := fmt.Sprintf(":%d", cfg.Server.Port)
@@ -56,11 +59,16 @@ func main() {
 	log.Println("Shutting down server...")
 
 	// Give outstanding requests a deadline for completion
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeout)*time.Second)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown:", err)
+		log.Printf("Server forced to shutdown: %v", err)
+	}
+
+	// Flush buffered data such as caches and the audit log within the same deadline
+	if err := handler.Close(ctx); err != nil {
+		log.Printf("Failed to close services cleanly: %v", err)
 	}
 
 	log.Println("Server exited")
@@ -8,6 +8,7 @@ import (
 
 	"go-rag/internal/embedding"
 	"go-rag/internal/store"
+	"go-rag/internal/textutil"
 	"go-rag/internal/types"
 )
 
@@ -123,13 +124,13 @@ func main() {
 	// 9. Search for similar content
 	fmt.Println("\n🔍 Searching for similar content...")
 	query := "What is machine learning?"
-	results, err := vectorStore.SearchSimilar(ctx, query, 5)
+	results, err := vectorStore.SearchSimilar(ctx, query, 5, "", nil)
 	if err != nil {
 		log.Printf("Warning: Search failed: %v", err)
 	} else {
 		fmt.Printf("✓ Found %d similar chunks for query: '%s'\n", len(results), query)
 		for i, chunk := range results {
-			fmt.Printf("  %d. %s (Document: %s)\n", i+1, chunk.Content[:50]+"...", chunk.DocumentID)
+			fmt.Printf("  %d. %s (Document: %s)\n", i+1, textutil.Truncate(chunk.Content, 50), chunk.DocumentID)
 		}
 	}
 
@@ -149,7 +150,7 @@ func main() {
 	if err != nil {
 		log.Printf("Warning: Failed to retrieve chunk: %v", err)
 	} else {
-		fmt.Printf("✓ Retrieved chunk: %s\n", chunk.Content[:50]+"...")
+		fmt.Printf("✓ Retrieved chunk: %s\n", textutil.Truncate(chunk.Content, 50))
 	}
 
 	fmt.Println("\n🎉 Basic usage example completed!")
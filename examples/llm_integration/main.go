@@ -7,6 +7,7 @@ import (
 	"os"
 
 	"go-rag/internal/generate"
+	"go-rag/internal/textutil"
 	"go-rag/internal/types"
 )
 
@@ -81,7 +82,7 @@ func main() {
 	for i, chunk := range chunks {
 		fmt.Printf("  %d. %s (Score: %.2f, Doc: %s)\n", 
 			i+1, 
-			truncateString(chunk.Content, 60), 
+			textutil.Truncate(chunk.Content, 60), 
 			chunk.Score, 
 			chunk.DocumentID)
 	}
@@ -115,8 +116,12 @@ func main() {
 
 		// Test streaming response
 		fmt.Println("\n📡 Testing streaming response...")
-		streamChan, err := service.StreamResponse(ctx, query, chunks)
-		if err != nil {
+		streamer, ok := service.(interface {
+			StreamResponse(ctx context.Context, query string, chunks []types.RankedChunk) (<-chan string, error)
+		})
+		if !ok {
+			log.Printf("Streaming is not supported by this generation service")
+		} else if streamChan, err := streamer.StreamResponse(ctx, query, chunks); err != nil {
 			log.Printf("Error creating stream: %v", err)
 		} else {
 			for response := range streamChan {
@@ -143,11 +148,3 @@ func maskAPIKey(apiKey string) string {
 	}
 	return apiKey[:4] + "..." + apiKey[len(apiKey)-4:]
 }
-
-// truncateString truncates a string to a maximum length
-func truncateString(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
-	}
-	return s[:maxLen-3] + "..."
-}
@@ -0,0 +1,122 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go-rag/internal/types"
+)
+
+// Operations recorded in the audit log
+const (
+	OperationIngest  = "ingest"
+	OperationUpdate  = "update"
+	OperationDelete  = "delete"
+	OperationRestore = "restore"
+	OperationPurge   = "purge"
+)
+
+// Entry is a single audit record of a mutating operation
+type Entry struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Operation  string    `json:"operation"`
+	DocumentID string    `json:"document_id"`
+	ChunkCount int       `json:"chunk_count,omitempty"`
+	Principal  string    `json:"principal,omitempty"`
+}
+
+// Logger records audit entries
+type Logger interface {
+	Log(ctx context.Context, entry Entry) error
+	Close() error
+}
+
+// NewLogger creates the audit logger selected by the configured sink
+func NewLogger(config types.AuditConfig) (Logger, error) {
+	switch config.Sink {
+	case "", "none":
+		return NoopLogger{}, nil
+	case "file":
+		return NewFileLogger(config.Path)
+	default:
+		return nil, fmt.Errorf("unsupported audit sink: %s", config.Sink)
+	}
+}
+
+// NoopLogger discards all entries
+type NoopLogger struct{}
+
+// Log implements Logger
+func (NoopLogger) Log(ctx context.Context, entry Entry) error { return nil }
+
+// Close implements Logger
+func (NoopLogger) Close() error { return nil }
+
+// FileLogger appends entries to a file as JSON lines
+type FileLogger struct {
+	mu      sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+}
+
+// NewFileLogger opens (or creates) the audit file for appending
+func NewFileLogger(path string) (*FileLogger, error) {
+	if path == "" {
+		return nil, fmt.Errorf("audit log path is required")
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	return &FileLogger{file: file, encoder: json.NewEncoder(file)}, nil
+}
+
+// Log writes an entry, stamping it with the principal from ctx and the current
+// time when they are not already set
+func (f *FileLogger) Log(ctx context.Context, entry Entry) error {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now().UTC()
+	}
+	if entry.Principal == "" {
+		entry.Principal = PrincipalFromContext(ctx)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.encoder.Encode(entry); err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	return nil
+}
+
+// Close flushes the audit file to disk and closes it
+func (f *FileLogger) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.file.Sync(); err != nil {
+		f.file.Close()
+		return fmt.Errorf("failed to flush audit log: %w", err)
+	}
+	return f.file.Close()
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context carrying the authenticated principal
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal set by WithPrincipal, if any
+func PrincipalFromContext(ctx context.Context) string {
+	principal, _ := ctx.Value(principalKey{}).(string)
+	return principal
+}
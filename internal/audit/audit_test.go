@@ -0,0 +1,61 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-rag/internal/types"
+)
+
+func TestFileLogger_WritesEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	logger, err := NewFileLogger(path)
+	if err != nil {
+		t.Fatalf("NewFileLogger failed: %v", err)
+	}
+
+	ctx := WithPrincipal(context.Background(), "alice")
+	if err := logger.Log(ctx, Entry{Operation: OperationIngest, DocumentID: "doc-1", ChunkCount: 3}); err != nil {
+		t.Fatalf("Log failed: %v", err)
+	}
+	if err := logger.Log(ctx, Entry{Operation: OperationDelete, DocumentID: "doc-1"}); err != nil {
+		t.Fatalf("Log failed: %v", err)
+	}
+	logger.Close()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open audit log: %v", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("invalid audit line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Operation != OperationIngest || entries[0].ChunkCount != 3 || entries[0].Principal != "alice" {
+		t.Errorf("unexpected ingest entry: %+v", entries[0])
+	}
+	if entries[1].Operation != OperationDelete || entries[1].Timestamp.IsZero() {
+		t.Errorf("unexpected delete entry: %+v", entries[1])
+	}
+}
+
+func TestNewLogger_UnsupportedSink(t *testing.T) {
+	if _, err := NewLogger(types.AuditConfig{Sink: "kafka"}); err == nil {
+		t.Error("expected error for unsupported sink")
+	}
+}
@@ -0,0 +1,49 @@
+// Package azure builds OpenAI client configurations for Azure OpenAI, which
+// addresses models by deployment name on a per-resource endpoint
+package azure
+
+import (
+	"fmt"
+	"strings"
+
+	"go-rag/internal/types"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Endpoint returns the resource URL, built from the resource name when no
+// endpoint is configured, or "" when neither is set
+func Endpoint(config types.AzureOpenAIConfig) string {
+	if config.Endpoint != "" {
+		return strings.TrimRight(config.Endpoint, "/")
+	}
+	if config.ResourceName != "" {
+		return fmt.Sprintf("https://%s.openai.azure.com", config.ResourceName)
+	}
+	return ""
+}
+
+// ClientConfig returns a client configuration for an Azure OpenAI resource.
+// Models found in the deployment map are sent to their deployment; others
+// use go-openai's default of the model name without "." and ":".
+func ClientConfig(apiKey string, config types.AzureOpenAIConfig) (openai.ClientConfig, error) {
+	endpoint := Endpoint(config)
+	if endpoint == "" {
+		return openai.ClientConfig{}, fmt.Errorf("Azure OpenAI endpoint or resource name is required")
+	}
+
+	clientConfig := openai.DefaultAzureConfig(apiKey, endpoint)
+	if config.APIVersion != "" {
+		clientConfig.APIVersion = config.APIVersion
+	}
+
+	defaultDeployment := clientConfig.AzureModelMapperFunc
+	clientConfig.AzureModelMapperFunc = func(model string) string {
+		if deployment, ok := config.Deployments[model]; ok {
+			return deployment
+		}
+		return defaultDeployment(model)
+	}
+
+	return clientConfig, nil
+}
@@ -0,0 +1,19 @@
+// Package cache provides the in-memory LRU behind the service's caches and the
+// per-request flag for bypassing them.
+package cache
+
+import "context"
+
+type bypassKey struct{}
+
+// WithBypass returns a context whose operations must skip cache reads, forcing
+// fresh embeddings and results. Fresh values may still be written back.
+func WithBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey{}, true)
+}
+
+// Bypassed reports whether caches should be skipped for ctx
+func Bypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(bypassKey{}).(bool)
+	return bypass
+}
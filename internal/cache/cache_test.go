@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBypass(t *testing.T) {
+	ctx := context.Background()
+	if Bypassed(ctx) {
+		t.Error("Expected caches to be used by default")
+	}
+	if !Bypassed(WithBypass(ctx)) {
+		t.Error("Expected caches to be bypassed")
+	}
+}
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	lru := NewLRU[int](2, 0)
+	lru.Set("a", 1)
+	lru.Set("b", 2)
+	lru.Get("a")
+	lru.Set("c", 3)
+
+	if _, ok := lru.Get("b"); ok {
+		t.Error("Expected b to be evicted")
+	}
+	if v, ok := lru.Get("a"); !ok || v != 1 {
+		t.Errorf("Expected a to be kept, got %d, %v", v, ok)
+	}
+	if lru.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", lru.Len())
+	}
+}
+
+func TestLRU_ExpiresEntries(t *testing.T) {
+	now := time.Now()
+	lru := NewLRU[string](10, time.Minute)
+	lru.now = func() time.Time { return now }
+	lru.Set("key", "value")
+
+	now = now.Add(30 * time.Second)
+	if _, ok := lru.Get("key"); !ok {
+		t.Error("Expected the entry to be cached within the TTL")
+	}
+	now = now.Add(time.Minute)
+	if _, ok := lru.Get("key"); ok {
+		t.Error("Expected the entry to expire after the TTL")
+	}
+}
+
+func TestLRU_DeleteAndStats(t *testing.T) {
+	lru := NewLRU[int](10, 0)
+	lru.Set("a", 1)
+	lru.Set("b", 2)
+	lru.Set("c", 3)
+
+	lru.Delete("a")
+	lru.DeleteFunc(func(key string, value int) bool { return value == 2 })
+	lru.Get("a")
+	lru.Get("b")
+	lru.Get("c")
+
+	stats := lru.Stats()
+	if stats.Hits != 1 || stats.Misses != 2 || stats.Entries != 1 {
+		t.Errorf("Expected 1 hit, 2 misses and 1 entry, got %+v", stats)
+	}
+}
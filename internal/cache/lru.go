@@ -0,0 +1,128 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"go-rag/internal/types"
+)
+
+// LRU is a size-bounded cache whose entries expire after a TTL. When full,
+// the least recently used entry is evicted. It is safe for concurrent use.
+type LRU[V any] struct {
+	mu         sync.Mutex
+	maxEntries int
+	ttl        time.Duration
+	order      *list.List // front is most recently used
+	entries    map[string]*list.Element
+	now        func() time.Time
+	hits       int64
+	misses     int64
+}
+
+type lruEntry[V any] struct {
+	key     string
+	value   V
+	expires time.Time
+}
+
+// NewLRU creates a cache holding at most maxEntries values; a ttl <= 0 never expires them
+func NewLRU[V any](maxEntries int, ttl time.Duration) *LRU[V] {
+	return &LRU[V]{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+		now:        time.Now,
+	}
+}
+
+// Get returns the value cached under key, if present and not expired
+func (c *LRU[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	element, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return zero, false
+	}
+
+	entry := element.Value.(*lruEntry[V])
+	if c.ttl > 0 && c.now().After(entry.expires) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		c.misses++
+		return zero, false
+	}
+
+	c.order.MoveToFront(element)
+	c.hits++
+	return entry.value, true
+}
+
+// Set caches value under key, evicting the least recently used entry if full
+func (c *LRU[V]) Set(key string, value V) {
+	if c.maxEntries <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := c.now().Add(c.ttl)
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*lruEntry[V])
+		entry.value, entry.expires = value, expires
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry[V]{key: key, value: value, expires: expires})
+	if c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry[V]).key)
+	}
+}
+
+// Len returns the number of cached entries, including expired ones not yet evicted
+func (c *LRU[V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Delete removes the entry for key, if any
+func (c *LRU[V]) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.order.Remove(element)
+		delete(c.entries, key)
+	}
+}
+
+// DeleteFunc removes every entry for which del returns true
+func (c *LRU[V]) DeleteFunc(del func(key string, value V) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, element := range c.entries {
+		if del(key, element.Value.(*lruEntry[V]).value) {
+			c.order.Remove(element)
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Stats reports the lookups served from and missed by the cache, and how
+// many entries it holds
+func (c *LRU[V]) Stats() types.CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return types.CacheStats{Hits: c.hits, Misses: c.misses, Entries: c.order.Len()}
+}
@@ -3,12 +3,17 @@ package chunk
 import (
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 // Service handles text chunking operations
 type Service struct {
 	chunkSize    int
 	chunkOverlap int
+	// tokenizer, when set, measures chunkSize and chunkOverlap in tokens instead of bytes
+	tokenizer Tokenizer
+	// separators are tried in order by the recursive strategy; empty means DefaultSeparators
+	separators []string
 }
 
 // NewService creates a new chunking service
@@ -29,12 +34,16 @@ func NewService(chunkSize, chunkOverlap int) *Service {
 	}
 }
 
-// ChunkText splits text into overlapping chunks
+// ChunkText splits text into overlapping chunks. A service measuring in
+// tokens chunks with ChunkByTokens.
 func (s *Service) ChunkText(text string) ([]string, error) {
 	if text == "" {
 		return []string{}, nil
 	}
-	
+	if s.tokenizer != nil {
+		return s.ChunkByTokens(text)
+	}
+
 	// Clean and normalize text
 	text = s.cleanText(text)
 	
@@ -58,13 +67,27 @@ func (s *Service) ChunkText(text string) ([]string, error) {
 		if end < len(text) {
 			end = s.findBestBreakPoint(text, start, end)
 		}
-		
+
+		// Never cut through a multibyte character
+		if end = runeStart(text, end); end <= start {
+			_, size := utf8.DecodeRuneInString(text[start:])
+			end = start + size
+		}
+
 		chunk := text[start:end]
 		chunks = append(chunks, strings.TrimSpace(chunk))
 		
+		// Stop once the end of the text has been consumed
+		if end >= len(text) {
+			break
+		}
+		
 		// Move start position with overlap
 		start = end - s.chunkOverlap
-		
+		for start > 0 && start < end && !utf8.RuneStart(text[start]) {
+			start++
+		}
+
 		// Ensure we make progress
 		if start <= 0 {
 			start = end
@@ -91,7 +114,7 @@ func (s *Service) findBestBreakPoint(text string, start, maxEnd int) int {
 	for i := maxEnd - 1; i > start+s.chunkSize/2; i-- {
 		if text[i] == '.' || text[i] == '!' || text[i] == '?' {
 			// Check if it's followed by whitespace (likely sentence end)
-			if i+1 < len(text) && unicode.IsSpace(rune(text[i+1])) {
+			if i+1 < len(text) && isSpaceByte(text[i+1]) {
 				return i + 1
 			}
 		}
@@ -106,7 +129,7 @@ func (s *Service) findBestBreakPoint(text string, start, maxEnd int) int {
 	
 	// Look for word boundaries
 	for i := maxEnd - 1; i > start+s.chunkSize/2; i-- {
-		if unicode.IsSpace(rune(text[i])) {
+		if isSpaceByte(text[i]) {
 			return i + 1
 		}
 	}
@@ -115,6 +138,20 @@ func (s *Service) findBestBreakPoint(text string, start, maxEnd int) int {
 	return maxEnd
 }
 
+// runeStart moves i back to the start of the character it falls in
+func runeStart(text string, i int) int {
+	for i > 0 && i < len(text) && !utf8.RuneStart(text[i]) {
+		i--
+	}
+	return i
+}
+
+// isSpaceByte reports whether b is an ASCII space. Bytes of multibyte
+// characters, such as 0x85 and 0xA0, are never spaces.
+func isSpaceByte(b byte) bool {
+	return b < utf8.RuneSelf && unicode.IsSpace(rune(b))
+}
+
 // ChunkByParagraphs splits text by paragraphs and then chunks large paragraphs
 func (s *Service) ChunkByParagraphs(text string) ([]string, error) {
 	paragraphs := strings.Split(text, "\n\n")
@@ -125,8 +162,8 @@ func (s *Service) ChunkByParagraphs(text string) ([]string, error) {
 		if paragraph == "" {
 			continue
 		}
-		
-		if len(paragraph) <= s.chunkSize {
+
+		if s.length(paragraph) <= s.chunkSize {
 			chunks = append(chunks, paragraph)
 		} else {
 			// Chunk large paragraphs
@@ -154,7 +191,7 @@ func (s *Service) ChunkBySentences(text string) ([]string, error) {
 		}
 		
 		// Check if adding this sentence would exceed chunk size
-		if currentChunk.Len()+len(sentence)+1 > s.chunkSize && currentChunk.Len() > 0 {
+		if s.length(currentChunk.String())+s.length(sentence)+1 > s.chunkSize && currentChunk.Len() > 0 {
 			chunks = append(chunks, strings.TrimSpace(currentChunk.String()))
 			currentChunk.Reset()
 		}
@@ -192,3 +229,54 @@ func (s *Service) splitIntoSentences(text string) []string {
 	
 	return result
 }
+
+// Span marks the byte range [Start, End) a chunk was taken from in the source text
+type Span struct {
+	Start int
+	End   int
+}
+
+// LocateChunks finds where each chunk came from in the original source text.
+// Chunkers normalize whitespace, so chunks are matched on their non-whitespace
+// characters only. Chunks are expected in document order and may overlap.
+// A chunk that cannot be found gets a zero Span.
+func LocateChunks(source string, chunks []string) []Span {
+	// Strip whitespace from the source, remembering where each byte came from
+	var stripped strings.Builder
+	positions := make([]int, 0, len(source))
+	for i := 0; i < len(source); {
+		r, size := utf8.DecodeRuneInString(source[i:])
+		if !unicode.IsSpace(r) {
+			stripped.WriteString(source[i : i+size])
+			for b := i; b < i+size; b++ {
+				positions = append(positions, b)
+			}
+		}
+		i += size
+	}
+	haystack := stripped.String()
+
+	spans := make([]Span, len(chunks))
+	cursor := 0
+	for i, chunk := range chunks {
+		needle := strings.Join(strings.Fields(chunk), "")
+		if needle == "" {
+			continue
+		}
+
+		idx := strings.Index(haystack[cursor:], needle)
+		if idx < 0 {
+			continue
+		}
+		idx += cursor
+
+		spans[i] = Span{
+			Start: positions[idx],
+			End:   positions[idx+len(needle)-1] + 1,
+		}
+		// The next chunk may overlap this one, but never starts before it
+		cursor = idx + 1
+	}
+
+	return spans
+}
@@ -0,0 +1,244 @@
+package chunk
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestLocateChunks_BoundsChunksInSource(t *testing.T) {
+	source := "Go is a language.\n\nIt has   goroutines!  Channels connect them.\nTests keep it honest?   Yes."
+	s := NewService(40, 10)
+
+	for name, chunker := range map[string]func(string) ([]string, error){
+		"text":      s.ChunkText,
+		"sentences": s.ChunkBySentences,
+	} {
+		t.Run(name, func(t *testing.T) {
+			chunks, err := chunker(source)
+			if err != nil {
+				t.Fatalf("Chunking failed: %v", err)
+			}
+
+			spans := LocateChunks(source, chunks)
+			if len(spans) != len(chunks) {
+				t.Fatalf("Expected %d spans, got %d", len(chunks), len(spans))
+			}
+
+			for i, chunk := range chunks {
+				span := spans[i]
+				if span.End <= span.Start || span.End > len(source) {
+					t.Fatalf("Chunk %d has invalid span %+v", i, span)
+				}
+				got := strings.Join(strings.Fields(source[span.Start:span.End]), " ")
+				if want := strings.Join(strings.Fields(chunk), " "); got != want {
+					t.Errorf("Chunk %d: span covers %q, expected %q", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReconstruct_FaithfulPerStrategy(t *testing.T) {
+	source := "Retrieval finds the relevant passages. Ranking orders them by usefulness! " +
+		"Generation writes the answer from the top passages. Does it cite sources? It can. " +
+		"Chunking decides how documents are cut before they are embedded and stored."
+	s := NewService(60, 15)
+
+	t.Run("fixed", func(t *testing.T) {
+		chunks, _ := s.ChunkText(source)
+		if len(chunks) < 3 {
+			t.Fatalf("expected several chunks, got %d", len(chunks))
+		}
+		if got, want := Reconstruct(StrategyFixed, chunks, s.Overlap()), s.cleanText(source); got != want {
+			t.Errorf("fixed reconstruction mismatch:\n got: %q\nwant: %q", got, want)
+		}
+	})
+
+	t.Run("sentence", func(t *testing.T) {
+		chunks, _ := s.ChunkBySentences(source)
+		want := strings.Join(s.splitIntoSentences(source), " ")
+		if got := Reconstruct(StrategySentence, chunks, s.Overlap()); got != want {
+			t.Errorf("sentence reconstruction mismatch:\n got: %q\nwant: %q", got, want)
+		}
+	})
+
+	t.Run("paragraph", func(t *testing.T) {
+		paragraphs := "Short first paragraph.\n\n" + source + "\n\nShort last paragraph."
+		chunks, _ := s.ChunkByParagraphs(paragraphs)
+		want := "Short first paragraph.\n\n" + s.cleanText(source) + "\n\nShort last paragraph."
+		if got := Reconstruct(StrategyParagraph, chunks, s.Overlap()); got != want {
+			t.Errorf("paragraph reconstruction mismatch:\n got: %q\nwant: %q", got, want)
+		}
+	})
+}
+
+func TestSentenceWindows(t *testing.T) {
+	s := NewService(1000, 200)
+	sentences, windows := s.SentenceWindows("One. Two! Three? Four. Five.", 1)
+
+	if len(sentences) != 5 || sentences[2] != "Three?" {
+		t.Fatalf("expected five single sentences, got %q", sentences)
+	}
+	want := []string{"One. Two!", "One. Two! Three?", "Two! Three? Four.", "Three? Four. Five.", "Four. Five."}
+	for i := range want {
+		if windows[i] != want[i] {
+			t.Errorf("window %d: expected %q, got %q", i, want[i], windows[i])
+		}
+	}
+}
+
+func TestApproximateTokenizer_RoundTripsText(t *testing.T) {
+	text := "Go's scheduler runs 1000000 goroutines. 日本語のテキスト, emoji 🚀 and café!"
+	tokens := ApproximateTokenizer{}.Tokenize(text)
+	if got := strings.Join(tokens, ""); got != text {
+		t.Fatalf("Tokens don't rebuild the text: %q", got)
+	}
+	for _, token := range tokens {
+		if !utf8.ValidString(token) {
+			t.Errorf("Token %q splits a character", token)
+		}
+	}
+	if got := len(ApproximateTokenizer{}.Tokenize("日本語")); got != 3 {
+		t.Errorf("Expected one token per CJK character, got %d", got)
+	}
+}
+
+func TestChunkByTokens(t *testing.T) {
+	source := "Retrieval finds the relevant passages. Ranking orders them by usefulness! " +
+		"Generation writes the answer from the top passages. Does it cite sources? It can. " +
+		"検索は関連する文章を見つけます。ランキングはそれらを並べ替えます。"
+	s := NewServiceWithTokenizer(20, 5, ApproximateTokenizer{})
+
+	chunks, err := s.ChunkWithStrategy(StrategyFixed, source)
+	if err != nil {
+		t.Fatalf("Chunking failed: %v", err)
+	}
+	if len(chunks) < 3 {
+		t.Fatalf("Expected several chunks, got %d", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if !utf8.ValidString(chunk) {
+			t.Errorf("Chunk %d is not valid UTF-8: %q", i, chunk)
+		}
+		if got := len(s.tokenizer.Tokenize(chunk)); got > 20 {
+			t.Errorf("Chunk %d has %d tokens, expected at most 20", i, got)
+		}
+	}
+
+	if got, want := ReconstructChunks(StrategyFixed, chunks, s.Overlaps(chunks)), s.cleanText(source); got != want {
+		t.Errorf("Token chunks don't reconstruct the text:\n got: %q\nwant: %q", got, want)
+	}
+
+	if s.Unit() != UnitTokens || s.WithSize(50, 0).Unit() != UnitTokens || NewService(50, 0).Unit() != UnitBytes {
+		t.Error("Expected the unit to follow the tokenizer")
+	}
+}
+
+func TestChunkText_KeepsMultibyteCharactersWhole(t *testing.T) {
+	source := "東京は日本の首都であり、世界で最も人口の多い都市圏の一つです。" +
+		"春には桜が咲き、多くの人々が花見を楽しみます。 مرحبا بالعالم 🚀🌸 café crème"
+	s := NewService(32, 8)
+
+	chunks, err := s.ChunkText(source)
+	if err != nil {
+		t.Fatalf("Chunking failed: %v", err)
+	}
+	if len(chunks) < 3 {
+		t.Fatalf("Expected several chunks, got %d", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if !utf8.ValidString(chunk) {
+			t.Errorf("Chunk %d is not valid UTF-8: %q", i, chunk)
+		}
+	}
+
+	if got, want := Reconstruct(StrategyFixed, chunks, s.Overlap()), s.cleanText(source); got != want {
+		t.Errorf("Reconstruction mismatch:\n got: %q\nwant: %q", got, want)
+	}
+
+	for i, span := range LocateChunks(source, chunks) {
+		if got := strings.Join(strings.Fields(source[span.Start:span.End]), " "); got != chunks[i] {
+			t.Errorf("Chunk %d: span covers %q, expected %q", i, got, chunks[i])
+		}
+	}
+}
+
+func TestChunkRecursive(t *testing.T) {
+	source := "Retrieval finds the relevant passages.\n\n" +
+		"Ranking orders them by usefulness. Generation writes the answer from the top passages. " +
+		"Does it cite sources? It can, when asked.\n\n" +
+		"Supercalifragilisticexpialidocious"
+	s := NewService(50, 20)
+
+	chunks, overlaps, err := s.ChunkWithOverlaps(StrategyRecursive, source)
+	if err != nil {
+		t.Fatalf("Chunking failed: %v", err)
+	}
+	if chunks[0] != "Retrieval finds the relevant passages." {
+		t.Errorf("Expected the short first paragraph as its own chunk, got %q", chunks[0])
+	}
+	for i, chunk := range chunks {
+		if len(chunk) > 50 {
+			t.Errorf("Chunk %d is %d bytes, expected at most 50: %q", i, len(chunk), chunk)
+		}
+	}
+	if got := chunks[2]; !strings.HasPrefix(got, "Generation writes") {
+		t.Errorf("Expected the third chunk to start at a sentence, got %q", got)
+	}
+	if got := chunks[len(chunks)-1]; got != "Supercalifragilisticexpialidocious" {
+		t.Errorf("Expected the word that fits to stay whole, got %q", got)
+	}
+
+	// Overlaps are trimmed exactly; chunks that don't overlap are joined by a blank line
+	got := ReconstructChunks(StrategyRecursive, chunks, overlaps)
+	if strings.Join(strings.Fields(got), " ") != strings.Join(strings.Fields(source), " ") {
+		t.Errorf("Recursive chunks don't reconstruct the text:\n got: %q\nwant: %q", got, source)
+	}
+
+	// Without separators that match, text is cut between characters with overlap
+	chunks, _ = s.ChunkRecursive(strings.Repeat("日本語", 10), []string{"\n"})
+	if len(chunks) != 3 || chunks[1] != "本語日本語日本語日本語日本語日本" {
+		t.Errorf("Expected hard cuts at whole characters, got %q", chunks)
+	}
+}
+
+func TestChunkMarkdown(t *testing.T) {
+	fence := "```"
+	source := "Intro before any heading.\n\n" +
+		"# Guide\n\nThe guide explains setup.\n\n" +
+		"## Install\n\nRun the installer.\n\n" +
+		fence + "sh\n# not a heading\nmake install\n\nmake test\n" + fence + "\n\n" +
+		"### Linux\n\nUse the package manager.\n\n" +
+		"## Usage\n\nStart the server and open the browser."
+	s := NewService(60, 10)
+
+	chunks, overlaps, err := s.ChunkWithOverlaps(StrategyMarkdown, source)
+	if err != nil {
+		t.Fatalf("Chunking failed: %v", err)
+	}
+
+	want := []string{
+		"Intro before any heading.",
+		"# Guide\n\nThe guide explains setup.",
+		"# Guide\n## Install\n\nRun the installer.",
+		"# Guide\n## Install\n\n" + fence + "sh\n# not a heading\nmake install\n\nmake test\n" + fence,
+		"# Guide\n## Install\n### Linux\n\nUse the package manager.",
+		"# Guide\n## Usage\n\nStart the server and open the browser.",
+	}
+	if strings.Join(chunks, "|") != strings.Join(want, "|") {
+		t.Fatalf("Unexpected chunks:\n got: %q\nwant: %q", chunks, want)
+	}
+	for i, overlap := range overlaps {
+		if overlap != 0 {
+			t.Errorf("Expected markdown chunk %d not to overlap, got %d", i, overlap)
+		}
+	}
+
+	if got := MarkdownBody(chunks[4]); got != "Use the package manager." {
+		t.Errorf("Expected the body without headings, got %q", got)
+	}
+	if got := ReconstructChunks(StrategyMarkdown, chunks, overlaps); got != source {
+		t.Errorf("Markdown chunks don't reconstruct the text:\n got: %q\nwant: %q", got, source)
+	}
+}
@@ -0,0 +1,200 @@
+package chunk
+
+import "strings"
+
+// markdownSeparators split a paragraph too large for one markdown chunk
+var markdownSeparators = []string{"\n", ". ", " "}
+
+// markdownBlock is a paragraph or fenced code block of a markdown section
+type markdownBlock struct {
+	text string
+	code bool
+}
+
+// markdownSection is the content under one heading, with the headings above it
+type markdownSection struct {
+	headings []string // heading lines, outermost first
+	blocks   []markdownBlock
+}
+
+// ChunkMarkdown splits markdown into chunks that never cross a heading. Each
+// section is cut into paragraphs and fenced code blocks, which are merged into
+// chunks of up to chunkSize. Code blocks are never split, even when they are
+// larger than chunkSize; paragraphs that are too large are split on lines,
+// sentences and words. Every chunk starts with the heading lines leading to
+// it, outermost first, and a blank line. Whitespace is kept as written and
+// chunks don't overlap.
+func (s *Service) ChunkMarkdown(text string) ([]string, error) {
+	var chunks []string
+	for _, section := range parseMarkdownSections(text) {
+		header := ""
+		if len(section.headings) > 0 {
+			header = strings.Join(section.headings, "\n") + "\n\n"
+		}
+		budget := s.chunkSize - s.length(header)
+		if budget < s.chunkSize/2 {
+			budget = s.chunkSize / 2
+		}
+
+		var current []string
+		size := 0
+		flush := func() {
+			if len(current) > 0 {
+				chunks = append(chunks, header+strings.Join(current, "\n\n"))
+				current, size = nil, 0
+			}
+		}
+		for _, block := range section.blocks {
+			length := s.length(block.text)
+			if length > budget && !block.code {
+				flush()
+				pieces, _ := s.WithSize(max(budget, 1), 0).recursiveChunks(block.text, markdownSeparators)
+				for _, piece := range pieces {
+					chunks = append(chunks, header+piece)
+				}
+				continue
+			}
+			if len(current) > 0 && size+length > budget {
+				flush()
+			}
+			current = append(current, block.text)
+			size += length + 2
+		}
+		flush()
+	}
+	return chunks, nil
+}
+
+// MarkdownBody returns a markdown chunk without the heading lines
+// ChunkMarkdown starts it with
+func MarkdownBody(chunk string) string {
+	headings, body := splitMarkdownHeader(chunk)
+	if len(headings) == 0 {
+		return chunk
+	}
+	return body
+}
+
+// splitMarkdownHeader separates the heading lines a markdown chunk starts
+// with from the rest of it
+func splitMarkdownHeader(chunk string) ([]string, string) {
+	var headings []string
+	rest := chunk
+	for {
+		line, after, found := strings.Cut(rest, "\n")
+		if headingLevel(line) == 0 {
+			break
+		}
+		headings = append(headings, line)
+		rest = after
+		if !found {
+			break
+		}
+	}
+	if len(headings) == 0 || !strings.HasPrefix(rest, "\n") {
+		return nil, chunk
+	}
+	return headings, strings.TrimPrefix(rest, "\n")
+}
+
+// reconstructMarkdown rebuilds markdown from its chunks, writing each heading
+// line once: where a chunk's headings repeat those of the chunk before, only
+// the ones that changed are kept
+func reconstructMarkdown(chunks []string) string {
+	var builder strings.Builder
+	var previous []string
+	for i, chunk := range chunks {
+		headings, body := splitMarkdownHeader(chunk)
+		shared := 0
+		for shared < len(headings) && shared < len(previous) && headings[shared] == previous[shared] {
+			shared++
+		}
+		if i > 0 {
+			builder.WriteString("\n\n")
+		}
+		if shared < len(headings) {
+			builder.WriteString(strings.Join(headings[shared:], "\n"))
+			builder.WriteString("\n\n")
+		}
+		builder.WriteString(body)
+		previous = headings
+	}
+	return builder.String()
+}
+
+// headingLevel returns the level of an ATX heading line, or 0 for any other line
+func headingLevel(line string) int {
+	trimmed := strings.TrimSpace(line)
+	level := 0
+	for level < len(trimmed) && trimmed[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || level == len(trimmed) || trimmed[level] != ' ' {
+		return 0
+	}
+	return level
+}
+
+// parseMarkdownSections splits markdown at its headings, outside code fences,
+// into sections of paragraphs and code blocks
+func parseMarkdownSections(text string) []markdownSection {
+	var sections []markdownSection
+	var stack []string // heading lines in effect, outermost first
+	var levels []int
+	section := markdownSection{}
+	var paragraph, code []string
+	fence := ""
+
+	endParagraph := func() {
+		if block := strings.TrimSpace(strings.Join(paragraph, "\n")); block != "" {
+			section.blocks = append(section.blocks, markdownBlock{text: block})
+		}
+		paragraph = nil
+	}
+	endSection := func() {
+		endParagraph()
+		if len(section.blocks) > 0 {
+			sections = append(sections, section)
+		}
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			code = append(code, line)
+			if strings.HasPrefix(trimmed, fence) {
+				section.blocks = append(section.blocks, markdownBlock{text: strings.Join(code, "\n"), code: true})
+				code, fence = nil, ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			endParagraph()
+			fence = trimmed[:3]
+			code = []string{line}
+			continue
+		}
+		if level := headingLevel(line); level > 0 {
+			endSection()
+			for len(levels) > 0 && levels[len(levels)-1] >= level {
+				stack, levels = stack[:len(stack)-1], levels[:len(levels)-1]
+			}
+			stack, levels = append(stack, trimmed), append(levels, level)
+			section = markdownSection{headings: append([]string(nil), stack...)}
+			continue
+		}
+		if trimmed == "" {
+			endParagraph()
+			continue
+		}
+		paragraph = append(paragraph, line)
+	}
+
+	// An unclosed fence runs to the end of the document
+	if code != nil {
+		section.blocks = append(section.blocks, markdownBlock{text: strings.Join(code, "\n"), code: true})
+	}
+	endSection()
+
+	return sections
+}
@@ -0,0 +1,92 @@
+package chunk
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Chunking strategies, recorded on each chunk so documents can be rebuilt
+const (
+	StrategyFixed     = "fixed"     // ChunkText: fixed-size windows with character overlap
+	StrategySentence  = "sentence"  // ChunkBySentences: whole sentences, no overlap
+	StrategyParagraph = "paragraph" // ChunkByParagraphs: paragraphs, large ones split like ChunkText
+	StrategyRecursive = "recursive" // ChunkRecursive: pieces split on separators and merged with overlap
+	StrategyMarkdown  = "markdown"  // ChunkMarkdown: markdown sections under their heading lines, no overlap
+)
+
+// Overlap returns the overlap used between fixed-size chunks, in the unit
+// reported by Unit
+func (s *Service) Overlap() int {
+	return s.chunkOverlap
+}
+
+// Reconstruct rebuilds document text from its chunks, in order, according to
+// the strategy that produced them. Sentence chunks do not overlap and are
+// joined with a space. Fixed-size chunks repeat the last overlap characters
+// of the previous chunk, which are trimmed. Paragraph chunks are separated by
+// blank lines, except for the overlapping pieces of a split paragraph.
+// Recursive chunks are rebuilt like paragraph chunks, since where they don't
+// overlap they were usually split at a paragraph. Markdown chunks are joined
+// with blank lines, writing each heading they start with only once.
+func Reconstruct(strategy string, chunks []string, overlap int) string {
+	overlaps := make([]int, len(chunks))
+	for i := range overlaps {
+		overlaps[i] = overlap
+	}
+	return ReconstructChunks(strategy, chunks, overlaps)
+}
+
+// ReconstructChunks is Reconstruct for chunks that each overlap the one before
+// by their own number of characters, as token-measured chunks do
+func ReconstructChunks(strategy string, chunks []string, overlaps []int) string {
+	if len(chunks) == 0 {
+		return ""
+	}
+	if strategy == StrategyMarkdown {
+		return reconstructMarkdown(chunks)
+	}
+
+	separator := " "
+	trimOverlap := false
+	switch strategy {
+	case StrategyFixed:
+		trimOverlap = true
+	case StrategyParagraph, StrategyRecursive:
+		separator = "\n\n"
+		trimOverlap = true
+	}
+
+	var builder strings.Builder
+	builder.WriteString(chunks[0])
+	for i, next := range chunks[1:] {
+		if trimOverlap {
+			if shared := overlapLength(builder.String(), next, overlaps[i+1]); shared > 0 {
+				builder.WriteString(next[shared:])
+				continue
+			}
+		}
+		builder.WriteString(separator)
+		builder.WriteString(next)
+	}
+
+	return builder.String()
+}
+
+// overlapLength finds how much of next repeats the end of text. ChunkText
+// starts each chunk overlap characters before the previous one ended, moved
+// forward to the next whole character, and then trims whitespace, so the
+// repeated part is at most utf8.UTFMax+1 characters shorter than overlap.
+// Shorter matches are ignored as coincidences.
+func overlapLength(text, next string, overlap int) int {
+	if overlap <= 0 {
+		return 0
+	}
+
+	minimum := max(overlap-utf8.UTFMax-1, 1)
+	for length := min(overlap, len(next)); length >= minimum; length-- {
+		if strings.HasSuffix(text, next[:length]) {
+			return length
+		}
+	}
+	return 0
+}
@@ -0,0 +1,138 @@
+package chunk
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultSeparators are the separators ChunkRecursive tries when none are
+// configured: paragraphs, then lines, then sentences, then words
+var DefaultSeparators = []string{"\n\n", "\n", ". ", " "}
+
+// WithSeparators returns a copy of the service whose recursive strategy tries
+// separators, in order, instead of DefaultSeparators
+func (s *Service) WithSeparators(separators []string) *Service {
+	copied := *s
+	copied.separators = separators
+	return &copied
+}
+
+// recursiveSeparators returns the separators the recursive strategy tries
+func (s *Service) recursiveSeparators() []string {
+	if len(s.separators) > 0 {
+		return s.separators
+	}
+	return DefaultSeparators
+}
+
+// ChunkRecursive splits text on the first of separators it contains, then
+// merges the pieces back into chunks of up to chunkSize, repeating up to
+// chunkOverlap of trailing pieces at the start of the next chunk. Pieces that
+// are too large on their own are split the same way with the separators that
+// follow, and cut between characters (or tokens) once none are left. Each
+// separator stays at the end of the piece before it. Newlines are kept, since
+// they are the separators paragraphs and lines are found by.
+func (s *Service) ChunkRecursive(text string, separators []string) ([]string, error) {
+	chunks, _ := s.recursiveChunks(text, separators)
+	return chunks, nil
+}
+
+// recursiveChunks is ChunkRecursive, also returning how many bytes each chunk
+// repeats from the end of the one before it
+func (s *Service) recursiveChunks(text string, separators []string) ([]string, []int) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return []string{}, []int{}
+	}
+
+	// Pick the first separator the text contains, keeping the rest for
+	// pieces that are still too large
+	var pieces []string
+	for i, separator := range separators {
+		if separator != "" && strings.Contains(text, separator) {
+			pieces = strings.SplitAfter(text, separator)
+			separators = separators[i+1:]
+			break
+		}
+	}
+	if pieces == nil {
+		return s.mergePieces(s.characters(text))
+	}
+
+	var chunks, pending []string
+	var overlaps []int
+	flush := func() {
+		merged, mergedOverlaps := s.mergePieces(pending)
+		chunks = append(chunks, merged...)
+		overlaps = append(overlaps, mergedOverlaps...)
+		pending = nil
+	}
+	for _, piece := range pieces {
+		if s.length(piece) <= s.chunkSize {
+			pending = append(pending, piece)
+			continue
+		}
+		flush()
+		split, splitOverlaps := s.recursiveChunks(piece, separators)
+		chunks = append(chunks, split...)
+		overlaps = append(overlaps, splitOverlaps...)
+	}
+	flush()
+
+	return chunks, overlaps
+}
+
+// characters splits text into its smallest pieces: tokens for a service
+// measuring in tokens, whole characters otherwise
+func (s *Service) characters(text string) []string {
+	if s.tokenizer != nil {
+		return s.tokenizer.Tokenize(text)
+	}
+	pieces := make([]string, 0, utf8.RuneCountInString(text))
+	for i := 0; i < len(text); {
+		_, size := utf8.DecodeRuneInString(text[i:])
+		pieces = append(pieces, text[i:i+size])
+		i += size
+	}
+	return pieces
+}
+
+// mergePieces joins consecutive pieces into chunks of up to chunkSize. Each
+// chunk after the first starts with the trailing pieces of the one before,
+// up to chunkOverlap, and the byte length of that repeated text is returned
+// for it.
+func (s *Service) mergePieces(pieces []string) ([]string, []int) {
+	var chunks []string
+	var overlaps []int
+	var current []string
+	total, carried := 0, 0
+
+	emit := func() {
+		chunk := strings.TrimSpace(strings.Join(current, ""))
+		if chunk == "" {
+			return
+		}
+		chunks = append(chunks, chunk)
+		overlaps = append(overlaps, len(strings.TrimSpace(strings.Join(current[:carried], ""))))
+	}
+
+	for _, piece := range pieces {
+		length := s.length(piece)
+		if total+length > s.chunkSize && len(current) > 0 {
+			emit()
+			// Keep the trailing pieces that fit in the overlap and leave room for this one
+			for len(current) > 0 && (total > s.chunkOverlap || total+length > s.chunkSize) {
+				total -= s.length(current[0])
+				current = current[1:]
+			}
+			carried = len(current)
+		}
+		current = append(current, piece)
+		total += length
+	}
+	if len(current) > carried {
+		emit()
+	}
+
+	return chunks, overlaps
+}
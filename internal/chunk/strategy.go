@@ -0,0 +1,52 @@
+package chunk
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnknownStrategy is returned for a chunking strategy that doesn't exist
+var ErrUnknownStrategy = errors.New("unknown chunking strategy")
+
+// Size returns the maximum chunk size, in the unit reported by Unit
+func (s *Service) Size() int {
+	return s.chunkSize
+}
+
+// ChunkWithStrategy splits text using the named strategy
+func (s *Service) ChunkWithStrategy(strategy, text string) ([]string, error) {
+	switch strategy {
+	case StrategyFixed:
+		return s.ChunkText(text)
+	case StrategySentence:
+		return s.ChunkBySentences(text)
+	case StrategyParagraph:
+		return s.ChunkByParagraphs(text)
+	case StrategyRecursive:
+		return s.ChunkRecursive(text, s.recursiveSeparators())
+	case StrategyMarkdown:
+		return s.ChunkMarkdown(text)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownStrategy, strategy)
+	}
+}
+
+// ChunkWithOverlaps splits text like ChunkWithStrategy, also returning how
+// many bytes each chunk repeats from the end of the one before it, as
+// ReconstructChunks needs them. Sentence and markdown chunks never overlap.
+func (s *Service) ChunkWithOverlaps(strategy, text string) ([]string, []int, error) {
+	switch strategy {
+	case StrategyRecursive:
+		chunks, overlaps := s.recursiveChunks(text, s.recursiveSeparators())
+		return chunks, overlaps, nil
+	case StrategySentence, StrategyMarkdown:
+		chunks, err := s.ChunkWithStrategy(strategy, text)
+		return chunks, make([]int, len(chunks)), err
+	}
+
+	chunks, err := s.ChunkWithStrategy(strategy, text)
+	if err != nil {
+		return nil, nil, err
+	}
+	return chunks, s.Overlaps(chunks), nil
+}
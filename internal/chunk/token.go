@@ -0,0 +1,167 @@
+package chunk
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Units chunk sizes and overlaps can be measured in
+const (
+	UnitBytes  = "bytes"  // bytes of text, the default
+	UnitTokens = "tokens" // tokens, as counted by the service's Tokenizer
+)
+
+// Tokenizer splits text into tokens. Joining the tokens gives back the text.
+type Tokenizer interface {
+	Tokenize(text string) []string
+}
+
+// maxTokenRunes is the longest word piece ApproximateTokenizer counts as one token
+const maxTokenRunes = 4
+
+// pretokenPattern follows cl100k's pre-tokenizer: contractions, words with one
+// leading character, numbers of up to three digits, punctuation runs and
+// whitespace. Go's regexp has no lookahead, so numbers take a leading space
+// instead of leaving it as a token of its own.
+var pretokenPattern = regexp.MustCompile(`(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\pL\pN]?\pL+| ?\pN{1,3}| ?[^\s\pL\pN]+[\r\n]*|\s*[\r\n]+|\s+`)
+
+// ApproximateTokenizer approximates tiktoken's cl100k encoding without its
+// vocabulary. Text is pre-tokenized the way cl100k does it, then pieces are
+// cut into tokens of at most four characters, and every character of three or
+// more UTF-8 bytes, such as CJK text, is a token of its own. That comes close
+// to cl100k's counts for English and never splits a character.
+type ApproximateTokenizer struct{}
+
+// Tokenize splits text into approximate tokens
+func (ApproximateTokenizer) Tokenize(text string) []string {
+	var tokens []string
+	for _, piece := range pretokenPattern.FindAllString(text, -1) {
+		start, runes := 0, 0
+		for i, r := range piece {
+			if utf8.RuneLen(r) >= 3 {
+				if i > start {
+					tokens = append(tokens, piece[start:i])
+				}
+				next := i + utf8.RuneLen(r)
+				tokens = append(tokens, piece[i:next])
+				start, runes = next, 0
+				continue
+			}
+			if runes == maxTokenRunes {
+				tokens = append(tokens, piece[start:i])
+				start, runes = i, 0
+			}
+			runes++
+		}
+		if start < len(piece) {
+			tokens = append(tokens, piece[start:])
+		}
+	}
+	return tokens
+}
+
+// NewServiceWithTokenizer creates a chunking service that measures chunk size
+// and overlap in tokens
+func NewServiceWithTokenizer(chunkSize, chunkOverlap int, tokenizer Tokenizer) *Service {
+	s := NewService(chunkSize, chunkOverlap)
+	s.tokenizer = tokenizer
+	return s
+}
+
+// Unit returns the unit chunk size and overlap are measured in
+func (s *Service) Unit() string {
+	if s.tokenizer != nil {
+		return UnitTokens
+	}
+	return UnitBytes
+}
+
+// WithSize returns a copy of the service with another chunk size and overlap,
+// measured in the same unit
+func (s *Service) WithSize(chunkSize, chunkOverlap int) *Service {
+	resized := NewService(chunkSize, chunkOverlap)
+	resized.tokenizer = s.tokenizer
+	resized.separators = s.separators
+	return resized
+}
+
+// length measures text in the service's unit
+func (s *Service) length(text string) int {
+	if s.tokenizer != nil {
+		return len(s.tokenizer.Tokenize(text))
+	}
+	return len(text)
+}
+
+// ChunkByTokens splits text into overlapping chunks of at most chunkSize
+// tokens, repeating the last chunkOverlap tokens of each chunk at the start of
+// the next. Chunks end after a sentence where one falls in their second half.
+// A service without a tokenizer uses ApproximateTokenizer.
+func (s *Service) ChunkByTokens(text string) ([]string, error) {
+	text = s.cleanText(text)
+	if text == "" {
+		return []string{}, nil
+	}
+
+	tokenizer := s.tokenizer
+	if tokenizer == nil {
+		tokenizer = ApproximateTokenizer{}
+	}
+	tokens := tokenizer.Tokenize(text)
+
+	var chunks []string
+	start := 0
+	for start < len(tokens) {
+		end := min(start+s.chunkSize, len(tokens))
+		if end < len(tokens) {
+			end = sentenceBreak(tokens, start, end, s.chunkSize)
+		}
+
+		chunks = append(chunks, strings.TrimSpace(strings.Join(tokens[start:end], "")))
+		if end >= len(tokens) {
+			break
+		}
+
+		// Move start back by the overlap, always making progress
+		start = max(end-s.chunkOverlap, start+1)
+	}
+
+	return chunks, nil
+}
+
+// sentenceBreak moves a chunk's end back to just after the last sentence
+// ending in the second half of the chunk, if there is one
+func sentenceBreak(tokens []string, start, end, size int) int {
+	for i := end; i > start+size/2; i-- {
+		token := strings.TrimRight(tokens[i-1], "\r\n")
+		if strings.HasSuffix(token, ".") || strings.HasSuffix(token, "!") || strings.HasSuffix(token, "?") {
+			return i
+		}
+	}
+	return end
+}
+
+// Overlaps returns how many bytes each chunk repeats from the end of the one
+// before it, as ReconstructChunks needs them. Byte-measured chunks all overlap
+// by the configured overlap. Token-measured chunks repeat the last
+// chunkOverlap tokens of the chunk before, whose length in bytes varies, so
+// each is measured; the first chunk overlaps by 0.
+func (s *Service) Overlaps(chunks []string) []int {
+	overlaps := make([]int, len(chunks))
+	for i := range chunks {
+		if s.tokenizer == nil {
+			overlaps[i] = s.chunkOverlap
+			continue
+		}
+		if i == 0 || s.chunkOverlap == 0 {
+			continue
+		}
+		tokens := s.tokenizer.Tokenize(chunks[i-1])
+		shared := strings.TrimSpace(strings.Join(tokens[max(len(tokens)-s.chunkOverlap, 0):], ""))
+		if strings.HasPrefix(chunks[i], shared) {
+			overlaps[i] = len(shared)
+		}
+	}
+	return overlaps
+}
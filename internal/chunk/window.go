@@ -0,0 +1,17 @@
+package chunk
+
+import "strings"
+
+// SentenceWindows splits text into single sentences and, for each one, the
+// window of up to size sentences on either side. Matching on the sentence is
+// precise, while the window gives the surrounding context.
+func (s *Service) SentenceWindows(text string, size int) (sentences, windows []string) {
+	sentences = s.splitIntoSentences(text)
+	windows = make([]string, len(sentences))
+	for i := range sentences {
+		start := max(i-size, 0)
+		end := min(i+size+1, len(sentences))
+		windows[i] = strings.Join(sentences[start:end], " ")
+	}
+	return sentences, windows
+}
@@ -4,18 +4,26 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
+	"go-rag/internal/chunk"
 	"go-rag/internal/types"
 	"github.com/joho/godotenv"
 )
 
 // Config holds all application configuration
 type Config struct {
-	Server      ServerConfig              `json:"server"`
-	VectorStore types.VectorStoreConfig   `json:"vector_store"`
-	Embedding   types.EmbeddingConfig     `json:"embedding"`
-	Generation  types.GenerationConfig    `json:"generation"`
-	Chunking    types.ChunkingConfig      `json:"chunking"`
+	Server      ServerConfig            `json:"server"`
+	VectorStore types.VectorStoreConfig `json:"vector_store"`
+	Embedding   types.EmbeddingConfig   `json:"embedding"`
+	Generation  types.GenerationConfig  `json:"generation"`
+	Chunking    types.ChunkingConfig    `json:"chunking"`
+	Ranking     types.RankingConfig     `json:"ranking"`
+	Retrieval   types.RetrievalConfig   `json:"retrieval"`
+	Audit       types.AuditConfig       `json:"audit"`
+	Moderation  types.ModerationConfig  `json:"moderation"`
+	// CollectionEmbeddings overrides the embedding model for specific collections
+	CollectionEmbeddings map[string]types.EmbeddingConfig `json:"collection_embeddings,omitempty"`
 }
 
 // ServerConfig holds server-specific configuration
@@ -23,6 +31,33 @@ type ServerConfig struct {
 	Port    int    `json:"port"`
 	Host    string `json:"host"`
 	GinMode string `json:"gin_mode"`
+	// EnableWebsocketIngest exposes the streaming ingest endpoint at /api/v1/ws/ingest
+	EnableWebsocketIngest bool `json:"enable_websocket_ingest"`
+	// WebsocketAllowedOrigins lists the browser origins, besides the server's
+	// own, that may open the streaming ingest websocket
+	WebsocketAllowedOrigins []string `json:"websocket_allowed_origins"`
+	// RetryBudget caps the provider retries made while serving one request; <= 0 leaves it unbounded
+	RetryBudget int `json:"retry_budget"`
+	// JobStatePath is where background job state is saved; empty keeps it in memory
+	JobStatePath string `json:"job_state_path"`
+	// JobRetentionHours and JobMaxFinished bound how long and how many
+	// finished jobs are kept; 0 turns a bound off
+	JobRetentionHours int `json:"job_retention_hours"`
+	JobMaxFinished    int `json:"job_max_finished"`
+	// Warmup primes the embedding and generation providers at startup
+	Warmup bool `json:"warmup"`
+	// SearchDiagnostics lets search requests ask for vector store diagnostics; keep it off in production
+	SearchDiagnostics bool `json:"search_diagnostics"`
+	// TimingBreakdown adds per-stage durations to RAG responses
+	TimingBreakdown bool `json:"timing_breakdown"`
+	// ResponseMeta adds the collection, embedding model and distance metric to search and RAG responses
+	ResponseMeta bool `json:"response_meta"`
+	// ShutdownTimeout is how many seconds shutdown waits for requests to finish and services to flush
+	ShutdownTimeout int `json:"shutdown_timeout"`
+	// FacetMaxValues caps the values returned per search facet; 0 returns them all
+	FacetMaxValues int `json:"facet_max_values"`
+	// MaxUploadMB caps the size of a file upload request in megabytes
+	MaxUploadMB int `json:"max_upload_mb"`
 }
 
 // LoadConfig loads configuration from environment variables
@@ -31,37 +66,207 @@ func LoadConfig() (*Config, error) {
 	_ = godotenv.Load()
 	config := &Config{
 		Server: ServerConfig{
-			Port:    getEnvAsInt("PORT", 8080),
-			Host:    getEnv("HOST", "localhost"),
-			GinMode: getEnv("GIN_MODE", "release"),
+			Port:                    getEnvAsInt("PORT", 8080),
+			Host:                    getEnv("HOST", "localhost"),
+			GinMode:                 getEnv("GIN_MODE", "release"),
+			EnableWebsocketIngest:   getEnvAsBool("ENABLE_WS_INGEST", false),
+			WebsocketAllowedOrigins: getEnvAsSlice("WS_INGEST_ALLOWED_ORIGINS", nil),
+			RetryBudget:             getEnvAsInt("REQUEST_RETRY_BUDGET", 3),
+			JobStatePath:            getEnv("JOB_STATE_PATH", ""),
+			JobRetentionHours:       getEnvAsInt("JOB_RETENTION_HOURS", 24),
+			JobMaxFinished:          getEnvAsInt("JOB_MAX_FINISHED", 100),
+			Warmup:                  getEnvAsBool("STARTUP_WARMUP", false),
+			SearchDiagnostics:       getEnvAsBool("SEARCH_DIAGNOSTICS_ENABLED", false),
+			TimingBreakdown:         getEnvAsBool("RESPONSE_TIMING_BREAKDOWN", false),
+			ShutdownTimeout:         getEnvAsInt("SHUTDOWN_TIMEOUT_SECONDS", 30),
+			ResponseMeta:            getEnvAsBool("RESPONSE_META", false),
+			FacetMaxValues:          getEnvAsInt("SEARCH_FACET_MAX_VALUES", 20),
+			MaxUploadMB:             getEnvAsInt("INGEST_MAX_UPLOAD_MB", 10),
 		},
 		VectorStore: types.VectorStoreConfig{
-			Provider:       getEnv("QDRANT_PROVIDER", "qdrant"),
-			Host:           getEnv("QDRANT_HOST", "localhost"),
-			Port:           getEnvAsInt("QDRANT_PORT", 6333),
-			CollectionName: getEnv("QDRANT_COLLECTION_NAME", "documents"),
-			APIKey:         getEnv("QDRANT_API_KEY", ""),
+			Provider:                 getEnv("QDRANT_PROVIDER", "qdrant"),
+			Host:                     getEnv("QDRANT_HOST", "localhost"),
+			Port:                     getEnvAsInt("QDRANT_PORT", 6333),
+			CollectionName:           getEnv("QDRANT_COLLECTION_NAME", "documents"),
+			APIKey:                   getEnv("QDRANT_API_KEY", ""),
+			TenantCollectionTemplate: getEnv("QDRANT_TENANT_COLLECTION_TEMPLATE", ""),
+			AllowedTenants:           getEnvAsSlice("QDRANT_ALLOWED_TENANTS", nil),
+			MaxTenants:               getEnvAsInt("QDRANT_MAX_TENANTS", 100),
+			CompressContent:          getEnvAsBool("QDRANT_COMPRESS_CONTENT", false),
+			VectorFields:             getEnvAsSlice("QDRANT_VECTOR_FIELDS", nil),
+			DimensionPolicy:          getEnv("QDRANT_DIMENSION_POLICY", "error"),
+			ConfirmRecreate:          getEnvAsBool("QDRANT_CONFIRM_RECREATE", false),
+			RecreateCollection:       getEnvAsBool("RECREATE_COLLECTION", false),
+			DistanceMetric:           getEnv("QDRANT_DISTANCE_METRIC", "cosine"),
+			SoftDelete:               getEnvAsBool("QDRANT_SOFT_DELETE", false),
+			SearchTimeoutSeconds:     getEnvAsInt("QDRANT_SEARCH_TIMEOUT_SECONDS", 0),
+			PartialResultsOnTimeout:  getEnvAsBool("QDRANT_PARTIAL_RESULTS_ON_TIMEOUT", false),
+			FilterOverfetch:          getEnvAsInt("QDRANT_FILTER_OVERFETCH", 10),
 		},
 		Embedding: types.EmbeddingConfig{
-			Provider:   getEnv("EMBEDDING_PROVIDER", "openai"),
-			Model:      getEnv("EMBEDDING_MODEL", "text-embedding-ada-002"),
-			Dimensions: getEnvAsInt("EMBEDDING_DIMENSIONS", 1536),
-			APIKey:     getEnv("OPENAI_API_KEY", ""),
+			Provider:        providerName(getEnv("EMBEDDING_PROVIDER", "openai")),
+			Model:           getEnv("EMBEDDING_MODEL", "text-embedding-ada-002"),
+			Dimensions:      getEnvAsInt("EMBEDDING_DIMENSIONS", 1536),
+			APIKey:          getEnv("OPENAI_API_KEY", ""),
+			Deduplicate:     getEnvAsBool("EMBEDDING_DEDUPLICATE", false),
+			MaxRetries:      getEnvAsInt("EMBEDDING_MAX_RETRIES", 0),
+			RetryDelayMs:    getEnvAsInt("PROVIDER_RETRY_DELAY_MS", 500),
+			RetryMaxDelayMs: getEnvAsInt("EMBEDDING_RETRY_MAX_DELAY_MS", 30000),
+			BatchSize:       getEnvAsInt("EMBEDDING_BATCH_SIZE", 512),
+			CacheSize:       getEnvAsInt("EMBEDDING_CACHE_SIZE", 0),
+			CacheTTLSecs:    getEnvAsInt("EMBEDDING_CACHE_TTL_SECONDS", 0),
 		},
 		Generation: types.GenerationConfig{
-			Provider:    getEnv("LLM_PROVIDER", "openai"),
-			Model:       getEnv("LLM_MODEL", "gpt-3.5-turbo"),
-			Temperature: getEnvAsFloat("LLM_TEMPERATURE", 0.7),
-			MaxTokens:   getEnvAsInt("LLM_MAX_TOKENS", 1000),
-			APIKey:      getEnv("OPENAI_API_KEY", ""),
+			Provider:             providerName(getEnv("LLM_PROVIDER", "openai")),
+			Model:                getEnv("LLM_MODEL", "gpt-3.5-turbo"),
+			AllowedModels:        getEnvAsSlice("LLM_ALLOWED_MODELS", nil),
+			Temperature:          getEnvAsFloat("LLM_TEMPERATURE", 0.7),
+			MaxTokens:            getEnvAsInt("LLM_MAX_TOKENS", 1000),
+			APIKey:               getEnv("OPENAI_API_KEY", ""),
+			RetryOnContextLength: getEnvAsBool("LLM_RETRY_ON_CONTEXT_LENGTH", false),
+			DegradeOnRateLimit:   getEnvAsBool("LLM_DEGRADE_ON_RATE_LIMIT", false),
+			MaxRetries:           getEnvAsInt("LLM_MAX_RETRIES", 0),
+			RetryDelayMs:         getEnvAsInt("PROVIDER_RETRY_DELAY_MS", 500),
+			EmptyAnswerFallback:  getEnv("LLM_EMPTY_ANSWER_FALLBACK", ""),
+			PromptTemplate:       getEnv("LLM_PROMPT_TEMPLATE", ""),
+			MaxContextTokens:     getEnvAsInt("LLM_MAX_CONTEXT_TOKENS", 0),
+			AnswerCacheSize:      getEnvAsInt("LLM_ANSWER_CACHE_SIZE", 0),
+			AnswerCacheTTLSecs:   getEnvAsInt("LLM_ANSWER_CACHE_TTL_SECONDS", 3600),
+			DeterministicCaching: getEnvAsBool("LLM_ANSWER_CACHE_DETERMINISTIC", true),
+			LogProbs:             getEnvAsBool("LLM_LOGPROBS", false),
+			Confidence: types.ConfidenceConfig{
+				Enabled:        getEnvAsBool("RAG_CONFIDENCE", false),
+				ScoreWeight:    getEnvAsFloat("RAG_CONFIDENCE_SCORE_WEIGHT", 0.6),
+				CoverageWeight: getEnvAsFloat("RAG_CONFIDENCE_COVERAGE_WEIGHT", 0.2),
+				LogProbWeight:  getEnvAsFloat("RAG_CONFIDENCE_LOGPROB_WEIGHT", 0.2),
+				TargetChunks:   getEnvAsInt("RAG_CONFIDENCE_TARGET_CHUNKS", 3),
+			},
 		},
 		Chunking: types.ChunkingConfig{
-			ChunkSize:    getEnvAsInt("CHUNK_SIZE", 1000),
-			ChunkOverlap: getEnvAsInt("CHUNK_OVERLAP", 200),
-			Strategy:     getEnv("CHUNKING_STRATEGY", "fixed"),
+			ChunkSize:          getEnvAsInt("CHUNK_SIZE", 1000),
+			ChunkOverlap:       getEnvAsInt("CHUNK_OVERLAP", 200),
+			Strategy:           getEnv("CHUNKING_STRATEGY", chunk.StrategySentence),
+			Unit:               getEnv("CHUNK_SIZE_UNIT", "bytes"),
+			Separators:         getEnvAsSeparators("CHUNK_SEPARATORS"),
+			StoreOffsets:       getEnvAsBool("CHUNK_STORE_OFFSETS", false),
+			SentenceWindow:     getEnvAsInt("CHUNK_SENTENCE_WINDOW", 0),
+			MaxDirectoryFiles:  getEnvAsInt("INGEST_MAX_DIRECTORY_FILES", 10000),
+			DirectoryBatchSize: getEnvAsInt("INGEST_DIRECTORY_BATCH_SIZE", 100),
+			ExtractMetadata:    getEnvAsBool("INGEST_EXTRACT_METADATA", false),
+			MarkdownHierarchy:  getEnvAsBool("CHUNK_MARKDOWN_HIERARCHY", false),
+		},
+		Ranking: types.RankingConfig{
+			Workers:          getEnvAsInt("RANKING_WORKERS", 1),
+			FallbackOnError:  getEnvAsBool("RANKING_FALLBACK_ON_ERROR", true),
+			Mode:             getEnv("RANKING_MODE", "keyword"),
+			BlendWeight:      getEnvAsFloat("RANKING_BLEND_WEIGHT", 0.5),
+			RRFK:             getEnvAsFloat("RANKING_RRF_K", 60),
+			RRFVectorWeight:  getEnvAsFloat("RANKING_RRF_VECTOR_WEIGHT", 1),
+			RRFKeywordWeight: getEnvAsFloat("RANKING_RRF_KEYWORD_WEIGHT", 1),
+			Scorer:           getEnv("RANKING_SCORER", "keyword"),
+			BM25K1:           getEnvAsFloat("RANKING_BM25_K1", 1.2),
+			BM25B:            getEnvAsFloat("RANKING_BM25_B", 0.75),
+			NegativeWeight:   getEnvAsFloat("RANKING_NEGATIVE_QUERY_WEIGHT", 0.5),
+			Reranker:         getEnv("RANKING_RERANKER", "none"),
+			RerankerURL:      getEnv("RANKING_RERANKER_URL", ""),
+			RerankerModel:    getEnv("RANKING_RERANKER_MODEL", "rerank-v3.5"),
+			RerankerAPIKey:   getEnv("RANKING_RERANKER_API_KEY", getEnv("COHERE_API_KEY", "")),
+		},
+		Retrieval: types.RetrievalConfig{
+			NormalizeQuery:        getEnvAsBool("QUERY_NORMALIZE", false),
+			LowercaseQuery:        getEnvAsBool("QUERY_LOWERCASE", false),
+			SkipGenerationOnEmpty: getEnvAsBool("RAG_SKIP_GENERATION_ON_EMPTY", true),
+			MaxDocumentChunks:     getEnvAsInt("MAX_DOCUMENT_CHUNKS", 10000),
+			DedupeAcrossDocuments: getEnvAsBool("RETRIEVAL_DEDUPE_ACROSS_DOCUMENTS", false),
+			ChunkCacheSize:        getEnvAsInt("RETRIEVAL_CHUNK_CACHE_SIZE", 0),
+			ChunkCacheTTLSecs:     getEnvAsInt("RETRIEVAL_CHUNK_CACHE_TTL_SECONDS", 300),
+		},
+		Audit: types.AuditConfig{
+			Sink:            getEnv("AUDIT_SINK", "none"),
+			Path:            getEnv("AUDIT_LOG_PATH", "audit.log"),
+			PrincipalHeader: getEnv("AUDIT_PRINCIPAL_HEADER", "X-User-ID"),
 		},
+		Moderation: types.ModerationConfig{
+			Provider:   getEnv("MODERATION_PROVIDER", "none"),
+			Model:      getEnv("MODERATION_MODEL", "omni-moderation-latest"),
+			APIKey:     getEnv("OPENAI_API_KEY", ""),
+			Ingest:     getEnv("MODERATION_INGEST", "off"),
+			Generation: getEnv("MODERATION_GENERATION", "off"),
+		},
+	}
+
+	// Pinecone addresses an index by its host and authenticates with its own key
+	if config.VectorStore.Provider == "pinecone" {
+		config.VectorStore.Host = getEnv("PINECONE_INDEX_HOST", "")
+		config.VectorStore.APIKey = getEnv("PINECONE_API_KEY", "")
+	}
+	if config.VectorStore.Provider == "weaviate" {
+		config.VectorStore.Host = getEnv("WEAVIATE_HOST", "localhost")
+		config.VectorStore.Port = getEnvAsInt("WEAVIATE_PORT", 8080)
+		config.VectorStore.APIKey = getEnv("WEAVIATE_API_KEY", "")
+	}
+	if config.VectorStore.Provider == "memory" {
+		config.VectorStore.PersistPath = getEnv("MEMORY_STORE_PATH", "")
+	}
+	if config.VectorStore.Provider == "pgvector" {
+		config.VectorStore.DSN = getEnv("PGVECTOR_DSN", "")
+		config.VectorStore.Host = getEnv("PGVECTOR_HOST", "localhost")
+		config.VectorStore.Port = getEnvAsInt("PGVECTOR_PORT", 5432)
+		config.VectorStore.Database = getEnv("PGVECTOR_DATABASE", "postgres")
+		config.VectorStore.APIKey = getEnv("PGVECTOR_PASSWORD", "")
+	}
+
+	if config.Embedding.Provider == "cohere" {
+		config.Embedding.APIKey = getEnv("COHERE_API_KEY", "")
+	}
+	// Ollama runs locally and needs no API key
+	if config.Embedding.Provider == "ollama" {
+		config.Embedding.BaseURL = getEnv("OLLAMA_BASE_URL", "http://localhost:11434")
+		config.Embedding.Concurrency = getEnvAsInt("OLLAMA_CONCURRENCY", 4)
 	}
 
+	// An OpenAI-compatible server, such as LiteLLM or vLLM, can stand in for api.openai.com
+	if config.Embedding.Provider == "openai" {
+		config.Embedding.BaseURL = getEnv("OPENAI_BASE_URL", "")
+	}
+	if config.Generation.Provider == "openai" {
+		config.Generation.BaseURL = getEnv("OPENAI_BASE_URL", "")
+	}
+
+	if config.Generation.Provider == "anthropic" {
+		config.Generation.Model = getEnv("LLM_MODEL", "claude-sonnet-4-20250514")
+		config.Generation.APIKey = getEnv("ANTHROPIC_API_KEY", "")
+	}
+
+	// Embedding and generation share one Azure OpenAI resource
+	if config.Embedding.Provider == "azure" || config.Generation.Provider == "azure" {
+		deployments, err := parseAzureDeployments(getEnv("AZURE_OPENAI_DEPLOYMENTS", ""))
+		if err != nil {
+			return nil, fmt.Errorf("invalid AZURE_OPENAI_DEPLOYMENTS: %w", err)
+		}
+		azure := types.AzureOpenAIConfig{
+			ResourceName: getEnv("AZURE_OPENAI_RESOURCE", ""),
+			Endpoint:     getEnv("AZURE_OPENAI_ENDPOINT", ""),
+			APIVersion:   getEnv("AZURE_OPENAI_API_VERSION", "2024-06-01"),
+			Deployments:  deployments,
+		}
+		apiKey := getEnv("AZURE_OPENAI_API_KEY", "")
+		if config.Embedding.Provider == "azure" {
+			config.Embedding.Azure = azure
+			config.Embedding.APIKey = apiKey
+		}
+		if config.Generation.Provider == "azure" {
+			config.Generation.Azure = azure
+			config.Generation.APIKey = apiKey
+		}
+	}
+
+	collectionEmbeddings, err := parseCollectionEmbeddings(getEnv("EMBEDDING_COLLECTION_MODELS", ""), config.Embedding)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EMBEDDING_COLLECTION_MODELS: %w", err)
+	}
+	config.CollectionEmbeddings = collectionEmbeddings
+
 	// Validate required fields
 	if err := validateConfig(config); err != nil {
 		return nil, fmt.Errorf("config validation failed: %w", err)
@@ -72,21 +277,271 @@ func LoadConfig() (*Config, error) {
 
 // validateConfig ensures required configuration is present
 func validateConfig(config *Config) error {
-	if config.VectorStore.Host == "" {
-		return fmt.Errorf("QDRANT_HOST is required")
+	switch config.VectorStore.Provider {
+	case "qdrant":
+		if config.VectorStore.Host == "" {
+			return fmt.Errorf("QDRANT_HOST is required")
+		}
+	case "pinecone":
+		if config.VectorStore.Host == "" {
+			return fmt.Errorf("PINECONE_INDEX_HOST is required when using Pinecone")
+		}
+		if config.VectorStore.APIKey == "" {
+			return fmt.Errorf("PINECONE_API_KEY is required when using Pinecone")
+		}
+	case "weaviate":
+		if config.VectorStore.Host == "" {
+			return fmt.Errorf("WEAVIATE_HOST is required when using Weaviate")
+		}
+	case "pgvector":
+		if config.VectorStore.DSN == "" && config.VectorStore.Host == "" {
+			return fmt.Errorf("PGVECTOR_DSN or PGVECTOR_HOST is required when using pgvector")
+		}
+	case "memory":
+	default:
+		return fmt.Errorf("QDRANT_PROVIDER must be qdrant, pinecone, weaviate, pgvector or memory, got %q", config.VectorStore.Provider)
 	}
 	if config.VectorStore.CollectionName == "" {
 		return fmt.Errorf("QDRANT_COLLECTION_NAME is required")
 	}
+	switch config.Chunking.Strategy {
+	case chunk.StrategyFixed, chunk.StrategySentence, chunk.StrategyParagraph, chunk.StrategyRecursive, chunk.StrategyMarkdown:
+	default:
+		return fmt.Errorf("CHUNKING_STRATEGY must be fixed, sentence, paragraph, recursive or markdown, got %q", config.Chunking.Strategy)
+	}
+	switch config.Chunking.Unit {
+	case "", chunk.UnitBytes, chunk.UnitTokens:
+	default:
+		return fmt.Errorf("CHUNK_SIZE_UNIT must be bytes or tokens, got %q", config.Chunking.Unit)
+	}
+	if config.Chunking.DirectoryBatchSize < 0 {
+		return fmt.Errorf("INGEST_DIRECTORY_BATCH_SIZE cannot be negative, got %d", config.Chunking.DirectoryBatchSize)
+	}
+	switch config.Ranking.Mode {
+	case "", "keyword", "passthrough", "blend", "rrf":
+	default:
+		return fmt.Errorf("RANKING_MODE must be keyword, passthrough, blend or rrf, got %q", config.Ranking.Mode)
+	}
+	if config.Ranking.BlendWeight < 0 || config.Ranking.BlendWeight > 1 {
+		return fmt.Errorf("RANKING_BLEND_WEIGHT must be between 0 and 1, got %v", config.Ranking.BlendWeight)
+	}
+	if config.Ranking.Mode == "rrf" {
+		if config.Ranking.RRFK <= 0 {
+			return fmt.Errorf("RANKING_RRF_K must be positive, got %v", config.Ranking.RRFK)
+		}
+		if config.Ranking.RRFVectorWeight < 0 || config.Ranking.RRFKeywordWeight < 0 {
+			return fmt.Errorf("RANKING_RRF_VECTOR_WEIGHT and RANKING_RRF_KEYWORD_WEIGHT cannot be negative")
+		}
+		if config.Ranking.RRFVectorWeight+config.Ranking.RRFKeywordWeight == 0 {
+			return fmt.Errorf("RANKING_RRF_VECTOR_WEIGHT and RANKING_RRF_KEYWORD_WEIGHT cannot both be zero")
+		}
+	}
+	switch config.Ranking.Scorer {
+	case "", "keyword", "bm25":
+	default:
+		return fmt.Errorf("RANKING_SCORER must be keyword or bm25, got %q", config.Ranking.Scorer)
+	}
+	// The scorer treats zero as unset, so an explicit 0 would silently become the default
+	if config.Ranking.Scorer == "bm25" && (config.Ranking.BM25K1 <= 0 || config.Ranking.BM25B <= 0) {
+		return fmt.Errorf("RANKING_BM25_K1 and RANKING_BM25_B must be greater than 0, got %v and %v", config.Ranking.BM25K1, config.Ranking.BM25B)
+	}
+	if config.Ranking.BM25K1 < 0 || config.Ranking.BM25B < 0 || config.Ranking.BM25B > 1 {
+		return fmt.Errorf("RANKING_BM25_K1 cannot be negative and RANKING_BM25_B must be between 0 and 1")
+	}
+	if config.Ranking.NegativeWeight < 0 || config.Ranking.NegativeWeight > 1 {
+		return fmt.Errorf("RANKING_NEGATIVE_QUERY_WEIGHT must be between 0 and 1, got %v", config.Ranking.NegativeWeight)
+	}
+	switch config.Ranking.Reranker {
+	case "", "none":
+	case "cohere":
+		if config.Ranking.RerankerURL == "" && config.Ranking.RerankerAPIKey == "" {
+			return fmt.Errorf("RANKING_RERANKER_API_KEY or COHERE_API_KEY is required for the Cohere reranker, unless RANKING_RERANKER_URL is set")
+		}
+	default:
+		return fmt.Errorf("RANKING_RERANKER must be none or cohere, got %q", config.Ranking.Reranker)
+	}
+	if config.Generation.MaxContextTokens < 0 {
+		return fmt.Errorf("LLM_MAX_CONTEXT_TOKENS cannot be negative, got %d", config.Generation.MaxContextTokens)
+	}
+	if confidence := config.Generation.Confidence; confidence.ScoreWeight < 0 || confidence.CoverageWeight < 0 || confidence.LogProbWeight < 0 {
+		return fmt.Errorf("RAG_CONFIDENCE_SCORE_WEIGHT, RAG_CONFIDENCE_COVERAGE_WEIGHT and RAG_CONFIDENCE_LOGPROB_WEIGHT cannot be negative")
+	}
+	switch config.VectorStore.DistanceMetric {
+	case "", "cosine":
+	case "dot", "euclid":
+		if config.VectorStore.Provider != "qdrant" {
+			return fmt.Errorf("QDRANT_DISTANCE_METRIC %s requires the qdrant vector store", config.VectorStore.DistanceMetric)
+		}
+	default:
+		return fmt.Errorf("QDRANT_DISTANCE_METRIC must be cosine, dot or euclid, got %q", config.VectorStore.DistanceMetric)
+	}
+	switch config.VectorStore.DimensionPolicy {
+	case "error", "recreate", "adapt":
+	default:
+		return fmt.Errorf("QDRANT_DIMENSION_POLICY must be error, recreate or adapt, got %q", config.VectorStore.DimensionPolicy)
+	}
+	if config.Server.JobRetentionHours < 0 {
+		return fmt.Errorf("JOB_RETENTION_HOURS cannot be negative, got %d", config.Server.JobRetentionHours)
+	}
+	if config.Server.JobMaxFinished < 0 {
+		return fmt.Errorf("JOB_MAX_FINISHED cannot be negative, got %d", config.Server.JobMaxFinished)
+	}
+	if config.VectorStore.SearchTimeoutSeconds < 0 {
+		return fmt.Errorf("QDRANT_SEARCH_TIMEOUT_SECONDS cannot be negative, got %d", config.VectorStore.SearchTimeoutSeconds)
+	}
+	if config.Server.FacetMaxValues < 0 {
+		return fmt.Errorf("SEARCH_FACET_MAX_VALUES cannot be negative, got %d", config.Server.FacetMaxValues)
+	}
+
+	if config.VectorStore.MaxTenants < 0 {
+		return fmt.Errorf("QDRANT_MAX_TENANTS cannot be negative, got %d", config.VectorStore.MaxTenants)
+	}
+
+	if config.Server.MaxUploadMB < 0 {
+		return fmt.Errorf("INGEST_MAX_UPLOAD_MB cannot be negative, got %d", config.Server.MaxUploadMB)
+	}
+	if config.VectorStore.FilterOverfetch < 0 {
+		return fmt.Errorf("QDRANT_FILTER_OVERFETCH cannot be negative, got %d", config.VectorStore.FilterOverfetch)
+	}
+	if config.Embedding.BatchSize < 0 || config.Embedding.BatchSize > 2048 {
+		return fmt.Errorf("EMBEDDING_BATCH_SIZE must be between 0 and 2048, got %d", config.Embedding.BatchSize)
+	}
+	if config.Embedding.CacheSize < 0 {
+		return fmt.Errorf("EMBEDDING_CACHE_SIZE cannot be negative, got %d", config.Embedding.CacheSize)
+	}
+	if config.Embedding.CacheTTLSecs < 0 {
+		return fmt.Errorf("EMBEDDING_CACHE_TTL_SECONDS cannot be negative, got %d", config.Embedding.CacheTTLSecs)
+	}
+	if config.Embedding.RetryMaxDelayMs < 0 {
+		return fmt.Errorf("EMBEDDING_RETRY_MAX_DELAY_MS cannot be negative, got %d", config.Embedding.RetryMaxDelayMs)
+	}
 	if config.Embedding.Provider == "openai" && config.Embedding.APIKey == "" {
 		return fmt.Errorf("OPENAI_API_KEY is required when using OpenAI for embeddings")
 	}
+	if config.Embedding.Provider == "cohere" && config.Embedding.APIKey == "" {
+		return fmt.Errorf("COHERE_API_KEY is required when using Cohere for embeddings")
+	}
+	if config.Embedding.Provider == "ollama" {
+		if config.Embedding.BaseURL == "" {
+			return fmt.Errorf("OLLAMA_BASE_URL is required when using Ollama for embeddings")
+		}
+		if config.Embedding.Concurrency < 1 {
+			return fmt.Errorf("OLLAMA_CONCURRENCY must be at least 1, got %d", config.Embedding.Concurrency)
+		}
+	}
 	if config.Generation.Provider == "openai" && config.Generation.APIKey == "" {
 		return fmt.Errorf("OPENAI_API_KEY is required when using OpenAI for generation")
 	}
+	if config.Generation.Provider == "anthropic" && config.Generation.APIKey == "" {
+		return fmt.Errorf("ANTHROPIC_API_KEY is required when using Anthropic for generation")
+	}
+	if config.Embedding.Provider == "azure" {
+		if err := validateAzure(config.Embedding.APIKey, config.Embedding.Azure, "embeddings"); err != nil {
+			return err
+		}
+	}
+	if config.Generation.Provider == "azure" {
+		if err := validateAzure(config.Generation.APIKey, config.Generation.Azure, "generation"); err != nil {
+			return err
+		}
+	}
+	for name, action := range map[string]string{"MODERATION_INGEST": config.Moderation.Ingest, "MODERATION_GENERATION": config.Moderation.Generation} {
+		switch action {
+		case "", "off":
+		case "reject", "redact":
+			if config.Moderation.Provider == "" || config.Moderation.Provider == "none" {
+				return fmt.Errorf("MODERATION_PROVIDER is required when %s is %s", name, action)
+			}
+		default:
+			return fmt.Errorf("%s must be off, reject or redact, got %q", name, action)
+		}
+	}
+	if config.Moderation.Provider == "openai" && config.Moderation.APIKey == "" {
+		return fmt.Errorf("OPENAI_API_KEY is required when using OpenAI for moderation")
+	}
 	return nil
 }
 
+// validateAzure checks that an Azure OpenAI resource can be reached
+func validateAzure(apiKey string, azure types.AzureOpenAIConfig, use string) error {
+	if apiKey == "" {
+		return fmt.Errorf("AZURE_OPENAI_API_KEY is required when using Azure OpenAI for %s", use)
+	}
+	if azure.Endpoint == "" && azure.ResourceName == "" {
+		return fmt.Errorf("AZURE_OPENAI_ENDPOINT or AZURE_OPENAI_RESOURCE is required when using Azure OpenAI for %s", use)
+	}
+	return nil
+}
+
+// parseCollectionEmbeddings reads per-collection embedding models from a
+// comma-separated list of collection=provider:model:dimensions entries. Other
+// settings, such as the API key and retries, are inherited from base.
+func parseCollectionEmbeddings(value string, base types.EmbeddingConfig) (map[string]types.EmbeddingConfig, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	collections := make(map[string]types.EmbeddingConfig)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		collection, spec, found := strings.Cut(entry, "=")
+		parts := strings.Split(spec, ":")
+		if !found || collection == "" || len(parts) != 3 {
+			return nil, fmt.Errorf("expected collection=provider:model:dimensions, got %q", entry)
+		}
+		dimensions, err := strconv.Atoi(parts[2])
+		if err != nil || dimensions <= 0 {
+			return nil, fmt.Errorf("invalid dimensions for collection %s: %q", collection, parts[2])
+		}
+
+		config := base
+		config.Provider = providerName(parts[0])
+		config.Model = parts[1]
+		config.Dimensions = dimensions
+		collections[collection] = config
+	}
+
+	return collections, nil
+}
+
+// providerName returns the provider a configured name stands for, accepting
+// "azure-openai" for the Azure OpenAI provider "azure"
+func providerName(name string) string {
+	if name == "azure-openai" {
+		return "azure"
+	}
+	return name
+}
+
+// parseAzureDeployments reads a comma-separated list of model=deployment
+// entries naming the Azure OpenAI deployment that serves each model
+func parseAzureDeployments(value string) (map[string]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	deployments := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		model, deployment, found := strings.Cut(entry, "=")
+		model, deployment = strings.TrimSpace(model), strings.TrimSpace(deployment)
+		if !found || model == "" || deployment == "" {
+			return nil, fmt.Errorf("expected model=deployment, got %q", entry)
+		}
+		deployments[model] = deployment
+	}
+
+	return deployments, nil
+}
+
 // Helper functions for environment variable parsing
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -112,3 +567,45 @@ func getEnvAsFloat(key string, defaultValue float64) float64 {
 	}
 	return defaultValue
 }
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsSeparators reads a list of chunk separators split by "|". Items are
+// not trimmed, since spaces are separators too, and may use Go escapes such
+// as \n.
+func getEnvAsSeparators(key string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	var separators []string
+	for _, item := range strings.Split(value, "|") {
+		if unquoted, err := strconv.Unquote(`"` + item + `"`); err == nil {
+			item = unquoted
+		}
+		if item != "" {
+			separators = append(separators, item)
+		}
+	}
+	return separators
+}
+
+func getEnvAsSlice(key string, defaultValue []string) []string {
+	if value := os.Getenv(key); value != "" {
+		var values []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+		return values
+	}
+	return defaultValue
+}
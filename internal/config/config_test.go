@@ -0,0 +1,337 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"go-rag/internal/chunk"
+	"go-rag/internal/types"
+)
+
+func TestParseCollectionEmbeddings(t *testing.T) {
+	base := types.EmbeddingConfig{Provider: "openai", Model: "text-embedding-ada-002", Dimensions: 1536, APIKey: "key", MaxRetries: 2}
+
+	collections, err := parseCollectionEmbeddings("fast=openai:text-embedding-3-small:512, quality=openai:text-embedding-3-large:3072", base)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(collections) != 2 {
+		t.Fatalf("Expected 2 collections, got %d", len(collections))
+	}
+	fast := collections["fast"]
+	if fast.Model != "text-embedding-3-small" || fast.Dimensions != 512 {
+		t.Errorf("Unexpected config for fast: %+v", fast)
+	}
+	if fast.APIKey != "key" || fast.MaxRetries != 2 {
+		t.Errorf("Expected the base settings to be inherited, got %+v", fast)
+	}
+
+	if collections, err := parseCollectionEmbeddings("", base); err != nil || collections != nil {
+		t.Errorf("Expected no overrides for an empty value, got %v, %v", collections, err)
+	}
+
+	for _, value := range []string{"fast", "fast=openai:model", "=openai:model:512", "fast=openai:model:abc", "fast=openai:model:0"} {
+		if _, err := parseCollectionEmbeddings(value, base); err == nil {
+			t.Errorf("Expected %q to be rejected", value)
+		}
+	}
+}
+
+func TestGetEnvAsSeparators(t *testing.T) {
+	t.Setenv("CHUNK_SEPARATORS", `\n\n|\n|. | |`)
+	got := getEnvAsSeparators("CHUNK_SEPARATORS")
+	want := []string{"\n\n", "\n", ". ", " "}
+	if strings.Join(got, "/") != strings.Join(want, "/") || len(got) != len(want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestValidateConfig_RejectsUnknownChunkingStrategy(t *testing.T) {
+	cfg := &Config{
+		VectorStore: types.VectorStoreConfig{Provider: "qdrant", Host: "localhost", CollectionName: "documents", DimensionPolicy: "error"},
+		Chunking:    types.ChunkingConfig{Strategy: "paragraph"},
+	}
+	if err := validateConfig(cfg); err != nil {
+		t.Fatalf("Unexpected error for a valid strategy: %v", err)
+	}
+
+	cfg.Chunking.Strategy = "recursive"
+	if err := validateConfig(cfg); err != nil {
+		t.Fatalf("Unexpected error for the recursive strategy: %v", err)
+	}
+
+	cfg.Chunking.Strategy = "semantic"
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "CHUNKING_STRATEGY") {
+		t.Errorf("Expected a CHUNKING_STRATEGY error, got %v", err)
+	}
+
+	cfg.Chunking = types.ChunkingConfig{Strategy: "fixed", Unit: "words"}
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "CHUNK_SIZE_UNIT") {
+		t.Errorf("Expected a CHUNK_SIZE_UNIT error, got %v", err)
+	}
+}
+
+func TestValidateConfig_CohereRequiresAPIKey(t *testing.T) {
+	cfg := &Config{
+		VectorStore: types.VectorStoreConfig{Provider: "qdrant", Host: "localhost", CollectionName: "documents", DimensionPolicy: "error"},
+		Chunking:    types.ChunkingConfig{Strategy: "fixed"},
+		Embedding:   types.EmbeddingConfig{Provider: "cohere", Model: "embed-english-v3.0"},
+	}
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "COHERE_API_KEY") {
+		t.Errorf("Expected a COHERE_API_KEY error, got %v", err)
+	}
+
+	cfg.Embedding.APIKey = "key"
+	if err := validateConfig(cfg); err != nil {
+		t.Errorf("Unexpected error for a complete Cohere config: %v", err)
+	}
+}
+
+func TestValidateConfig_AnthropicRequiresAPIKey(t *testing.T) {
+	cfg := &Config{
+		VectorStore: types.VectorStoreConfig{Provider: "qdrant", Host: "localhost", CollectionName: "documents", DimensionPolicy: "error"},
+		Chunking:    types.ChunkingConfig{Strategy: "fixed"},
+		Embedding:   types.EmbeddingConfig{Provider: "mock"},
+		Generation:  types.GenerationConfig{Provider: "anthropic", Model: "claude-sonnet-4-20250514"},
+	}
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "ANTHROPIC_API_KEY") {
+		t.Errorf("Expected an ANTHROPIC_API_KEY error, got %v", err)
+	}
+
+	cfg.Generation.APIKey = "key"
+	if err := validateConfig(cfg); err != nil {
+		t.Errorf("Unexpected error for a complete Anthropic config: %v", err)
+	}
+}
+
+func TestParseAzureDeployments(t *testing.T) {
+	deployments, err := parseAzureDeployments("text-embedding-3-small=embed-prod, gpt-4o = chat-prod")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if deployments["text-embedding-3-small"] != "embed-prod" || deployments["gpt-4o"] != "chat-prod" || len(deployments) != 2 {
+		t.Errorf("Unexpected deployments: %v", deployments)
+	}
+
+	for _, value := range []string{"gpt-4o", "gpt-4o=", "=chat-prod"} {
+		if _, err := parseAzureDeployments(value); err == nil {
+			t.Errorf("Expected %q to be rejected", value)
+		}
+	}
+}
+
+func TestProviderName_AcceptsAzureOpenAI(t *testing.T) {
+	if got := providerName("azure-openai"); got != "azure" {
+		t.Errorf("Expected azure-openai to select the azure provider, got %q", got)
+	}
+	if got := providerName("openai"); got != "openai" {
+		t.Errorf("Expected other providers to be kept, got %q", got)
+	}
+
+	collections, err := parseCollectionEmbeddings("docs=azure-openai:text-embedding-3-small:1536", types.EmbeddingConfig{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if provider := collections["docs"].Provider; provider != "azure" {
+		t.Errorf("Expected the collection to use the azure provider, got %q", provider)
+	}
+}
+
+func TestValidateConfig_AzureRequiresResource(t *testing.T) {
+	cfg := &Config{
+		VectorStore: types.VectorStoreConfig{Provider: "qdrant", Host: "localhost", CollectionName: "documents", DimensionPolicy: "error"},
+		Chunking:    types.ChunkingConfig{Strategy: "fixed"},
+		Embedding:   types.EmbeddingConfig{Provider: "mock"},
+		Generation:  types.GenerationConfig{Provider: "azure", Model: "gpt-4o", APIKey: "key"},
+	}
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "AZURE_OPENAI_RESOURCE") {
+		t.Errorf("Expected an AZURE_OPENAI_RESOURCE error, got %v", err)
+	}
+
+	cfg.Generation.Azure.ResourceName = "my-resource"
+	if err := validateConfig(cfg); err != nil {
+		t.Errorf("Unexpected error for a complete Azure config: %v", err)
+	}
+
+	cfg.Generation.APIKey = ""
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "AZURE_OPENAI_API_KEY") {
+		t.Errorf("Expected an AZURE_OPENAI_API_KEY error, got %v", err)
+	}
+}
+
+func TestValidateConfig_OllamaNeedsNoAPIKey(t *testing.T) {
+	cfg := &Config{
+		VectorStore: types.VectorStoreConfig{Provider: "qdrant", Host: "localhost", CollectionName: "documents", DimensionPolicy: "error"},
+		Chunking:    types.ChunkingConfig{Strategy: "fixed"},
+		Embedding:   types.EmbeddingConfig{Provider: "ollama", Model: "nomic-embed-text", BaseURL: "http://localhost:11434", Concurrency: 4},
+	}
+	if err := validateConfig(cfg); err != nil {
+		t.Errorf("Unexpected error for an Ollama config without an API key: %v", err)
+	}
+
+	cfg.Embedding.Concurrency = 0
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "OLLAMA_CONCURRENCY") {
+		t.Errorf("Expected an OLLAMA_CONCURRENCY error, got %v", err)
+	}
+}
+
+func TestValidateConfig_RRFParameters(t *testing.T) {
+	cfg := &Config{
+		VectorStore: types.VectorStoreConfig{Provider: "qdrant", Host: "localhost", CollectionName: "documents", DimensionPolicy: "error"},
+		Chunking:    types.ChunkingConfig{Strategy: "fixed"},
+		Ranking:     types.RankingConfig{Mode: "rrf", RRFK: 60, RRFVectorWeight: 1, RRFKeywordWeight: 0},
+	}
+	if err := validateConfig(cfg); err != nil {
+		t.Errorf("Unexpected error for a vector-only fusion: %v", err)
+	}
+
+	for name, ranking := range map[string]types.RankingConfig{
+		"zero k":          {Mode: "rrf", RRFK: 0, RRFVectorWeight: 1, RRFKeywordWeight: 1},
+		"negative weight": {Mode: "rrf", RRFK: 60, RRFVectorWeight: -1, RRFKeywordWeight: 2},
+		"zero weights":    {Mode: "rrf", RRFK: 60},
+	} {
+		cfg.Ranking = ranking
+		if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "RANKING_RRF") {
+			t.Errorf("Expected %s to be rejected, got %v", name, err)
+		}
+	}
+
+	// The RRF parameters only matter in rrf mode
+	cfg.Ranking = types.RankingConfig{Mode: "keyword"}
+	if err := validateConfig(cfg); err != nil {
+		t.Errorf("Unexpected error outside rrf mode: %v", err)
+	}
+}
+
+func TestValidateConfig_RankingScorer(t *testing.T) {
+	cfg := &Config{
+		VectorStore: types.VectorStoreConfig{Provider: "qdrant", Host: "localhost", CollectionName: "documents", DimensionPolicy: "error"},
+		Chunking:    types.ChunkingConfig{Strategy: "fixed"},
+		Ranking:     types.RankingConfig{Scorer: "bm25", BM25K1: 1.2, BM25B: 0.75},
+	}
+	if err := validateConfig(cfg); err != nil {
+		t.Errorf("Unexpected error for bm25: %v", err)
+	}
+
+	for name, ranking := range map[string]types.RankingConfig{
+		"unknown scorer": {Scorer: "tfidf"},
+		"negative k1":    {Scorer: "bm25", BM25K1: -1, BM25B: 0.75},
+		"b above one":    {Scorer: "bm25", BM25K1: 1.2, BM25B: 1.5},
+		"zero k1":        {Scorer: "bm25", BM25K1: 0, BM25B: 0.75},
+		"zero b":         {Scorer: "bm25", BM25K1: 1.2, BM25B: 0},
+		"weight over 1":  {NegativeWeight: 1.5},
+		"cohere no key":  {Reranker: "cohere"},
+		"bad reranker":   {Reranker: "colbert"},
+	} {
+		cfg.Ranking = ranking
+		if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "RANKING_") {
+			t.Errorf("Expected %s to be rejected, got %v", name, err)
+		}
+	}
+}
+
+func TestValidateConfig_DistanceMetric(t *testing.T) {
+	cfg := &Config{
+		VectorStore: types.VectorStoreConfig{Provider: "qdrant", Host: "localhost", CollectionName: "documents", DimensionPolicy: "error", DistanceMetric: "dot"},
+		Chunking:    types.ChunkingConfig{Strategy: "fixed"},
+	}
+	if err := validateConfig(cfg); err != nil {
+		t.Errorf("Unexpected error for dot with qdrant: %v", err)
+	}
+
+	cfg.VectorStore.DistanceMetric = "manhattan"
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "QDRANT_DISTANCE_METRIC") {
+		t.Errorf("Expected an unknown metric to be rejected, got %v", err)
+	}
+
+	cfg.VectorStore = types.VectorStoreConfig{Provider: "memory", CollectionName: "documents", DimensionPolicy: "error", DistanceMetric: "euclid"}
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "requires the qdrant") {
+		t.Errorf("Expected euclid to be rejected for the memory store, got %v", err)
+	}
+}
+
+func TestValidateConfig_VectorStoreProviders(t *testing.T) {
+	cfg := &Config{
+		VectorStore: types.VectorStoreConfig{Provider: "pinecone", Host: "docs.svc.pinecone.io", CollectionName: "documents", DimensionPolicy: "error"},
+		Chunking:    types.ChunkingConfig{Strategy: "fixed"},
+	}
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "PINECONE_API_KEY") {
+		t.Errorf("Expected a PINECONE_API_KEY error, got %v", err)
+	}
+
+	cfg.VectorStore.APIKey = "key"
+	if err := validateConfig(cfg); err != nil {
+		t.Errorf("Unexpected error for a complete Pinecone config: %v", err)
+	}
+
+	cfg.VectorStore.Provider = "weaviate"
+	cfg.VectorStore.APIKey = ""
+	if err := validateConfig(cfg); err != nil {
+		t.Errorf("Unexpected error for a Weaviate config without an API key: %v", err)
+	}
+
+	cfg.VectorStore.Provider = "pgvector"
+	cfg.VectorStore.Host = ""
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "PGVECTOR_DSN") {
+		t.Errorf("Expected a PGVECTOR_DSN error, got %v", err)
+	}
+
+	cfg.VectorStore.DSN = "postgres://localhost/rag"
+	if err := validateConfig(cfg); err != nil {
+		t.Errorf("Unexpected error for a pgvector config with a DSN: %v", err)
+	}
+
+	cfg.VectorStore.Provider = "milvus"
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "QDRANT_PROVIDER") {
+		t.Errorf("Expected a QDRANT_PROVIDER error, got %v", err)
+	}
+}
+
+func TestValidateConfig_ModerationActions(t *testing.T) {
+	cfg := &Config{
+		VectorStore: types.VectorStoreConfig{Provider: "qdrant", Host: "localhost", CollectionName: "documents", DimensionPolicy: "error"},
+		Chunking:    types.ChunkingConfig{Strategy: "fixed"},
+		Moderation:  types.ModerationConfig{Provider: "none", Ingest: "reject", Generation: "off"},
+	}
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "MODERATION_PROVIDER") {
+		t.Errorf("Expected a MODERATION_PROVIDER error, got %v", err)
+	}
+
+	cfg.Moderation = types.ModerationConfig{Provider: "openai", APIKey: "key", Ingest: "redact", Generation: "block"}
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "MODERATION_GENERATION") {
+		t.Errorf("Expected a MODERATION_GENERATION error, got %v", err)
+	}
+
+	cfg.Moderation.Generation = "reject"
+	if err := validateConfig(cfg); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestLoadConfig_DefaultsToSentenceChunking(t *testing.T) {
+	t.Setenv("CHUNKING_STRATEGY", "")
+	t.Setenv("EMBEDDING_PROVIDER", "mock")
+	t.Setenv("LLM_PROVIDER", "mock")
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if config.Chunking.Strategy != chunk.StrategySentence {
+		t.Errorf("Expected sentence chunking by default, got %q", config.Chunking.Strategy)
+	}
+}
+
+func TestLoadConfig_TimingBreakdownOffByDefault(t *testing.T) {
+	t.Setenv("RESPONSE_TIMING_BREAKDOWN", "")
+	t.Setenv("EMBEDDING_PROVIDER", "mock")
+	t.Setenv("LLM_PROVIDER", "mock")
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if config.Server.TimingBreakdown {
+		t.Error("Expected the timing breakdown to be off by default")
+	}
+}
@@ -0,0 +1,115 @@
+package embedding
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"go-rag/internal/cache"
+	"go-rag/internal/types"
+)
+
+// cachingService returns recent embeddings of the same text from a cache, so
+// re-ingested chunks and repeated queries aren't sent to the provider again.
+// Cached vectors are shared, so callers must not modify them.
+type cachingService struct {
+	Service
+	embeddings *cache.LRU[[]float64]
+}
+
+// withEmbeddingCache wraps service with an embedding cache when one is configured
+func withEmbeddingCache(service Service, config types.EmbeddingConfig) Service {
+	if config.CacheSize <= 0 {
+		return service
+	}
+	return &cachingService{
+		Service:    service,
+		embeddings: cache.NewLRU[[]float64](config.CacheSize, time.Duration(config.CacheTTLSecs)*time.Second),
+	}
+}
+
+// CacheStats reports the counters of service's embedding cache, or false when
+// it has none
+func CacheStats(service Service) (types.CacheStats, bool) {
+	if cached, ok := service.(*cachingService); ok {
+		return cached.embeddings.Stats(), true
+	}
+	return types.CacheStats{}, false
+}
+
+// GenerateEmbedding returns the cached embedding of text, or generates and caches it
+func (s *cachingService) GenerateEmbedding(ctx context.Context, text string) ([]float64, error) {
+	key := s.key(ctx, text)
+	if !cache.Bypassed(ctx) {
+		if embedding, ok := s.embeddings.Get(key); ok {
+			return embedding, nil
+		}
+	}
+
+	embedding, err := s.Service.GenerateEmbedding(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	s.embeddings.Set(key, embedding)
+	return embedding, nil
+}
+
+// GenerateEmbeddings returns the cached embeddings of texts and generates the
+// rest in one call to the wrapped service, keeping the order of texts
+func (s *cachingService) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float64, error) {
+	embeddings := make([][]float64, len(texts))
+	keys := make([]string, len(texts))
+	var missing []string
+	var missingIndexes []int
+	empty := 0
+	for i, text := range texts {
+		if text == "" {
+			empty++
+			continue
+		}
+		keys[i] = s.key(ctx, text)
+		if !cache.Bypassed(ctx) {
+			if embedding, ok := s.embeddings.Get(keys[i]); ok {
+				embeddings[i] = embedding
+				continue
+			}
+		}
+		missing = append(missing, text)
+		missingIndexes = append(missingIndexes, i)
+	}
+
+	// Without any text, the wrapped service reports the error
+	if empty == len(texts) {
+		return s.Service.GenerateEmbeddings(ctx, texts)
+	}
+	if len(missing) == 0 {
+		return embeddings, nil
+	}
+
+	generated, err := s.Service.GenerateEmbeddings(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	for j, i := range missingIndexes {
+		if j >= len(generated) || generated[j] == nil {
+			continue
+		}
+		embeddings[i] = generated[j]
+		s.embeddings.Set(keys[i], generated[j])
+	}
+	return embeddings, nil
+}
+
+// key identifies an embedding by the model, its size, the input type on ctx
+// and the text
+func (s *cachingService) key(ctx context.Context, text string) string {
+	config := s.GetConfig()
+	hash := sha256.New()
+	for _, part := range []string{config.Provider, config.Model, strconv.Itoa(config.Dimensions), InputTypeFromContext(ctx, ""), text} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
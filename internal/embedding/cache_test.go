@@ -0,0 +1,109 @@
+package embedding
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"go-rag/internal/cache"
+	"go-rag/internal/types"
+)
+
+// countingService records the texts sent to the wrapped service
+type countingService struct {
+	Service
+	embedded []string
+}
+
+func (s *countingService) GenerateEmbedding(ctx context.Context, text string) ([]float64, error) {
+	s.embedded = append(s.embedded, text)
+	return s.Service.GenerateEmbedding(ctx, text)
+}
+
+func (s *countingService) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float64, error) {
+	s.embedded = append(s.embedded, texts...)
+	return s.Service.GenerateEmbeddings(ctx, texts)
+}
+
+func newCachedTestService(t *testing.T) (*countingService, Service) {
+	t.Helper()
+	config := types.EmbeddingConfig{Provider: "mock", Model: "mock-model", Dimensions: 8, CacheSize: 10}
+	mock, err := NewMockService(config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	counting := &countingService{Service: mock}
+	return counting, withEmbeddingCache(counting, config)
+}
+
+func TestEmbeddingCache_EmbedsOnlyMisses(t *testing.T) {
+	counting, service := newCachedTestService(t)
+	ctx := context.Background()
+
+	if _, err := service.GenerateEmbedding(ctx, "alpha"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	embeddings, err := service.GenerateEmbeddings(ctx, []string{"beta", "", "alpha"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if want := []string{"alpha", "beta"}; !slices.Equal(counting.embedded, want) {
+		t.Errorf("Expected the provider to embed %v, got %v", want, counting.embedded)
+	}
+	if len(embeddings) != 3 || embeddings[1] != nil {
+		t.Fatalf("Expected 3 embeddings with a gap for the empty text, got %v", embeddings)
+	}
+	for i, text := range []string{"beta", "", "alpha"} {
+		if text == "" {
+			continue
+		}
+		want, _ := counting.Service.GenerateEmbedding(ctx, text)
+		if !slices.Equal(embeddings[i], want) {
+			t.Errorf("Embedding %d doesn't belong to %q", i, text)
+		}
+	}
+
+	stats, ok := CacheStats(service)
+	if !ok || stats.Hits != 1 || stats.Misses != 2 || stats.Entries != 2 {
+		t.Errorf("Expected 1 hit, 2 misses and 2 entries, got %+v (%v)", stats, ok)
+	}
+}
+
+func TestEmbeddingCache_KeysOnInputType(t *testing.T) {
+	counting, service := newCachedTestService(t)
+	ctx := context.Background()
+
+	service.GenerateEmbedding(WithInputType(ctx, "search_document"), "alpha")
+	service.GenerateEmbedding(WithInputType(ctx, "search_query"), "alpha")
+
+	if len(counting.embedded) != 2 {
+		t.Errorf("Expected each input type to be embedded, got %v", counting.embedded)
+	}
+}
+
+func TestEmbeddingCache_Bypass(t *testing.T) {
+	counting, service := newCachedTestService(t)
+	ctx := context.Background()
+
+	service.GenerateEmbedding(ctx, "alpha")
+	service.GenerateEmbeddings(cache.WithBypass(ctx), []string{"alpha"})
+	service.GenerateEmbedding(ctx, "alpha")
+
+	if want := []string{"alpha", "alpha"}; !slices.Equal(counting.embedded, want) {
+		t.Errorf("Expected a bypassed request to embed again, got %v", counting.embedded)
+	}
+}
+
+func TestEmbeddingCache_Disabled(t *testing.T) {
+	service, err := NewService(types.EmbeddingConfig{Provider: "mock", Dimensions: 8})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := CacheStats(service); ok {
+		t.Error("Expected no cache without a cache size")
+	}
+	if _, ok := service.(*MockService); !ok {
+		t.Errorf("Expected the provider to be used directly, got %T", service)
+	}
+}
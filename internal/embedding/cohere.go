@@ -0,0 +1,204 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-rag/internal/retry"
+	"go-rag/internal/types"
+)
+
+// cohereBaseURL is Cohere's API endpoint
+const cohereBaseURL = "https://api.cohere.com"
+
+// cohereMaxBatch is the most texts Cohere embeds in one request
+const cohereMaxBatch = 96
+
+// cohereModelDimensions are the fixed output sizes of Cohere's v3 models
+var cohereModelDimensions = map[string]int{
+	"embed-english-v3.0":            1024,
+	"embed-multilingual-v3.0":       1024,
+	"embed-english-light-v3.0":      384,
+	"embed-multilingual-light-v3.0": 384,
+}
+
+// cohereOutputDimensions are the sizes embed-v4.0 can be asked to return
+var cohereOutputDimensions = map[int]bool{256: true, 512: true, 1024: true, 1536: true}
+
+// CohereService implements the embedding Service interface using Cohere's embed API
+type CohereService struct {
+	config     types.EmbeddingConfig
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewCohereService creates a new Cohere embedding service. The v3 models have
+// a fixed size, which overrides the configured dimensions; embed-v4.0 is asked
+// for the configured size.
+func NewCohereService(config types.EmbeddingConfig) (*CohereService, error) {
+	if config.APIKey == "" {
+		return nil, fmt.Errorf("Cohere API key is required")
+	}
+
+	if config.Model == "" {
+		return nil, fmt.Errorf("Cohere model is required")
+	}
+
+	if dims, ok := cohereModelDimensions[config.Model]; ok {
+		config.Dimensions = dims
+	} else if config.Model == "embed-v4.0" && !cohereOutputDimensions[config.Dimensions] {
+		return nil, fmt.Errorf("embed-v4.0 supports 256, 512, 1024 or 1536 dimensions, got %d", config.Dimensions)
+	}
+
+	return &CohereService{
+		config:     config,
+		baseURL:    cohereBaseURL,
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+	}, nil
+}
+
+// GenerateEmbedding generates an embedding vector for a single text. Cohere
+// embeds it as a search query unless the context sets another input type.
+func (s *CohereService) GenerateEmbedding(ctx context.Context, text string) ([]float64, error) {
+	if text == "" {
+		return nil, fmt.Errorf("text cannot be empty")
+	}
+
+	embeddings, err := s.embed(ctx, []string{text}, InputTypeFromContext(ctx, InputTypeQuery))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embedding: %w", err)
+	}
+	return embeddings[0], nil
+}
+
+// GenerateEmbeddings generates embedding vectors for multiple texts, as
+// documents unless the context sets another input type. Empty texts are
+// skipped and get a nil embedding.
+func (s *CohereService) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float64, error) {
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("texts cannot be empty")
+	}
+
+	// Cohere rejects empty texts, so send only the others and remember where they go
+	var inputs []string
+	var inputIndexes []int
+	for i, text := range texts {
+		if text != "" {
+			inputs = append(inputs, text)
+			inputIndexes = append(inputIndexes, i)
+		}
+	}
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("no valid texts provided")
+	}
+
+	// Only send each distinct text once when deduplication is enabled
+	var positions []int
+	if s.config.Deduplicate {
+		inputs, positions = dedupeTexts(inputs)
+	}
+
+	inputType := InputTypeFromContext(ctx, InputTypeDocument)
+	var embeddings [][]float64
+	for start := 0; start < len(inputs); start += cohereMaxBatch {
+		batch, err := s.embed(ctx, inputs[start:min(start+cohereMaxBatch, len(inputs))], inputType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create embeddings: %w", err)
+		}
+		embeddings = append(embeddings, batch...)
+	}
+
+	results := make([][]float64, len(texts))
+	for j, i := range inputIndexes {
+		if positions != nil {
+			results[i] = embeddings[positions[j]]
+		} else {
+			results[i] = embeddings[j]
+		}
+	}
+	return results, nil
+}
+
+// embed calls the embed API for one batch, retrying transient failures
+// within the request's retry budget
+func (s *CohereService) embed(ctx context.Context, texts []string, inputType string) ([][]float64, error) {
+	body := map[string]any{
+		"model":           s.config.Model,
+		"texts":           texts,
+		"input_type":      inputType,
+		"embedding_types": []string{"float"},
+	}
+	if s.config.Model == "embed-v4.0" {
+		body["output_dimension"] = s.config.Dimensions
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	policy := retryPolicy(s.config)
+
+	var resp struct {
+		Embeddings struct {
+			Float [][]float64 `json:"float"`
+		} `json:"embeddings"`
+	}
+	err = retry.Do(ctx, policy, func() error {
+		return s.post(ctx, "/v2/embed", data, &resp)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Embeddings.Float) != len(texts) {
+		return nil, fmt.Errorf("embedding count mismatch: expected %d, got %d", len(texts), len(resp.Embeddings.Float))
+	}
+	return resp.Embeddings.Float, nil
+}
+
+// post sends a JSON request to Cohere and decodes the JSON response into out
+func (s *CohereService) post(ctx context.Context, path string, body []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+s.config.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &statusError{
+			provider:   "cohere",
+			status:     resp.StatusCode,
+			message:    strings.TrimSpace(string(message)),
+			retryAfter: retry.ParseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// GetDimensions returns the dimension size of the embeddings
+func (s *CohereService) GetDimensions() int {
+	return s.config.Dimensions
+}
+
+// GetConfig returns the embedding configuration
+func (s *CohereService) GetConfig() types.EmbeddingConfig {
+	return s.config
+}
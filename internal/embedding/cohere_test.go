@@ -0,0 +1,154 @@
+package embedding
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-rag/internal/types"
+)
+
+// cohereRequest is the body of an embed request
+type cohereRequest struct {
+	Model           string   `json:"model"`
+	Texts           []string `json:"texts"`
+	InputType       string   `json:"input_type"`
+	OutputDimension int      `json:"output_dimension"`
+}
+
+// newTestCohereService serves embed requests with handle, which returns the
+// status to answer with, and embeds each text as [len(text), 0, 0, 0]
+func newTestCohereService(t *testing.T, config types.EmbeddingConfig, handle func(cohereRequest) int) *CohereService {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/embed" || r.Header.Get("Authorization") != "Bearer key" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var req cohereRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if status := handle(req); status != http.StatusOK {
+			http.Error(w, "unavailable", status)
+			return
+		}
+
+		vectors := make([][]float64, len(req.Texts))
+		for i, text := range req.Texts {
+			vectors[i] = []float64{float64(len(text)), 0, 0, 0}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"embeddings": map[string]any{"float": vectors}})
+	}))
+	t.Cleanup(server.Close)
+
+	config.Provider = "cohere"
+	config.APIKey = "key"
+	service, err := NewCohereService(config)
+	if err != nil {
+		t.Fatalf("Failed to create Cohere service: %v", err)
+	}
+	service.baseURL = server.URL
+	return service
+}
+
+func TestNewCohereService(t *testing.T) {
+	service, err := NewCohereService(types.EmbeddingConfig{Provider: "cohere", Model: "embed-english-v3.0", Dimensions: 1536, APIKey: "key"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if service.GetDimensions() != 1024 || service.GetConfig().Dimensions != 1024 {
+		t.Errorf("Expected the model's 1024 dimensions, got %d", service.GetDimensions())
+	}
+
+	service, err = NewCohereService(types.EmbeddingConfig{Provider: "cohere", Model: "embed-v4.0", Dimensions: 512, APIKey: "key"})
+	if err != nil || service.GetDimensions() != 512 {
+		t.Errorf("Expected embed-v4.0 to use the configured 512 dimensions, got %v, %v", service, err)
+	}
+
+	for name, config := range map[string]types.EmbeddingConfig{
+		"missing key":         {Model: "embed-english-v3.0"},
+		"missing model":       {APIKey: "key"},
+		"unsupported v4 size": {Model: "embed-v4.0", Dimensions: 768, APIKey: "key"},
+	} {
+		if _, err := NewCohereService(config); err == nil {
+			t.Errorf("Expected %s to be rejected", name)
+		}
+	}
+
+	if service, err := NewService(types.EmbeddingConfig{Provider: "cohere", Model: "embed-english-v3.0", APIKey: "key"}); err != nil {
+		t.Errorf("Expected NewService to create a Cohere service, got %v", err)
+	} else if _, ok := service.(*CohereService); !ok {
+		t.Errorf("Expected a CohereService, got %T", service)
+	}
+}
+
+func TestCohereService_InputTypes(t *testing.T) {
+	var inputTypes []string
+	service := newTestCohereService(t, types.EmbeddingConfig{Model: "embed-english-v3.0"}, func(req cohereRequest) int {
+		inputTypes = append(inputTypes, req.InputType)
+		return http.StatusOK
+	})
+	ctx := context.Background()
+
+	service.GenerateEmbedding(ctx, "query")
+	service.GenerateEmbeddings(ctx, []string{"chunk"})
+	service.GenerateEmbeddings(WithInputType(ctx, InputTypeQuery), []string{"query"})
+	service.GenerateEmbedding(WithInputType(ctx, InputTypeDocument), "chunk")
+
+	want := []string{InputTypeQuery, InputTypeDocument, InputTypeQuery, InputTypeDocument}
+	if strings.Join(inputTypes, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected input types %v, got %v", want, inputTypes)
+	}
+}
+
+func TestCohereService_GenerateEmbeddingsBatches(t *testing.T) {
+	var batches []int
+	service := newTestCohereService(t, types.EmbeddingConfig{Model: "embed-v4.0", Dimensions: 256, Deduplicate: true}, func(req cohereRequest) int {
+		if req.OutputDimension != 256 {
+			t.Errorf("Expected output_dimension 256, got %d", req.OutputDimension)
+		}
+		batches = append(batches, len(req.Texts))
+		return http.StatusOK
+	})
+
+	// 100 distinct texts, a duplicate and an empty text
+	texts := make([]string, 0, 102)
+	for i := range 100 {
+		texts = append(texts, strings.Repeat("a", i+1))
+	}
+	texts = append(texts, "", "aaa")
+
+	embeddings, err := service.GenerateEmbeddings(context.Background(), texts)
+	if err != nil {
+		t.Fatalf("GenerateEmbeddings failed: %v", err)
+	}
+	if len(batches) != 2 || batches[0] != cohereMaxBatch || batches[1] != 4 {
+		t.Errorf("Expected batches of %d and 4 distinct texts, got %v", cohereMaxBatch, batches)
+	}
+	if len(embeddings) != len(texts) || embeddings[99][0] != 100 || embeddings[100] != nil || embeddings[101][0] != 3 {
+		t.Errorf("Expected embeddings aligned with the input texts, got %d", len(embeddings))
+	}
+}
+
+func TestCohereService_RetriesTransientFailures(t *testing.T) {
+	calls := 0
+	service := newTestCohereService(t, types.EmbeddingConfig{Model: "embed-english-v3.0", MaxRetries: 1}, func(cohereRequest) int {
+		calls++
+		if calls == 1 {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusOK
+	})
+
+	if _, err := service.GenerateEmbedding(context.Background(), "query"); err != nil || calls != 2 {
+		t.Errorf("Expected a retry after the 503, got %d calls and %v", calls, err)
+	}
+
+	calls = 0
+	service.config.MaxRetries = 0
+	_, err := service.GenerateEmbedding(context.Background(), "query")
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("Expected the Cohere status in the error, got %v", err)
+	}
+}
@@ -3,6 +3,9 @@ package embedding
 import (
 	"context"
 	"fmt"
+	"time"
+
+	"go-rag/internal/retry"
 	"go-rag/internal/types"
 )
 
@@ -11,7 +14,8 @@ type Service interface {
 	// GenerateEmbedding generates an embedding vector for a single text
 	GenerateEmbedding(ctx context.Context, text string) ([]float64, error)
 
-	// GenerateEmbeddings generates embedding vectors for multiple texts
+	// GenerateEmbeddings generates embedding vectors for multiple texts, in
+	// their order; empty texts get a nil embedding
 	GenerateEmbeddings(ctx context.Context, texts []string) ([][]float64, error)
 
 	// GetDimensions returns the dimension size of the embeddings
@@ -21,14 +25,78 @@ type Service interface {
 	GetConfig() types.EmbeddingConfig
 }
 
+// Input types tell providers that embed queries and documents differently,
+// such as Cohere, which side of a search a text is on
+const (
+	InputTypeDocument = "search_document"
+	InputTypeQuery    = "search_query"
+)
+
+type inputTypeKey struct{}
+
+// WithInputType returns a context whose embeddings are generated for the given
+// input type. Providers without input types ignore it.
+func WithInputType(ctx context.Context, inputType string) context.Context {
+	return context.WithValue(ctx, inputTypeKey{}, inputType)
+}
+
+// InputTypeFromContext returns the input type set on ctx, or fallback
+func InputTypeFromContext(ctx context.Context, fallback string) string {
+	if inputType, ok := ctx.Value(inputTypeKey{}).(string); ok && inputType != "" {
+		return inputType
+	}
+	return fallback
+}
+
+// statusError is a non-2xx response from an embedding provider's HTTP API
+type statusError struct {
+	provider   string
+	status     int
+	message    string
+	retryAfter time.Duration
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s returned %d: %s", e.provider, e.status, e.message)
+}
+
+// StatusCode lets retry.IsRetryable classify the failure
+func (e *statusError) StatusCode() int {
+	return e.status
+}
+
+// RetryAfter lets retry.Do wait as long as the provider's Retry-After asked
+func (e *statusError) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+// retryPolicy returns how a provider's calls are retried under config
+func retryPolicy(config types.EmbeddingConfig) retry.Policy {
+	return retry.Policy{
+		MaxRetries: config.MaxRetries,
+		Delay:      time.Duration(config.RetryDelayMs) * time.Millisecond,
+		MaxDelay:   time.Duration(config.RetryMaxDelayMs) * time.Millisecond,
+	}
+}
+
 // NewService creates a new embedding service based on the provider configuration
 func NewService(config types.EmbeddingConfig) (Service, error) {
+	var service Service
+	var err error
 	switch config.Provider {
-	case "openai":
-		return NewOpenAIService(config)
+	case "openai", "azure":
+		service, err = NewOpenAIService(config)
+	case "cohere":
+		service, err = NewCohereService(config)
+	case "ollama":
+		service, err = NewOllamaEmbeddingService(config)
 	case "mock":
-		return NewMockService(config)
+		service, err = NewMockService(config)
 	default:
 		return nil, fmt.Errorf("unsupported embedding provider: %s", config.Provider)
 	}
+	if err != nil {
+		return nil, err
+	}
+	return withEmbeddingCache(service, config), nil
 }
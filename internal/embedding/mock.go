@@ -48,7 +48,8 @@ func (s *MockService) GenerateEmbedding(ctx context.Context, text string) ([]flo
 	return normalizeVector(embedding), nil
 }
 
-// GenerateEmbeddings generates embedding vectors for multiple texts
+// GenerateEmbeddings generates embedding vectors for multiple texts, leaving
+// empty texts without one like the other providers
 func (s *MockService) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float64, error) {
 	if len(texts) == 0 {
 		return nil, fmt.Errorf("texts cannot be empty")
@@ -57,7 +58,7 @@ func (s *MockService) GenerateEmbeddings(ctx context.Context, texts []string) ([
 	embeddings := make([][]float64, len(texts))
 	for i, text := range texts {
 		if text == "" {
-			continue // Skip empty texts
+			continue // Keep the gap so later embeddings stay aligned
 		}
 		
 		embedding, err := s.GenerateEmbedding(ctx, text)
@@ -0,0 +1,227 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go-rag/internal/retry"
+	"go-rag/internal/types"
+)
+
+// defaultOllamaConcurrency is how many texts are embedded at once when no concurrency is configured
+const defaultOllamaConcurrency = 4
+
+// OllamaEmbeddingService implements the embedding Service interface using a
+// local Ollama server. It needs no API key.
+type OllamaEmbeddingService struct {
+	config     types.EmbeddingConfig
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewOllamaEmbeddingService creates a new Ollama embedding service
+func NewOllamaEmbeddingService(config types.EmbeddingConfig) (*OllamaEmbeddingService, error) {
+	if config.BaseURL == "" {
+		return nil, fmt.Errorf("Ollama base URL is required")
+	}
+
+	if config.Model == "" {
+		return nil, fmt.Errorf("Ollama model is required")
+	}
+
+	if config.Dimensions <= 0 {
+		return nil, fmt.Errorf("dimensions must be positive")
+	}
+
+	return &OllamaEmbeddingService{
+		config:     config,
+		baseURL:    strings.TrimRight(config.BaseURL, "/"),
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+	}, nil
+}
+
+// GenerateEmbedding generates an embedding vector for a single text
+func (s *OllamaEmbeddingService) GenerateEmbedding(ctx context.Context, text string) ([]float64, error) {
+	if text == "" {
+		return nil, fmt.Errorf("text cannot be empty")
+	}
+
+	embedding, err := s.embed(ctx, text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embedding: %w", err)
+	}
+	return embedding, nil
+}
+
+// GenerateEmbeddings generates embedding vectors for multiple texts. Ollama has
+// no batch endpoint, so texts are embedded one request each, a few at a time.
+// Empty texts are skipped and get a nil embedding.
+func (s *OllamaEmbeddingService) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float64, error) {
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("texts cannot be empty")
+	}
+
+	// Embed only the non-empty texts, each distinct one once when deduplication is enabled
+	var inputs []string
+	var inputIndexes []int
+	for i, text := range texts {
+		if text != "" {
+			inputs = append(inputs, text)
+			inputIndexes = append(inputIndexes, i)
+		}
+	}
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("no valid texts provided")
+	}
+
+	var positions []int
+	if s.config.Deduplicate {
+		inputs, positions = dedupeTexts(inputs)
+	}
+
+	embeddings, err := s.embedAll(ctx, inputs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embeddings: %w", err)
+	}
+
+	results := make([][]float64, len(texts))
+	for j, i := range inputIndexes {
+		if positions != nil {
+			results[i] = embeddings[positions[j]]
+		} else {
+			results[i] = embeddings[j]
+		}
+	}
+	return results, nil
+}
+
+// embedAll embeds texts across a pool of workers. Each worker writes only to
+// the indexes it receives; the first failure cancels the rest.
+func (s *OllamaEmbeddingService) embedAll(ctx context.Context, texts []string) ([][]float64, error) {
+	workers := s.config.Concurrency
+	if workers <= 0 {
+		workers = defaultOllamaConcurrency
+	}
+	workers = min(workers, len(texts))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	embeddings := make([][]float64, len(texts))
+	indexes := make(chan int)
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				embedding, err := s.embed(ctx, texts[i])
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					continue
+				}
+				embeddings[i] = embedding
+			}
+		}()
+	}
+
+feed:
+	for i := range texts {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return embeddings, nil
+}
+
+// embed calls the embeddings endpoint for one text, retrying transient
+// failures within the request's retry budget
+func (s *OllamaEmbeddingService) embed(ctx context.Context, text string) ([]float64, error) {
+	data, err := json.Marshal(map[string]string{"model": s.config.Model, "prompt": text})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	policy := retryPolicy(s.config)
+
+	var resp struct {
+		Embedding []float64 `json:"embedding"`
+	}
+	err = retry.Do(ctx, policy, func() error {
+		return s.post(ctx, "/api/embeddings", data, &resp)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Ollama reports the model's size only through the vectors it returns
+	if len(resp.Embedding) != s.config.Dimensions {
+		return nil, fmt.Errorf("ollama model %s returned %d dimensions, expected %d", s.config.Model, len(resp.Embedding), s.config.Dimensions)
+	}
+	return resp.Embedding, nil
+}
+
+// post sends a JSON request to Ollama and decodes the JSON response into out
+func (s *OllamaEmbeddingService) post(ctx context.Context, path string, body []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &statusError{
+			provider:   "ollama",
+			status:     resp.StatusCode,
+			message:    strings.TrimSpace(string(message)),
+			retryAfter: retry.ParseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// GetDimensions returns the dimension size of the embeddings
+func (s *OllamaEmbeddingService) GetDimensions() int {
+	return s.config.Dimensions
+}
+
+// GetConfig returns the embedding configuration
+func (s *OllamaEmbeddingService) GetConfig() types.EmbeddingConfig {
+	return s.config
+}
@@ -0,0 +1,132 @@
+package embedding
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-rag/internal/types"
+)
+
+// newTestOllamaServer embeds each prompt as [len(prompt), 0, 0] after delay,
+// answering 500 for prompts containing "fail"
+func newTestOllamaServer(t *testing.T, delay time.Duration, inFlight, peak *int64) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embeddings" || r.Header.Get("Authorization") != "" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		current := atomic.AddInt64(inFlight, 1)
+		defer atomic.AddInt64(inFlight, -1)
+		for {
+			seen := atomic.LoadInt64(peak)
+			if current <= seen || atomic.CompareAndSwapInt64(peak, seen, current) {
+				break
+			}
+		}
+		time.Sleep(delay)
+
+		var req struct {
+			Model  string `json:"model"`
+			Prompt string `json:"prompt"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "nomic-embed-text" || strings.Contains(req.Prompt, "fail") {
+			http.Error(w, "model failed", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"embedding": []float64{float64(len(req.Prompt)), 0, 0}})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestOllamaService(t *testing.T, baseURL string, concurrency int) *OllamaEmbeddingService {
+	service, err := NewOllamaEmbeddingService(types.EmbeddingConfig{
+		Provider:    "ollama",
+		Model:       "nomic-embed-text",
+		Dimensions:  3,
+		BaseURL:     baseURL + "/",
+		Concurrency: concurrency,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create Ollama service: %v", err)
+	}
+	return service
+}
+
+func TestNewOllamaEmbeddingService(t *testing.T) {
+	for name, config := range map[string]types.EmbeddingConfig{
+		"missing base URL": {Model: "nomic-embed-text", Dimensions: 768},
+		"missing model":    {BaseURL: "http://localhost:11434", Dimensions: 768},
+		"zero dimensions":  {BaseURL: "http://localhost:11434", Model: "nomic-embed-text"},
+	} {
+		if _, err := NewOllamaEmbeddingService(config); err == nil {
+			t.Errorf("Expected %s to be rejected", name)
+		}
+	}
+
+	service, err := NewService(types.EmbeddingConfig{Provider: "ollama", BaseURL: "http://localhost:11434", Model: "nomic-embed-text", Dimensions: 768})
+	if err != nil {
+		t.Fatalf("Expected an Ollama service without an API key, got %v", err)
+	}
+	if _, ok := service.(*OllamaEmbeddingService); !ok || service.GetDimensions() != 768 {
+		t.Errorf("Expected a 768-dimension OllamaEmbeddingService, got %T", service)
+	}
+}
+
+func TestOllamaEmbeddingService_GenerateEmbeddings(t *testing.T) {
+	var inFlight, peak int64
+	server := newTestOllamaServer(t, 20*time.Millisecond, &inFlight, &peak)
+	service := newTestOllamaService(t, server.URL, 2)
+
+	texts := []string{"a", "bb", "", "cccc", "ddddd", "eeeeee"}
+	embeddings, err := service.GenerateEmbeddings(context.Background(), texts)
+	if err != nil {
+		t.Fatalf("GenerateEmbeddings failed: %v", err)
+	}
+	for i, text := range texts {
+		if text == "" {
+			if embeddings[i] != nil {
+				t.Errorf("Expected no embedding for the empty text, got %v", embeddings[i])
+			}
+			continue
+		}
+		if embeddings[i][0] != float64(len(text)) {
+			t.Errorf("Expected embedding %d to belong to %q, got %v", i, text, embeddings[i])
+		}
+	}
+	if got := atomic.LoadInt64(&peak); got != 2 {
+		t.Errorf("Expected 2 requests in flight at most, saw %d", got)
+	}
+}
+
+func TestOllamaEmbeddingService_Errors(t *testing.T) {
+	var inFlight, peak int64
+	server := newTestOllamaServer(t, 0, &inFlight, &peak)
+	service := newTestOllamaService(t, server.URL, 4)
+	ctx := context.Background()
+
+	_, err := service.GenerateEmbeddings(ctx, []string{"one", "fail", "three"})
+	if err == nil || !strings.Contains(err.Error(), "ollama returned 500") {
+		t.Errorf("Expected the failed request's status, got %v", err)
+	}
+
+	service.config.Dimensions = 768
+	if _, err := service.GenerateEmbedding(ctx, "text"); err == nil || !strings.Contains(err.Error(), "768") {
+		t.Errorf("Expected a dimension mismatch error, got %v", err)
+	}
+
+	// Canceling the context stops the batch
+	service.config.Dimensions = 3
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := service.GenerateEmbeddings(canceled, []string{"a", "b"}); err == nil {
+		t.Error("Expected a canceled context to fail the batch")
+	}
+}
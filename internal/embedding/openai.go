@@ -3,25 +3,49 @@ package embedding
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"strings"
 
+	"go-rag/internal/azure"
+	"go-rag/internal/retry"
 	"go-rag/internal/types"
 
 	"github.com/sashabaranov/go-openai"
 )
 
+// defaultOpenAIBatchSize is how many texts are embedded per request when no
+// batch size is configured, well under the API's 2048 inputs per request
+const defaultOpenAIBatchSize = 512
+
 // OpenAIService implements the embedding Service interface using OpenAI
 type OpenAIService struct {
 	client *openai.Client
 	config types.EmbeddingConfig
 }
 
-// NewOpenAIService creates a new OpenAI embedding service
+// NewOpenAIService creates a new OpenAI embedding service. A BaseURL points it
+// at an OpenAI-compatible server. With the "azure" provider it calls the
+// configured Azure OpenAI resource instead, using the deployment mapped to
+// the model.
 func NewOpenAIService(config types.EmbeddingConfig) (*OpenAIService, error) {
 	if config.APIKey == "" {
 		return nil, fmt.Errorf("OpenAI API key is required")
 	}
 
-	client := openai.NewClient(config.APIKey)
+	clientConfig := openai.DefaultConfig(config.APIKey)
+	if config.BaseURL != "" {
+		clientConfig.BaseURL = strings.TrimRight(config.BaseURL, "/")
+	}
+	if config.Provider == "azure" {
+		var err error
+		clientConfig, err = azure.ClientConfig(config.APIKey, config.Azure)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Record Retry-After, which the client's errors leave out
+	clientConfig.HTTPClient = &http.Client{Transport: retry.Transport(nil)}
+	client := openai.NewClientWithConfig(clientConfig)
 
 	return &OpenAIService{
 		client: client,
@@ -40,7 +64,7 @@ func (s *OpenAIService) GenerateEmbedding(ctx context.Context, text string) ([]f
 		Model: openai.EmbeddingModel(s.config.Model),
 	}
 
-	resp, err := s.client.CreateEmbeddings(ctx, req)
+	resp, err := s.createEmbeddings(ctx, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create embedding: %w", err)
 	}
@@ -64,44 +88,121 @@ func (s *OpenAIService) GenerateEmbeddings(ctx context.Context, texts []string)
 		return nil, fmt.Errorf("texts cannot be empty")
 	}
 
-	// Filter out empty texts
-	validTexts := make([]string, 0, len(texts))
-	for _, text := range texts {
+	// The API rejects empty texts, so send only the others and remember where they go
+	var inputs []string
+	var inputIndexes []int
+	for i, text := range texts {
 		if text != "" {
-			validTexts = append(validTexts, text)
+			inputs = append(inputs, text)
+			inputIndexes = append(inputIndexes, i)
 		}
 	}
 
-	if len(validTexts) == 0 {
+	if len(inputs) == 0 {
 		return nil, fmt.Errorf("no valid texts provided")
 	}
 
+	// Only send each distinct text once when deduplication is enabled
+	var positions []int
+	if s.config.Deduplicate {
+		inputs, positions = dedupeTexts(inputs)
+	}
+
+	// The API caps the inputs and tokens of one request, so embed in batches
+	batchSize := s.config.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultOpenAIBatchSize
+	}
+	embeddings := make([][]float64, 0, len(inputs))
+	for start := 0; start < len(inputs); start += batchSize {
+		batch, err := s.embedBatch(ctx, inputs[start:min(start+batchSize, len(inputs))])
+		if err != nil {
+			return nil, err
+		}
+		embeddings = append(embeddings, batch...)
+	}
+
+	// Empty texts get no embedding; duplicates share their text's
+	results := make([][]float64, len(texts))
+	for j, i := range inputIndexes {
+		if positions != nil {
+			results[i] = embeddings[positions[j]]
+		} else {
+			results[i] = embeddings[j]
+		}
+	}
+	return results, nil
+}
+
+// embedBatch embeds one request's worth of texts, returning their embeddings
+// in the order of the texts
+func (s *OpenAIService) embedBatch(ctx context.Context, inputs []string) ([][]float64, error) {
 	req := openai.EmbeddingRequest{
-		Input: validTexts,
+		Input: inputs,
 		Model: openai.EmbeddingModel(s.config.Model),
 	}
 
-	resp, err := s.client.CreateEmbeddings(ctx, req)
+	resp, err := s.createEmbeddings(ctx, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create embeddings: %w", err)
 	}
 
-	if len(resp.Data) != len(validTexts) {
-		return nil, fmt.Errorf("embedding count mismatch: expected %d, got %d", len(validTexts), len(resp.Data))
+	if len(resp.Data) != len(inputs) {
+		return nil, fmt.Errorf("embedding count mismatch: expected %d, got %d", len(inputs), len(resp.Data))
 	}
 
-	embeddings := make([][]float64, len(resp.Data))
-	for i, data := range resp.Data {
+	// Each embedding says which input it belongs to
+	embeddings := make([][]float64, len(inputs))
+	for _, data := range resp.Data {
+		if data.Index < 0 || data.Index >= len(inputs) || embeddings[data.Index] != nil {
+			return nil, fmt.Errorf("embedding response has an invalid or repeated index %d", data.Index)
+		}
 		embedding := make([]float64, len(data.Embedding))
 		for j, v := range data.Embedding {
 			embedding[j] = float64(v)
 		}
-		embeddings[i] = embedding
+		embeddings[data.Index] = embedding
 	}
 
 	return embeddings, nil
 }
 
+// createEmbeddings calls the API, retrying transient failures within the
+// request's retry budget with exponential backoff, or after the Retry-After
+// the API asked for
+func (s *OpenAIService) createEmbeddings(ctx context.Context, req openai.EmbeddingRequest) (openai.EmbeddingResponse, error) {
+	policy := retryPolicy(s.config)
+	ctx = retry.WithRetryAfter(ctx)
+
+	var resp openai.EmbeddingResponse
+	err := retry.Do(ctx, policy, func() error {
+		var err error
+		resp, err = s.client.CreateEmbeddings(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+// dedupeTexts returns the distinct texts in first-seen order along with,
+// for each input text, the index of its entry in the distinct slice
+func dedupeTexts(texts []string) ([]string, []int) {
+	unique := make([]string, 0, len(texts))
+	positions := make([]int, len(texts))
+	seen := make(map[string]int, len(texts))
+
+	for i, text := range texts {
+		pos, exists := seen[text]
+		if !exists {
+			pos = len(unique)
+			seen[text] = pos
+			unique = append(unique, text)
+		}
+		positions[i] = pos
+	}
+
+	return unique, positions
+}
+
 // GetDimensions returns the dimension size of the embeddings
 func (s *OpenAIService) GetDimensions() int {
 	return s.config.Dimensions
@@ -2,9 +2,18 @@ package embedding
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
 	"testing"
+	"time"
 
+	"go-rag/internal/retry"
 	"go-rag/internal/types"
+
+	"github.com/sashabaranov/go-openai"
 )
 
 func TestNewOpenAIService(t *testing.T) {
@@ -156,3 +165,231 @@ func TestNewService_UnsupportedProvider(t *testing.T) {
 		t.Errorf("Expected error message '%s', got '%s'", expectedMsg, err.Error())
 	}
 }
+
+// newTestOpenAIService creates an OpenAI service pointed at a fake embeddings
+// endpoint that returns [len(text), index] for every input, listed last input first
+func newTestOpenAIService(t *testing.T, config types.EmbeddingConfig, onRequest func(inputs []string)) *OpenAIService {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input []string `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if onRequest != nil {
+			onRequest(req.Input)
+		}
+
+		data := make([]openai.Embedding, len(req.Input))
+		for i, text := range req.Input {
+			data[i] = openai.Embedding{
+				Object:    "embedding",
+				Index:     i,
+				Embedding: []float32{float32(len(text)), float32(i)},
+			}
+		}
+		slices.Reverse(data)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(openai.EmbeddingResponse{Object: "list", Data: data})
+	}))
+	t.Cleanup(server.Close)
+
+	clientConfig := openai.DefaultConfig("test-api-key")
+	clientConfig.BaseURL = server.URL + "/v1"
+
+	return &OpenAIService{
+		client: openai.NewClientWithConfig(clientConfig),
+		config: config,
+	}
+}
+
+func TestGenerateEmbeddings_Deduplicate(t *testing.T) {
+	config := types.EmbeddingConfig{
+		Provider:    "openai",
+		Model:       "text-embedding-ada-002",
+		Dimensions:  2,
+		APIKey:      "test-api-key",
+		Deduplicate: true,
+	}
+
+	var sentInputs []string
+	service := newTestOpenAIService(t, config, func(inputs []string) {
+		sentInputs = inputs
+	})
+
+	texts := []string{"footer", "body text", "footer", "body text", "footer"}
+	embeddings, err := service.GenerateEmbeddings(context.Background(), texts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(sentInputs) != 2 {
+		t.Errorf("Expected 2 unique inputs sent to provider, got %d", len(sentInputs))
+	}
+
+	if len(embeddings) != len(texts) {
+		t.Fatalf("Expected %d embeddings, got %d", len(texts), len(embeddings))
+	}
+
+	for i, text := range texts {
+		if embeddings[i][0] != float64(len(text)) {
+			t.Errorf("Embedding %d does not belong to text %q", i, text)
+		}
+	}
+}
+
+func TestNewService_UsesBaseURL(t *testing.T) {
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(openai.EmbeddingResponse{
+			Object: "list",
+			Data:   []openai.Embedding{{Object: "embedding", Embedding: []float32{1, 2}}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	service, err := NewService(types.EmbeddingConfig{
+		Provider:   "openai",
+		Model:      "text-embedding-3-small",
+		Dimensions: 2,
+		APIKey:     "test-api-key",
+		BaseURL:    server.URL + "/v1/",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	if _, err := service.GenerateEmbedding(context.Background(), "text"); err != nil {
+		t.Fatalf("GenerateEmbedding failed: %v", err)
+	}
+	if path != "/v1/embeddings" {
+		t.Errorf("Expected the compatible server's embeddings path, got %s", path)
+	}
+}
+
+func TestNewService_AzureUsesMappedDeployment(t *testing.T) {
+	var path, apiVersion, apiKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, apiVersion, apiKey = r.URL.Path, r.URL.Query().Get("api-version"), r.Header.Get("api-key")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(openai.EmbeddingResponse{
+			Object: "list",
+			Data:   []openai.Embedding{{Object: "embedding", Embedding: []float32{1, 2}}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	service, err := NewService(types.EmbeddingConfig{
+		Provider:   "azure",
+		Model:      "text-embedding-3-small",
+		Dimensions: 2,
+		APIKey:     "azure-key",
+		Azure: types.AzureOpenAIConfig{
+			Endpoint:    server.URL,
+			APIVersion:  "2024-06-01",
+			Deployments: map[string]string{"text-embedding-3-small": "embed-prod"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create Azure service: %v", err)
+	}
+
+	if _, err := service.GenerateEmbedding(context.Background(), "text"); err != nil {
+		t.Fatalf("GenerateEmbedding failed: %v", err)
+	}
+	if path != "/openai/deployments/embed-prod/embeddings" {
+		t.Errorf("Expected the mapped deployment's embeddings path, got %s", path)
+	}
+	if apiVersion != "2024-06-01" || apiKey != "azure-key" {
+		t.Errorf("Expected api-version 2024-06-01 and the api-key header, got %q and %q", apiVersion, apiKey)
+	}
+
+	if _, err := NewService(types.EmbeddingConfig{Provider: "azure", Model: "text-embedding-3-small", APIKey: "azure-key"}); err == nil {
+		t.Error("Expected an Azure config without an endpoint or resource to be rejected")
+	}
+}
+
+func TestGenerateEmbeddings_RetriesAfterRetryAfter(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		if calls == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error": {"message": "rate limited", "type": "rate_limit"}}`))
+			return
+		}
+		json.NewEncoder(w).Encode(openai.EmbeddingResponse{Object: "list", Data: []openai.Embedding{{Index: 0, Embedding: []float32{1, 2}}}})
+	}))
+	defer server.Close()
+
+	service, err := NewOpenAIService(types.EmbeddingConfig{Provider: "openai", Model: "text-embedding-3-small", Dimensions: 2, APIKey: "test-api-key",
+		MaxRetries: 2, RetryDelayMs: 3600000, RetryMaxDelayMs: 3600000})
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	service.client = openai.NewClientWithConfig(func() openai.ClientConfig {
+		config := openai.DefaultConfig("test-api-key")
+		config.BaseURL = server.URL + "/v1"
+		config.HTTPClient = &http.Client{Transport: retry.Transport(nil)}
+		return config
+	}())
+
+	// The hour-long backoff wouldn't fit the deadline, so only the
+	// one-second Retry-After lets the retry happen
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	embeddings, err := service.GenerateEmbeddings(ctx, []string{"text"})
+	if err != nil {
+		t.Fatalf("Expected the retry to succeed, got %v", err)
+	}
+	if calls != 2 || len(embeddings) != 1 {
+		t.Errorf("Expected one retry and one embedding, got %d calls and %d embeddings", calls, len(embeddings))
+	}
+}
+
+func TestGenerateEmbeddings_BatchesInOrder(t *testing.T) {
+	config := types.EmbeddingConfig{
+		Provider:   "openai",
+		Model:      "text-embedding-ada-002",
+		Dimensions: 2,
+		APIKey:     "test-api-key",
+		BatchSize:  2,
+	}
+
+	var batches [][]string
+	service := newTestOpenAIService(t, config, func(inputs []string) {
+		batches = append(batches, inputs)
+	})
+
+	texts := []string{"a", "", "ccc", "dddd", "", "eeeee"}
+	embeddings, err := service.GenerateEmbeddings(context.Background(), texts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := [][]string{{"a", "ccc"}, {"dddd", "eeeee"}}
+	if !reflect.DeepEqual(batches, want) {
+		t.Errorf("Expected batches %v, got %v", want, batches)
+	}
+	if len(embeddings) != len(texts) {
+		t.Fatalf("Expected %d embeddings, got %d", len(texts), len(embeddings))
+	}
+	for i, text := range texts {
+		if text == "" {
+			if embeddings[i] != nil {
+				t.Errorf("Expected no embedding for the empty text at %d, got %v", i, embeddings[i])
+			}
+			continue
+		}
+		if embeddings[i] == nil || embeddings[i][0] != float64(len(text)) {
+			t.Errorf("Embedding %d does not belong to text %q: %v", i, text, embeddings[i])
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package embedding
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"go-rag/internal/types"
+)
+
+// Registry resolves the embedding service for a collection, so collections
+// can be embedded with different models in one deployment
+type Registry struct {
+	defaultService Service
+	collections    map[string]Service
+}
+
+// NewRegistry creates the default service and one service per collection
+// override. Every configuration is validated up front so a bad override fails
+// at startup rather than on the first request for its collection.
+func NewRegistry(defaultConfig types.EmbeddingConfig, collections map[string]types.EmbeddingConfig) (*Registry, error) {
+	defaultService, err := NewService(defaultConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	registry := &Registry{
+		defaultService: defaultService,
+		collections:    make(map[string]Service, len(collections)),
+	}
+	for collection, config := range collections {
+		if config.Dimensions <= 0 {
+			return nil, fmt.Errorf("embedding model for collection %s: dimensions must be positive", collection)
+		}
+		service, err := NewService(config)
+		if err != nil {
+			return nil, fmt.Errorf("embedding model for collection %s: %w", collection, err)
+		}
+		registry.collections[collection] = service
+	}
+
+	return registry, nil
+}
+
+// For returns the embedding service configured for collection, or the default
+func (r *Registry) For(collection string) Service {
+	if service, ok := r.collections[collection]; ok {
+		return service
+	}
+	return r.defaultService
+}
+
+// CacheStats adds up the embedding cache counters of every model, or returns
+// false when none has a cache
+func (r *Registry) CacheStats() (types.CacheStats, bool) {
+	total, found := types.CacheStats{}, false
+	for _, service := range append([]Service{r.defaultService}, slices.Collect(maps.Values(r.collections))...) {
+		if stats, ok := CacheStats(service); ok {
+			total.Hits += stats.Hits
+			total.Misses += stats.Misses
+			total.Entries += stats.Entries
+			found = true
+		}
+	}
+	return total, found
+}
@@ -0,0 +1,49 @@
+package embedding
+
+import (
+	"testing"
+
+	"go-rag/internal/types"
+)
+
+func TestRegistry_ResolvesModelPerCollection(t *testing.T) {
+	registry, err := NewRegistry(
+		types.EmbeddingConfig{Provider: "mock", Model: "default-model", Dimensions: 8},
+		map[string]types.EmbeddingConfig{
+			"fast":    {Provider: "mock", Model: "fast-model", Dimensions: 4},
+			"quality": {Provider: "mock", Model: "quality-model", Dimensions: 16},
+		},
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	cases := map[string]string{
+		"fast":      "fast-model",
+		"quality":   "quality-model",
+		"documents": "default-model",
+	}
+	for collection, model := range cases {
+		if got := registry.For(collection).GetConfig().Model; got != model {
+			t.Errorf("Collection %s: expected %s, got %s", collection, model, got)
+		}
+	}
+	if dims := registry.For("quality").GetDimensions(); dims != 16 {
+		t.Errorf("Expected 16 dimensions for the quality model, got %d", dims)
+	}
+}
+
+func TestRegistry_ValidatesOverrides(t *testing.T) {
+	base := types.EmbeddingConfig{Provider: "mock", Model: "default-model", Dimensions: 8}
+
+	invalid := []types.EmbeddingConfig{
+		{Provider: "unknown", Model: "m", Dimensions: 8},
+		{Provider: "openai", Model: "m", Dimensions: 8}, // no API key
+		{Provider: "mock", Model: "m"},
+	}
+	for _, config := range invalid {
+		if _, err := NewRegistry(base, map[string]types.EmbeddingConfig{"broken": config}); err == nil {
+			t.Errorf("Expected %+v to be rejected", config)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package generate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"go-rag/internal/retry"
+	"go-rag/internal/types"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	anthropicBaseURL = "https://api.anthropic.com"
+	anthropicVersion = "2023-06-01"
+	// defaultAnthropicMaxTokens is sent when no max tokens are configured, since the API requires a value
+	defaultAnthropicMaxTokens = 1024
+)
+
+// AnthropicService implements GenerationService using the Anthropic Messages API
+type AnthropicService struct {
+	config     types.GenerationConfig
+	prompt     *template.Template
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewAnthropicService creates a new Anthropic generation service
+func NewAnthropicService(config types.GenerationConfig) (*AnthropicService, error) {
+	if config.APIKey == "" {
+		return nil, fmt.Errorf("API key is required for Anthropic generation service")
+	}
+
+	if config.Model == "" {
+		return nil, fmt.Errorf("model is required for Anthropic generation service")
+	}
+
+	prompt, err := parsePromptTemplate(config.PromptTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AnthropicService{
+		config:     config,
+		prompt:     prompt,
+		baseURL:    anthropicBaseURL,
+		httpClient: &http.Client{Timeout: 120 * time.Second},
+	}, nil
+}
+
+// GenerateResponse generates a response based on the query and relevant chunks
+func (s *AnthropicService) GenerateResponse(ctx context.Context, query string, chunks []types.RankedChunk) (*types.GeneratedResponse, error) {
+	return s.GenerateWithOptions(ctx, query, chunks, types.GenerationOptions{})
+}
+
+// GenerateWithOptions generates a response using per-request options. The
+// prompt is the same as for OpenAI; JSON answers are requested in the prompt
+// only, since the API has no JSON mode.
+func (s *AnthropicService) GenerateWithOptions(ctx context.Context, query string, chunks []types.RankedChunk, opts types.GenerationOptions) (*types.GeneratedResponse, error) {
+	return generateAnswer(ctx, s.config, s.prompt, query, chunks, opts, s.createMessage)
+}
+
+// anthropicRequest is the body of a Messages API request
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	MaxTokens   int                `json:"max_tokens"`
+	Temperature float64            `json:"temperature"`
+	Messages    []anthropicMessage `json:"messages"`
+	Tools       []anthropicTool    `json:"tools,omitempty"`
+}
+
+// anthropicMessage is a conversation turn; Content is a string or content blocks
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content any    `json:"content"`
+}
+
+// anthropicBlock is a content block of a request or response message
+type anthropicBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+}
+
+// anthropicTool is a tool the model may call
+type anthropicTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+// anthropicResponse is the part of a Messages API response the service uses
+type anthropicResponse struct {
+	Content    []anthropicBlock `json:"content"`
+	StopReason string           `json:"stop_reason"`
+}
+
+// anthropicError is an error response from the Anthropic API
+type anthropicError struct {
+	status  int
+	kind    string
+	message string
+}
+
+func (e *anthropicError) Error() string {
+	if e.kind == "" {
+		return fmt.Sprintf("anthropic returned %d: %s", e.status, e.message)
+	}
+	return fmt.Sprintf("anthropic returned %d (%s): %s", e.status, e.kind, e.message)
+}
+
+// StatusCode returns the HTTP status, so retries treat 429 and 5xx as transient
+func (e *anthropicError) StatusCode() int {
+	return e.status
+}
+
+// createMessage sends the prompt to the Messages API, returning either the
+// answer or the tool calls the model requested
+func (s *AnthropicService) createMessage(ctx context.Context, prompt string, opts types.GenerationOptions) (completion, error) {
+	if prompt == "" {
+		return completion{}, fmt.Errorf("prompt cannot be empty")
+	}
+
+	temperature := s.config.Temperature
+	if opts.Temperature != nil {
+		temperature = *opts.Temperature
+	}
+	maxTokens := s.config.MaxTokens
+	if opts.MaxTokens > 0 {
+		maxTokens = opts.MaxTokens
+	}
+	if maxTokens <= 0 {
+		maxTokens = defaultAnthropicMaxTokens
+	}
+	model := s.config.Model
+	if opts.Model != "" {
+		model = opts.Model
+	}
+
+	data, err := json.Marshal(anthropicRequest{
+		Model:       model,
+		MaxTokens:   maxTokens,
+		Temperature: anthropicTemperature(temperature),
+		Messages:    buildAnthropicMessages(prompt, opts),
+		Tools:       buildAnthropicTools(opts.Tools),
+	})
+	if err != nil {
+		return completion{}, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	policy := retry.Policy{
+		MaxRetries: s.config.MaxRetries,
+		Delay:      time.Duration(s.config.RetryDelayMs) * time.Millisecond,
+	}
+
+	var resp anthropicResponse
+	err = retry.Do(ctx, policy, func() error {
+		return s.post(ctx, data, &resp)
+	})
+	if err != nil {
+		return completion{}, fmt.Errorf("failed to create message: %w", err)
+	}
+
+	var text strings.Builder
+	var toolCalls []types.ToolCall
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			toolCalls = append(toolCalls, types.ToolCall{
+				ID:        block.ID,
+				Name:      block.Name,
+				Arguments: string(block.Input),
+			})
+		}
+	}
+
+	answer := text.String()
+	if strings.TrimSpace(answer) == "" && len(toolCalls) == 0 {
+		return completion{}, emptyResponseError(anthropicFinishReason(resp.StopReason))
+	}
+	return completion{answer: answer, toolCalls: toolCalls}, nil
+}
+
+// post sends a Messages API request and decodes the JSON response into out
+func (s *AnthropicService) post(ctx context.Context, body []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", s.config.APIKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return decodeAnthropicError(resp)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// decodeAnthropicError reads an error response, falling back to the raw body
+// when it isn't the API's JSON error shape
+func decodeAnthropicError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	var payload struct {
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil && payload.Error.Message != "" {
+		return &anthropicError{status: resp.StatusCode, kind: payload.Error.Type, message: payload.Error.Message}
+	}
+	return &anthropicError{status: resp.StatusCode, message: strings.TrimSpace(string(body))}
+}
+
+// anthropicTemperature clamps a temperature to the API's range of 0 to 1
+func anthropicTemperature(temperature float64) float64 {
+	return min(max(temperature, 0), 1)
+}
+
+// anthropicFinishReason maps a stop reason onto the finish reasons
+// emptyResponseError explains
+func anthropicFinishReason(stopReason string) openai.FinishReason {
+	switch stopReason {
+	case "max_tokens":
+		return openai.FinishReasonLength
+	case "refusal":
+		return openai.FinishReasonContentFilter
+	default:
+		return openai.FinishReason(stopReason)
+	}
+}
+
+// buildAnthropicMessages creates the conversation: the prompt, followed by any
+// tool calls from a previous turn and the caller's results for them
+func buildAnthropicMessages(prompt string, opts types.GenerationOptions) []anthropicMessage {
+	messages := []anthropicMessage{{Role: "user", Content: prompt}}
+
+	if len(opts.ToolCalls) == 0 {
+		return messages
+	}
+
+	calls := make([]anthropicBlock, len(opts.ToolCalls))
+	for i, call := range opts.ToolCalls {
+		input := json.RawMessage(call.Arguments)
+		if len(input) == 0 {
+			input = json.RawMessage(`{}`)
+		}
+		calls[i] = anthropicBlock{Type: "tool_use", ID: call.ID, Name: call.Name, Input: input}
+	}
+	messages = append(messages, anthropicMessage{Role: "assistant", Content: calls})
+
+	// Tool results go back to the model in a single user turn
+	results := make([]anthropicBlock, len(opts.ToolResults))
+	for i, result := range opts.ToolResults {
+		results[i] = anthropicBlock{Type: "tool_result", ToolUseID: result.ToolCallID, Content: result.Content}
+	}
+	if len(results) > 0 {
+		messages = append(messages, anthropicMessage{Role: "user", Content: results})
+	}
+
+	return messages
+}
+
+// buildAnthropicTools maps tool definitions onto Anthropic tools
+func buildAnthropicTools(definitions []types.ToolDefinition) []anthropicTool {
+	if len(definitions) == 0 {
+		return nil
+	}
+
+	tools := make([]anthropicTool, len(definitions))
+	for i, def := range definitions {
+		schema := json.RawMessage(`{"type":"object","properties":{}}`)
+		if len(def.Parameters) > 0 {
+			schema = def.Parameters
+		}
+		tools[i] = anthropicTool{Name: def.Name, Description: def.Description, InputSchema: schema}
+	}
+
+	return tools
+}
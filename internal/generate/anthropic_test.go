@@ -0,0 +1,201 @@
+package generate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-rag/internal/types"
+)
+
+// newTestAnthropicService creates an Anthropic service pointed at a fake
+// Messages endpoint served by handler
+func newTestAnthropicService(t *testing.T, config types.GenerationConfig, handler http.HandlerFunc) *AnthropicService {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" || r.Header.Get("x-api-key") != "test-api-key" || r.Header.Get("anthropic-version") == "" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	config.Provider = "anthropic"
+	config.APIKey = "test-api-key"
+	if config.Model == "" {
+		config.Model = "claude-sonnet-4-20250514"
+	}
+	service, err := NewAnthropicService(config)
+	if err != nil {
+		t.Fatalf("Failed to create Anthropic service: %v", err)
+	}
+	service.baseURL = server.URL
+	return service
+}
+
+// writeAnthropicMessage writes a successful response with the given content blocks
+func writeAnthropicMessage(w http.ResponseWriter, stopReason string, blocks ...anthropicBlock) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(anthropicResponse{Content: blocks, StopReason: stopReason})
+}
+
+// writeAnthropicError writes an Anthropic-style error response
+func writeAnthropicError(w http.ResponseWriter, status int, kind, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"type":  "error",
+		"error": map[string]any{"type": kind, "message": message},
+	})
+}
+
+func TestNewService_Anthropic(t *testing.T) {
+	service, err := NewService(types.GenerationConfig{Provider: "anthropic", Model: "claude-sonnet-4-20250514", APIKey: "key"})
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	if _, ok := service.(*AnthropicService); !ok {
+		t.Errorf("Expected an AnthropicService, got %T", service)
+	}
+
+	if _, err := NewService(types.GenerationConfig{Provider: "anthropic", Model: "claude-sonnet-4-20250514"}); err == nil {
+		t.Error("Expected an error for a missing API key")
+	}
+}
+
+func TestAnthropicService_GenerateResponse(t *testing.T) {
+	var req anthropicRequest
+	service := newTestAnthropicService(t, types.GenerationConfig{Temperature: 1.5, MaxTokens: 500}, func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&req)
+		writeAnthropicMessage(w, "end_turn", anthropicBlock{Type: "text", Text: "Claude's answer"})
+	})
+
+	response, err := service.GenerateResponse(context.Background(), "test query", rankedChunks(2))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if response.Response != "Claude's answer" || len(response.Sources) != 1 {
+		t.Errorf("Unexpected response %+v", response)
+	}
+
+	if req.MaxTokens != 500 || req.Temperature != 1 {
+		t.Errorf("Expected max_tokens 500 and temperature capped at 1, got %d and %v", req.MaxTokens, req.Temperature)
+	}
+	prompt, _ := req.Messages[0].Content.(string)
+	if len(req.Messages) != 1 || req.Messages[0].Role != "user" || !strings.Contains(prompt, "Context 2: chunk content") || !strings.Contains(prompt, "Question: test query") {
+		t.Errorf("Expected the shared prompt as a single user message, got %+v", req.Messages)
+	}
+
+	// A request temperature overrides the configured one
+	zero := 0.0
+	if _, err := service.GenerateWithOptions(context.Background(), "test query", rankedChunks(1), types.GenerationOptions{Temperature: &zero}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if req.Temperature != 0 {
+		t.Errorf("Expected the request temperature 0, got %v", req.Temperature)
+	}
+}
+
+func TestAnthropicService_Errors(t *testing.T) {
+	status, kind, message := http.StatusTooManyRequests, "rate_limit_error", "Number of request tokens has exceeded your rate limit"
+	service := newTestAnthropicService(t, types.GenerationConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		writeAnthropicError(w, status, kind, message)
+	})
+
+	_, err := service.GenerateResponse(context.Background(), "test query", rankedChunks(2))
+	if !errors.Is(err, ErrRateLimited) || !strings.Contains(err.Error(), "rate_limit_error") {
+		t.Errorf("Expected a wrapped ErrRateLimited, got %v", err)
+	}
+
+	status, kind, message = http.StatusUnauthorized, "authentication_error", "invalid x-api-key"
+	_, err = service.GenerateResponse(context.Background(), "test query", rankedChunks(2))
+	var apiErr *anthropicError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode() != http.StatusUnauthorized || errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected the wrapped API error, got %v", err)
+	}
+}
+
+func TestAnthropicService_RetriesOnceWithReducedContext(t *testing.T) {
+	var prompts []string
+	service := newTestAnthropicService(t, types.GenerationConfig{RetryOnContextLength: true}, func(w http.ResponseWriter, r *http.Request) {
+		var req anthropicRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		prompt, _ := req.Messages[0].Content.(string)
+		prompts = append(prompts, prompt)
+
+		if len(prompts) == 1 {
+			writeAnthropicError(w, http.StatusBadRequest, "invalid_request_error", "prompt is too long: 210000 tokens > 200000 maximum")
+			return
+		}
+		writeAnthropicMessage(w, "end_turn", anthropicBlock{Type: "text", Text: "reduced answer"})
+	})
+
+	response, err := service.GenerateResponse(context.Background(), "test query", rankedChunks(4))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(prompts) != 2 || strings.Contains(prompts[1], "Context 3:") || !response.ContextReduced || response.Response != "reduced answer" {
+		t.Errorf("Expected one retry with the top 2 chunks, got %d calls and %+v", len(prompts), response)
+	}
+}
+
+func TestAnthropicService_ToolCalls(t *testing.T) {
+	var req anthropicRequest
+	service := newTestAnthropicService(t, types.GenerationConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&req)
+		if len(req.Messages) == 1 {
+			writeAnthropicMessage(w, "tool_use",
+				anthropicBlock{Type: "text", Text: "Let me look that up."},
+				anthropicBlock{Type: "tool_use", ID: "toolu_1", Name: "lookup", Input: json.RawMessage(`{"q":"x"}`)})
+			return
+		}
+		writeAnthropicMessage(w, "end_turn", anthropicBlock{Type: "text", Text: "final answer"})
+	})
+	tools := []types.ToolDefinition{{Name: "lookup", Description: "Looks things up"}}
+
+	response, err := service.GenerateWithOptions(context.Background(), "test query", rankedChunks(1), types.GenerationOptions{Tools: tools})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(req.Tools) != 1 || string(req.Tools[0].InputSchema) != `{"type":"object","properties":{}}` {
+		t.Errorf("Expected the tool with an empty object schema, got %+v", req.Tools)
+	}
+	if len(response.ToolCalls) != 1 || response.ToolCalls[0].ID != "toolu_1" || response.ToolCalls[0].Arguments != `{"q":"x"}` {
+		t.Fatalf("Expected the requested tool call, got %+v", response.ToolCalls)
+	}
+
+	response, err = service.GenerateWithOptions(context.Background(), "test query", rankedChunks(1), types.GenerationOptions{
+		Tools:       tools,
+		ToolCalls:   response.ToolCalls,
+		ToolResults: []types.ToolResult{{ToolCallID: "toolu_1", Content: "found it"}},
+	})
+	if err != nil || response.Response != "final answer" {
+		t.Fatalf("Expected the final answer, got %+v, %v", response, err)
+	}
+	if len(req.Messages) != 3 || req.Messages[1].Role != "assistant" || req.Messages[2].Role != "user" {
+		t.Errorf("Expected the tool call and its result as the following turns, got %+v", req.Messages)
+	}
+}
+
+func TestAnthropicService_EmptyContent(t *testing.T) {
+	service := newTestAnthropicService(t, types.GenerationConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		writeAnthropicMessage(w, "max_tokens")
+	})
+
+	_, err := service.GenerateResponse(context.Background(), "test query", rankedChunks(1))
+	if !errors.Is(err, ErrEmptyResponse) || !strings.Contains(err.Error(), "max tokens") {
+		t.Errorf("Expected an empty response explained by max tokens, got %v", err)
+	}
+
+	service.config.EmptyAnswerFallback = "No answer."
+	response, err := service.GenerateResponse(context.Background(), "test query", rankedChunks(1))
+	if err != nil || !response.EmptyResponse || response.Response != "No answer." {
+		t.Errorf("Expected the fallback answer, got %+v, %v", response, err)
+	}
+}
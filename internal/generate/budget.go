@@ -0,0 +1,47 @@
+package generate
+
+import (
+	"fmt"
+	"strings"
+
+	"go-rag/internal/chunk"
+	"go-rag/internal/types"
+)
+
+// contextTokenizer counts the tokens of the context, close to OpenAI's
+// cl100k counts without needing the model's vocabulary
+var contextTokenizer chunk.Tokenizer = chunk.ApproximateTokenizer{}
+
+// fitContext keeps the top-ranked chunks whose context blocks fit in
+// maxTokens, in rank order. The first chunk that doesn't fit is cut to the
+// tokens left and marked Truncated, and the chunks after it are dropped. A
+// maxTokens <= 0 keeps every chunk.
+func fitContext(chunks []types.RankedChunk, maxTokens int) []types.RankedChunk {
+	if maxTokens <= 0 {
+		return chunks
+	}
+
+	fitted := make([]types.RankedChunk, 0, len(chunks))
+	remaining := maxTokens
+	for i, ranked := range chunks {
+		// Each block after the first is preceded by a blank line
+		overhead := len(contextTokenizer.Tokenize(fmt.Sprintf("Context %d: ", i+1)))
+		if i > 0 {
+			overhead += len(contextTokenizer.Tokenize("\n\n"))
+		}
+		tokens := contextTokenizer.Tokenize(ranked.Content)
+		if overhead+len(tokens) <= remaining {
+			fitted = append(fitted, ranked)
+			remaining -= overhead + len(tokens)
+			continue
+		}
+
+		if keep := remaining - overhead; keep > 0 {
+			ranked.Content = strings.Join(tokens[:keep], "")
+			ranked.Truncated = true
+			fitted = append(fitted, ranked)
+		}
+		break
+	}
+	return fitted
+}
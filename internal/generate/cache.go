@@ -0,0 +1,114 @@
+package generate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"go-rag/internal/cache"
+	"go-rag/internal/types"
+)
+
+// cachingService answers repeated questions over the same context from a
+// cache of generated answers, so FAQ-style requests get identical answers
+type cachingService struct {
+	GenerationService
+	answers       *cache.LRU[types.GeneratedResponse]
+	deterministic bool
+}
+
+// withAnswerCache wraps service with an answer cache when one is configured
+func withAnswerCache(service GenerationService, config types.GenerationConfig) GenerationService {
+	if config.AnswerCacheSize <= 0 {
+		return service
+	}
+	return &cachingService{
+		GenerationService: service,
+		answers:           cache.NewLRU[types.GeneratedResponse](config.AnswerCacheSize, time.Duration(config.AnswerCacheTTLSecs)*time.Second),
+		deterministic:     config.DeterministicCaching,
+	}
+}
+
+// GenerateResponse generates a response, or returns the cached answer for the same inputs
+func (s *cachingService) GenerateResponse(ctx context.Context, query string, chunks []types.RankedChunk) (*types.GeneratedResponse, error) {
+	return s.GenerateWithOptions(ctx, query, chunks, types.GenerationOptions{})
+}
+
+// GenerateWithOptions generates a response, or returns the cached answer for
+// the same query, context and options. Tool calling continues a conversation,
+// so those requests are never cached.
+func (s *cachingService) GenerateWithOptions(ctx context.Context, query string, chunks []types.RankedChunk, opts types.GenerationOptions) (*types.GeneratedResponse, error) {
+	return s.answer(ctx, query, chunks, opts, s.GenerationService.GenerateWithOptions)
+}
+
+// StreamResponse streams a response from the wrapped service, or delivers
+// the cached answer for the same inputs as a single delta
+func (s *cachingService) StreamResponse(ctx context.Context, query string, chunks []types.RankedChunk, opts types.GenerationOptions, onDelta func(delta string) error) (*types.GeneratedResponse, error) {
+	response, err := s.answer(ctx, query, chunks, opts, func(ctx context.Context, query string, chunks []types.RankedChunk, opts types.GenerationOptions) (*types.GeneratedResponse, error) {
+		return Stream(ctx, s.GenerationService, query, chunks, opts, onDelta)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if response.Cached {
+		return streamWhole(response, onDelta)
+	}
+	return response, nil
+}
+
+// answer returns the cached answer for the inputs, or calls generate and
+// caches what it returns
+func (s *cachingService) answer(ctx context.Context, query string, chunks []types.RankedChunk, opts types.GenerationOptions, generate func(context.Context, string, []types.RankedChunk, types.GenerationOptions) (*types.GeneratedResponse, error)) (*types.GeneratedResponse, error) {
+	if len(chunks) == 0 || len(opts.Tools) > 0 || len(opts.ToolCalls) > 0 {
+		return generate(ctx, query, chunks, opts)
+	}
+
+	// Sampling would make a retried request differ from the cached answer,
+	// unless the request asked for a temperature of its own
+	if s.deterministic && opts.Temperature == nil {
+		zero := 0.0
+		opts.Temperature = &zero
+	}
+
+	key := answerCacheKey(query, chunks, opts)
+	if !cache.Bypassed(ctx) {
+		if cached, ok := s.answers.Get(key); ok {
+			cached.Cached = true
+			return &cached, nil
+		}
+	}
+
+	response, err := generate(ctx, query, chunks, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// A fallback for a blank answer shouldn't stick for the whole TTL
+	if !response.EmptyResponse {
+		s.answers.Set(key, *response)
+	}
+	return response, nil
+}
+
+// answerCacheKey identifies a generation by its query, context chunks and options
+func answerCacheKey(query string, chunks []types.RankedChunk, opts types.GenerationOptions) string {
+	type contextChunk struct {
+		DocumentID string `json:"document_id"`
+		ChunkIndex int    `json:"chunk_index"`
+		Content    string `json:"content"`
+	}
+	key := struct {
+		Query   string                  `json:"query"`
+		Chunks  []contextChunk          `json:"chunks"`
+		Options types.GenerationOptions `json:"options"`
+	}{Query: query, Options: opts}
+	for _, chunk := range chunks {
+		key.Chunks = append(key.Chunks, contextChunk{chunk.DocumentID, chunk.ChunkIndex, chunk.Content})
+	}
+
+	data, _ := json.Marshal(key)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
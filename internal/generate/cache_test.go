@@ -0,0 +1,70 @@
+package generate
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"go-rag/internal/cache"
+	"go-rag/internal/types"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestAnswerCache_IdenticalInputsHitCache(t *testing.T) {
+	config := types.GenerationConfig{
+		Provider:             "openai",
+		Model:                "gpt-3.5-turbo",
+		APIKey:               "test-api-key",
+		Temperature:          0.7,
+		AnswerCacheSize:      10,
+		DeterministicCaching: true,
+	}
+
+	calls := 0
+	var temperatures []float32
+	service := withAnswerCache(newTestService(t, config, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		var req openai.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		temperatures = append(temperatures, req.Temperature)
+		writeChatCompletion(w, "answer")
+	}), config)
+
+	ctx := context.Background()
+	first, err := service.GenerateResponse(ctx, "What is RAG?", rankedChunks(2))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	second, err := service.GenerateResponse(ctx, "What is RAG?", rankedChunks(2))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if calls != 1 {
+		t.Errorf("Expected the repeat to be served from cache, got %d provider calls", calls)
+	}
+	if first.Cached || !second.Cached || second.Response != first.Response {
+		t.Errorf("Expected an identical cached answer, got %+v then %+v", first, second)
+	}
+	if temperatures[0] <= 0 || temperatures[0] > 1e-30 {
+		t.Errorf("Expected temperature 0 to be sent for cacheable requests, got %g", temperatures[0])
+	}
+
+	if _, err := service.GenerateResponse(ctx, "What is RAG today?", rankedChunks(2)); err != nil || calls != 2 {
+		t.Errorf("Expected a different query to miss the cache, got %d calls, %v", calls, err)
+	}
+
+	if _, err := service.GenerateResponse(cache.WithBypass(ctx), "What is RAG?", rankedChunks(2)); err != nil || calls != 3 {
+		t.Errorf("Expected no_cache to force a fresh answer, got %d calls, %v", calls, err)
+	}
+
+	opts := types.GenerationOptions{Tools: []types.ToolDefinition{{Name: "search"}}}
+	for range 2 {
+		service.GenerateWithOptions(ctx, "What is RAG?", rankedChunks(2), opts)
+	}
+	if calls != 5 {
+		t.Errorf("Expected tool calling requests to skip the cache, got %d calls", calls)
+	}
+}
@@ -0,0 +1,58 @@
+package generate
+
+import (
+	"math"
+
+	"go-rag/internal/types"
+)
+
+// defaultConfidenceTargetChunks is how many grounding chunks count as full
+// coverage when none is configured
+const defaultConfidenceTargetChunks = 3
+
+// Confidence estimates from 0 to 1 how well an answer is grounded in the
+// chunks it was generated from. It is the weighted average of:
+//
+//   - relevance: the mean score, clamped to 0..1, of the top TargetChunks chunks
+//   - coverage: the share of TargetChunks met by chunks with a positive score
+//   - log-probability: the answer's mean token probability, exp(LogProb)
+//
+// The log-probability term is left out when the provider reported none. An
+// answer without chunks, a fallback answer or one without a weighted signal
+// scores 0.
+func Confidence(config types.ConfidenceConfig, chunks []types.RankedChunk, response *types.GeneratedResponse) float64 {
+	if len(chunks) == 0 || response == nil || response.EmptyResponse {
+		return 0
+	}
+
+	target := config.TargetChunks
+	if target <= 0 {
+		target = defaultConfidenceTargetChunks
+	}
+
+	var relevance float64
+	top := chunks[:min(target, len(chunks))]
+	for _, chunk := range top {
+		relevance += min(max(chunk.Score, 0), 1)
+	}
+	relevance /= float64(len(top))
+
+	grounding := 0
+	for _, chunk := range chunks {
+		if chunk.Score > 0 {
+			grounding++
+		}
+	}
+	coverage := min(float64(grounding)/float64(target), 1)
+
+	sum := config.ScoreWeight*relevance + config.CoverageWeight*coverage
+	weights := config.ScoreWeight + config.CoverageWeight
+	if response.LogProb != nil {
+		sum += config.LogProbWeight * math.Exp(*response.LogProb)
+		weights += config.LogProbWeight
+	}
+	if weights <= 0 {
+		return 0
+	}
+	return sum / weights
+}
@@ -0,0 +1,48 @@
+package generate
+
+import (
+	"math"
+	"testing"
+
+	"go-rag/internal/types"
+)
+
+func scoredChunks(scores ...float64) []types.RankedChunk {
+	chunks := make([]types.RankedChunk, len(scores))
+	for i, score := range scores {
+		chunks[i] = types.RankedChunk{Score: score}
+	}
+	return chunks
+}
+
+func TestConfidence(t *testing.T) {
+	config := types.ConfidenceConfig{Enabled: true, ScoreWeight: 0.6, CoverageWeight: 0.2, LogProbWeight: 0.2, TargetChunks: 3}
+	answer := &types.GeneratedResponse{Response: "Go was announced in 2009."}
+
+	grounded := Confidence(config, scoredChunks(0.92, 0.88, 0.81), answer)
+	if grounded < 0.8 {
+		t.Errorf("Expected a well-grounded answer to score high, got %v", grounded)
+	}
+
+	offTopic := Confidence(config, scoredChunks(0.08, 0.03), answer)
+	if offTopic > 0.3 {
+		t.Errorf("Expected an off-topic answer to score low, got %v", offTopic)
+	}
+
+	// An unsure model pulls the score down once its log-probability is known
+	logProb := math.Log(0.2)
+	unsure := Confidence(config, scoredChunks(0.92, 0.88, 0.81), &types.GeneratedResponse{Response: "Maybe 2009.", LogProb: &logProb})
+	if unsure >= grounded {
+		t.Errorf("Expected a low log-probability to lower confidence below %v, got %v", grounded, unsure)
+	}
+
+	for name, got := range map[string]float64{
+		"no chunks":       Confidence(config, nil, answer),
+		"fallback answer": Confidence(config, scoredChunks(0.9), &types.GeneratedResponse{Response: "I don't know.", EmptyResponse: true}),
+		"no weights":      Confidence(types.ConfidenceConfig{Enabled: true}, scoredChunks(0.9), answer),
+	} {
+		if got != 0 {
+			t.Errorf("Expected %s to score 0, got %v", name, got)
+		}
+	}
+}
@@ -2,39 +2,95 @@ package generate
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"net/http"
 	"strings"
+	"text/template"
+	"time"
 
+	"go-rag/internal/azure"
+	"go-rag/internal/retry"
 	"go-rag/internal/types"
 
 	"github.com/sashabaranov/go-openai"
 )
 
+// NoContextResponse is the answer given when there are no chunks to ground a response in
+const NoContextResponse = "I don't have enough information to answer your question."
+
+// ErrRateLimited is returned when the provider keeps rejecting requests with HTTP 429
+var ErrRateLimited = errors.New("generation rate limited")
+
+// ErrEmptyResponse is returned when the provider answers with blank content,
+// e.g. because a content filter stopped it
+var ErrEmptyResponse = errors.New("generation returned an empty response")
+
 // Service handles response generation
 type Service struct {
 	client *openai.Client
 	config types.GenerationConfig
+	// prompt is the parsed PromptTemplate, nil for the default prompt
+	prompt *template.Template
 }
 
 // GenerationService interface defines the contract for generation operations
 type GenerationService interface {
 	GenerateResponse(ctx context.Context, query string, chunks []types.RankedChunk) (*types.GeneratedResponse, error)
+	GenerateWithOptions(ctx context.Context, query string, chunks []types.RankedChunk, opts types.GenerationOptions) (*types.GeneratedResponse, error)
 }
 
-// NewService creates a new generation service
+// NewService creates a new generation service. A configured prompt template
+// is parsed here, so a malformed one fails at startup.
 func NewService(config types.GenerationConfig) (GenerationService, error) {
+	prompt, err := parsePromptTemplate(config.PromptTemplate)
+	if err != nil {
+		return nil, err
+	}
+
 	switch config.Provider {
 	case "openai":
 		if config.APIKey == "" {
 			return nil, fmt.Errorf("API key is required for OpenAI generation service")
 		}
-		client := openai.NewClient(config.APIKey)
-		return &Service{
-			client: client,
+		clientConfig := openai.DefaultConfig(config.APIKey)
+		if config.BaseURL != "" {
+			clientConfig.BaseURL = strings.TrimRight(config.BaseURL, "/")
+		}
+		return withAnswerCache(&Service{
+			client: openai.NewClientWithConfig(clientConfig),
 			config: config,
-		}, nil
+			prompt: prompt,
+		}, config), nil
+	case "azure":
+		// Azure OpenAI speaks the OpenAI API, with the model's deployment in the URL
+		if config.APIKey == "" {
+			return nil, fmt.Errorf("API key is required for Azure OpenAI generation service")
+		}
+		clientConfig, err := azure.ClientConfig(config.APIKey, config.Azure)
+		if err != nil {
+			return nil, err
+		}
+		return withAnswerCache(&Service{
+			client: openai.NewClientWithConfig(clientConfig),
+			config: config,
+			prompt: prompt,
+		}, config), nil
+	case "anthropic":
+		service, err := NewAnthropicService(config)
+		if err != nil {
+			return nil, err
+		}
+		return withAnswerCache(service, config), nil
 	case "mock":
-		return NewMockService(config)
+		service, err := NewMockService(config)
+		if err != nil {
+			return nil, err
+		}
+		return withAnswerCache(service, config), nil
 	default:
 		return nil, fmt.Errorf("unsupported generation provider: %s", config.Provider)
 	}
@@ -42,36 +98,153 @@ func NewService(config types.GenerationConfig) (GenerationService, error) {
 
 // GenerateResponse generates a response based on the query and relevant chunks
 func (s *Service) GenerateResponse(ctx context.Context, query string, chunks []types.RankedChunk) (*types.GeneratedResponse, error) {
+	return s.GenerateWithOptions(ctx, query, chunks, types.GenerationOptions{})
+}
+
+// GenerateWithOptions generates a response using per-request options
+func (s *Service) GenerateWithOptions(ctx context.Context, query string, chunks []types.RankedChunk, opts types.GenerationOptions) (*types.GeneratedResponse, error) {
+	return generateAnswer(ctx, s.config, s.prompt, query, chunks, opts, s.generateWithLLM)
+}
+
+// completion is a provider's reply: either the answer or the tool calls the
+// model requested
+type completion struct {
+	answer    string
+	toolCalls []types.ToolCall
+	// logProb is the answer's mean token log-probability, when the provider reported it
+	logProb *float64
+}
+
+// completeFunc sends a prompt to a provider and returns its reply
+type completeFunc func(ctx context.Context, prompt string, opts types.GenerationOptions) (completion, error)
+
+// generateAnswer builds the prompt from the chunks and asks complete for an
+// answer, handling the fallbacks, retries and checks every provider shares
+func generateAnswer(ctx context.Context, config types.GenerationConfig, tmpl *template.Template, query string, chunks []types.RankedChunk, opts types.GenerationOptions, complete completeFunc) (*types.GeneratedResponse, error) {
 	if len(chunks) == 0 {
 		return &types.GeneratedResponse{
-			Response: "I don't have enough information to answer your question.",
+			Response: NoContextResponse,
 			Sources:  []string{},
 		}, nil
 	}
 
+	// Keep the best ranked chunks that fit the context token budget
+	retrieved := len(chunks)
+	chunks = fitContext(chunks, config.MaxContextTokens)
+	if len(chunks) == 0 {
+		return &types.GeneratedResponse{
+			Response:        NoContextResponse,
+			Sources:         []string{},
+			ChunksRetrieved: retrieved,
+		}, nil
+	}
+
 	// Build responseContext from chunks
-	responseContext := s.buildContext(chunks)
+	responseContext := buildContext(chunks)
 
 	// Create prompt
-	prompt := s.buildPrompt(query, responseContext)
+	prompt, err := renderPrompt(tmpl, query, responseContext)
+	if err != nil {
+		return nil, err
+	}
+	prompt += jsonInstructions(opts)
 
 	// Generate response
-	response, err := s.generateWithLLM(ctx, prompt)
+	reply, err := complete(ctx, prompt, opts)
+	if errors.Is(err, ErrEmptyResponse) && config.EmptyAnswerFallback != "" {
+		return &types.GeneratedResponse{
+			Response:      config.EmptyAnswerFallback,
+			Sources:       []string{},
+			EmptyResponse: true,
+		}, nil
+	}
+	contextReduced := false
+	if err != nil && config.RetryOnContextLength && isContextLengthError(err) && len(chunks) > 1 && retry.BudgetFromContext(ctx).Take() {
+		// Chunks arrive ranked, so keep the better half and try once more
+		chunks = chunks[:len(chunks)/2]
+		prompt, err = renderPrompt(tmpl, query, buildContext(chunks))
+		if err != nil {
+			return nil, err
+		}
+		reply, err = complete(ctx, prompt+jsonInstructions(opts), opts)
+		contextReduced = true
+	}
 	if err != nil {
+		if isRateLimitError(err) {
+			return nil, fmt.Errorf("failed to generate response: %w: %w", ErrRateLimited, err)
+		}
 		return nil, fmt.Errorf("failed to generate response: %w", err)
 	}
 
+	// The model asked for tools to be run first; hand the calls back to the caller
+	if len(reply.toolCalls) > 0 {
+		return &types.GeneratedResponse{
+			Response:        reply.answer,
+			Sources:         extractSources(chunks),
+			Citations:       extractCitations(chunks),
+			ContextReduced:  contextReduced,
+			ChunksUsed:      len(chunks),
+			ChunksRetrieved: retrieved,
+			ToolCalls:       reply.toolCalls,
+		}, nil
+	}
+
+	if opts.ResponseFormat == types.ResponseFormatJSON {
+		if err := validateJSONAnswer(reply.answer, opts.Schema); err != nil {
+			return nil, fmt.Errorf("model returned an invalid JSON answer: %w", err)
+		}
+	}
+
 	// Extract sources
-	sources := s.extractSources(chunks)
+	sources := extractSources(chunks)
 
 	return &types.GeneratedResponse{
-		Response: response,
-		Sources:  sources,
+		Response:        reply.answer,
+		Sources:         sources,
+		Citations:       extractCitations(chunks),
+		ContextReduced:  contextReduced,
+		ChunksUsed:      len(chunks),
+		ChunksRetrieved: retrieved,
+		LogProb:         reply.logProb,
 	}, nil
 }
 
+// isContextLengthError reports whether the provider rejected the request
+// because the prompt did not fit in the model's context window
+func isContextLengthError(err error) bool {
+	var anthropicErr *anthropicError
+	if errors.As(err, &anthropicErr) {
+		return anthropicErr.status == http.StatusBadRequest && strings.Contains(anthropicErr.message, "prompt is too long")
+	}
+	var apiErr *openai.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if code, ok := apiErr.Code.(string); ok && code == "context_length_exceeded" {
+		return true
+	}
+	return strings.Contains(strings.ToLower(apiErr.Message), "maximum context length")
+}
+
+// isRateLimitError reports whether the provider throttled the request
+func isRateLimitError(err error) bool {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.HTTPStatusCode == http.StatusTooManyRequests
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return reqErr.HTTPStatusCode == http.StatusTooManyRequests
+	}
+	var anthropicErr *anthropicError
+	if errors.As(err, &anthropicErr) {
+		return anthropicErr.status == http.StatusTooManyRequests
+	}
+	return false
+}
+
 // buildContext combines relevant chunks into a context string
-func (s *Service) buildContext(chunks []types.RankedChunk) string {
+func buildContext(chunks []types.RankedChunk) string {
 	var contextParts []string
 
 	for i, chunk := range chunks {
@@ -82,7 +255,7 @@ func (s *Service) buildContext(chunks []types.RankedChunk) string {
 }
 
 // buildPrompt creates a prompt for the LLM
-func (s *Service) buildPrompt(query, context string) string {
+func buildPrompt(query, context string) string {
 	return fmt.Sprintf(`Based on the following context, please answer the question. If the context doesn't contain enough information to answer the question, please say so.
 
 Context:
@@ -93,65 +266,393 @@ Question: %s
 Answer:`, context, query)
 }
 
-// generateWithLLM generates a response using an LLM
-func (s *Service) generateWithLLM(ctx context.Context, prompt string) (string, error) {
+// applyOverrides sets the model, temperature and answer length a request
+// asked for in place of the configured ones
+func applyOverrides(req *openai.ChatCompletionRequest, opts types.GenerationOptions) {
+	if opts.Model != "" {
+		req.Model = opts.Model
+	}
+	if opts.Temperature != nil {
+		req.Temperature = openAITemperature(*opts.Temperature)
+	}
+	if opts.MaxTokens > 0 {
+		req.MaxTokens = opts.MaxTokens
+	}
+}
+
+// generateWithLLM generates a response using an LLM, returning either the
+// answer or the tool calls the model requested
+func (s *Service) generateWithLLM(ctx context.Context, prompt string, opts types.GenerationOptions) (completion, error) {
 	if prompt == "" {
-		return "", fmt.Errorf("prompt cannot be empty")
+		return completion{}, fmt.Errorf("prompt cannot be empty")
 	}
 
 	req := openai.ChatCompletionRequest{
-		Model: s.config.Model,
-		Messages: []openai.ChatCompletionMessage{
-			{
-				Role:    openai.ChatMessageRoleUser,
-				Content: prompt,
-			},
-		},
-		Temperature: float32(s.config.Temperature),
+		Model:       s.config.Model,
+		Messages:    buildMessages(prompt, opts),
+		Temperature: openAITemperature(s.config.Temperature),
 		MaxTokens:   s.config.MaxTokens,
+		Tools:       buildTools(opts.Tools),
+		LogProbs:    s.config.LogProbs,
+	}
+	applyOverrides(&req, opts)
+
+	if opts.ResponseFormat == types.ResponseFormatJSON {
+		req.ResponseFormat = &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+		}
+		if len(opts.Schema) > 0 {
+			req.ResponseFormat = &openai.ChatCompletionResponseFormat{
+				Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+				JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+					Name:   "rag_answer",
+					Schema: opts.Schema,
+				},
+			}
+		}
 	}
 
-	resp, err := s.client.CreateChatCompletion(ctx, req)
+	policy := retry.Policy{
+		MaxRetries: s.config.MaxRetries,
+		Delay:      time.Duration(s.config.RetryDelayMs) * time.Millisecond,
+	}
+
+	var resp openai.ChatCompletionResponse
+	err := retry.Do(ctx, policy, func() error {
+		var err error
+		resp, err = s.client.CreateChatCompletion(ctx, req)
+		return err
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to create chat completion: %w", err)
+		return completion{}, fmt.Errorf("failed to create chat completion: %w", err)
 	}
 
 	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("no response choices returned")
+		return completion{}, fmt.Errorf("no response choices returned")
+	}
+
+	choice := resp.Choices[0]
+	message := choice.Message
+	if strings.TrimSpace(message.Content) == "" && len(message.ToolCalls) == 0 {
+		return completion{}, emptyResponseError(choice.FinishReason)
+	}
+
+	var toolCalls []types.ToolCall
+	for _, call := range message.ToolCalls {
+		toolCalls = append(toolCalls, types.ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: call.Function.Arguments,
+		})
+	}
+
+	return completion{answer: message.Content, toolCalls: toolCalls, logProb: meanLogProb(choice.LogProbs)}, nil
+}
+
+// meanLogProb averages the log-probabilities of the answer's tokens, or
+// returns nil when none were reported
+func meanLogProb(logProbs *openai.LogProbs) *float64 {
+	if logProbs == nil || len(logProbs.Content) == 0 {
+		return nil
+	}
+	var sum float64
+	for _, token := range logProbs.Content {
+		sum += token.LogProb
+	}
+	mean := sum / float64(len(logProbs.Content))
+	return &mean
+}
+
+// openAITemperature converts a temperature for the request. The client omits
+// a zero temperature, which the API would treat as its default of 1, so zero
+// is sent as the smallest positive value instead.
+func openAITemperature(temperature float64) float32 {
+	if temperature == 0 {
+		return math.SmallestNonzeroFloat32
+	}
+	return float32(temperature)
+}
+
+// emptyResponseError explains a blank answer using the provider's finish reason
+func emptyResponseError(reason openai.FinishReason) error {
+	switch reason {
+	case openai.FinishReasonContentFilter:
+		return fmt.Errorf("%w: blocked by the provider's content filter", ErrEmptyResponse)
+	case openai.FinishReasonLength:
+		return fmt.Errorf("%w: max tokens reached before any answer was written", ErrEmptyResponse)
+	case "":
+		return ErrEmptyResponse
+	default:
+		return fmt.Errorf("%w (finish reason: %s)", ErrEmptyResponse, reason)
+	}
+}
+
+// buildMessages creates the chat history: the prompt, followed by any tool
+// calls from a previous turn and the caller's results for them
+func buildMessages(prompt string, opts types.GenerationOptions) []openai.ChatCompletionMessage {
+	messages := []openai.ChatCompletionMessage{
+		{
+			Role:    openai.ChatMessageRoleUser,
+			Content: prompt,
+		},
+	}
+
+	if len(opts.ToolCalls) == 0 {
+		return messages
+	}
+
+	calls := make([]openai.ToolCall, len(opts.ToolCalls))
+	for i, call := range opts.ToolCalls {
+		calls[i] = openai.ToolCall{
+			ID:   call.ID,
+			Type: openai.ToolTypeFunction,
+			Function: openai.FunctionCall{
+				Name:      call.Name,
+				Arguments: call.Arguments,
+			},
+		}
+	}
+	messages = append(messages, openai.ChatCompletionMessage{
+		Role:      openai.ChatMessageRoleAssistant,
+		ToolCalls: calls,
+	})
+
+	for _, result := range opts.ToolResults {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:       openai.ChatMessageRoleTool,
+			Content:    result.Content,
+			ToolCallID: result.ToolCallID,
+		})
+	}
+
+	return messages
+}
+
+// buildTools maps tool definitions onto OpenAI function tools
+func buildTools(definitions []types.ToolDefinition) []openai.Tool {
+	if len(definitions) == 0 {
+		return nil
+	}
+
+	tools := make([]openai.Tool, len(definitions))
+	for i, def := range definitions {
+		var parameters any = json.RawMessage(`{"type":"object","properties":{}}`)
+		if len(def.Parameters) > 0 {
+			parameters = def.Parameters
+		}
+		tools[i] = openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        def.Name,
+				Description: def.Description,
+				Parameters:  parameters,
+			},
+		}
 	}
 
-	return resp.Choices[0].Message.Content, nil
+	return tools
+}
+
+// jsonInstructions returns the prompt suffix asking for a JSON answer, or an
+// empty string in text mode. OpenAI's JSON mode requires the prompt to
+// mention JSON explicitly.
+func jsonInstructions(opts types.GenerationOptions) string {
+	if opts.ResponseFormat != types.ResponseFormatJSON {
+		return ""
+	}
+	if len(opts.Schema) == 0 {
+		return "\n\nRespond only with a valid JSON object."
+	}
+	return fmt.Sprintf("\n\nRespond only with a valid JSON object that follows this JSON schema:\n%s", opts.Schema)
+}
+
+// validateJSONAnswer checks that an answer is valid JSON and, when a schema is
+// given, that it has the schema's top-level type and required properties.
+// It is a shallow check, not a full JSON schema validator.
+func validateJSONAnswer(answer string, schema json.RawMessage) error {
+	var value any
+	if err := json.Unmarshal([]byte(answer), &value); err != nil {
+		return err
+	}
+
+	if len(schema) == 0 {
+		return nil
+	}
+
+	var spec struct {
+		Type     string   `json:"type"`
+		Required []string `json:"required"`
+	}
+	if err := json.Unmarshal(schema, &spec); err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+
+	if spec.Type != "object" {
+		return nil
+	}
+
+	object, ok := value.(map[string]any)
+	if !ok {
+		return fmt.Errorf("expected a JSON object")
+	}
+	for _, key := range spec.Required {
+		if _, exists := object[key]; !exists {
+			return fmt.Errorf("missing required property %q", key)
+		}
+	}
+
+	return nil
 }
 
 // extractSources extracts source information from chunks
-func (s *Service) extractSources(chunks []types.RankedChunk) []string {
+func extractSources(chunks []types.RankedChunk) []string {
 	var sources []string
 	seenDocs := make(map[string]bool)
 
 	for _, chunk := range chunks {
-		if !seenDocs[chunk.DocumentID] {
-			sources = append(sources, chunk.DocumentID)
-			seenDocs[chunk.DocumentID] = true
+		// Documents whose identical chunk was collapsed into this one are sources too
+		for _, documentID := range append([]string{chunk.DocumentID}, chunk.DuplicateDocumentIDs...) {
+			if !seenDocs[documentID] {
+				sources = append(sources, documentID)
+				seenDocs[documentID] = true
+			}
 		}
 	}
 
 	return sources
 }
 
-// StreamResponse generates a streaming response (for future implementation)
-func (s *Service) StreamResponse(ctx context.Context, query string, chunks []types.RankedChunk) (<-chan string, error) {
-	// TODO: Implement streaming response
-	responseChan := make(chan string, 1)
+// extractCitations maps the number of each context block buildContext writes
+// to the chunk it holds
+func extractCitations(chunks []types.RankedChunk) []types.Citation {
+	citations := make([]types.Citation, len(chunks))
+	for i, chunk := range chunks {
+		citations[i] = types.Citation{
+			Number:     i + 1,
+			DocumentID: chunk.DocumentID,
+			ChunkID:    chunk.ID,
+			ChunkIndex: chunk.ChunkIndex,
+		}
+	}
+	return citations
+}
+
+// StreamResponse generates a text answer, passing each piece to onDelta as
+// the model writes it. Only opening the stream is retried, since a retry
+// after the first delta would repeat text the caller already has.
+func (s *Service) StreamResponse(ctx context.Context, query string, chunks []types.RankedChunk, opts types.GenerationOptions, onDelta func(delta string) error) (*types.GeneratedResponse, error) {
+	if len(chunks) == 0 {
+		return streamWhole(&types.GeneratedResponse{Response: NoContextResponse, Sources: []string{}}, onDelta)
+	}
+	retrieved := len(chunks)
+	chunks = fitContext(chunks, s.config.MaxContextTokens)
+	if len(chunks) == 0 {
+		return streamWhole(&types.GeneratedResponse{Response: NoContextResponse, Sources: []string{}, ChunksRetrieved: retrieved}, onDelta)
+	}
 
-	go func() {
-		defer close(responseChan)
-		response, err := s.GenerateResponse(ctx, query, chunks)
+	prompt, err := renderPrompt(s.prompt, query, buildContext(chunks))
+	if err != nil {
+		return nil, err
+	}
+	req := openai.ChatCompletionRequest{
+		Model:       s.config.Model,
+		Messages:    buildMessages(prompt, opts),
+		Temperature: openAITemperature(s.config.Temperature),
+		MaxTokens:   s.config.MaxTokens,
+	}
+	applyOverrides(&req, opts)
+
+	policy := retry.Policy{
+		MaxRetries: s.config.MaxRetries,
+		Delay:      time.Duration(s.config.RetryDelayMs) * time.Millisecond,
+	}
+
+	var stream *openai.ChatCompletionStream
+	err = retry.Do(ctx, policy, func() error {
+		var err error
+		stream, err = s.client.CreateChatCompletionStream(ctx, req)
+		return err
+	})
+	if err != nil {
+		if isRateLimitError(err) {
+			return nil, fmt.Errorf("failed to stream response: %w: %w", ErrRateLimited, err)
+		}
+		return nil, fmt.Errorf("failed to stream response: %w", err)
+	}
+	defer stream.Close()
+
+	var answer strings.Builder
+	var finishReason openai.FinishReason
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
 		if err != nil {
-			responseChan <- fmt.Sprintf("Error generating response: %v", err)
-		} else {
-			responseChan <- response.Response
+			return nil, fmt.Errorf("failed to stream response: %w", err)
+		}
+		if len(chunk.Choices) == 0 {
+			continue
 		}
-	}()
 
-	return responseChan, nil
+		choice := chunk.Choices[0]
+		if choice.FinishReason != "" {
+			finishReason = choice.FinishReason
+		}
+		if choice.Delta.Content == "" {
+			continue
+		}
+		answer.WriteString(choice.Delta.Content)
+		if err := onDelta(choice.Delta.Content); err != nil {
+			return nil, err
+		}
+	}
+
+	if strings.TrimSpace(answer.String()) == "" {
+		if s.config.EmptyAnswerFallback == "" {
+			return nil, emptyResponseError(finishReason)
+		}
+		return streamWhole(&types.GeneratedResponse{
+			Response:      s.config.EmptyAnswerFallback,
+			Sources:       []string{},
+			EmptyResponse: true,
+		}, onDelta)
+	}
+
+	return &types.GeneratedResponse{
+		Response:        answer.String(),
+		Sources:         extractSources(chunks),
+		Citations:       extractCitations(chunks),
+		ChunksUsed:      len(chunks),
+		ChunksRetrieved: retrieved,
+	}, nil
+}
+
+// Streamer is implemented by generation services that can stream a text
+// answer as it is written
+type Streamer interface {
+	StreamResponse(ctx context.Context, query string, chunks []types.RankedChunk, opts types.GenerationOptions, onDelta func(delta string) error) (*types.GeneratedResponse, error)
+}
+
+// Stream generates a text answer with service, passing each piece to onDelta
+// as it is written. Services that can't stream deliver the whole answer as a
+// single delta.
+func Stream(ctx context.Context, service GenerationService, query string, chunks []types.RankedChunk, opts types.GenerationOptions, onDelta func(delta string) error) (*types.GeneratedResponse, error) {
+	if streamer, ok := service.(Streamer); ok {
+		return streamer.StreamResponse(ctx, query, chunks, opts, onDelta)
+	}
+
+	response, err := service.GenerateWithOptions(ctx, query, chunks, opts)
+	if err != nil {
+		return nil, err
+	}
+	return streamWhole(response, onDelta)
+}
+
+// streamWhole delivers a complete answer as one delta
+func streamWhole(response *types.GeneratedResponse, onDelta func(delta string) error) (*types.GeneratedResponse, error) {
+	if err := onDelta(response.Response); err != nil {
+		return nil, err
+	}
+	return response, nil
 }
@@ -2,9 +2,19 @@ package generate
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 
+	"go-rag/internal/retry"
 	"go-rag/internal/types"
+
+	"github.com/sashabaranov/go-openai"
 )
 
 func TestNewService_Success(t *testing.T) {
@@ -16,15 +26,16 @@ func TestNewService_Success(t *testing.T) {
 		APIKey:      "test-api-key",
 	}
 
-	service, err := NewService(config)
+	generationService, err := NewService(config)
 	if err != nil {
 		t.Fatalf("Failed to create service: %v", err)
 	}
 
-	if service == nil {
+	if generationService == nil {
 		t.Fatal("Service is nil")
 	}
 
+	service := generationService.(*Service)
 	if service.config.Provider != "openai" {
 		t.Errorf("Expected provider 'openai', got '%s'", service.config.Provider)
 	}
@@ -34,6 +45,34 @@ func TestNewService_Success(t *testing.T) {
 	}
 }
 
+func TestNewService_UsesBaseURL(t *testing.T) {
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		writeChatCompletion(w, "An answer")
+	}))
+	t.Cleanup(server.Close)
+
+	service, err := NewService(types.GenerationConfig{
+		Provider:  "openai",
+		Model:     "gpt-3.5-turbo",
+		MaxTokens: 100,
+		APIKey:    "test-api-key",
+		BaseURL:   server.URL + "/v1",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	chunks := []types.RankedChunk{{DocumentChunk: types.DocumentChunk{DocumentID: "doc-1", Content: "Some context"}, Score: 0.9}}
+	if _, err := service.GenerateResponse(context.Background(), "question", chunks); err != nil {
+		t.Fatalf("GenerateResponse failed: %v", err)
+	}
+	if path != "/v1/chat/completions" {
+		t.Errorf("Expected the compatible server's chat completions path, got %s", path)
+	}
+}
+
 func TestNewService_MissingAPIKey(t *testing.T) {
 	config := types.GenerationConfig{
 		Provider:    "openai",
@@ -48,7 +87,7 @@ func TestNewService_MissingAPIKey(t *testing.T) {
 		t.Error("Expected error for missing API key, got nil")
 	}
 
-	expectedMsg := "API key is required for generation service"
+	expectedMsg := "API key is required for OpenAI generation service"
 	if err.Error() != expectedMsg {
 		t.Errorf("Expected error message '%s', got '%s'", expectedMsg, err.Error())
 	}
@@ -105,19 +144,6 @@ func TestGenerateResponse_EmptyChunks(t *testing.T) {
 }
 
 func TestBuildContext(t *testing.T) {
-	config := types.GenerationConfig{
-		Provider:    "openai",
-		Model:       "gpt-3.5-turbo",
-		Temperature: 0.7,
-		MaxTokens:   1000,
-		APIKey:      "test-api-key",
-	}
-
-	service, err := NewService(config)
-	if err != nil {
-		t.Fatalf("Failed to create service: %v", err)
-	}
-
 	chunks := []types.RankedChunk{
 		{
 			DocumentChunk: types.DocumentChunk{
@@ -131,30 +157,81 @@ func TestBuildContext(t *testing.T) {
 		},
 	}
 
-	context := service.buildContext(chunks)
+	context := buildContext(chunks)
 	expected := "Context 1: First chunk content\n\nContext 2: Second chunk content"
 	if context != expected {
 		t.Errorf("Expected context '%s', got '%s'", expected, context)
 	}
 }
 
-func TestBuildPrompt(t *testing.T) {
+func TestPromptTemplate_ReplacesDefaultPrompt(t *testing.T) {
 	config := types.GenerationConfig{
-		Provider:    "openai",
-		Model:       "gpt-3.5-turbo",
-		Temperature: 0.7,
-		MaxTokens:   1000,
-		APIKey:      "test-api-key",
+		Provider:       "openai",
+		Model:          "gpt-3.5-turbo",
+		APIKey:         "test-api-key",
+		PromptTemplate: "Réponds en français.\n{{.Context}}\nQ: {{.Query}}",
 	}
 
-	service, err := NewService(config)
-	if err != nil {
-		t.Fatalf("Failed to create service: %v", err)
+	var prompt string
+	service := newTestService(t, config, func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		prompt = req.Messages[0].Content
+		writeChatCompletion(w, "Réponse")
+	})
+
+	if _, err := service.GenerateResponse(context.Background(), "Qu'est-ce que l'IA ?", rankedChunks(1)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := "Réponds en français.\n" + buildContext(rankedChunks(1)) + "\nQ: Qu'est-ce que l'IA ?"
+	if prompt != want {
+		t.Errorf("Expected prompt %q, got %q", want, prompt)
+	}
+}
+
+func TestNewService_RejectsInvalidPromptTemplate(t *testing.T) {
+	for _, tmpl := range []string{
+		"{{.Context} {{.Query}}",     // malformed
+		"{{.Context}} {{.Question}}", // unknown field
+		"Answer: {{.Query}}",         // no context
+	} {
+		config := types.GenerationConfig{Provider: "mock", PromptTemplate: tmpl}
+		if _, err := NewService(config); err == nil || !contains(err.Error(), "prompt template") {
+			t.Errorf("Expected template %q to be rejected, got %v", tmpl, err)
+		}
+	}
+}
+
+func TestGenerateWithOptions_OverridesConfiguredSettings(t *testing.T) {
+	config := types.GenerationConfig{Provider: "openai", Model: "gpt-3.5-turbo", Temperature: 0.7, MaxTokens: 1000, APIKey: "test-api-key"}
+
+	var req openai.ChatCompletionRequest
+	service := newTestService(t, config, func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&req)
+		writeChatCompletion(w, "An answer")
+	})
+
+	if _, err := service.GenerateResponse(context.Background(), "question", rankedChunks(1)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if req.Model != "gpt-3.5-turbo" || req.MaxTokens != 1000 {
+		t.Errorf("Expected the configured model and max tokens, got %s and %d", req.Model, req.MaxTokens)
+	}
+
+	temperature := 0.0
+	opts := types.GenerationOptions{Model: "gpt-4o-mini", Temperature: &temperature, MaxTokens: 50}
+	if _, err := service.GenerateWithOptions(context.Background(), "question", rankedChunks(1), opts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if req.Model != "gpt-4o-mini" || req.MaxTokens != 50 || req.Temperature == 0.7 {
+		t.Errorf("Expected the requested model, max tokens and temperature, got %s, %d and %v", req.Model, req.MaxTokens, req.Temperature)
 	}
+}
 
+func TestBuildPrompt(t *testing.T) {
 	query := "What is AI?"
 	context := "AI is artificial intelligence"
-	prompt := service.buildPrompt(query, context)
+	prompt := buildPrompt(query, context)
 
 	if !contains(prompt, query) {
 		t.Errorf("Prompt should contain query '%s'", query)
@@ -170,19 +247,6 @@ func TestBuildPrompt(t *testing.T) {
 }
 
 func TestExtractSources(t *testing.T) {
-	config := types.GenerationConfig{
-		Provider:    "openai",
-		Model:       "gpt-3.5-turbo",
-		Temperature: 0.7,
-		MaxTokens:   1000,
-		APIKey:      "test-api-key",
-	}
-
-	service, err := NewService(config)
-	if err != nil {
-		t.Fatalf("Failed to create service: %v", err)
-	}
-
 	chunks := []types.RankedChunk{
 		{
 			DocumentChunk: types.DocumentChunk{
@@ -201,7 +265,7 @@ func TestExtractSources(t *testing.T) {
 		},
 	}
 
-	sources := service.extractSources(chunks)
+	sources := extractSources(chunks)
 	if len(sources) != 2 {
 		t.Errorf("Expected 2 unique sources, got %d", len(sources))
 	}
@@ -209,6 +273,12 @@ func TestExtractSources(t *testing.T) {
 	if sources[0] != "doc-1" || sources[1] != "doc-2" {
 		t.Errorf("Expected sources ['doc-1', 'doc-2'], got %v", sources)
 	}
+
+	// Documents of collapsed duplicate chunks are listed after the chunk's own
+	chunks[1].DuplicateDocumentIDs = []string{"doc-3", "doc-1"}
+	if sources := extractSources(chunks); strings.Join(sources, ",") != "doc-1,doc-2,doc-3" {
+		t.Errorf("Expected sources including duplicates, got %v", sources)
+	}
 }
 
 // Helper function to check if a string contains a substring
@@ -227,3 +297,535 @@ func containsSubstring(s, substr string) bool {
 	}
 	return false
 }
+
+// newTestService creates an OpenAI-backed service pointed at a fake chat
+// completions endpoint served by handler
+func newTestService(t *testing.T, config types.GenerationConfig, handler http.HandlerFunc) *Service {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	clientConfig := openai.DefaultConfig("test-api-key")
+	clientConfig.BaseURL = server.URL + "/v1"
+
+	prompt, err := parsePromptTemplate(config.PromptTemplate)
+	if err != nil {
+		t.Fatalf("Failed to parse prompt template: %v", err)
+	}
+	return &Service{
+		client: openai.NewClientWithConfig(clientConfig),
+		config: config,
+		prompt: prompt,
+	}
+}
+
+// writeChatCompletion writes a successful chat completion with the given content
+func writeChatCompletion(w http.ResponseWriter, content string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{
+			{Message: openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: content}},
+		},
+	})
+}
+
+// writeAPIError writes an OpenAI-style error response
+func writeAPIError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]any{"message": message, "type": "invalid_request_error", "code": code},
+	})
+}
+
+func rankedChunks(n int) []types.RankedChunk {
+	chunks := make([]types.RankedChunk, n)
+	for i := range chunks {
+		chunks[i] = types.RankedChunk{
+			DocumentChunk: types.DocumentChunk{DocumentID: "doc-1", ChunkIndex: i, Content: "chunk content"},
+			Score:         float64(n - i),
+		}
+	}
+	return chunks
+}
+
+func TestGenerateResponse_CitesChunksByNumber(t *testing.T) {
+	config := types.GenerationConfig{Provider: "openai", Model: "gpt-3.5-turbo", APIKey: "test-api-key"}
+	service := newTestService(t, config, func(w http.ResponseWriter, r *http.Request) {
+		writeChatCompletion(w, "An answer [2]")
+	})
+
+	chunks := []types.RankedChunk{
+		{DocumentChunk: types.DocumentChunk{ID: 11, DocumentID: "doc-a", ChunkIndex: 3, Content: "first"}},
+		{DocumentChunk: types.DocumentChunk{ID: 42, DocumentID: "doc-b", ChunkIndex: 0, Content: "second"}},
+	}
+	response, err := service.GenerateResponse(context.Background(), "question", chunks)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []types.Citation{
+		{Number: 1, DocumentID: "doc-a", ChunkID: 11, ChunkIndex: 3},
+		{Number: 2, DocumentID: "doc-b", ChunkID: 42, ChunkIndex: 0},
+	}
+	if !reflect.DeepEqual(response.Citations, want) {
+		t.Errorf("Expected citations %+v, got %+v", want, response.Citations)
+	}
+	if !strings.HasPrefix(buildContext(chunks), "Context 1: first\n\nContext 2: second") {
+		t.Errorf("Expected the context blocks to carry the citation numbers")
+	}
+}
+
+func TestFitContext_KeepsTopChunksWithinBudget(t *testing.T) {
+	chunks := []types.RankedChunk{
+		{DocumentChunk: types.DocumentChunk{DocumentID: "doc-1", Content: "one two three"}},
+		{DocumentChunk: types.DocumentChunk{DocumentID: "doc-2", Content: "a b c d e"}},
+		{DocumentChunk: types.DocumentChunk{DocumentID: "doc-3", Content: "nine"}},
+	}
+	tokens := func(text string) int { return len(contextTokenizer.Tokenize(text)) }
+	first := tokens("Context 1: ") + tokens("one two three")
+	second := tokens("\n\n") + tokens("Context 2: ")
+
+	if fitted := fitContext(chunks, 0); len(fitted) != 3 {
+		t.Errorf("Expected every chunk without a budget, got %d", len(fitted))
+	}
+
+	// Room for the first chunk and two tokens of the second
+	fitted := fitContext(chunks, first+second+2)
+	if len(fitted) != 2 || fitted[0].Content != "one two three" || fitted[1].Content != "a b" || !fitted[1].Truncated {
+		t.Errorf("Expected the first chunk and a cut second chunk, got %+v", fitted)
+	}
+	if chunks[1].Content != "a b c d e" {
+		t.Error("Expected the caller's chunks to be left unchanged")
+	}
+
+	// No room for the second chunk's content
+	if fitted := fitContext(chunks, first+second); len(fitted) != 1 {
+		t.Errorf("Expected only the first chunk, got %+v", fitted)
+	}
+}
+
+func TestGenerateResponse_ReportsChunksUsedWithinBudget(t *testing.T) {
+	config := types.GenerationConfig{Provider: "openai", Model: "gpt-3.5-turbo", APIKey: "test-api-key", MaxContextTokens: 10}
+
+	var prompt string
+	service := newTestService(t, config, func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		prompt = req.Messages[0].Content
+		writeChatCompletion(w, "An answer")
+	})
+
+	response, err := service.GenerateResponse(context.Background(), "question", rankedChunks(4))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if response.ChunksUsed != 1 || response.ChunksRetrieved != 4 || len(response.Citations) != 1 {
+		t.Errorf("Expected 1 of 4 chunks used, got %d of %d with %d citations", response.ChunksUsed, response.ChunksRetrieved, len(response.Citations))
+	}
+	if strings.Contains(prompt, "Context 2") {
+		t.Errorf("Expected chunks over the budget to be left out of the prompt, got %q", prompt)
+	}
+}
+
+func TestGenerateResponse_RetriesOnceWithReducedContext(t *testing.T) {
+	config := types.GenerationConfig{
+		Provider:             "openai",
+		Model:                "gpt-3.5-turbo",
+		APIKey:               "test-api-key",
+		RetryOnContextLength: true,
+	}
+
+	var prompts []string
+	service := newTestService(t, config, func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		prompts = append(prompts, req.Messages[0].Content)
+
+		if len(prompts) == 1 {
+			writeAPIError(w, http.StatusBadRequest, "context_length_exceeded", "This model's maximum context length is 4097 tokens.")
+			return
+		}
+		writeChatCompletion(w, "reduced answer")
+	})
+
+	response, err := service.GenerateResponse(context.Background(), "test query", rankedChunks(4))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(prompts) != 2 {
+		t.Fatalf("Expected exactly 2 provider calls, got %d", len(prompts))
+	}
+
+	if strings.Contains(prompts[1], "Context 3:") || !strings.Contains(prompts[1], "Context 2:") {
+		t.Errorf("Expected retry prompt to contain only the top 2 chunks, got %q", prompts[1])
+	}
+
+	if !response.ContextReduced {
+		t.Error("Expected ContextReduced to be set")
+	}
+	if len(response.Citations) != 2 {
+		t.Errorf("Expected citations for the 2 chunks kept, got %+v", response.Citations)
+	}
+
+	if response.Response != "reduced answer" {
+		t.Errorf("Expected response 'reduced answer', got '%s'", response.Response)
+	}
+}
+
+func TestGenerateResponse_NoRetryWhenDisabled(t *testing.T) {
+	config := types.GenerationConfig{
+		Provider: "openai",
+		Model:    "gpt-3.5-turbo",
+		APIKey:   "test-api-key",
+	}
+
+	calls := 0
+	service := newTestService(t, config, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		writeAPIError(w, http.StatusBadRequest, "context_length_exceeded", "This model's maximum context length is 4097 tokens.")
+	})
+
+	if _, err := service.GenerateResponse(context.Background(), "test query", rankedChunks(4)); err == nil {
+		t.Error("Expected error when retry is disabled, got nil")
+	}
+
+	if calls != 1 {
+		t.Errorf("Expected 1 provider call, got %d", calls)
+	}
+}
+
+func TestGenerateResponse_RateLimited(t *testing.T) {
+	config := types.GenerationConfig{
+		Provider: "openai",
+		Model:    "gpt-3.5-turbo",
+		APIKey:   "test-api-key",
+	}
+
+	service := newTestService(t, config, func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, http.StatusTooManyRequests, "rate_limit_exceeded", "Rate limit reached")
+	})
+
+	_, err := service.GenerateResponse(context.Background(), "test query", rankedChunks(2))
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited, got %v", err)
+	}
+}
+
+func TestGenerateResponse_RetriesWithinRequestBudget(t *testing.T) {
+	config := types.GenerationConfig{
+		Provider:   "openai",
+		Model:      "gpt-3.5-turbo",
+		APIKey:     "test-api-key",
+		MaxRetries: 2,
+	}
+
+	calls := 0
+	service := newTestService(t, config, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls%2 == 1 {
+			writeAPIError(w, http.StatusServiceUnavailable, "server_error", "Service unavailable")
+			return
+		}
+		writeChatCompletion(w, "answer")
+	})
+
+	// An earlier provider call in the request already spent the budget
+	spent := retry.WithBudget(context.Background(), retry.NewBudget(0))
+	if _, err := service.GenerateResponse(spent, "test query", rankedChunks(2)); err == nil {
+		t.Error("Expected error when the retry budget is exhausted, got nil")
+	}
+
+	calls = 0
+	withBudget := retry.WithBudget(context.Background(), retry.NewBudget(1))
+	if _, err := service.GenerateResponse(withBudget, "test query", rankedChunks(2)); err != nil {
+		t.Errorf("Expected retry within budget to succeed, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 provider calls, got %d", calls)
+	}
+}
+
+func TestGenerateWithOptions_JSONMode(t *testing.T) {
+	config := types.GenerationConfig{
+		Provider: "openai",
+		Model:    "gpt-4o-mini",
+		APIKey:   "test-api-key",
+	}
+	schema := json.RawMessage(`{"type":"object","properties":{"answer":{"type":"string"},"year":{"type":"integer"}},"required":["answer","year"]}`)
+
+	var sentFormat *openai.ChatCompletionResponseFormat
+	reply := `{"answer": "Go was announced in 2009", "year": 2009}`
+	service := newTestService(t, config, func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ResponseFormat *openai.ChatCompletionResponseFormat `json:"response_format"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		sentFormat = req.ResponseFormat
+		writeChatCompletion(w, reply)
+	})
+
+	opts := types.GenerationOptions{ResponseFormat: types.ResponseFormatJSON, Schema: schema}
+	response, err := service.GenerateWithOptions(context.Background(), "When was Go announced?", rankedChunks(1), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if sentFormat == nil || sentFormat.Type != openai.ChatCompletionResponseFormatTypeJSONSchema {
+		t.Errorf("Expected json_schema response format to be requested, got %+v", sentFormat)
+	}
+
+	var parsed map[string]any
+	if err := json.Unmarshal([]byte(response.Response), &parsed); err != nil {
+		t.Fatalf("Expected a JSON answer, got %q", response.Response)
+	}
+	if parsed["year"] != float64(2009) {
+		t.Errorf("Expected year 2009, got %v", parsed["year"])
+	}
+
+	// An answer missing a required property is rejected
+	reply = `{"answer": "no year"}`
+	if _, err := service.GenerateWithOptions(context.Background(), "When?", rankedChunks(1), opts); err == nil {
+		t.Error("Expected error for answer missing a required property, got nil")
+	}
+}
+
+func TestGenerateWithOptions_ReturnsToolCalls(t *testing.T) {
+	config := types.GenerationConfig{
+		Provider: "openai",
+		Model:    "gpt-4o-mini",
+		APIKey:   "test-api-key",
+	}
+
+	var sentTools []openai.Tool
+	service := newTestService(t, config, func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		sentTools = req.Tools
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{
+				Message: openai.ChatCompletionMessage{
+					Role: openai.ChatMessageRoleAssistant,
+					ToolCalls: []openai.ToolCall{{
+						ID:       "call_42",
+						Type:     openai.ToolTypeFunction,
+						Function: openai.FunctionCall{Name: "search_again", Arguments: `{"query":"go history"}`},
+					}},
+				},
+				FinishReason: openai.FinishReasonToolCalls,
+			}},
+		})
+	})
+
+	opts := types.GenerationOptions{
+		Tools: []types.ToolDefinition{{
+			Name:        "search_again",
+			Description: "Run another retrieval with a new query",
+			Parameters:  json.RawMessage(`{"type":"object","properties":{"query":{"type":"string"}},"required":["query"]}`),
+		}},
+	}
+
+	response, err := service.GenerateWithOptions(context.Background(), "When was Go created?", rankedChunks(1), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(sentTools) != 1 || sentTools[0].Function.Name != "search_again" {
+		t.Errorf("Expected search_again tool to be sent, got %+v", sentTools)
+	}
+
+	if len(response.ToolCalls) != 1 {
+		t.Fatalf("Expected 1 tool call, got %d", len(response.ToolCalls))
+	}
+	call := response.ToolCalls[0]
+	if call.ID != "call_42" || call.Name != "search_again" || call.Arguments != `{"query":"go history"}` {
+		t.Errorf("Unexpected tool call %+v", call)
+	}
+}
+
+func TestGenerateResponse_EmptyContent(t *testing.T) {
+	config := types.GenerationConfig{
+		Provider: "openai",
+		Model:    "gpt-3.5-turbo",
+		APIKey:   "test-api-key",
+	}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{
+				Message:      openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: "  \n"},
+				FinishReason: openai.FinishReasonContentFilter,
+			}},
+		})
+	}
+
+	service := newTestService(t, config, handler)
+	_, err := service.GenerateResponse(context.Background(), "test query", rankedChunks(2))
+	if !errors.Is(err, ErrEmptyResponse) {
+		t.Fatalf("Expected ErrEmptyResponse, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "content filter") {
+		t.Errorf("Expected the finish reason in the error, got %v", err)
+	}
+
+	config.EmptyAnswerFallback = "No answer is available for this question."
+	service = newTestService(t, config, handler)
+	response, err := service.GenerateResponse(context.Background(), "test query", rankedChunks(2))
+	if err != nil {
+		t.Fatalf("Expected the fallback answer, got error %v", err)
+	}
+	if response.Response != config.EmptyAnswerFallback || !response.EmptyResponse {
+		t.Errorf("Expected the fallback answer flagged as empty, got %+v", response)
+	}
+}
+
+func TestNewService_AzureUsesMappedDeployment(t *testing.T) {
+	var path, apiKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, apiKey = r.URL.Path, r.Header.Get("api-key")
+		writeChatCompletion(w, "azure answer")
+	}))
+	t.Cleanup(server.Close)
+
+	service, err := NewService(types.GenerationConfig{
+		Provider: "azure",
+		Model:    "gpt-4o",
+		APIKey:   "azure-key",
+		Azure: types.AzureOpenAIConfig{
+			Endpoint:    server.URL + "/",
+			Deployments: map[string]string{"gpt-4o": "chat-prod"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create Azure service: %v", err)
+	}
+
+	response, err := service.GenerateResponse(context.Background(), "test query", rankedChunks(1))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if path != "/openai/deployments/chat-prod/chat/completions" || apiKey != "azure-key" {
+		t.Errorf("Expected the mapped deployment and api-key header, got %s and %q", path, apiKey)
+	}
+	if response.Response != "azure answer" {
+		t.Errorf("Expected response 'azure answer', got '%s'", response.Response)
+	}
+}
+
+// writeChatStream writes a streamed chat completion sending each delta as its own event
+func writeChatStream(w http.ResponseWriter, deltas ...string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	for _, delta := range deltas {
+		data, _ := json.Marshal(openai.ChatCompletionStreamResponse{
+			Choices: []openai.ChatCompletionStreamChoice{{Delta: openai.ChatCompletionStreamChoiceDelta{Content: delta}}},
+		})
+		fmt.Fprintf(w, "data: %s\n\n", data)
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+func TestStreamResponse_DeliversDeltas(t *testing.T) {
+	config := types.GenerationConfig{
+		Provider: "openai",
+		Model:    "gpt-3.5-turbo",
+		APIKey:   "test-api-key",
+	}
+
+	var streamed bool
+	service := newTestService(t, config, func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		streamed = req.Stream
+		writeChatStream(w, "Retrieval ", "augmented ", "generation.")
+	})
+
+	var deltas []string
+	response, err := service.StreamResponse(context.Background(), "What is RAG?", rankedChunks(2), types.GenerationOptions{}, func(delta string) error {
+		deltas = append(deltas, delta)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !streamed {
+		t.Error("Expected a streaming request")
+	}
+	if len(deltas) != 3 || response.Response != "Retrieval augmented generation." {
+		t.Errorf("Expected 3 deltas making up the answer, got %q and %q", deltas, response.Response)
+	}
+	if len(response.Sources) == 0 {
+		t.Error("Expected the streamed response to list its sources")
+	}
+
+	// A failing callback, e.g. for a disconnected client, stops the stream
+	stop := errors.New("client gone")
+	calls := 0
+	_, err = service.StreamResponse(context.Background(), "What is RAG?", rankedChunks(2), types.GenerationOptions{}, func(delta string) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("Expected the stream to stop at the first failed delta, got %v after %d calls", err, calls)
+	}
+}
+
+func TestStream_FallsBackToWholeAnswer(t *testing.T) {
+	service, err := NewAnthropicService(types.GenerationConfig{Provider: "anthropic", Model: "claude-sonnet-4-20250514", APIKey: "key"})
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	var deltas []string
+	response, err := Stream(context.Background(), service, "What is RAG?", nil, types.GenerationOptions{}, func(delta string) error {
+		deltas = append(deltas, delta)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(deltas) != 1 || deltas[0] != NoContextResponse || response.Response != NoContextResponse {
+		t.Errorf("Expected the whole answer as one delta, got %q", deltas)
+	}
+}
+
+func TestGenerateResponse_ReportsMeanLogProb(t *testing.T) {
+	config := types.GenerationConfig{
+		Provider: "openai",
+		Model:    "gpt-4o-mini",
+		APIKey:   "test-api-key",
+		LogProbs: true,
+	}
+
+	var requested bool
+	service := newTestService(t, config, func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		requested = req.LogProbs
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{
+				Message: openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: "In 2009"},
+				LogProbs: &openai.LogProbs{Content: []openai.LogProb{
+					{Token: "In", LogProb: -0.5},
+					{Token: " 2009", LogProb: -0.1},
+				}},
+			}},
+		})
+	})
+
+	response, err := service.GenerateResponse(context.Background(), "When was Go announced?", rankedChunks(1))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !requested {
+		t.Error("Expected log-probabilities to be requested")
+	}
+	if response.LogProb == nil || *response.LogProb < -0.3001 || *response.LogProb > -0.2999 {
+		t.Errorf("Expected a mean log-probability of -0.3, got %v", response.LogProb)
+	}
+}
@@ -2,6 +2,7 @@ package generate
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -22,13 +23,29 @@ func NewMockService(config types.GenerationConfig) (*MockService, error) {
 
 // GenerateResponse generates a mock response based on the query and relevant chunks
 func (s *MockService) GenerateResponse(ctx context.Context, query string, chunks []types.RankedChunk) (*types.GeneratedResponse, error) {
+	return s.GenerateWithOptions(ctx, query, chunks, types.GenerationOptions{})
+}
+
+// GenerateWithOptions generates a mock response, wrapping it in a JSON object in JSON mode
+func (s *MockService) GenerateWithOptions(ctx context.Context, query string, chunks []types.RankedChunk, opts types.GenerationOptions) (*types.GeneratedResponse, error) {
 	if len(chunks) == 0 {
 		return &types.GeneratedResponse{
-			Response: "I don't have enough information to answer your question.",
+			Response: NoContextResponse,
 			Sources:  []string{},
 		}, nil
 	}
 
+	// Ask for the first tool until the caller has supplied tool results
+	if len(opts.Tools) > 0 && len(opts.ToolResults) == 0 {
+		arguments, _ := json.Marshal(map[string]string{"query": query})
+		return &types.GeneratedResponse{
+			Sources: []string{},
+			ToolCalls: []types.ToolCall{
+				{ID: "call_1", Name: opts.Tools[0].Name, Arguments: string(arguments)},
+			},
+		}, nil
+	}
+
 	// Build a simple mock response based on the chunks
 	var contextParts []string
 	var sources []string
@@ -38,6 +55,7 @@ func (s *MockService) GenerateResponse(ctx context.Context, query string, chunks
 			contextParts = append(contextParts, chunk.Content)
 		}
 		sources = append(sources, chunk.DocumentID)
+		sources = append(sources, chunk.DuplicateDocumentIDs...)
 	}
 
 	// Create a mock response that incorporates the query and context
@@ -55,8 +73,32 @@ func (s *MockService) GenerateResponse(ctx context.Context, query string, chunks
 		}
 	}
 
+	if opts.ResponseFormat == types.ResponseFormatJSON {
+		encoded, _ := json.Marshal(map[string]string{"answer": response})
+		response = string(encoded)
+	}
+
 	return &types.GeneratedResponse{
-		Response: response,
-		Sources:  finalSources,
+		Response:  response,
+		Sources:   finalSources,
+		Citations: extractCitations(chunks),
 	}, nil
 }
+
+// StreamResponse streams a mock response one word at a time
+func (s *MockService) StreamResponse(ctx context.Context, query string, chunks []types.RankedChunk, opts types.GenerationOptions, onDelta func(delta string) error) (*types.GeneratedResponse, error) {
+	response, err := s.GenerateWithOptions(ctx, query, chunks, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, word := range strings.SplitAfter(response.Response, " ") {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := onDelta(word); err != nil {
+			return nil, err
+		}
+	}
+	return response, nil
+}
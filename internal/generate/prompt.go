@@ -0,0 +1,51 @@
+package generate
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// promptData is what a prompt template can refer to
+type promptData struct {
+	Context string
+	Query   string
+}
+
+// parsePromptTemplate parses a prompt template and renders it once, so a
+// template that fails to render or leaves out the context or query is
+// rejected when the service is created rather than on the first request.
+// An empty template gives nil, which renders the default prompt.
+func parsePromptTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New("prompt").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt template: %w", err)
+	}
+
+	var rendered strings.Builder
+	sample := promptData{Context: "\x00context\x00", Query: "\x00query\x00"}
+	if err := tmpl.Execute(&rendered, sample); err != nil {
+		return nil, fmt.Errorf("invalid prompt template: %w", err)
+	}
+	if !strings.Contains(rendered.String(), sample.Context) || !strings.Contains(rendered.String(), sample.Query) {
+		return nil, fmt.Errorf("invalid prompt template: it must include {{.Context}} and {{.Query}}")
+	}
+	return tmpl, nil
+}
+
+// renderPrompt builds the prompt from the template parsed by
+// parsePromptTemplate, or the default prompt when tmpl is nil
+func renderPrompt(tmpl *template.Template, query, context string) (string, error) {
+	if tmpl == nil {
+		return buildPrompt(query, context), nil
+	}
+
+	var prompt strings.Builder
+	if err := tmpl.Execute(&prompt, promptData{Context: context, Query: query}); err != nil {
+		return "", fmt.Errorf("failed to render prompt template: %w", err)
+	}
+	return prompt.String(), nil
+}
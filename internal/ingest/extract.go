@@ -0,0 +1,231 @@
+package ingest
+
+import (
+	"html"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"go-rag/internal/types"
+)
+
+// Content types with metadata extraction support
+const (
+	contentTypeMarkdown = "text/markdown"
+	contentTypeHTML     = "text/html"
+	contentTypePlain    = "text/plain"
+	contentTypePDF      = "application/pdf"
+)
+
+var (
+	htmlTitlePattern   = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	htmlHeadingPattern = regexp.MustCompile(`(?is)<h1[^>]*>(.*?)</h1>`)
+	htmlTagPattern     = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// contentTypeForPath guesses a document's content type from its file extension
+func contentTypeForPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		return contentTypeMarkdown
+	case ".html", ".htm":
+		return contentTypeHTML
+	case ".txt":
+		return contentTypePlain
+	case ".pdf":
+		return contentTypePDF
+	default:
+		return ""
+	}
+}
+
+// ExtractMetadata enriches metadata from the document itself based on its
+// content type. Markdown front-matter fills known fields (title, author, tags,
+// ...) and Custom, and the first heading becomes the title; HTML uses its
+// <title> or first <h1>. Fields already set in metadata are kept. The returned
+// content has any front-matter removed.
+func ExtractMetadata(content string, metadata types.Metadata) (string, types.Metadata) {
+	contentType := metadata.ContentType
+	if contentType == "" && strings.HasPrefix(content, "---") {
+		// Front-matter is a strong enough hint to treat untyped content as markdown
+		contentType = contentTypeMarkdown
+	}
+
+	switch contentType {
+	case contentTypeMarkdown:
+		block, body, ok := splitFrontMatter(content)
+		if ok {
+			content = body
+			var extracted types.Metadata
+			for _, field := range parseFrontMatter(block) {
+				setMetadataField(&extracted, field.key, field.value)
+			}
+			metadata = mergeMetadata(metadata, extracted)
+		}
+		if metadata.Title == "" {
+			metadata.Title = markdownTitle(content)
+		}
+	case contentTypeHTML:
+		if metadata.Title == "" {
+			metadata.Title = htmlTitle(content)
+		}
+	}
+
+	return content, metadata
+}
+
+// splitFrontMatter separates a leading "---" delimited YAML block from the body
+func splitFrontMatter(content string) (block, body string, ok bool) {
+	content = strings.TrimPrefix(content, "\ufeff")
+	lines := strings.SplitAfter(content, "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return "", content, false
+	}
+
+	for i := 1; i < len(lines); i++ {
+		if marker := strings.TrimSpace(lines[i]); marker == "---" || marker == "..." {
+			return strings.Join(lines[1:i], ""), strings.Join(lines[i+1:], ""), true
+		}
+	}
+	return "", content, false
+}
+
+type frontMatterField struct {
+	key   string
+	value any
+}
+
+// parseFrontMatter reads the flat subset of YAML used in front-matter:
+// "key: value" pairs, inline lists ("[a, b]") and block lists ("- a").
+// Nested mappings are ignored.
+func parseFrontMatter(block string) []frontMatterField {
+	var fields []frontMatterField
+	for _, line := range strings.Split(block, "\n") {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		// Block list item belonging to the previous key
+		if strings.HasPrefix(trimmed, "- ") && len(fields) > 0 {
+			last := &fields[len(fields)-1]
+			if list, isList := last.value.([]any); isList {
+				last.value = append(list, unquote(strings.TrimSpace(trimmed[2:])))
+			}
+			continue
+		}
+
+		// Indented lines belong to nested mappings, which have no metadata equivalent
+		if line != trimmed {
+			continue
+		}
+
+		key, value, found := strings.Cut(trimmed, ":")
+		if !found {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		switch {
+		case value == "":
+			fields = append(fields, frontMatterField{key: key, value: []any{}})
+		case strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]"):
+			var list []any
+			for _, item := range strings.Split(value[1:len(value)-1], ",") {
+				if item = unquote(strings.TrimSpace(item)); item != "" {
+					list = append(list, item)
+				}
+			}
+			fields = append(fields, frontMatterField{key: key, value: list})
+		default:
+			fields = append(fields, frontMatterField{key: key, value: unquote(value)})
+		}
+	}
+
+	// Keys with no value and no list items carry nothing
+	result := fields[:0]
+	for _, field := range fields {
+		if list, isList := field.value.([]any); isList && len(list) == 0 {
+			continue
+		}
+		result = append(result, field)
+	}
+	return result
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// mergeMetadata fills empty fields of base from extracted
+func mergeMetadata(base, extracted types.Metadata) types.Metadata {
+	if base.Title == "" {
+		base.Title = extracted.Title
+	}
+	if base.Author == "" {
+		base.Author = extracted.Author
+	}
+	if base.Source == "" {
+		base.Source = extracted.Source
+	}
+	if base.Language == "" {
+		base.Language = extracted.Language
+	}
+	if len(base.Tags) == 0 {
+		base.Tags = extracted.Tags
+	}
+	if base.ParentID == "" {
+		base.ParentID = extracted.ParentID
+	}
+	if len(base.Path) == 0 {
+		base.Path = extracted.Path
+	}
+	if base.Section == "" {
+		base.Section = extracted.Section
+	}
+	if len(extracted.Custom) > 0 {
+		custom := make(map[string]string, len(base.Custom)+len(extracted.Custom))
+		for key, value := range extracted.Custom {
+			custom[key] = value
+		}
+		for key, value := range base.Custom {
+			custom[key] = value
+		}
+		base.Custom = custom
+	}
+	return base
+}
+
+// markdownTitle returns the text of the first level-one heading outside code blocks
+func markdownTitle(content string) string {
+	inFence := false
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if !inFence && strings.HasPrefix(trimmed, "# ") {
+			return strings.TrimSpace(strings.TrimRight(trimmed[2:], "#"))
+		}
+	}
+	return ""
+}
+
+// htmlTitle returns the document's <title>, falling back to its first <h1>
+func htmlTitle(content string) string {
+	for _, pattern := range []*regexp.Regexp{htmlTitlePattern, htmlHeadingPattern} {
+		if match := pattern.FindStringSubmatch(content); match != nil {
+			title := html.UnescapeString(htmlTagPattern.ReplaceAllString(match[1], ""))
+			if title = strings.Join(strings.Fields(title), " "); title != "" {
+				return title
+			}
+		}
+	}
+	return ""
+}
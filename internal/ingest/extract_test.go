@@ -0,0 +1,233 @@
+package ingest
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go-rag/internal/chunk"
+	"go-rag/internal/types"
+)
+
+const frontMatterDoc = `---
+title: "Vector Search Basics"
+author: Jane Doe
+tags: [search, vectors]
+category: tutorial
+aliases:
+  - basics
+  - intro
+---
+# Introduction
+
+Vector search finds similar items.
+`
+
+func TestExtractMetadata_MarkdownFrontMatter(t *testing.T) {
+	content, metadata := ExtractMetadata(frontMatterDoc, types.Metadata{ContentType: "text/markdown"})
+
+	if strings.Contains(content, "author:") {
+		t.Errorf("expected front-matter to be stripped, got %q", content)
+	}
+	if metadata.Title != "Vector Search Basics" {
+		t.Errorf("expected front-matter title, got %q", metadata.Title)
+	}
+	if metadata.Author != "Jane Doe" {
+		t.Errorf("expected author Jane Doe, got %q", metadata.Author)
+	}
+	if !reflect.DeepEqual(metadata.Tags, []string{"search", "vectors"}) {
+		t.Errorf("unexpected tags: %v", metadata.Tags)
+	}
+	if metadata.Custom["category"] != "tutorial" {
+		t.Errorf("expected custom category, got %v", metadata.Custom)
+	}
+	if metadata.Custom["aliases"] != `["basics","intro"]` {
+		t.Errorf("expected block list in custom aliases, got %q", metadata.Custom["aliases"])
+	}
+}
+
+func TestExtractMetadata_HeadingAndProvidedFields(t *testing.T) {
+	doc := "```\n# not a title\n```\n# Real Title\n\nBody text."
+	_, metadata := ExtractMetadata(doc, types.Metadata{ContentType: "text/markdown"})
+	if metadata.Title != "Real Title" {
+		t.Errorf("expected first heading outside code, got %q", metadata.Title)
+	}
+
+	_, metadata = ExtractMetadata(frontMatterDoc, types.Metadata{ContentType: "text/markdown", Title: "Given"})
+	if metadata.Title != "Given" {
+		t.Errorf("expected provided title to win, got %q", metadata.Title)
+	}
+
+	_, metadata = ExtractMetadata("<html><head><title>Page &amp; Co</title></head></html>", types.Metadata{ContentType: "text/html"})
+	if metadata.Title != "Page & Co" {
+		t.Errorf("expected HTML title, got %q", metadata.Title)
+	}
+
+	content, metadata := ExtractMetadata("# Plain text heading", types.Metadata{ContentType: "text/plain"})
+	if metadata.Title != "" || content != "# Plain text heading" {
+		t.Errorf("expected plain text to be left alone, got %q / %q", metadata.Title, content)
+	}
+}
+
+func TestIngestDirectory_ExtractsMarkdownMetadata(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "basics.md"), []byte(frontMatterDoc), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	store := newFakeStore()
+	service := NewService(*chunk.NewService(1000, 200), store, types.ChunkingConfig{ExtractMetadata: true})
+	if _, err := service.IngestDirectory(context.Background(), types.DirectoryIngestRequest{DirectoryPath: dir}); err != nil {
+		t.Fatalf("IngestDirectory failed: %v", err)
+	}
+
+	if len(store.chunks) == 0 {
+		t.Fatal("expected chunks to be stored")
+	}
+	for _, c := range store.chunks {
+		if c.Metadata.Title != "Vector Search Basics" || c.Metadata.Author != "Jane Doe" {
+			t.Errorf("expected extracted metadata on chunk, got %+v", c.Metadata)
+		}
+		if c.Metadata.ContentType != "text/markdown" {
+			t.Errorf("expected markdown content type, got %q", c.Metadata.ContentType)
+		}
+		if strings.Contains(c.Content, "author:") {
+			t.Errorf("expected front-matter to be excluded from chunks, got %q", c.Content)
+		}
+	}
+}
+
+func TestExtractHTML(t *testing.T) {
+	page := `<!DOCTYPE html>
+<html>
+<head>
+  <title>Release   Notes</title>
+  <meta name="author" content="Ada &amp; Grace">
+  <meta property="og:description" content="What changed in 2.0">
+  <style>body { color: red; }</style>
+</head>
+<body>
+  <script>var tracking = "ignored";</script>
+  <h1>Version 2.0</h1>
+  <p>Read the <a href="/guide">upgrade guide</a> first.
+     It covers &lt;breaking&gt; changes.</p>
+  <!-- hidden comment -->
+  <ul><li>Faster search</li><li>New <b>filters</b></li></ul>
+  <table><tr><td>Old</td><td>New</td></tr></table>
+  <noscript>Enable JavaScript</noscript>
+</body>
+</html>`
+
+	text, metadata := ExtractHTML(page, types.Metadata{ContentType: "text/html", Custom: map[string]string{"team": "search"}})
+
+	want := "Version 2.0\n\nRead the upgrade guide first. It covers <breaking> changes.\n\nFaster search\n\nNew filters\n\nOld New"
+	if text != want {
+		t.Errorf("Unexpected text:\n got: %q\nwant: %q", text, want)
+	}
+	if metadata.Title != "Release Notes" || metadata.Author != "Ada & Grace" {
+		t.Errorf("Expected the title and author from the head, got %+v", metadata)
+	}
+	if metadata.Custom["description"] != "What changed in 2.0" || metadata.Custom["team"] != "search" {
+		t.Errorf("Expected the description alongside the existing custom fields, got %v", metadata.Custom)
+	}
+
+	// Fields the caller set are kept
+	_, metadata = ExtractHTML(page, types.Metadata{Title: "Mine", Author: "Me", Custom: map[string]string{"description": "Set"}})
+	if metadata.Title != "Mine" || metadata.Author != "Me" || metadata.Custom["description"] != "Set" {
+		t.Errorf("Expected existing metadata to win, got %+v", metadata)
+	}
+}
+
+// buildPDF assembles a minimal PDF whose page draws the given content
+// streams, compressing those marked with FlateDecode
+func buildPDF(t *testing.T, streams ...string) []byte {
+	t.Helper()
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	pdf.WriteString("3 0 obj\n<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>\nendobj\n")
+	for i, content := range streams {
+		body, dict := []byte(content), fmt.Sprintf("<< /Length %d >>", len(content))
+		if i%2 == 1 {
+			var compressed bytes.Buffer
+			w := zlib.NewWriter(&compressed)
+			w.Write(body)
+			w.Close()
+			body = compressed.Bytes()
+			dict = fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>", len(body))
+		}
+		fmt.Fprintf(&pdf, "%d 0 obj\n%s\nstream\n%s\nendstream\nendobj\n", i+4, dict, body)
+	}
+	pdf.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return pdf.Bytes()
+}
+
+func TestIngestFile_PDFLimits(t *testing.T) {
+	svc := newTestService(newFakeStore())
+	svc.SetUploadLimit(1000)
+
+	// A small stream that inflates past ten times the upload limit
+	bomb := buildPDF(t, "BT ET", "BT ("+strings.Repeat("a", 20000)+") Tj ET")
+	if len(bomb) > 1000 {
+		t.Fatalf("Expected the compressed PDF to fit the upload limit, got %d bytes", len(bomb))
+	}
+	if _, _, err := svc.IngestFile(context.Background(), "", "bomb.pdf", bomb, types.Metadata{}, types.ChunkingOptions{}); !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("Expected ErrFileTooLarge for a PDF that inflates past the limit, got %v", err)
+	}
+
+	// A corrupt compressed stream is an error rather than missing text
+	corrupt := []byte("%PDF-1.4\n4 0 obj\n<< /Length 4 /Filter /FlateDecode >>\nstream\nBT x\nendstream\nendobj\n")
+	if _, _, err := svc.IngestFile(context.Background(), "", "corrupt.pdf", corrupt, types.Metadata{}, types.ChunkingOptions{}); !errors.Is(err, ErrUnsupportedFileType) {
+		t.Errorf("Expected ErrUnsupportedFileType for a corrupt stream, got %v", err)
+	}
+
+	// Composite fonts show glyph IDs, not text
+	cid := bytes.Replace(buildPDF(t, "BT /F1 12 Tf <00410042> Tj ET"), []byte("/Subtype /Type1"), []byte("/Subtype /Type0 /Encoding /Identity-H"), 1)
+	if _, _, err := svc.IngestFile(context.Background(), "", "cid.pdf", cid, types.Metadata{}, types.ChunkingOptions{}); !errors.Is(err, ErrUnsupportedFileType) {
+		t.Errorf("Expected ErrUnsupportedFileType for a PDF with CID fonts, got %v", err)
+	}
+}
+
+func TestIngestFile_PDF(t *testing.T) {
+	pdf := buildPDF(t,
+		"BT /F1 12 Tf 72 720 Td (Quarterly \\(Q3\\) report) Tj 0 -14 Td [(Rev)20(enue) -300 (grew)] TJ ET",
+		"BT /F1 12 Tf 72 680 Td <436166E9> Tj T* (by 12%) Tj ET",
+	)
+
+	text, err := pdfText(pdf, 1<<20)
+	if err != nil {
+		t.Fatalf("pdfText failed: %v", err)
+	}
+	want := "Quarterly (Q3) report\nRevenue grew\nCafé\nby 12%"
+	if text != want {
+		t.Errorf("Unexpected text:\n got: %q\nwant: %q", text, want)
+	}
+
+	store := newFakeStore()
+	svc := newTestService(store)
+	docID, count, err := svc.IngestFile(context.Background(), "", "report.pdf", pdf, types.Metadata{Title: "Q3"}, types.ChunkingOptions{})
+	if err != nil {
+		t.Fatalf("IngestFile failed: %v", err)
+	}
+	if !strings.HasPrefix(docID, "upload_") || count == 0 {
+		t.Fatalf("Expected a generated ID and chunks, got %q and %d", docID, count)
+	}
+	chunks, _ := store.GetChunksByDocumentID(context.Background(), docID)
+	if chunks[0].Metadata.ContentType != "application/pdf" || chunks[0].Metadata.Source != "report.pdf" || chunks[0].Metadata.Title != "Q3" {
+		t.Errorf("Unexpected metadata: %+v", chunks[0].Metadata)
+	}
+
+	// A PDF without drawn text, and a file of another type, are unsupported
+	if _, _, err := svc.IngestFile(context.Background(), "", "scan.pdf", buildPDF(t), types.Metadata{}, types.ChunkingOptions{}); !errors.Is(err, ErrUnsupportedFileType) {
+		t.Errorf("Expected ErrUnsupportedFileType for a PDF without text, got %v", err)
+	}
+	if _, _, err := svc.IngestFile(context.Background(), "", "archive.zip", []byte("PK\x03\x04"), types.Metadata{}, types.ChunkingOptions{}); !errors.Is(err, ErrUnsupportedFileType) {
+		t.Errorf("Expected ErrUnsupportedFileType for a zip, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"go-rag/internal/types"
+)
+
+var (
+	// ErrUnsupportedFileType is returned for a file whose text can't be extracted
+	ErrUnsupportedFileType = errors.New("unsupported file type")
+	// ErrEmptyFile is returned for a file without any text
+	ErrEmptyFile = errors.New("file has no text")
+	// ErrFileTooLarge is returned for a file whose content expands past the
+	// service's limit when decompressed
+	ErrFileTooLarge = errors.New("file too large")
+)
+
+// defaultUploadLimit is the upload size limit when none is set
+const defaultUploadLimit = 10 << 20
+
+// maxInflateRatio bounds how many times the upload limit a PDF's compressed
+// streams may inflate to
+const maxInflateRatio = 10
+
+// DetectContentType returns a file's content type from its extension, or from
+// its content when the extension isn't known
+func DetectContentType(filename string, data []byte) string {
+	if contentType := contentTypeForPath(filename); contentType != "" {
+		return contentType
+	}
+	contentType, _, _ := strings.Cut(http.DetectContentType(data), ";")
+	return contentType
+}
+
+// fileText extracts the text of a file of the given content type. Plain text,
+// markdown and HTML are used as they are, HTML being stripped when it's
+// ingested; PDFs have the text of their pages extracted, inflating at most
+// maxInflated bytes.
+func fileText(contentType string, data []byte, maxInflated int64) (string, error) {
+	switch contentType {
+	case contentTypePlain, contentTypeMarkdown, contentTypeHTML:
+		if !utf8.Valid(data) {
+			return "", fmt.Errorf("%w: %s is not valid UTF-8", ErrUnsupportedFileType, contentType)
+		}
+		return string(data), nil
+	case contentTypePDF:
+		return pdfText(data, maxInflated)
+	default:
+		return "", fmt.Errorf("%w: %q, use plain text, markdown, HTML or PDF", ErrUnsupportedFileType, contentType)
+	}
+}
+
+// SetUploadLimit sets the upload size limit in bytes. Compressed PDF streams
+// may inflate to at most maxInflateRatio times the limit.
+func (s *Service) SetUploadLimit(limit int64) {
+	s.uploadLimit = limit
+}
+
+// maxInflated is how many bytes a file's compressed content may inflate to
+func (s *Service) maxInflated() int64 {
+	limit := s.uploadLimit
+	if limit <= 0 {
+		limit = defaultUploadLimit
+	}
+	return limit * maxInflateRatio
+}
+
+// FormMetadata builds metadata from form fields named after metadata fields,
+// such as title, author and source. Tags and path may be repeated or
+// comma-separated; fields with other names go to Custom.
+func FormMetadata(form map[string][]string) types.Metadata {
+	var metadata types.Metadata
+	for field, values := range form {
+		for _, value := range values {
+			if field == "tags" || field == "path" {
+				for _, item := range strings.Split(value, ",") {
+					if item = strings.TrimSpace(item); item != "" {
+						setMetadataField(&metadata, field, item)
+					}
+				}
+				continue
+			}
+			setMetadataField(&metadata, field, value)
+		}
+	}
+	return metadata
+}
+
+// IngestFile extracts the text of an uploaded file and ingests it, returning
+// the document ID and chunk count. The file type is detected with
+// DetectContentType and recorded as the content type unless metadata sets
+// one. Without a docID, one is derived from the file's content, so uploading
+// the same file again replaces it.
+func (s *Service) IngestFile(ctx context.Context, docID, filename string, data []byte, metadata types.Metadata, opts types.ChunkingOptions) (string, int, error) {
+	contentType := DetectContentType(filename, data)
+	text, err := fileText(contentType, data, s.maxInflated())
+	if err != nil {
+		return "", 0, err
+	}
+	if strings.TrimSpace(text) == "" {
+		return "", 0, ErrEmptyFile
+	}
+
+	if docID == "" {
+		h := fnv.New64a()
+		h.Write(data)
+		docID = fmt.Sprintf("upload_%x", h.Sum64())
+	}
+	if metadata.ContentType == "" {
+		metadata.ContentType = contentType
+	}
+	if metadata.Source == "" {
+		metadata.Source = filename
+	}
+
+	chunks, err := s.ingestWithMetadata(ctx, docID, strings.NewReader(text), metadata, opts)
+	if err != nil {
+		return "", 0, err
+	}
+	return docID, chunks, nil
+}
@@ -0,0 +1,95 @@
+package ingest
+
+import (
+	"slices"
+	"strings"
+
+	"go-rag/internal/chunk"
+	"go-rag/internal/types"
+)
+
+// markdownHeading is an ATX heading and the byte offset its line starts at
+type markdownHeading struct {
+	offset int
+	level  int
+	text   string
+}
+
+// markdownHeadings lists the headings outside code blocks in document order
+func markdownHeadings(content string) []markdownHeading {
+	var headings []markdownHeading
+	inFence := false
+	offset := 0
+	for _, line := range strings.SplitAfter(content, "\n") {
+		start := offset
+		offset += len(line)
+
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		level := 0
+		for level < len(trimmed) && trimmed[level] == '#' {
+			level++
+		}
+		if level == 0 || level > 6 || (level < len(trimmed) && trimmed[level] != ' ') {
+			continue
+		}
+		text := strings.TrimSpace(strings.TrimRight(trimmed[level:], "#"))
+		if text != "" {
+			headings = append(headings, markdownHeading{offset: start, level: level, text: text})
+		}
+	}
+	return headings
+}
+
+// headingPaths returns, for each chunk, the headings in effect where the chunk
+// starts, outermost first. A chunk that can't be located in the content gets
+// the path of the one before it.
+func headingPaths(content string, chunks []string, spans []chunk.Span) [][]string {
+	if spans == nil {
+		spans = chunk.LocateChunks(content, chunks)
+	}
+	headings := markdownHeadings(content)
+
+	paths := make([][]string, len(chunks))
+	var stack []markdownHeading
+	next := 0
+	for i, span := range spans {
+		if span.End == 0 && i > 0 {
+			paths[i] = paths[i-1]
+			continue
+		}
+		// Chunks come in document order, so the stack only moves forward
+		for next < len(headings) && headings[next].offset <= span.Start {
+			heading := headings[next]
+			for len(stack) > 0 && stack[len(stack)-1].level >= heading.level {
+				stack = stack[:len(stack)-1]
+			}
+			stack = append(stack, heading)
+			next++
+		}
+		path := make([]string, len(stack))
+		for j, heading := range stack {
+			path[j] = heading.text
+		}
+		paths[i] = path
+	}
+	return paths
+}
+
+// withHeadingPath places a chunk under the given headings. The headings extend
+// any path the caller supplied, and the innermost becomes the section.
+func withHeadingPath(metadata types.Metadata, headings []string) types.Metadata {
+	if len(headings) == 0 {
+		return metadata
+	}
+	metadata.Path = append(slices.Clip(metadata.Path), headings...)
+	metadata.Section = headings[len(headings)-1]
+	return metadata
+}
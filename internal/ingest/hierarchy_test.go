@@ -0,0 +1,133 @@
+package ingest
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"go-rag/internal/chunk"
+	"go-rag/internal/types"
+)
+
+const handbook = `# Handbook
+
+Welcome to the handbook.
+
+## Chapter 3
+
+### Leave
+
+Vacation requests go to your manager.
+
+` + "```" + `
+# not a heading
+` + "```" + `
+
+## Chapter 4
+
+Expenses are reimbursed monthly.`
+
+func TestIngestText_FillsMarkdownHierarchy(t *testing.T) {
+	store := newFakeStore()
+	service := NewService(*chunk.NewService(100, 20), store, types.ChunkingConfig{
+		ChunkSize: 100, ChunkOverlap: 20, Strategy: chunk.StrategyParagraph, MarkdownHierarchy: true,
+	})
+	ctx := context.Background()
+
+	// The caller's path is the prefix the headings extend
+	metadata := types.Metadata{ContentType: "text/markdown", ParentID: "employee-guide", Path: []string{"Employee Guide"}}
+	if _, err := service.IngestText(ctx, "handbook", handbook, metadata); err != nil {
+		t.Fatalf("IngestText failed: %v", err)
+	}
+
+	chunks, _ := store.GetChunksByDocumentID(ctx, "handbook")
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i].ChunkIndex < chunks[j].ChunkIndex })
+	want := map[string][]string{
+		"Welcome to the handbook.":              {"Employee Guide", "Handbook"},
+		"Vacation requests go to your manager.": {"Employee Guide", "Handbook", "Chapter 3", "Leave"},
+		"```\n# not a heading\n```":             {"Employee Guide", "Handbook", "Chapter 3", "Leave"},
+		"Expenses are reimbursed monthly.":      {"Employee Guide", "Handbook", "Chapter 4"},
+	}
+	for _, c := range chunks {
+		path, ok := want[c.Content]
+		if !ok {
+			continue
+		}
+		delete(want, c.Content)
+		if !reflect.DeepEqual(c.Metadata.Path, path) || c.Metadata.Section != path[len(path)-1] || c.Metadata.ParentID != "employee-guide" {
+			t.Errorf("%q: expected path %v, got path %v and section %q", c.Content, path, c.Metadata.Path, c.Metadata.Section)
+		}
+	}
+	if len(want) > 0 {
+		t.Errorf("expected chunks were not stored: %v", want)
+	}
+
+	// Plain text is left alone
+	if _, err := service.IngestText(ctx, "plain", handbook, types.Metadata{}); err != nil {
+		t.Fatalf("IngestText failed: %v", err)
+	}
+	plain, _ := store.GetChunksByDocumentID(ctx, "plain")
+	for _, c := range plain {
+		if c.Metadata.Path != nil || c.Metadata.Section != "" {
+			t.Errorf("expected no hierarchy without the markdown content type, got %+v", c.Metadata)
+		}
+	}
+}
+
+func TestIngestText_MarkdownStrategyLocatesChunkBodies(t *testing.T) {
+	store := newFakeStore()
+	service := NewService(*chunk.NewService(100, 20), store, types.ChunkingConfig{
+		ChunkSize: 100, ChunkOverlap: 20, Strategy: chunk.StrategyMarkdown, StoreOffsets: true, MarkdownHierarchy: true,
+	})
+	ctx := context.Background()
+
+	if _, err := service.IngestText(ctx, "handbook", handbook, types.Metadata{ContentType: "text/markdown"}); err != nil {
+		t.Fatalf("IngestText failed: %v", err)
+	}
+
+	chunks, _ := store.GetChunksByDocumentID(ctx, "handbook")
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i].ChunkIndex < chunks[j].ChunkIndex })
+	leave := chunks[1]
+	if leave.Content != "# Handbook\n## Chapter 3\n### Leave\n\nVacation requests go to your manager.\n\n```\n# not a heading\n```" {
+		t.Fatalf("Unexpected chunk %q", leave.Content)
+	}
+	if got := handbook[leave.StartOffset:leave.EndOffset]; got != "Vacation requests go to your manager.\n\n```\n# not a heading\n```" {
+		t.Errorf("Expected the offsets to cover the chunk's body, got %q", got)
+	}
+	if want := []string{"Handbook", "Chapter 3", "Leave"}; !reflect.DeepEqual(leave.Metadata.Path, want) {
+		t.Errorf("Expected path %v, got %v", want, leave.Metadata.Path)
+	}
+}
+
+func TestIngestText_OffsetsCountFrontMatter(t *testing.T) {
+	store := newFakeStore()
+	service := NewService(*chunk.NewService(100, 20), store, types.ChunkingConfig{
+		ChunkSize: 100, ChunkOverlap: 20, Strategy: chunk.StrategyMarkdown, StoreOffsets: true, ExtractMetadata: true,
+	})
+	ctx := context.Background()
+
+	document := "---\ntitle: Leave policy\nauthor: HR\n---\n" + handbook
+	if _, err := service.IngestText(ctx, "handbook", document, types.Metadata{ContentType: "text/markdown"}); err != nil {
+		t.Fatalf("IngestText failed: %v", err)
+	}
+
+	chunks, _ := store.GetChunksByDocumentID(ctx, "handbook")
+	if len(chunks) == 0 || chunks[0].Metadata.Title != "Leave policy" {
+		t.Fatalf("Expected chunks titled from the front-matter, got %+v", chunks)
+	}
+	for _, c := range chunks {
+		if got := document[c.StartOffset:c.EndOffset]; !strings.HasSuffix(c.Content, got) || got == "" {
+			t.Errorf("Expected offsets into the ingested document for chunk %d, got %q", c.ChunkIndex, got)
+		}
+	}
+}
+
+func TestMarkdownHeadings(t *testing.T) {
+	headings := markdownHeadings("# Title #\ntext\n##Not a heading\n####### Too deep\n  ## Indented\n")
+	want := []markdownHeading{{offset: 0, level: 1, text: "Title"}, {offset: 48, level: 2, text: "Indented"}}
+	if !reflect.DeepEqual(headings, want) {
+		t.Errorf("expected %+v, got %+v", want, headings)
+	}
+}
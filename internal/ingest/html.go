@@ -0,0 +1,107 @@
+package ingest
+
+import (
+	"html"
+	"maps"
+	"regexp"
+	"strings"
+
+	"go-rag/internal/types"
+)
+
+var (
+	// htmlHiddenPatterns match comments and elements whose content isn't readable text
+	htmlHiddenPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?s)<!--.*?-->`),
+		regexp.MustCompile(`(?is)<head\b[^>]*>.*?</head\s*>`),
+		regexp.MustCompile(`(?is)<script\b[^>]*>.*?</script\s*>`),
+		regexp.MustCompile(`(?is)<style\b[^>]*>.*?</style\s*>`),
+		regexp.MustCompile(`(?is)<noscript\b[^>]*>.*?</noscript\s*>`),
+		regexp.MustCompile(`(?is)<template\b[^>]*>.*?</template\s*>`),
+	}
+	htmlBlockPattern = regexp.MustCompile(`(?i)</?(p|div|br|hr|h[1-6]|li|ul|ol|dl|dt|dd|tr|table|thead|tbody|section|article|main|header|footer|nav|aside|blockquote|pre|figure|figcaption|form)\b[^>]*>`)
+	htmlCellPattern  = regexp.MustCompile(`(?i)</?(td|th)\b[^>]*>`)
+	htmlMetaPattern  = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	htmlAttrPattern  = regexp.MustCompile(`(?is)([a-z][a-z:-]*)\s*=\s*("[^"]*"|'[^']*'|[^\s"'>]+)`)
+)
+
+// ExtractHTML turns an HTML document into readable text. Scripts, styles,
+// comments and the <head> are dropped, other tags are removed while keeping
+// their text (such as link text), and each block element, such as a paragraph
+// or list item, becomes a paragraph of its own. The <title> (or first <h1>)
+// and the author and description <meta> tags fill the title, author and
+// Custom["description"] when they aren't set already.
+func ExtractHTML(content string, metadata types.Metadata) (string, types.Metadata) {
+	if metadata.Title == "" {
+		metadata.Title = htmlTitle(content)
+	}
+
+	var author, description string
+	for _, tag := range htmlMetaPattern.FindAllString(content, -1) {
+		attrs := htmlAttributes(tag)
+		name := attrs["name"]
+		if name == "" {
+			name = attrs["property"]
+		}
+		value := strings.Join(strings.Fields(attrs["content"]), " ")
+		switch strings.ToLower(name) {
+		case "author", "article:author":
+			if author == "" {
+				author = value
+			}
+		case "description", "og:description":
+			if description == "" {
+				description = value
+			}
+		}
+	}
+	if metadata.Author == "" {
+		metadata.Author = author
+	}
+	if _, ok := metadata.Custom["description"]; !ok && description != "" {
+		custom := maps.Clone(metadata.Custom)
+		if custom == nil {
+			custom = make(map[string]string, 1)
+		}
+		custom["description"] = description
+		metadata.Custom = custom
+	}
+
+	return htmlText(content), metadata
+}
+
+// htmlText extracts the readable text of an HTML document, one paragraph per
+// block element
+func htmlText(content string) string {
+	for _, pattern := range htmlHiddenPatterns {
+		content = pattern.ReplaceAllString(content, " ")
+	}
+	// Markup whitespace is insignificant, so line breaks only come from blocks
+	content = strings.Join(strings.Fields(content), " ")
+	content = htmlBlockPattern.ReplaceAllString(content, "\n")
+	content = htmlCellPattern.ReplaceAllString(content, " ")
+	content = htmlTagPattern.ReplaceAllString(content, "")
+	content = html.UnescapeString(content)
+
+	var paragraphs []string
+	for _, line := range strings.Split(content, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			paragraphs = append(paragraphs, line)
+		}
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// htmlAttributes reads the attributes of a tag, keyed by lowercase name, with
+// entities decoded
+func htmlAttributes(tag string) map[string]string {
+	attrs := make(map[string]string)
+	for _, match := range htmlAttrPattern.FindAllStringSubmatch(tag, -1) {
+		value := match[2]
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') {
+			value = value[1 : len(value)-1]
+		}
+		attrs[strings.ToLower(match[1])] = html.UnescapeString(value)
+	}
+	return attrs
+}
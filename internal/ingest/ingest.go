@@ -1,126 +1,618 @@
 package ingest
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"log"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"go-rag/internal/audit"
 	"go-rag/internal/chunk"
+	"go-rag/internal/moderation"
 	"go-rag/internal/store"
 	"go-rag/internal/types"
 )
 
+// ErrInvalidChunking is returned when per-request chunking options are invalid
+var ErrInvalidChunking = errors.New("invalid chunking options")
+
+// ErrNotDirectory is returned when a directory ingest path is a file
+var ErrNotDirectory = errors.New("path is not a directory")
+
+// ErrDocumentNotFound is returned when deleting a document that has no chunks
+var ErrDocumentNotFound = errors.New("document not found")
+
+// errFileLimit stops the directory walk once the file limit is reached
+var errFileLimit = errors.New("file limit reached")
+
+// defaultDirectoryBatchSize is how many files a directory ingest processes at a time when none is configured
+const defaultDirectoryBatchSize = 100
+
+// maxChunkSize bounds per-request chunk sizes, well above what embedding models accept
+const maxChunkSize = 100000
+
 // Service handles document ingestion
 type Service struct {
 	chunker chunk.Service
 	store   store.VectorStore
+	config  types.ChunkingConfig
+	audit   audit.Logger
+	locks   *documentLocks
+
+	moderator        moderation.Moderator
+	moderationAction string
+
+	// uploadLimit bounds decompressed file content, see SetUploadLimit
+	uploadLimit int64
+
+	// onChange is called with the ID of each document written or deleted
+	onChange func(docID string)
 }
 
 // NewService creates a new ingestion service
-func NewService(chunker chunk.Service, store store.VectorStore) *Service {
+func NewService(chunker chunk.Service, store store.VectorStore, config types.ChunkingConfig) *Service {
 	return &Service{
 		chunker: chunker,
 		store:   store,
+		config:  config,
+		audit:   audit.NoopLogger{},
+		locks:   newDocumentLocks(),
+	}
+}
+
+// WithStore returns a copy of the service that writes to store. The copy shares
+// the audit logger and the per-document locks, so mutations of a document are
+// serialized across all services derived from this one.
+func (s *Service) WithStore(store store.VectorStore) *Service {
+	derived := *s
+	derived.store = store
+	return &derived
+}
+
+// SetAuditLogger records every mutating operation to logger
+func (s *Service) SetAuditLogger(logger audit.Logger) {
+	if logger == nil {
+		logger = audit.NoopLogger{}
+	}
+	s.audit = logger
+}
+
+// OnDocumentChanged calls fn with the ID of every document the service
+// writes, deletes, restores or purges, e.g. to invalidate cached reads
+func (s *Service) OnDocumentChanged(fn func(docID string)) {
+	s.onChange = fn
+}
+
+// SetModerator checks every chunk with moderator before it is stored, taking
+// action (moderation.ActionReject or ActionRedact) on flagged chunks
+func (s *Service) SetModerator(moderator moderation.Moderator, action string) {
+	s.moderator = moderator
+	s.moderationAction = action
+}
+
+// moderateChunks applies the moderation action to each chunk in place. A
+// redacted chunk loses its window too, since the window contains the chunk.
+func (s *Service) moderateChunks(ctx context.Context, chunks []types.DocumentChunk) error {
+	for i := range chunks {
+		content, err := moderation.Check(ctx, s.moderator, s.moderationAction, chunks[i].Content)
+		if err != nil {
+			return fmt.Errorf("chunk %d: %w", chunks[i].ChunkIndex, err)
+		}
+		if content != chunks[i].Content {
+			chunks[i].Content = content
+			chunks[i].Window = ""
+		}
+	}
+	return nil
+}
+
+// record writes an audit entry and reports the change to the listener. Audit
+// failures are logged rather than returned, since the operation itself has
+// already happened.
+func (s *Service) record(ctx context.Context, operation, docID string, chunkCount int) {
+	if s.onChange != nil {
+		s.onChange(docID)
+	}
+
+	err := s.audit.Log(ctx, audit.Entry{
+		Operation:  operation,
+		DocumentID: docID,
+		ChunkCount: chunkCount,
+	})
+	if err != nil {
+		log.Printf("Failed to record %s of document %s in audit log: %v", operation, docID, err)
 	}
 }
 
 // IngestDocument processes and stores a document
-func (s *Service) IngestDocument(ctx context.Context, docID string, content io.Reader) (int, error) {
+func (s *Service) IngestDocument(ctx context.Context, docID string, content io.Reader, metadata types.Metadata) (int, error) {
+	return s.ingestWithMetadata(ctx, docID, content, metadata, types.ChunkingOptions{})
+}
+
+// ingestWithMetadata chunks and stores a document, attaching metadata to every chunk
+func (s *Service) ingestWithMetadata(ctx context.Context, docID string, content io.Reader, metadata types.Metadata, opts types.ChunkingOptions) (int, error) {
+	docChunks, err := s.prepareChunks(ctx, docID, content, metadata, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	// A re-ingested document replaces every chunk of its previous version
+	if err := s.replaceChunks(ctx, docID, docChunks); err != nil {
+		return 0, err
+	}
+
+	s.record(ctx, audit.OperationIngest, docID, len(docChunks))
+	return len(docChunks), nil
+}
+
+// replaceChunks purges a document's chunks and stores docChunks in their
+// place, holding the document's lock, so no chunk of a previous version with
+// more chunks is left behind. If storing fails after the purge, the document
+// is left missing or partly stored.
+func (s *Service) replaceChunks(ctx context.Context, docID string, docChunks []types.DocumentChunk) error {
+	defer s.locks.lock(docID)()
+
+	if err := s.store.PurgeDocument(ctx, docID); err != nil {
+		return fmt.Errorf("failed to remove previous chunks: %w", err)
+	}
+	if err := s.store.StoreChunks(ctx, docChunks); err != nil {
+		s.record(ctx, audit.OperationPurge, docID, 0)
+		return fmt.Errorf("failed to store chunks, any previous version was removed: %w", err)
+	}
+	return nil
+}
+
+// UpdateDocument replaces a document with new content, creating it if it
+// doesn't exist. The content is chunked and moderated first, then the old
+// version is replaced as replaceChunks does. The two steps aren't atomic: if
+// storing fails after the purge, the update should be retried.
+func (s *Service) UpdateDocument(ctx context.Context, docID string, content io.Reader, metadata types.Metadata, opts types.ChunkingOptions) (int, error) {
+	docChunks, err := s.prepareChunks(ctx, docID, content, metadata, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.replaceChunks(ctx, docID, docChunks); err != nil {
+		return 0, err
+	}
+
+	s.record(ctx, audit.OperationUpdate, docID, len(docChunks))
+	return len(docChunks), nil
+}
+
+// UpdateText replaces a document with new text, as UpdateDocument does
+func (s *Service) UpdateText(ctx context.Context, docID, text string, metadata types.Metadata, opts types.ChunkingOptions) (int, error) {
+	return s.UpdateDocument(ctx, docID, strings.NewReader(text), metadata, opts)
+}
+
+// prepareChunks reads, chunks and moderates a document, returning the chunks
+// to store for it
+func (s *Service) prepareChunks(ctx context.Context, docID string, content io.Reader, metadata types.Metadata, opts types.ChunkingOptions) ([]types.DocumentChunk, error) {
+	chunker, strategy, err := s.chunkerFor(opts)
+	if err != nil {
+		return nil, err
+	}
+
 	// Read content
 	contentBytes, err := io.ReadAll(content)
 	if err != nil {
-		return 0, fmt.Errorf("failed to read content: %w", err)
+		return nil, fmt.Errorf("failed to read content: %w", err)
 	}
 	
 	text := string(contentBytes)
 
-	// Chunk the document using sentence-based chunking
-	chunks, err := s.chunker.ChunkBySentences(text)
-	if err != nil {
-		return 0, fmt.Errorf("failed to chunk document: %w", err)
+	// HTML is reduced to its readable text, with its title and meta tags as metadata
+	if metadata.ContentType == contentTypeHTML {
+		text, metadata = ExtractHTML(text, metadata)
 	}
-	
+
+	// Pull metadata such as front-matter and titles out of the document.
+	// Offsets still count from the start of the document, front-matter included.
+	offsetBase := 0
+	if s.config.ExtractMetadata {
+		var body string
+		body, metadata = ExtractMetadata(text, metadata)
+		if strings.HasSuffix(text, body) {
+			offsetBase = len(text) - len(body)
+		}
+		text = body
+	}
+
+	// Sentence windows match on single sentences but keep their surroundings.
+	// Other strategies report how much each chunk repeats of the one before.
+	var chunks, windows []string
+	var overlaps []int
+	if strategy == chunk.StrategySentence && s.config.SentenceWindow > 0 {
+		chunks, windows = chunker.SentenceWindows(text, s.config.SentenceWindow)
+	} else {
+		chunks, overlaps, err = chunker.ChunkWithOverlaps(strategy, text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to chunk document: %w", err)
+		}
+	}
+
+	// Markdown chunks repeat their headings, which aren't where the chunk is found
+	located := chunks
+	if strategy == chunk.StrategyMarkdown {
+		located = make([]string, len(chunks))
+		for i, content := range chunks {
+			located[i] = chunk.MarkdownBody(content)
+		}
+	}
+
+	// Locate each chunk in the original text for precise citations. HTML is
+	// chunked from its extracted text, so its chunks have no offsets into the
+	// markup that was ingested.
+	var spans []chunk.Span
+	if s.config.StoreOffsets && metadata.ContentType != contentTypeHTML {
+		spans = chunk.LocateChunks(text, located)
+	}
+
+	// Place markdown chunks under the headings they fall beneath
+	var paths [][]string
+	if s.config.MarkdownHierarchy && metadata.ContentType == contentTypeMarkdown {
+		paths = headingPaths(text, located, spans)
+	}
+
 	// Convert to document chunks
 	var docChunks []types.DocumentChunk
-	for i, chunk := range chunks {
-		docChunks = append(docChunks, types.DocumentChunk{
-			ID:         types.GenerateChunkID(docID, i),
-			DocumentID: docID,
-			Content:    chunk,
-			ChunkIndex: i,
-		})
-	}
-	
-	// Store chunks in vector database
-	err = s.store.StoreChunks(ctx, docChunks)
-	if err != nil {
-		return 0, err
+	for i, content := range chunks {
+		docChunk := types.DocumentChunk{
+			ID:            types.GenerateChunkID(docID, i),
+			DocumentID:    docID,
+			Content:       content,
+			ChunkIndex:    i,
+			TotalChunks:   len(chunks),
+			Metadata:      metadata,
+			ChunkStrategy: strategy,
+		}
+		if overlaps != nil {
+			docChunk.ChunkOverlap = overlaps[i]
+		}
+		if spans != nil {
+			docChunk.StartOffset = offsetBase + spans[i].Start
+			docChunk.EndOffset = offsetBase + spans[i].End
+		}
+		if windows != nil {
+			docChunk.Window = windows[i]
+		}
+		if paths != nil {
+			docChunk.Metadata = withHeadingPath(metadata, paths[i])
+		}
+		docChunks = append(docChunks, docChunk)
 	}
 
-	return len(chunks), nil
+	if err := s.moderateChunks(ctx, docChunks); err != nil {
+		return nil, err
+	}
+	return docChunks, nil
 }
 
 // IngestText processes and stores raw text
-func (s *Service) IngestText(ctx context.Context, docID, text string) (int, error) {
-	return s.IngestDocument(ctx, docID, strings.NewReader(text))
+func (s *Service) IngestText(ctx context.Context, docID, text string, metadata types.Metadata) (int, error) {
+	return s.IngestDocument(ctx, docID, strings.NewReader(text), metadata)
 }
 
-// DeleteDocument removes a document and all its chunks
+// IngestTextWithOptions processes and stores raw text, chunking it with the
+// given per-document overrides
+func (s *Service) IngestTextWithOptions(ctx context.Context, docID, text string, metadata types.Metadata, opts types.ChunkingOptions) (int, error) {
+	return s.ingestWithMetadata(ctx, docID, strings.NewReader(text), metadata, opts)
+}
+
+// chunkerFor returns the chunker and strategy to use for a document, applying
+// any per-request overrides on top of the server defaults. A default overlap
+// that doesn't fit a smaller chunk size is shrunk; an explicit one is rejected.
+func (s *Service) chunkerFor(opts types.ChunkingOptions) (*chunk.Service, string, error) {
+	strategy := opts.Strategy
+	if strategy == "" {
+		strategy = s.config.Strategy
+	}
+	if strategy == "" {
+		strategy = chunk.StrategySentence
+	}
+	switch strategy {
+	case chunk.StrategyFixed, chunk.StrategySentence, chunk.StrategyParagraph, chunk.StrategyRecursive, chunk.StrategyMarkdown:
+	default:
+		return nil, "", fmt.Errorf("%w: unknown strategy %q", ErrInvalidChunking, strategy)
+	}
+
+	if opts.ChunkSize == 0 && opts.ChunkOverlap == nil {
+		return &s.chunker, strategy, nil
+	}
+
+	size := s.chunker.Size()
+	if opts.ChunkSize != 0 {
+		if opts.ChunkSize < 0 || opts.ChunkSize > maxChunkSize {
+			return nil, "", fmt.Errorf("%w: chunk_size must be between 1 and %d", ErrInvalidChunking, maxChunkSize)
+		}
+		size = opts.ChunkSize
+	}
+
+	overlap := s.chunker.Overlap()
+	if opts.ChunkOverlap != nil {
+		overlap = *opts.ChunkOverlap
+		if overlap < 0 || overlap >= size {
+			return nil, "", fmt.Errorf("%w: chunk_overlap must be at least 0 and less than chunk_size (%d)", ErrInvalidChunking, size)
+		}
+	}
+
+	return s.chunker.WithSize(size, overlap), strategy, nil
+}
+
+// IngestBatch ingests documents one at a time, in order, reporting each
+// outcome to onResult as soon as it completes. A failed document does not
+// stop the batch; cancelling ctx does, and its error is returned.
+func (s *Service) IngestBatch(ctx context.Context, docs []types.IngestRequest, onResult func(types.IngestEvent)) error {
+	for _, doc := range docs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		event := types.IngestEvent{DocumentID: doc.DocumentID}
+		chunksCount, err := s.IngestTextWithOptions(ctx, doc.DocumentID, doc.Content, doc.Metadata, doc.ChunkingOptions)
+		if err != nil {
+			event.Status = "failed"
+			event.Error = err.Error()
+		} else {
+			event.Status = "success"
+			event.ChunksCount = chunksCount
+		}
+		onResult(event)
+	}
+
+	return ctx.Err()
+}
+
+// DeleteDocument removes a document and all its chunks, returning
+// ErrDocumentNotFound when it has none that aren't deleted already
 func (s *Service) DeleteDocument(ctx context.Context, docID string) error {
-	return s.store.DeleteDocument(ctx, docID)
+	defer s.locks.lock(docID)()
+
+	if err := s.checkDocumentExists(ctx, docID, false); err != nil {
+		return err
+	}
+	if err := s.store.DeleteDocument(ctx, docID); err != nil {
+		return err
+	}
+	s.record(ctx, audit.OperationDelete, docID, 0)
+	return nil
+}
+
+// RestoreDocument brings back a soft-deleted document
+func (s *Service) RestoreDocument(ctx context.Context, docID string) error {
+	defer s.locks.lock(docID)()
+
+	if err := s.store.RestoreDocument(ctx, docID); err != nil {
+		return err
+	}
+	s.record(ctx, audit.OperationRestore, docID, 0)
+	return nil
+}
+
+// PurgeDocument permanently removes a document, including soft-deleted
+// chunks, returning ErrDocumentNotFound when it has no chunks at all
+func (s *Service) PurgeDocument(ctx context.Context, docID string) error {
+	defer s.locks.lock(docID)()
+
+	if err := s.checkDocumentExists(ctx, docID, true); err != nil {
+		return err
+	}
+	if err := s.store.PurgeDocument(ctx, docID); err != nil {
+		return err
+	}
+	s.record(ctx, audit.OperationPurge, docID, 0)
+	return nil
+}
+
+// checkDocumentExists returns ErrDocumentNotFound when a document has no
+// chunks, counting soft-deleted ones with withDeleted. Stores that can't count
+// are checked by reading the document's live chunks, and since those can't
+// see soft-deleted chunks, a purge on them is never refused.
+func (s *Service) checkDocumentExists(ctx context.Context, docID string, withDeleted bool) error {
+	if counter, ok := s.store.(store.DocumentCounter); ok {
+		count, err := counter.CountDocumentChunks(ctx, docID, withDeleted)
+		if err != nil {
+			return fmt.Errorf("failed to check document %s: %w", docID, err)
+		}
+		if count == 0 {
+			return fmt.Errorf("%w: %s", ErrDocumentNotFound, docID)
+		}
+		return nil
+	}
+	if withDeleted {
+		return nil
+	}
+
+	chunks, err := s.store.GetChunksByDocumentID(ctx, docID)
+	if err != nil {
+		return fmt.Errorf("failed to check document %s: %w", docID, err)
+	}
+	if len(chunks) == 0 {
+		return fmt.Errorf("%w: %s", ErrDocumentNotFound, docID)
+	}
+	return nil
+}
+
+// ReindexDocuments re-embeds and re-stores the chunks of each document, for
+// example after switching embedding models. With no IDs, every document is
+// reindexed if the store can list them. progress is called after each document.
+func (s *Service) ReindexDocuments(ctx context.Context, docIDs []string, progress func(done, total int)) error {
+	if len(docIDs) == 0 {
+		lister, ok := s.store.(store.DocumentLister)
+		if !ok {
+			return fmt.Errorf("store cannot list documents; document IDs are required")
+		}
+		ids, err := lister.ListDocumentIDs(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list documents: %w", err)
+		}
+		docIDs = ids
+	}
+
+	progress(0, len(docIDs))
+	for i, docID := range docIDs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := s.reindexDocument(ctx, docID); err != nil {
+			return err
+		}
+
+		progress(i+1, len(docIDs))
+	}
+
+	return nil
+}
+
+// reindexDocument re-stores one document's chunks while holding its lock, so a
+// concurrent re-ingest can't be overwritten with the chunks read here
+func (s *Service) reindexDocument(ctx context.Context, docID string) error {
+	defer s.locks.lock(docID)()
+
+	chunks, err := s.store.GetChunksByDocumentID(ctx, docID)
+	if err != nil {
+		return fmt.Errorf("failed to load document %s: %w", docID, err)
+	}
+	if len(chunks) > 0 {
+		if err := s.store.StoreChunks(ctx, chunks); err != nil {
+			return fmt.Errorf("failed to reindex document %s: %w", docID, err)
+		}
+	}
+	return nil
 }
 
 // IngestDirectory processes and stores all files from a directory
 func (s *Service) IngestDirectory(ctx context.Context, req types.DirectoryIngestRequest) (*types.DirectoryIngestResponse, error) {
+	return s.IngestDirectoryWithProgress(ctx, req, nil)
+}
+
+// IngestDirectoryWithProgress ingests a directory while it is walked: files
+// are collected in batches and each batch is processed before the walk goes
+// on, so only one batch of paths is held at a time. progress, if not nil, is
+// called after each batch. Files already ingested stay ingested if the walk
+// fails or ctx is canceled part way.
+func (s *Service) IngestDirectoryWithProgress(ctx context.Context, req types.DirectoryIngestRequest, progress func(types.DirectoryIngestProgress)) (*types.DirectoryIngestResponse, error) {
 	start := time.Now()
 
-	// Scan directory for files
-	files, err := s.scanDirectory(req.DirectoryPath, req.Recursive, req.FilePattern)
-	if err != nil {
-		return nil, fmt.Errorf("failed to scan directory: %w", err)
+	limit := s.directoryFileLimit(req.MaxFiles)
+	batchSize := s.directoryBatchSize(req.BatchSize)
+	response := &types.DirectoryIngestResponse{
+		DirectoryPath: req.DirectoryPath,
+		FileLimit:     limit,
 	}
 
-	var successfulIngestions []types.IngestResponse
-	var errors []string
+	batch := make([]string, 0, batchSize)
+	processBatch := func() {
+		for _, filePath := range batch {
+			result := s.processFile(ctx, filePath, req.Metadata)
+			if result.Error != "" {
+				response.FailedFiles++
+				response.Errors = append(response.Errors, fmt.Sprintf("%s: %s", result.FilePath, result.Error))
+				continue
+			}
+			response.SucceededFiles++
+			if !req.SummaryOnly {
+				response.SuccessfulIngestions = append(response.SuccessfulIngestions, types.IngestResponse{
+					DocumentID: result.DocumentID,
+					Status:     result.Status,
+				})
+			}
+		}
 
-	// Process each file
-	for _, filePath := range files {
-		result := s.processFile(ctx, filePath, req.Metadata)
-		if result.Error != "" {
-			errors = append(errors, fmt.Sprintf("%s: %s", result.FilePath, result.Error))
-		} else {
-			successfulIngestions = append(successfulIngestions, types.IngestResponse{
-				DocumentID:     result.DocumentID,
-				Status:         result.Status,
-				ProcessingTime: "", // Individual file processing time could be added if needed
+		response.ProcessedFiles += len(batch)
+		response.Batches++
+		if progress != nil {
+			progress(types.DirectoryIngestProgress{
+				Batch:          response.Batches,
+				BatchFiles:     len(batch),
+				ProcessedFiles: response.ProcessedFiles,
+				FailedFiles:    response.FailedFiles,
 			})
 		}
+
+		// Clear the paths so the processed batch can be collected
+		clear(batch)
+		batch = batch[:0]
+	}
+
+	// Walk the directory, stopping at the requested or server limit
+	limitReached, err := s.walkDirectory(req.DirectoryPath, req.Recursive, req.FilePattern, limit, func(path string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		batch = append(batch, path)
+		if len(batch) == batchSize {
+			processBatch()
+		}
+		return nil
+	})
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, fmt.Errorf("directory ingest stopped after %d files: %w", response.ProcessedFiles, ctxErr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan directory: %w", err)
+	}
+	if len(batch) > 0 {
+		processBatch()
 	}
 
-	return &types.DirectoryIngestResponse{
-		DirectoryPath:        req.DirectoryPath,
-		ProcessedFiles:       len(files),
-		SuccessfulIngestions: successfulIngestions,
-		Errors:               errors,
-		ProcessingTime:       time.Since(start).String(),
-	}, nil
+	response.FileLimitReached = limitReached
+	response.ProcessingTime = time.Since(start).String()
+	return response, nil
 }
 
-// scanDirectory scans a directory for files matching the pattern
-func (s *Service) scanDirectory(dirPath string, recursive bool, pattern string) ([]string, error) {
-	var files []string
+// directoryBatchSize returns the requested batch size, or the server's, or
+// defaultDirectoryBatchSize when neither is set
+func (s *Service) directoryBatchSize(requested int) int {
+	switch {
+	case requested > 0:
+		return requested
+	case s.config.DirectoryBatchSize > 0:
+		return s.config.DirectoryBatchSize
+	default:
+		return defaultDirectoryBatchSize
+	}
+}
 
-	// Check if directory exists
-	if _, err := os.Stat(dirPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("directory does not exist: %s", dirPath)
+// directoryFileLimit returns the smaller of the requested and server file
+// limits, where 0 means no limit
+func (s *Service) directoryFileLimit(requested int) int {
+	limit := s.config.MaxDirectoryFiles
+	if requested > 0 && (limit <= 0 || requested < limit) {
+		limit = requested
+	}
+	return max(limit, 0)
+}
+
+// walkDirectory calls fn for each file in a directory matching the pattern,
+// in walk order, stopping at the first error fn returns. With a limit > 0 the
+// walk stops when a file past the limit matches, and the result reports that
+// matching files were left out.
+func (s *Service) walkDirectory(dirPath string, recursive bool, pattern string, limit int, fn func(path string) error) (bool, error) {
+	matched := 0
+
+	// Check the directory exists and is readable; errors wrap fs.ErrNotExist or fs.ErrPermission
+	info, err := os.Stat(dirPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, fmt.Errorf("directory does not exist: %s: %w", dirPath, err)
+	}
+	if err != nil {
+		return false, fmt.Errorf("cannot access directory %s: %w", dirPath, err)
+	}
+	if !info.IsDir() {
+		return false, fmt.Errorf("%w: %s", ErrNotDirectory, dirPath)
 	}
 
 	// Walk through directory
-	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
+	err = filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -136,24 +628,32 @@ func (s *Service) scanDirectory(dirPath string, recursive bool, pattern string)
 
 		// Check file pattern if specified
 		if pattern != "" {
-			matched, err := s.matchesPattern(filepath.Base(path), pattern)
+			ok, err := s.matchesPattern(filepath.Base(path), pattern)
 			if err != nil {
 				return fmt.Errorf("pattern matching error: %w", err)
 			}
-			if !matched {
+			if !ok {
 				return nil
 			}
 		}
 
-		files = append(files, path)
-		return nil
+		// Stop at the next match after the limit, so reaching the limit exactly isn't reported
+		if limit > 0 && matched == limit {
+			return errFileLimit
+		}
+
+		matched++
+		return fn(path)
 	})
 
+	if errors.Is(err, errFileLimit) {
+		return true, nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("error walking directory: %w", err)
+		return false, fmt.Errorf("error walking directory: %w", err)
 	}
 
-	return files, nil
+	return false, nil
 }
 
 // processFile processes a single file and returns the result
@@ -182,8 +682,24 @@ func (s *Service) processFile(ctx context.Context, filePath string, metadata typ
 		}
 	}
 
-	// Ingest the text content
-	_, err = s.IngestText(ctx, docID, string(content))
+	// Ingest the text content, typed by extension so metadata can be extracted
+	if metadata.ContentType == "" {
+		metadata.ContentType = contentTypeForPath(filePath)
+	}
+	// PDFs are ingested as the text of their pages
+	if metadata.ContentType == contentTypePDF {
+		text, err := pdfText(content, s.maxInflated())
+		if err != nil {
+			return types.FileIngestResult{
+				FilePath:   filePath,
+				DocumentID: docID,
+				Status:     "failed",
+				Error:      fmt.Sprintf("failed to extract text: %v", err),
+			}
+		}
+		content = []byte(text)
+	}
+	_, err = s.ingestWithMetadata(ctx, docID, bytes.NewReader(content), metadata, types.ChunkingOptions{})
 	if err != nil {
 		return types.FileIngestResult{
 			FilePath:   filePath,
@@ -0,0 +1,677 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-rag/internal/audit"
+	"go-rag/internal/chunk"
+	"go-rag/internal/moderation"
+	"go-rag/internal/types"
+)
+
+// fakeStore is an in-memory VectorStore that records stored chunks
+type fakeStore struct {
+	mu      sync.Mutex
+	chunks  map[uint64]types.DocumentChunk
+	onStore func(chunks []types.DocumentChunk)
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{chunks: make(map[uint64]types.DocumentChunk)}
+}
+
+func (f *fakeStore) StoreChunks(ctx context.Context, chunks []types.DocumentChunk) error {
+	if f.onStore != nil {
+		f.onStore(chunks)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range chunks {
+		f.chunks[c.ID] = c
+	}
+	return nil
+}
+
+func (f *fakeStore) SearchSimilar(ctx context.Context, query string, limit int, vectorName string, filters map[string]string) ([]types.DocumentChunk, error) {
+	return nil, nil
+}
+
+func (f *fakeStore) GetChunksByDocumentID(ctx context.Context, documentID string) ([]types.DocumentChunk, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var result []types.DocumentChunk
+	for _, c := range f.chunks {
+		if c.DocumentID == documentID {
+			result = append(result, c)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeStore) GetChunkByID(ctx context.Context, chunkID uint64) (*types.DocumentChunk, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.chunks[chunkID]
+	if !ok {
+		return nil, fmt.Errorf("chunk not found: %d", chunkID)
+	}
+	return &c, nil
+}
+
+func (f *fakeStore) DeleteDocument(ctx context.Context, documentID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for id, c := range f.chunks {
+		if c.DocumentID == documentID {
+			delete(f.chunks, id)
+		}
+	}
+	return nil
+}
+
+func (f *fakeStore) RestoreDocument(ctx context.Context, documentID string) error {
+	return nil
+}
+
+func (f *fakeStore) PurgeDocument(ctx context.Context, documentID string) error {
+	return f.DeleteDocument(ctx, documentID)
+}
+
+func (f *fakeStore) DeleteChunk(ctx context.Context, chunkID uint64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.chunks, chunkID)
+	return nil
+}
+
+func (f *fakeStore) HealthCheck(ctx context.Context) error {
+	return nil
+}
+
+func (f *fakeStore) CreateCollection(ctx context.Context, vectorSize int) error {
+	return nil
+}
+
+func newTestService(store *fakeStore) *Service {
+	return NewService(*chunk.NewService(100, 20), store, types.ChunkingConfig{ChunkSize: 100, ChunkOverlap: 20})
+}
+
+func TestIngestBatch_ReportsInOrder(t *testing.T) {
+	service := newTestService(newFakeStore())
+
+	docs := []types.IngestRequest{
+		{DocumentID: "doc-1", Content: "First document."},
+		{DocumentID: "doc-2", Content: "Second document. It has two sentences."},
+		{DocumentID: "doc-3", Content: "Third document."},
+	}
+
+	var events []types.IngestEvent
+	err := service.IngestBatch(context.Background(), docs, func(event types.IngestEvent) {
+		events = append(events, event)
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(events) != len(docs) {
+		t.Fatalf("Expected %d events, got %d", len(docs), len(events))
+	}
+	for i, event := range events {
+		if event.DocumentID != docs[i].DocumentID || event.Status != "success" {
+			t.Errorf("Event %d: expected success for %s, got %+v", i, docs[i].DocumentID, event)
+		}
+	}
+}
+
+func TestIngestBatch_StopsWhenCancelled(t *testing.T) {
+	store := newFakeStore()
+	service := newTestService(store)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Simulate the client disconnecting while the first document is stored
+	store.onStore = func([]types.DocumentChunk) { cancel() }
+
+	docs := []types.IngestRequest{
+		{DocumentID: "doc-1", Content: "First document."},
+		{DocumentID: "doc-2", Content: "Second document."},
+	}
+
+	var events []types.IngestEvent
+	err := service.IngestBatch(ctx, docs, func(event types.IngestEvent) {
+		events = append(events, event)
+	})
+	if err == nil {
+		t.Error("Expected cancellation error, got nil")
+	}
+
+	if len(events) != 1 {
+		t.Errorf("Expected processing to stop after 1 document, got %d events", len(events))
+	}
+}
+
+// recordingAuditLogger keeps audit entries in memory
+type recordingAuditLogger struct {
+	entries []audit.Entry
+}
+
+func (r *recordingAuditLogger) Log(ctx context.Context, entry audit.Entry) error {
+	entry.Principal = audit.PrincipalFromContext(ctx)
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func (r *recordingAuditLogger) Close() error { return nil }
+
+func TestAuditLog_RecordsIngestAndDelete(t *testing.T) {
+	logger := &recordingAuditLogger{}
+	service := newTestService(newFakeStore())
+	service.SetAuditLogger(logger)
+
+	ctx := audit.WithPrincipal(context.Background(), "alice")
+	chunksCount, err := service.IngestText(ctx, "doc-1", "First sentence. Second sentence.", types.Metadata{})
+	if err != nil {
+		t.Fatalf("IngestText failed: %v", err)
+	}
+	if err := service.DeleteDocument(ctx, "doc-1"); err != nil {
+		t.Fatalf("DeleteDocument failed: %v", err)
+	}
+
+	if len(logger.entries) != 2 {
+		t.Fatalf("Expected 2 audit entries, got %d", len(logger.entries))
+	}
+
+	ingested, deleted := logger.entries[0], logger.entries[1]
+	if ingested.Operation != audit.OperationIngest || ingested.DocumentID != "doc-1" || ingested.ChunkCount != chunksCount {
+		t.Errorf("Unexpected ingest entry: %+v", ingested)
+	}
+	if deleted.Operation != audit.OperationDelete || deleted.DocumentID != "doc-1" {
+		t.Errorf("Unexpected delete entry: %+v", deleted)
+	}
+	if ingested.Principal != "alice" || deleted.Principal != "alice" {
+		t.Errorf("Expected principal alice on both entries, got %q and %q", ingested.Principal, deleted.Principal)
+	}
+}
+
+func TestOnDocumentChanged_ReportsWrites(t *testing.T) {
+	service := newTestService(newFakeStore())
+	var changed []string
+	service.OnDocumentChanged(func(docID string) {
+		changed = append(changed, docID)
+	})
+
+	ctx := context.Background()
+	if _, err := service.IngestText(ctx, "doc-1", "First sentence. Second sentence.", types.Metadata{}); err != nil {
+		t.Fatalf("IngestText failed: %v", err)
+	}
+	if _, err := service.IngestText(ctx, "doc-2", "Another sentence.", types.Metadata{}); err != nil {
+		t.Fatalf("IngestText failed: %v", err)
+	}
+	if err := service.DeleteDocument(ctx, "doc-2"); err != nil {
+		t.Fatalf("DeleteDocument failed: %v", err)
+	}
+
+	if strings.Join(changed, ",") != "doc-1,doc-2,doc-2" {
+		t.Errorf("Expected changes to doc-1 and doc-2, got %v", changed)
+	}
+}
+
+func TestDeleteDocument_NotFound(t *testing.T) {
+	store := newFakeStore()
+	service := newTestService(store)
+	ctx := context.Background()
+
+	if err := service.DeleteDocument(ctx, "missing"); !errors.Is(err, ErrDocumentNotFound) {
+		t.Errorf("Expected ErrDocumentNotFound, got %v", err)
+	}
+
+	if _, err := service.IngestText(ctx, "doc-1", "First sentence. Second sentence.", types.Metadata{}); err != nil {
+		t.Fatalf("IngestText failed: %v", err)
+	}
+	if err := service.DeleteDocument(ctx, "doc-1"); err != nil {
+		t.Fatalf("DeleteDocument failed: %v", err)
+	}
+	if err := service.DeleteDocument(ctx, "doc-1"); !errors.Is(err, ErrDocumentNotFound) {
+		t.Errorf("Expected a second delete to find nothing, got %v", err)
+	}
+}
+
+func TestIngestTextWithOptions_OverridesChunking(t *testing.T) {
+	text := strings.Repeat("Chunking overrides apply per request. ", 20)
+	ctx := context.Background()
+	service := newTestService(newFakeStore())
+
+	defaultCount, err := service.IngestText(ctx, "doc-default", text, types.Metadata{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	smallCount, err := service.IngestTextWithOptions(ctx, "doc-small", text, types.Metadata{}, types.ChunkingOptions{ChunkSize: 40})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if smallCount <= defaultCount {
+		t.Errorf("Expected a smaller chunk size to produce more chunks, got %d vs %d by default", smallCount, defaultCount)
+	}
+
+	overlap := 5
+	_, err = service.IngestTextWithOptions(ctx, "doc-fixed", text, types.Metadata{}, types.ChunkingOptions{ChunkSize: 60, ChunkOverlap: &overlap, Strategy: chunk.StrategyFixed})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	chunks, _ := service.store.GetChunksByDocumentID(ctx, "doc-fixed")
+	if len(chunks) == 0 || chunks[0].ChunkStrategy != chunk.StrategyFixed || chunks[0].ChunkOverlap != 5 {
+		t.Errorf("Expected fixed chunks recording an overlap of 5, got %+v", chunks)
+	}
+
+	tooLarge := 60
+	invalid := []types.ChunkingOptions{
+		{ChunkSize: -1},
+		{ChunkSize: maxChunkSize + 1},
+		{ChunkSize: 60, ChunkOverlap: &tooLarge},
+		{Strategy: "semantic"},
+	}
+	for _, opts := range invalid {
+		if _, err := service.IngestTextWithOptions(ctx, "doc-invalid", text, types.Metadata{}, opts); !errors.Is(err, ErrInvalidChunking) {
+			t.Errorf("Expected ErrInvalidChunking for %+v, got %v", opts, err)
+		}
+	}
+}
+
+func TestIngestChunksWithVectors(t *testing.T) {
+	store := newFakeStore()
+	service := newTestService(store)
+
+	count, err := service.IngestChunksWithVectors(context.Background(), types.VectorIngestRequest{
+		DocumentID: "doc-1",
+		Chunks: []types.VectorChunk{
+			{Content: "first chunk", Embedding: []float64{0.1, 0.2}},
+			{Content: "second chunk", Embedding: []float64{0.3, 0.4}},
+		},
+		Metadata: types.Metadata{Title: "Vectors"},
+	})
+	if err != nil || count != 2 {
+		t.Fatalf("Expected 2 chunks stored, got %d, %v", count, err)
+	}
+
+	chunk, err := store.GetChunkByID(context.Background(), types.GenerateChunkID("doc-1", 1))
+	if err != nil {
+		t.Fatalf("Expected the second chunk to be stored: %v", err)
+	}
+	if chunk.Embedding[1] != 0.4 || chunk.ChunkIndex != 1 || chunk.TotalChunks != 2 || chunk.Metadata.Title != "Vectors" {
+		t.Errorf("Unexpected stored chunk: %+v", chunk)
+	}
+
+	_, err = service.IngestChunksWithVectors(context.Background(), types.VectorIngestRequest{
+		DocumentID: "doc-2",
+		Chunks:     []types.VectorChunk{{Content: "no vector"}},
+	})
+	if !errors.Is(err, ErrInvalidVectors) {
+		t.Errorf("Expected ErrInvalidVectors for a chunk without an embedding, got %v", err)
+	}
+}
+
+func TestIngestText_UsesConfiguredStrategy(t *testing.T) {
+	text := "First paragraph.\n\nSecond paragraph."
+	ctx := context.Background()
+	store := newFakeStore()
+	service := NewService(*chunk.NewService(100, 20), store, types.ChunkingConfig{ChunkSize: 100, ChunkOverlap: 20, Strategy: chunk.StrategyParagraph})
+
+	count, err := service.IngestText(ctx, "doc-paragraphs", text, types.Metadata{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected one chunk per paragraph, got %d", count)
+	}
+	chunks, _ := store.GetChunksByDocumentID(ctx, "doc-paragraphs")
+	if len(chunks) == 0 || chunks[0].ChunkStrategy != chunk.StrategyParagraph {
+		t.Errorf("Expected chunks recorded as paragraph chunks, got %+v", chunks)
+	}
+
+	// A per-request strategy still takes precedence over the configured one
+	count, err = service.IngestTextWithOptions(ctx, "doc-sentences", text, types.Metadata{}, types.ChunkingOptions{Strategy: chunk.StrategySentence})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected the sentence strategy to merge both paragraphs into one chunk, got %d", count)
+	}
+}
+
+func TestIngestText_SerializesConcurrentReingests(t *testing.T) {
+	store := newFakeStore()
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	store.onStore = func(chunks []types.DocumentChunk) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}
+	service := newTestService(store)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(version int) {
+			defer wg.Done()
+			text := strings.Repeat(fmt.Sprintf("Version %d of the document. ", version), 10)
+			if _, err := service.WithStore(store).IngestText(ctx, "doc-1", text, types.Metadata{}); err != nil {
+				t.Errorf("IngestText failed: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if maxInFlight != 1 {
+		t.Errorf("Expected re-ingests of one document to be serialized, saw %d at once", maxInFlight)
+	}
+	chunks, _ := store.GetChunksByDocumentID(ctx, "doc-1")
+	if len(chunks) == 0 || len(chunks) != chunks[0].TotalChunks {
+		t.Fatalf("Expected one complete version of the document, got %d chunks", len(chunks))
+	}
+	version := chunks[0].Content[:len("Version 0")]
+	for _, c := range chunks {
+		if !strings.HasPrefix(c.Content, version) {
+			t.Errorf("Expected every chunk from %q, got %q", version, c.Content)
+		}
+	}
+	if len(service.locks.locks) != 0 {
+		t.Errorf("Expected idle document locks to be released, %d remain", len(service.locks.locks))
+	}
+}
+
+func TestIngestText_AttachesMetadataToChunks(t *testing.T) {
+	store := newFakeStore()
+	service := newTestService(store)
+	ctx := context.Background()
+	metadata := types.Metadata{
+		Title:  "Guide",
+		Author: "Docs Team",
+		Tags:   []string{"setup", "linux"},
+		Custom: map[string]string{"version": "2"},
+	}
+
+	text := strings.Repeat("Metadata travels with every chunk. ", 10)
+	if _, err := service.IngestText(ctx, "doc-single", text, metadata); err != nil {
+		t.Fatalf("IngestText failed: %v", err)
+	}
+	err := service.IngestBatch(ctx, []types.IngestRequest{{DocumentID: "doc-batch", Content: text, Metadata: metadata}}, func(types.IngestEvent) {})
+	if err != nil {
+		t.Fatalf("IngestBatch failed: %v", err)
+	}
+
+	for _, docID := range []string{"doc-single", "doc-batch"} {
+		chunks, _ := store.GetChunksByDocumentID(ctx, docID)
+		if len(chunks) < 2 {
+			t.Fatalf("%s: expected several chunks, got %d", docID, len(chunks))
+		}
+		for _, c := range chunks {
+			if c.Metadata.Title != "Guide" || c.Metadata.Author != "Docs Team" ||
+				len(c.Metadata.Tags) != 2 || c.Metadata.Custom["version"] != "2" {
+				t.Errorf("%s: chunk %d lost its metadata: %+v", docID, c.ChunkIndex, c.Metadata)
+			}
+		}
+	}
+}
+
+func TestIngestText_StripsHTML(t *testing.T) {
+	store := newFakeStore()
+	service := NewService(*chunk.NewService(100, 20), store, types.ChunkingConfig{ChunkSize: 100, ChunkOverlap: 20, StoreOffsets: true})
+	ctx := context.Background()
+
+	page := `<html><head><title>Guide</title><meta name="author" content="Docs Team"></head>` +
+		`<body><script>track()</script><p>Install with <a href="/get">the installer</a>.</p></body></html>`
+	if _, err := service.IngestText(ctx, "page", page, types.Metadata{ContentType: "text/html"}); err != nil {
+		t.Fatalf("IngestText failed: %v", err)
+	}
+
+	chunks, _ := store.GetChunksByDocumentID(ctx, "page")
+	if len(chunks) != 1 || chunks[0].Content != "Install with the installer." {
+		t.Fatalf("Expected the page's text only, got %+v", chunks)
+	}
+	if chunks[0].Metadata.Title != "Guide" || chunks[0].Metadata.Author != "Docs Team" {
+		t.Errorf("Expected the title and author from the page, got %+v", chunks[0].Metadata)
+	}
+	// Offsets into the extracted text wouldn't point into the ingested markup
+	if chunks[0].StartOffset != 0 || chunks[0].EndOffset != 0 {
+		t.Errorf("Expected no offsets for HTML, got %d-%d", chunks[0].StartOffset, chunks[0].EndOffset)
+	}
+}
+
+func TestIngestDirectory_StopsAtFileLimit(t *testing.T) {
+	dir := t.TempDir()
+	for i := range 5 {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("doc-%d.txt", i)), []byte("Some text."), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	store := newFakeStore()
+	service := NewService(*chunk.NewService(100, 20), store, types.ChunkingConfig{ChunkSize: 100, ChunkOverlap: 20, MaxDirectoryFiles: 3})
+
+	result, err := service.IngestDirectory(context.Background(), types.DirectoryIngestRequest{DirectoryPath: dir})
+	if err != nil {
+		t.Fatalf("IngestDirectory failed: %v", err)
+	}
+	if result.ProcessedFiles != 3 || !result.FileLimitReached || result.FileLimit != 3 {
+		t.Errorf("Expected the server cap to stop the scan at 3 files, got %+v", result)
+	}
+
+	// A request can lower the limit but not raise it past the server cap
+	result, err = service.IngestDirectory(context.Background(), types.DirectoryIngestRequest{DirectoryPath: dir, MaxFiles: 2})
+	if err != nil {
+		t.Fatalf("IngestDirectory failed: %v", err)
+	}
+	if result.ProcessedFiles != 2 || result.FileLimit != 2 {
+		t.Errorf("Expected the request limit to stop the scan at 2 files, got %+v", result)
+	}
+	result, err = service.IngestDirectory(context.Background(), types.DirectoryIngestRequest{DirectoryPath: dir, MaxFiles: 10})
+	if err != nil {
+		t.Fatalf("IngestDirectory failed: %v", err)
+	}
+	if result.ProcessedFiles != 3 {
+		t.Errorf("Expected the server cap to win over a larger request limit, got %d files", result.ProcessedFiles)
+	}
+
+	// Finding exactly as many files as the limit isn't reported as hitting it
+	service.config.MaxDirectoryFiles = 0
+	result, err = service.IngestDirectory(context.Background(), types.DirectoryIngestRequest{DirectoryPath: dir, MaxFiles: 5})
+	if err != nil {
+		t.Fatalf("IngestDirectory failed: %v", err)
+	}
+	if result.ProcessedFiles != 5 || result.FileLimitReached {
+		t.Errorf("Expected all 5 files without hitting the limit, got %+v", result)
+	}
+}
+
+func TestIngestDirectory_ProcessesInBatches(t *testing.T) {
+	dir := t.TempDir()
+	for i := range 205 {
+		sub := filepath.Join(dir, fmt.Sprintf("part-%d", i%7))
+		if err := os.MkdirAll(sub, 0o755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(sub, fmt.Sprintf("doc-%d.txt", i)), []byte("Some text."), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	store := newFakeStore()
+	service := NewService(*chunk.NewService(100, 20), store, types.ChunkingConfig{ChunkSize: 100, ChunkOverlap: 20, DirectoryBatchSize: 50})
+
+	var updates []types.DirectoryIngestProgress
+	result, err := service.IngestDirectoryWithProgress(context.Background(), types.DirectoryIngestRequest{DirectoryPath: dir, Recursive: true, BatchSize: 10, SummaryOnly: true}, func(p types.DirectoryIngestProgress) {
+		updates = append(updates, p)
+	})
+	if err != nil {
+		t.Fatalf("IngestDirectory failed: %v", err)
+	}
+
+	// The request's batch size wins over the server's, and no batch holds more
+	if len(updates) != 21 || result.Batches != 21 {
+		t.Fatalf("Expected 21 batches of at most 10 files, got %d updates and %d batches", len(updates), result.Batches)
+	}
+	for i, p := range updates {
+		if p.Batch != i+1 || p.BatchFiles > 10 || p.ProcessedFiles != min((i+1)*10, 205) {
+			t.Errorf("Unexpected progress update %d: %+v", i, p)
+		}
+	}
+	if result.ProcessedFiles != 205 || result.SucceededFiles != 205 || result.FailedFiles != 0 {
+		t.Errorf("Expected all 205 files to succeed, got %+v", result)
+	}
+	if len(result.SuccessfulIngestions) != 0 {
+		t.Errorf("Expected summary_only to leave out the per-document list, got %d entries", len(result.SuccessfulIngestions))
+	}
+
+	// Without a request batch size the server's applies
+	result, err = service.IngestDirectory(context.Background(), types.DirectoryIngestRequest{DirectoryPath: dir, Recursive: true})
+	if err != nil {
+		t.Fatalf("IngestDirectory failed: %v", err)
+	}
+	if result.Batches != 5 || len(result.SuccessfulIngestions) != 205 {
+		t.Errorf("Expected 5 batches listing 205 documents, got %d batches and %d documents", result.Batches, len(result.SuccessfulIngestions))
+	}
+}
+
+func TestIngestDirectory_StopsWhenCanceled(t *testing.T) {
+	dir := t.TempDir()
+	for i := range 30 {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("doc-%d.txt", i)), []byte("Some text."), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	service := NewService(*chunk.NewService(100, 20), newFakeStore(), types.ChunkingConfig{ChunkSize: 100, ChunkOverlap: 20})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Cancel once the first batch is done; the walk stops before the next
+	_, err := service.IngestDirectoryWithProgress(ctx, types.DirectoryIngestRequest{DirectoryPath: dir, BatchSize: 10}, func(types.DirectoryIngestProgress) {
+		cancel()
+	})
+	if !errors.Is(err, context.Canceled) || !strings.Contains(err.Error(), "after 10 files") {
+		t.Errorf("Expected the ingest to stop after the first batch, got %v", err)
+	}
+}
+
+// flagModerator flags any text containing "forbidden"
+type flagModerator struct{}
+
+func (flagModerator) Moderate(ctx context.Context, text string) (moderation.Result, error) {
+	return moderation.Result{Flagged: strings.Contains(text, "forbidden")}, nil
+}
+
+func TestIngestText_ModeratesChunks(t *testing.T) {
+	store := newFakeStore()
+	service := newTestService(store)
+	service.SetModerator(flagModerator{}, moderation.ActionReject)
+
+	text := "This sentence is fine. This one is forbidden."
+	_, err := service.IngestText(context.Background(), "doc-1", text, types.Metadata{})
+	if !errors.Is(err, moderation.ErrFlagged) {
+		t.Fatalf("Expected ErrFlagged, got %v", err)
+	}
+	if len(store.chunks) != 0 {
+		t.Errorf("Expected nothing to be stored for rejected content, got %d chunks", len(store.chunks))
+	}
+
+	service = NewService(*chunk.NewService(30, 0), store, types.ChunkingConfig{Strategy: chunk.StrategySentence})
+	service.SetModerator(flagModerator{}, moderation.ActionRedact)
+	if _, err := service.IngestText(context.Background(), "doc-1", text, types.Metadata{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	chunks, _ := store.GetChunksByDocumentID(context.Background(), "doc-1")
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i].ChunkIndex < chunks[j].ChunkIndex })
+	if len(chunks) != 2 || chunks[0].Content != "This sentence is fine." || chunks[1].Content != moderation.RedactedText {
+		t.Errorf("Expected only the flagged chunk to be redacted, got %+v", chunks)
+	}
+}
+
+func TestIngestText_ReingestRemovesStaleChunks(t *testing.T) {
+	store := newFakeStore()
+	service := newTestService(store)
+	ctx := context.Background()
+
+	long := strings.Repeat("The first version of the document is long. ", 10)
+	if before, err := service.IngestText(ctx, "doc-1", long, types.Metadata{}); err != nil || before < 3 {
+		t.Fatalf("Expected the first version to need several chunks, got %d: %v", before, err)
+	}
+	if _, err := service.IngestText(ctx, "doc-1", "A short second version.", types.Metadata{}); err != nil {
+		t.Fatalf("IngestText failed: %v", err)
+	}
+
+	chunks, _ := store.GetChunksByDocumentID(ctx, "doc-1")
+	if len(chunks) != 1 || chunks[0].Content != "A short second version." {
+		t.Errorf("Expected only the new version's chunk to remain, got %+v", chunks)
+	}
+}
+
+func TestUpdateText_RemovesStaleChunks(t *testing.T) {
+	store := newFakeStore()
+	service := newTestService(store)
+	logger := &recordingAuditLogger{}
+	service.SetAuditLogger(logger)
+	ctx := context.Background()
+
+	long := strings.Repeat("The first version of the document is long. ", 10)
+	before, err := service.IngestText(ctx, "doc-1", long, types.Metadata{Title: "v1"})
+	if err != nil {
+		t.Fatalf("IngestText failed: %v", err)
+	}
+	if before < 3 {
+		t.Fatalf("Expected the first version to need several chunks, got %d", before)
+	}
+	if _, err := service.IngestText(ctx, "doc-2", "Another document.", types.Metadata{}); err != nil {
+		t.Fatalf("IngestText failed: %v", err)
+	}
+
+	after, err := service.UpdateText(ctx, "doc-1", "A short second version.", types.Metadata{Title: "v2"}, types.ChunkingOptions{})
+	if err != nil {
+		t.Fatalf("UpdateText failed: %v", err)
+	}
+	if after != 1 {
+		t.Fatalf("Expected one chunk for the second version, got %d", after)
+	}
+
+	chunks, _ := store.GetChunksByDocumentID(ctx, "doc-1")
+	if len(chunks) != 1 || chunks[0].Content != "A short second version." || chunks[0].Metadata.Title != "v2" || chunks[0].TotalChunks != 1 {
+		t.Errorf("Expected only the new chunk to remain, got %+v", chunks)
+	}
+	if others, _ := store.GetChunksByDocumentID(ctx, "doc-2"); len(others) != 1 {
+		t.Errorf("Expected other documents to be untouched, got %d chunks", len(others))
+	}
+	if last := logger.entries[len(logger.entries)-1]; last.Operation != audit.OperationUpdate || last.DocumentID != "doc-1" || last.ChunkCount != 1 {
+		t.Errorf("Unexpected audit entry: %+v", last)
+	}
+
+	// A document that doesn't exist yet is created
+	if count, err := service.UpdateText(ctx, "doc-3", "Brand new.", types.Metadata{}, types.ChunkingOptions{}); err != nil || count != 1 {
+		t.Errorf("Expected the update to create doc-3, got %d chunks and %v", count, err)
+	}
+
+	// Invalid chunking is rejected before anything is removed
+	if _, err := service.UpdateText(ctx, "doc-2", "Replacement.", types.Metadata{}, types.ChunkingOptions{Strategy: "bogus"}); !errors.Is(err, ErrInvalidChunking) {
+		t.Errorf("Expected ErrInvalidChunking, got %v", err)
+	}
+	if others, _ := store.GetChunksByDocumentID(ctx, "doc-2"); len(others) != 1 || others[0].Content != "Another document." {
+		t.Errorf("Expected a rejected update to keep the document, got %+v", others)
+	}
+}
@@ -0,0 +1,195 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"go-rag/internal/types"
+)
+
+// IngestJSON ingests structured JSON records, building each document's content
+// and metadata from the request's field mapping. Every record is reported in
+// order; a record that fails to map or ingest does not stop the others.
+func (s *Service) IngestJSON(ctx context.Context, req types.JSONIngestRequest) ([]types.IngestEvent, error) {
+	if len(req.ContentFields) == 0 {
+		return nil, fmt.Errorf("at least one content field is required")
+	}
+
+	records, err := parseRecords(req.Records)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]types.IngestEvent, 0, len(records))
+	for i, record := range records {
+		if err := ctx.Err(); err != nil {
+			return events, err
+		}
+
+		doc, err := mapRecord(record, req)
+		if err != nil {
+			events = append(events, types.IngestEvent{
+				Status: "failed",
+				Error:  fmt.Sprintf("record %d: %v", i, err),
+			})
+			continue
+		}
+
+		event := types.IngestEvent{DocumentID: doc.DocumentID}
+		chunksCount, err := s.ingestWithMetadata(ctx, doc.DocumentID, strings.NewReader(doc.Content), doc.Metadata, doc.ChunkingOptions)
+		if err != nil {
+			event.Status = "failed"
+			event.Error = err.Error()
+		} else {
+			event.Status = "success"
+			event.ChunksCount = chunksCount
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// parseRecords decodes either a single JSON object or an array of objects
+func parseRecords(raw json.RawMessage) ([]map[string]any, error) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("records cannot be empty")
+	}
+
+	if trimmed[0] == '{' {
+		var record map[string]any
+		if err := decodeRecords(trimmed, &record); err != nil {
+			return nil, fmt.Errorf("invalid JSON record: %w", err)
+		}
+		return []map[string]any{record}, nil
+	}
+
+	var records []map[string]any
+	if err := decodeRecords(trimmed, &records); err != nil {
+		return nil, fmt.Errorf("records must be a JSON object or an array of objects: %w", err)
+	}
+	return records, nil
+}
+
+// decodeRecords decodes data into v, keeping numbers as json.Number so large
+// numeric IDs keep every digit rather than being rounded through float64
+func decodeRecords(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if decoder.InputOffset() != int64(len(data)) {
+		return fmt.Errorf("unexpected data after the top-level value")
+	}
+	return nil
+}
+
+// mapRecord builds an ingest request from a record using the field mapping
+func mapRecord(record map[string]any, req types.JSONIngestRequest) (types.IngestRequest, error) {
+	// Join the content fields in the order they were requested
+	var parts []string
+	for _, field := range req.ContentFields {
+		if value, ok := record[field]; ok && value != nil {
+			if text := stringifyValue(value); text != "" {
+				parts = append(parts, text)
+			}
+		}
+	}
+	if len(parts) == 0 {
+		return types.IngestRequest{}, fmt.Errorf("none of the content fields %v are present", req.ContentFields)
+	}
+	content := strings.Join(parts, "\n\n")
+
+	// Use the ID field when configured, otherwise derive a stable ID from the content
+	var docID string
+	if req.IDField != "" {
+		value, ok := record[req.IDField]
+		if !ok || value == nil {
+			return types.IngestRequest{}, fmt.Errorf("id field %q is missing", req.IDField)
+		}
+		docID = stringifyValue(value)
+	} else {
+		h := fnv.New64a()
+		h.Write([]byte(content))
+		docID = fmt.Sprintf("json_%x", h.Sum64())
+	}
+
+	metadata := types.Metadata{}
+	for _, field := range req.MetadataFields {
+		if value, ok := record[field]; ok && value != nil {
+			setMetadataField(&metadata, field, value)
+		}
+	}
+
+	return types.IngestRequest{
+		DocumentID: docID,
+		Content:    content,
+		Metadata:   metadata,
+	}, nil
+}
+
+// setMetadataField assigns a record field to the matching known metadata
+// field, or to the custom map when there is no match
+func setMetadataField(metadata *types.Metadata, field string, value any) {
+	switch field {
+	case "title":
+		metadata.Title = stringifyValue(value)
+	case "author":
+		metadata.Author = stringifyValue(value)
+	case "source":
+		metadata.Source = stringifyValue(value)
+	case "language":
+		metadata.Language = stringifyValue(value)
+	case "content_type":
+		metadata.ContentType = stringifyValue(value)
+	case "tags":
+		if list, ok := value.([]any); ok {
+			for _, tag := range list {
+				metadata.Tags = append(metadata.Tags, stringifyValue(tag))
+			}
+		} else {
+			metadata.Tags = append(metadata.Tags, stringifyValue(value))
+		}
+	case "parent_id":
+		metadata.ParentID = stringifyValue(value)
+	case "section":
+		metadata.Section = stringifyValue(value)
+	case "path":
+		if list, ok := value.([]any); ok {
+			for _, heading := range list {
+				metadata.Path = append(metadata.Path, stringifyValue(heading))
+			}
+		} else {
+			metadata.Path = append(metadata.Path, stringifyValue(value))
+		}
+	default:
+		if metadata.Custom == nil {
+			metadata.Custom = make(map[string]string)
+		}
+		metadata.Custom[field] = stringifyValue(value)
+	}
+}
+
+// stringifyValue renders a decoded JSON value as text
+func stringifyValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return fmt.Sprint(v)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(encoded)
+	}
+}
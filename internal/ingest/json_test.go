@@ -0,0 +1,97 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"go-rag/internal/types"
+)
+
+func TestIngestJSON_MapsFields(t *testing.T) {
+	store := newFakeStore()
+	service := newTestService(store)
+
+	req := types.JSONIngestRequest{
+		Records: json.RawMessage(`[
+			{"sku": "A-1", "name": "Widget", "description": "A small widget.", "author": "Acme", "tags": ["tools", "small"], "price": 9.5},
+			{"name": "No SKU", "description": "Missing id."}
+		]`),
+		ContentFields:  []string{"name", "description"},
+		MetadataFields: []string{"author", "tags", "price"},
+		IDField:        "sku",
+	}
+
+	events, err := service.IngestJSON(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+	if events[0].DocumentID != "A-1" || events[0].Status != "success" {
+		t.Errorf("Expected record 0 to ingest as A-1, got %+v", events[0])
+	}
+	if events[1].Status != "failed" {
+		t.Errorf("Expected record without id to fail, got %+v", events[1])
+	}
+
+	chunks, _ := store.GetChunksByDocumentID(context.Background(), "A-1")
+	if len(chunks) == 0 {
+		t.Fatal("Expected chunks for A-1")
+	}
+
+	chunk := chunks[0]
+	if chunk.Content != "Widget\n\nA small widget." {
+		t.Errorf("Unexpected content %q", chunk.Content)
+	}
+	if chunk.Metadata.Author != "Acme" {
+		t.Errorf("Expected author 'Acme', got %q", chunk.Metadata.Author)
+	}
+	if len(chunk.Metadata.Tags) != 2 || chunk.Metadata.Tags[0] != "tools" {
+		t.Errorf("Expected tags [tools small], got %v", chunk.Metadata.Tags)
+	}
+	if chunk.Metadata.Custom["price"] != "9.5" {
+		t.Errorf("Expected custom price '9.5', got %q", chunk.Metadata.Custom["price"])
+	}
+}
+
+func TestIngestJSON_NumericID(t *testing.T) {
+	store := newFakeStore()
+	service := newTestService(store)
+
+	events, err := service.IngestJSON(context.Background(), types.JSONIngestRequest{
+		Records:        json.RawMessage(`[{"id": 1234567, "body": "Numbered record.", "views": 98765432109876543}]`),
+		ContentFields:  []string{"body"},
+		MetadataFields: []string{"views"},
+		IDField:        "id",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(events) != 1 || events[0].DocumentID != "1234567" {
+		t.Fatalf("Expected the record to ingest as 1234567, got %+v", events)
+	}
+
+	chunks, _ := store.GetChunksByDocumentID(context.Background(), "1234567")
+	if len(chunks) == 0 || chunks[0].Metadata.Custom["views"] != "98765432109876543" {
+		t.Errorf("Expected every digit of the views count to be kept, got %+v", chunks)
+	}
+}
+
+func TestIngestJSON_SingleObject(t *testing.T) {
+	service := newTestService(newFakeStore())
+
+	events, err := service.IngestJSON(context.Background(), types.JSONIngestRequest{
+		Records:       json.RawMessage(`{"body": "Just one record."}`),
+		ContentFields: []string{"body"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(events) != 1 || events[0].Status != "success" || events[0].DocumentID == "" {
+		t.Errorf("Expected one successful record with a derived ID, got %+v", events)
+	}
+}
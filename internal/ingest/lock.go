@@ -0,0 +1,47 @@
+package ingest
+
+import "sync"
+
+// documentLocks serializes mutations of the same document, so concurrent
+// re-ingests, deletes and reindexes of one ID can't interleave their writes.
+// Locks for different documents are independent.
+type documentLocks struct {
+	mu    sync.Mutex
+	locks map[string]*documentLock
+}
+
+// documentLock is one document's mutex and the number of callers holding or
+// waiting for it, so idle entries can be dropped
+type documentLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+func newDocumentLocks() *documentLocks {
+	return &documentLocks{locks: make(map[string]*documentLock)}
+}
+
+// lock blocks until the caller holds the document's lock and returns the
+// function that releases it
+func (d *documentLocks) lock(docID string) func() {
+	d.mu.Lock()
+	l, ok := d.locks[docID]
+	if !ok {
+		l = &documentLock{}
+		d.locks[docID] = l
+	}
+	l.refs++
+	d.mu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+
+		d.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(d.locks, docID)
+		}
+		d.mu.Unlock()
+	}
+}
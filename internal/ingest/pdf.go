@@ -0,0 +1,379 @@
+package ingest
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// errNoPDFText is returned for a PDF without text the extractor can read
+var errNoPDFText = fmt.Errorf("%w: no extractable text in PDF (it may be scanned, encrypted or use embedded font encodings)", ErrUnsupportedFileType)
+
+// pdfCIDFontMarkers identify composite fonts and their Identity encodings,
+// whose strings are glyph IDs rather than text
+var pdfCIDFontMarkers = [][]byte{[]byte("/Type0"), []byte("/Identity-H"), []byte("/Identity-V")}
+
+// pdfOperand is an operand of a content stream operator
+type pdfOperand struct {
+	text     string
+	number   float64
+	isText   bool
+	isNumber bool
+	items    []pdfOperand // the elements of an array
+}
+
+// pdfText extracts the text a PDF shows from its page content streams. It
+// reads uncompressed and FlateDecode streams and the strings of the text
+// operators Tj, TJ, ' and ". Strings are decoded as UTF-16 when they start
+// with a byte order mark and as Latin-1 otherwise, so fonts with custom
+// encodings aren't supported and PDFs with composite fonts are rejected.
+// Compressed streams may inflate to at most maxInflated bytes in total.
+func pdfText(data []byte, maxInflated int64) (string, error) {
+	streams, err := pdfContentStreams(data, maxInflated)
+	if err != nil {
+		return "", err
+	}
+
+	var text strings.Builder
+	for _, content := range streams {
+		text.WriteString(pdfContentText(content))
+		text.WriteString("\n")
+	}
+
+	var lines []string
+	for _, line := range strings.Split(text.String(), "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return "", errNoPDFText
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// pdfContentStreams returns the decoded streams of a PDF that draw text.
+// Streams with a type or subtype, such as images, fonts and object streams,
+// are skipped, as are streams in filters other than FlateDecode. Fonts are
+// checked for composite types, including those packed in object streams.
+func pdfContentStreams(data []byte, maxInflated int64) ([][]byte, error) {
+	if hasPDFCIDFont(data) {
+		return nil, errNoPDFText
+	}
+
+	var streams [][]byte
+	remaining := maxInflated
+	for pos := 0; ; {
+		i := bytes.Index(data[pos:], []byte("stream"))
+		if i < 0 {
+			break
+		}
+		i += pos
+		pos = i + len("stream")
+		if i >= 3 && string(data[i-3:i]) == "end" {
+			continue
+		}
+
+		// The stream's dictionary sits between its object header and the keyword
+		dict := data[:i]
+		if obj := bytes.LastIndex(dict, []byte("obj")); obj >= 0 {
+			dict = dict[obj:]
+		}
+
+		start := pos
+		if bytes.HasPrefix(data[start:], []byte("\r\n")) {
+			start += 2
+		} else if bytes.HasPrefix(data[start:], []byte("\n")) {
+			start++
+		}
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		body := data[start : start+end]
+		pos = start + end + len("endstream")
+
+		objectStream := bytes.Contains(dict, []byte("/ObjStm"))
+		if !objectStream && (bytes.Contains(dict, []byte("/Type")) || bytes.Contains(dict, []byte("/Subtype")) || bytes.Contains(dict, []byte("/Length1"))) {
+			continue
+		}
+		if bytes.Contains(dict, []byte("/Filter")) {
+			if !bytes.Contains(dict, []byte("/FlateDecode")) {
+				continue
+			}
+			inflated, err := inflatePDFStream(body, remaining)
+			if err != nil {
+				return nil, err
+			}
+			remaining -= int64(len(inflated))
+			body = inflated
+		}
+		if objectStream {
+			if hasPDFCIDFont(body) {
+				return nil, errNoPDFText
+			}
+			continue
+		}
+		if bytes.Contains(body, []byte("BT")) {
+			streams = append(streams, body)
+		}
+	}
+	return streams, nil
+}
+
+// inflatePDFStream decompresses a FlateDecode stream of at most limit bytes
+func inflatePDFStream(body []byte, limit int64) ([]byte, error) {
+	reader, err := zlib.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid compressed PDF stream: %v", ErrUnsupportedFileType, err)
+	}
+	defer reader.Close()
+
+	inflated, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid compressed PDF stream: %v", ErrUnsupportedFileType, err)
+	}
+	if int64(len(inflated)) > limit {
+		return nil, fmt.Errorf("%w: PDF content inflates past %d bytes", ErrFileTooLarge, limit)
+	}
+	return inflated, nil
+}
+
+// hasPDFCIDFont reports whether data declares a composite font
+func hasPDFCIDFont(data []byte) bool {
+	for _, marker := range pdfCIDFontMarkers {
+		if bytes.Contains(data, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// pdfContentText runs the text operators of a content stream, starting a new
+// line wherever the text moves down the page
+func pdfContentText(content []byte) string {
+	var out strings.Builder
+	var operands []pdfOperand
+	var array []pdfOperand
+	inArray := false
+	push := func(operand pdfOperand) {
+		if inArray {
+			array = append(array, operand)
+		} else {
+			operands = append(operands, operand)
+		}
+	}
+	lastText := func() (string, bool) {
+		if len(operands) == 0 || !operands[len(operands)-1].isText {
+			return "", false
+		}
+		return operands[len(operands)-1].text, true
+	}
+
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case isPDFSpace(c):
+			i++
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c == '(':
+			text, n := readPDFLiteral(content[i:])
+			push(pdfOperand{text: text, isText: true})
+			i += n
+		case c == '<' && i+1 < len(content) && content[i+1] == '<', c == '>' && i+1 < len(content) && content[i+1] == '>':
+			i += 2
+		case c == '<':
+			text, n := readPDFHex(content[i:])
+			push(pdfOperand{text: text, isText: true})
+			i += n
+		case c == '[':
+			inArray, array = true, nil
+			i++
+		case c == ']':
+			inArray = false
+			push(pdfOperand{items: array})
+			i++
+		case c == '/':
+			i++
+			for i < len(content) && !isPDFSpace(content[i]) && !isPDFDelimiter(content[i]) {
+				i++
+			}
+			push(pdfOperand{})
+		default:
+			start := i
+			i++
+			for i < len(content) && !isPDFSpace(content[i]) && !isPDFDelimiter(content[i]) {
+				i++
+			}
+			word := string(content[start:i])
+			if number, err := strconv.ParseFloat(word, 64); err == nil {
+				push(pdfOperand{number: number, isNumber: true})
+				continue
+			}
+
+			switch word {
+			case "Tj":
+				if text, ok := lastText(); ok {
+					out.WriteString(text)
+				}
+			case "'", "\"":
+				out.WriteString("\n")
+				if text, ok := lastText(); ok {
+					out.WriteString(text)
+				}
+			case "TJ":
+				if len(operands) > 0 {
+					for _, item := range operands[len(operands)-1].items {
+						if item.isText {
+							out.WriteString(item.text)
+						} else if item.isNumber && item.number < -200 {
+							// A large negative adjustment is a gap between words
+							out.WriteString(" ")
+						}
+					}
+				}
+			case "Td", "TD":
+				if len(operands) >= 2 && operands[len(operands)-1].number != 0 {
+					out.WriteString("\n")
+				} else {
+					out.WriteString(" ")
+				}
+			case "T*", "Tm", "ET":
+				out.WriteString("\n")
+			case "BI":
+				// Skip inline image data
+				if end := bytes.Index(content[i:], []byte("EI")); end >= 0 {
+					i += end + 2
+				} else {
+					i = len(content)
+				}
+			}
+			operands = operands[:0]
+		}
+	}
+	return out.String()
+}
+
+// readPDFLiteral decodes the literal string at the start of data, returning
+// it and how many bytes it took
+func readPDFLiteral(data []byte) (string, int) {
+	var raw []byte
+	depth := 0
+	i := 0
+	for ; i < len(data); i++ {
+		c := data[i]
+		switch c {
+		case '(':
+			depth++
+			if depth == 1 {
+				continue
+			}
+		case ')':
+			depth--
+			if depth == 0 {
+				return decodePDFString(raw), i + 1
+			}
+		case '\\':
+			i++
+			if i >= len(data) {
+				continue
+			}
+			switch e := data[i]; e {
+			case 'n':
+				raw = append(raw, '\n')
+			case 'r':
+				raw = append(raw, '\r')
+			case 't':
+				raw = append(raw, '\t')
+			case 'b':
+				raw = append(raw, '\b')
+			case 'f':
+				raw = append(raw, '\f')
+			case '\r', '\n':
+				// A line continuation
+				if e == '\r' && i+1 < len(data) && data[i+1] == '\n' {
+					i++
+				}
+			default:
+				if e >= '0' && e <= '7' {
+					value := 0
+					for n := 0; n < 3 && i < len(data) && data[i] >= '0' && data[i] <= '7'; n++ {
+						value = value*8 + int(data[i]-'0')
+						i++
+					}
+					i--
+					raw = append(raw, byte(value))
+				} else {
+					raw = append(raw, e)
+				}
+			}
+			continue
+		}
+		raw = append(raw, c)
+	}
+	return decodePDFString(raw), i
+}
+
+// readPDFHex decodes the hexadecimal string at the start of data, returning
+// it and how many bytes it took
+func readPDFHex(data []byte) (string, int) {
+	end := bytes.IndexByte(data, '>')
+	if end < 0 {
+		end = len(data)
+	}
+	var digits []byte
+	for _, c := range data[1:end] {
+		if strings.IndexByte("0123456789abcdefABCDEF", c) >= 0 {
+			digits = append(digits, c)
+		}
+	}
+	// A missing final digit is taken as 0
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	raw, _ := hex.DecodeString(string(digits))
+	return decodePDFString(raw), min(end+1, len(data))
+}
+
+// decodePDFString turns string bytes into text: UTF-16 after a byte order
+// mark, Latin-1 otherwise. Control characters other than whitespace are dropped.
+func decodePDFString(raw []byte) string {
+	var runes []rune
+	if len(raw) >= 2 && raw[0] == 0xFE && raw[1] == 0xFF {
+		units := make([]uint16, 0, len(raw)/2)
+		for i := 2; i+1 < len(raw); i += 2 {
+			units = append(units, uint16(raw[i])<<8|uint16(raw[i+1]))
+		}
+		runes = utf16.Decode(units)
+	} else {
+		runes = make([]rune, len(raw))
+		for i, b := range raw {
+			runes[i] = rune(b)
+		}
+	}
+
+	var text strings.Builder
+	for _, r := range runes {
+		if r < 0x20 && r != '\n' && r != '\t' {
+			continue
+		}
+		text.WriteRune(r)
+	}
+	return text.String()
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
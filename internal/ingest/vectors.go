@@ -0,0 +1,50 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go-rag/internal/audit"
+	"go-rag/internal/types"
+)
+
+// ErrInvalidVectors is returned when a pre-computed chunk is missing its content or embedding
+var ErrInvalidVectors = errors.New("invalid pre-computed chunks")
+
+// IngestChunksWithVectors stores a document that was chunked and embedded
+// elsewhere. The chunks are stored in order with their own embeddings, so the
+// embedding service isn't called; the store checks the vector dimensions.
+func (s *Service) IngestChunksWithVectors(ctx context.Context, req types.VectorIngestRequest) (int, error) {
+	if len(req.Chunks) == 0 {
+		return 0, fmt.Errorf("%w: at least one chunk is required", ErrInvalidVectors)
+	}
+
+	docChunks := make([]types.DocumentChunk, len(req.Chunks))
+	for i, chunk := range req.Chunks {
+		if chunk.Content == "" || len(chunk.Embedding) == 0 {
+			return 0, fmt.Errorf("%w: chunk %d needs both content and an embedding", ErrInvalidVectors, i)
+		}
+		docChunks[i] = types.DocumentChunk{
+			ID:          types.GenerateChunkID(req.DocumentID, i),
+			DocumentID:  req.DocumentID,
+			Content:     chunk.Content,
+			ChunkIndex:  i,
+			TotalChunks: len(req.Chunks),
+			Metadata:    req.Metadata,
+			Embedding:   chunk.Embedding,
+		}
+	}
+
+	// Redacted chunks keep their embedding, which was computed by the caller
+	if err := s.moderateChunks(ctx, docChunks); err != nil {
+		return 0, err
+	}
+
+	if err := s.replaceChunks(ctx, req.DocumentID, docChunks); err != nil {
+		return 0, err
+	}
+
+	s.record(ctx, audit.OperationIngest, req.DocumentID, len(docChunks))
+	return len(docChunks), nil
+}
@@ -0,0 +1,302 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Job states
+const (
+	StatusRunning     = "running"
+	StatusSucceeded   = "succeeded"
+	StatusFailed      = "failed"
+	StatusCancelled   = "cancelled"
+	StatusInterrupted = "interrupted" // the server stopped while the job was running
+)
+
+// Finished jobs are kept this long, and at most this many, by default
+const (
+	DefaultRetention   = 24 * time.Hour
+	DefaultMaxFinished = 100
+)
+
+var (
+	// ErrJobNotFound is returned for unknown job IDs
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobFinished is returned when cancelling a job that is no longer running
+	ErrJobFinished = errors.New("job already finished")
+)
+
+// Job is the externally visible state of a background job
+type Job struct {
+	ID         string     `json:"id"`
+	Type       string     `json:"type"`
+	Status     string     `json:"status"`
+	Done       int        `json:"done"`
+	Total      int        `json:"total"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Func is the work a job performs. It should stop when ctx is cancelled and
+// call progress as items complete.
+type Func func(ctx context.Context, progress func(done, total int)) error
+
+// Manager runs jobs in the background and tracks their state. When a state
+// path is set, job state is saved there so it survives restarts.
+type Manager struct {
+	mu        sync.Mutex
+	jobs      map[string]*Job
+	cancels   map[string]context.CancelFunc
+	statePath string
+	wg        sync.WaitGroup
+	closing   bool // jobs stopped from now on were interrupted by shutdown
+	// retention and maxFinished bound how long and how many finished jobs
+	// are kept; 0 leaves that bound off
+	retention   time.Duration
+	maxFinished int
+}
+
+// NewManager creates a job manager, loading saved state from statePath if it
+// exists. Jobs that were running when the state was saved are marked interrupted.
+func NewManager(statePath string) (*Manager, error) {
+	m := &Manager{
+		jobs:        make(map[string]*Job),
+		cancels:     make(map[string]context.CancelFunc),
+		statePath:   statePath,
+		retention:   DefaultRetention,
+		maxFinished: DefaultMaxFinished,
+	}
+
+	if statePath == "" {
+		return m, nil
+	}
+
+	data, err := os.ReadFile(statePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job state: %w", err)
+	}
+
+	var saved []*Job
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("failed to parse job state: %w", err)
+	}
+	for _, job := range saved {
+		if job.Status == StatusRunning {
+			job.Status = StatusInterrupted
+			job.FinishedAt = &job.UpdatedAt
+		}
+		m.jobs[job.ID] = job
+	}
+
+	return m, nil
+}
+
+// SetRetention sets how long finished jobs are kept and how many of them at
+// most; older ones are dropped as jobs start and finish. 0 turns a bound off.
+func (m *Manager) SetRetention(retention time.Duration, maxFinished int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retention = retention
+	m.maxFinished = maxFinished
+}
+
+// Start runs fn in the background and returns the new job
+func (m *Manager) Start(jobType string, fn Func) Job {
+	ctx, cancel := context.WithCancel(context.Background())
+	now := time.Now().UTC()
+	job := &Job{
+		ID:        newJobID(),
+		Type:      jobType,
+		Status:    StatusRunning,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	m.mu.Lock()
+	m.jobs[job.ID] = job
+	m.cancels[job.ID] = cancel
+	snapshot := *job
+	m.pruneLocked(now)
+	m.saveLocked()
+	m.mu.Unlock()
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer cancel()
+
+		err := fn(ctx, func(done, total int) {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			job.Done, job.Total = done, total
+			job.UpdatedAt = time.Now().UTC()
+		})
+		m.finish(job, ctx, err)
+	}()
+
+	return snapshot
+}
+
+// finish records the outcome of a job
+func (m *Manager) finish(job *Job, ctx context.Context, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC()
+	switch {
+	case ctx.Err() != nil && m.closing:
+		job.Status = StatusInterrupted
+	case ctx.Err() != nil:
+		job.Status = StatusCancelled
+	case err != nil:
+		job.Status = StatusFailed
+		job.Error = err.Error()
+	default:
+		job.Status = StatusSucceeded
+	}
+	job.UpdatedAt = now
+	job.FinishedAt = &now
+
+	delete(m.cancels, job.ID)
+	m.pruneLocked(now)
+	m.saveLocked()
+}
+
+// Get returns a snapshot of a job's state
+func (m *Manager) Get(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, exists := m.jobs[id]
+	if !exists {
+		return Job{}, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	return *job, nil
+}
+
+// Cancel stops a running job. The job reports cancelled once its work returns.
+func (m *Manager) Cancel(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.jobs[id]; !exists {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	cancel, running := m.cancels[id]
+	if !running {
+		return fmt.Errorf("%w: %s", ErrJobFinished, id)
+	}
+
+	cancel()
+	return nil
+}
+
+// Wait blocks until all started jobs have finished
+func (m *Manager) Wait() {
+	m.wg.Wait()
+}
+
+// Close stops all running jobs for shutdown and waits for them to record
+// their state, until ctx expires. Stopped jobs are marked interrupted.
+func (m *Manager) Close(ctx context.Context) error {
+	m.mu.Lock()
+	m.closing = true
+	for _, cancel := range m.cancels {
+		cancel()
+	}
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("jobs did not stop before shutdown deadline: %w", ctx.Err())
+	}
+}
+
+// pruneLocked drops finished jobs older than the retention period, then the
+// oldest finished jobs beyond maxFinished; callers must hold m.mu
+func (m *Manager) pruneLocked(now time.Time) {
+	var finished []*Job
+	for id, job := range m.jobs {
+		if job.Status == StatusRunning || job.FinishedAt == nil {
+			continue
+		}
+		if m.retention > 0 && now.Sub(*job.FinishedAt) > m.retention {
+			delete(m.jobs, id)
+			continue
+		}
+		finished = append(finished, job)
+	}
+
+	if m.maxFinished <= 0 || len(finished) <= m.maxFinished {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].FinishedAt.Before(*finished[j].FinishedAt) })
+	for _, job := range finished[:len(finished)-m.maxFinished] {
+		delete(m.jobs, job.ID)
+	}
+}
+
+// saveLocked writes job state to disk; callers must hold m.mu. Persistence is
+// best effort, so failures are logged and do not affect the jobs themselves.
+func (m *Manager) saveLocked() {
+	if err := m.writeStateLocked(); err != nil {
+		log.Printf("Failed to save job state: %v", err)
+	}
+}
+
+// writeStateLocked writes job state to the state path, if one is set
+func (m *Manager) writeStateLocked() error {
+	if m.statePath == "" {
+		return nil
+	}
+
+	saved := make([]*Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		saved = append(saved, job)
+	}
+
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return fmt.Errorf("failed to encode job state: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves partial state
+	tmpPath := m.statePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write job state: %w", err)
+	}
+	if err := os.Rename(tmpPath, m.statePath); err != nil {
+		return fmt.Errorf("failed to replace job state: %w", err)
+	}
+	return nil
+}
+
+// newJobID returns a random identifier for a job
+func newJobID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,184 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestManager_StartPollAndCancel(t *testing.T) {
+	manager, err := NewManager("")
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+
+	started := make(chan struct{})
+	job := manager.Start("reindex", func(ctx context.Context, progress func(done, total int)) error {
+		progress(1, 10)
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	<-started
+
+	polled, err := manager.Get(job.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if polled.Status != StatusRunning || polled.Done != 1 || polled.Total != 10 {
+		t.Errorf("unexpected running job state: %+v", polled)
+	}
+
+	if err := manager.Cancel(job.ID); err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+	manager.Wait()
+
+	polled, _ = manager.Get(job.ID)
+	if polled.Status != StatusCancelled || polled.FinishedAt == nil {
+		t.Errorf("expected cancelled job, got %+v", polled)
+	}
+	if err := manager.Cancel(job.ID); !errors.Is(err, ErrJobFinished) {
+		t.Errorf("expected ErrJobFinished, got %v", err)
+	}
+	if _, err := manager.Get("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("expected ErrJobNotFound, got %v", err)
+	}
+}
+
+func TestManager_RecordsOutcome(t *testing.T) {
+	manager, _ := NewManager("")
+
+	ok := manager.Start("reindex", func(ctx context.Context, progress func(done, total int)) error { return nil })
+	failed := manager.Start("reindex", func(ctx context.Context, progress func(done, total int)) error {
+		return errors.New("embedding service unavailable")
+	})
+	manager.Wait()
+
+	if job, _ := manager.Get(ok.ID); job.Status != StatusSucceeded {
+		t.Errorf("expected succeeded, got %s", job.Status)
+	}
+	if job, _ := manager.Get(failed.ID); job.Status != StatusFailed || job.Error == "" {
+		t.Errorf("expected failed with error, got %+v", job)
+	}
+}
+
+func TestManager_PersistsState(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "jobs.json")
+	manager, _ := NewManager(statePath)
+
+	done := manager.Start("reindex", func(ctx context.Context, progress func(done, total int)) error { return nil })
+	manager.Wait()
+
+	release := make(chan struct{})
+	running := manager.Start("reindex", func(ctx context.Context, progress func(done, total int)) error {
+		<-release
+		return nil
+	})
+
+	// A new manager simulates a restart while the second job was running
+	restarted, err := NewManager(statePath)
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	close(release)
+	manager.Wait()
+
+	if job, err := restarted.Get(done.ID); err != nil || job.Status != StatusSucceeded {
+		t.Errorf("expected finished job to be restored, got %+v, %v", job, err)
+	}
+	if job, err := restarted.Get(running.ID); err != nil || job.Status != StatusInterrupted {
+		t.Errorf("expected running job to be marked interrupted, got %+v, %v", job, err)
+	}
+}
+
+func TestManager_CloseInterruptsRunningJobs(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "jobs.json")
+	manager, err := NewManager(statePath)
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+
+	started := make(chan struct{})
+	job := manager.Start("reindex", func(ctx context.Context, progress func(done, total int)) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	<-started
+
+	if err := manager.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	reloaded, err := NewManager(statePath)
+	if err != nil {
+		t.Fatalf("NewManager failed to reload state: %v", err)
+	}
+	polled, _ := reloaded.Get(job.ID)
+	if polled.Status != StatusInterrupted || polled.FinishedAt == nil {
+		t.Errorf("expected interrupted job, got %+v", polled)
+	}
+}
+
+func TestManager_PrunesFinishedJobs(t *testing.T) {
+	manager, _ := NewManager("")
+	manager.SetRetention(time.Hour, 2)
+	noop := func(ctx context.Context, progress func(done, total int)) error { return nil }
+
+	var finished []Job
+	for range 3 {
+		finished = append(finished, manager.Start("reindex", noop))
+		manager.Wait()
+	}
+	if _, err := manager.Get(finished[0].ID); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("expected the oldest finished job to be dropped, got %v", err)
+	}
+	for _, job := range finished[1:] {
+		if _, err := manager.Get(job.ID); err != nil {
+			t.Errorf("expected job %s to be kept, got %v", job.ID, err)
+		}
+	}
+
+	// Jobs finished longer ago than the retention period are dropped as well
+	manager.mu.Lock()
+	expired := time.Now().UTC().Add(-2 * time.Hour)
+	manager.jobs[finished[1].ID].FinishedAt = &expired
+	manager.mu.Unlock()
+
+	release := make(chan struct{})
+	running := manager.Start("reindex", func(ctx context.Context, progress func(done, total int)) error {
+		<-release
+		return nil
+	})
+	if _, err := manager.Get(finished[1].ID); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("expected the expired job to be dropped, got %v", err)
+	}
+	if _, err := manager.Get(running.ID); err != nil {
+		t.Errorf("expected the running job to be kept, got %v", err)
+	}
+	close(release)
+	manager.Wait()
+}
+
+func TestManager_ReportsSaveErrors(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "missing", "jobs.json")
+	manager, err := NewManager(statePath)
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+
+	if err := manager.writeStateLocked(); err == nil || !strings.Contains(err.Error(), "failed to write job state") {
+		t.Errorf("expected a write error, got %v", err)
+	}
+
+	// The job still runs when its state can't be saved
+	job := manager.Start("reindex", func(ctx context.Context, progress func(done, total int)) error { return nil })
+	manager.Wait()
+	if polled, _ := manager.Get(job.ID); polled.Status != StatusSucceeded {
+		t.Errorf("expected succeeded, got %s", polled.Status)
+	}
+}
@@ -0,0 +1,131 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"go-rag/internal/types"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Actions taken on flagged content at a stage
+const (
+	ActionOff    = "off"    // don't moderate the stage
+	ActionReject = "reject" // fail the request
+	ActionRedact = "redact" // replace the flagged text and carry on
+)
+
+// RedactedText replaces flagged content that is redacted rather than rejected
+const RedactedText = "[content removed by moderation]"
+
+// ErrFlagged is returned when moderation rejects content
+var ErrFlagged = errors.New("content flagged by moderation")
+
+// Result is the moderation verdict for one text
+type Result struct {
+	Flagged    bool
+	Categories []string
+}
+
+// Moderator checks text against a content policy
+type Moderator interface {
+	Moderate(ctx context.Context, text string) (Result, error)
+}
+
+// NewModerator creates the moderator selected by the configured provider
+func NewModerator(config types.ModerationConfig) (Moderator, error) {
+	switch config.Provider {
+	case "", "none":
+		return NoopModerator{}, nil
+	case "openai":
+		return NewOpenAIModerator(config)
+	default:
+		return nil, fmt.Errorf("unsupported moderation provider: %s", config.Provider)
+	}
+}
+
+// NoopModerator flags nothing
+type NoopModerator struct{}
+
+// Moderate implements Moderator
+func (NoopModerator) Moderate(ctx context.Context, text string) (Result, error) {
+	return Result{}, nil
+}
+
+// OpenAIModerator uses the OpenAI moderation endpoint
+type OpenAIModerator struct {
+	client *openai.Client
+	model  string
+}
+
+// NewOpenAIModerator creates a moderator backed by OpenAI
+func NewOpenAIModerator(config types.ModerationConfig) (*OpenAIModerator, error) {
+	if config.APIKey == "" {
+		return nil, fmt.Errorf("OpenAI API key is required")
+	}
+
+	return &OpenAIModerator{
+		client: openai.NewClient(config.APIKey),
+		model:  config.Model,
+	}, nil
+}
+
+// Moderate implements Moderator
+func (m *OpenAIModerator) Moderate(ctx context.Context, text string) (Result, error) {
+	resp, err := m.client.Moderations(ctx, openai.ModerationRequest{Input: text, Model: m.model})
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to moderate content: %w", err)
+	}
+	if len(resp.Results) == 0 {
+		return Result{}, fmt.Errorf("no moderation result returned")
+	}
+
+	result := resp.Results[0]
+	return Result{Flagged: result.Flagged, Categories: flaggedCategories(result.Categories)}, nil
+}
+
+// flaggedCategories lists the names of the categories set in categories, sorted
+func flaggedCategories(categories openai.ResultCategories) []string {
+	// The JSON field names are the category names the API documents
+	data, _ := json.Marshal(categories)
+	var set map[string]bool
+	json.Unmarshal(data, &set)
+
+	var names []string
+	for name, flagged := range set {
+		if flagged {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Check moderates text for a stage. It returns text unchanged when it passes
+// or the action is off, RedactedText when the action is redact, and an error
+// wrapping ErrFlagged when the action is reject.
+func Check(ctx context.Context, moderator Moderator, action, text string) (string, error) {
+	if moderator == nil || action == "" || action == ActionOff || text == "" {
+		return text, nil
+	}
+
+	result, err := moderator.Moderate(ctx, text)
+	if err != nil {
+		return "", err
+	}
+	if !result.Flagged {
+		return text, nil
+	}
+
+	if action == ActionRedact {
+		return RedactedText, nil
+	}
+	if len(result.Categories) == 0 {
+		return "", ErrFlagged
+	}
+	return "", fmt.Errorf("%w: %v", ErrFlagged, result.Categories)
+}
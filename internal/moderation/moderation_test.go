@@ -0,0 +1,60 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// wordModerator flags any text containing word
+type wordModerator struct {
+	word string
+	err  error
+}
+
+func (m wordModerator) Moderate(ctx context.Context, text string) (Result, error) {
+	if m.err != nil {
+		return Result{}, m.err
+	}
+	if strings.Contains(text, m.word) {
+		return Result{Flagged: true, Categories: []string{"violence"}}, nil
+	}
+	return Result{}, nil
+}
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	moderator := wordModerator{word: "forbidden"}
+
+	if text, err := Check(ctx, moderator, ActionReject, "a fine answer"); err != nil || text != "a fine answer" {
+		t.Errorf("Expected clean text to pass, got %q, %v", text, err)
+	}
+
+	_, err := Check(ctx, moderator, ActionReject, "a forbidden answer")
+	if !errors.Is(err, ErrFlagged) || !strings.Contains(err.Error(), "violence") {
+		t.Errorf("Expected ErrFlagged with the category, got %v", err)
+	}
+
+	if text, err := Check(ctx, moderator, ActionRedact, "a forbidden answer"); err != nil || text != RedactedText {
+		t.Errorf("Expected the text to be redacted, got %q, %v", text, err)
+	}
+
+	if text, err := Check(ctx, moderator, ActionOff, "a forbidden answer"); err != nil || text != "a forbidden answer" {
+		t.Errorf("Expected moderation to be skipped when off, got %q, %v", text, err)
+	}
+
+	// A moderation outage fails closed rather than letting content through
+	if _, err := Check(ctx, wordModerator{err: errors.New("unavailable")}, ActionRedact, "text"); err == nil || errors.Is(err, ErrFlagged) {
+		t.Errorf("Expected the moderator error, got %v", err)
+	}
+}
+
+func TestFlaggedCategories(t *testing.T) {
+	got := flaggedCategories(openai.ResultCategories{Violence: true, HateThreatening: true})
+	if strings.Join(got, ",") != "hate/threatening,violence" {
+		t.Errorf("Expected the API category names, got %v", got)
+	}
+}
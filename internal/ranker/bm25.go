@@ -0,0 +1,94 @@
+package ranker
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"unicode"
+
+	"go-rag/internal/types"
+)
+
+// BM25 parameters used when none are configured
+const (
+	DefaultBM25K1 = 1.2
+	DefaultBM25B  = 0.75
+)
+
+// scoreBM25 scores chunks with Okapi BM25, treating the candidate chunks as
+// the corpus. Scores are divided by the best one, so they fall between 0 and 1
+// like keyword scores and can be blended with vector scores.
+func (s *Service) scoreBM25(query string, chunks []types.DocumentChunk, out []types.RankedChunk) {
+	k1 := s.config.BM25K1
+	if k1 <= 0 {
+		k1 = DefaultBM25K1
+	}
+	b := s.config.BM25B
+	if b <= 0 {
+		b = DefaultBM25B
+	}
+
+	// Term frequencies per chunk, then the number of chunks containing each term
+	frequencies := make([]map[string]int, len(chunks))
+	lengths := make([]int, len(chunks))
+	s.forEachChunk(len(chunks), func(i int) {
+		terms := tokenize(chunks[i].Content)
+		frequencies[i] = make(map[string]int, len(terms))
+		for _, term := range terms {
+			frequencies[i][term]++
+		}
+		lengths[i] = len(terms)
+	})
+	containing := make(map[string]int)
+	totalLength := 0
+	for i := range chunks {
+		for term := range frequencies[i] {
+			containing[term]++
+		}
+		totalLength += lengths[i]
+	}
+
+	averageLength := 1.0
+	if totalLength > 0 {
+		averageLength = float64(totalLength) / float64(len(chunks))
+	}
+
+	// Unique query terms in a fixed order, so scores sum the same way every time
+	queryTerms := tokenize(query)
+	slices.Sort(queryTerms)
+	queryTerms = slices.Compact(queryTerms)
+
+	n := float64(len(chunks))
+	s.forEachChunk(len(chunks), func(i int) {
+		score := 0.0
+		for _, term := range queryTerms {
+			tf := float64(frequencies[i][term])
+			if tf == 0 {
+				continue
+			}
+			// The +1 keeps the IDF positive for terms found in most chunks
+			df := float64(containing[term])
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			norm := k1 * (1 - b + b*float64(lengths[i])/averageLength)
+			score += idf * tf * (k1 + 1) / (tf + norm)
+		}
+		out[i] = types.RankedChunk{DocumentChunk: chunks[i], Score: score}
+	})
+
+	best := 0.0
+	for i := range out {
+		best = max(best, out[i].Score)
+	}
+	if best > 0 {
+		for i := range out {
+			out[i].Score /= best
+		}
+	}
+}
+
+// tokenize lowercases text and splits it into words of letters and digits
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,75 @@
+package ranker
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go-rag/internal/types"
+)
+
+func TestRankChunks_BM25PrefersShortRelevantChunks(t *testing.T) {
+	// The long chunk only matches the query words inside other words, which
+	// keyword overlap counts as a full match
+	chunks := []types.DocumentChunk{
+		{ID: 1, Content: "Every category of careful planning tips the balance: the committee reviewed budgets, schedules, staffing and vendor contracts over several long meetings before deciding anything."},
+		{ID: 2, Content: "Cat care tips: brush your cat weekly."},
+		{ID: 3, Content: "Quarterly revenue grew in every region."},
+	}
+	ctx := context.Background()
+	query := "cat care tips"
+
+	ranked, err := NewService(types.RankingConfig{}).RankChunks(ctx, query, chunks)
+	if err != nil {
+		t.Fatalf("RankChunks failed: %v", err)
+	}
+	if ranked[0].ID != 1 {
+		t.Fatalf("expected keyword overlap to keep the long chunk first, got %+v", ranked)
+	}
+
+	ranked, err = NewService(types.RankingConfig{Scorer: ScorerBM25}).RankChunks(ctx, query, chunks)
+	if err != nil {
+		t.Fatalf("RankChunks failed: %v", err)
+	}
+	if ranked[0].ID != 2 || ranked[0].Score != 1 {
+		t.Errorf("expected BM25 to put the short relevant chunk first with a score of 1, got %+v", ranked)
+	}
+	if ranked[1].ID != 1 || ranked[1].Score <= 0 || ranked[1].Score >= 0.5 {
+		t.Errorf("expected the long chunk to match only \"tips\" and score well below 1, got %+v", ranked[1])
+	}
+	if ranked[2].ID != 3 || ranked[2].Score != 0 {
+		t.Errorf("expected the unrelated chunk last with a score of 0, got %+v", ranked[2])
+	}
+}
+
+func TestScoreBM25_LengthNormalization(t *testing.T) {
+	// Both chunks mention the term once; only length sets them apart
+	chunks := []types.DocumentChunk{
+		{ID: 1, Content: "kubernetes scheduling notes from the platform team offsite agenda"},
+		{ID: 2, Content: "kubernetes scheduling"},
+		{ID: 3, Content: "release notes"},
+	}
+
+	score := func(b float64) []types.RankedChunk {
+		service := NewService(types.RankingConfig{Scorer: ScorerBM25, BM25K1: 1.2, BM25B: b})
+		out := make([]types.RankedChunk, len(chunks))
+		service.scoreBM25("kubernetes", chunks, out)
+		return out
+	}
+
+	if out := score(0.75); out[1].Score != 1 || out[0].Score >= out[1].Score {
+		t.Errorf("expected the short chunk to score higher, got %v and %v", out[0].Score, out[1].Score)
+	}
+	// A tiny b all but disables length normalization
+	if out := score(1e-9); out[1].Score-out[0].Score > 1e-6 {
+		t.Errorf("expected equal scores without length normalization, got %v and %v", out[0].Score, out[1].Score)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	got := tokenize("Go's BM25, re-ranked!")
+	want := []string{"go", "s", "bm25", "re", "ranked"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,93 @@
+package ranker
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"go-rag/internal/types"
+)
+
+// ErrInvalidBoost is returned for a boost expression that cannot be applied
+var ErrInvalidBoost = errors.New("invalid boost")
+
+// Boost multiplies the score of chunks whose metadata field equals a value
+type Boost struct {
+	Field  string
+	Value  string
+	Factor float64
+}
+
+// ParseBoosts validates boost expressions of the form "field=value" mapped to
+// their multipliers. Known metadata fields match directly, tags match any tag,
+// and other fields match custom metadata.
+func ParseBoosts(expressions map[string]float64) ([]Boost, error) {
+	boosts := make([]Boost, 0, len(expressions))
+	for expr, factor := range expressions {
+		field, value, ok := strings.Cut(expr, "=")
+		field = strings.TrimSpace(field)
+		if !ok || field == "" {
+			return nil, fmt.Errorf("%w: %q must have the form field=value", ErrInvalidBoost, expr)
+		}
+		if factor <= 0 || math.IsInf(factor, 0) || math.IsNaN(factor) {
+			return nil, fmt.Errorf("%w: multiplier for %q must be a positive number", ErrInvalidBoost, expr)
+		}
+		boosts = append(boosts, Boost{Field: field, Value: strings.TrimSpace(value), Factor: factor})
+	}
+
+	// Keep application order stable regardless of map iteration
+	sort.Slice(boosts, func(i, j int) bool {
+		if boosts[i].Field != boosts[j].Field {
+			return boosts[i].Field < boosts[j].Field
+		}
+		return boosts[i].Value < boosts[j].Value
+	})
+	return boosts, nil
+}
+
+// matches reports whether the chunk's metadata satisfies the boost condition
+func (b Boost) matches(metadata types.Metadata) bool {
+	switch b.Field {
+	case "title":
+		return metadata.Title == b.Value
+	case "author":
+		return metadata.Author == b.Value
+	case "source":
+		return metadata.Source == b.Value
+	case "language":
+		return metadata.Language == b.Value
+	case "content_type":
+		return metadata.ContentType == b.Value
+	case "tags":
+		for _, tag := range metadata.Tags {
+			if tag == b.Value {
+				return true
+			}
+		}
+		return false
+	default:
+		value, ok := metadata.Custom[b.Field]
+		return ok && value == b.Value
+	}
+}
+
+// ApplyBoosts multiplies the score of each chunk by every boost it matches and
+// re-sorts the chunks by the boosted score
+func (s *Service) ApplyBoosts(rankedChunks []types.RankedChunk, boosts []Boost) []types.RankedChunk {
+	if len(boosts) == 0 {
+		return rankedChunks
+	}
+
+	for i := range rankedChunks {
+		for _, boost := range boosts {
+			if boost.matches(rankedChunks[i].Metadata) {
+				rankedChunks[i].Score *= boost.Factor
+			}
+		}
+	}
+
+	sortByScore(rankedChunks)
+	return rankedChunks
+}
@@ -0,0 +1,75 @@
+package ranker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-rag/internal/types"
+)
+
+func TestApplyBoosts_BoostedSourceOutranksEqualChunk(t *testing.T) {
+	chunks := []types.DocumentChunk{
+		{ID: 1, Content: "install the agent", Metadata: types.Metadata{Source: "forum"}},
+		{ID: 2, Content: "install the agent", Metadata: types.Metadata{Source: "official"}},
+	}
+	service := NewService(types.RankingConfig{})
+
+	ranked, err := service.RankChunks(context.Background(), "install agent", chunks)
+	if err != nil {
+		t.Fatalf("RankChunks failed: %v", err)
+	}
+	if ranked[0].ID != 1 || ranked[0].Score != ranked[1].Score {
+		t.Fatalf("expected equally scored chunks in input order, got %+v", ranked)
+	}
+
+	boosts, err := ParseBoosts(map[string]float64{"source=official": 1.5})
+	if err != nil {
+		t.Fatalf("ParseBoosts failed: %v", err)
+	}
+	ranked = service.ApplyBoosts(ranked, boosts)
+
+	if ranked[0].ID != 2 {
+		t.Errorf("expected the official chunk first, got chunk %d", ranked[0].ID)
+	}
+	if ranked[0].Score != 1.5 || ranked[1].Score != 1.0 {
+		t.Errorf("expected scores 1.5 and 1.0, got %v and %v", ranked[0].Score, ranked[1].Score)
+	}
+}
+
+func TestApplyBoosts_MatchesTagsAndCustomFields(t *testing.T) {
+	ranked := []types.RankedChunk{
+		{DocumentChunk: types.DocumentChunk{ID: 1}, Score: 1},
+		{DocumentChunk: types.DocumentChunk{ID: 2, Metadata: types.Metadata{Tags: []string{"faq", "billing"}}}, Score: 1},
+		{DocumentChunk: types.DocumentChunk{ID: 3, Metadata: types.Metadata{Tags: []string{"billing"}, Custom: map[string]string{"tier": "gold"}}}, Score: 1},
+	}
+	boosts, err := ParseBoosts(map[string]float64{"tags=billing": 2, "tier=gold": 1.5})
+	if err != nil {
+		t.Fatalf("ParseBoosts failed: %v", err)
+	}
+
+	ranked = NewService(types.RankingConfig{}).ApplyBoosts(ranked, boosts)
+
+	want := map[uint64]float64{1: 1, 2: 2, 3: 3}
+	for _, chunk := range ranked {
+		if chunk.Score != want[chunk.ID] {
+			t.Errorf("chunk %d: expected score %v, got %v", chunk.ID, want[chunk.ID], chunk.Score)
+		}
+	}
+	if ranked[0].ID != 3 {
+		t.Errorf("expected chunk 3 first, got %d", ranked[0].ID)
+	}
+}
+
+func TestParseBoosts_RejectsInvalidExpressions(t *testing.T) {
+	for _, expressions := range []map[string]float64{
+		{"source": 1.5},
+		{"=official": 1.5},
+		{"source=official": 0},
+		{"source=official": -2},
+	} {
+		if _, err := ParseBoosts(expressions); !errors.Is(err, ErrInvalidBoost) {
+			t.Errorf("%v: expected ErrInvalidBoost, got %v", expressions, err)
+		}
+	}
+}
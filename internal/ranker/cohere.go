@@ -0,0 +1,117 @@
+package ranker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-rag/internal/types"
+)
+
+const (
+	// cohereRerankURL is Cohere's API endpoint
+	cohereRerankURL = "https://api.cohere.com"
+	// defaultCohereRerankModel is used when no reranker model is configured
+	defaultCohereRerankModel = "rerank-v3.5"
+)
+
+// CohereReranker scores chunks with a cross-encoder through Cohere's rerank
+// API, or any endpoint that serves the same /v2/rerank request and response
+type CohereReranker struct {
+	config     types.RankingConfig
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewCohereReranker creates a reranker for Cohere, or for the endpoint at
+// RerankerURL, which needs no API key
+func NewCohereReranker(config types.RankingConfig) (*CohereReranker, error) {
+	baseURL := strings.TrimRight(config.RerankerURL, "/")
+	if baseURL == "" {
+		if config.RerankerAPIKey == "" {
+			return nil, fmt.Errorf("Cohere API key is required for reranking")
+		}
+		baseURL = cohereRerankURL
+	}
+	if config.RerankerModel == "" {
+		config.RerankerModel = defaultCohereRerankModel
+	}
+
+	return &CohereReranker{
+		config:     config,
+		baseURL:    baseURL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Score implements Reranker, returning each chunk's relevance to the query
+// from 0 to 1
+func (r *CohereReranker) Score(ctx context.Context, query string, chunks []types.DocumentChunk) ([]float64, error) {
+	documents := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		documents[i] = chunk.Content
+	}
+
+	data, err := json.Marshal(map[string]any{
+		"model":     r.config.RerankerModel,
+		"query":     query,
+		"documents": documents,
+		"top_n":     len(documents),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	var resp struct {
+		Results []struct {
+			Index          int     `json:"index"`
+			RelevanceScore float64 `json:"relevance_score"`
+		} `json:"results"`
+	}
+	if err := r.post(ctx, "/v2/rerank", data, &resp); err != nil {
+		return nil, fmt.Errorf("failed to rerank: %w", err)
+	}
+
+	// Results come back best first; put each score back at its chunk's position
+	scores := make([]float64, len(chunks))
+	for _, result := range resp.Results {
+		if result.Index < 0 || result.Index >= len(scores) {
+			return nil, fmt.Errorf("reranker returned index %d for %d documents", result.Index, len(scores))
+		}
+		scores[result.Index] = result.RelevanceScore
+	}
+	return scores, nil
+}
+
+// post sends a JSON request to the rerank API and decodes the JSON response into out
+func (r *CohereReranker) post(ctx context.Context, path string, body []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if r.config.RerankerAPIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+r.config.RerankerAPIKey)
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("reranker returned %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
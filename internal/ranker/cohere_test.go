@@ -0,0 +1,90 @@
+package ranker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-rag/internal/types"
+)
+
+// newTestRerankServer scores each document by whether it contains "learning",
+// returning results best first as Cohere does
+func newTestRerankServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/rerank" || r.Header.Get("Authorization") != "Bearer test-key" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			Model     string   `json:"model"`
+			Query     string   `json:"query"`
+			Documents []string `json:"documents"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "rerank-v3.5" {
+			http.Error(w, "unknown model", http.StatusBadRequest)
+			return
+		}
+
+		type result struct {
+			Index          int     `json:"index"`
+			RelevanceScore float64 `json:"relevance_score"`
+		}
+		var best, rest []result
+		for i, document := range req.Documents {
+			if strings.Contains(document, "learning") {
+				best = append(best, result{Index: i, RelevanceScore: 0.9})
+			} else {
+				rest = append(rest, result{Index: i, RelevanceScore: 0.05})
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"results": append(best, rest...)})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCohereReranker_ReplacesLexicalScores(t *testing.T) {
+	server := newTestRerankServer(t)
+	reranker, err := NewReranker(types.RankingConfig{Reranker: "cohere", RerankerURL: server.URL + "/", RerankerAPIKey: "test-key"})
+	if err != nil {
+		t.Fatalf("Failed to create reranker: %v", err)
+	}
+
+	// Lexically "deep neural networks" wins; the model prefers the chunk about learning
+	chunks := []types.DocumentChunk{
+		{ID: 1, Content: "deep neural networks"},
+		{ID: 2, Content: "how machines go about learning"},
+		{ID: 3, Content: "cooking recipes"},
+	}
+	ranked, err := NewServiceWithReranker(types.RankingConfig{}, reranker).RankChunks(context.Background(), "deep neural networks", chunks)
+	if err != nil {
+		t.Fatalf("RankChunks failed: %v", err)
+	}
+	if ranked[0].ID != 2 || ranked[0].Score != 0.9 || ranked[2].Score != 0.05 {
+		t.Errorf("Expected the model's scores to rank chunk 2 first, got %+v", ranked)
+	}
+
+	// An unreachable endpoint falls back to lexical scoring when allowed
+	server.Close()
+	ranked, err = NewServiceWithReranker(types.RankingConfig{FallbackOnError: true}, reranker).RankChunks(context.Background(), "deep neural networks", chunks)
+	if err != nil || ranked[0].ID != 1 {
+		t.Errorf("Expected a keyword fallback ranking chunk 1 first, got %v: %+v", err, ranked)
+	}
+}
+
+func TestNewReranker(t *testing.T) {
+	if reranker, err := NewReranker(types.RankingConfig{Reranker: "none"}); reranker != nil || err != nil {
+		t.Errorf("Expected no reranker for none, got %v, %v", reranker, err)
+	}
+	if _, err := NewReranker(types.RankingConfig{Reranker: "cohere"}); err == nil {
+		t.Error("Expected Cohere without an API key or URL to be rejected")
+	}
+	if _, err := NewReranker(types.RankingConfig{Reranker: "colbert"}); err == nil {
+		t.Error("Expected an unknown reranker to be rejected")
+	}
+}
@@ -0,0 +1,92 @@
+package ranker
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"go-rag/internal/types"
+)
+
+// ErrInvalidFacet is returned for a facet field that cannot be counted
+var ErrInvalidFacet = errors.New("invalid facet")
+
+// ValidateFacets checks that facet fields are non-empty and not repeated
+func ValidateFacets(fields []string) error {
+	seen := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		if strings.TrimSpace(field) == "" {
+			return fmt.Errorf("%w: field names cannot be empty", ErrInvalidFacet)
+		}
+		if seen[field] {
+			return fmt.Errorf("%w: %q is requested more than once", ErrInvalidFacet, field)
+		}
+		seen[field] = true
+	}
+	return nil
+}
+
+// CountFacets counts, for each field, how many chunks have each of its
+// values. Fields are interpreted like boost fields, plus document_id; "tag"
+// is accepted for "tags", and each tag of a chunk is counted. Chunks without
+// a value aren't counted. Values are ordered by count, then value, and cut to
+// maxValues when it is positive.
+func CountFacets(chunks []types.RankedChunk, fields []string, maxValues int) map[string][]types.FacetCount {
+	facets := make(map[string][]types.FacetCount, len(fields))
+	for _, field := range fields {
+		counts := make(map[string]int)
+		for _, chunk := range chunks {
+			for _, value := range facetValues(chunk.DocumentChunk, field) {
+				counts[value]++
+			}
+		}
+
+		values := make([]types.FacetCount, 0, len(counts))
+		for value, count := range counts {
+			values = append(values, types.FacetCount{Value: value, Count: count})
+		}
+		sort.Slice(values, func(i, j int) bool {
+			if values[i].Count != values[j].Count {
+				return values[i].Count > values[j].Count
+			}
+			return values[i].Value < values[j].Value
+		})
+		if maxValues > 0 && len(values) > maxValues {
+			values = values[:maxValues]
+		}
+		facets[field] = values
+	}
+	return facets
+}
+
+// facetValues returns a chunk's non-empty values for a facet field
+func facetValues(chunk types.DocumentChunk, field string) []string {
+	var value string
+	switch field {
+	case "document_id":
+		value = chunk.DocumentID
+	case "title":
+		value = chunk.Metadata.Title
+	case "author":
+		value = chunk.Metadata.Author
+	case "source":
+		value = chunk.Metadata.Source
+	case "language":
+		value = chunk.Metadata.Language
+	case "content_type":
+		value = chunk.Metadata.ContentType
+	case "tags", "tag":
+		// A tag listed twice on one chunk still counts the chunk once
+		tags := slices.Clone(chunk.Metadata.Tags)
+		slices.Sort(tags)
+		return slices.DeleteFunc(slices.Compact(tags), func(tag string) bool { return tag == "" })
+	default:
+		value = chunk.Metadata.Custom[field]
+	}
+	if value == "" {
+		return nil
+	}
+	return []string{value}
+}
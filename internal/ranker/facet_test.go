@@ -0,0 +1,59 @@
+package ranker
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"go-rag/internal/types"
+)
+
+func facetFixture() []types.RankedChunk {
+	metadata := []types.Metadata{
+		{Source: "handbook", Language: "en", Tags: []string{"hr", "policy"}},
+		{Source: "handbook", Language: "en", Tags: []string{"policy", "policy"}},
+		{Source: "wiki", Language: "de", Tags: []string{"hr"}, Custom: map[string]string{"team": "people"}},
+		{Source: "handbook", Custom: map[string]string{"team": "people"}},
+		{Source: "forum", Language: "en"},
+	}
+	chunks := make([]types.RankedChunk, len(metadata))
+	for i, m := range metadata {
+		chunks[i] = types.RankedChunk{DocumentChunk: types.DocumentChunk{ID: uint64(i + 1), DocumentID: "doc", Metadata: m}}
+	}
+	return chunks
+}
+
+func TestCountFacets(t *testing.T) {
+	facets := CountFacets(facetFixture(), []string{"source", "tags", "language", "team", "document_id"}, 0)
+
+	// A chunk counts once per value, and equal counts are ordered by value
+	want := map[string][]types.FacetCount{
+		"source":      {{Value: "handbook", Count: 3}, {Value: "forum", Count: 1}, {Value: "wiki", Count: 1}},
+		"tags":        {{Value: "hr", Count: 2}, {Value: "policy", Count: 2}},
+		"language":    {{Value: "en", Count: 3}, {Value: "de", Count: 1}},
+		"team":        {{Value: "people", Count: 2}},
+		"document_id": {{Value: "doc", Count: 5}},
+	}
+	if !reflect.DeepEqual(facets, want) {
+		t.Errorf("Unexpected facets:\n got %+v\nwant %+v", facets, want)
+	}
+
+	facets = CountFacets(facetFixture(), []string{"tag", "source", "missing"}, 1)
+	if len(facets["tag"]) != 1 || facets["tag"][0].Value != "hr" || len(facets["source"]) != 1 || facets["source"][0].Count != 3 {
+		t.Errorf("Expected the top value of each facet, got %+v", facets)
+	}
+	if values, ok := facets["missing"]; !ok || len(values) != 0 {
+		t.Errorf("Expected an empty facet for a field no chunk has, got %+v", values)
+	}
+}
+
+func TestValidateFacets(t *testing.T) {
+	if err := ValidateFacets([]string{"source", "tags"}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	for _, fields := range [][]string{{""}, {"source", "source"}} {
+		if err := ValidateFacets(fields); !errors.Is(err, ErrInvalidFacet) {
+			t.Errorf("Expected %q to be rejected, got %v", fields, err)
+		}
+	}
+}
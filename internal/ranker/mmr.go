@@ -0,0 +1,76 @@
+package ranker
+
+import (
+	"math"
+
+	"go-rag/internal/types"
+)
+
+// ApplyMMR reorders chunks by maximal marginal relevance, so near-duplicates
+// don't crowd the top. Each step picks the chunk with the best
+// lambda*sim(query, chunk) - (1-lambda)*max sim(chunk, picked), by cosine
+// similarity of the embeddings, which are given in the same order as
+// rankedChunks. A lambda of 1 orders purely by similarity to the query and 0
+// purely by dissimilarity to the chunks already picked. Scores are kept; ties
+// keep the input order.
+func (s *Service) ApplyMMR(rankedChunks []types.RankedChunk, queryEmbedding []float64, embeddings [][]float64, lambda float64) []types.RankedChunk {
+	if len(rankedChunks) < 2 || len(embeddings) != len(rankedChunks) {
+		return rankedChunks
+	}
+
+	relevance := make([]float64, len(rankedChunks))
+	for i, embedding := range embeddings {
+		relevance[i] = cosineSimilarity(queryEmbedding, embedding)
+	}
+
+	// redundancy[i] is chunk i's highest similarity to any chunk picked so far
+	redundancy := make([]float64, len(rankedChunks))
+	picked := make([]bool, len(rankedChunks))
+	reordered := make([]types.RankedChunk, 0, len(rankedChunks))
+	for len(reordered) < len(rankedChunks) {
+		best, bestScore := -1, math.Inf(-1)
+		for i := range rankedChunks {
+			if picked[i] {
+				continue
+			}
+			score := lambda * relevance[i]
+			if len(reordered) > 0 {
+				score -= (1 - lambda) * redundancy[i]
+			}
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+
+		picked[best] = true
+		reordered = append(reordered, rankedChunks[best])
+		for i := range rankedChunks {
+			if !picked[i] {
+				similarity := cosineSimilarity(embeddings[i], embeddings[best])
+				if len(reordered) == 1 || similarity > redundancy[i] {
+					redundancy[i] = similarity
+				}
+			}
+		}
+	}
+	return reordered
+}
+
+// cosineSimilarity returns the cosine of the angle between two vectors, or 0
+// when they differ in length or either is zero
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
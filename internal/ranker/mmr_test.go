@@ -0,0 +1,42 @@
+package ranker
+
+import (
+	"testing"
+
+	"go-rag/internal/types"
+)
+
+func TestApplyMMR_LambdaTradesRelevanceForDiversity(t *testing.T) {
+	rankedChunks := []types.RankedChunk{
+		{DocumentChunk: types.DocumentChunk{ID: 1}, Score: 0.9},
+		{DocumentChunk: types.DocumentChunk{ID: 2}, Score: 0.8},
+		{DocumentChunk: types.DocumentChunk{ID: 3}, Score: 0.7},
+	}
+	query := []float64{1, 0}
+	// Chunks 1 and 2 are near-duplicates; chunk 3 is less relevant but different
+	embeddings := [][]float64{{1, 0.1}, {1, 0.12}, {0.6, 0.8}}
+	service := NewService(types.RankingConfig{})
+
+	ids := func(lambda float64) []uint64 {
+		var ids []uint64
+		for _, chunk := range service.ApplyMMR(rankedChunks, query, embeddings, lambda) {
+			ids = append(ids, chunk.ID)
+		}
+		return ids
+	}
+
+	if got := ids(1); got[0] != 1 || got[1] != 2 || got[2] != 3 {
+		t.Errorf("expected a lambda of 1 to keep relevance order 1, 2, 3, got %v", got)
+	}
+	if got := ids(0.1); got[0] != 1 || got[1] != 3 || got[2] != 2 {
+		t.Errorf("expected a small lambda to move the near-duplicate last, got %v", got)
+	}
+
+	// Scores are kept, and mismatched embeddings leave the order alone
+	if reordered := service.ApplyMMR(rankedChunks, query, embeddings, 0); reordered[1].Score != 0.7 {
+		t.Errorf("expected chunk 3 to keep its score, got %+v", reordered[1])
+	}
+	if got := service.ApplyMMR(rankedChunks, query, embeddings[:2], 0); got[1].ID != 2 {
+		t.Errorf("expected the input order without an embedding per chunk, got %+v", got)
+	}
+}
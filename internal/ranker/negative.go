@@ -0,0 +1,31 @@
+package ranker
+
+import "go-rag/internal/types"
+
+// DefaultNegativeQueryWeight is how strongly a negative query demotes similar
+// chunks when no weight is configured
+const DefaultNegativeQueryWeight = 0.5
+
+// ApplyNegativeQuery demotes chunks similar to a negative query, multiplying
+// each score by 1 - weight*sim(negative query, chunk). Only positive cosine
+// similarity counts, so unrelated chunks keep their scores. The embeddings are
+// given in the same order as rankedChunks.
+func (s *Service) ApplyNegativeQuery(rankedChunks []types.RankedChunk, negativeEmbedding []float64, embeddings [][]float64) []types.RankedChunk {
+	if len(embeddings) != len(rankedChunks) {
+		return rankedChunks
+	}
+
+	weight := s.config.NegativeWeight
+	if weight <= 0 {
+		weight = DefaultNegativeQueryWeight
+	}
+
+	for i, embedding := range embeddings {
+		if similarity := cosineSimilarity(negativeEmbedding, embedding); similarity > 0 {
+			rankedChunks[i].Score *= 1 - weight*similarity
+		}
+	}
+
+	sortByScore(rankedChunks)
+	return rankedChunks
+}
@@ -0,0 +1,32 @@
+package ranker
+
+import (
+	"math"
+	"testing"
+
+	"go-rag/internal/types"
+)
+
+func TestApplyNegativeQuery_DemotesSimilarChunks(t *testing.T) {
+	rankedChunks := []types.RankedChunk{
+		{DocumentChunk: types.DocumentChunk{ID: 1}, Score: 1},
+		{DocumentChunk: types.DocumentChunk{ID: 2}, Score: 0.8},
+		{DocumentChunk: types.DocumentChunk{ID: 3}, Score: 0.6},
+	}
+	negative := []float64{1, 0}
+	// Chunk 1 matches the negative query, chunk 2 half does and chunk 3 points away
+	embeddings := [][]float64{{1, 0}, {0.5, math.Sqrt(0.75)}, {-1, 0}}
+
+	demoted := NewService(types.RankingConfig{NegativeWeight: 0.8}).ApplyNegativeQuery(rankedChunks, negative, embeddings)
+
+	// 1: 1*(1-0.8), 2: 0.8*(1-0.4), 3 is unaffected
+	want := []struct {
+		id    uint64
+		score float64
+	}{{3, 0.6}, {2, 0.48}, {1, 0.2}}
+	for i, w := range want {
+		if demoted[i].ID != w.id || math.Abs(demoted[i].Score-w.score) > 1e-9 {
+			t.Errorf("position %d: expected chunk %d with %v, got chunk %d with %v", i, w.id, w.score, demoted[i].ID, demoted[i].Score)
+		}
+	}
+}
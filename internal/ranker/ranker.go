@@ -2,40 +2,248 @@ package ranker
 
 import (
 	"context"
+	"fmt"
+	"log"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
 
 	"go-rag/internal/types"
 )
 
+// Reranker scores chunks against a query using a model, e.g. an embedding
+// similarity or a cross-encoder. Scores must align with the input chunks.
+type Reranker interface {
+	Score(ctx context.Context, query string, chunks []types.DocumentChunk) ([]float64, error)
+}
+
+// Ranking modes decide how the vector store's scores are used
+const (
+	ModeKeyword     = "keyword"     // score by keywords or the reranker, ignoring vector scores
+	ModePassthrough = "passthrough" // keep the vector store's scores
+	ModeBlend       = "blend"       // weighted mix of vector and keyword or reranker scores
+	ModeRRF         = "rrf"         // weighted reciprocal rank fusion of the vector and keyword or reranker rankings
+)
+
+// Lexical scorers used in place of a reranker
+const (
+	ScorerKeyword = "keyword" // share of query words found anywhere in the chunk
+	ScorerBM25    = "bm25"    // Okapi BM25 over the candidate chunks
+)
+
+// DefaultRRFK is the reciprocal rank fusion constant used when none is configured
+const DefaultRRFK = 60
+
 // Service handles ranking and reranking of retrieved chunks
 type Service struct {
-	// Add any dependencies like reranking models here
+	config   types.RankingConfig
+	reranker Reranker
+}
+
+// NewReranker creates the reranker named by the config, or returns nil when
+// none is configured and chunks are scored lexically
+func NewReranker(config types.RankingConfig) (Reranker, error) {
+	switch config.Reranker {
+	case "", "none":
+		return nil, nil
+	case "cohere":
+		return NewCohereReranker(config)
+	default:
+		return nil, fmt.Errorf("unsupported reranker: %s", config.Reranker)
+	}
 }
 
-// NewService creates a new ranking service
-func NewService() *Service {
-	return &Service{}
+// NewService creates a new ranking service that scores chunks by keyword matching
+func NewService(config types.RankingConfig) *Service {
+	return NewServiceWithReranker(config, nil)
+}
+
+// NewServiceWithReranker creates a ranking service that scores chunks with the
+// given reranker, using keyword matching when reranker is nil
+func NewServiceWithReranker(config types.RankingConfig, reranker Reranker) *Service {
+	return &Service{
+		config:   config,
+		reranker: reranker,
+	}
 }
 
 // RankChunks reranks chunks based on relevance to the query
 func (s *Service) RankChunks(ctx context.Context, query string, chunks []types.DocumentChunk) ([]types.RankedChunk, error) {
-	var rankedChunks []types.RankedChunk
-	
-	for _, chunk := range chunks {
-		score := s.calculateRelevanceScore(query, chunk.Content)
-		rankedChunks = append(rankedChunks, types.RankedChunk{
-			DocumentChunk: chunk,
-			Score:         score,
+	// Trust the store's similarity and skip rescoring entirely
+	if s.config.Mode == ModePassthrough {
+		return s.RankByVector(ctx, query, chunks)
+	}
+
+	rankedChunks := make([]types.RankedChunk, len(chunks))
+
+	if err := s.rerank(ctx, query, chunks, rankedChunks); err != nil {
+		if !s.config.FallbackOnError {
+			return nil, fmt.Errorf("failed to rerank chunks: %w", err)
+		}
+		log.Printf("Reranker failed, falling back to keyword scoring: %v", err)
+		s.scoreKeywords(query, chunks, rankedChunks)
+	}
+
+	if s.config.Mode == ModeBlend {
+		weight := s.config.BlendWeight
+		for i := range rankedChunks {
+			rankedChunks[i].Score = weight*rankedChunks[i].VectorScore + (1-weight)*rankedChunks[i].Score
+		}
+	}
+
+	if s.config.Mode == ModeRRF {
+		vectorRanked := slices.Clone(rankedChunks)
+		sort.SliceStable(vectorRanked, func(i, j int) bool {
+			return vectorRanked[i].VectorScore > vectorRanked[j].VectorScore
 		})
+		sortByScore(rankedChunks)
+		return s.FuseRankings(vectorRanked, rankedChunks), nil
 	}
-	
-	// Sort by score in descending order
-	sort.Slice(rankedChunks, func(i, j int) bool {
+
+	sortByScore(rankedChunks)
+	return rankedChunks, nil
+}
+
+// RankByVector ranks chunks by the similarity the vector store computed,
+// without rescoring them, as in passthrough mode
+func (s *Service) RankByVector(ctx context.Context, query string, chunks []types.DocumentChunk) ([]types.RankedChunk, error) {
+	rankedChunks := make([]types.RankedChunk, len(chunks))
+	for i, chunk := range chunks {
+		rankedChunks[i] = types.RankedChunk{DocumentChunk: chunk, Score: chunk.VectorScore}
+	}
+	sortByScore(rankedChunks)
+	return rankedChunks, nil
+}
+
+// RankHybrid ranks chunks like RankChunks and fuses the result with the order
+// the store returned them in, so the vector ranking isn't discarded. In rrf
+// mode RankChunks already fuses the two and RankHybrid is the same.
+func (s *Service) RankHybrid(ctx context.Context, query string, chunks []types.DocumentChunk) ([]types.RankedChunk, error) {
+	rankedChunks, err := s.RankChunks(ctx, query, chunks)
+	if err != nil || s.config.Mode == ModeRRF {
+		return rankedChunks, err
+	}
+
+	vectorRanked := make([]types.RankedChunk, len(chunks))
+	for i, chunk := range chunks {
+		vectorRanked[i] = types.RankedChunk{DocumentChunk: chunk, Score: chunk.VectorScore}
+	}
+	return s.FuseRankings(vectorRanked, rankedChunks), nil
+}
+
+// FuseRankings combines a vector ranking and a lexical ranking, each given
+// best first, with weighted reciprocal rank fusion: a chunk scores
+// weight/(k+rank) summed over the rankings it appears in. Only the order
+// within each ranking matters, not the scale of its scores. Chunks are matched
+// by ID, and ties keep the vector ranking's order.
+func (s *Service) FuseRankings(vectorRanked, lexicalRanked []types.RankedChunk) []types.RankedChunk {
+	k := s.config.RRFK
+	if k <= 0 {
+		k = DefaultRRFK
+	}
+	vectorWeight, lexicalWeight := s.config.RRFVectorWeight, s.config.RRFKeywordWeight
+	if vectorWeight == 0 && lexicalWeight == 0 {
+		vectorWeight, lexicalWeight = 1, 1
+	}
+
+	fused := make([]types.RankedChunk, 0, len(vectorRanked))
+	positions := make(map[uint64]int, len(vectorRanked))
+	add := func(ranking []types.RankedChunk, weight float64) {
+		for rank, chunk := range ranking {
+			i, ok := positions[chunk.ID]
+			if !ok {
+				i = len(fused)
+				positions[chunk.ID] = i
+				chunk.Score = 0
+				fused = append(fused, chunk)
+			}
+			fused[i].Score += weight / (k + float64(rank+1))
+		}
+	}
+	add(vectorRanked, vectorWeight)
+	add(lexicalRanked, lexicalWeight)
+
+	sortByScore(fused)
+	return fused
+}
+
+// sortByScore sorts chunks by descending score, keeping the input order of ties
+func sortByScore(rankedChunks []types.RankedChunk) {
+	sort.SliceStable(rankedChunks, func(i, j int) bool {
 		return rankedChunks[i].Score > rankedChunks[j].Score
 	})
-	
-	return rankedChunks, nil
+}
+
+// rerank scores chunks with the configured reranker, or by keywords if there is none
+func (s *Service) rerank(ctx context.Context, query string, chunks []types.DocumentChunk, out []types.RankedChunk) error {
+	if s.reranker == nil || len(chunks) == 0 {
+		s.scoreKeywords(query, chunks, out)
+		return nil
+	}
+
+	scores, err := s.reranker.Score(ctx, query, chunks)
+	if err != nil {
+		return err
+	}
+	if len(scores) != len(chunks) {
+		return fmt.Errorf("reranker returned %d scores for %d chunks", len(scores), len(chunks))
+	}
+
+	for i, chunk := range chunks {
+		out[i] = types.RankedChunk{
+			DocumentChunk: chunk,
+			Score:         scores[i],
+		}
+	}
+	return nil
+}
+
+// scoreKeywords scores chunks lexically against the query, with BM25 or by
+// keyword overlap
+func (s *Service) scoreKeywords(query string, chunks []types.DocumentChunk, out []types.RankedChunk) {
+	if s.config.Scorer == ScorerBM25 {
+		s.scoreBM25(query, chunks, out)
+		return
+	}
+
+	s.forEachChunk(len(chunks), func(i int) {
+		out[i] = types.RankedChunk{
+			DocumentChunk: chunks[i],
+			Score:         s.calculateRelevanceScore(query, chunks[i].Content),
+		}
+	})
+}
+
+// forEachChunk calls score with each index below n, across a pool of workers
+// when more than one is configured. Each call must write only to its own
+// index, so the output slices need no locking.
+func (s *Service) forEachChunk(n int, score func(i int)) {
+	workers := min(s.config.Workers, n)
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			score(i)
+		}
+		return
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				score(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
 }
 
 // calculateRelevanceScore calculates a simple relevance score
@@ -0,0 +1,197 @@
+package ranker
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+
+	"go-rag/internal/types"
+)
+
+func makeChunks(n int) []types.DocumentChunk {
+	words := []string{"machine", "learning", "neural", "network", "data", "model"}
+	chunks := make([]types.DocumentChunk, n)
+	for i := range chunks {
+		chunks[i] = types.DocumentChunk{
+			ID:         uint64(i + 1),
+			DocumentID: fmt.Sprintf("doc-%d", i%7),
+			Content:    fmt.Sprintf("%s %s chunk %d", words[i%len(words)], words[(i*3)%len(words)], i),
+			ChunkIndex: i,
+		}
+	}
+	return chunks
+}
+
+func TestRankChunks_ParallelBM25MatchesSequential(t *testing.T) {
+	ctx := context.Background()
+	chunks := makeChunks(500)
+	query := "machine learning model"
+
+	sequential, err := NewService(types.RankingConfig{Scorer: ScorerBM25, Workers: 1}).RankChunks(ctx, query, chunks)
+	if err != nil {
+		t.Fatalf("Sequential ranking failed: %v", err)
+	}
+	parallel, err := NewService(types.RankingConfig{Scorer: ScorerBM25, Workers: 8}).RankChunks(ctx, query, chunks)
+	if err != nil {
+		t.Fatalf("Parallel ranking failed: %v", err)
+	}
+	if !reflect.DeepEqual(sequential, parallel) {
+		t.Error("Parallel BM25 ranking produced different results than sequential ranking")
+	}
+}
+
+func TestRankChunks_ParallelMatchesSequential(t *testing.T) {
+	chunks := makeChunks(500)
+	query := "machine learning model"
+	ctx := context.Background()
+
+	sequential, err := NewService(types.RankingConfig{Workers: 1}).RankChunks(ctx, query, chunks)
+	if err != nil {
+		t.Fatalf("Sequential ranking failed: %v", err)
+	}
+
+	parallel, err := NewService(types.RankingConfig{Workers: 8}).RankChunks(ctx, query, chunks)
+	if err != nil {
+		t.Fatalf("Parallel ranking failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(sequential, parallel) {
+		t.Error("Parallel ranking produced different results than sequential ranking")
+	}
+}
+
+// failingReranker simulates an unavailable reranking provider
+type failingReranker struct{}
+
+func (failingReranker) Score(ctx context.Context, query string, chunks []types.DocumentChunk) ([]float64, error) {
+	return nil, fmt.Errorf("reranker unavailable")
+}
+
+func TestRankChunks_FallsBackWhenRerankerFails(t *testing.T) {
+	chunks := []types.DocumentChunk{
+		{ID: 1, Content: "cooking recipes"},
+		{ID: 2, Content: "machine learning models"},
+	}
+
+	service := NewServiceWithReranker(types.RankingConfig{FallbackOnError: true}, failingReranker{})
+	ranked, err := service.RankChunks(context.Background(), "machine learning", chunks)
+	if err != nil {
+		t.Fatalf("Expected fallback ranking, got error: %v", err)
+	}
+
+	if len(ranked) != 2 || ranked[0].ID != 2 {
+		t.Errorf("Expected keyword ranking with chunk 2 first, got %+v", ranked)
+	}
+}
+
+func TestRankChunks_RerankerErrorWithoutFallback(t *testing.T) {
+	service := NewServiceWithReranker(types.RankingConfig{}, failingReranker{})
+	_, err := service.RankChunks(context.Background(), "query", []types.DocumentChunk{{ID: 1, Content: "text"}})
+	if err == nil {
+		t.Error("Expected reranker error when fallback is disabled, got nil")
+	}
+}
+
+func TestRankChunks_PassthroughKeepsStoreScores(t *testing.T) {
+	chunks := makeChunks(6)
+	for i := range chunks {
+		chunks[i].VectorScore = 0.9 - float64(i)*0.1
+	}
+
+	ranked, err := NewService(types.RankingConfig{Mode: ModePassthrough}).RankChunks(context.Background(), "data model", chunks)
+	if err != nil {
+		t.Fatalf("RankChunks failed: %v", err)
+	}
+	for i, chunk := range ranked {
+		if chunk.ID != chunks[i].ID || chunk.Score != chunks[i].VectorScore {
+			t.Errorf("position %d: expected chunk %d with its store score %v, got chunk %d with %v", i, chunks[i].ID, chunks[i].VectorScore, chunk.ID, chunk.Score)
+		}
+	}
+}
+
+func TestRankChunks_BlendMixesScores(t *testing.T) {
+	chunks := []types.DocumentChunk{
+		{ID: 1, Content: "unrelated text", VectorScore: 0.9},
+		{ID: 2, Content: "machine learning", VectorScore: 0.5},
+	}
+	ctx := context.Background()
+
+	ranked, err := NewService(types.RankingConfig{Mode: ModeBlend, BlendWeight: 0.25}).RankChunks(ctx, "machine learning", chunks)
+	if err != nil {
+		t.Fatalf("RankChunks failed: %v", err)
+	}
+	// 0.25*0.5 + 0.75*1 beats 0.25*0.9 + 0.75*0
+	if ranked[0].ID != 2 || ranked[0].Score != 0.875 || ranked[1].Score != 0.225 {
+		t.Errorf("expected blended scores 0.875 and 0.225 with chunk 2 first, got %+v", ranked)
+	}
+
+	ranked, _ = NewService(types.RankingConfig{Mode: ModeBlend, BlendWeight: 1}).RankChunks(ctx, "machine learning", chunks)
+	if ranked[0].ID != 1 {
+		t.Errorf("expected a weight of 1 to rank by vector score alone, got chunk %d first", ranked[0].ID)
+	}
+}
+
+func TestRankChunks_RRFWeightsShiftTheLeader(t *testing.T) {
+	chunks := []types.DocumentChunk{
+		{ID: 1, Content: "unrelated text", VectorScore: 0.9},
+		{ID: 2, Content: "machine learning", VectorScore: 0.5},
+		{ID: 3, Content: "machine", VectorScore: 0.1},
+	}
+	ctx := context.Background()
+
+	rank := func(vectorWeight, keywordWeight float64) []types.RankedChunk {
+		config := types.RankingConfig{Mode: ModeRRF, RRFK: 60, RRFVectorWeight: vectorWeight, RRFKeywordWeight: keywordWeight}
+		ranked, err := NewService(config).RankChunks(ctx, "machine learning", chunks)
+		if err != nil {
+			t.Fatalf("RankChunks failed: %v", err)
+		}
+		return ranked
+	}
+
+	// Chunk 1 leads the vector ranking and chunk 2 the keyword ranking
+	if ranked := rank(3, 1); ranked[0].ID != 1 {
+		t.Errorf("expected a heavy vector weight to put chunk 1 first, got %+v", ranked)
+	}
+	if ranked := rank(1, 3); ranked[0].ID != 2 {
+		t.Errorf("expected a heavy keyword weight to put chunk 2 first, got %+v", ranked)
+	}
+
+	// Chunk 2 is second by vector score and first by keywords
+	ranked := rank(1, 1)
+	if want := 1.0/62 + 1.0/61; ranked[0].ID != 2 || math.Abs(ranked[0].Score-want) > 1e-12 {
+		t.Errorf("expected chunk 2 first with score %v, got %+v", want, ranked)
+	}
+
+	// A zero k falls back to the default constant
+	ranked, _ = NewService(types.RankingConfig{Mode: ModeRRF, RRFVectorWeight: 1}).RankChunks(ctx, "machine learning", chunks)
+	if want := 1.0 / (DefaultRRFK + 1); ranked[0].ID != 1 || math.Abs(ranked[0].Score-want) > 1e-12 {
+		t.Errorf("expected chunk 1 first with score %v, got %+v", want, ranked)
+	}
+}
+
+func TestFuseRankings(t *testing.T) {
+	chunk := func(id uint64) types.RankedChunk {
+		return types.RankedChunk{DocumentChunk: types.DocumentChunk{ID: id}}
+	}
+	vectorRanked := []types.RankedChunk{chunk(1), chunk(2), chunk(3)}
+	lexicalRanked := []types.RankedChunk{chunk(3), chunk(4), chunk(1)}
+
+	fused := NewService(types.RankingConfig{RRFK: 1}).FuseRankings(vectorRanked, lexicalRanked)
+
+	// 1: 1/2 + 1/4, 3: 1/4 + 1/2, 2: 1/3, 4: 1/3; ties keep the vector order,
+	// and chunks found by only one ranking come after it
+	want := []struct {
+		id    uint64
+		score float64
+	}{{1, 0.75}, {3, 0.75}, {2, 1.0 / 3}, {4, 1.0 / 3}}
+	if len(fused) != len(want) {
+		t.Fatalf("expected %d fused chunks, got %+v", len(want), fused)
+	}
+	for i, w := range want {
+		if fused[i].ID != w.id || math.Abs(fused[i].Score-w.score) > 1e-9 {
+			t.Errorf("position %d: expected chunk %d with %v, got chunk %d with %v", i, w.id, w.score, fused[i].ID, fused[i].Score)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package retriever
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"go-rag/internal/cache"
+	"go-rag/internal/types"
+)
+
+// chunkCache is a read-through cache of chunks read by ID and by document.
+// Entries of a document are dropped whenever the document changes.
+type chunkCache struct {
+	chunks    *cache.LRU[types.DocumentChunk]
+	documents *cache.LRU[[]types.DocumentChunk]
+	// version changes with every invalidation, so a read that raced with one
+	// isn't cached with data from before the change
+	version atomic.Uint64
+}
+
+// newChunkCache creates a cache for the configured size, or returns nil when caching is disabled
+func newChunkCache(config types.RetrievalConfig) *chunkCache {
+	if config.ChunkCacheSize <= 0 {
+		return nil
+	}
+	ttl := time.Duration(config.ChunkCacheTTLSecs) * time.Second
+	return &chunkCache{
+		chunks:    cache.NewLRU[types.DocumentChunk](config.ChunkCacheSize, ttl),
+		documents: cache.NewLRU[[]types.DocumentChunk](config.ChunkCacheSize, ttl),
+	}
+}
+
+// chunk returns the chunk with chunkID, reading it with load on a miss
+func (c *chunkCache) chunk(ctx context.Context, chunkID uint64, load func() (*types.DocumentChunk, error)) (*types.DocumentChunk, error) {
+	key := strconv.FormatUint(chunkID, 10)
+	if !cache.Bypassed(ctx) {
+		if chunk, ok := c.chunks.Get(key); ok {
+			return &chunk, nil
+		}
+	}
+
+	version := c.version.Load()
+	chunk, err := load()
+	if err != nil {
+		return nil, err
+	}
+	if c.version.Load() == version {
+		c.chunks.Set(key, *chunk)
+	}
+	return chunk, nil
+}
+
+// document returns the chunks of documentID, reading them with load on a
+// miss. Callers get their own slice, so they may reorder it.
+func (c *chunkCache) document(ctx context.Context, documentID string, load func() ([]types.DocumentChunk, error)) ([]types.DocumentChunk, error) {
+	if chunks, ok := c.cachedDocument(ctx, documentID); ok {
+		return chunks, nil
+	}
+
+	version := c.version.Load()
+	chunks, err := load()
+	if err != nil {
+		return nil, err
+	}
+	c.storeDocument(documentID, chunks, version)
+	return chunks, nil
+}
+
+// cachedDocument returns a copy of the cached chunks of documentID, if any
+func (c *chunkCache) cachedDocument(ctx context.Context, documentID string) ([]types.DocumentChunk, bool) {
+	if cache.Bypassed(ctx) {
+		return nil, false
+	}
+	chunks, ok := c.documents.Get(documentID)
+	if !ok {
+		return nil, false
+	}
+	return slices.Clone(chunks), true
+}
+
+// storeDocument caches the chunks of documentID read at version, unless the
+// document changed since
+func (c *chunkCache) storeDocument(documentID string, chunks []types.DocumentChunk, version uint64) {
+	if c.version.Load() == version {
+		c.documents.Set(documentID, slices.Clone(chunks))
+	}
+}
+
+// invalidate drops the cached chunks of documentID
+func (c *chunkCache) invalidate(documentID string) {
+	c.version.Add(1)
+	c.documents.Delete(documentID)
+	c.chunks.DeleteFunc(func(_ string, chunk types.DocumentChunk) bool {
+		return chunk.DocumentID == documentID
+	})
+}
+
+// stats combines the counters of the chunk and document caches
+func (c *chunkCache) stats() types.CacheStats {
+	chunks, documents := c.chunks.Stats(), c.documents.Stats()
+	return types.CacheStats{
+		Hits:    chunks.Hits + documents.Hits,
+		Misses:  chunks.Misses + documents.Misses,
+		Entries: chunks.Entries + documents.Entries,
+	}
+}
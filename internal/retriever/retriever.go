@@ -2,41 +2,251 @@ package retriever
 
 import (
 	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
 
+	"go-rag/internal/chunk"
+	"go-rag/internal/embedding"
 	"go-rag/internal/store"
 	"go-rag/internal/types"
 )
 
+// ErrDocumentNotFound is returned when a document has no stored chunks
+var ErrDocumentNotFound = errors.New("document not found")
+
+// ErrStatsUnsupported is returned by Stats when the store can't summarize its collection
+var ErrStatsUnsupported = errors.New("vector store does not report collection statistics")
+
+// dedupeOverfetch is how many candidates per result are searched when
+// duplicates are collapsed, so collapsing still fills the limit
+const dedupeOverfetch = 2
+
+// maxCachedStreamChunks bounds the documents StreamByDocumentID caches, so
+// streaming a huge document doesn't buffer it in memory
+const maxCachedStreamChunks = 1000
+
 // Service handles document retrieval
 type Service struct {
-	store store.VectorStore
+	store  store.VectorStore
+	config types.RetrievalConfig
+	// cache holds chunks read by ID and by document; nil when disabled
+	cache *chunkCache
+	// embeddings is the collection's embedding model, used by Embed
+	embeddings embedding.Service
 }
 
 // NewService creates a new retrieval service
-func NewService(store store.VectorStore) *Service {
+func NewService(store store.VectorStore, config types.RetrievalConfig) *Service {
 	return &Service{
-		store: store,
+		store:  store,
+		config: config,
+		cache:  newChunkCache(config),
+	}
+}
+
+// WithEmbeddingService returns a copy of the service that embeds with the given
+// model, which should be the one the store's collection was built with
+func (s *Service) WithEmbeddingService(embeddings embedding.Service) *Service {
+	derived := *s
+	derived.embeddings = embeddings
+	return &derived
+}
+
+// Embed embeds the query and each chunk's content, reusing any vector a chunk
+// already carries. It needs an embedding service; see WithEmbeddingService.
+func (s *Service) Embed(ctx context.Context, query string, chunks []types.DocumentChunk) ([]float64, [][]float64, error) {
+	if s.embeddings == nil {
+		return nil, nil, fmt.Errorf("no embedding service configured for retrieval")
+	}
+
+	queryEmbedding, err := s.embeddings.GenerateEmbedding(embedding.WithInputType(ctx, embedding.InputTypeQuery), s.NormalizeQuery(query))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+
+	embeddings := make([][]float64, len(chunks))
+	var texts []string
+	var missing []int
+	for i, chunk := range chunks {
+		if chunk.Embedding != nil {
+			embeddings[i] = chunk.Embedding
+			continue
+		}
+		texts = append(texts, chunk.Content)
+		missing = append(missing, i)
+	}
+	if len(texts) > 0 {
+		generated, err := s.embeddings.GenerateEmbeddings(embedding.WithInputType(ctx, embedding.InputTypeDocument), texts)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to embed chunks: %w", err)
+		}
+		for j, i := range missing {
+			embeddings[i] = generated[j]
+		}
+	}
+
+	return queryEmbedding, embeddings, nil
+}
+
+// InvalidateDocument drops any cached chunks of a document, so the next read
+// sees its latest version. Call it whenever a document is written or deleted.
+func (s *Service) InvalidateDocument(documentID string) {
+	if s.cache != nil {
+		s.cache.invalidate(documentID)
+	}
+}
+
+// CacheStats reports the hits and misses of the chunk cache, or false when
+// caching is disabled
+func (s *Service) CacheStats() (types.CacheStats, bool) {
+	if s.cache == nil {
+		return types.CacheStats{}, false
+	}
+	return s.cache.stats(), true
+}
+
+// Describe reports the collection and distance metric searched by this service,
+// or an empty description when the store can't provide one
+func (s *Service) Describe() types.ResponseMeta {
+	if describer, ok := s.store.(store.CollectionDescriber); ok {
+		return describer.Describe()
 	}
+	return types.ResponseMeta{}
+}
+
+// Stats summarizes the collection searched by this service
+func (s *Service) Stats(ctx context.Context) (*types.CollectionStats, error) {
+	reporter, ok := s.store.(store.StatsReporter)
+	if !ok {
+		return nil, ErrStatsUnsupported
+	}
+	return reporter.Stats(ctx)
 }
 
 // RetrieveRelevantChunks finds the most relevant document chunks for a query
 func (s *Service) RetrieveRelevantChunks(ctx context.Context, query string, limit int) ([]types.DocumentChunk, error) {
+	return s.RetrieveFromVector(ctx, query, "", limit, nil)
+}
+
+// RetrieveFromVector finds the most relevant chunks by comparing the query
+// against a named vector (e.g. "title"); an empty name uses the default vector.
+// filters restricts results to chunks with matching metadata. When the search
+// timed out with partial results enabled, the chunks found are returned with
+// an error wrapping store.ErrPartialResults.
+func (s *Service) RetrieveFromVector(ctx context.Context, query, vectorName string, limit int, filters map[string]string) ([]types.DocumentChunk, error) {
 	if limit <= 0 {
 		limit = 10 // default limit
 	}
-	
-	chunks, err := s.store.SearchSimilar(ctx, query, limit)
+
+	query = s.NormalizeQuery(query)
+
+	chunks, err := s.store.SearchSimilar(ctx, query, s.searchLimit(limit), vectorName, filters)
+	if errors.Is(err, store.ErrPartialResults) {
+		return s.collapseDuplicates(expandWindows(chunks), limit), err
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to search similar chunks: %w", err)
 	}
-	
-	return chunks, nil
+
+	return s.collapseDuplicates(expandWindows(chunks), limit), nil
+}
+
+// searchLimit is how many chunks to search for to return limit results
+func (s *Service) searchLimit(limit int) int {
+	if s.config.DedupeAcrossDocuments {
+		return limit * dedupeOverfetch
+	}
+	return limit
+}
+
+// collapseDuplicates keeps the first, best-scored of the chunks that share
+// content across documents, listing the other documents on it, and returns
+// at most limit chunks. Repeated content within one document is kept.
+func (s *Service) collapseDuplicates(chunks []types.DocumentChunk, limit int) []types.DocumentChunk {
+	if !s.config.DedupeAcrossDocuments {
+		return chunks
+	}
+
+	kept := make(map[[sha256.Size]byte]int, len(chunks))
+	results := chunks[:0]
+	for _, chunk := range chunks {
+		// Whitespace differences don't make a passage distinct
+		hash := sha256.Sum256([]byte(strings.Join(strings.Fields(chunk.Content), " ")))
+		i, seen := kept[hash]
+		if !seen {
+			kept[hash] = len(results)
+			results = append(results, chunk)
+			continue
+		}
+		if first := &results[i]; first.DocumentID == chunk.DocumentID {
+			results = append(results, chunk)
+		} else if !slices.Contains(first.DuplicateDocumentIDs, chunk.DocumentID) {
+			first.DuplicateDocumentIDs = append(first.DuplicateDocumentIDs, chunk.DocumentID)
+		}
+	}
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// expandWindows returns the stored sentence window as the content of each
+// chunk that has one, keeping the matched sentence in MatchedText
+func expandWindows(chunks []types.DocumentChunk) []types.DocumentChunk {
+	for i := range chunks {
+		if chunks[i].Window == "" {
+			continue
+		}
+		chunks[i].MatchedText = chunks[i].Content
+		chunks[i].Content = chunks[i].Window
+		chunks[i].Window = ""
+	}
+	return chunks
+}
+
+// RetrieveWithDiagnostics is RetrieveFromVector that also reports how the store
+// ran the search; diagnostics are nil when the store can't provide them
+func (s *Service) RetrieveWithDiagnostics(ctx context.Context, query, vectorName string, limit int, filters map[string]string) ([]types.DocumentChunk, *types.SearchDiagnostics, error) {
+	searcher, ok := s.store.(store.DiagnosticSearcher)
+	if !ok {
+		chunks, err := s.RetrieveFromVector(ctx, query, vectorName, limit, filters)
+		return chunks, nil, err
+	}
+
+	if limit <= 0 {
+		limit = 10 // default limit
+	}
+
+	chunks, diagnostics, err := searcher.SearchWithDiagnostics(ctx, s.NormalizeQuery(query), s.searchLimit(limit), vectorName, filters)
+	if errors.Is(err, store.ErrPartialResults) {
+		return s.collapseDuplicates(expandWindows(chunks), limit), diagnostics, err
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to search similar chunks: %w", err)
+	}
+
+	return s.collapseDuplicates(expandWindows(chunks), limit), diagnostics, nil
 }
 
 // RetrieveByDocumentID gets all chunks for a specific document
 func (s *Service) RetrieveByDocumentID(ctx context.Context, documentID string) ([]types.DocumentChunk, error) {
-	chunks, err := s.store.GetChunksByDocumentID(ctx, documentID)
+	load := func() ([]types.DocumentChunk, error) {
+		return s.store.GetChunksByDocumentID(ctx, documentID)
+	}
+
+	var chunks []types.DocumentChunk
+	var err error
+	if s.cache != nil {
+		chunks, err = s.cache.document(ctx, documentID, load)
+	} else {
+		chunks, err = load()
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get chunks by document ID: %w", err)
 	}
@@ -44,12 +254,133 @@ func (s *Service) RetrieveByDocumentID(ctx context.Context, documentID string) (
 	return chunks, nil
 }
 
+// StreamByDocumentID calls fn for each chunk of a document in chunk_index order.
+// A cached document is served from the cache. Stores that can't stream are
+// read in full and sorted first; a streamed document of up to
+// maxCachedStreamChunks chunks is cached once it has been read to the end.
+func (s *Service) StreamByDocumentID(ctx context.Context, documentID string, fn func(types.DocumentChunk) error) error {
+	streamer, ok := s.store.(store.ChunkStreamer)
+	if !ok {
+		chunks, err := s.RetrieveByDocumentID(ctx, documentID)
+		if err != nil {
+			return err
+		}
+		return emitInOrder(chunks, fn)
+	}
+
+	if s.cache == nil {
+		if err := streamer.StreamChunksByDocumentID(ctx, documentID, fn); err != nil {
+			return fmt.Errorf("failed to stream document chunks: %w", err)
+		}
+		return nil
+	}
+	if chunks, ok := s.cache.cachedDocument(ctx, documentID); ok {
+		return emitInOrder(chunks, fn)
+	}
+
+	version := s.cache.version.Load()
+	var streamed []types.DocumentChunk
+	tooLarge := false
+	err := streamer.StreamChunksByDocumentID(ctx, documentID, func(chunk types.DocumentChunk) error {
+		if !tooLarge {
+			streamed = append(streamed, chunk)
+			if len(streamed) > maxCachedStreamChunks {
+				streamed, tooLarge = nil, true
+			}
+		}
+		return fn(chunk)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to stream document chunks: %w", err)
+	}
+	if len(streamed) > 0 {
+		s.cache.storeDocument(documentID, streamed, version)
+	}
+	return nil
+}
+
+// emitInOrder calls fn for each chunk in chunk_index order
+func emitInOrder(chunks []types.DocumentChunk, fn func(types.DocumentChunk) error) error {
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i].ChunkIndex < chunks[j].ChunkIndex })
+	for _, chunk := range chunks {
+		if err := fn(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReconstructDocument rebuilds a document's text from its chunks, honoring the
+// chunking strategy recorded on them. Chunks stored without a strategy were
+// produced by sentence chunking.
+func (s *Service) ReconstructDocument(ctx context.Context, documentID string) (*types.DocumentContentResponse, error) {
+	chunks, err := s.RetrieveByDocumentID(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrDocumentNotFound, documentID)
+	}
+
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i].ChunkIndex < chunks[j].ChunkIndex })
+
+	strategy := chunks[0].ChunkStrategy
+	if strategy == "" {
+		strategy = chunk.StrategySentence
+	}
+
+	contents := make([]string, len(chunks))
+	overlaps := make([]int, len(chunks))
+	for i, c := range chunks {
+		contents[i] = c.Content
+		overlaps[i] = c.ChunkOverlap
+	}
+
+	return &types.DocumentContentResponse{
+		DocumentID:    documentID,
+		Content:       chunk.ReconstructChunks(strategy, contents, overlaps),
+		ChunkStrategy: strategy,
+		TotalChunks:   len(chunks),
+	}, nil
+}
+
 // RetrieveChunkByID gets a specific chunk by its ID
 func (s *Service) RetrieveChunkByID(ctx context.Context, chunkID uint64) (*types.DocumentChunk, error) {
-	chunk, err := s.store.GetChunkByID(ctx, chunkID)
+	load := func() (*types.DocumentChunk, error) {
+		return s.store.GetChunkByID(ctx, chunkID)
+	}
+
+	var chunk *types.DocumentChunk
+	var err error
+	if s.cache != nil {
+		chunk, err = s.cache.chunk(ctx, chunkID, load)
+	} else {
+		chunk, err = load()
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get chunk by ID: %w", err)
 	}
 
 	return chunk, nil
 }
+
+// NormalizeQuery canonicalizes a query so trivially different phrasings embed
+// (and cache) identically. It is a no-op unless normalization is enabled.
+func (s *Service) NormalizeQuery(query string) string {
+	if !s.config.NormalizeQuery {
+		return query
+	}
+
+	// Trim and collapse internal whitespace
+	query = strings.Join(strings.Fields(query), " ")
+
+	// Strip trailing punctuation such as "?" or "..."
+	query = strings.TrimRightFunc(query, unicode.IsPunct)
+	query = strings.TrimSpace(query)
+
+	if s.config.LowercaseQuery {
+		query = strings.ToLower(query)
+	}
+
+	return query
+}
@@ -0,0 +1,264 @@
+package retriever
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"go-rag/internal/cache"
+	"go-rag/internal/embedding"
+	"go-rag/internal/store"
+	"go-rag/internal/types"
+)
+
+func TestNormalizeQuery(t *testing.T) {
+	service := NewService(nil, types.RetrievalConfig{NormalizeQuery: true, LowercaseQuery: true})
+
+	a := service.NormalizeQuery("What is AI?")
+	b := service.NormalizeQuery("  what   is ai ")
+	if a != b {
+		t.Errorf("Expected queries to normalize identically, got %q and %q", a, b)
+	}
+	if a != "what is ai" {
+		t.Errorf("Expected 'what is ai', got %q", a)
+	}
+}
+
+func TestNormalizeQuery_Disabled(t *testing.T) {
+	service := NewService(nil, types.RetrievalConfig{})
+
+	if got := service.NormalizeQuery("What is AI?"); got != "What is AI?" {
+		t.Errorf("Expected query to be unchanged, got %q", got)
+	}
+}
+
+func TestRetrieveFromVector_CollapsesDuplicatesAcrossDocuments(t *testing.T) {
+	embedder, err := embedding.NewMockService(types.EmbeddingConfig{Provider: "mock", Dimensions: 16})
+	if err != nil {
+		t.Fatalf("Failed to create embedding service: %v", err)
+	}
+	memory, err := store.NewMemoryStore(types.VectorStoreConfig{Provider: "memory", CollectionName: "documents"}, embedder)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	footer := "Copyright Example Corp. All rights reserved."
+	chunks := []types.DocumentChunk{
+		{ID: 1, DocumentID: "a", Content: footer},
+		{ID: 2, DocumentID: "b", Content: "Copyright  Example Corp.\nAll rights reserved."},
+		{ID: 3, DocumentID: "c", Content: footer},
+		{ID: 4, DocumentID: "a", ChunkIndex: 1, Content: footer},
+		{ID: 5, DocumentID: "b", ChunkIndex: 1, Content: "Quarterly revenue grew."},
+	}
+	if err := memory.StoreChunks(context.Background(), chunks); err != nil {
+		t.Fatalf("StoreChunks failed: %v", err)
+	}
+
+	service := NewService(memory, types.RetrievalConfig{DedupeAcrossDocuments: true})
+	results, err := service.RetrieveFromVector(context.Background(), footer, "", 10, nil)
+	if err != nil {
+		t.Fatalf("RetrieveFromVector failed: %v", err)
+	}
+
+	// Document a's two copies stay separate; b's and c's collapse into the first
+	var footers []types.DocumentChunk
+	for _, chunk := range results {
+		if chunk.ID != 5 {
+			footers = append(footers, chunk)
+		}
+	}
+	if len(results) != 3 || len(footers) != 2 || footers[0].DocumentID != "a" || footers[1].DocumentID != "a" {
+		t.Fatalf("Expected document a's footers and the revenue chunk, got %+v", results)
+	}
+	duplicates := slices.Concat(footers[0].DuplicateDocumentIDs, footers[1].DuplicateDocumentIDs)
+	slices.Sort(duplicates)
+	if !slices.Equal(duplicates, []string{"b", "c"}) || len(footers[0].DuplicateDocumentIDs) != 2 {
+		t.Errorf("Expected b and c listed on the best-scored footer, got %v and %v", footers[0].DuplicateDocumentIDs, footers[1].DuplicateDocumentIDs)
+	}
+
+	// The limit applies after collapsing
+	results, err = service.RetrieveFromVector(context.Background(), footer, "", 1, nil)
+	if err != nil || len(results) != 1 {
+		t.Errorf("Expected one result, got %d: %v", len(results), err)
+	}
+
+	service = NewService(memory, types.RetrievalConfig{})
+	if results, _ := service.RetrieveFromVector(context.Background(), footer, "", 10, nil); len(results) != 5 {
+		t.Errorf("Expected every chunk without deduplication, got %d", len(results))
+	}
+}
+
+// countingStore counts the chunk reads that reach the store
+type countingStore struct {
+	store.VectorStore
+	chunkReads, documentReads int
+}
+
+func (s *countingStore) GetChunkByID(ctx context.Context, chunkID uint64) (*types.DocumentChunk, error) {
+	s.chunkReads++
+	return s.VectorStore.GetChunkByID(ctx, chunkID)
+}
+
+func (s *countingStore) GetChunksByDocumentID(ctx context.Context, documentID string) ([]types.DocumentChunk, error) {
+	s.documentReads++
+	return s.VectorStore.GetChunksByDocumentID(ctx, documentID)
+}
+
+// streamingStore streams document chunks, counting the streams
+type streamingStore struct {
+	*countingStore
+	streams int
+}
+
+func (s *streamingStore) StreamChunksByDocumentID(ctx context.Context, documentID string, fn func(types.DocumentChunk) error) error {
+	s.streams++
+	chunks, err := s.VectorStore.GetChunksByDocumentID(ctx, documentID)
+	if err != nil {
+		return err
+	}
+	slices.SortFunc(chunks, func(a, b types.DocumentChunk) int { return a.ChunkIndex - b.ChunkIndex })
+	for _, chunk := range chunks {
+		if err := fn(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestChunkCache_StreamByDocumentID(t *testing.T) {
+	embedder, err := embedding.NewMockService(types.EmbeddingConfig{Provider: "mock", Dimensions: 16})
+	if err != nil {
+		t.Fatalf("Failed to create embedding service: %v", err)
+	}
+	memory, err := store.NewMemoryStore(types.VectorStoreConfig{Provider: "memory", CollectionName: "documents"}, embedder)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	ctx := context.Background()
+	memory.StoreChunks(ctx, []types.DocumentChunk{
+		{ID: 1, DocumentID: "a", ChunkIndex: 1, Content: "second"},
+		{ID: 2, DocumentID: "a", Content: "first"},
+	})
+
+	streaming := &streamingStore{countingStore: &countingStore{VectorStore: memory}}
+	service := NewService(streaming, types.RetrievalConfig{ChunkCacheSize: 10})
+	read := func(ctx context.Context) []string {
+		var contents []string
+		if err := service.StreamByDocumentID(ctx, "a", func(chunk types.DocumentChunk) error {
+			contents = append(contents, chunk.Content)
+			return nil
+		}); err != nil {
+			t.Fatalf("StreamByDocumentID failed: %v", err)
+		}
+		return contents
+	}
+
+	// The first read streams the document and caches it for the next ones
+	for range 2 {
+		if contents := read(ctx); !slices.Equal(contents, []string{"first", "second"}) {
+			t.Errorf("Expected chunks in order, got %v", contents)
+		}
+	}
+	service.RetrieveByDocumentID(ctx, "a")
+	if streaming.streams != 1 || streaming.documentReads != 0 {
+		t.Errorf("Expected one stream and cache hits after it, got %d streams and %d document reads", streaming.streams, streaming.documentReads)
+	}
+
+	// A change or a bypass reads the store again
+	service.InvalidateDocument("a")
+	read(ctx)
+	read(cache.WithBypass(ctx))
+	if streaming.streams != 3 {
+		t.Errorf("Expected invalidated and bypassed reads to stream, got %d streams", streaming.streams)
+	}
+}
+
+func TestChunkCache_ReadThroughAndInvalidate(t *testing.T) {
+	embedder, err := embedding.NewMockService(types.EmbeddingConfig{Provider: "mock", Dimensions: 16})
+	if err != nil {
+		t.Fatalf("Failed to create embedding service: %v", err)
+	}
+	memory, err := store.NewMemoryStore(types.VectorStoreConfig{Provider: "memory", CollectionName: "documents"}, embedder)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	ctx := context.Background()
+	memory.StoreChunks(ctx, []types.DocumentChunk{
+		{ID: 1, DocumentID: "a", Content: "first version"},
+		{ID: 2, DocumentID: "a", ChunkIndex: 1, Content: "second chunk"},
+		{ID: 3, DocumentID: "b", Content: "other document"},
+	})
+
+	counting := &countingStore{VectorStore: memory}
+	service := NewService(counting, types.RetrievalConfig{ChunkCacheSize: 10, ChunkCacheTTLSecs: 60})
+
+	// The second fetch of the same chunk is served from the cache
+	for range 2 {
+		if chunk, err := service.RetrieveChunkByID(ctx, 1); err != nil || chunk.Content != "first version" {
+			t.Fatalf("Unexpected chunk %+v: %v", chunk, err)
+		}
+	}
+	for range 2 {
+		chunks, err := service.RetrieveByDocumentID(ctx, "a")
+		if err != nil || len(chunks) != 2 {
+			t.Fatalf("Expected 2 chunks, got %d: %v", len(chunks), err)
+		}
+		// Reordering the result must not reorder the cached copy
+		slices.Reverse(chunks)
+	}
+	service.RetrieveChunkByID(ctx, 3)
+	if counting.chunkReads != 2 || counting.documentReads != 1 {
+		t.Errorf("Expected repeated reads to hit the cache, got %d chunk and %d document reads", counting.chunkReads, counting.documentReads)
+	}
+	if stats, ok := service.CacheStats(); !ok || stats.Hits != 2 || stats.Misses != 3 {
+		t.Errorf("Expected 2 hits and 3 misses, got %+v", stats)
+	}
+
+	// Updating a document drops its cached chunks but keeps other documents'
+	memory.StoreChunks(ctx, []types.DocumentChunk{{ID: 1, DocumentID: "a", Content: "updated version"}})
+	service.InvalidateDocument("a")
+	if chunk, _ := service.RetrieveChunkByID(ctx, 1); chunk.Content != "updated version" {
+		t.Errorf("Expected the updated chunk after invalidation, got %q", chunk.Content)
+	}
+	service.RetrieveByDocumentID(ctx, "a")
+	service.RetrieveChunkByID(ctx, 3)
+	if counting.chunkReads != 3 || counting.documentReads != 2 {
+		t.Errorf("Expected only document a to be re-read, got %d chunk and %d document reads", counting.chunkReads, counting.documentReads)
+	}
+
+	// Requests that bypass caches read the store
+	service.RetrieveChunkByID(cache.WithBypass(ctx), 3)
+	if counting.chunkReads != 4 {
+		t.Errorf("Expected a bypassed read to reach the store, got %d chunk reads", counting.chunkReads)
+	}
+
+	if _, ok := NewService(counting, types.RetrievalConfig{}).CacheStats(); ok {
+		t.Error("Expected no cache unless a size is configured")
+	}
+}
+
+func TestEmbed_ReusesCarriedVectors(t *testing.T) {
+	embedder, err := embedding.NewMockService(types.EmbeddingConfig{Provider: "mock", Dimensions: 4})
+	if err != nil {
+		t.Fatalf("Failed to create embedding service: %v", err)
+	}
+	ctx := context.Background()
+	chunks := []types.DocumentChunk{
+		{ID: 1, Content: "first chunk"},
+		{ID: 2, Content: "second chunk", Embedding: []float64{1, 2, 3, 4}},
+	}
+
+	if _, _, err := NewService(nil, types.RetrievalConfig{}).Embed(ctx, "query", chunks); err == nil {
+		t.Error("Expected an error without an embedding service")
+	}
+
+	service := NewService(nil, types.RetrievalConfig{}).WithEmbeddingService(embedder)
+	query, embeddings, err := service.Embed(ctx, "query", chunks)
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	want, _ := embedder.GenerateEmbedding(ctx, "first chunk")
+	if len(query) != 4 || !slices.Equal(embeddings[0], want) || !slices.Equal(embeddings[1], chunks[1].Embedding) {
+		t.Errorf("Expected a generated and a carried vector, got %v", embeddings)
+	}
+}
@@ -0,0 +1,232 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Budget bounds the total number of retries made while serving one request,
+// shared by every provider call the request makes
+type Budget struct {
+	remaining atomic.Int64
+}
+
+// NewBudget creates a budget allowing n retries
+func NewBudget(n int) *Budget {
+	b := &Budget{}
+	b.remaining.Store(int64(n))
+	return b
+}
+
+// Take consumes one retry, reporting false once the budget is exhausted.
+// A nil budget is unlimited.
+func (b *Budget) Take() bool {
+	if b == nil {
+		return true
+	}
+	for {
+		remaining := b.remaining.Load()
+		if remaining <= 0 {
+			return false
+		}
+		if b.remaining.CompareAndSwap(remaining, remaining-1) {
+			return true
+		}
+	}
+}
+
+// Remaining returns the number of retries left
+func (b *Budget) Remaining() int {
+	return int(b.remaining.Load())
+}
+
+type budgetKey struct{}
+
+// WithBudget returns a context whose provider calls draw retries from budget
+func WithBudget(ctx context.Context, budget *Budget) context.Context {
+	return context.WithValue(ctx, budgetKey{}, budget)
+}
+
+// BudgetFromContext returns the request's retry budget, or nil when there is none
+func BudgetFromContext(ctx context.Context) *Budget {
+	budget, _ := ctx.Value(budgetKey{}).(*Budget)
+	return budget
+}
+
+// Policy controls how a single provider call is retried
+type Policy struct {
+	MaxRetries int           // retries after the first attempt; 0 disables retrying
+	Delay      time.Duration // wait before the first retry, doubled for each one after
+	MaxDelay   time.Duration // longest wait between attempts; 0 leaves it uncapped
+}
+
+// Do runs fn, retrying retryable errors up to the policy's limit while the
+// context's retry budget allows. Waits back off exponentially with jitter,
+// unless the server said how long to wait with Retry-After. A retry that
+// couldn't start before the context's deadline isn't attempted.
+func Do(ctx context.Context, policy Policy, fn func() error) error {
+	hint, _ := ctx.Value(hintKey{}).(*retryAfterHint)
+
+	err := fn()
+	for attempt := 0; err != nil && attempt < policy.MaxRetries && IsRetryable(err); attempt++ {
+		wait := policy.backoff(attempt)
+		if after := retryAfter(err, hint); after > 0 {
+			wait = after
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			break
+		}
+		if !BudgetFromContext(ctx).Take() {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+
+		hint.reset()
+		err = fn()
+	}
+	return err
+}
+
+// backoff returns the wait before the given retry: the delay doubled once per
+// earlier retry and capped at MaxDelay, of which a random half is waited, so
+// clients that failed together don't retry together
+func (p Policy) backoff(attempt int) time.Duration {
+	delay := p.Delay
+	for i := 0; i < attempt && delay > 0 && delay < math.MaxInt64/2; i++ {
+		if p.MaxDelay > 0 && delay >= p.MaxDelay {
+			break
+		}
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 1 {
+		return delay
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// retryAfterer is implemented by provider errors that carry the wait a
+// server asked for before retrying
+type retryAfterer interface {
+	RetryAfter() time.Duration
+}
+
+// retryAfter returns how long the server asked to wait after err, from the
+// error itself or from the response Transport saw, or 0 when it didn't say
+func retryAfter(err error, hint *retryAfterHint) time.Duration {
+	var afterErr retryAfterer
+	if errors.As(err, &afterErr) && afterErr.RetryAfter() > 0 {
+		return afterErr.RetryAfter()
+	}
+	return hint.get()
+}
+
+// ParseRetryAfter reads a Retry-After header, given in seconds or as an HTTP
+// date, returning 0 when it's missing or invalid
+func ParseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0)
+	}
+	return 0
+}
+
+type hintKey struct{}
+
+// retryAfterHint holds the Retry-After of the last response Transport saw
+// for a call, for clients whose errors don't carry response headers
+type retryAfterHint struct {
+	after atomic.Int64
+}
+
+func (h *retryAfterHint) get() time.Duration {
+	if h == nil {
+		return 0
+	}
+	return time.Duration(h.after.Load())
+}
+
+func (h *retryAfterHint) reset() {
+	if h != nil {
+		h.after.Store(0)
+	}
+}
+
+// WithRetryAfter returns a context under which Transport records the
+// Retry-After header of failed responses, for Do to honor when retrying calls
+// made with it
+func WithRetryAfter(ctx context.Context) context.Context {
+	return context.WithValue(ctx, hintKey{}, &retryAfterHint{})
+}
+
+// Transport wraps base, or http.DefaultTransport when it's nil, to record
+// the Retry-After header of retryable responses to requests made with a
+// WithRetryAfter context
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := base.RoundTrip(req)
+		if hint, ok := req.Context().Value(hintKey{}).(*retryAfterHint); ok && err == nil && retryableStatus(resp.StatusCode) {
+			hint.after.Store(int64(ParseRetryAfter(resp.Header.Get("Retry-After"))))
+		}
+		return resp, err
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// IsRetryable reports whether err is a transient provider failure: rate
+// limiting, a server error or a network error. Errors from providers other
+// than OpenAI are classified by their StatusCode method, if they have one.
+func IsRetryable(err error) bool {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return retryableStatus(apiErr.HTTPStatusCode)
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return retryableStatus(reqErr.HTTPStatusCode)
+	}
+	var statusErr statusCoder
+	if errors.As(err, &statusErr) {
+		return retryableStatus(statusErr.StatusCode())
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// statusCoder is implemented by provider errors that carry an HTTP status
+type statusCoder interface {
+	StatusCode() int
+}
+
+func retryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
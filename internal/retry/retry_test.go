@@ -0,0 +1,200 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+var errUnavailable = &openai.APIError{HTTPStatusCode: http.StatusServiceUnavailable, Message: "unavailable"}
+
+func TestDo_SharedBudget(t *testing.T) {
+	ctx := WithBudget(context.Background(), NewBudget(3))
+	policy := Policy{MaxRetries: 5}
+
+	// Embedding keeps failing and uses up the whole budget
+	embeddingCalls := 0
+	err := Do(ctx, policy, func() error {
+		embeddingCalls++
+		return errUnavailable
+	})
+	if err == nil {
+		t.Fatal("expected embedding to fail")
+	}
+	if embeddingCalls != 4 {
+		t.Errorf("expected 1 attempt + 3 retries for embedding, got %d calls", embeddingCalls)
+	}
+
+	// Generation gets no retries left
+	generationCalls := 0
+	Do(ctx, policy, func() error {
+		generationCalls++
+		return errUnavailable
+	})
+	if generationCalls != 1 {
+		t.Errorf("expected a single generation attempt once the budget is spent, got %d", generationCalls)
+	}
+}
+
+func TestDo_PartialBudgetLeavesFewerRetries(t *testing.T) {
+	ctx := WithBudget(context.Background(), NewBudget(3))
+	policy := Policy{MaxRetries: 2}
+
+	// Embedding succeeds on its second retry, consuming two retries
+	embeddingCalls := 0
+	if err := Do(ctx, policy, func() error {
+		embeddingCalls++
+		if embeddingCalls < 3 {
+			return errUnavailable
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("expected embedding to succeed, got %v", err)
+	}
+
+	generationCalls := 0
+	Do(ctx, policy, func() error {
+		generationCalls++
+		return errUnavailable
+	})
+	if generationCalls != 2 {
+		t.Errorf("expected generation to get the one remaining retry, got %d calls", generationCalls)
+	}
+}
+
+func TestDo_NonRetryableAndNoBudget(t *testing.T) {
+	calls := 0
+	Do(context.Background(), Policy{MaxRetries: 3}, func() error {
+		calls++
+		return errors.New("invalid request")
+	})
+	if calls != 1 {
+		t.Errorf("expected non-retryable error not to be retried, got %d calls", calls)
+	}
+
+	// Without a budget in the context only the policy limit applies
+	calls = 0
+	Do(context.Background(), Policy{MaxRetries: 2}, func() error {
+		calls++
+		return errUnavailable
+	})
+	if calls != 3 {
+		t.Errorf("expected 3 calls without a budget, got %d", calls)
+	}
+}
+
+// retryAfterError is a retryable failure that asks for a specific wait
+type retryAfterError struct {
+	after time.Duration
+}
+
+func (e *retryAfterError) Error() string             { return "rate limited" }
+func (e *retryAfterError) StatusCode() int           { return http.StatusTooManyRequests }
+func (e *retryAfterError) RetryAfter() time.Duration { return e.after }
+
+func TestPolicy_BackoffDoublesWithJitterUpToMaxDelay(t *testing.T) {
+	policy := Policy{Delay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt, full := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		full *= time.Millisecond
+		for range 20 {
+			if wait := policy.backoff(attempt); wait < full/2 || wait > full {
+				t.Fatalf("retry %d: expected a wait between %v and %v, got %v", attempt, full/2, full, wait)
+			}
+		}
+	}
+
+	// Without a cap the delay keeps doubling, without overflowing
+	if wait := (Policy{Delay: time.Second}).backoff(100); wait <= 0 {
+		t.Errorf("expected a positive wait for a late retry, got %v", wait)
+	}
+	if wait := (Policy{}).backoff(3); wait != 0 {
+		t.Errorf("expected no wait without a delay, got %v", wait)
+	}
+}
+
+func TestDo_HonorsRetryAfterAndDeadline(t *testing.T) {
+	calls := 0
+	start := time.Now()
+	err := Do(context.Background(), Policy{MaxRetries: 1, Delay: time.Hour}, func() error {
+		calls++
+		if calls == 1 {
+			return &retryAfterError{after: 20 * time.Millisecond}
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Fatalf("expected a successful retry, got %v after %d calls", err, calls)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond || elapsed > time.Second {
+		t.Errorf("expected to wait the Retry-After instead of the backoff, waited %v", elapsed)
+	}
+
+	// A wait past the deadline isn't started, and leaves the budget alone
+	budget := NewBudget(3)
+	ctx, cancel := context.WithTimeout(WithBudget(context.Background(), budget), time.Second)
+	defer cancel()
+	calls = 0
+	err = Do(ctx, Policy{MaxRetries: 3}, func() error {
+		calls++
+		return &retryAfterError{after: time.Minute}
+	})
+	if err == nil || calls != 1 || budget.Remaining() != 3 {
+		t.Errorf("expected one attempt without retrying past the deadline, got %d calls, %d retries left", calls, budget.Remaining())
+	}
+}
+
+func TestTransport_RecordsRetryAfter(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: Transport(nil)}
+
+	ctx := WithRetryAfter(context.Background())
+	hint := ctx.Value(hintKey{}).(*retryAfterHint)
+	get := func() {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	get()
+	if after := hint.get(); after != 0 {
+		t.Errorf("expected no wait for Retry-After: 0, got %v", after)
+	}
+	get()
+	if after := hint.get(); after != 2*time.Minute {
+		t.Errorf("expected the 120 second Retry-After, got %v", after)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if after := ParseRetryAfter("3"); after != 3*time.Second {
+		t.Errorf("expected 3s, got %v", after)
+	}
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if after := ParseRetryAfter(date); after < 58*time.Second || after > time.Minute {
+		t.Errorf("expected about a minute for %s, got %v", date, after)
+	}
+	for _, value := range []string{"", "soon", "-5"} {
+		if after := ParseRetryAfter(value); after != 0 {
+			t.Errorf("expected no wait for %q, got %v", value, after)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package store
+
+import (
+	"fmt"
+
+	"go-rag/internal/embedding"
+	"go-rag/internal/types"
+)
+
+// NewStore creates the vector store for the configured provider
+func NewStore(config types.VectorStoreConfig, embeddingService embedding.Service) (VectorStore, error) {
+	// Assign through the concrete types so a failed constructor returns a nil interface
+	switch config.Provider {
+	case "qdrant":
+		qdrantStore, err := NewQdrantStore(config, embeddingService)
+		if err != nil {
+			return nil, err
+		}
+		return qdrantStore, nil
+	case "pinecone":
+		pineconeStore, err := NewPineconeStore(config, embeddingService)
+		if err != nil {
+			return nil, err
+		}
+		return pineconeStore, nil
+	case "memory":
+		memoryStore, err := NewMemoryStore(config, embeddingService)
+		if err != nil {
+			return nil, err
+		}
+		return memoryStore, nil
+	case "weaviate":
+		weaviateStore, err := NewWeaviateStore(config, embeddingService)
+		if err != nil {
+			return nil, err
+		}
+		return weaviateStore, nil
+	case "pgvector":
+		pgVectorStore, err := NewPgVectorStore(config, embeddingService)
+		if err != nil {
+			return nil, err
+		}
+		return pgVectorStore, nil
+	default:
+		return nil, fmt.Errorf("unsupported vector store provider: %s", config.Provider)
+	}
+}
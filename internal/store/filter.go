@@ -0,0 +1,132 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"go-rag/internal/types"
+)
+
+// Search filter operators. A filter key can end in a colon and an operator,
+// such as "source:icontains"; a key without one matches the value exactly.
+const (
+	FilterEqual        = "eq"
+	FilterContains     = "contains"
+	FilterPrefix       = "prefix"
+	FilterContainsFold = "icontains"
+)
+
+// defaultFilterOverfetch is how many candidates per result a store fetches
+// when it has to apply some filters itself and none is configured
+const defaultFilterOverfetch = 10
+
+// maxFilterPages bounds how many pages of candidates a store fetches to fill
+// a search whose remaining filters reject most of them
+const maxFilterPages = 10
+
+// ErrInvalidFilter is returned for a search filter key that can't be parsed
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// filterCondition is a parsed search filter
+type filterCondition struct {
+	// Field is the payload key the filter applies to, as mapped by filterPayloadKey
+	Field    string
+	Operator string
+	Value    string
+}
+
+// parseFilters parses search filters into conditions, sorted by filter key
+func parseFilters(filters map[string]string) ([]filterCondition, error) {
+	keys := make([]string, 0, len(filters))
+	for key := range filters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	conditions := make([]filterCondition, 0, len(keys))
+	for _, key := range keys {
+		field, operator := key, FilterEqual
+		if i := strings.LastIndex(key, ":"); i >= 0 {
+			field, operator = key[:i], key[i+1:]
+		}
+		switch operator {
+		case FilterEqual, FilterContains, FilterPrefix, FilterContainsFold:
+		default:
+			return nil, fmt.Errorf("%w: unknown operator %q in %q, use eq, contains, prefix or icontains", ErrInvalidFilter, operator, key)
+		}
+		if field == "" {
+			return nil, fmt.Errorf("%w: %q has no field", ErrInvalidFilter, key)
+		}
+		conditions = append(conditions, filterCondition{Field: filterPayloadKey(field), Operator: operator, Value: filters[key]})
+	}
+	return conditions, nil
+}
+
+// ValidateFilters checks that every search filter key names a field and a
+// known operator
+func ValidateFilters(filters map[string]string) error {
+	_, err := parseFilters(filters)
+	return err
+}
+
+// matches reports whether a stored value satisfies the condition
+func (c filterCondition) matches(value string) bool {
+	switch c.Operator {
+	case FilterContains:
+		return strings.Contains(value, c.Value)
+	case FilterPrefix:
+		return strings.HasPrefix(value, c.Value)
+	case FilterContainsFold:
+		return strings.Contains(strings.ToLower(value), strings.ToLower(c.Value))
+	default:
+		return value == c.Value
+	}
+}
+
+// matchesChunk reports whether a chunk's metadata satisfies the condition. A
+// tags or path condition is satisfied by any tag or heading.
+func (c filterCondition) matchesChunk(chunk types.DocumentChunk) bool {
+	switch c.Field {
+	case "document_id":
+		return c.matches(chunk.DocumentID)
+	case "title":
+		return c.matches(chunk.Metadata.Title)
+	case "author":
+		return c.matches(chunk.Metadata.Author)
+	case "source":
+		return c.matches(chunk.Metadata.Source)
+	case "language":
+		return c.matches(chunk.Metadata.Language)
+	case "content_type":
+		return c.matches(chunk.Metadata.ContentType)
+	case "tags":
+		return slices.ContainsFunc(chunk.Metadata.Tags, c.matches)
+	case "parent_id":
+		return c.matches(chunk.Metadata.ParentID)
+	case "section":
+		return c.matches(chunk.Metadata.Section)
+	case "path":
+		return slices.ContainsFunc(chunk.Metadata.Path, c.matches)
+	default:
+		custom, ok := chunk.Metadata.Custom[strings.TrimPrefix(c.Field, "custom_")]
+		return ok && c.matches(custom)
+	}
+}
+
+// isListProperty reports whether a filter field holds a list, matched by any element
+func isListProperty(field string) bool {
+	return field == "tags" || field == "path"
+}
+
+// matchesConditions reports whether a chunk satisfies every condition
+func matchesConditions(chunk types.DocumentChunk, conditions []filterCondition) bool {
+	for _, condition := range conditions {
+		if !condition.matchesChunk(chunk) {
+			return false
+		}
+	}
+	return true
+}
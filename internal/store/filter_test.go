@@ -0,0 +1,32 @@
+package store
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseFilters(t *testing.T) {
+	conditions, err := parseFilters(map[string]string{"title:prefix": "Guide", "team": "search", "source:icontains": "Hand"})
+	if err != nil {
+		t.Fatalf("parseFilters failed: %v", err)
+	}
+	want := []filterCondition{
+		{Field: "source", Operator: FilterContainsFold, Value: "Hand"},
+		{Field: "custom_team", Operator: FilterEqual, Value: "search"},
+		{Field: "title", Operator: FilterPrefix, Value: "Guide"},
+	}
+	if len(conditions) != len(want) {
+		t.Fatalf("Expected %d conditions, got %+v", len(want), conditions)
+	}
+	for i := range want {
+		if conditions[i] != want[i] {
+			t.Errorf("Condition %d: expected %+v, got %+v", i, want[i], conditions[i])
+		}
+	}
+
+	for _, key := range []string{"source:like", ":eq", "source:"} {
+		if err := ValidateFilters(map[string]string{key: "x"}); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("Expected %q to be rejected, got %v", key, err)
+		}
+	}
+}
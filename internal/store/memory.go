@@ -0,0 +1,417 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+
+	"go-rag/internal/embedding"
+	"go-rag/internal/types"
+)
+
+// MemoryStore implements VectorStore in process memory, searching by brute-force
+// cosine similarity. Data is lost on restart unless a persist path is set, in
+// which case it's loaded at startup and saved by Flush at shutdown.
+type MemoryStore struct {
+	config           types.VectorStoreConfig
+	embeddingService embedding.Service
+
+	mu      sync.RWMutex
+	entries map[uint64]memoryEntry
+}
+
+// memoryEntry is a stored chunk with its embedding and soft-delete flag
+type memoryEntry struct {
+	chunk   types.DocumentChunk
+	vector  []float32
+	deleted bool
+}
+
+// memoryRecord is how an entry is saved to the persist file
+type memoryRecord struct {
+	Chunk   types.DocumentChunk `json:"chunk"`
+	Vector  []float32           `json:"vector"`
+	Deleted bool                `json:"deleted,omitempty"`
+}
+
+// NewMemoryStore creates an in-memory vector store, loading the persist file
+// if one is configured and exists
+func NewMemoryStore(config types.VectorStoreConfig, embeddingService embedding.Service) (*MemoryStore, error) {
+	if config.Provider != "memory" {
+		return nil, fmt.Errorf("unsupported vector store provider: %s", config.Provider)
+	}
+
+	if embeddingService == nil {
+		return nil, fmt.Errorf("embedding service is required")
+	}
+
+	if len(config.VectorFields) > 0 {
+		return nil, fmt.Errorf("named vectors are not supported by the memory store")
+	}
+
+	m := &MemoryStore{
+		config:           config,
+		embeddingService: embeddingService,
+		entries:          make(map[uint64]memoryEntry),
+	}
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// load reads the entries saved in the persist file. Vectors must match the
+// embedding dimensions, since they can't be compared with new ones otherwise.
+func (m *MemoryStore) load() error {
+	if m.config.PersistPath == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(m.config.PersistPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read memory store: %w", err)
+	}
+
+	var records []memoryRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return fmt.Errorf("failed to parse memory store: %w", err)
+	}
+
+	dims := m.embeddingService.GetDimensions()
+	for _, record := range records {
+		if len(record.Vector) != dims {
+			return fmt.Errorf("%w: saved chunk %d has %d dimensions, expected %d", ErrDimensionMismatch, record.Chunk.ID, len(record.Vector), dims)
+		}
+		m.entries[record.Chunk.ID] = memoryEntry{chunk: record.Chunk, vector: record.Vector, deleted: record.Deleted}
+	}
+	return nil
+}
+
+// Flush saves every entry, including soft-deleted ones, to the persist file.
+// It does nothing when no persist path is configured.
+func (m *MemoryStore) Flush(ctx context.Context) error {
+	if m.config.PersistPath == "" {
+		return nil
+	}
+
+	m.mu.RLock()
+	records := make([]memoryRecord, 0, len(m.entries))
+	for _, entry := range m.entries {
+		records = append(records, memoryRecord{Chunk: entry.chunk, Vector: entry.vector, Deleted: entry.deleted})
+	}
+	m.mu.RUnlock()
+
+	// Sort so unchanged data saves to an identical file
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Chunk.ID < records[j].Chunk.ID
+	})
+
+	data, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("failed to encode memory store: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a partial store
+	tmpPath := m.config.PersistPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to save memory store: %w", err)
+	}
+	if err := os.Rename(tmpPath, m.config.PersistPath); err != nil {
+		return fmt.Errorf("failed to save memory store: %w", err)
+	}
+	return nil
+}
+
+// Stats counts the live chunks and documents held in memory
+func (m *MemoryStore) Stats(ctx context.Context) (*types.CollectionStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stats := &types.CollectionStats{
+		Collection: m.config.CollectionName,
+		Dimensions: m.embeddingService.GetDimensions(),
+		Distance:   m.Describe().Distance,
+	}
+	documents := make(map[string]bool)
+	for _, entry := range m.entries {
+		if entry.deleted {
+			continue
+		}
+		stats.Chunks++
+		documents[entry.chunk.DocumentID] = true
+	}
+	stats.Documents = uint64(len(documents))
+	return stats, nil
+}
+
+// CountDocumentChunks counts the chunks of a document, leaving out
+// soft-deleted ones unless withDeleted is set
+func (m *MemoryStore) CountDocumentChunks(ctx context.Context, documentID string, withDeleted bool) (uint64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var count uint64
+	for _, entry := range m.entries {
+		if entry.chunk.DocumentID == documentID && (withDeleted || !entry.deleted) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Describe reports the collection name and the cosine metric the store searches with
+func (m *MemoryStore) Describe() types.ResponseMeta {
+	return types.ResponseMeta{
+		Collection: m.config.CollectionName,
+		Distance:   "cosine",
+	}
+}
+
+// StoreChunks embeds chunks and stores them, replacing chunks with the same ID
+func (m *MemoryStore) StoreChunks(ctx context.Context, chunks []types.DocumentChunk) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	embeddings, err := embedContent(ctx, m.embeddingService, chunks)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, chunk := range chunks {
+		// Keep the caller's slices and maps from aliasing the stored copy
+		chunk.Embedding = nil
+		chunk.Metadata.Tags = slices.Clone(chunk.Metadata.Tags)
+		chunk.Metadata.Path = slices.Clone(chunk.Metadata.Path)
+		custom := make(map[string]string, len(chunk.Metadata.Custom))
+		for key, value := range chunk.Metadata.Custom {
+			custom[key] = value
+		}
+		chunk.Metadata.Custom = custom
+
+		m.entries[chunk.ID] = memoryEntry{chunk: chunk, vector: embeddings[i]}
+	}
+
+	return nil
+}
+
+// SearchSimilar returns the chunks most similar to the query. Named vectors
+// aren't supported, so vectorName must be empty. Only chunks whose metadata
+// matches every filter are returned.
+func (m *MemoryStore) SearchSimilar(ctx context.Context, query string, limit int, vectorName string, filters map[string]string) ([]types.DocumentChunk, error) {
+	if query == "" {
+		return nil, fmt.Errorf("query cannot be empty")
+	}
+
+	if vectorName != "" {
+		return nil, fmt.Errorf("named vectors are not supported, cannot search %q", vectorName)
+	}
+
+	if limit <= 0 {
+		limit = 10
+	}
+
+	conditions, err := parseFilters(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	queryEmbedding, err := m.embeddingService.GenerateEmbedding(embedding.WithInputType(ctx, embedding.InputTypeQuery), query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
+	}
+	queryVector := toFloat32(queryEmbedding)
+
+	m.mu.RLock()
+	var results []types.DocumentChunk
+	for _, entry := range m.entries {
+		if entry.deleted || !matchesConditions(entry.chunk, conditions) {
+			continue
+		}
+		chunk := entry.chunk
+		chunk.VectorScore = cosineSimilarity(queryVector, entry.vector)
+		results = append(results, chunk)
+	}
+	m.mu.RUnlock()
+
+	// Ties break on ID so results are stable across calls
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].VectorScore != results[j].VectorScore {
+			return results[i].VectorScore > results[j].VectorScore
+		}
+		return results[i].ID < results[j].ID
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// cosineSimilarity returns the cosine of the angle between two vectors, or 0
+// when either is zero or their lengths differ
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// GetChunksByDocumentID retrieves all live chunks for a document in chunk order
+func (m *MemoryStore) GetChunksByDocumentID(ctx context.Context, documentID string) ([]types.DocumentChunk, error) {
+	if documentID == "" {
+		return nil, fmt.Errorf("document ID cannot be empty")
+	}
+
+	m.mu.RLock()
+	var chunks []types.DocumentChunk
+	for _, entry := range m.entries {
+		if !entry.deleted && entry.chunk.DocumentID == documentID {
+			chunks = append(chunks, entry.chunk)
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(chunks, func(i, j int) bool {
+		return chunks[i].ChunkIndex < chunks[j].ChunkIndex
+	})
+	return chunks, nil
+}
+
+// GetChunkByID retrieves a specific chunk by its ID
+func (m *MemoryStore) GetChunkByID(ctx context.Context, chunkID uint64) (*types.DocumentChunk, error) {
+	if chunkID == 0 {
+		return nil, fmt.Errorf("chunk ID cannot be zero")
+	}
+
+	m.mu.RLock()
+	entry, ok := m.entries[chunkID]
+	m.mu.RUnlock()
+
+	if !ok || entry.deleted {
+		return nil, fmt.Errorf("chunk not found: %d", chunkID)
+	}
+	chunk := entry.chunk
+	return &chunk, nil
+}
+
+// ListDocumentIDs returns the IDs of documents with live chunks, sorted
+func (m *MemoryStore) ListDocumentIDs(ctx context.Context) ([]string, error) {
+	m.mu.RLock()
+	seen := make(map[string]bool)
+	for _, entry := range m.entries {
+		if !entry.deleted {
+			seen[entry.chunk.DocumentID] = true
+		}
+	}
+	m.mu.RUnlock()
+
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// DeleteDocument removes a document, or flags it as deleted when soft delete is enabled
+func (m *MemoryStore) DeleteDocument(ctx context.Context, documentID string) error {
+	if m.config.SoftDelete {
+		return m.setDeleted(documentID, true)
+	}
+	return m.PurgeDocument(ctx, documentID)
+}
+
+// RestoreDocument clears the soft-delete flag on a document's chunks
+func (m *MemoryStore) RestoreDocument(ctx context.Context, documentID string) error {
+	return m.setDeleted(documentID, false)
+}
+
+// setDeleted sets the soft-delete flag on every chunk of a document
+func (m *MemoryStore) setDeleted(documentID string, deleted bool) error {
+	if documentID == "" {
+		return fmt.Errorf("document ID cannot be empty")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, entry := range m.entries {
+		if entry.chunk.DocumentID == documentID {
+			entry.deleted = deleted
+			m.entries[id] = entry
+		}
+	}
+	return nil
+}
+
+// PurgeDocument permanently removes all chunks for a document, whether or not
+// they were soft-deleted
+func (m *MemoryStore) PurgeDocument(ctx context.Context, documentID string) error {
+	if documentID == "" {
+		return fmt.Errorf("document ID cannot be empty")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, entry := range m.entries {
+		if entry.chunk.DocumentID == documentID {
+			delete(m.entries, id)
+		}
+	}
+	return nil
+}
+
+// CreateCollection does nothing, since the store needs no schema
+func (m *MemoryStore) CreateCollection(ctx context.Context, vectorSize int) error {
+	return nil
+}
+
+// DropCollection removes every chunk
+func (m *MemoryStore) DropCollection(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.entries = make(map[uint64]memoryEntry)
+	return nil
+}
+
+// HealthCheck always succeeds, since the store lives in process memory
+func (m *MemoryStore) HealthCheck(ctx context.Context) error {
+	return nil
+}
+
+// DeleteChunk removes a specific chunk
+func (m *MemoryStore) DeleteChunk(ctx context.Context, chunkID uint64) error {
+	if chunkID == 0 {
+		return fmt.Errorf("chunk ID cannot be zero")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.entries, chunkID)
+	return nil
+}
@@ -0,0 +1,285 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"math"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+
+	"go-rag/internal/types"
+)
+
+func newTestMemoryStore(t *testing.T, softDelete bool) *MemoryStore {
+	store, err := NewMemoryStore(types.VectorStoreConfig{Provider: "memory", CollectionName: "documents", SoftDelete: softDelete},
+		&wordEmbeddingService{MockEmbeddingService{dimensions: 64}})
+	if err != nil {
+		t.Fatalf("Failed to create memory store: %v", err)
+	}
+	return store
+}
+
+func TestMemoryStore_SearchRanksBySimilarity(t *testing.T) {
+	store := newTestMemoryStore(t, false)
+	ctx := context.Background()
+
+	chunks := []types.DocumentChunk{
+		{ID: 1, DocumentID: "cats", Content: "cats purr and sleep all day", Metadata: types.Metadata{Language: "en"}},
+		{ID: 2, DocumentID: "rockets", Content: "rockets burn fuel to reach orbit", Metadata: types.Metadata{Language: "en", Tags: []string{"space"}}},
+		{ID: 3, DocumentID: "raketen", Content: "rockets reach orbit", Metadata: types.Metadata{Language: "de", Custom: map[string]string{"team": "launch"}}},
+	}
+	if err := store.StoreChunks(ctx, chunks); err != nil {
+		t.Fatalf("StoreChunks failed: %v", err)
+	}
+
+	results, err := store.SearchSimilar(ctx, "rockets orbit", 2, "", nil)
+	if err != nil {
+		t.Fatalf("SearchSimilar failed: %v", err)
+	}
+	if len(results) != 2 || results[0].ID != 3 || results[1].ID != 2 {
+		t.Fatalf("Expected the rocket chunks, closest first, got %+v", results)
+	}
+	if results[0].VectorScore <= results[1].VectorScore || results[0].VectorScore > 1 {
+		t.Errorf("Expected descending cosine scores, got %v and %v", results[0].VectorScore, results[1].VectorScore)
+	}
+
+	for filter, want := range map[string]uint64{"language": 2, "tags": 2, "team": 3} {
+		value := map[string]string{"language": "en", "tags": "space", "team": "launch"}[filter]
+		results, err := store.SearchSimilar(ctx, "rockets orbit", 5, "", map[string]string{filter: value})
+		if err != nil {
+			t.Fatalf("SearchSimilar failed: %v", err)
+		}
+		if len(results) == 0 || results[0].ID != want {
+			t.Errorf("Expected filter %s=%s to keep chunk %d first, got %+v", filter, value, want, results)
+		}
+	}
+
+	if _, err := store.SearchSimilar(ctx, "rockets", 5, "title", nil); err == nil {
+		t.Error("Expected a named vector search to be rejected")
+	}
+}
+
+func TestMemoryStore_SearchFilterOperators(t *testing.T) {
+	store := newTestMemoryStore(t, false)
+	ctx := context.Background()
+
+	chunks := []types.DocumentChunk{
+		{ID: 1, DocumentID: "a", Content: "vacation policy", Metadata: types.Metadata{Source: "handbook", Tags: []string{"HR-policy"}, Path: []string{"Handbook", "Chapter 3"}}},
+		{ID: 2, DocumentID: "b", Content: "vacation policy", Metadata: types.Metadata{Source: "Employee Handbook", Path: []string{"Handbook", "Chapter 4"}}},
+		{ID: 3, DocumentID: "c", Content: "vacation policy", Metadata: types.Metadata{Source: "wiki", Custom: map[string]string{"team": "People Ops"}}},
+	}
+	if err := store.StoreChunks(ctx, chunks); err != nil {
+		t.Fatalf("StoreChunks failed: %v", err)
+	}
+
+	cases := []struct {
+		filters map[string]string
+		want    []string
+	}{
+		{map[string]string{"source": "Handbook"}, nil},
+		{map[string]string{"source:eq": "handbook"}, []string{"a"}},
+		{map[string]string{"source:icontains": "Handbook"}, []string{"a", "b"}},
+		{map[string]string{"source:contains": "Hand"}, []string{"b"}},
+		{map[string]string{"source:prefix": "hand"}, []string{"a"}},
+		{map[string]string{"tags:prefix": "HR-"}, []string{"a"}},
+		{map[string]string{"team:icontains": "ops"}, []string{"c"}},
+		{map[string]string{"path:contains": "Chapter 3"}, []string{"a"}},
+		{map[string]string{"path": "Handbook"}, []string{"a", "b"}},
+	}
+	for _, tc := range cases {
+		results, err := store.SearchSimilar(ctx, "vacation policy", 5, "", tc.filters)
+		if err != nil {
+			t.Fatalf("SearchSimilar failed: %v", err)
+		}
+		var got []string
+		for _, chunk := range results {
+			got = append(got, chunk.DocumentID)
+		}
+		sort.Strings(got)
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%v: expected documents %v, got %v", tc.filters, tc.want, got)
+		}
+	}
+
+	if _, err := store.SearchSimilar(ctx, "vacation", 5, "", map[string]string{"source:like": "hand"}); !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("Expected an unknown operator to be rejected, got %v", err)
+	}
+}
+
+func TestMemoryStore_DocumentLifecycle(t *testing.T) {
+	store := newTestMemoryStore(t, true)
+	ctx := context.Background()
+
+	if err := store.StoreChunks(ctx, []types.DocumentChunk{
+		{ID: 2, DocumentID: "doc-1", Content: "second", ChunkIndex: 1},
+		{ID: 1, DocumentID: "doc-1", Content: "first", ChunkIndex: 0},
+		{ID: 3, DocumentID: "doc-2", Content: "other", ChunkIndex: 0},
+	}); err != nil {
+		t.Fatalf("StoreChunks failed: %v", err)
+	}
+
+	chunks, err := store.GetChunksByDocumentID(ctx, "doc-1")
+	if err != nil || len(chunks) != 2 || chunks[0].ID != 1 {
+		t.Fatalf("Expected doc-1's chunks in order, got %+v, %v", chunks, err)
+	}
+
+	if err := store.DeleteDocument(ctx, "doc-1"); err != nil {
+		t.Fatalf("DeleteDocument failed: %v", err)
+	}
+	if chunks, _ := store.GetChunksByDocumentID(ctx, "doc-1"); len(chunks) != 0 {
+		t.Errorf("Expected soft-deleted chunks to be hidden, got %d", len(chunks))
+	}
+	if results, _ := store.SearchSimilar(ctx, "first", 5, "", nil); len(results) != 1 || results[0].ID != 3 {
+		t.Errorf("Expected search to skip soft-deleted chunks, got %+v", results)
+	}
+
+	if err := store.RestoreDocument(ctx, "doc-1"); err != nil {
+		t.Fatalf("RestoreDocument failed: %v", err)
+	}
+	if _, err := store.GetChunkByID(ctx, 1); err != nil {
+		t.Errorf("Expected the restored chunk, got %v", err)
+	}
+
+	if err := store.DeleteChunk(ctx, 1); err != nil {
+		t.Fatalf("DeleteChunk failed: %v", err)
+	}
+	if _, err := store.GetChunkByID(ctx, 1); err == nil {
+		t.Error("Expected the deleted chunk to be gone")
+	}
+
+	if err := store.PurgeDocument(ctx, "doc-1"); err != nil {
+		t.Fatalf("PurgeDocument failed: %v", err)
+	}
+	ids, _ := store.ListDocumentIDs(ctx)
+	if len(ids) != 1 || ids[0] != "doc-2" {
+		t.Errorf("Expected only doc-2 to remain, got %v", ids)
+	}
+}
+
+func TestMemoryStore_PersistsAcrossRestarts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	config := types.VectorStoreConfig{Provider: "memory", CollectionName: "documents", SoftDelete: true, PersistPath: path}
+	embeddingService := &wordEmbeddingService{MockEmbeddingService{dimensions: 64}}
+	ctx := context.Background()
+
+	store, err := NewMemoryStore(config, embeddingService)
+	if err != nil {
+		t.Fatalf("Failed to create memory store: %v", err)
+	}
+	if err := store.StoreChunks(ctx, []types.DocumentChunk{
+		{ID: 1, DocumentID: "rockets", Content: "rockets reach orbit", Metadata: types.Metadata{Title: "Launch", Custom: map[string]string{"team": "space"}}},
+		{ID: 2, DocumentID: "cats", Content: "cats purr and sleep"},
+	}); err != nil {
+		t.Fatalf("StoreChunks failed: %v", err)
+	}
+	store.DeleteDocument(ctx, "cats")
+	if err := store.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	// A new store on the same file stands in for a restart
+	restarted, err := NewMemoryStore(config, embeddingService)
+	if err != nil {
+		t.Fatalf("Failed to reload memory store: %v", err)
+	}
+	results, err := restarted.SearchSimilar(ctx, "rockets orbit", 5, "", map[string]string{"team": "space"})
+	if err != nil {
+		t.Fatalf("SearchSimilar failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Content != "rockets reach orbit" || results[0].Metadata.Title != "Launch" || results[0].VectorScore <= 0 {
+		t.Errorf("Expected the reloaded rocket chunk with its metadata and vector, got %+v", results)
+	}
+	if ids, _ := restarted.ListDocumentIDs(ctx); len(ids) != 1 {
+		t.Errorf("Expected the soft delete to survive the restart, got %v", ids)
+	}
+	restarted.RestoreDocument(ctx, "cats")
+	if _, err := restarted.GetChunkByID(ctx, 2); err != nil {
+		t.Errorf("Expected the soft-deleted chunk to be restorable, got %v", err)
+	}
+
+	_, err = NewMemoryStore(config, &wordEmbeddingService{MockEmbeddingService{dimensions: 32}})
+	if !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("Expected saved vectors of another size to be rejected, got %v", err)
+	}
+}
+
+func TestMemoryStore_FlushWithoutPathIsNoop(t *testing.T) {
+	store := newTestMemoryStore(t, false)
+	if err := store.Flush(context.Background()); err != nil {
+		t.Errorf("Expected no error without a persist path, got %v", err)
+	}
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	if got := cosineSimilarity([]float32{1, 0}, []float32{2, 0}); math.Abs(got-1) > 1e-9 {
+		t.Errorf("Expected parallel vectors to score 1, got %v", got)
+	}
+	if got := cosineSimilarity([]float32{1, 0}, []float32{0, 1}); got != 0 {
+		t.Errorf("Expected orthogonal vectors to score 0, got %v", got)
+	}
+	if got := cosineSimilarity([]float32{0, 0}, []float32{1, 1}); got != 0 {
+		t.Errorf("Expected a zero vector to score 0, got %v", got)
+	}
+}
+
+func TestMemoryStore_CountDocumentChunks(t *testing.T) {
+	store := newTestMemoryStore(t, true)
+	ctx := context.Background()
+
+	chunks := []types.DocumentChunk{
+		{ID: 1, DocumentID: "a", Content: "first chunk"},
+		{ID: 2, DocumentID: "a", Content: "second chunk"},
+		{ID: 3, DocumentID: "a-10", Content: "document sharing a prefix"},
+	}
+	if err := store.StoreChunks(ctx, chunks); err != nil {
+		t.Fatalf("StoreChunks failed: %v", err)
+	}
+	if err := store.DeleteDocument(ctx, "a"); err != nil {
+		t.Fatalf("DeleteDocument failed: %v", err)
+	}
+
+	for _, tc := range []struct {
+		documentID  string
+		withDeleted bool
+		want        uint64
+	}{
+		{"a", false, 0},
+		{"a", true, 2},
+		{"missing", true, 0},
+		{"a-10", false, 1},
+	} {
+		count, err := store.CountDocumentChunks(ctx, tc.documentID, tc.withDeleted)
+		if err != nil || count != tc.want {
+			t.Errorf("CountDocumentChunks(%s, %v): expected %d, got %d (%v)", tc.documentID, tc.withDeleted, tc.want, count, err)
+		}
+	}
+}
+
+func TestMemoryStore_Stats(t *testing.T) {
+	store := newTestMemoryStore(t, true)
+	ctx := context.Background()
+
+	chunks := []types.DocumentChunk{
+		{ID: 1, DocumentID: "a", Content: "first chunk"},
+		{ID: 2, DocumentID: "a", Content: "second chunk"},
+		{ID: 3, DocumentID: "b", Content: "only chunk"},
+		{ID: 4, DocumentID: "c", Content: "deleted chunk"},
+	}
+	if err := store.StoreChunks(ctx, chunks); err != nil {
+		t.Fatalf("StoreChunks failed: %v", err)
+	}
+	if err := store.DeleteDocument(ctx, "c"); err != nil {
+		t.Fatalf("DeleteDocument failed: %v", err)
+	}
+
+	stats, err := store.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	want := types.CollectionStats{Collection: "documents", Chunks: 3, Documents: 2, Dimensions: 64, Distance: "cosine"}
+	if *stats != want {
+		t.Errorf("Expected %+v, got %+v", want, *stats)
+	}
+}
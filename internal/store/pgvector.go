@@ -0,0 +1,621 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"go-rag/internal/embedding"
+	"go-rag/internal/types"
+)
+
+// pgvectorTablePattern restricts table names to unquoted lowercase Postgres identifiers
+var pgvectorTablePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+
+// pgvectorColumns are the columns read back for every chunk
+const pgvectorColumns = `id, document_id, content, content_compressed, chunk_window, chunk_index, total_chunks,
+start_offset, end_offset, chunk_strategy, chunk_overlap, metadata, created_at, updated_at`
+
+// PgVectorStore implements VectorStore using a Postgres table with the
+// pgvector extension. The collection name is the table name.
+type PgVectorStore struct {
+	config           types.VectorStoreConfig
+	table            string // quoted table identifier
+	pool             *pgxpool.Pool
+	embeddingService embedding.Service
+
+	schemaMu    sync.Mutex
+	schemaReady bool
+}
+
+// NewPgVectorStore creates a pgvector store. Connections are opened on first
+// use, and the table is created before the first query.
+func NewPgVectorStore(config types.VectorStoreConfig, embeddingService embedding.Service) (*PgVectorStore, error) {
+	if config.Provider != "pgvector" {
+		return nil, fmt.Errorf("unsupported vector store provider: %s", config.Provider)
+	}
+
+	if config.DSN == "" && config.Host == "" {
+		return nil, fmt.Errorf("host or DSN is required")
+	}
+
+	if embeddingService == nil {
+		return nil, fmt.Errorf("embedding service is required")
+	}
+
+	if len(config.VectorFields) > 0 {
+		return nil, fmt.Errorf("named vectors are not supported by the pgvector store")
+	}
+
+	if !pgvectorTablePattern.MatchString(config.CollectionName) {
+		return nil, fmt.Errorf("collection name %q is not a valid Postgres table name", config.CollectionName)
+	}
+
+	poolConfig, err := pgxpool.ParseConfig(pgvectorDSN(config))
+	if err != nil {
+		return nil, fmt.Errorf("invalid pgvector connection settings: %w", err)
+	}
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pgvector connection pool: %w", err)
+	}
+
+	return &PgVectorStore{
+		config:           config,
+		table:            pgx.Identifier{config.CollectionName}.Sanitize(),
+		pool:             pool,
+		embeddingService: embeddingService,
+	}, nil
+}
+
+// pgvectorDSN returns the configured DSN, or builds one from the host, port,
+// database and password (APIKey). The user and anything else unset fall back
+// to the standard PG* environment variables.
+func pgvectorDSN(config types.VectorStoreConfig) string {
+	if config.DSN != "" {
+		return config.DSN
+	}
+
+	quote := func(value string) string {
+		value = strings.ReplaceAll(value, `\`, `\\`)
+		return "'" + strings.ReplaceAll(value, "'", `\'`) + "'"
+	}
+	settings := []string{"host=" + quote(config.Host)}
+	if config.Port > 0 {
+		settings = append(settings, "port="+strconv.Itoa(config.Port))
+	}
+	if config.Database != "" {
+		settings = append(settings, "dbname="+quote(config.Database))
+	}
+	if config.APIKey != "" {
+		settings = append(settings, "password="+quote(config.APIKey))
+	}
+	return strings.Join(settings, " ")
+}
+
+// Close closes the connection pool
+func (p *PgVectorStore) Close() error {
+	p.pool.Close()
+	return nil
+}
+
+// Describe reports the table and the cosine metric the index is built with
+func (p *PgVectorStore) Describe() types.ResponseMeta {
+	return types.ResponseMeta{
+		Collection: p.config.CollectionName,
+		Distance:   "cosine",
+	}
+}
+
+// Stats counts the live rows and documents in the table. The dimensions are
+// the embedding dimensions the table is created with.
+func (p *PgVectorStore) Stats(ctx context.Context) (*types.CollectionStats, error) {
+	if err := p.ensureSchema(ctx); err != nil {
+		return nil, err
+	}
+
+	stats := &types.CollectionStats{
+		Collection: p.config.CollectionName,
+		Dimensions: p.embeddingService.GetDimensions(),
+		Distance:   p.Describe().Distance,
+	}
+	err := p.pool.QueryRow(ctx, fmt.Sprintf(`SELECT count(*), count(DISTINCT document_id) FROM %s WHERE NOT deleted`, p.table)).
+		Scan(&stats.Chunks, &stats.Documents)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count rows in pgvector: %w", err)
+	}
+	return stats, nil
+}
+
+// CountDocumentChunks counts the rows of a document, leaving out
+// soft-deleted ones unless withDeleted is set
+func (p *PgVectorStore) CountDocumentChunks(ctx context.Context, documentID string, withDeleted bool) (uint64, error) {
+	if err := p.ensureSchema(ctx); err != nil {
+		return 0, err
+	}
+
+	var count uint64
+	err := p.pool.QueryRow(ctx, fmt.Sprintf(`SELECT count(*) FROM %s WHERE document_id = $1 AND ($2 OR NOT deleted)`, p.table), documentID, withDeleted).
+		Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count document rows in pgvector: %w", err)
+	}
+	return count, nil
+}
+
+// CreateCollection enables the vector extension and creates the table and its
+// indexes if they don't exist. An HNSW index with vector_cosine_ops serves
+// the <=> searches.
+func (p *PgVectorStore) CreateCollection(ctx context.Context, vectorSize int) error {
+	if vectorSize <= 0 {
+		vectorSize = p.embeddingService.GetDimensions()
+	}
+
+	name := p.config.CollectionName
+	statements := []string{
+		`CREATE EXTENSION IF NOT EXISTS vector`,
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id BIGINT PRIMARY KEY,
+	document_id TEXT NOT NULL,
+	content TEXT NOT NULL,
+	content_compressed BOOLEAN NOT NULL DEFAULT false,
+	chunk_window TEXT NOT NULL DEFAULT '',
+	chunk_index INTEGER NOT NULL,
+	total_chunks INTEGER NOT NULL,
+	start_offset INTEGER NOT NULL,
+	end_offset INTEGER NOT NULL,
+	chunk_strategy TEXT NOT NULL DEFAULT '',
+	chunk_overlap INTEGER NOT NULL,
+	metadata JSONB NOT NULL DEFAULT '{}',
+	deleted BOOLEAN NOT NULL DEFAULT false,
+	created_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL,
+	embedding vector(%d) NOT NULL
+)`, p.table, vectorSize),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s USING hnsw (embedding vector_cosine_ops)`,
+			pgx.Identifier{name + "_embedding_idx"}.Sanitize(), p.table),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s (document_id, chunk_index)`,
+			pgx.Identifier{name + "_document_idx"}.Sanitize(), p.table),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s USING gin (metadata jsonb_path_ops)`,
+			pgx.Identifier{name + "_metadata_idx"}.Sanitize(), p.table),
+	}
+
+	for _, statement := range statements {
+		if _, err := p.pool.Exec(ctx, statement); err != nil {
+			return fmt.Errorf("failed to create collection: %w", err)
+		}
+	}
+	return nil
+}
+
+// DropCollection drops the table and its indexes, if they exist
+func (p *PgVectorStore) DropCollection(ctx context.Context) error {
+	p.schemaMu.Lock()
+	defer p.schemaMu.Unlock()
+
+	if _, err := p.pool.Exec(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS %s`, p.table)); err != nil {
+		return fmt.Errorf("failed to drop collection: %w", err)
+	}
+	p.schemaReady = false
+	return nil
+}
+
+// ensureSchema creates the table before the first query
+func (p *PgVectorStore) ensureSchema(ctx context.Context) error {
+	p.schemaMu.Lock()
+	defer p.schemaMu.Unlock()
+
+	if p.schemaReady {
+		return nil
+	}
+	if err := p.CreateCollection(ctx, p.embeddingService.GetDimensions()); err != nil {
+		return err
+	}
+	p.schemaReady = true
+	return nil
+}
+
+// vectorLiteral renders an embedding in pgvector's text input format
+func vectorLiteral(vector []float32) string {
+	items := make([]string, len(vector))
+	for i, value := range vector {
+		items[i] = strconv.FormatFloat(float64(value), 'g', -1, 32)
+	}
+	return "[" + strings.Join(items, ",") + "]"
+}
+
+// StoreChunks embeds chunks and upserts them as rows, clearing any soft-delete flag
+func (p *PgVectorStore) StoreChunks(ctx context.Context, chunks []types.DocumentChunk) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	if err := p.ensureSchema(ctx); err != nil {
+		return err
+	}
+
+	embeddings, err := embedContent(ctx, p.embeddingService, chunks)
+	if err != nil {
+		return err
+	}
+
+	statement := fmt.Sprintf(`INSERT INTO %s (%s, deleted, embedding)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, false, $15::vector)
+ON CONFLICT (id) DO UPDATE SET
+	document_id = EXCLUDED.document_id, content = EXCLUDED.content, content_compressed = EXCLUDED.content_compressed,
+	chunk_window = EXCLUDED.chunk_window, chunk_index = EXCLUDED.chunk_index, total_chunks = EXCLUDED.total_chunks,
+	start_offset = EXCLUDED.start_offset, end_offset = EXCLUDED.end_offset, chunk_strategy = EXCLUDED.chunk_strategy,
+	chunk_overlap = EXCLUDED.chunk_overlap, metadata = EXCLUDED.metadata, created_at = EXCLUDED.created_at,
+	updated_at = EXCLUDED.updated_at, deleted = false, embedding = EXCLUDED.embedding`, p.table, pgvectorColumns)
+
+	batch := &pgx.Batch{}
+	for i, chunk := range chunks {
+		row, err := p.chunkRow(chunk)
+		if err != nil {
+			return err
+		}
+		batch.Queue(statement, append(row, vectorLiteral(embeddings[i]))...)
+	}
+
+	if err := p.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to store chunks in pgvector: %w", err)
+	}
+	return nil
+}
+
+// chunkRow returns the values of pgvectorColumns for a chunk. Chunk IDs are
+// stored bit-for-bit as BIGINT, and metadata as JSONB.
+func (p *PgVectorStore) chunkRow(chunk types.DocumentChunk) ([]any, error) {
+	content, window := chunk.Content, chunk.Window
+	if p.config.CompressContent {
+		var err error
+		if content, err = compressContent(chunk.Content); err != nil {
+			return nil, fmt.Errorf("failed to compress content for chunk %d: %w", chunk.ID, err)
+		}
+		if window != "" {
+			if window, err = compressContent(chunk.Window); err != nil {
+				return nil, fmt.Errorf("failed to compress window for chunk %d: %w", chunk.ID, err)
+			}
+		}
+	}
+
+	metadata, err := json.Marshal(chunk.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode metadata for chunk %d: %w", chunk.ID, err)
+	}
+
+	return []any{
+		int64(chunk.ID), chunk.DocumentID, content, p.config.CompressContent, window,
+		chunk.ChunkIndex, chunk.TotalChunks, chunk.StartOffset, chunk.EndOffset,
+		chunk.ChunkStrategy, chunk.ChunkOverlap, string(metadata), chunk.CreatedAt, chunk.UpdatedAt,
+	}, nil
+}
+
+// pgvectorRow holds a chunk's columns as scanned from the table
+type pgvectorRow struct {
+	id                int64
+	documentID        string
+	content           string
+	contentCompressed bool
+	window            string
+	chunkIndex        int
+	totalChunks       int
+	startOffset       int
+	endOffset         int
+	chunkStrategy     string
+	chunkOverlap      int
+	metadata          []byte
+	createdAt         time.Time
+	updatedAt         time.Time
+}
+
+// scanTargets returns pointers to the fields, in pgvectorColumns order
+func (r *pgvectorRow) scanTargets() []any {
+	return []any{
+		&r.id, &r.documentID, &r.content, &r.contentCompressed, &r.window, &r.chunkIndex, &r.totalChunks,
+		&r.startOffset, &r.endOffset, &r.chunkStrategy, &r.chunkOverlap, &r.metadata, &r.createdAt, &r.updatedAt,
+	}
+}
+
+// toDocumentChunk converts a scanned row back into a chunk
+func (r *pgvectorRow) toDocumentChunk() (*types.DocumentChunk, error) {
+	chunkID := uint64(r.id)
+
+	content, window := r.content, r.window
+	if r.contentCompressed {
+		var err error
+		if content, err = decompressContent(content); err != nil {
+			return nil, fmt.Errorf("failed to decompress content for chunk %d: %w", chunkID, err)
+		}
+		if window != "" {
+			if window, err = decompressContent(window); err != nil {
+				return nil, fmt.Errorf("failed to decompress window for chunk %d: %w", chunkID, err)
+			}
+		}
+	}
+
+	var metadata types.Metadata
+	if len(r.metadata) > 0 {
+		if err := json.Unmarshal(r.metadata, &metadata); err != nil {
+			return nil, fmt.Errorf("failed to decode metadata for chunk %d: %w", chunkID, err)
+		}
+	}
+	if metadata.Custom == nil {
+		metadata.Custom = make(map[string]string)
+	}
+
+	return &types.DocumentChunk{
+		ID:            chunkID,
+		DocumentID:    r.documentID,
+		Content:       content,
+		ChunkIndex:    r.chunkIndex,
+		TotalChunks:   r.totalChunks,
+		StartOffset:   r.startOffset,
+		EndOffset:     r.endOffset,
+		ChunkStrategy: r.chunkStrategy,
+		ChunkOverlap:  r.chunkOverlap,
+		Window:        window,
+		Metadata:      metadata,
+		CreatedAt:     r.createdAt,
+		UpdatedAt:     r.updatedAt,
+	}, nil
+}
+
+// SearchSimilar returns the chunks closest to the query by cosine distance
+// (<=>). Named vectors aren't supported, so vectorName must be empty. Only
+// chunks whose metadata matches every filter are returned.
+func (p *PgVectorStore) SearchSimilar(ctx context.Context, query string, limit int, vectorName string, filters map[string]string) ([]types.DocumentChunk, error) {
+	if query == "" {
+		return nil, fmt.Errorf("query cannot be empty")
+	}
+
+	if vectorName != "" {
+		return nil, fmt.Errorf("named vectors are not supported, cannot search %q", vectorName)
+	}
+
+	if limit <= 0 {
+		limit = 10
+	}
+
+	conditions, err := parseFilters(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.ensureSchema(ctx); err != nil {
+		return nil, err
+	}
+
+	queryEmbedding, err := p.embeddingService.GenerateEmbedding(embedding.WithInputType(ctx, embedding.InputTypeQuery), query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
+	}
+
+	args := []any{vectorLiteral(toFloat32(queryEmbedding)), limit}
+	where, args := pgvectorSearchWhere(conditions, args)
+	statement := fmt.Sprintf(`SELECT %s, embedding <=> $1::vector AS distance FROM %s WHERE %s
+ORDER BY distance LIMIT $2`, pgvectorColumns, p.table, where)
+
+	rows, err := p.pool.Query(ctx, statement, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search in pgvector: %w", err)
+	}
+	defer rows.Close()
+
+	var chunks []types.DocumentChunk
+	for rows.Next() {
+		var row pgvectorRow
+		var distance float64
+		if err := rows.Scan(append(row.scanTargets(), &distance)...); err != nil {
+			return nil, fmt.Errorf("failed to read search result: %w", err)
+		}
+		chunk, err := row.toDocumentChunk()
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert row to document chunk: %w", err)
+		}
+		// Cosine distance runs from 0 to 2; report it as a similarity like Qdrant does
+		chunk.VectorScore = 1 - distance
+		chunks = append(chunks, *chunk)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search in pgvector: %w", err)
+	}
+	return chunks, nil
+}
+
+// pgvectorSearchWhere builds the condition for live chunks matching every
+// filter condition, appending the filter values to args as placeholders.
+func pgvectorSearchWhere(filters []filterCondition, args []any) (string, []any) {
+	conditions := []string{"NOT deleted"}
+	for _, filter := range filters {
+		args = append(args, filter.Value)
+		placeholder := "$" + strconv.Itoa(len(args))
+
+		switch property := filter.Field; {
+		case property == "document_id":
+			conditions = append(conditions, pgvectorMatch("document_id", filter.Operator, placeholder))
+		case isListProperty(property) && filter.Operator == FilterEqual:
+			conditions = append(conditions, fmt.Sprintf("metadata->'%s' ? %s", property, placeholder))
+		case isListProperty(property):
+			element := strings.TrimSuffix(property, "s")
+			conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM jsonb_array_elements_text(metadata->'%s') AS %s WHERE %s)",
+				property, element, pgvectorMatch(element, filter.Operator, placeholder)))
+		case strings.HasPrefix(property, "custom_"):
+			// Custom keys come from the caller, so they're passed as a parameter too
+			args = append(args, strings.TrimPrefix(property, "custom_"))
+			conditions = append(conditions, pgvectorMatch(fmt.Sprintf("metadata->'custom'->>$%d::text", len(args)), filter.Operator, placeholder))
+		default:
+			conditions = append(conditions, pgvectorMatch(fmt.Sprintf("metadata->>'%s'", property), filter.Operator, placeholder))
+		}
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// pgvectorMatch compares a text expression with a placeholder using a filter operator
+func pgvectorMatch(expr, operator, placeholder string) string {
+	switch operator {
+	case FilterContains:
+		return fmt.Sprintf("strpos(%s, %s) > 0", expr, placeholder)
+	case FilterPrefix:
+		return fmt.Sprintf("starts_with(%s, %s)", expr, placeholder)
+	case FilterContainsFold:
+		return fmt.Sprintf("strpos(lower(%s), lower(%s)) > 0", expr, placeholder)
+	default:
+		return expr + " = " + placeholder
+	}
+}
+
+// queryChunks runs a SELECT of pgvectorColumns with the given condition
+func (p *PgVectorStore) queryChunks(ctx context.Context, where string, args ...any) ([]types.DocumentChunk, error) {
+	if err := p.ensureSchema(ctx); err != nil {
+		return nil, err
+	}
+
+	statement := fmt.Sprintf(`SELECT %s FROM %s WHERE %s ORDER BY chunk_index`, pgvectorColumns, p.table, where)
+	rows, err := p.pool.Query(ctx, statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chunks []types.DocumentChunk
+	for rows.Next() {
+		var row pgvectorRow
+		if err := rows.Scan(row.scanTargets()...); err != nil {
+			return nil, err
+		}
+		chunk, err := row.toDocumentChunk()
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert row to document chunk: %w", err)
+		}
+		chunks = append(chunks, *chunk)
+	}
+	return chunks, rows.Err()
+}
+
+// GetChunksByDocumentID retrieves all live chunks for a document in chunk order
+func (p *PgVectorStore) GetChunksByDocumentID(ctx context.Context, documentID string) ([]types.DocumentChunk, error) {
+	if documentID == "" {
+		return nil, fmt.Errorf("document ID cannot be empty")
+	}
+
+	chunks, err := p.queryChunks(ctx, "document_id = $1 AND NOT deleted", documentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chunks from pgvector: %w", err)
+	}
+	return chunks, nil
+}
+
+// GetChunkByID retrieves a specific chunk by its ID
+func (p *PgVectorStore) GetChunkByID(ctx context.Context, chunkID uint64) (*types.DocumentChunk, error) {
+	if chunkID == 0 {
+		return nil, fmt.Errorf("chunk ID cannot be zero")
+	}
+
+	chunks, err := p.queryChunks(ctx, "id = $1 AND NOT deleted", int64(chunkID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chunk from pgvector: %w", err)
+	}
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("chunk not found: %d", chunkID)
+	}
+	return &chunks[0], nil
+}
+
+// ListDocumentIDs returns the IDs of documents with live chunks, sorted
+func (p *PgVectorStore) ListDocumentIDs(ctx context.Context) ([]string, error) {
+	if err := p.ensureSchema(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := p.pool.Query(ctx, fmt.Sprintf(`SELECT DISTINCT document_id FROM %s WHERE NOT deleted ORDER BY document_id`, p.table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents in pgvector: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents in pgvector: %w", err)
+	}
+	return ids, nil
+}
+
+// DeleteDocument removes a document, or flags it as deleted when soft delete is enabled
+func (p *PgVectorStore) DeleteDocument(ctx context.Context, documentID string) error {
+	if p.config.SoftDelete {
+		return p.setDeleted(ctx, documentID, true)
+	}
+	return p.PurgeDocument(ctx, documentID)
+}
+
+// RestoreDocument clears the soft-delete flag on a document's chunks
+func (p *PgVectorStore) RestoreDocument(ctx context.Context, documentID string) error {
+	return p.setDeleted(ctx, documentID, false)
+}
+
+// setDeleted sets the soft-delete flag on every chunk of a document
+func (p *PgVectorStore) setDeleted(ctx context.Context, documentID string, deleted bool) error {
+	if documentID == "" {
+		return fmt.Errorf("document ID cannot be empty")
+	}
+
+	if err := p.ensureSchema(ctx); err != nil {
+		return err
+	}
+
+	_, err := p.pool.Exec(ctx, fmt.Sprintf(`UPDATE %s SET deleted = $2 WHERE document_id = $1`, p.table), documentID, deleted)
+	if err != nil {
+		return fmt.Errorf("failed to update deleted flag in pgvector: %w", err)
+	}
+	return nil
+}
+
+// PurgeDocument permanently removes all chunks for a document, whether or not
+// they were soft-deleted
+func (p *PgVectorStore) PurgeDocument(ctx context.Context, documentID string) error {
+	if documentID == "" {
+		return fmt.Errorf("document ID cannot be empty")
+	}
+
+	if err := p.ensureSchema(ctx); err != nil {
+		return err
+	}
+
+	if _, err := p.pool.Exec(ctx, fmt.Sprintf(`DELETE FROM %s WHERE document_id = $1`, p.table), documentID); err != nil {
+		return fmt.Errorf("failed to delete document from pgvector: %w", err)
+	}
+	return nil
+}
+
+// HealthCheck checks if Postgres is accessible
+func (p *PgVectorStore) HealthCheck(ctx context.Context) error {
+	if err := p.pool.Ping(ctx); err != nil {
+		return fmt.Errorf("Postgres health check failed: %w", err)
+	}
+	return nil
+}
+
+// DeleteChunk removes a specific chunk
+func (p *PgVectorStore) DeleteChunk(ctx context.Context, chunkID uint64) error {
+	if chunkID == 0 {
+		return fmt.Errorf("chunk ID cannot be zero")
+	}
+
+	if err := p.ensureSchema(ctx); err != nil {
+		return err
+	}
+
+	if _, err := p.pool.Exec(ctx, fmt.Sprintf(`DELETE FROM %s WHERE id = $1`, p.table), int64(chunkID)); err != nil {
+		return fmt.Errorf("failed to delete chunk from pgvector: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package store
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"go-rag/internal/types"
+)
+
+func TestNewPgVectorStore(t *testing.T) {
+	embeddingService := &MockEmbeddingService{dimensions: 4}
+	valid := types.VectorStoreConfig{Provider: "pgvector", Host: "localhost", Port: 5432, Database: "rag", CollectionName: "documents"}
+
+	store, err := NewPgVectorStore(valid, embeddingService)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer store.Close()
+	if store.table != `"documents"` {
+		t.Errorf("Expected the quoted table name, got %s", store.table)
+	}
+
+	badName := valid
+	badName.CollectionName = "my-docs"
+	missingHost := valid
+	missingHost.Host = ""
+	badDSN := valid
+	badDSN.DSN = "postgres://%zz"
+	for name, config := range map[string]types.VectorStoreConfig{"invalid table name": badName, "missing host": missingHost, "invalid DSN": badDSN} {
+		if _, err := NewPgVectorStore(config, embeddingService); err == nil {
+			t.Errorf("Expected %s to be rejected", name)
+		}
+	}
+
+	vectorStore, err := NewStore(valid, embeddingService)
+	if _, ok := vectorStore.(*PgVectorStore); err != nil || !ok {
+		t.Errorf("Expected NewStore to create a PgVectorStore, got %T, %v", vectorStore, err)
+	}
+	vectorStore.(*PgVectorStore).Close()
+}
+
+func TestPgvectorDSN(t *testing.T) {
+	config := types.VectorStoreConfig{Host: "db", Port: 5433, Database: "rag", APIKey: `it's\secret`}
+	want := `host='db' port=5433 dbname='rag' password='it\'s\\secret'`
+	if got := pgvectorDSN(config); got != want {
+		t.Errorf("Unexpected DSN:\n got %s\nwant %s", got, want)
+	}
+
+	config.DSN = "postgres://user@db/rag"
+	if got := pgvectorDSN(config); got != config.DSN {
+		t.Errorf("Expected the configured DSN to win, got %s", got)
+	}
+}
+
+func TestPgvectorSearchWhere(t *testing.T) {
+	conditions, err := parseFilters(map[string]string{"source": "docs", "tags": "go", "team": "search", "document_id": "doc-1"})
+	if err != nil {
+		t.Fatalf("parseFilters failed: %v", err)
+	}
+	where, args := pgvectorSearchWhere(conditions, []any{"[0.1]", 5})
+
+	want := `NOT deleted AND document_id = $3 AND metadata->>'source' = $4 AND metadata->'tags' ? $5 AND metadata->'custom'->>$7::text = $6`
+	if where != want {
+		t.Errorf("Unexpected condition:\n got %s\nwant %s", where, want)
+	}
+	wantArgs := []any{"[0.1]", 5, "doc-1", "docs", "go", "search", "team"}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("Unexpected args: %v", args)
+	}
+
+	if where, args := pgvectorSearchWhere(nil, nil); where != "NOT deleted" || len(args) != 0 {
+		t.Errorf("Expected only the live-chunk condition, got %s, %v", where, args)
+	}
+
+	conditions, err = parseFilters(map[string]string{"path:contains": "Chapter 3"})
+	if err != nil {
+		t.Fatalf("parseFilters failed: %v", err)
+	}
+	where, _ = pgvectorSearchWhere(conditions, nil)
+	want = `NOT deleted AND EXISTS (SELECT 1 FROM jsonb_array_elements_text(metadata->'path') AS path WHERE strpos(path, $1) > 0)`
+	if where != want {
+		t.Errorf("Unexpected path condition:\n got %s\nwant %s", where, want)
+	}
+
+	conditions, err = parseFilters(map[string]string{"source:icontains": "Hand", "tags:prefix": "g", "team:contains": "ear"})
+	if err != nil {
+		t.Fatalf("parseFilters failed: %v", err)
+	}
+	where, args = pgvectorSearchWhere(conditions, nil)
+	want = `NOT deleted AND strpos(lower(metadata->>'source'), lower($1)) > 0 AND ` +
+		`EXISTS (SELECT 1 FROM jsonb_array_elements_text(metadata->'tags') AS tag WHERE starts_with(tag, $2)) AND ` +
+		`strpos(metadata->'custom'->>$4::text, $3) > 0`
+	if where != want {
+		t.Errorf("Unexpected operator condition:\n got %s\nwant %s", where, want)
+	}
+	if !reflect.DeepEqual(args, []any{"Hand", "g", "ear", "team"}) {
+		t.Errorf("Unexpected args: %v", args)
+	}
+}
+
+func TestVectorLiteral(t *testing.T) {
+	if got := vectorLiteral([]float32{0.1, -2, 3.5}); got != "[0.1,-2,3.5]" {
+		t.Errorf("Unexpected vector literal %s", got)
+	}
+}
+
+func TestPgVectorRowConversion(t *testing.T) {
+	store := &PgVectorStore{config: types.VectorStoreConfig{CompressContent: true}}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	chunk := types.DocumentChunk{
+		ID: ^uint64(0) - 1, DocumentID: "doc-1", Content: "content", Window: "the window", ChunkIndex: 2, TotalChunks: 3,
+		Metadata:  types.Metadata{Title: "Guide", Tags: []string{"go"}, Custom: map[string]string{"team": "search"}},
+		CreatedAt: now, UpdatedAt: now,
+	}
+
+	values, err := store.chunkRow(chunk)
+	if err != nil {
+		t.Fatalf("chunkRow failed: %v", err)
+	}
+	if values[2] == "content" {
+		t.Error("Expected content to be compressed")
+	}
+
+	// Fill the scanned row from the written values, as Postgres would return them
+	var row pgvectorRow
+	targets := row.scanTargets()
+	if len(targets) != len(values) {
+		t.Fatalf("Expected %d columns written and read, got %d and %d", len(targets), len(values), len(targets))
+	}
+	for i, value := range values {
+		if metadata, ok := value.(string); ok && i == 11 {
+			value = []byte(metadata)
+		}
+		reflect.ValueOf(targets[i]).Elem().Set(reflect.ValueOf(value))
+	}
+
+	got, err := row.toDocumentChunk()
+	if err != nil {
+		t.Fatalf("toDocumentChunk failed: %v", err)
+	}
+	if got.ID != chunk.ID || got.Content != "content" || got.Window != "the window" || got.ChunkIndex != 2 ||
+		got.Metadata.Title != "Guide" || len(got.Metadata.Tags) != 1 || got.Metadata.Custom["team"] != "search" || !got.CreatedAt.Equal(now) {
+		t.Errorf("Chunk did not round-trip: %+v", got)
+	}
+
+	var metadata map[string]any
+	if err := json.Unmarshal(row.metadata, &metadata); err != nil || metadata["title"] != "Guide" {
+		t.Errorf("Expected metadata stored as a JSON object, got %s", row.metadata)
+	}
+}
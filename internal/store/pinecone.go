@@ -0,0 +1,560 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-rag/internal/embedding"
+	"go-rag/internal/types"
+)
+
+const (
+	// pineconeAPIVersion pins the data plane API version the store was written against
+	pineconeAPIVersion = "2024-07"
+	// pineconeMaxTopK is the most matches Pinecone returns per query when metadata is included
+	pineconeMaxTopK = 1000
+	// pineconeUpsertBatch keeps upsert requests well under Pinecone's 2MB request limit
+	pineconeUpsertBatch = 100
+	// pineconeDeleteBatch is the most IDs Pinecone deletes in one request
+	pineconeDeleteBatch = 1000
+)
+
+// PineconeStore implements VectorStore using a Pinecone index. The collection
+// name is used as the index namespace, and chunk payloads are stored as vector
+// metadata with the same keys QdrantStore uses.
+type PineconeStore struct {
+	config           types.VectorStoreConfig
+	baseURL          string
+	httpClient       *http.Client
+	embeddingService embedding.Service
+}
+
+// pineconeVector is a vector as sent to and returned by the Pinecone data plane
+type pineconeVector struct {
+	ID       string         `json:"id"`
+	Values   []float32      `json:"values,omitempty"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// pineconeMatch is one query result
+type pineconeMatch struct {
+	ID       string         `json:"id"`
+	Score    float32        `json:"score"`
+	Metadata map[string]any `json:"metadata"`
+}
+
+// NewPineconeStore creates a Pinecone vector store. config.Host is the index
+// host shown in the Pinecone console, e.g. "docs-abc123.svc.us-east-1.pinecone.io".
+func NewPineconeStore(config types.VectorStoreConfig, embeddingService embedding.Service) (*PineconeStore, error) {
+	if config.Provider != "pinecone" {
+		return nil, fmt.Errorf("unsupported vector store provider: %s", config.Provider)
+	}
+
+	if config.Host == "" {
+		return nil, fmt.Errorf("pinecone index host is required")
+	}
+
+	if config.APIKey == "" {
+		return nil, fmt.Errorf("pinecone API key is required")
+	}
+
+	if config.CollectionName == "" {
+		return nil, fmt.Errorf("collection name is required")
+	}
+
+	if embeddingService == nil {
+		return nil, fmt.Errorf("embedding service is required")
+	}
+
+	// Pinecone indexes hold a single dense vector per record
+	if len(config.VectorFields) > 0 {
+		return nil, fmt.Errorf("named vectors are not supported by the pinecone store")
+	}
+
+	baseURL := config.Host
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "https://" + baseURL
+	}
+
+	return &PineconeStore{
+		config:           config,
+		baseURL:          strings.TrimRight(baseURL, "/"),
+		httpClient:       &http.Client{Timeout: 30 * time.Second},
+		embeddingService: embeddingService,
+	}, nil
+}
+
+// do sends a JSON request to the index and decodes the JSON response into out
+func (p *PineconeStore) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Api-Key", p.config.APIKey)
+	req.Header.Set("X-Pinecone-API-Version", pineconeAPIVersion)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("pinecone returned %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// StoreChunks embeds and upserts chunks into the index namespace
+func (p *PineconeStore) StoreChunks(ctx context.Context, chunks []types.DocumentChunk) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	embeddings, err := embedContent(ctx, p.embeddingService, chunks)
+	if err != nil {
+		return err
+	}
+
+	vectors := make([]pineconeVector, len(chunks))
+	for i, chunk := range chunks {
+		metadata, err := p.chunkMetadata(chunk)
+		if err != nil {
+			return err
+		}
+		vectors[i] = pineconeVector{
+			ID:       strconv.FormatUint(chunk.ID, 10),
+			Values:   embeddings[i],
+			Metadata: metadata,
+		}
+	}
+
+	for start := 0; start < len(vectors); start += pineconeUpsertBatch {
+		end := min(start+pineconeUpsertBatch, len(vectors))
+		err := p.do(ctx, http.MethodPost, "/vectors/upsert", map[string]any{
+			"vectors":   vectors[start:end],
+			"namespace": p.config.CollectionName,
+		}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to upsert vectors to Pinecone: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// chunkMetadata builds the metadata stored with a chunk, using the same keys
+// as the Qdrant payload. Pinecone metadata can't hold nulls, so empty fields
+// are left out.
+func (p *PineconeStore) chunkMetadata(chunk types.DocumentChunk) (map[string]any, error) {
+	content, window := chunk.Content, chunk.Window
+	if p.config.CompressContent {
+		var err error
+		if content, err = compressContent(chunk.Content); err != nil {
+			return nil, fmt.Errorf("failed to compress content for chunk %d: %w", chunk.ID, err)
+		}
+		if window != "" {
+			if window, err = compressContent(chunk.Window); err != nil {
+				return nil, fmt.Errorf("failed to compress window for chunk %d: %w", chunk.ID, err)
+			}
+		}
+	}
+
+	metadata := map[string]any{
+		"document_id":  chunk.DocumentID,
+		"content":      content,
+		"chunk_index":  chunk.ChunkIndex,
+		"total_chunks": chunk.TotalChunks,
+		"created_at":   chunk.CreatedAt.Format(time.RFC3339),
+		"updated_at":   chunk.UpdatedAt.Format(time.RFC3339),
+	}
+	if p.config.CompressContent {
+		metadata["content_compressed"] = true
+	}
+
+	optional := map[string]string{
+		"chunk_strategy": chunk.ChunkStrategy,
+		"window":         window,
+		"title":          chunk.Metadata.Title,
+		"author":         chunk.Metadata.Author,
+		"source":         chunk.Metadata.Source,
+		"language":       chunk.Metadata.Language,
+		"content_type":   chunk.Metadata.ContentType,
+		"parent_id":      chunk.Metadata.ParentID,
+		"section":        chunk.Metadata.Section,
+	}
+	for key, value := range optional {
+		if value != "" {
+			metadata[key] = value
+		}
+	}
+	if chunk.ChunkOverlap > 0 {
+		metadata["chunk_overlap"] = chunk.ChunkOverlap
+	}
+	if chunk.EndOffset > 0 {
+		metadata["start_offset"] = chunk.StartOffset
+		metadata["end_offset"] = chunk.EndOffset
+	}
+	if len(chunk.Metadata.Tags) > 0 {
+		metadata["tags"] = chunk.Metadata.Tags
+	}
+	if len(chunk.Metadata.Path) > 0 {
+		metadata["path"] = chunk.Metadata.Path
+	}
+	for key, value := range chunk.Metadata.Custom {
+		metadata["custom_"+key] = value
+	}
+
+	return metadata, nil
+}
+
+// vectorToDocumentChunk converts a Pinecone record back into a chunk
+func (p *PineconeStore) vectorToDocumentChunk(id string, score float32, metadata map[string]any) (*types.DocumentChunk, error) {
+	chunkID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil || chunkID == 0 {
+		return nil, fmt.Errorf("vector ID must be a non-zero number, got %q", id)
+	}
+
+	text := func(key string) string {
+		value, _ := metadata[key].(string)
+		return value
+	}
+	number := func(key string) int {
+		value, _ := metadata[key].(float64)
+		return int(value)
+	}
+
+	content, window := text("content"), text("window")
+	if compressed, _ := metadata["content_compressed"].(bool); compressed {
+		if content, err = decompressContent(content); err != nil {
+			return nil, fmt.Errorf("failed to decompress content for chunk %d: %w", chunkID, err)
+		}
+		if window != "" {
+			if window, err = decompressContent(window); err != nil {
+				return nil, fmt.Errorf("failed to decompress window for chunk %d: %w", chunkID, err)
+			}
+		}
+	}
+
+	chunkMetadata := types.Metadata{
+		Title:       text("title"),
+		Author:      text("author"),
+		Source:      text("source"),
+		Language:    text("language"),
+		ContentType: text("content_type"),
+		Custom:      make(map[string]string),
+		ParentID:    text("parent_id"),
+		Section:     text("section"),
+	}
+	if tags, ok := metadata["tags"].([]any); ok {
+		for _, tag := range tags {
+			if tag, ok := tag.(string); ok && tag != "" {
+				chunkMetadata.Tags = append(chunkMetadata.Tags, tag)
+			}
+		}
+	}
+	if path, ok := metadata["path"].([]any); ok {
+		for _, heading := range path {
+			if heading, ok := heading.(string); ok {
+				chunkMetadata.Path = append(chunkMetadata.Path, heading)
+			}
+		}
+	}
+	for key, value := range metadata {
+		if customKey, ok := strings.CutPrefix(key, "custom_"); ok && customKey != "" {
+			chunkMetadata.Custom[customKey], _ = value.(string)
+		}
+	}
+
+	createdAt, _ := time.Parse(time.RFC3339, text("created_at"))
+	updatedAt, _ := time.Parse(time.RFC3339, text("updated_at"))
+
+	return &types.DocumentChunk{
+		ID:            chunkID,
+		DocumentID:    text("document_id"),
+		Content:       content,
+		ChunkIndex:    number("chunk_index"),
+		TotalChunks:   number("total_chunks"),
+		StartOffset:   number("start_offset"),
+		EndOffset:     number("end_offset"),
+		ChunkStrategy: text("chunk_strategy"),
+		ChunkOverlap:  number("chunk_overlap"),
+		Window:        window,
+		VectorScore:   float64(score),
+		Metadata:      chunkMetadata,
+		CreatedAt:     createdAt,
+		UpdatedAt:     updatedAt,
+	}, nil
+}
+
+// SearchSimilar searches for similar chunks using vector similarity. Named
+// vectors aren't supported, so vectorName must be empty. Only chunks whose
+// metadata matches every filter are returned.
+func (p *PineconeStore) SearchSimilar(ctx context.Context, query string, limit int, vectorName string, filters map[string]string) ([]types.DocumentChunk, error) {
+	if query == "" {
+		return nil, fmt.Errorf("query cannot be empty")
+	}
+
+	if vectorName != "" {
+		return nil, fmt.Errorf("named vectors are not supported, cannot search %q", vectorName)
+	}
+
+	if limit <= 0 {
+		limit = 10
+	}
+
+	conditions, err := parseFilters(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pinecone metadata filters only compare whole values
+	filter := pineconeLiveFilter()
+	for _, condition := range conditions {
+		if condition.Operator != FilterEqual {
+			return nil, fmt.Errorf("the %s filter operator is not supported by the pinecone store", condition.Operator)
+		}
+		filter[condition.Field] = map[string]any{"$eq": condition.Value}
+	}
+
+	queryEmbedding, err := p.embeddingService.GenerateEmbedding(embedding.WithInputType(ctx, embedding.InputTypeQuery), query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
+	}
+
+	chunks, err := p.query(ctx, toFloat32(queryEmbedding), min(limit, pineconeMaxTopK), filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search in Pinecone: %w", err)
+	}
+	return chunks, nil
+}
+
+// query runs a vector query in the namespace and converts the matches
+func (p *PineconeStore) query(ctx context.Context, vector []float32, topK int, filter map[string]any) ([]types.DocumentChunk, error) {
+	var resp struct {
+		Matches []pineconeMatch `json:"matches"`
+	}
+	err := p.do(ctx, http.MethodPost, "/query", map[string]any{
+		"vector":          vector,
+		"topK":            topK,
+		"filter":          filter,
+		"includeMetadata": true,
+		"namespace":       p.config.CollectionName,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make([]types.DocumentChunk, len(resp.Matches))
+	for i, match := range resp.Matches {
+		chunk, err := p.vectorToDocumentChunk(match.ID, match.Score, match.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert vector to document chunk: %w", err)
+		}
+		chunks[i] = *chunk
+	}
+	return chunks, nil
+}
+
+// pineconeLiveFilter matches chunks that haven't been soft-deleted
+func pineconeLiveFilter() map[string]any {
+	return map[string]any{"deleted": map[string]any{"$ne": true}}
+}
+
+// GetChunksByDocumentID retrieves all live chunks for a document in chunk order
+func (p *PineconeStore) GetChunksByDocumentID(ctx context.Context, documentID string) ([]types.DocumentChunk, error) {
+	if documentID == "" {
+		return nil, fmt.Errorf("document ID cannot be empty")
+	}
+	return p.documentChunks(ctx, documentID, false)
+}
+
+// documentChunks collects a document's chunks. Pinecone can only look records
+// up by ID or by vector query, so this queries with a placeholder vector and
+// a metadata filter, one chunk_index window of pineconeMaxTopK at a time.
+func (p *PineconeStore) documentChunks(ctx context.Context, documentID string, includeDeleted bool) ([]types.DocumentChunk, error) {
+	placeholder := make([]float32, p.embeddingService.GetDimensions())
+	for i := range placeholder {
+		placeholder[i] = 1
+	}
+
+	var chunks []types.DocumentChunk
+	total := 0
+	for start := 0; start == 0 || start < total; start += pineconeMaxTopK {
+		filter := map[string]any{
+			"document_id": map[string]any{"$eq": documentID},
+			"chunk_index": map[string]any{"$gte": start, "$lt": start + pineconeMaxTopK},
+		}
+		if !includeDeleted {
+			filter["deleted"] = pineconeLiveFilter()["deleted"]
+		}
+
+		window, err := p.query(ctx, placeholder, pineconeMaxTopK, filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get chunks from Pinecone: %w", err)
+		}
+		for _, chunk := range window {
+			total = max(total, chunk.TotalChunks)
+			chunk.VectorScore = 0
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	sort.Slice(chunks, func(i, j int) bool {
+		return chunks[i].ChunkIndex < chunks[j].ChunkIndex
+	})
+	return chunks, nil
+}
+
+// GetChunkByID retrieves a specific chunk by its ID
+func (p *PineconeStore) GetChunkByID(ctx context.Context, chunkID uint64) (*types.DocumentChunk, error) {
+	if chunkID == 0 {
+		return nil, fmt.Errorf("chunk ID cannot be zero")
+	}
+
+	id := strconv.FormatUint(chunkID, 10)
+	params := url.Values{"ids": {id}, "namespace": {p.config.CollectionName}}
+
+	var resp struct {
+		Vectors map[string]pineconeVector `json:"vectors"`
+	}
+	if err := p.do(ctx, http.MethodGet, "/vectors/fetch?"+params.Encode(), nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to fetch vector from Pinecone: %w", err)
+	}
+
+	vector, ok := resp.Vectors[id]
+	if deleted, _ := vector.Metadata["deleted"].(bool); !ok || deleted {
+		return nil, fmt.Errorf("chunk not found: %d", chunkID)
+	}
+
+	chunk, err := p.vectorToDocumentChunk(vector.ID, 0, vector.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert vector to document chunk: %w", err)
+	}
+	return chunk, nil
+}
+
+// DeleteDocument removes a document, or flags it as deleted when soft delete is enabled
+func (p *PineconeStore) DeleteDocument(ctx context.Context, documentID string) error {
+	if p.config.SoftDelete {
+		return p.setDeleted(ctx, documentID, true)
+	}
+	return p.PurgeDocument(ctx, documentID)
+}
+
+// RestoreDocument clears the soft-delete flag on a document's chunks
+func (p *PineconeStore) RestoreDocument(ctx context.Context, documentID string) error {
+	return p.setDeleted(ctx, documentID, false)
+}
+
+// setDeleted sets the soft-delete flag on every chunk of a document. Pinecone
+// updates metadata one record at a time.
+func (p *PineconeStore) setDeleted(ctx context.Context, documentID string, deleted bool) error {
+	if documentID == "" {
+		return fmt.Errorf("document ID cannot be empty")
+	}
+
+	chunks, err := p.documentChunks(ctx, documentID, true)
+	if err != nil {
+		return err
+	}
+
+	for _, chunk := range chunks {
+		err := p.do(ctx, http.MethodPost, "/vectors/update", map[string]any{
+			"id":          strconv.FormatUint(chunk.ID, 10),
+			"setMetadata": map[string]any{"deleted": deleted},
+			"namespace":   p.config.CollectionName,
+		}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to update deleted flag in Pinecone: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// PurgeDocument permanently removes all chunks for a document, whether or not
+// they were soft-deleted. Serverless indexes can't delete by metadata filter,
+// so the chunks are looked up and deleted by ID.
+func (p *PineconeStore) PurgeDocument(ctx context.Context, documentID string) error {
+	if documentID == "" {
+		return fmt.Errorf("document ID cannot be empty")
+	}
+
+	chunks, err := p.documentChunks(ctx, documentID, true)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		ids[i] = strconv.FormatUint(chunk.ID, 10)
+	}
+	return p.deleteIDs(ctx, ids)
+}
+
+// CreateCollection does nothing: the index is created with its dimension
+// outside the service, and namespaces are created by their first write
+func (p *PineconeStore) CreateCollection(ctx context.Context, vectorSize int) error {
+	return nil
+}
+
+// HealthCheck checks if the Pinecone index is accessible
+func (p *PineconeStore) HealthCheck(ctx context.Context) error {
+	if err := p.do(ctx, http.MethodPost, "/describe_index_stats", map[string]any{}, nil); err != nil {
+		return fmt.Errorf("Pinecone health check failed: %w", err)
+	}
+	return nil
+}
+
+// DeleteChunk removes a specific chunk
+func (p *PineconeStore) DeleteChunk(ctx context.Context, chunkID uint64) error {
+	if chunkID == 0 {
+		return fmt.Errorf("chunk ID cannot be zero")
+	}
+	return p.deleteIDs(ctx, []string{strconv.FormatUint(chunkID, 10)})
+}
+
+// deleteIDs deletes records by ID in batches
+func (p *PineconeStore) deleteIDs(ctx context.Context, ids []string) error {
+	for start := 0; start < len(ids); start += pineconeDeleteBatch {
+		end := min(start+pineconeDeleteBatch, len(ids))
+		err := p.do(ctx, http.MethodPost, "/vectors/delete", map[string]any{
+			"ids":       ids[start:end],
+			"namespace": p.config.CollectionName,
+		}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to delete vectors from Pinecone: %w", err)
+		}
+	}
+	return nil
+}
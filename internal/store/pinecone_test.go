@@ -0,0 +1,293 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-rag/internal/types"
+)
+
+// fakePinecone serves the subset of the Pinecone data plane the store uses
+type fakePinecone struct {
+	mu      sync.Mutex
+	vectors map[string]pineconeVector
+	queries int
+}
+
+func newFakePinecone(t *testing.T) (*fakePinecone, *httptest.Server) {
+	fake := &fakePinecone{vectors: make(map[string]pineconeVector)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Api-Key") != "key" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+
+		var body map[string]json.RawMessage
+		if r.Method == http.MethodPost {
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		switch r.URL.Path {
+		case "/vectors/upsert":
+			var vectors []pineconeVector
+			json.Unmarshal(body["vectors"], &vectors)
+			for _, vector := range vectors {
+				// Round-trip through JSON so metadata has the types Pinecone returns
+				data, _ := json.Marshal(vector)
+				json.Unmarshal(data, &vector)
+				fake.vectors[vector.ID] = vector
+			}
+			w.Write([]byte(`{}`))
+		case "/query":
+			fake.queries++
+			var topK int
+			var filter map[string]map[string]any
+			json.Unmarshal(body["topK"], &topK)
+			json.Unmarshal(body["filter"], &filter)
+			matches := []pineconeMatch{}
+			for _, vector := range fake.vectors {
+				if len(matches) < topK && matchesPineconeFilter(vector.Metadata, filter) {
+					matches = append(matches, pineconeMatch{ID: vector.ID, Score: 0.5, Metadata: vector.Metadata})
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]any{"matches": matches})
+		case "/vectors/fetch":
+			found := map[string]pineconeVector{}
+			for _, id := range r.URL.Query()["ids"] {
+				if vector, ok := fake.vectors[id]; ok {
+					found[id] = vector
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]any{"vectors": found})
+		case "/vectors/update":
+			var id string
+			var setMetadata map[string]any
+			json.Unmarshal(body["id"], &id)
+			json.Unmarshal(body["setMetadata"], &setMetadata)
+			for key, value := range setMetadata {
+				fake.vectors[id].Metadata[key] = value
+			}
+			w.Write([]byte(`{}`))
+		case "/vectors/delete":
+			var ids []string
+			json.Unmarshal(body["ids"], &ids)
+			for _, id := range ids {
+				delete(fake.vectors, id)
+			}
+			w.Write([]byte(`{}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+// matchesPineconeFilter evaluates the $eq, $ne, $gte and $lt operators
+func matchesPineconeFilter(metadata map[string]any, filter map[string]map[string]any) bool {
+	for key, conditions := range filter {
+		value := metadata[key]
+		for op, operand := range conditions {
+			number, _ := value.(float64)
+			bound, _ := operand.(float64)
+			switch {
+			case op == "$eq" && value != operand,
+				op == "$ne" && value == operand,
+				op == "$gte" && (value == nil || number < bound),
+				op == "$lt" && (value == nil || number >= bound):
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func newTestPineconeStore(t *testing.T, softDelete bool) (*PineconeStore, *fakePinecone) {
+	fake, server := newFakePinecone(t)
+	store, err := NewPineconeStore(types.VectorStoreConfig{
+		Provider:       "pinecone",
+		Host:           server.URL,
+		APIKey:         "key",
+		CollectionName: "documents",
+		SoftDelete:     softDelete,
+	}, &MockEmbeddingService{dimensions: 4})
+	if err != nil {
+		t.Fatalf("Failed to create Pinecone store: %v", err)
+	}
+	return store, fake
+}
+
+func TestNewPineconeStore_InvalidConfig(t *testing.T) {
+	valid := types.VectorStoreConfig{Provider: "pinecone", Host: "docs.svc.pinecone.io", APIKey: "key", CollectionName: "documents"}
+	embeddingService := &MockEmbeddingService{dimensions: 4}
+
+	store, err := NewPineconeStore(valid, embeddingService)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if store.baseURL != "https://docs.svc.pinecone.io" {
+		t.Errorf("Expected an https base URL, got %q", store.baseURL)
+	}
+
+	missingKey := valid
+	missingKey.APIKey = ""
+	namedVectors := valid
+	namedVectors.VectorFields = []string{"title"}
+	for name, config := range map[string]types.VectorStoreConfig{"missing key": missingKey, "named vectors": namedVectors} {
+		if _, err := NewPineconeStore(config, embeddingService); err == nil {
+			t.Errorf("Expected %s to be rejected", name)
+		}
+	}
+}
+
+func TestNewStore_DispatchesOnProvider(t *testing.T) {
+	embeddingService := &MockEmbeddingService{dimensions: 4}
+
+	vectorStore, err := NewStore(types.VectorStoreConfig{Provider: "pinecone", Host: "docs.svc.pinecone.io", APIKey: "key", CollectionName: "documents"}, embeddingService)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := vectorStore.(*PineconeStore); !ok {
+		t.Errorf("Expected a PineconeStore, got %T", vectorStore)
+	}
+
+	vectorStore, err = NewStore(types.VectorStoreConfig{Provider: "pinecone", CollectionName: "documents"}, embeddingService)
+	if err == nil || vectorStore != nil {
+		t.Errorf("Expected a nil store and an error for an invalid config, got %v, %v", vectorStore, err)
+	}
+
+	if _, err := NewStore(types.VectorStoreConfig{Provider: "milvus"}, embeddingService); err == nil {
+		t.Error("Expected an unsupported provider to be rejected")
+	}
+}
+
+func TestPineconeStore_RoundTrip(t *testing.T) {
+	store, _ := newTestPineconeStore(t, false)
+	store.config.CompressContent = true
+	ctx := context.Background()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	chunks := []types.DocumentChunk{
+		{ID: 2, DocumentID: "doc-1", Content: "second", ChunkIndex: 1, TotalChunks: 2, CreatedAt: now, UpdatedAt: now},
+		{ID: 1, DocumentID: "doc-1", Content: "first", ChunkIndex: 0, TotalChunks: 2, Window: "first second", CreatedAt: now, UpdatedAt: now,
+			Metadata: types.Metadata{Title: "Guide", Tags: []string{"go"}, Custom: map[string]string{"team": "search"}}},
+		{ID: 3, DocumentID: "doc-2", Content: "other", ChunkIndex: 0, TotalChunks: 1, CreatedAt: now, UpdatedAt: now},
+	}
+	if err := store.StoreChunks(ctx, chunks); err != nil {
+		t.Fatalf("StoreChunks failed: %v", err)
+	}
+
+	got, err := store.GetChunksByDocumentID(ctx, "doc-1")
+	if err != nil {
+		t.Fatalf("GetChunksByDocumentID failed: %v", err)
+	}
+	if len(got) != 2 || got[0].ID != 1 || got[1].ID != 2 {
+		t.Fatalf("Expected chunks 1 and 2 in order, got %+v", got)
+	}
+	first := got[0]
+	if first.Content != "first" || first.Window != "first second" || first.Metadata.Title != "Guide" ||
+		len(first.Metadata.Tags) != 1 || first.Metadata.Custom["team"] != "search" || !first.CreatedAt.Equal(now) {
+		t.Errorf("Chunk did not round-trip: %+v", first)
+	}
+
+	results, err := store.SearchSimilar(ctx, "query", 10, "", map[string]string{"team": "search"})
+	if err != nil {
+		t.Fatalf("SearchSimilar failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != 1 || results[0].VectorScore != 0.5 {
+		t.Errorf("Expected only chunk 1 with its score, got %+v", results)
+	}
+
+	if _, err := store.SearchSimilar(ctx, "query", 10, "title", nil); err == nil {
+		t.Error("Expected a named vector search to be rejected")
+	}
+
+	chunk, err := store.GetChunkByID(ctx, 3)
+	if err != nil || chunk.DocumentID != "doc-2" {
+		t.Errorf("Expected chunk 3 of doc-2, got %+v, %v", chunk, err)
+	}
+	if _, err := store.GetChunkByID(ctx, 99); err == nil {
+		t.Error("Expected a missing chunk to return an error")
+	}
+}
+
+func TestPineconeStore_SoftDeleteRestoreAndPurge(t *testing.T) {
+	store, fake := newTestPineconeStore(t, true)
+	ctx := context.Background()
+
+	if err := store.StoreChunks(ctx, []types.DocumentChunk{
+		{ID: 1, DocumentID: "doc-1", Content: "first", ChunkIndex: 0, TotalChunks: 1},
+	}); err != nil {
+		t.Fatalf("StoreChunks failed: %v", err)
+	}
+
+	if err := store.DeleteDocument(ctx, "doc-1"); err != nil {
+		t.Fatalf("DeleteDocument failed: %v", err)
+	}
+	if got, _ := store.GetChunksByDocumentID(ctx, "doc-1"); len(got) != 0 {
+		t.Errorf("Expected soft-deleted chunks to be hidden, got %d", len(got))
+	}
+	if _, err := store.GetChunkByID(ctx, 1); err == nil {
+		t.Error("Expected a soft-deleted chunk to be hidden")
+	}
+
+	if err := store.RestoreDocument(ctx, "doc-1"); err != nil {
+		t.Fatalf("RestoreDocument failed: %v", err)
+	}
+	if got, _ := store.GetChunksByDocumentID(ctx, "doc-1"); len(got) != 1 {
+		t.Errorf("Expected the restored chunk, got %d", len(got))
+	}
+
+	if err := store.PurgeDocument(ctx, "doc-1"); err != nil {
+		t.Fatalf("PurgeDocument failed: %v", err)
+	}
+	if len(fake.vectors) != 0 {
+		t.Errorf("Expected purge to remove every vector, %d left", len(fake.vectors))
+	}
+}
+
+func TestPineconeStore_PagesLargeDocuments(t *testing.T) {
+	store, fake := newTestPineconeStore(t, false)
+	ctx := context.Background()
+
+	total := pineconeMaxTopK + 5
+	chunks := make([]types.DocumentChunk, total)
+	for i := range chunks {
+		chunks[i] = types.DocumentChunk{ID: uint64(i + 1), DocumentID: "doc-1", Content: "chunk", ChunkIndex: i, TotalChunks: total}
+	}
+	if err := store.StoreChunks(ctx, chunks); err != nil {
+		t.Fatalf("StoreChunks failed: %v", err)
+	}
+
+	got, err := store.GetChunksByDocumentID(ctx, "doc-1")
+	if err != nil {
+		t.Fatalf("GetChunksByDocumentID failed: %v", err)
+	}
+	if len(got) != total || got[total-1].ChunkIndex != total-1 {
+		t.Fatalf("Expected %d ordered chunks, got %d", total, len(got))
+	}
+	if fake.queries != 2 {
+		t.Errorf("Expected two chunk_index windows, got %d queries", fake.queries)
+	}
+}
+
+func TestPineconeStore_SurfacesAPIErrors(t *testing.T) {
+	store, _ := newTestPineconeStore(t, false)
+	store.config.APIKey = "wrong"
+
+	_, err := store.SearchSimilar(context.Background(), "query", 5, "", nil)
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected the Pinecone status in the error, got %v", err)
+	}
+}
@@ -1,24 +1,107 @@
 package store
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
 	"time"
 
 	"go-rag/internal/embedding"
 	"go-rag/internal/types"
 
 	"github.com/qdrant/go-client/qdrant"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // VectorStore interface defines the contract for vector storage operations
 type VectorStore interface {
 	StoreChunks(ctx context.Context, chunks []types.DocumentChunk) error
-	SearchSimilar(ctx context.Context, query string, limit int) ([]types.DocumentChunk, error)
+	SearchSimilar(ctx context.Context, query string, limit int, vectorName string, filters map[string]string) ([]types.DocumentChunk, error)
 	GetChunksByDocumentID(ctx context.Context, documentID string) ([]types.DocumentChunk, error)
 	GetChunkByID(ctx context.Context, chunkID uint64) (*types.DocumentChunk, error)
 	DeleteDocument(ctx context.Context, documentID string) error
+	RestoreDocument(ctx context.Context, documentID string) error
+	PurgeDocument(ctx context.Context, documentID string) error
 	DeleteChunk(ctx context.Context, chunkID uint64) error
+	// HealthCheck returns an error when the store can't be reached
+	HealthCheck(ctx context.Context) error
+	// CreateCollection creates the collection for vectors of the given size
+	// if it doesn't exist yet
+	CreateCollection(ctx context.Context, vectorSize int) error
+}
+
+// ErrDimensionMismatch is returned when a pre-computed vector doesn't match the collection's vector size
+var ErrDimensionMismatch = errors.New("vector dimensions do not match the collection")
+
+// ErrPartialResults is returned along with the chunks found when a search hit
+// its timeout and the store is configured to return partial results
+var ErrPartialResults = errors.New("search timed out, results may be incomplete")
+
+// DiagnosticSearcher is implemented by stores that can report how a search was executed
+type DiagnosticSearcher interface {
+	SearchWithDiagnostics(ctx context.Context, query string, limit int, vectorName string, filters map[string]string) ([]types.DocumentChunk, *types.SearchDiagnostics, error)
+}
+
+// CollectionDropper is implemented by stores that can delete their whole
+// collection, for a clean re-index
+type CollectionDropper interface {
+	DropCollection(ctx context.Context) error
+}
+
+// ChunkStreamer is implemented by stores that can stream a document's chunks in
+// chunk_index order without loading the whole document at once
+type ChunkStreamer interface {
+	StreamChunksByDocumentID(ctx context.Context, documentID string, fn func(types.DocumentChunk) error) error
+}
+
+// CollectionDescriber is implemented by stores that can report the collection
+// they search and its distance metric
+type CollectionDescriber interface {
+	Describe() types.ResponseMeta
+}
+
+// StatsReporter is implemented by stores that can summarize their collection
+type StatsReporter interface {
+	Stats(ctx context.Context) (*types.CollectionStats, error)
+}
+
+// DocumentCounter is implemented by stores that can count a document's chunks
+// without reading them. Soft-deleted chunks are counted with withDeleted.
+type DocumentCounter interface {
+	CountDocumentChunks(ctx context.Context, documentID string, withDeleted bool) (uint64, error)
+}
+
+// DocumentLister is implemented by stores that can enumerate their documents
+type DocumentLister interface {
+	ListDocumentIDs(ctx context.Context) ([]string, error)
+}
+
+// Distance metrics a Qdrant collection can be created with
+const (
+	DistanceCosine = "cosine"
+	DistanceDot    = "dot"
+	DistanceEuclid = "euclid"
+)
+
+// collectionDistance maps a configured distance metric onto Qdrant's, using
+// cosine when none is set
+func collectionDistance(metric string) qdrant.Distance {
+	switch metric {
+	case DistanceDot:
+		return qdrant.Distance_Dot
+	case DistanceEuclid:
+		return qdrant.Distance_Euclid
+	default:
+		return qdrant.Distance_Cosine
+	}
 }
 
 // QdrantStore implements VectorStore using Qdrant
@@ -64,6 +147,105 @@ func NewQdrantStore(config types.VectorStoreConfig, embeddingService embedding.S
 	}, nil
 }
 
+// Close closes the connection to Qdrant. Stores created with WithCollection or
+// WithEmbeddingService share the connection, so close only the original store.
+func (q *QdrantStore) Close() error {
+	return q.client.Close()
+}
+
+// WithEmbeddingService returns a store that shares this store's client and
+// collection but embeds with a different service
+func (q *QdrantStore) WithEmbeddingService(embeddingService embedding.Service) *QdrantStore {
+	return &QdrantStore{
+		config:           q.config,
+		client:           q.client,
+		embeddingService: embeddingService,
+	}
+}
+
+// Describe reports the store's collection and distance metric. The embedding
+// model isn't known to the store, so it is left empty.
+func (q *QdrantStore) Describe() types.ResponseMeta {
+	return types.ResponseMeta{
+		Collection: q.config.CollectionName,
+		Distance:   strings.ToLower(collectionDistance(q.config.DistanceMetric).String()),
+	}
+}
+
+// Stats reads the vector size, distance metric and point count from the
+// collection info. Documents are counted by their first chunk, and with soft
+// delete enabled, chunks are counted too, so flagged ones are left out.
+func (q *QdrantStore) Stats(ctx context.Context) (*types.CollectionStats, error) {
+	info, err := q.client.GetCollectionInfo(ctx, q.config.CollectionName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collection %s: %w", q.config.CollectionName, err)
+	}
+
+	vectors := info.GetConfig().GetParams().GetVectorsConfig()
+	stats := &types.CollectionStats{
+		Collection: q.config.CollectionName,
+		Chunks:     info.GetPointsCount(),
+		Dimensions: vectorSize(vectors),
+		Distance:   q.Describe().Distance,
+	}
+	if distance := vectorDistance(vectors); distance != "" {
+		stats.Distance = distance
+	}
+
+	count := func(filter *qdrant.Filter) (uint64, error) {
+		return q.client.Count(ctx, &qdrant.CountPoints{
+			CollectionName: q.config.CollectionName,
+			Filter:         filter,
+			Exact:          qdrant.PtrOf(true),
+		})
+	}
+	if q.config.SoftDelete {
+		if stats.Chunks, err = count(activeFilter()); err != nil {
+			return nil, fmt.Errorf("failed to count points in Qdrant: %w", err)
+		}
+	}
+	firstChunks := activeFilter()
+	firstChunks.Must = []*qdrant.Condition{qdrant.NewMatchInt("chunk_index", 0)}
+	if stats.Documents, err = count(firstChunks); err != nil {
+		return nil, fmt.Errorf("failed to count documents in Qdrant: %w", err)
+	}
+
+	return stats, nil
+}
+
+// CountDocumentChunks counts the points of a document, leaving out
+// soft-deleted ones unless withDeleted is set
+func (q *QdrantStore) CountDocumentChunks(ctx context.Context, documentID string, withDeleted bool) (uint64, error) {
+	filter := &qdrant.Filter{}
+	if !withDeleted {
+		filter = activeFilter()
+	}
+	filter.Must = []*qdrant.Condition{documentIDCondition(documentID)}
+
+	count, err := q.client.Count(ctx, &qdrant.CountPoints{
+		CollectionName: q.config.CollectionName,
+		Filter:         filter,
+		Exact:          qdrant.PtrOf(true),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count document chunks in Qdrant: %w", err)
+	}
+	return count, nil
+}
+
+// WithCollection returns a store that shares this store's client and
+// embedding service but operates on a different collection
+func (q *QdrantStore) WithCollection(collectionName string) *QdrantStore {
+	config := q.config
+	config.CollectionName = collectionName
+
+	return &QdrantStore{
+		config:           config,
+		client:           q.client,
+		embeddingService: q.embeddingService,
+	}
+}
+
 // GetConfig returns the vector store configuration
 func (q *QdrantStore) GetConfig() types.VectorStoreConfig {
 	return q.config
@@ -75,36 +257,65 @@ func (q *QdrantStore) StoreChunks(ctx context.Context, chunks []types.DocumentCh
 		return nil
 	}
 
-	// Extract texts for batch embedding generation
-	texts := make([]string, len(chunks))
-	for i, chunk := range chunks {
-		texts[i] = chunk.Content
-	}
-
 	// Generate embeddings for all chunks
-	embeddings, err := q.embeddingService.GenerateEmbeddings(ctx, texts)
+	vectors, err := q.embedChunks(ctx, chunks)
 	if err != nil {
-		return fmt.Errorf("failed to generate embeddings: %w", err)
+		return err
 	}
 
 	// Prepare points for Qdrant
 	points := make([]*qdrant.PointStruct, len(chunks))
 	for i, chunk := range chunks {
-		// Convert embedding to float32
-		vector := make([]float32, len(embeddings[i]))
-		for j, v := range embeddings[i] {
-			vector[j] = float32(v)
+		// Compress content when configured
+		content := chunk.Content
+		if q.config.CompressContent {
+			content, err = compressContent(chunk.Content)
+			if err != nil {
+				return fmt.Errorf("failed to compress content for chunk %d: %w", chunk.ID, err)
+			}
 		}
 
 		// Prepare payload (metadata)
 		payload := map[string]*qdrant.Value{
 			"document_id":  qdrant.NewValueString(chunk.DocumentID),
-			"content":      qdrant.NewValueString(chunk.Content),
+			"content":      qdrant.NewValueString(content),
 			"chunk_index":  qdrant.NewValueInt(int64(chunk.ChunkIndex)),
+			"total_chunks": qdrant.NewValueInt(int64(chunk.TotalChunks)),
 			"created_at":   qdrant.NewValueString(chunk.CreatedAt.Format(time.RFC3339)),
 			"updated_at":   qdrant.NewValueString(chunk.UpdatedAt.Format(time.RFC3339)),
 		}
 
+		// Record how the document was chunked for reconstruction
+		if chunk.ChunkStrategy != "" {
+			payload["chunk_strategy"] = qdrant.NewValueString(chunk.ChunkStrategy)
+		}
+		if chunk.ChunkOverlap > 0 {
+			payload["chunk_overlap"] = qdrant.NewValueInt(int64(chunk.ChunkOverlap))
+		}
+
+		// Flag compressed content so readers know to decompress it
+		if q.config.CompressContent {
+			payload["content_compressed"] = qdrant.NewValueBool(true)
+		}
+
+		// Keep the sentence window for returning in search results, compressed like the content
+		if chunk.Window != "" {
+			window := chunk.Window
+			if q.config.CompressContent {
+				window, err = compressContent(chunk.Window)
+				if err != nil {
+					return fmt.Errorf("failed to compress window for chunk %d: %w", chunk.ID, err)
+				}
+			}
+			payload["window"] = qdrant.NewValueString(window)
+		}
+
+		// Add source offsets when they were recorded
+		if chunk.EndOffset > 0 {
+			payload["start_offset"] = qdrant.NewValueInt(int64(chunk.StartOffset))
+			payload["end_offset"] = qdrant.NewValueInt(int64(chunk.EndOffset))
+		}
+
 		// Add metadata fields
 		if chunk.Metadata.Title != "" {
 			payload["title"] = qdrant.NewValueString(chunk.Metadata.Title)
@@ -121,6 +332,12 @@ func (q *QdrantStore) StoreChunks(ctx context.Context, chunks []types.DocumentCh
 		if chunk.Metadata.ContentType != "" {
 			payload["content_type"] = qdrant.NewValueString(chunk.Metadata.ContentType)
 		}
+		if chunk.Metadata.ParentID != "" {
+			payload["parent_id"] = qdrant.NewValueString(chunk.Metadata.ParentID)
+		}
+		if chunk.Metadata.Section != "" {
+			payload["section"] = qdrant.NewValueString(chunk.Metadata.Section)
+		}
 
 		// Add tags as a list
 		if len(chunk.Metadata.Tags) > 0 {
@@ -132,6 +349,16 @@ func (q *QdrantStore) StoreChunks(ctx context.Context, chunks []types.DocumentCh
 			payload["tags"] = qdrant.NewValueList(listValue)
 		}
 
+		// Add the heading path as a list, outermost first
+		if len(chunk.Metadata.Path) > 0 {
+			headings := make([]interface{}, len(chunk.Metadata.Path))
+			for j, heading := range chunk.Metadata.Path {
+				headings[j] = heading
+			}
+			listValue, _ := qdrant.NewListValue(headings)
+			payload["path"] = qdrant.NewValueList(listValue)
+		}
+
 		// Add custom metadata
 		for key, value := range chunk.Metadata.Custom {
 			payload["custom_"+key] = qdrant.NewValueString(value)
@@ -139,7 +366,7 @@ func (q *QdrantStore) StoreChunks(ctx context.Context, chunks []types.DocumentCh
 
 		points[i] = &qdrant.PointStruct{
 			Id:      qdrant.NewIDNum(chunk.ID),
-			Vectors: qdrant.NewVectors(vector...),
+			Vectors: vectors[i],
 			Payload: payload,
 		}
 	}
@@ -156,50 +383,376 @@ func (q *QdrantStore) StoreChunks(ctx context.Context, chunks []types.DocumentCh
 	return nil
 }
 
-// SearchSimilar searches for similar chunks using vector similarity
-func (q *QdrantStore) SearchSimilar(ctx context.Context, query string, limit int) ([]types.DocumentChunk, error) {
+// embedChunks generates the vectors for each chunk: a single vector of the
+// content by default, or one named vector per configured field. Chunks that
+// carry a pre-computed embedding use it as is.
+func (q *QdrantStore) embedChunks(ctx context.Context, chunks []types.DocumentChunk) ([]*qdrant.Vectors, error) {
+	vectors := make([]*qdrant.Vectors, len(chunks))
+
+	if len(q.config.VectorFields) == 0 {
+		embeddings, err := embedContent(ctx, q.embeddingService, chunks)
+		if err != nil {
+			return nil, err
+		}
+		for i, embedding := range embeddings {
+			vectors[i] = qdrant.NewVectors(embedding...)
+		}
+		return vectors, nil
+	}
+
+	// Named vectors embed several fields, so a single pre-computed vector can't fill them
+	for _, chunk := range chunks {
+		if chunk.Embedding != nil {
+			return nil, fmt.Errorf("pre-computed embeddings are not supported with named vectors (chunk %d)", chunk.ID)
+		}
+	}
+
+	named := make([]map[string]*qdrant.Vector, len(chunks))
+	for i := range named {
+		named[i] = make(map[string]*qdrant.Vector, len(q.config.VectorFields))
+	}
+
+	for _, field := range q.config.VectorFields {
+		embeddings, err := q.embeddingService.GenerateEmbeddings(embedding.WithInputType(ctx, embedding.InputTypeDocument), chunkFieldTexts(chunks, field))
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate %s embeddings: %w", field, err)
+		}
+		if err := checkEmbeddings(chunks, embeddings); err != nil {
+			return nil, err
+		}
+		for i := range chunks {
+			named[i][field] = qdrant.NewVector(toFloat32(embeddings[i])...)
+		}
+	}
+
+	for i := range chunks {
+		vectors[i] = qdrant.NewVectorsMap(named[i])
+	}
+	return vectors, nil
+}
+
+// embedContent returns one content vector per chunk. Pre-computed embeddings
+// are used after checking their dimensions; only the other chunks are embedded.
+func embedContent(ctx context.Context, embeddingService embedding.Service, chunks []types.DocumentChunk) ([][]float32, error) {
+	vectors := make([][]float32, len(chunks))
+	var missing []types.DocumentChunk
+	var missingIndexes []int
+	for i, chunk := range chunks {
+		if chunk.Embedding == nil {
+			missing = append(missing, chunk)
+			missingIndexes = append(missingIndexes, i)
+			continue
+		}
+		if dims := embeddingService.GetDimensions(); len(chunk.Embedding) != dims {
+			return nil, fmt.Errorf("%w: chunk %d has %d dimensions, expected %d", ErrDimensionMismatch, chunk.ID, len(chunk.Embedding), dims)
+		}
+		vectors[i] = toFloat32(chunk.Embedding)
+	}
+	if len(missing) == 0 {
+		return vectors, nil
+	}
+
+	embeddings, err := embeddingService.GenerateEmbeddings(embedding.WithInputType(ctx, embedding.InputTypeDocument), chunkFieldTexts(missing, "body"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate embeddings: %w", err)
+	}
+	if err := checkEmbeddings(missing, embeddings); err != nil {
+		return nil, err
+	}
+	for j, i := range missingIndexes {
+		vectors[i] = toFloat32(embeddings[j])
+	}
+	return vectors, nil
+}
+
+// checkEmbeddings makes sure there is one embedding per chunk, since vectors
+// are matched to chunks by position. Embedding services leave empty texts
+// without an embedding, so a chunk without content is rejected here rather
+// than stored with an empty vector.
+func checkEmbeddings(chunks []types.DocumentChunk, embeddings [][]float64) error {
+	if len(embeddings) != len(chunks) {
+		return fmt.Errorf("embedding count mismatch: expected %d, got %d", len(chunks), len(embeddings))
+	}
+	for i, embedding := range embeddings {
+		if len(embedding) == 0 {
+			return fmt.Errorf("chunk %d has no content to embed", chunks[i].ID)
+		}
+	}
+	return nil
+}
+
+// chunkFieldTexts extracts the text of a field from each chunk. Chunks without
+// the field fall back to their content so every point gets every vector.
+func chunkFieldTexts(chunks []types.DocumentChunk, field string) []string {
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		var text string
+		switch field {
+		case "body", "content":
+			text = chunk.Content
+		case "title":
+			text = chunk.Metadata.Title
+		default:
+			text = chunk.Metadata.Custom[field]
+		}
+		if text == "" {
+			text = chunk.Content
+		}
+		texts[i] = text
+	}
+	return texts
+}
+
+// resolveVectorName checks a requested vector name against the configured
+// fields; an empty name selects the first field when named vectors are enabled
+func (q *QdrantStore) resolveVectorName(vectorName string) (string, error) {
+	if len(q.config.VectorFields) == 0 {
+		if vectorName != "" {
+			return "", fmt.Errorf("named vectors are not enabled, cannot search %q", vectorName)
+		}
+		return "", nil
+	}
+
+	if vectorName == "" {
+		return q.config.VectorFields[0], nil
+	}
+	for _, field := range q.config.VectorFields {
+		if field == vectorName {
+			return vectorName, nil
+		}
+	}
+	return "", fmt.Errorf("unknown vector name %q", vectorName)
+}
+
+func toFloat32(values []float64) []float32 {
+	vector := make([]float32, len(values))
+	for i, v := range values {
+		vector[i] = float32(v)
+	}
+	return vector
+}
+
+// SearchSimilar searches for similar chunks using vector similarity. vectorName
+// selects a named vector; empty uses the default one. Only chunks whose
+// metadata matches every filter are returned.
+func (q *QdrantStore) SearchSimilar(ctx context.Context, query string, limit int, vectorName string, filters map[string]string) ([]types.DocumentChunk, error) {
+	chunks, _, err := q.search(ctx, query, limit, vectorName, filters)
+	return chunks, err
+}
+
+// SearchWithDiagnostics searches like SearchSimilar and also reports Qdrant's
+// timing, hardware usage and whether the HNSW index covered the collection
+func (q *QdrantStore) SearchWithDiagnostics(ctx context.Context, query string, limit int, vectorName string, filters map[string]string) ([]types.DocumentChunk, *types.SearchDiagnostics, error) {
+	chunks, resp, searchErr := q.search(ctx, query, limit, vectorName, filters)
+	if searchErr != nil && !errors.Is(searchErr, ErrPartialResults) {
+		return nil, nil, searchErr
+	}
+
+	// Index coverage is best effort; the search itself already succeeded
+	info, err := q.client.GetCollectionInfo(ctx, q.config.CollectionName)
+	if err != nil {
+		log.Printf("Failed to get collection info for search diagnostics: %v", err)
+	}
+
+	return chunks, searchDiagnostics(resp, info, len(q.config.VectorFields)), searchErr
+}
+
+// search runs a similarity query and returns the chunks with the raw Qdrant
+// response. A timed-out search returns ErrPartialResults with whatever was
+// found when partial results are enabled.
+func (q *QdrantStore) search(ctx context.Context, query string, limit int, vectorName string, filters map[string]string) ([]types.DocumentChunk, *qdrant.QueryResponse, error) {
 	if query == "" {
-		return nil, fmt.Errorf("query cannot be empty")
+		return nil, nil, fmt.Errorf("query cannot be empty")
 	}
 
 	if limit <= 0 {
 		limit = 10
 	}
 
-	// Generate embedding for the query
-	queryEmbedding, err := q.embeddingService.GenerateEmbedding(ctx, query)
+	using, err := q.resolveVectorName(vectorName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conditions, err := parseFilters(filters)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
+		return nil, nil, err
+	}
+	filter, remaining := searchFilter(conditions)
+
+	// Fetch extra candidates for the filters applied here, since some of them
+	// won't match, and page through more until the limit is filled
+	fetch, pages := limit, 1
+	if len(remaining) > 0 {
+		overfetch := q.config.FilterOverfetch
+		if overfetch <= 0 {
+			overfetch = defaultFilterOverfetch
+		}
+		fetch, pages = limit*overfetch, maxFilterPages
 	}
 
-	// Convert to float32
-	queryVector := make([]float32, len(queryEmbedding))
-	for i, v := range queryEmbedding {
-		queryVector[i] = float32(v)
+	// Generate embedding for the query
+	queryEmbedding, err := q.embeddingService.GenerateEmbedding(embedding.WithInputType(ctx, embedding.InputTypeQuery), query)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate query embedding: %w", err)
 	}
 
 	// Search in Qdrant using Query
-	searchResult, err := q.client.Query(ctx, &qdrant.QueryPoints{
+	queryPoints := &qdrant.QueryPoints{
 		CollectionName: q.config.CollectionName,
-		Query:          qdrant.NewQuery(queryVector...),
-		Limit:          qdrant.PtrOf(uint64(limit)),
+		Query:          qdrant.NewQuery(toFloat32(queryEmbedding)...),
+		Filter:         filter,
+		Limit:          qdrant.PtrOf(uint64(fetch)),
 		WithPayload:    qdrant.NewWithPayload(true),
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to search in Qdrant: %w", err)
+	}
+	if using != "" {
+		queryPoints.Using = qdrant.PtrOf(using)
+	}
+	if q.config.SearchTimeoutSeconds > 0 {
+		queryPoints.Timeout = qdrant.PtrOf(uint64(q.config.SearchTimeoutSeconds))
+	}
+
+	// Use the points client directly to keep the timing and usage in the
+	// response, merged across pages
+	resp := &qdrant.QueryResponse{}
+	timedOut := false
+	fetchPage := func(offset uint64) ([]*qdrant.ScoredPoint, bool, error) {
+		if offset > 0 {
+			queryPoints.Offset = qdrant.PtrOf(offset)
+		}
+		page, err := q.client.GetPointsClient().Query(ctx, queryPoints)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to search in Qdrant: %w", err)
+		}
+		mergeQueryResponse(resp, page)
+		// Qdrant stops a search at the timeout with the results it had, so a
+		// search that took the whole timeout may be missing matches
+		timedOut = q.config.PartialResultsOnTimeout && q.config.SearchTimeoutSeconds > 0 &&
+			page.GetTime() >= float64(q.config.SearchTimeoutSeconds)
+		return page.GetResult(), timedOut, nil
 	}
 
 	// Convert results to DocumentChunk
-	chunks := make([]types.DocumentChunk, len(searchResult))
-	for i, point := range searchResult {
+	chunks := make([]types.DocumentChunk, 0, limit)
+	accept := func(point *qdrant.ScoredPoint) (bool, error) {
 		chunk, err := q.pointToDocumentChunk(point)
 		if err != nil {
-			return nil, fmt.Errorf("failed to convert point to document chunk: %w", err)
+			return false, fmt.Errorf("failed to convert point to document chunk: %w", err)
+		}
+		if !matchesConditions(*chunk, remaining) {
+			return false, nil
+		}
+		if q.config.DistanceMetric == DistanceEuclid {
+			// Qdrant scores Euclidean matches by distance; report a similarity so higher is better
+			chunk.VectorScore = 1 / (1 + chunk.VectorScore)
+		}
+		chunks = append(chunks, *chunk)
+		return len(chunks) == limit, nil
+	}
+
+	if err := fillFromPages(fetch, pages, fetchPage, accept); err != nil {
+		if q.config.PartialResultsOnTimeout && isSearchTimeout(ctx, err) {
+			return chunks, resp, ErrPartialResults
+		}
+		return nil, nil, err
+	}
+	if timedOut {
+		return chunks, resp, ErrPartialResults
+	}
+
+	return chunks, resp, nil
+}
+
+// fillFromPages passes candidates from successive pages to accept until it
+// reports the results full, a page comes back short or marked last, or
+// maxPages pages have been fetched. Filters Qdrant can't evaluate may reject
+// most of a page, so a single page often wouldn't fill the limit.
+func fillFromPages(pageSize, maxPages int, fetch func(offset uint64) (points []*qdrant.ScoredPoint, last bool, err error), accept func(*qdrant.ScoredPoint) (full bool, err error)) error {
+	for page := 0; page < maxPages; page++ {
+		points, last, err := fetch(uint64(page * pageSize))
+		if err != nil {
+			return err
+		}
+		for _, point := range points {
+			full, err := accept(point)
+			if err != nil {
+				return err
+			}
+			if full {
+				return nil
+			}
+		}
+		if last || len(points) < pageSize {
+			return nil
 		}
-		chunks[i] = *chunk
 	}
+	return nil
+}
 
-	return chunks, nil
+// mergeQueryResponse adds a page's results, time and hardware usage to resp
+func mergeQueryResponse(resp, page *qdrant.QueryResponse) {
+	resp.Result = append(resp.Result, page.GetResult()...)
+	resp.Time += page.GetTime()
+	hardware := page.GetUsage().GetHardware()
+	if hardware == nil {
+		return
+	}
+	if resp.Usage == nil {
+		resp.Usage = &qdrant.Usage{Hardware: &qdrant.HardwareUsage{}}
+	}
+	total := resp.Usage.Hardware
+	total.Cpu += hardware.GetCpu()
+	total.PayloadIoRead += hardware.GetPayloadIoRead()
+	total.PayloadIoWrite += hardware.GetPayloadIoWrite()
+	total.PayloadIndexIoRead += hardware.GetPayloadIndexIoRead()
+	total.PayloadIndexIoWrite += hardware.GetPayloadIndexIoWrite()
+	total.VectorIoRead += hardware.GetVectorIoRead()
+	total.VectorIoWrite += hardware.GetVectorIoWrite()
+}
+
+// isSearchTimeout reports whether Qdrant itself stopped a search at its
+// timeout. Connection failures and the caller's own deadline aren't timeouts:
+// they fail the search rather than returning partial results.
+func isSearchTimeout(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	s, ok := status.FromError(err)
+	return ok && s.Code() == codes.DeadlineExceeded
+}
+
+// searchDiagnostics summarizes a query response. Qdrant scans segments that
+// aren't HNSW-indexed yet (e.g. below the indexing threshold), so when fewer
+// vectors are indexed than stored the search mode is reported as "mixed".
+func searchDiagnostics(resp *qdrant.QueryResponse, info *qdrant.CollectionInfo, vectorFields int) *types.SearchDiagnostics {
+	diagnostics := &types.SearchDiagnostics{
+		QdrantTimeMs: resp.GetTime() * 1000,
+		ResultCount:  len(resp.GetResult()),
+	}
+
+	if hardware := resp.GetUsage().GetHardware(); hardware != nil {
+		diagnostics.CPU = hardware.GetCpu()
+		diagnostics.VectorIORead = hardware.GetVectorIoRead()
+		diagnostics.PayloadIORead = hardware.GetPayloadIoRead() + hardware.GetPayloadIndexIoRead()
+	}
+
+	if info != nil {
+		diagnostics.PointsCount = info.GetPointsCount()
+		diagnostics.IndexedVectorsCount = info.GetIndexedVectorsCount()
+
+		// Each point stores one vector per named field
+		vectors := diagnostics.PointsCount * uint64(max(vectorFields, 1))
+		switch {
+		case diagnostics.IndexedVectorsCount == 0 && vectors > 0:
+			diagnostics.SearchMode = "full_scan"
+		case diagnostics.IndexedVectorsCount < vectors:
+			diagnostics.SearchMode = "mixed"
+		default:
+			diagnostics.SearchMode = "hnsw"
+		}
+	}
+
+	return diagnostics
 }
 
 // pointToDocumentChunk converts a Qdrant point to a DocumentChunk
@@ -228,6 +781,27 @@ func (q *QdrantStore) pointToDocumentChunk(point *qdrant.ScoredPoint) (*types.Do
 	documentID := q.getStringFromPayload(payload, "document_id")
 	content := q.getStringFromPayload(payload, "content")
 	chunkIndex := int(q.getIntFromPayload(payload, "chunk_index"))
+	totalChunks := int(q.getIntFromPayload(payload, "total_chunks"))
+	startOffset := int(q.getIntFromPayload(payload, "start_offset"))
+	endOffset := int(q.getIntFromPayload(payload, "end_offset"))
+	chunkStrategy := q.getStringFromPayload(payload, "chunk_strategy")
+	chunkOverlap := int(q.getIntFromPayload(payload, "chunk_overlap"))
+	window := q.getStringFromPayload(payload, "window")
+
+	// Decompress content stored in compressed form
+	if payload["content_compressed"].GetBoolValue() {
+		decompressed, err := decompressContent(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress content for chunk %d: %w", id, err)
+		}
+		content = decompressed
+
+		if window != "" {
+			if window, err = decompressContent(window); err != nil {
+				return nil, fmt.Errorf("failed to decompress window for chunk %d: %w", id, err)
+			}
+		}
+	}
 
 	// Parse timestamps
 	createdAt, _ := time.Parse(time.RFC3339, q.getStringFromPayload(payload, "created_at"))
@@ -241,6 +815,8 @@ func (q *QdrantStore) pointToDocumentChunk(point *qdrant.ScoredPoint) (*types.Do
 		Language:    q.getStringFromPayload(payload, "language"),
 		ContentType: q.getStringFromPayload(payload, "content_type"),
 		Custom:      make(map[string]string),
+		ParentID:    q.getStringFromPayload(payload, "parent_id"),
+		Section:     q.getStringFromPayload(payload, "section"),
 	}
 
 	// Extract tags
@@ -254,6 +830,13 @@ func (q *QdrantStore) pointToDocumentChunk(point *qdrant.ScoredPoint) (*types.Do
 		metadata.Tags = tags
 	}
 
+	// Extract the heading path, keeping its order
+	if pathValue, exists := payload["path"]; exists && pathValue.GetListValue() != nil {
+		for _, headingValue := range pathValue.GetListValue().Values {
+			metadata.Path = append(metadata.Path, headingValue.GetStringValue())
+		}
+	}
+
 	// Extract custom metadata
 	for key, value := range payload {
 		if len(key) > 7 && key[:7] == "custom_" {
@@ -263,16 +846,56 @@ func (q *QdrantStore) pointToDocumentChunk(point *qdrant.ScoredPoint) (*types.Do
 	}
 
 	return &types.DocumentChunk{
-		ID:         id,
-		DocumentID: documentID,
-		Content:    content,
-		ChunkIndex: chunkIndex,
-		Metadata:   metadata,
-		CreatedAt:  createdAt,
-		UpdatedAt:  updatedAt,
+		ID:            id,
+		DocumentID:    documentID,
+		Content:       content,
+		ChunkIndex:    chunkIndex,
+		TotalChunks:   totalChunks,
+		StartOffset:   startOffset,
+		EndOffset:     endOffset,
+		ChunkStrategy: chunkStrategy,
+		ChunkOverlap:  chunkOverlap,
+		Window:        window,
+		VectorScore:   float64(point.Score),
+		Metadata:      metadata,
+		CreatedAt:     createdAt,
+		UpdatedAt:     updatedAt,
 	}, nil
 }
 
+// compressContent gzips content and encodes it as base64 for storage in a string payload field
+func compressContent(content string) (string, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write([]byte(content)); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// decompressContent reverses compressContent
+func decompressContent(encoded string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("invalid base64 content: %w", err)
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("invalid gzip content: %w", err)
+	}
+	defer reader.Close()
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to read gzip content: %w", err)
+	}
+	return string(content), nil
+}
+
 // Helper functions for payload extraction
 func (q *QdrantStore) getStringFromPayload(payload map[string]*qdrant.Value, key string) string {
 	if value, exists := payload[key]; exists {
@@ -294,50 +917,170 @@ func (q *QdrantStore) GetChunksByDocumentID(ctx context.Context, documentID stri
 		return nil, fmt.Errorf("document ID cannot be empty")
 	}
 
-	// Create filter for document ID
-	filter := &qdrant.Filter{
-		Must: []*qdrant.Condition{
-			{
-				ConditionOneOf: &qdrant.Condition_Field{
-					Field: &qdrant.FieldCondition{
-						Key: "document_id",
-						Match: &qdrant.Match{
-							MatchValue: &qdrant.Match_Text{
-								Text: documentID,
-							},
-						},
-					},
-				},
-			},
-		},
-	}
-
-	// Scroll through all points with the filter
-	scrollResult, err := q.client.Scroll(ctx, &qdrant.ScrollPoints{
-		CollectionName: q.config.CollectionName,
-		Filter:         filter,
-		WithPayload:    qdrant.NewWithPayload(true),
-		Limit:          qdrant.PtrOf(uint32(1000)), // Adjust as needed
+	var chunks []types.DocumentChunk
+	err := q.StreamChunksByDocumentID(ctx, documentID, func(chunk types.DocumentChunk) error {
+		chunks = append(chunks, chunk)
+		return nil
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to scroll points in Qdrant: %w", err)
+		return nil, err
 	}
 
-	// Convert results to DocumentChunk
-	chunks := make([]types.DocumentChunk, len(scrollResult))
-	for i, point := range scrollResult {
-		chunk, err := q.pointToDocumentChunk(&qdrant.ScoredPoint{
-			Id:      point.Id,
-			Payload: point.Payload,
-			Vectors: point.Vectors,
+	return chunks, nil
+}
+
+// streamWindowSize is how many chunk indexes are fetched per page when streaming
+const streamWindowSize = 256
+
+// StreamChunksByDocumentID calls fn for each chunk of a document in chunk_index
+// order, holding at most one page of chunks in memory
+func (q *QdrantStore) StreamChunksByDocumentID(ctx context.Context, documentID string, fn func(types.DocumentChunk) error) error {
+	if documentID == "" {
+		return fmt.Errorf("document ID cannot be empty")
+	}
+
+	fetch := func(lo, hi int) ([]types.DocumentChunk, error) {
+		var chunks []types.DocumentChunk
+		scroll := func(offset *qdrant.PointId) ([]*qdrant.RetrievedPoint, *qdrant.PointId, error) {
+			return q.client.ScrollAndOffset(ctx, &qdrant.ScrollPoints{
+				CollectionName: q.config.CollectionName,
+				Filter: q.documentFilter(documentID, qdrant.NewRange("chunk_index", &qdrant.Range{
+					Gte: qdrant.PtrOf(float64(lo)),
+					Lt:  qdrant.PtrOf(float64(hi)),
+				})),
+				Offset:      offset,
+				Limit:       qdrant.PtrOf(uint32(hi - lo)),
+				WithPayload: qdrant.NewWithPayload(true),
+			})
+		}
+		err := scrollAll(scroll, func(point *qdrant.RetrievedPoint) error {
+			chunk, err := q.pointToDocumentChunk(&qdrant.ScoredPoint{
+				Id:      point.Id,
+				Payload: point.Payload,
+				Vectors: point.Vectors,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to convert point to document chunk: %w", err)
+			}
+			chunks = append(chunks, *chunk)
+			return nil
 		})
 		if err != nil {
-			return nil, fmt.Errorf("failed to convert point to document chunk: %w", err)
+			return nil, err
 		}
-		chunks[i] = *chunk
+		return chunks, nil
 	}
 
-	return chunks, nil
+	remaining := func(from int) (bool, error) {
+		count, err := q.client.Count(ctx, &qdrant.CountPoints{
+			CollectionName: q.config.CollectionName,
+			Filter: q.documentFilter(documentID, qdrant.NewRange("chunk_index", &qdrant.Range{
+				Gte: qdrant.PtrOf(float64(from)),
+			})),
+			Exact: qdrant.PtrOf(true),
+		})
+		if err != nil {
+			return false, fmt.Errorf("failed to count points in Qdrant: %w", err)
+		}
+		return count > 0, nil
+	}
+
+	return streamInWindows(streamWindowSize, fetch, remaining, fn)
+}
+
+// scrollAll calls scroll with each next page offset, starting without one,
+// and fn for every point returned, until Qdrant reports no more pages
+func scrollAll(scroll func(offset *qdrant.PointId) ([]*qdrant.RetrievedPoint, *qdrant.PointId, error), fn func(*qdrant.RetrievedPoint) error) error {
+	var offset *qdrant.PointId
+	for {
+		points, next, err := scroll(offset)
+		if err != nil {
+			return fmt.Errorf("failed to scroll points in Qdrant: %w", err)
+		}
+
+		for _, point := range points {
+			if err := fn(point); err != nil {
+				return err
+			}
+		}
+
+		if next == nil {
+			return nil
+		}
+		offset = next
+	}
+}
+
+// streamInWindows pages through chunk indexes [lo, lo+size) in order, sorting
+// each page before passing its chunks to fn. An empty page may just be a gap
+// left by deleted chunks, so it only ends the stream when nothing follows it.
+func streamInWindows(size int, fetch func(lo, hi int) ([]types.DocumentChunk, error), remaining func(from int) (bool, error), fn func(types.DocumentChunk) error) error {
+	for lo := 0; ; lo += size {
+		chunks, err := fetch(lo, lo+size)
+		if err != nil {
+			return err
+		}
+
+		if len(chunks) == 0 {
+			more, err := remaining(lo + size)
+			if err != nil {
+				return err
+			}
+			if !more {
+				return nil
+			}
+			continue
+		}
+
+		sort.Slice(chunks, func(i, j int) bool { return chunks[i].ChunkIndex < chunks[j].ChunkIndex })
+		for _, chunk := range chunks {
+			if err := fn(chunk); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// documentIDCondition matches the chunks of exactly documentID. It's a
+// keyword match: a text match would also pick up "doc-10" for "doc-1".
+func documentIDCondition(documentID string) *qdrant.Condition {
+	return qdrant.NewMatch("document_id", documentID)
+}
+
+// documentFilter matches the active chunks of a document, plus any extra conditions
+func (q *QdrantStore) documentFilter(documentID string, conditions ...*qdrant.Condition) *qdrant.Filter {
+	return &qdrant.Filter{
+		Must:    append([]*qdrant.Condition{documentIDCondition(documentID)}, conditions...),
+		MustNot: activeFilter().MustNot,
+	}
+}
+
+// ListDocumentIDs returns the IDs of all documents that are not soft-deleted
+func (q *QdrantStore) ListDocumentIDs(ctx context.Context) ([]string, error) {
+	seen := make(map[string]bool)
+	var documentIDs []string
+
+	scroll := func(offset *qdrant.PointId) ([]*qdrant.RetrievedPoint, *qdrant.PointId, error) {
+		return q.client.ScrollAndOffset(ctx, &qdrant.ScrollPoints{
+			CollectionName: q.config.CollectionName,
+			Filter:         activeFilter(),
+			Offset:         offset,
+			Limit:          qdrant.PtrOf(uint32(1000)),
+			WithPayload:    qdrant.NewWithPayloadInclude("document_id"),
+		})
+	}
+	err := scrollAll(scroll, func(point *qdrant.RetrievedPoint) error {
+		documentID := q.getStringFromPayload(point.Payload, "document_id")
+		if documentID != "" && !seen[documentID] {
+			seen[documentID] = true
+			documentIDs = append(documentIDs, documentID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return documentIDs, nil
 }
 
 // GetChunkByID retrieves a specific chunk by its ID
@@ -356,7 +1099,7 @@ func (q *QdrantStore) GetChunkByID(ctx context.Context, chunkID uint64) (*types.
 		return nil, fmt.Errorf("failed to get point from Qdrant: %w", err)
 	}
 
-	if len(getResult) == 0 {
+	if len(getResult) == 0 || isSoftDeleted(getResult[0].Payload) {
 		return nil, fmt.Errorf("chunk not found: %d", chunkID)
 	}
 
@@ -374,28 +1117,98 @@ func (q *QdrantStore) GetChunkByID(ctx context.Context, chunkID uint64) (*types.
 	return chunk, nil
 }
 
-// DeleteDocument removes all chunks for a specific document
+// DeleteDocument removes all chunks for a specific document. In soft-delete
+// mode the chunks are only flagged as deleted and can be restored.
 func (q *QdrantStore) DeleteDocument(ctx context.Context, documentID string) error {
+	if q.config.SoftDelete {
+		return q.setDeleted(ctx, documentID, true)
+	}
+	return q.PurgeDocument(ctx, documentID)
+}
+
+// RestoreDocument clears the soft-delete flag on a document's chunks
+func (q *QdrantStore) RestoreDocument(ctx context.Context, documentID string) error {
+	return q.setDeleted(ctx, documentID, false)
+}
+
+// setDeleted sets the soft-delete flag on all chunks of a document
+func (q *QdrantStore) setDeleted(ctx context.Context, documentID string, deleted bool) error {
 	if documentID == "" {
 		return fmt.Errorf("document ID cannot be empty")
 	}
 
-	// Create filter for document ID
+	_, err := q.client.SetPayload(ctx, &qdrant.SetPayloadPoints{
+		CollectionName: q.config.CollectionName,
+		Payload:        map[string]*qdrant.Value{"deleted": qdrant.NewValueBool(deleted)},
+		PointsSelector: qdrant.NewPointsSelectorFilter(&qdrant.Filter{
+			Must: []*qdrant.Condition{documentIDCondition(documentID)},
+		}),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update deleted flag in Qdrant: %w", err)
+	}
+
+	return nil
+}
+
+// activeFilter excludes soft-deleted chunks. Chunks stored before soft delete
+// was enabled have no flag and are kept.
+func activeFilter() *qdrant.Filter {
+	return &qdrant.Filter{
+		MustNot: []*qdrant.Condition{qdrant.NewMatchBool("deleted", true)},
+	}
+}
+
+// searchFilter restricts a search to live chunks whose metadata matches the
+// conditions Qdrant can evaluate. Known metadata fields match their payload
+// key, "tags" and "path" match any tag or heading, and other keys match custom metadata. Qdrant
+// matches keywords exactly and, on fields without a full-text index, text as
+// a substring; it has no case-insensitive or prefix match, so those
+// conditions are returned for the caller to apply to the results.
+func searchFilter(conditions []filterCondition) (*qdrant.Filter, []filterCondition) {
+	filter := activeFilter()
+
+	var remaining []filterCondition
+	for _, condition := range conditions {
+		switch condition.Operator {
+		case FilterEqual:
+			filter.Must = append(filter.Must, qdrant.NewMatch(condition.Field, condition.Value))
+		case FilterContains:
+			filter.Must = append(filter.Must, qdrant.NewMatchText(condition.Field, condition.Value))
+		default:
+			remaining = append(remaining, condition)
+		}
+	}
+	return filter, remaining
+}
+
+// filterPayloadKey maps a filter key to the payload key it is stored under
+func filterPayloadKey(key string) string {
+	switch key {
+	case "document_id", "title", "author", "source", "language", "content_type", "tags", "parent_id", "section", "path":
+		return key
+	}
+	if strings.HasPrefix(key, "custom_") {
+		return key
+	}
+	return "custom_" + key
+}
+
+// isSoftDeleted reports whether a point's payload carries the soft-delete flag
+func isSoftDeleted(payload map[string]*qdrant.Value) bool {
+	return payload["deleted"].GetBoolValue()
+}
+
+// PurgeDocument permanently removes all chunks for a document, whether or not
+// they were soft-deleted
+func (q *QdrantStore) PurgeDocument(ctx context.Context, documentID string) error {
+	if documentID == "" {
+		return fmt.Errorf("document ID cannot be empty")
+	}
+
+	// Match every chunk of the document, soft-deleted or not
 	filter := &qdrant.Filter{
-		Must: []*qdrant.Condition{
-			{
-				ConditionOneOf: &qdrant.Condition_Field{
-					Field: &qdrant.FieldCondition{
-						Key: "document_id",
-						Match: &qdrant.Match{
-							MatchValue: &qdrant.Match_Text{
-								Text: documentID,
-							},
-						},
-					},
-				},
-			},
-		},
+		Must: []*qdrant.Condition{documentIDCondition(documentID)},
 	}
 
 	// Delete points with the filter
@@ -447,17 +1260,25 @@ func (q *QdrantStore) CreateCollection(ctx context.Context, vectorSize int) erro
 		}
 	}
 
+	params := &qdrant.VectorParams{
+		Size:     uint64(vectorSize),
+		Distance: collectionDistance(q.config.DistanceMetric),
+	}
+
+	// One named vector per configured field, or a single unnamed vector
+	vectorsConfig := qdrant.NewVectorsConfig(params)
+	if len(q.config.VectorFields) > 0 {
+		paramsMap := make(map[string]*qdrant.VectorParams, len(q.config.VectorFields))
+		for _, field := range q.config.VectorFields {
+			paramsMap[field] = params
+		}
+		vectorsConfig = qdrant.NewVectorsConfigMap(paramsMap)
+	}
+
 	// Create collection
 	err = q.client.CreateCollection(ctx, &qdrant.CreateCollection{
 		CollectionName: q.config.CollectionName,
-		VectorsConfig: &qdrant.VectorsConfig{
-			Config: &qdrant.VectorsConfig_Params{
-				Params: &qdrant.VectorParams{
-					Size:     uint64(vectorSize),
-					Distance: qdrant.Distance_Cosine,
-				},
-			},
-		},
+		VectorsConfig:  vectorsConfig,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create collection: %w", err)
@@ -466,6 +1287,168 @@ func (q *QdrantStore) CreateCollection(ctx context.Context, vectorSize int) erro
 	return nil
 }
 
+// DropCollection deletes the collection and all its points, if it exists
+func (q *QdrantStore) DropCollection(ctx context.Context) error {
+	exists, err := q.client.CollectionExists(ctx, q.config.CollectionName)
+	if err != nil {
+		return fmt.Errorf("failed to check collection %s: %w", q.config.CollectionName, err)
+	}
+	if !exists {
+		return nil
+	}
+	if err := q.client.DeleteCollection(ctx, q.config.CollectionName); err != nil {
+		return fmt.Errorf("failed to delete collection %s: %w", q.config.CollectionName, err)
+	}
+	return nil
+}
+
+// Policies for a collection whose vector size differs from the embedding dimensions
+const (
+	DimensionPolicyError    = "error"    // refuse to start
+	DimensionPolicyRecreate = "recreate" // drop and recreate the collection (requires confirmation)
+	DimensionPolicyAdapt    = "adapt"    // use a dimension-suffixed collection instead
+)
+
+// ReconcileDimensions compares the live collection's vector size with the
+// embedding dimensions and applies the configured policy. It returns the store
+// to use, which is a different collection under the adapt policy.
+func (q *QdrantStore) ReconcileDimensions(ctx context.Context) (*QdrantStore, error) {
+	exists, err := q.client.CollectionExists(ctx, q.config.CollectionName)
+	if err != nil {
+		// Qdrant may not be up yet; the check is best effort so startup is not blocked
+		log.Printf("Skipping dimension check for collection %s: %v", q.config.CollectionName, err)
+		return q, nil
+	}
+	if !exists {
+		return q, nil
+	}
+
+	info, err := q.client.GetCollectionInfo(ctx, q.config.CollectionName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collection %s: %w", q.config.CollectionName, err)
+	}
+
+	liveSize := vectorSize(info.GetConfig().GetParams().GetVectorsConfig())
+	wantSize := q.embeddingService.GetDimensions()
+	action, err := dimensionAction(q.config, liveSize, wantSize)
+	if err != nil {
+		return nil, err
+	}
+
+	switch action {
+	case DimensionPolicyRecreate:
+		log.Printf("Recreating collection %s: vector size %d does not match embedding dimensions %d", q.config.CollectionName, liveSize, wantSize)
+		if err := q.client.DeleteCollection(ctx, q.config.CollectionName); err != nil {
+			return nil, fmt.Errorf("failed to delete collection %s: %w", q.config.CollectionName, err)
+		}
+		if err := q.CreateCollection(ctx, wantSize); err != nil {
+			return nil, err
+		}
+		return q, nil
+	case DimensionPolicyAdapt:
+		adapted := q.WithCollection(fmt.Sprintf("%s_%d", q.config.CollectionName, wantSize))
+		log.Printf("Collection %s has vector size %d, using %s for embedding dimensions %d", q.config.CollectionName, liveSize, adapted.config.CollectionName, wantSize)
+		if err := adapted.CreateCollection(ctx, wantSize); err != nil {
+			return nil, err
+		}
+		return adapted, nil
+	}
+
+	return q, nil
+}
+
+// CheckDimensions returns an error wrapping ErrDimensionMismatch when the
+// collection exists with a vector size other than the embedding dimensions.
+// Unlike ReconcileDimensions it never changes the collection, so it suits
+// collections opened while serving, such as tenants'.
+func (q *QdrantStore) CheckDimensions(ctx context.Context) error {
+	exists, err := q.client.CollectionExists(ctx, q.config.CollectionName)
+	if err != nil {
+		return fmt.Errorf("failed to check collection %s: %w", q.config.CollectionName, err)
+	}
+	if !exists {
+		return nil
+	}
+
+	info, err := q.client.GetCollectionInfo(ctx, q.config.CollectionName)
+	if err != nil {
+		return fmt.Errorf("failed to get collection %s: %w", q.config.CollectionName, err)
+	}
+	liveSize := vectorSize(info.GetConfig().GetParams().GetVectorsConfig())
+	if liveSize == 0 || liveSize == q.embeddingService.GetDimensions() {
+		return nil
+	}
+	return dimensionMismatch(q.config.CollectionName, liveSize, q.embeddingService.GetDimensions())
+}
+
+// dimensionMismatch describes a collection whose vector size differs from the
+// embedding dimensions, wrapping ErrDimensionMismatch
+func dimensionMismatch(collection string, liveSize, wantSize int) error {
+	if liveSize < 0 {
+		return fmt.Errorf("%w: collection %s has named vectors of different sizes, embedding service produces %d dimensions",
+			ErrDimensionMismatch, collection, wantSize)
+	}
+	return fmt.Errorf("%w: collection %s expects %d-dim vectors, embedding service produces %d",
+		ErrDimensionMismatch, collection, liveSize, wantSize)
+}
+
+// dimensionAction decides how to handle the live vector size of a collection.
+// It returns an empty action when the sizes match.
+func dimensionAction(config types.VectorStoreConfig, liveSize, wantSize int) (string, error) {
+	if liveSize == 0 || liveSize == wantSize {
+		return "", nil
+	}
+
+	switch config.DimensionPolicy {
+	case "", DimensionPolicyError:
+		return "", fmt.Errorf("%w; set EMBEDDING_DIMENSIONS or the embedding model to match, "+
+			"or set QDRANT_DIMENSION_POLICY to recreate or adapt", dimensionMismatch(config.CollectionName, liveSize, wantSize))
+	case DimensionPolicyRecreate:
+		if !config.ConfirmRecreate {
+			return "", fmt.Errorf("%w; set QDRANT_CONFIRM_RECREATE=true to delete and recreate it",
+				dimensionMismatch(config.CollectionName, liveSize, wantSize))
+		}
+		return DimensionPolicyRecreate, nil
+	case DimensionPolicyAdapt:
+		return DimensionPolicyAdapt, nil
+	default:
+		return "", fmt.Errorf("unsupported dimension policy: %s", config.DimensionPolicy)
+	}
+}
+
+// vectorSize returns the vector size of a collection's vectors config. Named
+// vectors must all share one size; a mismatch between them returns -1.
+func vectorSize(config *qdrant.VectorsConfig) int {
+	if params := config.GetParams(); params != nil {
+		return int(params.GetSize())
+	}
+
+	size := 0
+	for _, params := range config.GetParamsMap().GetMap() {
+		if size != 0 && int(params.GetSize()) != size {
+			return -1
+		}
+		size = int(params.GetSize())
+	}
+	return size
+}
+
+// vectorDistance returns the lowercase distance metric of a collection's
+// vectors config, or "" when it has none
+func vectorDistance(config *qdrant.VectorsConfig) string {
+	params := config.GetParams()
+	if params == nil {
+		for _, named := range config.GetParamsMap().GetMap() {
+			params = named
+			break
+		}
+	}
+	if params == nil {
+		return ""
+	}
+	return strings.ToLower(params.GetDistance().String())
+}
+
 // HealthCheck checks if Qdrant is accessible
 func (q *QdrantStore) HealthCheck(ctx context.Context) error {
 	// Try to list collections as a health check
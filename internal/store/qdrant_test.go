@@ -2,12 +2,19 @@ package store
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"strings"
 	"testing"
 
 	"go-rag/internal/embedding"
 	"go-rag/internal/types"
 
 	"github.com/qdrant/go-client/qdrant"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // MockEmbeddingService for testing
@@ -209,3 +216,587 @@ func TestEmbeddingGeneration(t *testing.T) {
 		}
 	}
 }
+
+func TestCompressedContentRoundTrip(t *testing.T) {
+	store := &QdrantStore{config: types.VectorStoreConfig{CompressContent: true}}
+	original := "Große Datenmengen lassen sich gut komprimieren. " + strings.Repeat("repeated text ", 50)
+
+	compressed, err := compressContent(original)
+	if err != nil {
+		t.Fatalf("compressContent failed: %v", err)
+	}
+	if len(compressed) >= len(original) {
+		t.Errorf("expected compressed content to be smaller, got %d >= %d bytes", len(compressed), len(original))
+	}
+
+	point := &qdrant.ScoredPoint{
+		Id: qdrant.NewIDNum(7),
+		Payload: map[string]*qdrant.Value{
+			"document_id":        qdrant.NewValueString("doc-1"),
+			"content":            qdrant.NewValueString(compressed),
+			"content_compressed": qdrant.NewValueBool(true),
+		},
+	}
+
+	chunk, err := store.pointToDocumentChunk(point)
+	if err != nil {
+		t.Fatalf("pointToDocumentChunk failed: %v", err)
+	}
+	if chunk.Content != original {
+		t.Errorf("content mismatch after round trip: got %q", chunk.Content)
+	}
+
+	// Uncompressed payloads written before compression was enabled stay readable
+	point.Payload["content"] = qdrant.NewValueString("plain text")
+	delete(point.Payload, "content_compressed")
+	chunk, err = store.pointToDocumentChunk(point)
+	if err != nil {
+		t.Fatalf("pointToDocumentChunk failed: %v", err)
+	}
+	if chunk.Content != "plain text" {
+		t.Errorf("expected plain content, got %q", chunk.Content)
+	}
+}
+
+// wordEmbeddingService embeds text as a bag of hashed words, so texts sharing
+// words get similar vectors
+type wordEmbeddingService struct {
+	MockEmbeddingService
+}
+
+func (w *wordEmbeddingService) GenerateEmbedding(ctx context.Context, text string) ([]float64, error) {
+	vector := make([]float64, w.dimensions)
+	for _, word := range strings.Fields(strings.ToLower(text)) {
+		hash := fnv.New32a()
+		hash.Write([]byte(word))
+		vector[hash.Sum32()%uint32(w.dimensions)]++
+	}
+	return vector, nil
+}
+
+func (w *wordEmbeddingService) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float64, error) {
+	embeddings := make([][]float64, len(texts))
+	for i, text := range texts {
+		embeddings[i], _ = w.GenerateEmbedding(ctx, text)
+	}
+	return embeddings, nil
+}
+
+func TestEmbedChunks_NamedVectorsRankIndependently(t *testing.T) {
+	embeddingService := &wordEmbeddingService{MockEmbeddingService{dimensions: 64}}
+	store := &QdrantStore{
+		config:           types.VectorStoreConfig{VectorFields: []string{"title", "body"}},
+		embeddingService: embeddingService,
+	}
+
+	chunks := []types.DocumentChunk{
+		{ID: 1, Content: "proofing dough and baking bread at home", Metadata: types.Metadata{Title: "golang concurrency patterns"}},
+		{ID: 2, Content: "golang concurrency with goroutines and channels", Metadata: types.Metadata{Title: "sourdough bread recipes"}},
+	}
+
+	vectors, err := store.embedChunks(context.Background(), chunks)
+	if err != nil {
+		t.Fatalf("embedChunks failed: %v", err)
+	}
+
+	query, _ := embeddingService.GenerateEmbedding(context.Background(), "golang concurrency")
+	best := func(vectorName string) uint64 {
+		var bestID uint64
+		bestScore := -1.0
+		for i, chunkVectors := range vectors {
+			named := chunkVectors.GetVectors().GetVectors()
+			vector, exists := named[vectorName]
+			if !exists {
+				t.Fatalf("chunk %d is missing the %s vector", chunks[i].ID, vectorName)
+			}
+			score := 0.0
+			for j, v := range vector.GetData() {
+				score += float64(v) * query[j]
+			}
+			if score > bestScore {
+				bestID, bestScore = chunks[i].ID, score
+			}
+		}
+		return bestID
+	}
+
+	if id := best("title"); id != 1 {
+		t.Errorf("expected chunk 1 to rank first on the title vector, got %d", id)
+	}
+	if id := best("body"); id != 2 {
+		t.Errorf("expected chunk 2 to rank first on the body vector, got %d", id)
+	}
+}
+
+func TestResolveVectorName(t *testing.T) {
+	named := &QdrantStore{config: types.VectorStoreConfig{VectorFields: []string{"title", "body"}}}
+	if name, err := named.resolveVectorName(""); err != nil || name != "title" {
+		t.Errorf("expected default to first field, got %q, %v", name, err)
+	}
+	if name, err := named.resolveVectorName("body"); err != nil || name != "body" {
+		t.Errorf("expected body, got %q, %v", name, err)
+	}
+	if _, err := named.resolveVectorName("summary"); err == nil {
+		t.Error("expected error for unknown vector name")
+	}
+
+	single := &QdrantStore{}
+	if name, err := single.resolveVectorName(""); err != nil || name != "" {
+		t.Errorf("expected default vector, got %q, %v", name, err)
+	}
+	if _, err := single.resolveVectorName("title"); err == nil {
+		t.Error("expected error when named vectors are disabled")
+	}
+}
+
+func TestDimensionAction(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   types.VectorStoreConfig
+		liveSize int
+		want     string
+		wantErr  bool
+	}{
+		{name: "matching size", config: types.VectorStoreConfig{DimensionPolicy: "error"}, liveSize: 1536},
+		{name: "no collection", config: types.VectorStoreConfig{DimensionPolicy: "error"}, liveSize: 0},
+		{name: "mismatch errors", config: types.VectorStoreConfig{DimensionPolicy: "error"}, liveSize: 384, wantErr: true},
+		{name: "recreate needs confirmation", config: types.VectorStoreConfig{DimensionPolicy: "recreate"}, liveSize: 384, wantErr: true},
+		{name: "confirmed recreate", config: types.VectorStoreConfig{DimensionPolicy: "recreate", ConfirmRecreate: true}, liveSize: 384, want: DimensionPolicyRecreate},
+		{name: "adapt", config: types.VectorStoreConfig{DimensionPolicy: "adapt"}, liveSize: 384, want: DimensionPolicyAdapt},
+		{name: "mismatched named vectors", config: types.VectorStoreConfig{DimensionPolicy: "error"}, liveSize: -1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action, err := dimensionAction(tt.config, tt.liveSize, 1536)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if err != nil && !errors.Is(err, ErrDimensionMismatch) {
+				t.Errorf("expected the error to wrap ErrDimensionMismatch, got %v", err)
+			}
+			if action != tt.want {
+				t.Errorf("expected action %q, got %q", tt.want, action)
+			}
+		})
+	}
+}
+
+func TestVectorSize(t *testing.T) {
+	single := qdrant.NewVectorsConfig(&qdrant.VectorParams{Size: 384})
+	if size := vectorSize(single); size != 384 {
+		t.Errorf("expected 384, got %d", size)
+	}
+
+	named := qdrant.NewVectorsConfigMap(map[string]*qdrant.VectorParams{
+		"title": {Size: 768},
+		"body":  {Size: 768},
+	})
+	if size := vectorSize(named); size != 768 {
+		t.Errorf("expected 768, got %d", size)
+	}
+
+	mixed := qdrant.NewVectorsConfigMap(map[string]*qdrant.VectorParams{
+		"title": {Size: 384},
+		"body":  {Size: 768},
+	})
+	if size := vectorSize(mixed); size != -1 {
+		t.Errorf("expected -1 for mixed sizes, got %d", size)
+	}
+}
+
+func TestVectorDistance(t *testing.T) {
+	single := qdrant.NewVectorsConfig(&qdrant.VectorParams{Size: 384, Distance: qdrant.Distance_Dot})
+	if distance := vectorDistance(single); distance != "dot" {
+		t.Errorf("expected dot, got %q", distance)
+	}
+
+	named := qdrant.NewVectorsConfigMap(map[string]*qdrant.VectorParams{
+		"title": {Size: 768, Distance: qdrant.Distance_Euclid},
+	})
+	if distance := vectorDistance(named); distance != "euclid" {
+		t.Errorf("expected euclid, got %q", distance)
+	}
+
+	if distance := vectorDistance(nil); distance != "" {
+		t.Errorf("expected no distance without a config, got %q", distance)
+	}
+}
+
+func TestSoftDeleteFlag(t *testing.T) {
+	if isSoftDeleted(map[string]*qdrant.Value{"document_id": qdrant.NewValueString("doc-1")}) {
+		t.Error("expected points without the flag to be active")
+	}
+	if !isSoftDeleted(map[string]*qdrant.Value{"deleted": qdrant.NewValueBool(true)}) {
+		t.Error("expected flagged points to be soft-deleted")
+	}
+	if isSoftDeleted(map[string]*qdrant.Value{"deleted": qdrant.NewValueBool(false)}) {
+		t.Error("expected restored points to be active")
+	}
+
+	filter := activeFilter()
+	if len(filter.MustNot) != 1 || filter.MustNot[0].GetField().GetKey() != "deleted" {
+		t.Errorf("expected search filter to exclude deleted points, got %v", filter)
+	}
+}
+
+func TestDocumentFilter_MatchesExactDocumentID(t *testing.T) {
+	q := &QdrantStore{}
+	extra := qdrant.NewRange("chunk_index", &qdrant.Range{Gte: qdrant.PtrOf(0.0)})
+	filter := q.documentFilter("doc-1", extra)
+	if len(filter.Must) != 2 || filter.Must[1] != extra || len(filter.MustNot) != 1 {
+		t.Fatalf("expected the document condition, the extra one and the active filter, got %v", filter)
+	}
+
+	// A keyword match on "doc-1" doesn't reach "doc-10", as a text match would
+	for _, condition := range []*qdrant.Condition{filter.Must[0], documentIDCondition("doc-1")} {
+		field := condition.GetField()
+		if field.GetKey() != "document_id" || field.GetMatch().GetKeyword() != "doc-1" || field.GetMatch().GetText() != "" {
+			t.Errorf("expected an exact keyword match on doc-1, got %v", field)
+		}
+	}
+}
+
+func TestSearchDiagnostics(t *testing.T) {
+	resp := &qdrant.QueryResponse{
+		Result: []*qdrant.ScoredPoint{{}, {}},
+		Time:   0.0125,
+		Usage: &qdrant.Usage{Hardware: &qdrant.HardwareUsage{
+			Cpu:                3,
+			VectorIoRead:       4096,
+			PayloadIoRead:      100,
+			PayloadIndexIoRead: 20,
+		}},
+	}
+
+	diagnostics := searchDiagnostics(resp, &qdrant.CollectionInfo{PointsCount: qdrant.PtrOf(uint64(50)), IndexedVectorsCount: qdrant.PtrOf(uint64(50))}, 0)
+	if diagnostics.QdrantTimeMs != 12.5 || diagnostics.ResultCount != 2 {
+		t.Errorf("Expected 12.5ms and 2 results, got %+v", diagnostics)
+	}
+	if diagnostics.CPU != 3 || diagnostics.VectorIORead != 4096 || diagnostics.PayloadIORead != 120 {
+		t.Errorf("Expected hardware usage from the response, got %+v", diagnostics)
+	}
+	if diagnostics.SearchMode != "hnsw" {
+		t.Errorf("Expected hnsw when every vector is indexed, got %q", diagnostics.SearchMode)
+	}
+
+	// Two named vectors per point, only half of them indexed
+	diagnostics = searchDiagnostics(resp, &qdrant.CollectionInfo{PointsCount: qdrant.PtrOf(uint64(50)), IndexedVectorsCount: qdrant.PtrOf(uint64(50))}, 2)
+	if diagnostics.SearchMode != "mixed" {
+		t.Errorf("Expected mixed when some vectors are unindexed, got %q", diagnostics.SearchMode)
+	}
+
+	diagnostics = searchDiagnostics(resp, &qdrant.CollectionInfo{PointsCount: qdrant.PtrOf(uint64(50))}, 0)
+	if diagnostics.SearchMode != "full_scan" {
+		t.Errorf("Expected full_scan without an index, got %q", diagnostics.SearchMode)
+	}
+
+	diagnostics = searchDiagnostics(&qdrant.QueryResponse{}, nil, 0)
+	if diagnostics.SearchMode != "" || diagnostics.CPU != 0 {
+		t.Errorf("Expected empty diagnostics without usage or collection info, got %+v", diagnostics)
+	}
+}
+
+func TestStreamInWindows_OrdersLargeDocumentPageByPage(t *testing.T) {
+	// 5000 chunks stored out of order, with a gap wider than a page
+	var stored []types.DocumentChunk
+	for i := 4999; i >= 0; i-- {
+		if i >= 1000 && i < 1600 {
+			continue
+		}
+		stored = append(stored, types.DocumentChunk{ChunkIndex: i})
+	}
+
+	largestPage := 0
+	fetch := func(lo, hi int) ([]types.DocumentChunk, error) {
+		var page []types.DocumentChunk
+		for _, chunk := range stored {
+			if chunk.ChunkIndex >= lo && chunk.ChunkIndex < hi {
+				page = append(page, chunk)
+			}
+		}
+		largestPage = max(largestPage, len(page))
+		return page, nil
+	}
+	remaining := func(from int) (bool, error) {
+		for _, chunk := range stored {
+			if chunk.ChunkIndex >= from {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	var streamed []int
+	err := streamInWindows(256, fetch, remaining, func(chunk types.DocumentChunk) error {
+		streamed = append(streamed, chunk.ChunkIndex)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(streamed) != len(stored) {
+		t.Fatalf("Expected %d chunks, got %d", len(stored), len(streamed))
+	}
+	for i := 1; i < len(streamed); i++ {
+		if streamed[i] <= streamed[i-1] {
+			t.Fatalf("Expected chunk_index order, got %d after %d", streamed[i], streamed[i-1])
+		}
+	}
+	if largestPage > 256 {
+		t.Errorf("Expected at most one window of chunks in memory, got %d", largestPage)
+	}
+}
+
+func TestScrollAll_FollowsPageOffsets(t *testing.T) {
+	// Qdrant returns at most 1000 points per page, with the next page's offset
+	const total, pageSize = 2500, 1000
+	pages := 0
+	scroll := func(offset *qdrant.PointId) ([]*qdrant.RetrievedPoint, *qdrant.PointId, error) {
+		pages++
+		start := uint64(1)
+		if offset != nil {
+			start = offset.GetNum()
+		}
+		var points []*qdrant.RetrievedPoint
+		for id := start; id <= total && len(points) < pageSize; id++ {
+			points = append(points, &qdrant.RetrievedPoint{Id: qdrant.NewIDNum(id)})
+		}
+		if next := start + uint64(len(points)); next <= total {
+			return points, qdrant.NewIDNum(next), nil
+		}
+		return points, nil, nil
+	}
+
+	seen := make(map[uint64]bool)
+	err := scrollAll(scroll, func(point *qdrant.RetrievedPoint) error {
+		seen[point.Id.GetNum()] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(seen) != total || pages != 3 {
+		t.Errorf("Expected all %d points over 3 pages, got %d over %d", total, len(seen), pages)
+	}
+}
+
+func TestWithEmbeddingService_EmbedsPerCollection(t *testing.T) {
+	base := &QdrantStore{
+		config:           types.VectorStoreConfig{CollectionName: "documents"},
+		embeddingService: &MockEmbeddingService{dimensions: 8},
+	}
+	storeA := base.WithCollection("corpus_a").WithEmbeddingService(&MockEmbeddingService{dimensions: 4})
+	storeB := base.WithCollection("corpus_b").WithEmbeddingService(&MockEmbeddingService{dimensions: 16})
+
+	chunks := []types.DocumentChunk{{ID: 1, Content: "shared text"}}
+	for store, want := range map[*QdrantStore]int{base: 8, storeA: 4, storeB: 16} {
+		vectors, err := store.embedChunks(context.Background(), chunks)
+		if err != nil {
+			t.Fatalf("embedChunks failed: %v", err)
+		}
+		if got := len(vectors[0].GetVector().GetData()); got != want {
+			t.Errorf("Collection %s: expected %d dimensions, got %d", store.config.CollectionName, want, got)
+		}
+	}
+	if storeA.config.CollectionName != "corpus_a" {
+		t.Errorf("Expected the collection to be kept, got %s", storeA.config.CollectionName)
+	}
+}
+
+// countingEmbeddingService counts the texts it is asked to embed
+type countingEmbeddingService struct {
+	MockEmbeddingService
+	embedded int
+}
+
+func (c *countingEmbeddingService) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float64, error) {
+	c.embedded += len(texts)
+	return c.MockEmbeddingService.GenerateEmbeddings(ctx, texts)
+}
+
+func TestEmbedChunks_UsesPrecomputedVectors(t *testing.T) {
+	embeddingService := &countingEmbeddingService{MockEmbeddingService: MockEmbeddingService{dimensions: 3}}
+	store := &QdrantStore{embeddingService: embeddingService}
+
+	precomputed := []float64{0.1, 0.2, 0.3}
+	vectors, err := store.embedChunks(context.Background(), []types.DocumentChunk{
+		{ID: 1, Content: "first", Embedding: precomputed},
+		{ID: 2, Content: "second", Embedding: precomputed},
+	})
+	if err != nil {
+		t.Fatalf("embedChunks failed: %v", err)
+	}
+	if embeddingService.embedded != 0 {
+		t.Errorf("Expected no embedding calls for pre-computed vectors, embedded %d texts", embeddingService.embedded)
+	}
+	if got := vectors[1].GetVector().GetData(); len(got) != 3 || got[2] != float32(0.3) {
+		t.Errorf("Expected the supplied vector to be stored, got %v", got)
+	}
+
+	// Only chunks without a vector are embedded
+	vectors, err = store.embedChunks(context.Background(), []types.DocumentChunk{
+		{ID: 1, Content: "first", Embedding: precomputed},
+		{ID: 2, Content: "second"},
+	})
+	if err != nil {
+		t.Fatalf("embedChunks failed: %v", err)
+	}
+	if embeddingService.embedded != 1 || len(vectors[1].GetVector().GetData()) != 3 {
+		t.Errorf("Expected only the second chunk to be embedded, embedded %d texts", embeddingService.embedded)
+	}
+
+	_, err = store.embedChunks(context.Background(), []types.DocumentChunk{{ID: 1, Content: "first", Embedding: []float64{0.1}}})
+	if !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("Expected ErrDimensionMismatch, got %v", err)
+	}
+}
+
+func TestDescribe_ReportsCollectionAndDistance(t *testing.T) {
+	store := &QdrantStore{config: types.VectorStoreConfig{CollectionName: "documents"}}
+
+	meta := store.WithCollection("tenant_acme").Describe()
+	if meta.Collection != "tenant_acme" || meta.Distance != "cosine" {
+		t.Errorf("Expected the tenant collection with cosine distance, got %+v", meta)
+	}
+
+	store.config.DistanceMetric = DistanceEuclid
+	if meta := store.Describe(); meta.Distance != "euclid" {
+		t.Errorf("Expected the configured euclid distance, got %q", meta.Distance)
+	}
+}
+
+func TestSearchFilter(t *testing.T) {
+	conditions, err := parseFilters(map[string]string{"language": "en", "team": "search", "tags": "faq"})
+	if err != nil {
+		t.Fatalf("parseFilters failed: %v", err)
+	}
+	filter, remaining := searchFilter(conditions)
+
+	if len(filter.MustNot) != 1 {
+		t.Errorf("expected soft-deleted chunks to stay excluded, got %v", filter.MustNot)
+	}
+	var keys []string
+	for _, condition := range filter.Must {
+		field := condition.GetField()
+		keys = append(keys, field.GetKey()+"="+field.GetMatch().GetKeyword())
+	}
+	want := []string{"language=en", "tags=faq", "custom_team=search"}
+	if strings.Join(keys, ",") != strings.Join(want, ",") || len(remaining) != 0 {
+		t.Errorf("expected conditions %v, got %v and %v left over", want, keys, remaining)
+	}
+
+	if filter, _ := searchFilter(nil); len(filter.Must) != 0 {
+		t.Errorf("expected no conditions without filters, got %v", filter.Must)
+	}
+
+	// Substring matches go to Qdrant; case-insensitive and prefix matches are left to the store
+	conditions, err = parseFilters(map[string]string{"source:contains": "book", "title:icontains": "guide", "author:prefix": "J", "path:contains": "Chapter 3"})
+	if err != nil {
+		t.Fatalf("parseFilters failed: %v", err)
+	}
+	filter, remaining = searchFilter(conditions)
+	if len(filter.Must) != 2 || filter.Must[0].GetField().GetKey() != "path" || filter.Must[0].GetField().GetMatch().GetText() != "Chapter 3" ||
+		filter.Must[1].GetField().GetKey() != "source" || filter.Must[1].GetField().GetMatch().GetText() != "book" {
+		t.Errorf("expected text matches on path and source, got %v", filter.Must)
+	}
+	if len(remaining) != 2 || remaining[0].Field != "author" || remaining[1].Field != "title" {
+		t.Errorf("expected the author and title conditions to be left over, got %+v", remaining)
+	}
+}
+
+func TestFillFromPages_PagesUntilLimitIsFilled(t *testing.T) {
+	// 100 candidates, of which only every 7th passes the remaining filters
+	const total, pageSize, limit = 100, 10, 5
+	var offsets []uint64
+	fetch := func(offset uint64) ([]*qdrant.ScoredPoint, bool, error) {
+		offsets = append(offsets, offset)
+		var points []*qdrant.ScoredPoint
+		for id := offset; id < total && len(points) < pageSize; id++ {
+			points = append(points, &qdrant.ScoredPoint{Id: qdrant.NewIDNum(id)})
+		}
+		return points, false, nil
+	}
+	var accepted []uint64
+	accept := func(point *qdrant.ScoredPoint) (bool, error) {
+		if point.Id.GetNum()%7 == 0 {
+			accepted = append(accepted, point.Id.GetNum())
+		}
+		return len(accepted) == limit, nil
+	}
+
+	if err := fillFromPages(pageSize, maxFilterPages, fetch, accept); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(accepted, []uint64{0, 7, 14, 21, 28}) {
+		t.Errorf("Expected the limit to be filled from later pages, got %v", accepted)
+	}
+	if !reflect.DeepEqual(offsets, []uint64{0, 10, 20}) {
+		t.Errorf("Expected paging to stop once the limit was filled, got offsets %v", offsets)
+	}
+
+	// A selective filter stops at the page cap rather than scanning everything
+	offsets, accepted = nil, nil
+	rare := func(point *qdrant.ScoredPoint) (bool, error) { return false, nil }
+	if err := fillFromPages(pageSize, 3, fetch, rare); err != nil || len(offsets) != 3 {
+		t.Errorf("Expected 3 pages, got %d (%v)", len(offsets), err)
+	}
+}
+
+func TestIsSearchTimeout(t *testing.T) {
+	ctx := context.Background()
+	timeout := status.Error(codes.DeadlineExceeded, "Timeout error: Operation 'Search' timed out after 1 seconds")
+	if !isSearchTimeout(ctx, timeout) || !isSearchTimeout(ctx, fmt.Errorf("query: %w", timeout)) {
+		t.Errorf("Expected %q to be a timeout", timeout)
+	}
+
+	failures := []error{
+		context.DeadlineExceeded,
+		errors.New("dial tcp 10.0.0.1:6334: i/o timeout"),
+		status.Error(codes.Unavailable, "connection refused"),
+		status.Error(codes.NotFound, "collection documents not found"),
+	}
+	for _, err := range failures {
+		if isSearchTimeout(ctx, err) {
+			t.Errorf("Expected %q not to be a search timeout", err)
+		}
+	}
+
+	// The caller's own deadline fails the search
+	expired, cancel := context.WithTimeout(ctx, 0)
+	defer cancel()
+	if isSearchTimeout(expired, status.Error(codes.DeadlineExceeded, "context deadline exceeded")) {
+		t.Error("Expected the caller's deadline not to be a search timeout")
+	}
+}
+
+func TestEmbedChunks_KeepsVectorsAlignedWithChunks(t *testing.T) {
+	embeddingService, err := embedding.NewMockService(types.EmbeddingConfig{Provider: "mock", Dimensions: 8})
+	if err != nil {
+		t.Fatalf("Failed to create mock embedding service: %v", err)
+	}
+	store := &QdrantStore{embeddingService: embeddingService}
+
+	chunks := []types.DocumentChunk{
+		{ID: 1, Content: "first", Embedding: []float64{1, 0, 0, 0, 0, 0, 0, 0}},
+		{ID: 2, Content: "second"},
+		{ID: 3, Content: "third"},
+	}
+	vectors, err := store.embedChunks(context.Background(), chunks)
+	if err != nil {
+		t.Fatalf("embedChunks failed: %v", err)
+	}
+	for i, text := range []string{"second", "third"} {
+		want, _ := embeddingService.GenerateEmbedding(context.Background(), text)
+		if got := vectors[i+1].GetVector().GetData(); len(got) != 8 || got[0] != float32(want[0]) || got[7] != float32(want[7]) {
+			t.Errorf("Expected chunk %d to get the embedding of %q, got %v", i+2, text, got)
+		}
+	}
+
+	// An empty chunk is rejected instead of shifting the vectors after it
+	chunks[1].Content = ""
+	if _, err := store.embedChunks(context.Background(), chunks); err == nil || !strings.Contains(err.Error(), "chunk 2 has no content") {
+		t.Errorf("Expected the empty chunk to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrInvalidTenantID is returned when a tenant ID cannot be used in a collection name
+	ErrInvalidTenantID = errors.New("invalid tenant ID")
+	// ErrUnknownTenant is returned for a tenant missing from the allow-list
+	ErrUnknownTenant = errors.New("unknown tenant")
+	// ErrTooManyTenants is returned when a new tenant would exceed the tenant limit
+	ErrTooManyTenants = errors.New("tenant limit reached")
+)
+
+// tenantIDPattern restricts tenant IDs to characters that are safe in collection names
+var tenantIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// TenantStoreFactory creates a ready-to-use store for a collection, creating
+// the collection if it does not exist yet
+type TenantStoreFactory func(ctx context.Context, collection string) (VectorStore, error)
+
+// TenantRouter maps tenants to their own collections, giving each tenant a
+// separate store. Stores are created on first use and reused afterwards.
+type TenantRouter struct {
+	template string
+	newStore TenantStoreFactory
+	// allowed lists the tenants that may be used; nil allows any valid ID
+	allowed map[string]bool
+	// maxTenants caps the number of tenant stores; 0 means no cap
+	maxTenants int
+
+	mu     sync.Mutex
+	stores map[string]*tenantStore
+}
+
+// tenantStore is a tenant's store, created once by the first request for it
+type tenantStore struct {
+	ready chan struct{}
+	store VectorStore
+	err   error
+}
+
+// NewTenantRouter creates a router that names collections from template,
+// replacing "{id}" with the tenant ID (e.g. "tenant_{id}")
+func NewTenantRouter(template string, newStore TenantStoreFactory) (*TenantRouter, error) {
+	if !strings.Contains(template, "{id}") {
+		return nil, fmt.Errorf("tenant collection template must contain {id}: %q", template)
+	}
+
+	if newStore == nil {
+		return nil, fmt.Errorf("tenant store factory is required")
+	}
+
+	return &TenantRouter{
+		template: template,
+		newStore: newStore,
+		stores:   make(map[string]*tenantStore),
+	}, nil
+}
+
+// SetAllowedTenants restricts the router to the given tenant IDs. Without
+// any, every valid tenant ID is accepted.
+func (r *TenantRouter) SetAllowedTenants(tenantIDs []string) {
+	if len(tenantIDs) == 0 {
+		r.allowed = nil
+		return
+	}
+	r.allowed = make(map[string]bool, len(tenantIDs))
+	for _, tenantID := range tenantIDs {
+		r.allowed[tenantID] = true
+	}
+}
+
+// SetMaxTenants caps how many tenant collections the router opens; 0 means no cap
+func (r *TenantRouter) SetMaxTenants(maxTenants int) {
+	r.maxTenants = maxTenants
+}
+
+// CollectionName returns the collection that holds the tenant's data
+func (r *TenantRouter) CollectionName(tenantID string) (string, error) {
+	if !tenantIDPattern.MatchString(tenantID) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidTenantID, tenantID)
+	}
+	return strings.ReplaceAll(r.template, "{id}", tenantID), nil
+}
+
+// StoreFor returns the store for a tenant's collection, creating it on first
+// use. The store is created outside the router's lock, so a slow tenant
+// doesn't hold up others; concurrent requests for a new tenant wait for the
+// first one to create it. A store that failed to be created is retried by
+// the next request.
+func (r *TenantRouter) StoreFor(ctx context.Context, tenantID string) (VectorStore, error) {
+	collection, err := r.CollectionName(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if r.allowed != nil && !r.allowed[tenantID] {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownTenant, tenantID)
+	}
+
+	r.mu.Lock()
+	tenant, exists := r.stores[collection]
+	if !exists {
+		if r.maxTenants > 0 && len(r.stores) >= r.maxTenants {
+			r.mu.Unlock()
+			return nil, fmt.Errorf("%w: at most %d tenants", ErrTooManyTenants, r.maxTenants)
+		}
+		tenant = &tenantStore{ready: make(chan struct{})}
+		r.stores[collection] = tenant
+	}
+	r.mu.Unlock()
+
+	if !exists {
+		tenant.store, tenant.err = r.newStore(ctx, collection)
+		if tenant.err != nil {
+			r.mu.Lock()
+			delete(r.stores, collection)
+			r.mu.Unlock()
+		}
+		close(tenant.ready)
+	}
+
+	select {
+	case <-tenant.ready:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if tenant.err != nil {
+		return nil, fmt.Errorf("failed to create store for tenant %s: %w", tenantID, tenant.err)
+	}
+	return tenant.store, nil
+}
@@ -0,0 +1,167 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTenantRouter_SeparateCollections(t *testing.T) {
+	var created []string
+	router, err := NewTenantRouter("tenant_{id}", func(ctx context.Context, collection string) (VectorStore, error) {
+		created = append(created, collection)
+		return &QdrantStore{}, nil
+	})
+	if err != nil {
+		t.Fatalf("NewTenantRouter failed: %v", err)
+	}
+
+	storeA, err := router.StoreFor(context.Background(), "acme")
+	if err != nil {
+		t.Fatalf("StoreFor(acme) failed: %v", err)
+	}
+	storeB, err := router.StoreFor(context.Background(), "globex")
+	if err != nil {
+		t.Fatalf("StoreFor(globex) failed: %v", err)
+	}
+	if storeA == storeB {
+		t.Error("expected different stores for different tenants")
+	}
+
+	again, err := router.StoreFor(context.Background(), "acme")
+	if err != nil {
+		t.Fatalf("StoreFor(acme) failed: %v", err)
+	}
+	if again != storeA {
+		t.Error("expected store to be reused for the same tenant")
+	}
+
+	if len(created) != 2 || created[0] != "tenant_acme" || created[1] != "tenant_globex" {
+		t.Errorf("unexpected collections created: %v", created)
+	}
+}
+
+func TestTenantRouter_InvalidTenantID(t *testing.T) {
+	router, err := NewTenantRouter("tenant_{id}", func(ctx context.Context, collection string) (VectorStore, error) {
+		return &QdrantStore{}, nil
+	})
+	if err != nil {
+		t.Fatalf("NewTenantRouter failed: %v", err)
+	}
+
+	for _, tenantID := range []string{"", "../other", "a b"} {
+		if _, err := router.StoreFor(context.Background(), tenantID); !errors.Is(err, ErrInvalidTenantID) {
+			t.Errorf("StoreFor(%q): expected ErrInvalidTenantID, got %v", tenantID, err)
+		}
+	}
+}
+
+func TestNewTenantRouter_RequiresPlaceholder(t *testing.T) {
+	_, err := NewTenantRouter("documents", func(ctx context.Context, collection string) (VectorStore, error) {
+		return nil, nil
+	})
+	if err == nil {
+		t.Error("expected error for template without {id}")
+	}
+}
+
+func TestTenantRouter_AllowListAndLimit(t *testing.T) {
+	router, err := NewTenantRouter("tenant_{id}", func(ctx context.Context, collection string) (VectorStore, error) {
+		return &QdrantStore{}, nil
+	})
+	if err != nil {
+		t.Fatalf("NewTenantRouter failed: %v", err)
+	}
+	ctx := context.Background()
+
+	router.SetAllowedTenants([]string{"acme", "globex"})
+	if _, err := router.StoreFor(ctx, "initech"); !errors.Is(err, ErrUnknownTenant) {
+		t.Errorf("expected ErrUnknownTenant for a tenant off the list, got %v", err)
+	}
+
+	router.SetAllowedTenants(nil)
+	router.SetMaxTenants(1)
+	if _, err := router.StoreFor(ctx, "acme"); err != nil {
+		t.Fatalf("StoreFor(acme) failed: %v", err)
+	}
+	if _, err := router.StoreFor(ctx, "globex"); !errors.Is(err, ErrTooManyTenants) {
+		t.Errorf("expected ErrTooManyTenants past the limit, got %v", err)
+	}
+	if _, err := router.StoreFor(ctx, "acme"); err != nil {
+		t.Errorf("expected a known tenant to stay usable at the limit, got %v", err)
+	}
+}
+
+func TestTenantRouter_CreatesOutsideLock(t *testing.T) {
+	release := make(chan struct{})
+	var creations atomic.Int32
+	router, err := NewTenantRouter("tenant_{id}", func(ctx context.Context, collection string) (VectorStore, error) {
+		creations.Add(1)
+		if collection == "tenant_slow" {
+			<-release
+		}
+		return &QdrantStore{}, nil
+	})
+	if err != nil {
+		t.Fatalf("NewTenantRouter failed: %v", err)
+	}
+	ctx := context.Background()
+
+	// Concurrent requests for a slow new tenant share one creation
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := router.StoreFor(ctx, "slow"); err != nil {
+				t.Errorf("StoreFor(slow) failed: %v", err)
+			}
+		}()
+	}
+
+	// Other tenants aren't held up meanwhile
+	done := make(chan error)
+	go func() {
+		_, err := router.StoreFor(ctx, "fast")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("StoreFor(fast) failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected another tenant to resolve while a slow one is created")
+	}
+
+	close(release)
+	wg.Wait()
+	if n := creations.Load(); n != 2 {
+		t.Errorf("expected one creation per tenant, got %d", n)
+	}
+}
+
+func TestTenantRouter_RetriesFailedCreation(t *testing.T) {
+	fail := true
+	router, err := NewTenantRouter("tenant_{id}", func(ctx context.Context, collection string) (VectorStore, error) {
+		if fail {
+			return nil, errors.New("qdrant unavailable")
+		}
+		return &QdrantStore{}, nil
+	})
+	if err != nil {
+		t.Fatalf("NewTenantRouter failed: %v", err)
+	}
+	router.SetMaxTenants(1)
+
+	if _, err := router.StoreFor(context.Background(), "acme"); err == nil {
+		t.Fatal("expected the creation error")
+	}
+	fail = false
+	if _, err := router.StoreFor(context.Background(), "acme"); err != nil {
+		t.Errorf("expected a failed tenant to be retried without counting toward the limit, got %v", err)
+	}
+}
@@ -0,0 +1,778 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"go-rag/internal/embedding"
+	"go-rag/internal/types"
+)
+
+// weaviatePageSize is how many chunks each document lookup fetches per query
+const weaviatePageSize = 1000
+
+// weaviateClassPattern is the form Weaviate requires of class names
+var weaviateClassPattern = regexp.MustCompile(`^[A-Z][_0-9A-Za-z]*$`)
+
+// weaviateFields are the properties read back for every chunk
+const weaviateFields = `document_id content content_compressed window chunk_index total_chunks start_offset end_offset
+chunk_strategy chunk_overlap created_at updated_at title author source language content_type tags parent_id section path
+custom_metadata
+_additional { id distance }`
+
+// WeaviateStore implements VectorStore using a Weaviate instance. The
+// collection name, with its first letter capitalized, is the Weaviate class.
+type WeaviateStore struct {
+	config           types.VectorStoreConfig
+	className        string
+	baseURL          string
+	httpClient       *http.Client
+	embeddingService embedding.Service
+
+	schemaMu    sync.Mutex
+	schemaReady bool
+}
+
+// graphqlEnum is rendered bare in GraphQL, e.g. the Equal in operator: Equal
+type graphqlEnum string
+
+// NewWeaviateStore creates a Weaviate vector store
+func NewWeaviateStore(config types.VectorStoreConfig, embeddingService embedding.Service) (*WeaviateStore, error) {
+	if config.Provider != "weaviate" {
+		return nil, fmt.Errorf("unsupported vector store provider: %s", config.Provider)
+	}
+
+	if config.Host == "" {
+		return nil, fmt.Errorf("host is required")
+	}
+
+	if config.CollectionName == "" {
+		return nil, fmt.Errorf("collection name is required")
+	}
+
+	if embeddingService == nil {
+		return nil, fmt.Errorf("embedding service is required")
+	}
+
+	if len(config.VectorFields) > 0 {
+		return nil, fmt.Errorf("named vectors are not supported by the weaviate store")
+	}
+
+	className := weaviateClassName(config.CollectionName)
+	if !weaviateClassPattern.MatchString(className) {
+		return nil, fmt.Errorf("collection name %q is not a valid Weaviate class name", config.CollectionName)
+	}
+
+	baseURL := config.Host
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "http://" + baseURL
+		if config.Port > 0 {
+			baseURL += ":" + strconv.Itoa(config.Port)
+		}
+	}
+
+	return &WeaviateStore{
+		config:           config,
+		className:        className,
+		baseURL:          strings.TrimRight(baseURL, "/"),
+		httpClient:       &http.Client{Timeout: 30 * time.Second},
+		embeddingService: embeddingService,
+	}, nil
+}
+
+// weaviateClassName capitalizes the first letter, as Weaviate does for class names
+func weaviateClassName(collectionName string) string {
+	runes := []rune(collectionName)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+// weaviateUUID maps a chunk ID onto the object UUID; Weaviate only accepts UUID IDs
+func weaviateUUID(chunkID uint64) string {
+	return fmt.Sprintf("00000000-0000-0000-%04x-%012x", chunkID>>48, chunkID&(1<<48-1))
+}
+
+// chunkIDFromUUID reverses weaviateUUID
+func chunkIDFromUUID(id string) (uint64, error) {
+	hex := strings.ReplaceAll(id, "-", "")
+	if len(hex) != 32 || strings.Trim(hex[:16], "0") != "" {
+		return 0, fmt.Errorf("object ID %q was not written by this store", id)
+	}
+	return strconv.ParseUint(hex[16:], 16, 64)
+}
+
+// do sends a JSON request to Weaviate and decodes the JSON response into out
+func (w *WeaviateStore) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, w.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if w.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+w.config.APIKey)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &weaviateStatusError{status: resp.StatusCode, message: strings.TrimSpace(string(message))}
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// weaviateStatusError is a non-2xx response from Weaviate
+type weaviateStatusError struct {
+	status  int
+	message string
+}
+
+func (e *weaviateStatusError) Error() string {
+	return fmt.Sprintf("weaviate returned %d: %s", e.status, e.message)
+}
+
+// isWeaviateNotFound reports whether err is a 404 from Weaviate
+func isWeaviateNotFound(err error) bool {
+	statusErr, ok := err.(*weaviateStatusError)
+	return ok && statusErr.status == http.StatusNotFound
+}
+
+// CreateCollection creates the class if it doesn't exist. Identifier-like
+// properties use field tokenization so filters match whole values. Weaviate
+// takes the vector size from the first object, so vectorSize is unused.
+func (w *WeaviateStore) CreateCollection(ctx context.Context, vectorSize int) error {
+	err := w.do(ctx, http.MethodGet, "/v1/schema/"+w.className, nil, nil)
+	if err == nil {
+		return nil
+	}
+	if !isWeaviateNotFound(err) {
+		return fmt.Errorf("failed to get class: %w", err)
+	}
+
+	property := func(name, dataType, tokenization string) map[string]any {
+		p := map[string]any{"name": name, "dataType": []string{dataType}}
+		if tokenization != "" {
+			p["tokenization"] = tokenization
+		}
+		return p
+	}
+	class := map[string]any{
+		"class":             w.className,
+		"vectorizer":        "none",
+		"vectorIndexConfig": map[string]any{"distance": "cosine"},
+		"properties": []map[string]any{
+			property("document_id", "text", "field"),
+			property("content", "text", "word"),
+			property("content_compressed", "boolean", ""),
+			property("window", "text", "word"),
+			property("chunk_index", "int", ""),
+			property("total_chunks", "int", ""),
+			property("start_offset", "int", ""),
+			property("end_offset", "int", ""),
+			property("chunk_strategy", "text", "field"),
+			property("chunk_overlap", "int", ""),
+			property("created_at", "date", ""),
+			property("updated_at", "date", ""),
+			property("title", "text", "field"),
+			property("author", "text", "field"),
+			property("source", "text", "field"),
+			property("language", "text", "field"),
+			property("content_type", "text", "field"),
+			property("tags", "text[]", "field"),
+			property("parent_id", "text", "field"),
+			property("section", "text", "field"),
+			property("path", "text[]", "field"),
+			property("custom_metadata", "text", "field"),
+			property("deleted", "boolean", ""),
+		},
+	}
+
+	if err := w.do(ctx, http.MethodPost, "/v1/schema", class, nil); err != nil {
+		return fmt.Errorf("failed to create class: %w", err)
+	}
+	return nil
+}
+
+// DropCollection deletes the class and all its objects, if it exists
+func (w *WeaviateStore) DropCollection(ctx context.Context) error {
+	w.schemaMu.Lock()
+	defer w.schemaMu.Unlock()
+
+	err := w.do(ctx, http.MethodDelete, "/v1/schema/"+w.className, nil, nil)
+	if err != nil && !isWeaviateNotFound(err) {
+		return fmt.Errorf("failed to delete class: %w", err)
+	}
+	w.schemaReady = false
+	return nil
+}
+
+// ensureSchema creates the class before the first write
+func (w *WeaviateStore) ensureSchema(ctx context.Context) error {
+	w.schemaMu.Lock()
+	defer w.schemaMu.Unlock()
+
+	if w.schemaReady {
+		return nil
+	}
+	if err := w.CreateCollection(ctx, w.embeddingService.GetDimensions()); err != nil {
+		return err
+	}
+	w.schemaReady = true
+	return nil
+}
+
+// StoreChunks embeds chunks and writes them as Weaviate objects
+func (w *WeaviateStore) StoreChunks(ctx context.Context, chunks []types.DocumentChunk) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	if err := w.ensureSchema(ctx); err != nil {
+		return err
+	}
+
+	embeddings, err := embedContent(ctx, w.embeddingService, chunks)
+	if err != nil {
+		return err
+	}
+
+	objects := make([]map[string]any, len(chunks))
+	for i, chunk := range chunks {
+		properties, err := w.chunkProperties(chunk)
+		if err != nil {
+			return err
+		}
+		objects[i] = map[string]any{
+			"class":      w.className,
+			"id":         weaviateUUID(chunk.ID),
+			"properties": properties,
+			"vector":     embeddings[i],
+		}
+	}
+
+	var results []struct {
+		ID     string `json:"id"`
+		Result struct {
+			Errors *struct {
+				Error []struct {
+					Message string `json:"message"`
+				} `json:"error"`
+			} `json:"errors"`
+		} `json:"result"`
+	}
+	if err := w.do(ctx, http.MethodPost, "/v1/batch/objects", map[string]any{"objects": objects}, &results); err != nil {
+		return fmt.Errorf("failed to store objects in Weaviate: %w", err)
+	}
+
+	// The batch endpoint reports failures per object with a 200 status
+	for _, result := range results {
+		if result.Result.Errors != nil && len(result.Result.Errors.Error) > 0 {
+			return fmt.Errorf("failed to store object %s in Weaviate: %s", result.ID, result.Result.Errors.Error[0].Message)
+		}
+	}
+
+	return nil
+}
+
+// chunkProperties maps a chunk onto the class properties. Custom metadata is
+// stored as one JSON property, since its keys needn't be valid property names.
+func (w *WeaviateStore) chunkProperties(chunk types.DocumentChunk) (map[string]any, error) {
+	content, window := chunk.Content, chunk.Window
+	if w.config.CompressContent {
+		var err error
+		if content, err = compressContent(chunk.Content); err != nil {
+			return nil, fmt.Errorf("failed to compress content for chunk %d: %w", chunk.ID, err)
+		}
+		if window != "" {
+			if window, err = compressContent(chunk.Window); err != nil {
+				return nil, fmt.Errorf("failed to compress window for chunk %d: %w", chunk.ID, err)
+			}
+		}
+	}
+
+	custom, err := json.Marshal(chunk.Metadata.Custom)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode custom metadata for chunk %d: %w", chunk.ID, err)
+	}
+
+	tags := chunk.Metadata.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	path := chunk.Metadata.Path
+	if path == nil {
+		path = []string{}
+	}
+
+	return map[string]any{
+		"document_id":        chunk.DocumentID,
+		"content":            content,
+		"content_compressed": w.config.CompressContent,
+		"window":             window,
+		"chunk_index":        chunk.ChunkIndex,
+		"total_chunks":       chunk.TotalChunks,
+		"start_offset":       chunk.StartOffset,
+		"end_offset":         chunk.EndOffset,
+		"chunk_strategy":     chunk.ChunkStrategy,
+		"chunk_overlap":      chunk.ChunkOverlap,
+		"created_at":         chunk.CreatedAt.Format(time.RFC3339),
+		"updated_at":         chunk.UpdatedAt.Format(time.RFC3339),
+		"title":              chunk.Metadata.Title,
+		"author":             chunk.Metadata.Author,
+		"source":             chunk.Metadata.Source,
+		"language":           chunk.Metadata.Language,
+		"content_type":       chunk.Metadata.ContentType,
+		"tags":               tags,
+		"parent_id":          chunk.Metadata.ParentID,
+		"section":            chunk.Metadata.Section,
+		"path":               path,
+		"custom_metadata":    string(custom),
+		// Always written so the live-chunk filter can match on it
+		"deleted": false,
+	}, nil
+}
+
+// objectToDocumentChunk converts a Weaviate object's properties back into a chunk
+func (w *WeaviateStore) objectToDocumentChunk(id string, properties map[string]any) (*types.DocumentChunk, error) {
+	chunkID, err := chunkIDFromUUID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	text := func(key string) string {
+		value, _ := properties[key].(string)
+		return value
+	}
+	number := func(key string) int {
+		value, _ := properties[key].(float64)
+		return int(value)
+	}
+
+	content, window := text("content"), text("window")
+	if compressed, _ := properties["content_compressed"].(bool); compressed {
+		if content, err = decompressContent(content); err != nil {
+			return nil, fmt.Errorf("failed to decompress content for chunk %d: %w", chunkID, err)
+		}
+		if window != "" {
+			if window, err = decompressContent(window); err != nil {
+				return nil, fmt.Errorf("failed to decompress window for chunk %d: %w", chunkID, err)
+			}
+		}
+	}
+
+	metadata := types.Metadata{
+		Title:       text("title"),
+		Author:      text("author"),
+		Source:      text("source"),
+		Language:    text("language"),
+		ContentType: text("content_type"),
+		Custom:      make(map[string]string),
+		ParentID:    text("parent_id"),
+		Section:     text("section"),
+	}
+	if tags, ok := properties["tags"].([]any); ok {
+		for _, tag := range tags {
+			if tag, ok := tag.(string); ok && tag != "" {
+				metadata.Tags = append(metadata.Tags, tag)
+			}
+		}
+	}
+	if path, ok := properties["path"].([]any); ok {
+		for _, heading := range path {
+			if heading, ok := heading.(string); ok {
+				metadata.Path = append(metadata.Path, heading)
+			}
+		}
+	}
+	if custom := text("custom_metadata"); custom != "" && custom != "null" {
+		if err := json.Unmarshal([]byte(custom), &metadata.Custom); err != nil {
+			return nil, fmt.Errorf("failed to decode custom metadata for chunk %d: %w", chunkID, err)
+		}
+	}
+
+	createdAt, _ := time.Parse(time.RFC3339, text("created_at"))
+	updatedAt, _ := time.Parse(time.RFC3339, text("updated_at"))
+
+	return &types.DocumentChunk{
+		ID:            chunkID,
+		DocumentID:    text("document_id"),
+		Content:       content,
+		ChunkIndex:    number("chunk_index"),
+		TotalChunks:   number("total_chunks"),
+		StartOffset:   number("start_offset"),
+		EndOffset:     number("end_offset"),
+		ChunkStrategy: text("chunk_strategy"),
+		ChunkOverlap:  number("chunk_overlap"),
+		Window:        window,
+		Metadata:      metadata,
+		CreatedAt:     createdAt,
+		UpdatedAt:     updatedAt,
+	}, nil
+}
+
+// SearchSimilar searches for similar chunks with a nearVector query. Named
+// vectors aren't supported, so vectorName must be empty. Only chunks whose
+// metadata matches every filter are returned.
+func (w *WeaviateStore) SearchSimilar(ctx context.Context, query string, limit int, vectorName string, filters map[string]string) ([]types.DocumentChunk, error) {
+	if query == "" {
+		return nil, fmt.Errorf("query cannot be empty")
+	}
+
+	if vectorName != "" {
+		return nil, fmt.Errorf("named vectors are not supported, cannot search %q", vectorName)
+	}
+
+	if limit <= 0 {
+		limit = 10
+	}
+
+	where, err := weaviateSearchWhere(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	queryEmbedding, err := w.embeddingService.GenerateEmbedding(embedding.WithInputType(ctx, embedding.InputTypeQuery), query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
+	}
+
+	args := map[string]any{
+		"nearVector": map[string]any{"vector": toFloat32(queryEmbedding)},
+		"limit":      limit,
+		"where":      where,
+	}
+	chunks, err := w.get(ctx, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search in Weaviate: %w", err)
+	}
+	return chunks, nil
+}
+
+// get runs a GraphQL Get query on the class with the given arguments
+func (w *WeaviateStore) get(ctx context.Context, args map[string]any) ([]types.DocumentChunk, error) {
+	query := fmt.Sprintf("{ Get { %s(%s) { %s } } }", w.className, graphqlArguments(args), weaviateFields)
+
+	var resp struct {
+		Data struct {
+			Get map[string][]map[string]any `json:"Get"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := w.do(ctx, http.MethodPost, "/v1/graphql", map[string]string{"query": query}, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Errors) > 0 {
+		return nil, fmt.Errorf("graphql error: %s", resp.Errors[0].Message)
+	}
+
+	objects := resp.Data.Get[w.className]
+	chunks := make([]types.DocumentChunk, 0, len(objects))
+	for _, object := range objects {
+		additional, _ := object["_additional"].(map[string]any)
+		id, _ := additional["id"].(string)
+		chunk, err := w.objectToDocumentChunk(id, object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert object to document chunk: %w", err)
+		}
+		// Cosine distance runs from 0 to 2; report it as a similarity like Qdrant does
+		if distance, ok := additional["distance"].(float64); ok {
+			chunk.VectorScore = 1 - distance
+		}
+		chunks = append(chunks, *chunk)
+	}
+	return chunks, nil
+}
+
+// weaviateSearchWhere builds the filter for live chunks matching every filter.
+// Custom metadata is stored as JSON, so it can't be filtered on. contains and
+// prefix filters become Like patterns, where * and ? in the value are
+// wildcards too; Weaviate has no case-insensitive match for whole values.
+func weaviateSearchWhere(filters map[string]string) (map[string]any, error) {
+	conditions, err := parseFilters(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	operands := []any{weaviateLiveWhere()}
+	for _, condition := range conditions {
+		property := condition.Field
+		switch {
+		case strings.HasPrefix(property, "custom_"):
+			return nil, fmt.Errorf("filtering on custom metadata %q is not supported by the weaviate store", strings.TrimPrefix(property, "custom_"))
+		case condition.Operator == FilterContainsFold || (isListProperty(property) && condition.Operator != FilterEqual):
+			return nil, fmt.Errorf("the %s filter operator on %q is not supported by the weaviate store", condition.Operator, property)
+		case isListProperty(property):
+			operands = append(operands, map[string]any{"path": []string{property}, "operator": graphqlEnum("ContainsAny"), "valueText": []string{condition.Value}})
+		case condition.Operator == FilterContains:
+			operands = append(operands, weaviateLike(property, "*"+condition.Value+"*"))
+		case condition.Operator == FilterPrefix:
+			operands = append(operands, weaviateLike(property, condition.Value+"*"))
+		default:
+			operands = append(operands, weaviateEqual(property, condition.Value))
+		}
+	}
+
+	if len(operands) == 1 {
+		return operands[0].(map[string]any), nil
+	}
+	return map[string]any{"operator": graphqlEnum("And"), "operands": operands}, nil
+}
+
+// weaviateEqual matches objects whose text property equals value
+func weaviateEqual(property, value string) map[string]any {
+	return map[string]any{"path": []string{property}, "operator": graphqlEnum("Equal"), "valueText": value}
+}
+
+// weaviateLike matches objects whose text property matches a wildcard pattern
+func weaviateLike(property, pattern string) map[string]any {
+	return map[string]any{"path": []string{property}, "operator": graphqlEnum("Like"), "valueText": pattern}
+}
+
+// weaviateLiveWhere matches chunks that haven't been soft-deleted
+func weaviateLiveWhere() map[string]any {
+	return map[string]any{"path": []string{"deleted"}, "operator": graphqlEnum("Equal"), "valueBoolean": false}
+}
+
+// graphqlArguments renders query arguments in GraphQL input syntax, sorted by name
+func graphqlArguments(args map[string]any) string {
+	rendered := graphqlValue(args)
+	return rendered[1 : len(rendered)-1]
+}
+
+// graphqlValue renders a value in GraphQL input syntax, which is JSON with
+// bare object keys and enums
+func graphqlValue(value any) string {
+	switch v := value.(type) {
+	case graphqlEnum:
+		return string(v)
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		fields := make([]string, len(keys))
+		for i, key := range keys {
+			fields[i] = key + ": " + graphqlValue(v[key])
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = graphqlValue(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case []string:
+		items := make([]any, len(v))
+		for i, item := range v {
+			items[i] = item
+		}
+		return graphqlValue(items)
+	case []float32:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = strconv.FormatFloat(float64(item), 'g', -1, 32)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	default:
+		// Strings, numbers and booleans are written the same as in JSON
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+// GetChunksByDocumentID retrieves all live chunks for a document in chunk order
+func (w *WeaviateStore) GetChunksByDocumentID(ctx context.Context, documentID string) ([]types.DocumentChunk, error) {
+	if documentID == "" {
+		return nil, fmt.Errorf("document ID cannot be empty")
+	}
+	return w.documentChunks(ctx, documentID, false)
+}
+
+// documentChunks collects a document's chunks one chunk_index window at a
+// time, which stays under Weaviate's limit on offset paging
+func (w *WeaviateStore) documentChunks(ctx context.Context, documentID string, includeDeleted bool) ([]types.DocumentChunk, error) {
+	var chunks []types.DocumentChunk
+	total := 0
+	for start := 0; start == 0 || start < total; start += weaviatePageSize {
+		operands := []any{
+			weaviateEqual("document_id", documentID),
+			map[string]any{"path": []string{"chunk_index"}, "operator": graphqlEnum("GreaterThanEqual"), "valueInt": start},
+			map[string]any{"path": []string{"chunk_index"}, "operator": graphqlEnum("LessThan"), "valueInt": start + weaviatePageSize},
+		}
+		if !includeDeleted {
+			operands = append(operands, weaviateLiveWhere())
+		}
+
+		window, err := w.get(ctx, map[string]any{
+			"where": map[string]any{"operator": graphqlEnum("And"), "operands": operands},
+			"limit": weaviatePageSize,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get chunks from Weaviate: %w", err)
+		}
+		for _, chunk := range window {
+			total = max(total, chunk.TotalChunks)
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	sort.Slice(chunks, func(i, j int) bool {
+		return chunks[i].ChunkIndex < chunks[j].ChunkIndex
+	})
+	return chunks, nil
+}
+
+// GetChunkByID retrieves a specific chunk by its ID
+func (w *WeaviateStore) GetChunkByID(ctx context.Context, chunkID uint64) (*types.DocumentChunk, error) {
+	if chunkID == 0 {
+		return nil, fmt.Errorf("chunk ID cannot be zero")
+	}
+
+	var object struct {
+		ID         string         `json:"id"`
+		Properties map[string]any `json:"properties"`
+	}
+	err := w.do(ctx, http.MethodGet, "/v1/objects/"+w.className+"/"+weaviateUUID(chunkID), nil, &object)
+	if isWeaviateNotFound(err) {
+		return nil, fmt.Errorf("chunk not found: %d", chunkID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object from Weaviate: %w", err)
+	}
+
+	if deleted, _ := object.Properties["deleted"].(bool); deleted {
+		return nil, fmt.Errorf("chunk not found: %d", chunkID)
+	}
+
+	chunk, err := w.objectToDocumentChunk(object.ID, object.Properties)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert object to document chunk: %w", err)
+	}
+	return chunk, nil
+}
+
+// DeleteDocument removes a document, or flags it as deleted when soft delete is enabled
+func (w *WeaviateStore) DeleteDocument(ctx context.Context, documentID string) error {
+	if w.config.SoftDelete {
+		return w.setDeleted(ctx, documentID, true)
+	}
+	return w.PurgeDocument(ctx, documentID)
+}
+
+// RestoreDocument clears the soft-delete flag on a document's chunks
+func (w *WeaviateStore) RestoreDocument(ctx context.Context, documentID string) error {
+	return w.setDeleted(ctx, documentID, false)
+}
+
+// setDeleted sets the soft-delete flag on every chunk of a document
+func (w *WeaviateStore) setDeleted(ctx context.Context, documentID string, deleted bool) error {
+	if documentID == "" {
+		return fmt.Errorf("document ID cannot be empty")
+	}
+
+	chunks, err := w.documentChunks(ctx, documentID, true)
+	if err != nil {
+		return err
+	}
+
+	for _, chunk := range chunks {
+		err := w.do(ctx, http.MethodPatch, "/v1/objects/"+w.className+"/"+weaviateUUID(chunk.ID), map[string]any{
+			"class":      w.className,
+			"properties": map[string]any{"deleted": deleted},
+		}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to update deleted flag in Weaviate: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// PurgeDocument permanently removes all chunks for a document, whether or not
+// they were soft-deleted
+func (w *WeaviateStore) PurgeDocument(ctx context.Context, documentID string) error {
+	if documentID == "" {
+		return fmt.Errorf("document ID cannot be empty")
+	}
+
+	// Each batch delete removes at most Weaviate's query limit, so repeat until nothing matches
+	for {
+		var resp struct {
+			Results struct {
+				Matches int `json:"matches"`
+				Limit   int `json:"limit"`
+				Failed  int `json:"failed"`
+			} `json:"results"`
+		}
+		err := w.do(ctx, http.MethodDelete, "/v1/batch/objects", map[string]any{
+			"match":  map[string]any{"class": w.className, "where": weaviateEqual("document_id", documentID)},
+			"output": "minimal",
+		}, &resp)
+		if err != nil {
+			return fmt.Errorf("failed to delete objects from Weaviate: %w", err)
+		}
+		if resp.Results.Failed > 0 {
+			return fmt.Errorf("failed to delete %d objects from Weaviate", resp.Results.Failed)
+		}
+		if resp.Results.Matches == 0 || resp.Results.Matches < resp.Results.Limit {
+			return nil
+		}
+	}
+}
+
+// HealthCheck checks if Weaviate is ready to serve requests
+func (w *WeaviateStore) HealthCheck(ctx context.Context) error {
+	if err := w.do(ctx, http.MethodGet, "/v1/.well-known/ready", nil, nil); err != nil {
+		return fmt.Errorf("Weaviate health check failed: %w", err)
+	}
+	return nil
+}
+
+// DeleteChunk removes a specific chunk
+func (w *WeaviateStore) DeleteChunk(ctx context.Context, chunkID uint64) error {
+	if chunkID == 0 {
+		return fmt.Errorf("chunk ID cannot be zero")
+	}
+
+	err := w.do(ctx, http.MethodDelete, "/v1/objects/"+w.className+"/"+weaviateUUID(chunkID), nil, nil)
+	if err != nil && !isWeaviateNotFound(err) {
+		return fmt.Errorf("failed to delete object from Weaviate: %w", err)
+	}
+	return nil
+}
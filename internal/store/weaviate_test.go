@@ -0,0 +1,282 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go-rag/internal/types"
+)
+
+func newTestWeaviateStore(t *testing.T, handler http.HandlerFunc) *WeaviateStore {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	store, err := NewWeaviateStore(types.VectorStoreConfig{
+		Provider:       "weaviate",
+		Host:           server.URL,
+		CollectionName: "documents",
+	}, &MockEmbeddingService{dimensions: 4})
+	if err != nil {
+		t.Fatalf("Failed to create Weaviate store: %v", err)
+	}
+	return store
+}
+
+func TestNewWeaviateStore(t *testing.T) {
+	embeddingService := &MockEmbeddingService{dimensions: 4}
+
+	store, err := NewWeaviateStore(types.VectorStoreConfig{Provider: "weaviate", Host: "localhost", Port: 8080, CollectionName: "documents"}, embeddingService)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if store.className != "Documents" || store.baseURL != "http://localhost:8080" {
+		t.Errorf("Expected class Documents at http://localhost:8080, got %s at %s", store.className, store.baseURL)
+	}
+
+	if _, err := NewWeaviateStore(types.VectorStoreConfig{Provider: "weaviate", Host: "localhost", CollectionName: "my-docs"}, embeddingService); err == nil {
+		t.Error("Expected an invalid class name to be rejected")
+	}
+
+	vectorStore, err := NewStore(types.VectorStoreConfig{Provider: "weaviate", Host: "localhost", CollectionName: "documents"}, embeddingService)
+	if _, ok := vectorStore.(*WeaviateStore); err != nil || !ok {
+		t.Errorf("Expected NewStore to create a WeaviateStore, got %T, %v", vectorStore, err)
+	}
+}
+
+func TestWeaviateUUID_RoundTrip(t *testing.T) {
+	for _, id := range []uint64{1, 1<<48 + 7, ^uint64(0)} {
+		got, err := chunkIDFromUUID(weaviateUUID(id))
+		if err != nil || got != id {
+			t.Errorf("Expected %d to round-trip, got %d, %v", id, got, err)
+		}
+	}
+
+	if _, err := chunkIDFromUUID("6f1c2a3b-0000-0000-0000-000000000001"); err == nil {
+		t.Error("Expected a foreign UUID to be rejected")
+	}
+}
+
+func TestWeaviateObjectConversion(t *testing.T) {
+	store, err := NewWeaviateStore(types.VectorStoreConfig{Provider: "weaviate", Host: "localhost", CollectionName: "documents", CompressContent: true}, &MockEmbeddingService{dimensions: 4})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	chunk := types.DocumentChunk{
+		ID: 42, DocumentID: "doc-1", Content: "content", Window: "the window", ChunkIndex: 2, TotalChunks: 3,
+		Metadata:  types.Metadata{Title: "Guide", Tags: []string{"go"}, Custom: map[string]string{"team-name": "search"}, Path: []string{"Guide", "Install"}, Section: "Install"},
+		CreatedAt: now, UpdatedAt: now,
+	}
+
+	properties, err := store.chunkProperties(chunk)
+	if err != nil {
+		t.Fatalf("chunkProperties failed: %v", err)
+	}
+	if properties["content"] == "content" {
+		t.Error("Expected content to be compressed")
+	}
+
+	// Round-trip through JSON so properties have the types Weaviate returns
+	data, _ := json.Marshal(properties)
+	var decoded map[string]any
+	json.Unmarshal(data, &decoded)
+
+	got, err := store.objectToDocumentChunk(weaviateUUID(chunk.ID), decoded)
+	if err != nil {
+		t.Fatalf("objectToDocumentChunk failed: %v", err)
+	}
+	if got.ID != 42 || got.Content != "content" || got.Window != "the window" || got.ChunkIndex != 2 ||
+		got.Metadata.Title != "Guide" || len(got.Metadata.Tags) != 1 || got.Metadata.Custom["team-name"] != "search" || !got.CreatedAt.Equal(now) ||
+		!reflect.DeepEqual(got.Metadata.Path, chunk.Metadata.Path) || got.Metadata.Section != "Install" {
+		t.Errorf("Chunk did not round-trip: %+v", got)
+	}
+}
+
+func TestGraphqlValue(t *testing.T) {
+	where, err := weaviateSearchWhere(map[string]string{"source": `say "hi"`, "tags": "go"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	got := graphqlValue(where)
+	want := `{operands: [{operator: Equal, path: ["deleted"], valueBoolean: false}, ` +
+		`{operator: Equal, path: ["source"], valueText: "say \"hi\""}, ` +
+		`{operator: ContainsAny, path: ["tags"], valueText: ["go"]}], operator: And}`
+	if got != want {
+		t.Errorf("Unexpected GraphQL:\n got %s\nwant %s", got, want)
+	}
+
+	if _, err := weaviateSearchWhere(map[string]string{"team": "search"}); err == nil {
+		t.Error("Expected a custom metadata filter to be rejected")
+	}
+
+	where, err = weaviateSearchWhere(map[string]string{"source:contains": "book", "title:prefix": "Guide"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := graphqlValue(where); !strings.Contains(got, `{operator: Like, path: ["source"], valueText: "*book*"}`) ||
+		!strings.Contains(got, `{operator: Like, path: ["title"], valueText: "Guide*"}`) {
+		t.Errorf("Expected Like patterns, got %s", got)
+	}
+	if _, err := weaviateSearchWhere(map[string]string{"source:icontains": "book"}); err == nil {
+		t.Error("Expected a case-insensitive filter to be rejected")
+	}
+}
+
+func TestWeaviateStore_StoreChunksCreatesClass(t *testing.T) {
+	var created, batches int
+	store := newTestWeaviateStore(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/schema/Documents":
+			if created == 0 {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write([]byte(`{}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/schema":
+			created++
+			w.Write([]byte(`{}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/batch/objects":
+			batches++
+			var body struct {
+				Objects []struct {
+					Class  string    `json:"class"`
+					ID     string    `json:"id"`
+					Vector []float32 `json:"vector"`
+				} `json:"objects"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			if len(body.Objects) != 1 || body.Objects[0].Class != "Documents" || body.Objects[0].ID != weaviateUUID(7) || len(body.Objects[0].Vector) != 4 {
+				t.Errorf("Unexpected batch: %+v", body)
+			}
+			w.Write([]byte(`[{"id":"` + weaviateUUID(7) + `","result":{}}]`))
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	chunks := []types.DocumentChunk{{ID: 7, DocumentID: "doc-1", Content: "text", TotalChunks: 1}}
+	for range 2 {
+		if err := store.StoreChunks(context.Background(), chunks); err != nil {
+			t.Fatalf("StoreChunks failed: %v", err)
+		}
+	}
+	if created != 1 || batches != 2 {
+		t.Errorf("Expected the class to be created once and two batches, got %d and %d", created, batches)
+	}
+}
+
+func TestWeaviateStore_StoreChunksReportsObjectErrors(t *testing.T) {
+	store := newTestWeaviateStore(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/batch/objects" {
+			w.Write([]byte(`[{"id":"x","result":{"errors":{"error":[{"message":"invalid property"}]}}}]`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	})
+
+	err := store.StoreChunks(context.Background(), []types.DocumentChunk{{ID: 1, DocumentID: "doc-1", Content: "text"}})
+	if err == nil || !strings.Contains(err.Error(), "invalid property") {
+		t.Errorf("Expected the object error, got %v", err)
+	}
+}
+
+func TestWeaviateStore_SearchSimilar(t *testing.T) {
+	var query string
+	store := newTestWeaviateStore(t, func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		query = body["query"]
+		w.Write([]byte(`{"data":{"Get":{"Documents":[{"document_id":"doc-1","content":"hello","chunk_index":0,"total_chunks":1,` +
+			`"_additional":{"id":"` + weaviateUUID(5) + `","distance":0.25}}]}}}`))
+	})
+
+	results, err := store.SearchSimilar(context.Background(), "hello", 3, "", map[string]string{"source": "docs"})
+	if err != nil {
+		t.Fatalf("SearchSimilar failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != 5 || results[0].Content != "hello" || results[0].VectorScore != 0.75 {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+	for _, part := range []string{"Get { Documents(", "limit: 3", "nearVector: {vector: [0.1, 0.2, 0.3, 0.4]}", `valueText: "docs"`} {
+		if !strings.Contains(query, part) {
+			t.Errorf("Expected query to contain %q, got %s", part, query)
+		}
+	}
+
+	if _, err := store.SearchSimilar(context.Background(), "hello", 3, "title", nil); err == nil {
+		t.Error("Expected a named vector search to be rejected")
+	}
+}
+
+func TestWeaviateStore_GetChunkByID(t *testing.T) {
+	store := newTestWeaviateStore(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/objects/Documents/" + weaviateUUID(1):
+			w.Write([]byte(`{"id":"` + weaviateUUID(1) + `","properties":{"document_id":"doc-1","content":"live"}}`))
+		case "/v1/objects/Documents/" + weaviateUUID(2):
+			w.Write([]byte(`{"id":"` + weaviateUUID(2) + `","properties":{"document_id":"doc-1","deleted":true}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	chunk, err := store.GetChunkByID(context.Background(), 1)
+	if err != nil || chunk.Content != "live" {
+		t.Errorf("Expected the live chunk, got %+v, %v", chunk, err)
+	}
+	for _, id := range []uint64{2, 3} {
+		if _, err := store.GetChunkByID(context.Background(), id); err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Errorf("Expected chunk %d to be not found, got %v", id, err)
+		}
+	}
+}
+
+func TestWeaviateStore_HealthCheck(t *testing.T) {
+	ready := true
+	store := newTestWeaviateStore(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/.well-known/ready" || !ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+
+	if err := store.HealthCheck(context.Background()); err != nil {
+		t.Errorf("Expected a ready Weaviate to be healthy, got %v", err)
+	}
+	ready = false
+	if err := store.HealthCheck(context.Background()); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("Expected the 503 to fail the health check, got %v", err)
+	}
+}
+
+func TestWeaviateStore_DropCollection(t *testing.T) {
+	var deleted []string
+	store := newTestWeaviateStore(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		deleted = append(deleted, r.URL.Path)
+		if len(deleted) > 1 {
+			http.NotFound(w, r)
+		}
+	})
+	store.schemaReady = true
+
+	// Dropping a class that's already gone is not an error
+	for i := 0; i < 2; i++ {
+		if err := store.DropCollection(context.Background()); err != nil {
+			t.Fatalf("DropCollection failed: %v", err)
+		}
+	}
+	if len(deleted) != 2 || deleted[0] != "/v1/schema/Documents" || store.schemaReady {
+		t.Errorf("Expected the class deleted and the schema recreated on next write, got %v (ready=%v)", deleted, store.schemaReady)
+	}
+}
@@ -0,0 +1,29 @@
+package textutil
+
+// ellipsis marks text that was cut short
+const ellipsis = "..."
+
+// Truncate shortens s to at most maxLen characters for display, ending it
+// with "..." when anything was cut. It counts runes, so multi-byte characters
+// are never split, and it never panics on short input.
+func Truncate(s string, maxLen int) string {
+	truncated, _ := TruncateWithFlag(s, maxLen)
+	return truncated
+}
+
+// TruncateWithFlag is Truncate that also reports whether s was cut
+func TruncateWithFlag(s string, maxLen int) (string, bool) {
+	if maxLen <= 0 {
+		return "", s != ""
+	}
+
+	runes := []rune(s)
+	if len(runes) <= maxLen {
+		return s, false
+	}
+
+	if maxLen <= len(ellipsis) {
+		return string(runes[:maxLen]), true
+	}
+	return string(runes[:maxLen-len(ellipsis)]) + ellipsis, true
+}
@@ -0,0 +1,48 @@
+package textutil
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		maxLen int
+		want   string
+	}{
+		{name: "shorter than limit", input: "short", maxLen: 50, want: "short"},
+		{name: "empty", input: "", maxLen: 50, want: ""},
+		{name: "exact length", input: "abcde", maxLen: 5, want: "abcde"},
+		{name: "truncated", input: "abcdefghij", maxLen: 8, want: "abcde..."},
+		{name: "multi-byte runes", input: "héllo wörld", maxLen: 8, want: "héllo..."},
+		{name: "tiny limit", input: "abcdef", maxLen: 2, want: "ab"},
+		{name: "zero limit", input: "abc", maxLen: 0, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Truncate(tt.input, tt.maxLen); got != tt.want {
+				t.Errorf("Truncate(%q, %d) = %q, want %q", tt.input, tt.maxLen, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateWithFlag(t *testing.T) {
+	if got, cut := TruncateWithFlag("short", 5); got != "short" || cut {
+		t.Errorf("Expected text at the limit to be kept, got %q (cut %v)", got, cut)
+	}
+	if got, cut := TruncateWithFlag("Grüße aus Köln", 9); got != "Grüße ..." || !cut {
+		t.Errorf("Expected cut text, got %q (cut %v)", got, cut)
+	}
+
+	content := "東京🚀é東京🚀é東京🚀é"
+	for maxLen := 1; maxLen <= utf8.RuneCountInString(content); maxLen++ {
+		got, _ := TruncateWithFlag(content, maxLen)
+		if !utf8.ValidString(got) || utf8.RuneCountInString(got) > maxLen {
+			t.Errorf("maxLen %d produced %q", maxLen, got)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package types
 
 import (
+	"encoding/json"
 	"fmt"
 	"hash/fnv"
 	"time"
@@ -8,13 +9,33 @@ import (
 
 // DocumentChunk represents a chunk of a document with metadata
 type DocumentChunk struct {
-	ID         uint64    `json:"id"`
-	DocumentID string    `json:"document_id"`
-	Content    string    `json:"content"`
-	ChunkIndex int       `json:"chunk_index"`
-	Metadata   Metadata  `json:"metadata,omitempty"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID         uint64 `json:"id"`
+	DocumentID string `json:"document_id"`
+	Content    string `json:"content"`
+	ChunkIndex int    `json:"chunk_index"`
+	// TotalChunks is the number of chunks the document was split into, if known
+	TotalChunks int `json:"total_chunks,omitempty"`
+	// StartOffset and EndOffset bound the chunk within the original document content
+	StartOffset int       `json:"start_offset,omitempty"`
+	EndOffset   int       `json:"end_offset,omitempty"`
+	Metadata    Metadata  `json:"metadata,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	// ChunkStrategy and ChunkOverlap record how the document was chunked, so it can be reconstructed
+	ChunkStrategy string `json:"chunk_strategy,omitempty"`
+	ChunkOverlap  int    `json:"chunk_overlap,omitempty"`
+	// Embedding is a pre-computed vector for the content; when set, storing
+	// the chunk uses it instead of calling the embedding service
+	Embedding []float64 `json:"embedding,omitempty"`
+	// Window holds the sentences around a single-sentence chunk. Search
+	// returns it as Content, with the sentence itself in MatchedText.
+	Window      string `json:"window,omitempty"`
+	MatchedText string `json:"matched_text,omitempty"`
+	// VectorScore is the similarity the vector store computed for the query, if any
+	VectorScore float64 `json:"vector_score,omitempty"`
+	// DuplicateDocumentIDs lists other documents whose identical chunk was
+	// collapsed into this search result
+	DuplicateDocumentIDs []string `json:"duplicate_document_ids,omitempty"`
 }
 
 // Metadata contains additional information about a document chunk
@@ -26,12 +47,30 @@ type Metadata struct {
 	Language    string            `json:"language,omitempty"`
 	ContentType string            `json:"content_type,omitempty"`
 	Custom      map[string]string `json:"custom,omitempty"`
+	// ParentID identifies the document this one belongs to, such as the book of a chapter
+	ParentID string `json:"parent_id,omitempty"`
+	// Path lists the headings leading to the chunk, outermost first, and
+	// Section is the innermost of them
+	Path    []string `json:"path,omitempty"`
+	Section string   `json:"section,omitempty"`
+}
+
+// DocumentContentResponse is a document rebuilt from its stored chunks
+type DocumentContentResponse struct {
+	DocumentID    string `json:"document_id"`
+	Content       string `json:"content"`
+	ChunkStrategy string `json:"chunk_strategy"`
+	TotalChunks   int    `json:"total_chunks"`
 }
 
 // RankedChunk represents a document chunk with a relevance score
 type RankedChunk struct {
 	DocumentChunk
-	Score float64 `json:"score"`
+	Score       float64 `json:"score"`
+	PrevChunkID *uint64 `json:"prev_chunk_id,omitempty"`
+	NextChunkID *uint64 `json:"next_chunk_id,omitempty"`
+	// Truncated is set when Content was shortened to the requested max_content_length
+	Truncated bool `json:"truncated,omitempty"`
 }
 
 // SearchRequest represents a search query request
@@ -40,19 +79,100 @@ type SearchRequest struct {
 	Limit     int               `json:"limit,omitempty"`
 	Threshold float64           `json:"threshold,omitempty"`
 	Filters   map[string]string `json:"filters,omitempty"`
+	// IncludeNeighbors adds the IDs of the previous/next chunks in the same document to each result
+	IncludeNeighbors bool `json:"include_neighbors,omitempty"`
+	// VectorName searches a specific named vector (e.g. "title") instead of the default one
+	VectorName string `json:"vector_name,omitempty"`
+	// NoCache forces fresh embeddings and results for this request
+	NoCache bool `json:"no_cache,omitempty"`
+	// Diagnostics reports how the vector store executed the search; requires SEARCH_DIAGNOSTICS_ENABLED
+	Diagnostics bool `json:"diagnostics,omitempty"`
+	// Boosts maps metadata conditions like "source=official" to score multipliers
+	Boosts map[string]float64 `json:"boosts,omitempty"`
+	// Facets lists metadata fields (e.g. "source", "tags") to count values of among the retrieved chunks
+	Facets []string `json:"facets,omitempty"`
+	// MaxContentLength cuts each result's content to this many characters; 0 returns it whole.
+	// GET /api/v1/chunks/:id always returns the full content.
+	MaxContentLength int `json:"max_content_length,omitempty"`
+	// Hybrid fuses the store's vector ranking with the keyword or reranker
+	// ranking by reciprocal rank fusion
+	Hybrid bool `json:"hybrid,omitempty"`
+	// MMRLambda, when set, reorders results by maximal marginal relevance:
+	// 1 favors relevance to the query and 0 diversity
+	MMRLambda *float64 `json:"mmr_lambda,omitempty"`
+	// NegativeQuery demotes results similar to it, for "about X but not Y" searches
+	NegativeQuery string `json:"negative_query,omitempty"`
+	// Rerank set to false skips re-ranking, so each result's score is the
+	// vector store's similarity, whatever the ranking mode
+	Rerank *bool `json:"rerank,omitempty"`
+	// RerankTopK keeps only this many of the best ranked results, while Limit
+	// sets how many candidates are retrieved for ranking; 0 keeps them all
+	RerankTopK int `json:"rerank_top_k,omitempty"`
 }
 
 // SearchResponse represents the response to a search query
 type SearchResponse struct {
-	Query   string        `json:"query"`
-	Results []RankedChunk `json:"results"`
-	Total   int           `json:"total"`
+	Query       string             `json:"query"`
+	Results     []RankedChunk      `json:"results"`
+	Total       int                `json:"total"`
+	Diagnostics *SearchDiagnostics `json:"diagnostics,omitempty"`
+	Meta        *ResponseMeta      `json:"meta,omitempty"`
+	// Partial is set when the vector search timed out and results may be incomplete
+	Partial bool `json:"partial,omitempty"`
+	// Facets maps each requested facet field to its values, most common first
+	Facets map[string][]FacetCount `json:"facets,omitempty"`
+}
+
+// FacetCount is how many retrieved chunks have a metadata value
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// SearchDiagnostics describes how the vector store executed a search
+type SearchDiagnostics struct {
+	QdrantTimeMs float64 `json:"qdrant_time_ms"`
+	ResultCount  int     `json:"result_count"`
+	// SearchMode is "hnsw", "mixed" (some segments not indexed yet and scanned) or "full_scan"
+	SearchMode          string `json:"search_mode,omitempty"`
+	PointsCount         uint64 `json:"points_count,omitempty"`
+	IndexedVectorsCount uint64 `json:"indexed_vectors_count,omitempty"`
+	// Hardware usage, reported when Qdrant runs with hardware reporting enabled
+	CPU           uint64 `json:"cpu,omitempty"`
+	VectorIORead  uint64 `json:"vector_io_read,omitempty"`
+	PayloadIORead uint64 `json:"payload_io_read,omitempty"`
 }
 
 // GeneratedResponse represents an AI-generated response
 type GeneratedResponse struct {
 	Response string   `json:"response"`
 	Sources  []string `json:"sources"`
+	// Citations maps the numbered context blocks of the prompt to their chunks
+	Citations []Citation `json:"citations,omitempty"`
+	// ContextReduced is set when the context was cut down to fit the model's window
+	ContextReduced bool `json:"context_reduced,omitempty"`
+	// ChunksUsed counts the chunks the prompt included, out of the
+	// ChunksRetrieved given for context; the context token budget and a
+	// reduced context drop the lowest ranked ones
+	ChunksUsed      int `json:"chunks_used,omitempty"`
+	ChunksRetrieved int `json:"chunks_retrieved,omitempty"`
+	// ToolCalls lists the tools the model wants the caller to run before it answers
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// EmptyResponse is set when the model gave no answer and Response is the configured fallback
+	EmptyResponse bool `json:"empty_response,omitempty"`
+	// Cached is set when the answer was served from the answer cache
+	Cached bool `json:"cached,omitempty"`
+	// LogProb is the answer's mean token log-probability, when the provider reported it
+	LogProb *float64 `json:"log_prob,omitempty"`
+}
+
+// Citation identifies the chunk behind a numbered context block, so an answer
+// citing [n] can be linked to the exact chunk
+type Citation struct {
+	Number     int    `json:"number"`
+	DocumentID string `json:"document_id"`
+	ChunkID    uint64 `json:"chunk_id"`
+	ChunkIndex int    `json:"chunk_index"`
 }
 
 // RAGRequest represents a complete RAG (Retrieve-Augment-Generate) request
@@ -61,14 +181,135 @@ type RAGRequest struct {
 	Limit     int               `json:"limit,omitempty"`
 	Threshold float64           `json:"threshold,omitempty"`
 	Filters   map[string]string `json:"filters,omitempty"`
+	// RetrieveLimit is how many chunks are retrieved and ranked; defaults to Limit
+	RetrieveLimit int `json:"retrieve_limit,omitempty"`
+	// ContextLimit is how many of the top ranked chunks are passed to generation; defaults to all
+	ContextLimit int `json:"context_limit,omitempty"`
+	// ResponseFormat is "text" (default) or "json" for a machine-parseable answer
+	ResponseFormat string `json:"response_format,omitempty"`
+	// Schema is an optional JSON schema the answer must follow in "json" mode
+	Schema json.RawMessage `json:"schema,omitempty"`
+	// Tools, ToolCalls and ToolResults enable tool calling; see GenerationOptions
+	Tools       []ToolDefinition `json:"tools,omitempty"`
+	ToolCalls   []ToolCall       `json:"tool_calls,omitempty"`
+	ToolResults []ToolResult     `json:"tool_results,omitempty"`
+	// Model, Temperature and MaxTokens override the configured generation
+	// settings for this request; omitted fields use the configured defaults
+	Model       string   `json:"model,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	// VectorName retrieves against a specific named vector (e.g. "title")
+	VectorName string `json:"vector_name,omitempty"`
+	// NoCache forces fresh embeddings and results for this request
+	NoCache bool `json:"no_cache,omitempty"`
+	// Hybrid fuses the store's vector ranking with the keyword or reranker
+	// ranking by reciprocal rank fusion
+	Hybrid bool `json:"hybrid,omitempty"`
+	// MMRLambda, when set, reorders the ranked chunks by maximal marginal
+	// relevance before the context is picked: 1 favors relevance and 0 diversity
+	MMRLambda *float64 `json:"mmr_lambda,omitempty"`
+	// RerankTopK keeps only this many of the best ranked chunks before the
+	// context is picked; 0 keeps them all
+	RerankTopK int `json:"rerank_top_k,omitempty"`
+}
+
+// Response formats supported by generation
+const (
+	ResponseFormatText = "text"
+	ResponseFormatJSON = "json"
+)
+
+// GenerationOptions holds per-request generation settings. Zero values fall
+// back to the service's configured behavior.
+type GenerationOptions struct {
+	ResponseFormat string          `json:"response_format,omitempty"`
+	Schema         json.RawMessage `json:"schema,omitempty"`
+	// Tools the model may call instead of answering directly
+	Tools []ToolDefinition `json:"tools,omitempty"`
+	// ToolCalls and ToolResults continue a conversation after the caller ran
+	// the tools requested in a previous response
+	ToolCalls   []ToolCall   `json:"tool_calls,omitempty"`
+	ToolResults []ToolResult `json:"tool_results,omitempty"`
+	// Temperature overrides the configured sampling temperature
+	Temperature *float64 `json:"temperature,omitempty"`
+	// Model and MaxTokens override the configured model and answer length
+	Model     string `json:"model,omitempty"`
+	MaxTokens int    `json:"max_tokens,omitempty"`
+}
+
+// ToolDefinition describes a function the model may call
+type ToolDefinition struct {
+	Name        string          `json:"name" binding:"required"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"` // JSON schema of the arguments
+}
+
+// ToolCall is a function call requested by the model
+type ToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"` // JSON-encoded arguments
+}
+
+// ToolResult is the output of a tool call executed by the caller
+type ToolResult struct {
+	ToolCallID string `json:"tool_call_id"`
+	Content    string `json:"content"`
 }
 
 // RAGResponse represents the response to a RAG request
 type RAGResponse struct {
-	Query            string        `json:"query"`
+	Query             string            `json:"query"`
 	GeneratedResponse GeneratedResponse `json:"generated_response"`
-	RetrievedChunks  []RankedChunk `json:"retrieved_chunks"`
-	ProcessingTime   string        `json:"processing_time"`
+	RetrievedChunks   []RankedChunk     `json:"retrieved_chunks"`
+	ProcessingTime    string            `json:"processing_time"`
+	// NoResultsReason explains why nothing was retrieved when generation was skipped
+	NoResultsReason string `json:"no_results_reason,omitempty"`
+	// GenerationSkippedReason explains why only retrieval results were returned
+	GenerationSkippedReason string `json:"generation_skipped_reason,omitempty"`
+	// Timings breaks ProcessingTime down by stage
+	Timings *TimingBreakdown `json:"timings,omitempty"`
+	// Meta identifies the collection and embedding model that produced the results
+	Meta *ResponseMeta `json:"meta,omitempty"`
+	// Partial is set when the vector search timed out and results may be incomplete
+	Partial bool `json:"partial,omitempty"`
+	// Confidence estimates from 0 to 1 how well the answer is grounded in the
+	// retrieved chunks; set only when confidence scoring is enabled
+	Confidence *float64 `json:"confidence,omitempty"`
+}
+
+// RAGStreamDelta is a piece of the answer sent by the streaming RAG endpoint.
+// The stream ends with a RAGResponse carrying the whole answer, the retrieved
+// chunks and the processing time.
+type RAGStreamDelta struct {
+	Delta string `json:"delta"`
+}
+
+// ResponseMeta describes where search results came from, for provenance and
+// for clients that combine several RAG backends
+type ResponseMeta struct {
+	Collection     string `json:"collection"`
+	EmbeddingModel string `json:"embedding_model"`
+	Distance       string `json:"distance"` // similarity metric of the collection, e.g. "cosine"
+}
+
+// CollectionStats summarizes what a collection holds, to confirm it's
+// populated and sized as expected. Soft-deleted chunks aren't counted.
+type CollectionStats struct {
+	Collection string `json:"collection"`
+	Chunks     uint64 `json:"chunks"`
+	Documents  uint64 `json:"documents"`
+	Dimensions int    `json:"dimensions"`
+	Distance   string `json:"distance"`
+}
+
+// TimingBreakdown reports how long each RAG stage took, in milliseconds.
+// Stages that didn't run are zero.
+type TimingBreakdown struct {
+	RetrievalMs  float64 `json:"retrieval_ms"`
+	RankingMs    float64 `json:"ranking_ms"`
+	GenerationMs float64 `json:"generation_ms"`
+	TotalMs      float64 `json:"total_ms"`
 }
 
 // IngestRequest represents a document ingestion request
@@ -76,6 +317,78 @@ type IngestRequest struct {
 	DocumentID string   `json:"document_id" binding:"required"`
 	Content    string   `json:"content" binding:"required"`
 	Metadata   Metadata `json:"metadata,omitempty"`
+	// NoCache forces fresh embeddings instead of reusing cached ones
+	NoCache bool `json:"no_cache,omitempty"`
+	// ChunkingOptions override the server's chunking for this document
+	ChunkingOptions
+}
+
+// UpdateDocumentRequest replaces the content of the document named in the path
+type UpdateDocumentRequest struct {
+	Content  string   `json:"content" binding:"required"`
+	Metadata Metadata `json:"metadata,omitempty"`
+	// NoCache forces fresh embeddings instead of reusing cached ones
+	NoCache bool `json:"no_cache,omitempty"`
+	// ChunkingOptions override the server's chunking for this document
+	ChunkingOptions
+}
+
+// ChunkingOptions overrides the server's chunking settings for one document.
+// Zero values fall back to the configured behavior.
+type ChunkingOptions struct {
+	ChunkSize    int    `json:"chunk_size,omitempty"`
+	ChunkOverlap *int   `json:"chunk_overlap,omitempty"` // nil keeps the default; 0 disables overlap
+	Strategy     string `json:"strategy,omitempty"`      // "fixed", "sentence", "paragraph", "recursive" or "markdown"
+}
+
+// BatchIngestRequest represents a request to ingest several documents at once
+type BatchIngestRequest struct {
+	Documents []IngestRequest `json:"documents" binding:"required"`
+}
+
+// ReindexRequest starts a background job that re-embeds documents
+type ReindexRequest struct {
+	// DocumentIDs to reindex; empty reindexes every document
+	DocumentIDs []string `json:"document_ids,omitempty"`
+}
+
+// IngestEvent reports the outcome of ingesting a single document in a batch
+type IngestEvent struct {
+	DocumentID  string `json:"document_id"`
+	ChunksCount int    `json:"chunks_count"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+}
+
+// JSONIngestRequest represents a request to ingest structured JSON records.
+// Records may be a single object or an array of objects.
+type JSONIngestRequest struct {
+	Records        json.RawMessage `json:"records" binding:"required"`
+	ContentFields  []string        `json:"content_fields" binding:"required"`
+	MetadataFields []string        `json:"metadata_fields,omitempty"`
+	IDField        string          `json:"id_field,omitempty"`
+	// NoCache forces fresh embeddings instead of reusing cached ones
+	NoCache bool `json:"no_cache,omitempty"`
+}
+
+// VectorIngestRequest ingests a document that was chunked and embedded by an
+// external pipeline, so the server stores the vectors as given
+type VectorIngestRequest struct {
+	DocumentID string        `json:"document_id" binding:"required"`
+	Chunks     []VectorChunk `json:"chunks" binding:"required"`
+	Metadata   Metadata      `json:"metadata,omitempty"`
+}
+
+// VectorChunk is a chunk of content with its pre-computed embedding
+type VectorChunk struct {
+	Content   string    `json:"content" binding:"required"`
+	Embedding []float64 `json:"embedding" binding:"required"`
+}
+
+// JSONIngestResponse represents the response to a JSON ingestion request
+type JSONIngestResponse struct {
+	Documents      []IngestEvent `json:"documents"`
+	ProcessingTime string        `json:"processing_time"`
 }
 
 // IngestResponse represents the response to an ingestion request
@@ -93,6 +406,19 @@ type HealthCheckResponse struct {
 	Services  map[string]string `json:"services"`
 }
 
+// CacheStats counts the lookups a cache served and missed, and its current size
+type CacheStats struct {
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+	Entries int   `json:"entries"`
+}
+
+// MetricsResponse reports runtime counters of the service
+type MetricsResponse struct {
+	// Caches maps each enabled cache to its counters
+	Caches map[string]CacheStats `json:"caches"`
+}
+
 // ErrorResponse represents an error response
 type ErrorResponse struct {
 	Error   string `json:"error"`
@@ -114,24 +440,118 @@ type Document struct {
 type ChunkingConfig struct {
 	ChunkSize    int    `json:"chunk_size"`
 	ChunkOverlap int    `json:"chunk_overlap"`
-	Strategy     string `json:"strategy"` // "fixed", "sentence", "paragraph"
+	Strategy     string `json:"strategy"`      // "fixed", "sentence", "paragraph", "recursive", "markdown"; used unless a request overrides it
+	StoreOffsets bool   `json:"store_offsets"` // record each chunk's character offsets in the source document
+	// Unit is what ChunkSize and ChunkOverlap count: "bytes" (default) or "tokens"
+	Unit string `json:"unit"`
+	// Separators are tried in order by the recursive strategy (empty = paragraphs,
+	// lines, sentences, then words)
+	Separators []string `json:"separators,omitempty"`
+	// ExtractMetadata fills metadata from markdown front-matter and headings or HTML titles
+	ExtractMetadata bool `json:"extract_metadata"`
+	// MarkdownHierarchy fills each markdown chunk's Path and Section from the
+	// headings above it
+	MarkdownHierarchy bool `json:"markdown_hierarchy"`
+	// SentenceWindow, when > 0 with the sentence strategy, stores one sentence
+	// per chunk plus this many sentences either side as the chunk's window
+	SentenceWindow int `json:"sentence_window"`
+	// MaxDirectoryFiles caps the files one directory ingest processes (0 = unlimited)
+	MaxDirectoryFiles int `json:"max_directory_files"`
+	// DirectoryBatchSize is how many files a directory ingest collects from
+	// the walk before processing them, so only one batch of paths is held at a
+	// time (0 = the default of 100)
+	DirectoryBatchSize int `json:"directory_batch_size"`
 }
 
 // EmbeddingConfig represents configuration for embeddings
 type EmbeddingConfig struct {
 	Model      string `json:"model"`
 	Dimensions int    `json:"dimensions"`
-	Provider   string `json:"provider"` // "openai", "huggingface", etc.
+	Provider   string `json:"provider"` // "openai", "azure", "cohere", "ollama" or "mock"
 	APIKey     string `json:"api_key,omitempty"`
+	// Deduplicate embeds each distinct text once per batch and maps the
+	// result back to every position it appeared in
+	Deduplicate bool `json:"deduplicate,omitempty"`
+	// MaxRetries retries transient provider failures, within the request's retry budget.
+	// The wait starts at RetryDelayMs and doubles per retry, up to RetryMaxDelayMs.
+	MaxRetries      int `json:"max_retries,omitempty"`
+	RetryDelayMs    int `json:"retry_delay_ms,omitempty"`
+	RetryMaxDelayMs int `json:"retry_max_delay_ms,omitempty"`
+	// BaseURL is the server address for self-hosted providers such as Ollama,
+	// or an OpenAI-compatible server replacing api.openai.com
+	BaseURL string `json:"base_url,omitempty"`
+	// BatchSize caps the texts sent in one embeddings request to OpenAI
+	BatchSize int `json:"batch_size,omitempty"`
+	// Concurrency caps the requests in flight for providers that embed one text per request
+	Concurrency int `json:"concurrency,omitempty"`
+	// CacheSize caches up to this many embeddings; 0 disables the cache.
+	// Entries expire after CacheTTLSecs, or never when it is 0.
+	CacheSize    int `json:"cache_size,omitempty"`
+	CacheTTLSecs int `json:"cache_ttl_secs,omitempty"`
+	// Azure addresses the Azure OpenAI resource when Provider is "azure"
+	Azure AzureOpenAIConfig `json:"azure,omitempty"`
+}
+
+// AzureOpenAIConfig represents an Azure OpenAI resource, which serves each
+// model under a deployment name
+type AzureOpenAIConfig struct {
+	// ResourceName builds the endpoint https://<name>.openai.azure.com when Endpoint is empty
+	ResourceName string `json:"resource_name,omitempty"`
+	Endpoint     string `json:"endpoint,omitempty"`
+	APIVersion   string `json:"api_version,omitempty"`
+	// Deployments maps model names to the deployments serving them
+	Deployments map[string]string `json:"deployments,omitempty"`
 }
 
 // VectorStoreConfig represents configuration for vector storage
 type VectorStoreConfig struct {
-	Provider       string `json:"provider"` // "qdrant", "pinecone", "weaviate"
+	Provider       string `json:"provider"` // "qdrant", "pinecone", "weaviate", "pgvector" or "memory"
 	Host           string `json:"host"`
 	Port           int    `json:"port"`
 	CollectionName string `json:"collection_name"`
 	APIKey         string `json:"api_key,omitempty"`
+	// DSN is a full Postgres connection string for the pgvector store; when
+	// empty one is built from Host, Port, Database and APIKey (the password)
+	DSN string `json:"dsn,omitempty"`
+	// Database is the Postgres database the pgvector store connects to
+	Database string `json:"database,omitempty"`
+	// TenantCollectionTemplate enables a collection per tenant, named by
+	// replacing "{id}" with the tenant ID (e.g. "tenant_{id}")
+	TenantCollectionTemplate string `json:"tenant_collection_template,omitempty"`
+	// AllowedTenants lists the tenant IDs requests may use; empty allows any
+	AllowedTenants []string `json:"allowed_tenants,omitempty"`
+	// MaxTenants caps how many tenant collections are opened; 0 means no cap
+	MaxTenants int `json:"max_tenants,omitempty"`
+	// CompressContent gzips chunk content in the payload to save storage
+	CompressContent bool `json:"compress_content,omitempty"`
+	// VectorFields embeds each listed chunk field as its own named vector
+	// ("body", "title" or a custom metadata key). Empty stores a single vector.
+	VectorFields []string `json:"vector_fields,omitempty"`
+	// DimensionPolicy decides what happens at startup when the collection's
+	// vector size differs from the embedding dimensions: "error", "recreate" or "adapt"
+	DimensionPolicy string `json:"dimension_policy,omitempty"`
+	// ConfirmRecreate must be set for the "recreate" policy to delete data
+	ConfirmRecreate bool `json:"confirm_recreate,omitempty"`
+	// DistanceMetric is the similarity metric new Qdrant collections are
+	// created with: "cosine" (default), "dot" or "euclid"
+	DistanceMetric string `json:"distance_metric,omitempty"`
+	// RecreateCollection drops the collection and all its chunks at startup,
+	// for a clean re-index
+	RecreateCollection bool `json:"recreate_collection,omitempty"`
+	// SoftDelete flags deleted documents instead of removing them, so they can be restored
+	SoftDelete bool `json:"soft_delete,omitempty"`
+	// SearchTimeoutSeconds is passed to Qdrant as the search timeout (0 = Qdrant's default)
+	SearchTimeoutSeconds int `json:"search_timeout_seconds,omitempty"`
+	// PartialResultsOnTimeout returns whatever a timed-out search found,
+	// flagged as partial, instead of an error
+	PartialResultsOnTimeout bool `json:"partial_results_on_timeout,omitempty"`
+	// FilterOverfetch is how many candidates per result each page from Qdrant
+	// holds when a search has filters it can't evaluate, so the store can
+	// apply them (0 = 10)
+	FilterOverfetch int `json:"filter_overfetch,omitempty"`
+	// PersistPath is the file the memory store loads at startup and saves to
+	// at shutdown; empty keeps it in memory only
+	PersistPath string `json:"persist_path,omitempty"`
 }
 
 // GenerateChunkID creates a deterministic numeric ID from document ID and chunk index
@@ -141,13 +561,151 @@ func GenerateChunkID(documentID string, chunkIndex int) uint64 {
 	return h.Sum64()
 }
 
+// NeighborChunkIDs computes the IDs of the chunks adjacent to the given chunk
+// from the deterministic ID scheme. Next is only omitted when the document's
+// total chunk count is known and the chunk is the last one.
+func NeighborChunkIDs(chunk DocumentChunk) (prev, next *uint64) {
+	if chunk.ChunkIndex > 0 {
+		id := GenerateChunkID(chunk.DocumentID, chunk.ChunkIndex-1)
+		prev = &id
+	}
+	if chunk.TotalChunks == 0 || chunk.ChunkIndex+1 < chunk.TotalChunks {
+		id := GenerateChunkID(chunk.DocumentID, chunk.ChunkIndex+1)
+		next = &id
+	}
+	return prev, next
+}
+
 // GenerationConfig represents configuration for response generation
 type GenerationConfig struct {
-	Provider    string  `json:"provider"` // "openai", "anthropic", "huggingface"
+	Provider    string  `json:"provider"` // "openai", "azure", "anthropic" or "mock"
 	Model       string  `json:"model"`
 	Temperature float64 `json:"temperature"`
 	MaxTokens   int     `json:"max_tokens"`
 	APIKey      string  `json:"api_key,omitempty"`
+	// AllowedModels lists the models a request may pick besides Model
+	AllowedModels []string `json:"allowed_models,omitempty"`
+	// MaxContextTokens caps the tokens of context chunks in the prompt; the
+	// best ranked chunks are kept and the first that doesn't fit is cut.
+	// 0 includes every chunk.
+	MaxContextTokens int `json:"max_context_tokens,omitempty"`
+	// PromptTemplate replaces the default RAG prompt. It is a text/template
+	// that must include {{.Context}} and {{.Query}}.
+	PromptTemplate string `json:"prompt_template,omitempty"`
+	// BaseURL points the "openai" provider at an OpenAI-compatible server,
+	// such as a proxy or self-hosted gateway, instead of api.openai.com
+	BaseURL string `json:"base_url,omitempty"`
+	// RetryOnContextLength retries once with the lowest-ranked half of the
+	// chunks dropped when the prompt exceeds the model's context window
+	RetryOnContextLength bool `json:"retry_on_context_length,omitempty"`
+	// DegradeOnRateLimit returns the retrieved chunks without an answer,
+	// instead of failing the request, when the LLM is rate limited
+	DegradeOnRateLimit bool `json:"degrade_on_rate_limit,omitempty"`
+	// MaxRetries retries transient provider failures, within the request's retry budget
+	MaxRetries   int `json:"max_retries,omitempty"`
+	RetryDelayMs int `json:"retry_delay_ms,omitempty"`
+	// EmptyAnswerFallback is answered when the model returns blank content;
+	// empty fails the request with ErrEmptyResponse instead
+	EmptyAnswerFallback string `json:"empty_answer_fallback,omitempty"`
+	// AnswerCacheSize caches up to this many generated answers; 0 disables the cache
+	AnswerCacheSize    int `json:"answer_cache_size,omitempty"`
+	AnswerCacheTTLSecs int `json:"answer_cache_ttl_secs,omitempty"`
+	// DeterministicCaching generates cacheable answers at temperature 0, so
+	// repeated and retried requests get byte-identical answers
+	DeterministicCaching bool `json:"deterministic_caching,omitempty"`
+	// Azure addresses the Azure OpenAI resource when Provider is "azure"
+	Azure AzureOpenAIConfig `json:"azure,omitempty"`
+	// LogProbs asks OpenAI for token log-probabilities, which feed the confidence score
+	LogProbs bool `json:"log_probs,omitempty"`
+	// Confidence configures the confidence score returned with RAG answers
+	Confidence ConfidenceConfig `json:"confidence,omitempty"`
+}
+
+// ConfidenceConfig weighs the signals blended into a RAG answer's confidence.
+// Each signal is between 0 and 1 and the score is their weighted average; the
+// log-probability signal is left out when the provider didn't report one.
+type ConfidenceConfig struct {
+	Enabled bool `json:"enabled"`
+	// ScoreWeight weighs the mean relevance score of the top TargetChunks chunks
+	ScoreWeight float64 `json:"score_weight"`
+	// CoverageWeight weighs how many chunks, up to TargetChunks, ground the answer
+	CoverageWeight float64 `json:"coverage_weight"`
+	// LogProbWeight weighs the answer's mean token probability
+	LogProbWeight float64 `json:"log_prob_weight"`
+	// TargetChunks is how many grounding chunks count as full coverage (<= 0 uses 3)
+	TargetChunks int `json:"target_chunks"`
+}
+
+// RankingConfig represents configuration for ranking retrieved chunks
+type RankingConfig struct {
+	Workers         int  `json:"workers"`           // number of goroutines used to score candidates; <= 1 scores sequentially
+	FallbackOnError bool `json:"fallback_on_error"` // fall back to keyword scoring when the reranker fails
+	// Mode is "keyword" (score by keywords or the reranker), "passthrough"
+	// (keep the vector store's scores), "blend" (mix both) or "rrf" (fuse both rankings)
+	Mode string `json:"mode"`
+	// BlendWeight is the share of the vector score in "blend" mode, from 0 to 1
+	BlendWeight float64 `json:"blend_weight"`
+	// RRFK is the rank constant in "rrf" mode; larger values flatten the
+	// advantage of top ranks (<= 0 uses 60)
+	RRFK float64 `json:"rrf_k"`
+	// RRFVectorWeight and RRFKeywordWeight scale each ranking's contribution in "rrf" mode
+	RRFVectorWeight  float64 `json:"rrf_vector_weight"`
+	RRFKeywordWeight float64 `json:"rrf_keyword_weight"`
+	// Scorer is the lexical scoring used without a reranker: "keyword"
+	// (default, share of query words found) or "bm25"
+	Scorer string `json:"scorer"`
+	// BM25K1 and BM25B tune term frequency saturation and document length
+	// normalization for "bm25" (<= 0 uses 1.2 and 0.75; config rejects 0)
+	BM25K1 float64 `json:"bm25_k1"`
+	BM25B  float64 `json:"bm25_b"`
+	// NegativeWeight is how strongly a request's negative_query demotes
+	// similar chunks, from 0 to 1 (0 uses 0.5)
+	NegativeWeight float64 `json:"negative_weight"`
+	// Reranker scores candidates with a cross-encoder instead of lexically:
+	// "none" (default) or "cohere"
+	Reranker string `json:"reranker,omitempty"`
+	// RerankerURL points the reranker at another endpoint with the same API,
+	// such as a self-hosted model; empty uses Cohere
+	RerankerURL    string `json:"reranker_url,omitempty"`
+	RerankerModel  string `json:"reranker_model,omitempty"`
+	RerankerAPIKey string `json:"reranker_api_key,omitempty"`
+}
+
+// RetrievalConfig represents configuration for retrieving chunks
+type RetrievalConfig struct {
+	NormalizeQuery bool `json:"normalize_query"` // trim, collapse whitespace and strip trailing punctuation
+	LowercaseQuery bool `json:"lowercase_query"` // also lowercase the query when normalizing
+	// SkipGenerationOnEmpty answers RAG requests with the fallback response
+	// immediately when retrieval finds nothing, instead of calling the LLM
+	SkipGenerationOnEmpty bool `json:"skip_generation_on_empty"`
+	// MaxDocumentChunks caps the chunks returned in one JSON response for a
+	// document; larger documents must be streamed as JSON lines. <= 0 is unlimited
+	MaxDocumentChunks int `json:"max_document_chunks"`
+	// DedupeAcrossDocuments collapses search results with the same content
+	// from different documents into the best-scored one
+	DedupeAcrossDocuments bool `json:"dedupe_across_documents"`
+	// ChunkCacheSize caches up to this many chunk and document reads, so hot
+	// chunks aren't fetched from the store every time; 0 disables the cache
+	ChunkCacheSize    int `json:"chunk_cache_size"`
+	ChunkCacheTTLSecs int `json:"chunk_cache_ttl_seconds"`
+}
+
+// ModerationConfig represents configuration for content moderation
+type ModerationConfig struct {
+	Provider string `json:"provider"` // "none" or "openai"
+	Model    string `json:"model,omitempty"`
+	APIKey   string `json:"api_key,omitempty"`
+	// Ingest and Generation are the actions for flagged ingested content and
+	// generated answers: "off", "reject" or "redact"
+	Ingest     string `json:"ingest"`
+	Generation string `json:"generation"`
+}
+
+// AuditConfig represents configuration for the audit log of mutating operations
+type AuditConfig struct {
+	Sink            string `json:"sink"`             // "none" or "file"
+	Path            string `json:"path,omitempty"`   // file sink location
+	PrincipalHeader string `json:"principal_header"` // request header identifying the authenticated caller
 }
 
 // DirectoryIngestRequest represents a request to ingest all files from a directory
@@ -156,6 +714,13 @@ type DirectoryIngestRequest struct {
 	Recursive     bool              `json:"recursive,omitempty"`
 	FilePattern   string            `json:"file_pattern,omitempty"` // e.g., "*.txt,*.md"
 	Metadata      Metadata          `json:"metadata,omitempty"`
+	// MaxFiles stops the scan after this many matching files; the server cap applies regardless
+	MaxFiles int `json:"max_files,omitempty"`
+	// BatchSize overrides the server's directory batch size for this request
+	BatchSize int `json:"batch_size,omitempty"`
+	// SummaryOnly leaves successful files out of the response, keeping only
+	// counts and errors, so memory doesn't grow with the size of the tree
+	SummaryOnly bool `json:"summary_only,omitempty"`
 }
 
 // DirectoryIngestResponse represents the response from directory ingestion
@@ -165,6 +730,22 @@ type DirectoryIngestResponse struct {
 	SuccessfulIngestions []IngestResponse `json:"successful_ingestions"`
 	Errors               []string         `json:"errors,omitempty"`
 	ProcessingTime       string           `json:"processing_time"`
+	// FileLimit and FileLimitReached report that the scan stopped early at the file limit
+	FileLimit        int  `json:"file_limit,omitempty"`
+	FileLimitReached bool `json:"file_limit_reached,omitempty"`
+	// SucceededFiles and FailedFiles count the outcomes, including any left
+	// out of the lists by summary_only
+	SucceededFiles int `json:"succeeded_files"`
+	FailedFiles    int `json:"failed_files"`
+	Batches        int `json:"batches"`
+}
+
+// DirectoryIngestProgress is reported after each batch of a directory ingest
+type DirectoryIngestProgress struct {
+	Batch          int // 1-based number of the batch just processed
+	BatchFiles     int // files in that batch
+	ProcessedFiles int // files processed so far
+	FailedFiles    int // files that failed so far
 }
 
 // FileIngestResult represents the result of ingesting a single file
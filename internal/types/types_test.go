@@ -0,0 +1,24 @@
+package types
+
+import "testing"
+
+func TestNeighborChunkIDs(t *testing.T) {
+	middle := DocumentChunk{DocumentID: "doc-1", ChunkIndex: 1, TotalChunks: 3}
+	prev, next := NeighborChunkIDs(middle)
+	if prev == nil || *prev != GenerateChunkID("doc-1", 0) {
+		t.Errorf("Expected prev to be chunk 0 of doc-1, got %v", prev)
+	}
+	if next == nil || *next != GenerateChunkID("doc-1", 2) {
+		t.Errorf("Expected next to be chunk 2 of doc-1, got %v", next)
+	}
+
+	first := DocumentChunk{DocumentID: "doc-1", ChunkIndex: 0, TotalChunks: 3}
+	if prev, _ := NeighborChunkIDs(first); prev != nil {
+		t.Errorf("Expected no prev for first chunk, got %d", *prev)
+	}
+
+	last := DocumentChunk{DocumentID: "doc-1", ChunkIndex: 2, TotalChunks: 3}
+	if _, next := NeighborChunkIDs(last); next != nil {
+		t.Errorf("Expected no next for last chunk, got %d", *next)
+	}
+}
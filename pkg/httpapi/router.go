@@ -1,22 +1,41 @@
 package httpapi
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"maps"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
+	"go-rag/internal/audit"
+	"go-rag/internal/cache"
 	"go-rag/internal/chunk"
 	"go-rag/internal/config"
 	"go-rag/internal/embedding"
 	"go-rag/internal/generate"
 	"go-rag/internal/ingest"
+	"go-rag/internal/jobs"
+	"go-rag/internal/moderation"
 	"go-rag/internal/ranker"
 	"go-rag/internal/retriever"
+	"go-rag/internal/retry"
 	"go-rag/internal/store"
+	"go-rag/internal/textutil"
 	"go-rag/internal/types"
 
 	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
 )
 
 // Handler contains all the service dependencies
@@ -26,74 +45,446 @@ type Handler struct {
 	rankerService    *ranker.Service
 	generateService  generate.GenerationService
 	vectorStore      store.VectorStore
+	config           *config.Config
+	chunker          *chunk.Service
+	tenantRouter     *store.TenantRouter
+	auditLogger      audit.Logger
+	jobManager       *jobs.Manager
+	moderator        moderation.Moderator
+	// embeddings holds each collection's embedding model, for tenant retrievers
+	embeddings *embedding.Registry
 }
 
+// tenantHeader carries the tenant ID when per-tenant collections are enabled
+const tenantHeader = "X-Tenant-ID"
+
+// Context keys for the services resolved for the request's tenant
+const (
+	ingestServiceKey    = "ingest_service"
+	retrieverServiceKey = "retriever_service"
+)
+
 // NewHandler creates a new HTTP handler with all dependencies
-func NewHandler(cfg *config.Config) *Handler {
-	// Initialize embedding service
-	embeddingService, err := embedding.NewService(cfg.Embedding)
+func NewHandler(cfg *config.Config) (*Handler, error) {
+	// Initialize embedding services, one per collection with its own model
+	embeddings, err := embedding.NewRegistry(cfg.Embedding, cfg.CollectionEmbeddings)
 	if err != nil {
-		panic(fmt.Sprintf("Failed to create embedding service: %v", err))
+		return nil, fmt.Errorf("failed to create embedding service: %w", err)
 	}
+	embeddingService := embeddings.For(cfg.VectorStore.CollectionName)
 
 	// Initialize services with configuration
 	chunker := chunk.NewService(cfg.Chunking.ChunkSize, cfg.Chunking.ChunkOverlap)
-	vectorStore, err := store.NewQdrantStore(cfg.VectorStore, embeddingService)
+	if cfg.Chunking.Unit == chunk.UnitTokens {
+		chunker = chunk.NewServiceWithTokenizer(cfg.Chunking.ChunkSize, cfg.Chunking.ChunkOverlap, chunk.ApproximateTokenizer{})
+	}
+	if len(cfg.Chunking.Separators) > 0 {
+		chunker = chunker.WithSeparators(cfg.Chunking.Separators)
+	}
+	vectorStore, err := store.NewStore(cfg.VectorStore, embeddingService)
 	if err != nil {
-		panic(fmt.Sprintf("Failed to create vector store: %v", err))
+		return nil, fmt.Errorf("failed to create vector store: %w", err)
+	}
+
+	// Start from an empty collection for a clean re-index when asked
+	if cfg.VectorStore.RecreateCollection {
+		dropper, ok := vectorStore.(store.CollectionDropper)
+		if !ok {
+			return nil, fmt.Errorf("RECREATE_COLLECTION is not supported by the %s vector store", cfg.VectorStore.Provider)
+		}
+		log.Printf("Dropping collection %s to recreate it", cfg.VectorStore.CollectionName)
+		if err := dropper.DropCollection(context.Background()); err != nil {
+			return nil, fmt.Errorf("failed to drop vector store collection: %w", err)
+		}
+	}
+
+	// Catch a collection created with different embedding dimensions now rather than at first upsert
+	qdrantStore, isQdrant := vectorStore.(*store.QdrantStore)
+	if isQdrant {
+		qdrantStore, err = qdrantStore.ReconcileDimensions(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify vector store dimensions: %w", err)
+		}
+		vectorStore = qdrantStore
+	}
+
+	// Create the collection so the first ingest into a fresh store succeeds. Like
+	// the dimension check, this is best effort if the store isn't up yet.
+	if err := vectorStore.CreateCollection(context.Background(), embeddingService.GetDimensions()); err != nil {
+		log.Printf("Could not create collection %s at startup: %v", cfg.VectorStore.CollectionName, err)
 	}
 
 	// Initialize generation service
 	generateService, err := generate.NewService(cfg.Generation)
 	if err != nil {
-		panic(fmt.Sprintf("Failed to create generation service: %v", err))
+		return nil, fmt.Errorf("failed to create generation service: %w", err)
+	}
+
+	if cfg.Server.Warmup {
+		runWarmup(embeddingService, generateService)
+	}
+
+	// Record mutating operations for compliance when configured
+	auditLogger, err := audit.NewLogger(cfg.Audit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audit logger: %w", err)
 	}
 
+	// Run long maintenance operations in the background
+	jobManager, err := jobs.NewManager(cfg.Server.JobStatePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job manager: %w", err)
+	}
+	jobManager.SetRetention(time.Duration(cfg.Server.JobRetentionHours)*time.Hour, cfg.Server.JobMaxFinished)
+
+	// Route each tenant to its own collection when configured
+	var tenantRouter *store.TenantRouter
+	if cfg.VectorStore.TenantCollectionTemplate != "" {
+		if !isQdrant {
+			return nil, fmt.Errorf("tenant collections require the qdrant vector store")
+		}
+		tenantRouter, err = store.NewTenantRouter(cfg.VectorStore.TenantCollectionTemplate, func(ctx context.Context, collection string) (store.VectorStore, error) {
+			tenantEmbeddings := embeddings.For(collection)
+			tenantStore := qdrantStore.WithCollection(collection).WithEmbeddingService(tenantEmbeddings)
+			if err := tenantStore.CheckDimensions(ctx); err != nil {
+				return nil, err
+			}
+			if err := tenantStore.CreateCollection(ctx, tenantEmbeddings.GetDimensions()); err != nil {
+				return nil, err
+			}
+			return tenantStore, nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create tenant router: %w", err)
+		}
+		tenantRouter.SetAllowedTenants(cfg.VectorStore.AllowedTenants)
+		tenantRouter.SetMaxTenants(cfg.VectorStore.MaxTenants)
+	}
+
+	// Screen ingested content and generated answers when configured
+	moderator, err := moderation.NewModerator(cfg.Moderation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create moderator: %w", err)
+	}
+
+	reranker, err := ranker.NewReranker(cfg.Ranking)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reranker: %w", err)
+	}
+
+	// Writes through the ingest service keep the retriever's chunk cache fresh
+	retrieverService := retriever.NewService(vectorStore, cfg.Retrieval).WithEmbeddingService(embeddingService)
+	ingestService := ingest.NewService(*chunker, vectorStore, cfg.Chunking)
+	ingestService.SetAuditLogger(auditLogger)
+	ingestService.SetModerator(moderator, cfg.Moderation.Ingest)
+	ingestService.SetUploadLimit(int64(uploadLimitMB(cfg)) << 20)
+	ingestService.OnDocumentChanged(retrieverService.InvalidateDocument)
+
 	return &Handler{
-		ingestService:    ingest.NewService(*chunker, vectorStore),
-		retrieverService: retriever.NewService(vectorStore),
-		rankerService:    ranker.NewService(),
+		ingestService:    ingestService,
+		retrieverService: retrieverService,
+		rankerService:    ranker.NewServiceWithReranker(cfg.Ranking, reranker),
 		generateService:  generateService,
 		vectorStore:      vectorStore,
-	}
+		config:           cfg,
+		chunker:          chunker,
+		tenantRouter:     tenantRouter,
+		auditLogger:      auditLogger,
+		jobManager:       jobManager,
+		moderator:        moderator,
+		embeddings:       embeddings,
+	}, nil
 }
 
-// SetupRoutes configures all API routes
-func SetupRoutes(router *gin.Engine, cfg *config.Config) {
-	handler := NewHandler(cfg)
+// SetupRoutes configures all API routes and returns the handler serving them,
+// which must be closed on shutdown
+func SetupRoutes(router *gin.Engine, cfg *config.Config) (*Handler, error) {
+	handler, err := NewHandler(cfg)
+	if err != nil {
+		return nil, err
+	}
 
 	// Health check
 	router.GET("/health", handler.HealthCheck)
+	router.GET("/metrics", handler.Metrics)
 
 	// API v1 routes
 	v1 := router.Group("/api/v1")
+	v1.Use(handler.AttachPrincipal, handler.AttachCacheBypass)
+	if cfg.Server.RetryBudget > 0 {
+		v1.Use(handler.AttachRetryBudget)
+	}
+	if handler.tenantRouter != nil {
+		v1.Use(handler.ResolveTenant)
+	}
 	{
 		// Document ingestion
 		v1.POST("/ingest", handler.IngestDocument)
 		v1.POST("/ingest/directory", handler.IngestDirectory)
+		v1.POST("/ingest/json", handler.IngestJSON)
+		v1.POST("/ingest/vectors", handler.IngestVectors)
+		v1.POST("/ingest/file", handler.IngestFile)
+		v1.PUT("/documents/:id", handler.UpdateDocument)
 		v1.DELETE("/documents/:id", handler.DeleteDocument)
+		v1.POST("/documents/:id/restore", handler.RestoreDocument)
 
 		// Search and retrieval
 		v1.POST("/search", handler.SearchDocuments)
+		v1.GET("/stats", handler.GetStats)
 		v1.GET("/documents/:id/chunks", handler.GetDocumentChunks)
+		v1.GET("/documents/:id/content", handler.GetDocumentContent)
 		v1.GET("/chunks/:id", handler.GetChunk)
 
+		// Background jobs
+		v1.POST("/jobs/reindex", handler.StartReindex)
+		v1.GET("/jobs/:id", handler.GetJob)
+		v1.DELETE("/jobs/:id", handler.CancelJob)
+
 		// RAG endpoint
 		v1.POST("/rag", handler.RAGQuery)
+		v1.POST("/rag/stream", handler.RAGStream)
+
+		// Streaming ingestion
+		if cfg.Server.EnableWebsocketIngest {
+			v1.GET("/ws/ingest", handler.StreamIngest)
+		}
+	}
+
+	return handler, nil
+}
+
+// AttachPrincipal is middleware that puts the caller identified by the
+// configured principal header on the request context for the audit log
+func (h *Handler) AttachPrincipal(c *gin.Context) {
+	header := h.config.Audit.PrincipalHeader
+	if principal := c.GetHeader(header); header != "" && principal != "" {
+		c.Request = c.Request.WithContext(audit.WithPrincipal(c.Request.Context(), principal))
+	}
+	c.Next()
+}
+
+// noCacheHeader forces fresh embeddings and results for any request
+const noCacheHeader = "X-No-Cache"
+
+// AttachCacheBypass is middleware that skips the embedding and result caches
+// when the request sets the X-No-Cache header
+func (h *Handler) AttachCacheBypass(c *gin.Context) {
+	if noCache, _ := strconv.ParseBool(c.GetHeader(noCacheHeader)); noCache {
+		bypassCache(c)
+	}
+	c.Next()
+}
+
+// bypassCache makes the rest of the request skip cached embeddings and results
+func bypassCache(c *gin.Context) {
+	c.Request = c.Request.WithContext(cache.WithBypass(c.Request.Context()))
+}
+
+// AttachRetryBudget is middleware that gives each request a shared budget of
+// provider retries, so retries across embedding and generation stay bounded
+func (h *Handler) AttachRetryBudget(c *gin.Context) {
+	budget := retry.NewBudget(h.config.Server.RetryBudget)
+	c.Request = c.Request.WithContext(retry.WithBudget(c.Request.Context(), budget))
+	c.Next()
+}
+
+// ResolveTenant is middleware that routes the request to the collection of the
+// tenant named in the X-Tenant-ID header
+func (h *Handler) ResolveTenant(c *gin.Context) {
+	tenantID := c.GetHeader(tenantHeader)
+	if tenantID == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "missing_tenant",
+			Code:    http.StatusBadRequest,
+			Message: tenantHeader + " header is required",
+		})
+		return
+	}
+
+	tenantStore, err := h.tenantRouter.StoreFor(c.Request.Context(), tenantID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, store.ErrInvalidTenantID):
+			status = http.StatusBadRequest
+		case errors.Is(err, store.ErrUnknownTenant):
+			status = http.StatusForbidden
+		case errors.Is(err, store.ErrTooManyTenants):
+			status = http.StatusServiceUnavailable
+		}
+		c.AbortWithStatusJSON(status, types.ErrorResponse{
+			Error:   "tenant_resolution_failed",
+			Code:    status,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.Set(ingestServiceKey, h.ingestService.WithStore(tenantStore))
+	// A retriever that lives for one request would never reuse its chunk cache
+	tenantRetrieval := h.config.Retrieval
+	tenantRetrieval.ChunkCacheSize = 0
+	tenantRetriever := retriever.NewService(tenantStore, tenantRetrieval)
+	if collection, err := h.tenantRouter.CollectionName(tenantID); err == nil && h.embeddings != nil {
+		tenantRetriever = tenantRetriever.WithEmbeddingService(h.embeddings.For(collection))
+	}
+	c.Set(retrieverServiceKey, tenantRetriever)
+	c.Next()
+}
+
+// ingestFor returns the ingest service for the request's tenant, or the default one
+func (h *Handler) ingestFor(c *gin.Context) *ingest.Service {
+	if service, exists := c.Get(ingestServiceKey); exists {
+		return service.(*ingest.Service)
+	}
+	return h.ingestService
+}
+
+// retrieverFor returns the retriever service for the request's tenant, or the default one
+func (h *Handler) retrieverFor(c *gin.Context) *retriever.Service {
+	if service, exists := c.Get(retrieverServiceKey); exists {
+		return service.(*retriever.Service)
+	}
+	return h.retrieverService
+}
+
+// validateVectorName checks that a requested vector name is one of the configured vector fields
+func (h *Handler) validateVectorName(vectorName string) error {
+	if vectorName == "" {
+		return nil
+	}
+	for _, field := range h.config.VectorStore.VectorFields {
+		if field == vectorName {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown vector_name: %s", vectorName)
+}
+
+// GetStats reports how many chunks and documents the collection holds and
+// the size and metric of its vectors
+func (h *Handler) GetStats(c *gin.Context) {
+	stats, err := h.retrieverFor(c).Stats(c.Request.Context())
+	if errors.Is(err, retriever.ErrStatsUnsupported) {
+		c.JSON(http.StatusNotImplemented, types.ErrorResponse{
+			Error:   "not_supported",
+			Code:    http.StatusNotImplemented,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{
+			Error:   "stats_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// StartReindex starts a background job that re-embeds documents
+func (h *Handler) StartReindex(c *gin.Context) {
+	var req types.ReindexRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, types.ErrorResponse{
+				Error:   "invalid_request",
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	// Resolve the service now; the job outlives the request
+	ingestService := h.ingestFor(c)
+	job := h.jobManager.Start("reindex", func(ctx context.Context, progress func(done, total int)) error {
+		return ingestService.ReindexDocuments(ctx, req.DocumentIDs, progress)
+	})
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetJob reports the status of a background job
+func (h *Handler) GetJob(c *gin.Context) {
+	job, err := h.jobManager.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, types.ErrorResponse{
+			Error:   "job_not_found",
+			Code:    http.StatusNotFound,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// CancelJob cancels a running background job
+func (h *Handler) CancelJob(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := h.jobManager.Cancel(id); err != nil {
+		status := http.StatusNotFound
+		errorCode := "job_not_found"
+		if errors.Is(err, jobs.ErrJobFinished) {
+			status = http.StatusConflict
+			errorCode = "job_finished"
+		}
+		c.JSON(status, types.ErrorResponse{
+			Error:   errorCode,
+			Code:    status,
+			Message: err.Error(),
+		})
+		return
 	}
+
+	c.JSON(http.StatusAccepted, gin.H{"status": "cancelling", "job_id": id})
 }
 
-// HealthCheck checks the health of all services
+// healthCheckTimeout bounds how long the health endpoint waits for the vector store
+const healthCheckTimeout = 5 * time.Second
+
+// HealthCheck checks the health of all services, answering 503 when the
+// vector store can't be reached
 func (h *Handler) HealthCheck(c *gin.Context) {
 	response := types.HealthCheckResponse{
 		Status:    "healthy",
 		Timestamp: time.Now(),
 		Services: map[string]string{
 			"api":          "healthy",
-			"vector_store": "healthy", // TODO: implement actual health check
+			"vector_store": "healthy",
 		},
 	}
 
+	ctx, cancel := context.WithTimeout(c.Request.Context(), healthCheckTimeout)
+	defer cancel()
+	if err := h.vectorStore.HealthCheck(ctx); err != nil {
+		response.Status = "unhealthy"
+		response.Services["vector_store"] = "unhealthy"
+		response.Services["vector_store_error"] = err.Error()
+		c.JSON(http.StatusServiceUnavailable, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Metrics reports the hit and miss counters of the enabled caches
+func (h *Handler) Metrics(c *gin.Context) {
+	response := types.MetricsResponse{Caches: map[string]types.CacheStats{}}
+	if stats, ok := h.retrieverService.CacheStats(); ok {
+		response.Caches["chunks"] = stats
+	}
+	if h.embeddings != nil {
+		if stats, ok := h.embeddings.CacheStats(); ok {
+			response.Caches["embeddings"] = stats
+		}
+	}
+
 	c.JSON(http.StatusOK, response)
 }
 
@@ -109,9 +500,25 @@ func (h *Handler) IngestDocument(c *gin.Context) {
 		return
 	}
 
+	if req.NoCache {
+		bypassCache(c)
+	}
+
 	start := time.Now()
 
-	chunksCount, err := h.ingestService.IngestText(c.Request.Context(), req.DocumentID, req.Content)
+	chunksCount, err := h.ingestFor(c).IngestTextWithOptions(c.Request.Context(), req.DocumentID, req.Content, req.Metadata, req.ChunkingOptions)
+	if errors.Is(err, ingest.ErrInvalidChunking) {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, moderation.ErrFlagged) {
+		respondFlagged(c, err)
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, types.ErrorResponse{
 			Error:   "ingestion_failed",
@@ -131,26 +538,12 @@ func (h *Handler) IngestDocument(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
-// DeleteDocument handles document deletion requests
-func (h *Handler) DeleteDocument(c *gin.Context) {
+// UpdateDocument handles replacing a document's content, removing every chunk
+// of the previous version
+func (h *Handler) UpdateDocument(c *gin.Context) {
 	documentID := c.Param("id")
 
-	err := h.ingestService.DeleteDocument(c.Request.Context(), documentID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.ErrorResponse{
-			Error:   "deletion_failed",
-			Code:    http.StatusInternalServerError,
-			Message: err.Error(),
-		})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"status": "deleted", "document_id": documentID})
-}
-
-// IngestDirectory handles directory ingestion requests
-func (h *Handler) IngestDirectory(c *gin.Context) {
-	var req types.DirectoryIngestRequest
+	var req types.UpdateDocumentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, types.ErrorResponse{
 			Error:   "invalid_request",
@@ -160,181 +553,859 @@ func (h *Handler) IngestDirectory(c *gin.Context) {
 		return
 	}
 
-	// Validate directory path
-	if req.DirectoryPath == "" {
+	if req.NoCache {
+		bypassCache(c)
+	}
+
+	start := time.Now()
+
+	chunksCount, err := h.ingestFor(c).UpdateText(c.Request.Context(), documentID, req.Content, req.Metadata, req.ChunkingOptions)
+	if errors.Is(err, ingest.ErrInvalidChunking) {
 		c.JSON(http.StatusBadRequest, types.ErrorResponse{
 			Error:   "invalid_request",
 			Code:    http.StatusBadRequest,
-			Message: "directory_path is required",
+			Message: err.Error(),
 		})
 		return
 	}
-
-	start := time.Now()
-
-	result, err := h.ingestService.IngestDirectory(c.Request.Context(), req)
+	if errors.Is(err, moderation.ErrFlagged) {
+		respondFlagged(c, err)
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, types.ErrorResponse{
-			Error:   "directory_ingestion_failed",
+			Error:   "update_failed",
 			Code:    http.StatusInternalServerError,
 			Message: err.Error(),
 		})
 		return
 	}
 
-	// Update processing time
-	result.ProcessingTime = time.Since(start).String()
+	response := types.IngestResponse{
+		DocumentID:     documentID,
+		ChunksCount:    chunksCount,
+		Status:         "success",
+		ProcessingTime: time.Since(start).String(),
+	}
 
-	c.JSON(http.StatusOK, result)
+	c.JSON(http.StatusOK, response)
 }
 
-// SearchDocuments handles search requests
-func (h *Handler) SearchDocuments(c *gin.Context) {
-	var req types.SearchRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, types.ErrorResponse{
-			Error:   "invalid_request",
-			Code:    http.StatusBadRequest,
-			Message: err.Error(),
-		})
-		return
+// StreamIngest accepts batches of documents over a websocket and streams back
+// one event per document as it completes. Each batch is followed by a
+// "done" event. Closing the connection cancels any in-flight ingestion.
+func (h *Handler) StreamIngest(c *gin.Context) {
+	server := websocket.Server{
+		Handshake: func(_ *websocket.Config, r *http.Request) error {
+			return checkWebsocketOrigin(r, h.config.Server.WebsocketAllowedOrigins)
+		},
+		Handler: func(ws *websocket.Conn) {
+			ctx, cancel := context.WithCancel(c.Request.Context())
+			defer cancel()
+
+			// Read batches in the background so a disconnect is noticed mid-batch
+			batches := make(chan types.BatchIngestRequest)
+			go func() {
+				defer close(batches)
+				defer cancel()
+				for {
+					var batch types.BatchIngestRequest
+					if err := websocket.JSON.Receive(ws, &batch); err != nil {
+						return
+					}
+					select {
+					case batches <- batch:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+
+			for batch := range batches {
+				err := h.ingestFor(c).IngestBatch(ctx, batch.Documents, func(event types.IngestEvent) {
+					if err := websocket.JSON.Send(ws, event); err != nil {
+						cancel()
+					}
+				})
+				if err != nil {
+					log.Printf("Streaming ingest aborted: %v", err)
+					return
+				}
+				if err := websocket.JSON.Send(ws, types.IngestEvent{Status: "done"}); err != nil {
+					return
+				}
+			}
+		},
 	}
 
-	if req.Limit <= 0 {
-		req.Limit = 10
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// checkWebsocketOrigin refuses websocket upgrades that a browser made from a
+// page on another site, unless its origin is allowed. Clients other than
+// browsers send no Origin header and are accepted.
+func checkWebsocketOrigin(r *http.Request, allowedOrigins []string) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" || slices.Contains(allowedOrigins, origin) {
+		return nil
 	}
+	if parsed, err := url.Parse(origin); err == nil && strings.EqualFold(parsed.Host, r.Host) {
+		return nil
+	}
+	return fmt.Errorf("websocket origin %q is not allowed", origin)
+}
 
-	// Retrieve relevant chunks
-	chunks, err := h.retrieverService.RetrieveRelevantChunks(c.Request.Context(), req.Query, req.Limit)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.ErrorResponse{
-			Error:   "search_failed",
-			Code:    http.StatusInternalServerError,
+// DeleteDocument handles document deletion requests
+func (h *Handler) DeleteDocument(c *gin.Context) {
+	documentID := c.Param("id")
+
+	// purge=true deletes permanently even when soft delete is enabled
+	purge := c.Query("purge") == "true"
+
+	var err error
+	if purge {
+		err = h.ingestFor(c).PurgeDocument(c.Request.Context(), documentID)
+	} else {
+		err = h.ingestFor(c).DeleteDocument(c.Request.Context(), documentID)
+	}
+	if errors.Is(err, ingest.ErrDocumentNotFound) {
+		c.JSON(http.StatusNotFound, types.ErrorResponse{
+			Error:   "document_not_found",
+			Code:    http.StatusNotFound,
 			Message: err.Error(),
 		})
 		return
 	}
-
-	// Rank chunks
-	rankedChunks, err := h.rankerService.RankChunks(c.Request.Context(), req.Query, chunks)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, types.ErrorResponse{
-			Error:   "ranking_failed",
+			Error:   "deletion_failed",
 			Code:    http.StatusInternalServerError,
 			Message: err.Error(),
 		})
 		return
 	}
 
-	// Apply threshold filter if specified
-	if req.Threshold > 0 {
-		rankedChunks = h.rankerService.FilterByThreshold(rankedChunks, req.Threshold)
-	}
-
-	response := types.SearchResponse{
-		Query:   req.Query,
-		Results: rankedChunks,
-		Total:   len(rankedChunks),
+	status := "deleted"
+	if purge {
+		status = "purged"
 	}
-
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, gin.H{"status": status, "document_id": documentID})
 }
 
-// GetDocumentChunks retrieves all chunks for a specific document
-func (h *Handler) GetDocumentChunks(c *gin.Context) {
+// RestoreDocument handles restoring a soft-deleted document
+func (h *Handler) RestoreDocument(c *gin.Context) {
 	documentID := c.Param("id")
 
-	chunks, err := h.retrieverService.RetrieveByDocumentID(c.Request.Context(), documentID)
+	err := h.ingestFor(c).RestoreDocument(c.Request.Context(), documentID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, types.ErrorResponse{
-			Error:   "retrieval_failed",
+			Error:   "restore_failed",
 			Code:    http.StatusInternalServerError,
 			Message: err.Error(),
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"document_id": documentID,
-		"chunks":      chunks,
-		"total":       len(chunks),
-	})
+	c.JSON(http.StatusOK, gin.H{"status": "restored", "document_id": documentID})
 }
 
-// GetChunk retrieves a specific chunk by ID
-func (h *Handler) GetChunk(c *gin.Context) {
-	chunkIDStr := c.Param("id")
-
-	chunkID, err := strconv.ParseUint(chunkIDStr, 10, 64)
-	if err != nil {
+// IngestDirectory handles directory ingestion requests
+func (h *Handler) IngestDirectory(c *gin.Context) {
+	var req types.DirectoryIngestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, types.ErrorResponse{
-			Error:   "invalid_chunk_id",
+			Error:   "invalid_request",
 			Code:    http.StatusBadRequest,
-			Message: "chunk ID must be a valid number",
+			Message: err.Error(),
 		})
 		return
 	}
 
-	chunk, err := h.retrieverService.RetrieveChunkByID(c.Request.Context(), chunkID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, types.ErrorResponse{
-			Error:   "chunk_not_found",
-			Code:    http.StatusNotFound,
-			Message: err.Error(),
+	// Validate directory path
+	if req.DirectoryPath == "" {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: "directory_path is required",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, chunk)
-}
-
-// RAGQuery handles complete RAG (Retrieve-Augment-Generate) requests
-func (h *Handler) RAGQuery(c *gin.Context) {
-	var req types.RAGRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if req.MaxFiles < 0 {
 		c.JSON(http.StatusBadRequest, types.ErrorResponse{
 			Error:   "invalid_request",
 			Code:    http.StatusBadRequest,
-			Message: err.Error(),
+			Message: "max_files cannot be negative",
 		})
 		return
 	}
 
-	start := time.Now()
-
+	if req.BatchSize < 0 {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: "batch_size cannot be negative",
+		})
+		return
+	}
+
+	start := time.Now()
+
+	result, err := h.ingestFor(c).IngestDirectoryWithProgress(c.Request.Context(), req, func(p types.DirectoryIngestProgress) {
+		log.Printf("Directory ingest %s: batch %d done (%d files), %d processed, %d failed",
+			req.DirectoryPath, p.Batch, p.BatchFiles, p.ProcessedFiles, p.FailedFiles)
+	})
+	if err != nil {
+		status := directoryErrorStatus(err)
+		c.JSON(status, types.ErrorResponse{
+			Error:   "directory_ingestion_failed",
+			Code:    status,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Update processing time
+	result.ProcessingTime = time.Since(start).String()
+
+	c.JSON(http.StatusOK, result)
+}
+
+// directoryErrorStatus maps a directory scan failure to an HTTP status, so a
+// missing or unreadable path isn't reported as a server error
+func directoryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return http.StatusNotFound
+	case errors.Is(err, fs.ErrPermission):
+		return http.StatusForbidden
+	case errors.Is(err, ingest.ErrNotDirectory), errors.Is(err, filepath.ErrBadPattern):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// IngestJSON handles ingestion of structured JSON records
+func (h *Handler) IngestJSON(c *gin.Context) {
+	var req types.JSONIngestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if req.NoCache {
+		bypassCache(c)
+	}
+
+	start := time.Now()
+
+	events, err := h.ingestFor(c).IngestJSON(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "json_ingestion_failed",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.JSONIngestResponse{
+		Documents:      events,
+		ProcessingTime: time.Since(start).String(),
+	})
+}
+
+// IngestVectors stores a document whose chunks were embedded by an external pipeline
+func (h *Handler) IngestVectors(c *gin.Context) {
+	var req types.VectorIngestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	start := time.Now()
+
+	chunksCount, err := h.ingestFor(c).IngestChunksWithVectors(c.Request.Context(), req)
+	if errors.Is(err, ingest.ErrInvalidVectors) || errors.Is(err, store.ErrDimensionMismatch) {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, moderation.ErrFlagged) {
+		respondFlagged(c, err)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{
+			Error:   "ingestion_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.IngestResponse{
+		DocumentID:     req.DocumentID,
+		ChunksCount:    chunksCount,
+		Status:         "success",
+		ProcessingTime: time.Since(start).String(),
+	})
+}
+
+// defaultMaxUploadMB caps file uploads when no limit is configured
+const defaultMaxUploadMB = 10
+
+// uploadLimitMB is the configured upload limit in megabytes
+func uploadLimitMB(cfg *config.Config) int {
+	if cfg.Server.MaxUploadMB <= 0 {
+		return defaultMaxUploadMB
+	}
+	return cfg.Server.MaxUploadMB
+}
+
+// uploadFormFields are the file upload form fields that aren't metadata
+var uploadFormFields = []string{"document_id", "no_cache", "strategy", "chunk_size", "chunk_overlap"}
+
+// IngestFile ingests a document uploaded as multipart/form-data. The "file"
+// part holds the document; "document_id", "no_cache", "strategy",
+// "chunk_size" and "chunk_overlap" fields work as in JSON ingestion, and any
+// other field is metadata.
+func (h *Handler) IngestFile(c *gin.Context) {
+	if c.ContentType() != "multipart/form-data" {
+		c.JSON(http.StatusUnsupportedMediaType, types.ErrorResponse{
+			Error:   "unsupported_media_type",
+			Code:    http.StatusUnsupportedMediaType,
+			Message: "upload the file as multipart/form-data",
+		})
+		return
+	}
+
+	maxUploadMB := uploadLimitMB(h.config)
+	limit := int64(maxUploadMB) << 20
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) || c.Request.ContentLength > limit {
+			c.JSON(http.StatusRequestEntityTooLarge, types.ErrorResponse{
+				Error:   "file_too_large",
+				Code:    http.StatusRequestEntityTooLarge,
+				Message: fmt.Sprintf("uploads are limited to %d MB", maxUploadMB),
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("a file is required in the \"file\" field: %v", err),
+		})
+		return
+	}
+
+	data, err := readUpload(header)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	form := maps.Clone(c.Request.MultipartForm.Value)
+	opts, err := uploadChunkingOptions(form)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if noCache, _ := strconv.ParseBool(c.PostForm("no_cache")); noCache {
+		bypassCache(c)
+	}
+	for _, field := range uploadFormFields {
+		delete(form, field)
+	}
+
+	start := time.Now()
+
+	docID, chunksCount, err := h.ingestFor(c).IngestFile(c.Request.Context(), c.PostForm("document_id"), header.Filename, data, ingest.FormMetadata(form), opts)
+	if errors.Is(err, ingest.ErrUnsupportedFileType) {
+		c.JSON(http.StatusUnsupportedMediaType, types.ErrorResponse{
+			Error:   "unsupported_media_type",
+			Code:    http.StatusUnsupportedMediaType,
+			Message: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ingest.ErrFileTooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, types.ErrorResponse{
+			Error:   "file_too_large",
+			Code:    http.StatusRequestEntityTooLarge,
+			Message: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ingest.ErrInvalidChunking) || errors.Is(err, ingest.ErrEmptyFile) {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, moderation.ErrFlagged) {
+		respondFlagged(c, err)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{
+			Error:   "ingestion_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.IngestResponse{
+		DocumentID:     docID,
+		ChunksCount:    chunksCount,
+		Status:         "success",
+		ProcessingTime: time.Since(start).String(),
+	})
+}
+
+// readUpload reads an uploaded file into memory
+func readUpload(header *multipart.FileHeader) ([]byte, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open upload: %w", err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	return data, nil
+}
+
+// uploadChunkingOptions reads the chunking overrides of a file upload form
+func uploadChunkingOptions(form map[string][]string) (types.ChunkingOptions, error) {
+	value := func(field string) string {
+		if values := form[field]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+
+	opts := types.ChunkingOptions{Strategy: value("strategy")}
+	if size := value("chunk_size"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil {
+			return opts, fmt.Errorf("chunk_size must be a number, got %q", size)
+		}
+		opts.ChunkSize = n
+	}
+	if overlap := value("chunk_overlap"); overlap != "" {
+		n, err := strconv.Atoi(overlap)
+		if err != nil {
+			return opts, fmt.Errorf("chunk_overlap must be a number, got %q", overlap)
+		}
+		opts.ChunkOverlap = &n
+	}
+	return opts, nil
+}
+
+// SearchDocuments handles search requests
+func (h *Handler) SearchDocuments(c *gin.Context) {
+	var req types.SearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if req.NoCache {
+		bypassCache(c)
+	}
+
 	if req.Limit <= 0 {
-		req.Limit = 5 // Default for RAG
+		req.Limit = 10
+	}
+
+	if req.MaxContentLength < 0 {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: "max_content_length must not be negative",
+		})
+		return
+	}
+
+	if req.RerankTopK < 0 {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: "rerank_top_k must not be negative",
+		})
+		return
+	}
+
+	if req.MMRLambda != nil && (*req.MMRLambda < 0 || *req.MMRLambda > 1) {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: "mmr_lambda must be between 0 and 1",
+		})
+		return
+	}
+
+	if err := h.validateVectorName(req.VectorName); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := store.ValidateFilters(req.Filters); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "invalid_filter",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	boosts, err := ranker.ParseBoosts(req.Boosts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "invalid_boost",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := ranker.ValidateFacets(req.Facets); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "invalid_facet",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if req.Diagnostics && !h.config.Server.SearchDiagnostics {
+		c.JSON(http.StatusForbidden, types.ErrorResponse{
+			Error:   "diagnostics_disabled",
+			Code:    http.StatusForbidden,
+			Message: "search diagnostics are disabled; set SEARCH_DIAGNOSTICS_ENABLED=true to allow them",
+		})
+		return
+	}
+
+	// Retrieve relevant chunks
+	var chunks []types.DocumentChunk
+	var diagnostics *types.SearchDiagnostics
+	if req.Diagnostics {
+		chunks, diagnostics, err = h.retrieverFor(c).RetrieveWithDiagnostics(c.Request.Context(), req.Query, req.VectorName, req.Limit, req.Filters)
+	} else {
+		chunks, err = h.retrieverFor(c).RetrieveFromVector(c.Request.Context(), req.Query, req.VectorName, req.Limit, req.Filters)
+	}
+	partial := errors.Is(err, store.ErrPartialResults)
+	if err != nil && !partial {
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{
+			Error:   "search_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Rank chunks
+	rank := h.rankerService.RankChunks
+	if req.Hybrid {
+		rank = h.rankerService.RankHybrid
+	}
+	if req.Rerank != nil && !*req.Rerank {
+		rank = h.rankerService.RankByVector
+	}
+	rankedChunks, err := rank(c.Request.Context(), req.Query, chunks)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{
+			Error:   "ranking_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	rankedChunks = h.rankerService.ApplyBoosts(rankedChunks, boosts)
+
+	if req.NegativeQuery != "" && len(rankedChunks) > 0 {
+		rankedChunks, err = h.demote(c, req.NegativeQuery, rankedChunks)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, types.ErrorResponse{
+				Error:   "ranking_failed",
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	// Facets count the whole candidate set, before the threshold drops any
+	var facets map[string][]types.FacetCount
+	if len(req.Facets) > 0 {
+		facets = ranker.CountFacets(rankedChunks, req.Facets, h.config.Server.FacetMaxValues)
+	}
+
+	// Apply threshold filter if specified
+	if req.Threshold > 0 {
+		rankedChunks = h.rankerService.FilterByThreshold(rankedChunks, req.Threshold)
+	}
+
+	if req.MMRLambda != nil {
+		rankedChunks, err = h.diversify(c, req.Query, rankedChunks, *req.MMRLambda)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, types.ErrorResponse{
+				Error:   "ranking_failed",
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	// Only the best ranked candidates are returned
+	rankedChunks = h.rankerService.GetTopK(rankedChunks, req.RerankTopK)
+
+	if req.IncludeNeighbors {
+		for i := range rankedChunks {
+			rankedChunks[i].PrevChunkID, rankedChunks[i].NextChunkID = types.NeighborChunkIDs(rankedChunks[i].DocumentChunk)
+		}
+	}
+
+	// Long content is shortened for compact result lists
+	if req.MaxContentLength > 0 {
+		for i := range rankedChunks {
+			content, cut := textutil.TruncateWithFlag(rankedChunks[i].Content, req.MaxContentLength)
+			rankedChunks[i].Content = content
+			rankedChunks[i].Truncated = rankedChunks[i].Truncated || cut
+		}
+	}
+
+	response := types.SearchResponse{
+		Query:       req.Query,
+		Results:     rankedChunks,
+		Total:       len(rankedChunks),
+		Diagnostics: diagnostics,
+		Meta:        h.responseMeta(c),
+		Partial:     partial,
+		Facets:      facets,
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetDocumentChunks retrieves all chunks for a specific document
+func (h *Handler) GetDocumentChunks(c *gin.Context) {
+	documentID := c.Param("id")
+
+	if c.Query("format") == "jsonl" {
+		h.streamDocumentChunks(c, documentID)
+		return
+	}
+
+	// Stop reading once the cap is exceeded instead of buffering a huge document
+	maxChunks := h.config.Retrieval.MaxDocumentChunks
+	truncated := false
+	var chunks []types.DocumentChunk
+	err := h.retrieverFor(c).StreamByDocumentID(c.Request.Context(), documentID, func(chunk types.DocumentChunk) error {
+		if maxChunks > 0 && len(chunks) >= maxChunks {
+			truncated = true
+			return errStopStreaming
+		}
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStopStreaming) {
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{
+			Error:   "retrieval_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	response := gin.H{
+		"document_id": documentID,
+		"chunks":      chunks,
+		"total":       len(chunks),
+	}
+	if truncated {
+		response["truncated"] = true
+		response["message"] = fmt.Sprintf("document has more than %d chunks; use ?format=jsonl to stream all of them", maxChunks)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// errStopStreaming ends a chunk stream early without reporting a failure
+var errStopStreaming = errors.New("stop streaming")
+
+// streamDocumentChunks writes a document's chunks as JSON lines in chunk_index
+// order, so memory use doesn't grow with the document size
+func (h *Handler) streamDocumentChunks(c *gin.Context, documentID string) {
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(c.Writer)
+	err := h.retrieverFor(c).StreamByDocumentID(c.Request.Context(), documentID, func(chunk types.DocumentChunk) error {
+		return encoder.Encode(chunk)
+	})
+	if err != nil {
+		// The status line is already sent; the client sees a truncated stream
+		log.Printf("Streaming chunks of document %s failed: %v", documentID, err)
 	}
+}
 
-	// Retrieve relevant chunks
-	chunks, err := h.retrieverService.RetrieveRelevantChunks(c.Request.Context(), req.Query, req.Limit)
+// GetDocumentContent reconstructs a document's text from its chunks
+func (h *Handler) GetDocumentContent(c *gin.Context) {
+	documentID := c.Param("id")
+
+	response, err := h.retrieverFor(c).ReconstructDocument(c.Request.Context(), documentID)
+	if errors.Is(err, retriever.ErrDocumentNotFound) {
+		c.JSON(http.StatusNotFound, types.ErrorResponse{
+			Error:   "document_not_found",
+			Code:    http.StatusNotFound,
+			Message: err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, types.ErrorResponse{
-			Error:   "retrieval_failed",
+			Error:   "reconstruction_failed",
 			Code:    http.StatusInternalServerError,
 			Message: err.Error(),
 		})
 		return
 	}
 
-	// Rank chunks
-	rankedChunks, err := h.rankerService.RankChunks(c.Request.Context(), req.Query, chunks)
+	c.JSON(http.StatusOK, response)
+}
+
+// GetChunk retrieves a specific chunk by ID
+func (h *Handler) GetChunk(c *gin.Context) {
+	chunkIDStr := c.Param("id")
+
+	chunkID, err := strconv.ParseUint(chunkIDStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.ErrorResponse{
-			Error:   "ranking_failed",
-			Code:    http.StatusInternalServerError,
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "invalid_chunk_id",
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("invalid chunk id %q: must be a non-negative integer", chunkIDStr),
+		})
+		return
+	}
+
+	chunk, err := h.retrieverFor(c).RetrieveChunkByID(c.Request.Context(), chunkID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, types.ErrorResponse{
+			Error:   "chunk_not_found",
+			Code:    http.StatusNotFound,
 			Message: err.Error(),
 		})
 		return
 	}
 
-	// Apply threshold filter if specified
-	if req.Threshold > 0 {
-		rankedChunks = h.rankerService.FilterByThreshold(rankedChunks, req.Threshold)
+	c.JSON(http.StatusOK, chunk)
+}
+
+// RAGQuery handles complete RAG (Retrieve-Augment-Generate) requests
+func (h *Handler) RAGQuery(c *gin.Context) {
+	var req types.RAGRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	retrieval, ok := h.retrieveForRAG(c, &req)
+	if !ok {
+		return
+	}
+	start, timings, partial := retrieval.start, retrieval.timings, retrieval.partial
+	rankedChunks := retrieval.rankedChunks
+
+	// Nothing to ground an answer in, so skip the LLM round trip
+	if retrieval.noResultsReason != "" {
+		c.JSON(http.StatusOK, types.RAGResponse{
+			Query: req.Query,
+			GeneratedResponse: types.GeneratedResponse{
+				Response: generate.NoContextResponse,
+				Sources:  []string{},
+			},
+			RetrievedChunks: []types.RankedChunk{},
+			ProcessingTime:  time.Since(start).String(),
+			Timings:         h.timingBreakdown(timings, start),
+			Meta:            h.responseMeta(c),
+			NoResultsReason: retrieval.noResultsReason,
+			Partial:         partial,
+			Confidence:      h.confidence(nil, nil),
+		})
+		return
 	}
 
 	// Generate response
-	generatedResponse, err := h.generateService.GenerateResponse(c.Request.Context(), req.Query, rankedChunks)
+	generateStart := time.Now()
+	generatedResponse, err := h.generateService.GenerateWithOptions(c.Request.Context(), req.Query, retrieval.contextChunks, generationOptions(&req))
+	timings.GenerationMs = milliseconds(time.Since(generateStart))
+	if errors.Is(err, generate.ErrEmptyResponse) {
+		c.JSON(http.StatusBadGateway, types.ErrorResponse{
+			Error:   "empty_generation",
+			Code:    http.StatusBadGateway,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err != nil && h.config.Generation.DegradeOnRateLimit && errors.Is(err, generate.ErrRateLimited) {
+		// Keep the endpoint useful while the LLM is throttled
+		c.JSON(http.StatusOK, types.RAGResponse{
+			Query:                   req.Query,
+			GeneratedResponse:       types.GeneratedResponse{Sources: []string{}},
+			RetrievedChunks:         rankedChunks,
+			ProcessingTime:          time.Since(start).String(),
+			Timings:                 h.timingBreakdown(timings, start),
+			Meta:                    h.responseMeta(c),
+			GenerationSkippedReason: "generation rate-limited, returning retrieval only",
+			Partial:                 partial,
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, types.ErrorResponse{
 			Error:   "generation_failed",
@@ -344,12 +1415,468 @@ func (h *Handler) RAGQuery(c *gin.Context) {
 		return
 	}
 
+	generatedResponse.Response, err = moderation.Check(c.Request.Context(), h.moderator, h.config.Moderation.Generation, generatedResponse.Response)
+	if errors.Is(err, moderation.ErrFlagged) {
+		respondFlagged(c, err)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{
+			Error:   "moderation_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
 	response := types.RAGResponse{
 		Query:             req.Query,
 		GeneratedResponse: *generatedResponse,
 		RetrievedChunks:   rankedChunks,
 		ProcessingTime:    time.Since(start).String(),
+		Timings:           h.timingBreakdown(timings, start),
+		Meta:              h.responseMeta(c),
+		Partial:           partial,
+		Confidence:        h.confidence(retrieval.contextChunks, generatedResponse),
 	}
 
 	c.JSON(http.StatusOK, response)
 }
+
+// Server-sent event names of the streaming RAG endpoint
+const (
+	ragEventDelta = "delta"
+	ragEventDone  = "done"
+	ragEventError = "error"
+)
+
+// RAGStream answers a RAG query like RAGQuery, but streams the answer as
+// server-sent events: a delta event per piece of text, then a done event with
+// the full response. Errors before the first event get a JSON error response;
+// later ones end the stream with an error event.
+func (h *Handler) RAGStream(c *gin.Context) {
+	var req types.RAGRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Tool calls and JSON answers are only useful once complete
+	if req.ResponseFormat == types.ResponseFormatJSON || len(req.Tools) > 0 || len(req.ToolCalls) > 0 {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: "streaming supports text answers only; use /api/v1/rag for json answers and tool calling",
+		})
+		return
+	}
+
+	retrieval, ok := h.retrieveForRAG(c, &req)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	stream := &sseStream{c: c}
+
+	response := types.RAGResponse{
+		Query:           req.Query,
+		RetrievedChunks: retrieval.rankedChunks,
+		Partial:         retrieval.partial,
+	}
+	if retrieval.noResultsReason != "" {
+		response.GeneratedResponse = types.GeneratedResponse{Response: generate.NoContextResponse, Sources: []string{}}
+		response.RetrievedChunks = []types.RankedChunk{}
+		response.NoResultsReason = retrieval.noResultsReason
+		if err := stream.send(ragEventDelta, types.RAGStreamDelta{Delta: generate.NoContextResponse}); err != nil {
+			log.Printf("RAG stream aborted: %v", err)
+			return
+		}
+		h.finishRAGStream(c, stream, response, retrieval)
+		return
+	}
+
+	// A moderated answer must be checked whole before any of it is sent
+	moderated := h.config.Moderation.Generation != "" && h.config.Moderation.Generation != moderation.ActionOff
+	onDelta := func(delta string) error {
+		if moderated {
+			return nil
+		}
+		return stream.send(ragEventDelta, types.RAGStreamDelta{Delta: delta})
+	}
+
+	generateStart := time.Now()
+	generatedResponse, err := generate.Stream(ctx, h.generateService, req.Query, retrieval.contextChunks, generationOptions(&req), onDelta)
+	retrieval.timings.GenerationMs = milliseconds(time.Since(generateStart))
+	if err != nil && ctx.Err() != nil {
+		// The client went away; there is nobody left to tell
+		log.Printf("RAG stream aborted: %v", ctx.Err())
+		return
+	}
+	if err != nil && !stream.started && h.config.Generation.DegradeOnRateLimit && errors.Is(err, generate.ErrRateLimited) {
+		response.GeneratedResponse = types.GeneratedResponse{Sources: []string{}}
+		response.GenerationSkippedReason = "generation rate-limited, returning retrieval only"
+		h.finishRAGStream(c, stream, response, retrieval)
+		return
+	}
+	if err != nil {
+		status, code := http.StatusInternalServerError, "generation_failed"
+		if errors.Is(err, generate.ErrEmptyResponse) {
+			status, code = http.StatusBadGateway, "empty_generation"
+		}
+		stream.fail(status, types.ErrorResponse{Error: code, Code: status, Message: err.Error()})
+		return
+	}
+
+	if moderated {
+		generatedResponse.Response, err = moderation.Check(ctx, h.moderator, h.config.Moderation.Generation, generatedResponse.Response)
+		if errors.Is(err, moderation.ErrFlagged) {
+			respondFlagged(c, err)
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, types.ErrorResponse{
+				Error:   "moderation_failed",
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+			return
+		}
+		if err := stream.send(ragEventDelta, types.RAGStreamDelta{Delta: generatedResponse.Response}); err != nil {
+			log.Printf("RAG stream aborted: %v", err)
+			return
+		}
+	}
+
+	response.GeneratedResponse = *generatedResponse
+	h.finishRAGStream(c, stream, response, retrieval)
+}
+
+// finishRAGStream completes a streamed RAG response with the done event
+func (h *Handler) finishRAGStream(c *gin.Context, stream *sseStream, response types.RAGResponse, retrieval *ragRetrieval) {
+	response.ProcessingTime = time.Since(retrieval.start).String()
+	response.Timings = h.timingBreakdown(retrieval.timings, retrieval.start)
+	response.Meta = h.responseMeta(c)
+	if response.GenerationSkippedReason == "" {
+		response.Confidence = h.confidence(retrieval.contextChunks, &response.GeneratedResponse)
+	}
+	if err := stream.send(ragEventDone, response); err != nil {
+		log.Printf("RAG stream aborted: %v", err)
+	}
+}
+
+// sseStream writes server-sent events, sending the event stream headers with
+// the first event so earlier failures can still get a JSON error response
+type sseStream struct {
+	c       *gin.Context
+	started bool
+}
+
+// send writes an event with a JSON payload and flushes it to the client. It
+// fails once the client has disconnected.
+func (s *sseStream) send(event string, data any) error {
+	if err := s.c.Request.Context().Err(); err != nil {
+		return err
+	}
+	if !s.started {
+		// SSEvent sets the text/event-stream content type
+		s.c.Header("Cache-Control", "no-cache")
+		s.c.Header("Connection", "keep-alive")
+		s.c.Status(http.StatusOK)
+		s.started = true
+	}
+	s.c.SSEvent(event, data)
+	s.c.Writer.Flush()
+	return nil
+}
+
+// fail reports an error as a JSON response, or as an error event once the
+// stream has started
+func (s *sseStream) fail(status int, response types.ErrorResponse) {
+	if !s.started {
+		s.c.JSON(status, response)
+		return
+	}
+	if err := s.send(ragEventError, response); err != nil {
+		log.Printf("RAG stream aborted: %v", err)
+	}
+}
+
+// maxRequestTokens is the longest answer a RAG request may ask for
+const maxRequestTokens = 32768
+
+// generationOptions collects the generation settings of a RAG request
+func generationOptions(req *types.RAGRequest) types.GenerationOptions {
+	return types.GenerationOptions{
+		ResponseFormat: req.ResponseFormat,
+		Schema:         req.Schema,
+		Tools:          req.Tools,
+		ToolCalls:      req.ToolCalls,
+		ToolResults:    req.ToolResults,
+		Model:          req.Model,
+		Temperature:    req.Temperature,
+		MaxTokens:      req.MaxTokens,
+	}
+}
+
+// ragRetrieval holds the chunks a RAG request retrieved and ranked for generation
+type ragRetrieval struct {
+	start         time.Time
+	timings       types.TimingBreakdown
+	rankedChunks  []types.RankedChunk
+	contextChunks []types.RankedChunk
+	partial       bool
+	// noResultsReason is set when nothing was retrieved and generation should be skipped
+	noResultsReason string
+}
+
+// retrieveForRAG validates a RAG request, then retrieves and ranks the chunks
+// to answer it with. When the request can't go on it writes the error
+// response and returns false.
+func (h *Handler) retrieveForRAG(c *gin.Context, req *types.RAGRequest) (*ragRetrieval, bool) {
+	if req.NoCache {
+		bypassCache(c)
+	}
+
+	if req.RetrieveLimit < 0 || req.ContextLimit < 0 {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: "retrieve_limit and context_limit must not be negative",
+		})
+		return nil, false
+	}
+	if req.RerankTopK < 0 {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: "rerank_top_k must not be negative",
+		})
+		return nil, false
+	}
+
+	// Requests are billed to the server's key, so they may only pick allowed models
+	generation := h.config.Generation
+	if req.Model != "" && req.Model != generation.Model && !slices.Contains(generation.AllowedModels, req.Model) {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("model %q is not allowed; set LLM_ALLOWED_MODELS to allow it", req.Model),
+		})
+		return nil, false
+	}
+	if req.Temperature != nil && (*req.Temperature < 0 || *req.Temperature > 2) {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: "temperature must be between 0 and 2",
+		})
+		return nil, false
+	}
+	if req.MaxTokens < 0 || req.MaxTokens > maxRequestTokens {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("max_tokens must be between 1 and %d", maxRequestTokens),
+		})
+		return nil, false
+	}
+
+	if req.MMRLambda != nil && (*req.MMRLambda < 0 || *req.MMRLambda > 1) {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: "mmr_lambda must be between 0 and 1",
+		})
+		return nil, false
+	}
+
+	switch req.ResponseFormat {
+	case "", types.ResponseFormatText, types.ResponseFormatJSON:
+	default:
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("unsupported response_format: %s", req.ResponseFormat),
+		})
+		return nil, false
+	}
+
+	if err := h.validateVectorName(req.VectorName); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "invalid_request",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return nil, false
+	}
+
+	if err := store.ValidateFilters(req.Filters); err != nil {
+		c.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error:   "invalid_filter",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return nil, false
+	}
+
+	retrieval := &ragRetrieval{start: time.Now()}
+
+	if req.Limit <= 0 {
+		req.Limit = 5 // Default for RAG
+	}
+	if req.RetrieveLimit == 0 {
+		req.RetrieveLimit = req.Limit
+	}
+
+	// Retrieve relevant chunks
+	chunks, err := h.retrieverFor(c).RetrieveFromVector(c.Request.Context(), req.Query, req.VectorName, req.RetrieveLimit, req.Filters)
+	retrieval.timings.RetrievalMs = milliseconds(time.Since(retrieval.start))
+	retrieval.partial = errors.Is(err, store.ErrPartialResults)
+	if err != nil && !retrieval.partial {
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{
+			Error:   "retrieval_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return nil, false
+	}
+
+	// Nothing to ground an answer in, so skip ranking and the LLM round trip
+	if len(chunks) == 0 && h.config.Retrieval.SkipGenerationOnEmpty {
+		// Unfiltered vector search always returns neighbors unless the collection is empty
+		retrieval.noResultsReason = "collection empty"
+		if len(req.Filters) > 0 {
+			retrieval.noResultsReason = "no documents matched filters"
+		}
+		if retrieval.partial {
+			retrieval.noResultsReason = "search timed out"
+		}
+		return retrieval, true
+	}
+
+	// Rank chunks
+	rankStart := time.Now()
+	rank := h.rankerService.RankChunks
+	if req.Hybrid {
+		rank = h.rankerService.RankHybrid
+	}
+	retrieval.rankedChunks, err = rank(c.Request.Context(), req.Query, chunks)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.ErrorResponse{
+			Error:   "ranking_failed",
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return nil, false
+	}
+
+	// Apply threshold filter if specified
+	if req.Threshold > 0 {
+		retrieval.rankedChunks = h.rankerService.FilterByThreshold(retrieval.rankedChunks, req.Threshold)
+	}
+
+	// Spread the context over distinct chunks rather than near-duplicates
+	if req.MMRLambda != nil {
+		retrieval.rankedChunks, err = h.diversify(c, req.Query, retrieval.rankedChunks, *req.MMRLambda)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, types.ErrorResponse{
+				Error:   "ranking_failed",
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+			return nil, false
+		}
+	}
+
+	// Only the best ranked candidates are kept, and the best of those go to the LLM
+	retrieval.rankedChunks = h.rankerService.GetTopK(retrieval.rankedChunks, req.RerankTopK)
+	retrieval.contextChunks = h.rankerService.GetTopK(retrieval.rankedChunks, req.ContextLimit)
+	retrieval.timings.RankingMs = milliseconds(time.Since(rankStart))
+
+	return retrieval, true
+}
+
+// respondFlagged rejects a request whose content failed moderation
+func respondFlagged(c *gin.Context, err error) {
+	c.JSON(http.StatusUnprocessableEntity, types.ErrorResponse{
+		Error:   "content_flagged",
+		Code:    http.StatusUnprocessableEntity,
+		Message: err.Error(),
+	})
+}
+
+// timingBreakdown completes the per-stage timings with the total since start,
+// or returns nil when the breakdown is disabled
+func (h *Handler) timingBreakdown(timings types.TimingBreakdown, start time.Time) *types.TimingBreakdown {
+	if !h.config.Server.TimingBreakdown {
+		return nil
+	}
+	timings.TotalMs = milliseconds(time.Since(start))
+	return &timings
+}
+
+// confidence scores how well an answer is grounded in its context chunks, or
+// returns nil when confidence scoring is disabled
+func (h *Handler) confidence(contextChunks []types.RankedChunk, response *types.GeneratedResponse) *float64 {
+	if !h.config.Generation.Confidence.Enabled {
+		return nil
+	}
+	confidence := generate.Confidence(h.config.Generation.Confidence, contextChunks, response)
+	return &confidence
+}
+
+// demote lowers the scores of ranked chunks similar to a negative query,
+// embedding them with the model of the request's collection
+func (h *Handler) demote(c *gin.Context, negativeQuery string, rankedChunks []types.RankedChunk) ([]types.RankedChunk, error) {
+	negativeEmbedding, embeddings, err := h.retrieverFor(c).Embed(c.Request.Context(), negativeQuery, rankedDocumentChunks(rankedChunks))
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply negative query: %w", err)
+	}
+	return h.rankerService.ApplyNegativeQuery(rankedChunks, negativeEmbedding, embeddings), nil
+}
+
+// rankedDocumentChunks returns the chunks behind ranked results
+func rankedDocumentChunks(rankedChunks []types.RankedChunk) []types.DocumentChunk {
+	chunks := make([]types.DocumentChunk, len(rankedChunks))
+	for i, chunk := range rankedChunks {
+		chunks[i] = chunk.DocumentChunk
+	}
+	return chunks
+}
+
+// diversify reorders ranked chunks by maximal marginal relevance, embedding
+// them with the model of the request's collection
+func (h *Handler) diversify(c *gin.Context, query string, rankedChunks []types.RankedChunk, lambda float64) ([]types.RankedChunk, error) {
+	if len(rankedChunks) < 2 {
+		return rankedChunks, nil
+	}
+
+	queryEmbedding, embeddings, err := h.retrieverFor(c).Embed(c.Request.Context(), query, rankedDocumentChunks(rankedChunks))
+	if err != nil {
+		return nil, fmt.Errorf("failed to diversify results: %w", err)
+	}
+	return h.rankerService.ApplyMMR(rankedChunks, queryEmbedding, embeddings, lambda), nil
+}
+
+// responseMeta describes the collection and embedding model that served the
+// request, or returns nil when response metadata is disabled
+func (h *Handler) responseMeta(c *gin.Context) *types.ResponseMeta {
+	if !h.config.Server.ResponseMeta {
+		return nil
+	}
+	meta := h.retrieverFor(c).Describe()
+	meta.EmbeddingModel = h.config.Embedding.Model
+	if override, ok := h.config.CollectionEmbeddings[meta.Collection]; ok {
+		meta.EmbeddingModel = override.Model
+	}
+	return &meta
+}
+
+// milliseconds converts d to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,1507 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-rag/internal/cache"
+	"go-rag/internal/chunk"
+	"go-rag/internal/config"
+	"go-rag/internal/embedding"
+	"go-rag/internal/generate"
+	"go-rag/internal/ingest"
+	"go-rag/internal/jobs"
+	"go-rag/internal/moderation"
+	"go-rag/internal/ranker"
+	"go-rag/internal/retriever"
+	"go-rag/internal/store"
+	"go-rag/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeStore is an in-memory VectorStore that returns stored chunks in ID order.
+// With softDelete set, deleted documents are hidden until restored or purged.
+type fakeStore struct {
+	mu          sync.Mutex
+	chunks      map[uint64]types.DocumentChunk
+	searchLimit int
+	softDelete  bool
+	deleted     map[string]bool
+	// searchBypassedCache records whether the last search was told to skip caches
+	searchBypassedCache bool
+	// searchFilters records the metadata filters of the last search
+	searchFilters map[string]string
+	// searchTimesOut makes searches return their results as partial
+	searchTimesOut bool
+	// unreachable makes health checks fail
+	unreachable bool
+}
+
+func newFakeStore(chunks ...types.DocumentChunk) *fakeStore {
+	f := &fakeStore{chunks: make(map[uint64]types.DocumentChunk), deleted: make(map[string]bool)}
+	for _, c := range chunks {
+		f.chunks[c.ID] = c
+	}
+	return f
+}
+
+func (f *fakeStore) sorted() []types.DocumentChunk {
+	result := make([]types.DocumentChunk, 0, len(f.chunks))
+	for _, c := range f.chunks {
+		if !f.deleted[c.DocumentID] {
+			result = append(result, c)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+func (f *fakeStore) StoreChunks(ctx context.Context, chunks []types.DocumentChunk) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range chunks {
+		f.chunks[c.ID] = c
+	}
+	return nil
+}
+
+func (f *fakeStore) SearchSimilar(ctx context.Context, query string, limit int, vectorName string, filters map[string]string) ([]types.DocumentChunk, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.searchLimit = limit
+	f.searchFilters = filters
+	f.searchBypassedCache = cache.Bypassed(ctx)
+	result := f.sorted()
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	if f.searchTimesOut {
+		return result, store.ErrPartialResults
+	}
+	return result, nil
+}
+
+func (f *fakeStore) SearchWithDiagnostics(ctx context.Context, query string, limit int, vectorName string, filters map[string]string) ([]types.DocumentChunk, *types.SearchDiagnostics, error) {
+	chunks, err := f.SearchSimilar(ctx, query, limit, vectorName, filters)
+	return chunks, &types.SearchDiagnostics{QdrantTimeMs: 1.5, ResultCount: len(chunks), SearchMode: "hnsw"}, err
+}
+
+func (f *fakeStore) Describe() types.ResponseMeta {
+	return types.ResponseMeta{Collection: "documents", Distance: "cosine"}
+}
+
+func (f *fakeStore) Stats(ctx context.Context) (*types.CollectionStats, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	documents := make(map[string]bool)
+	for _, c := range f.chunks {
+		documents[c.DocumentID] = true
+	}
+	return &types.CollectionStats{Collection: "documents", Chunks: uint64(len(f.chunks)), Documents: uint64(len(documents)), Dimensions: 3, Distance: "cosine"}, nil
+}
+
+func (f *fakeStore) GetChunksByDocumentID(ctx context.Context, documentID string) ([]types.DocumentChunk, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var result []types.DocumentChunk
+	for _, c := range f.sorted() {
+		if c.DocumentID == documentID {
+			result = append(result, c)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeStore) GetChunkByID(ctx context.Context, chunkID uint64) (*types.DocumentChunk, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.chunks[chunkID]
+	if !ok || f.deleted[c.DocumentID] {
+		return nil, fmt.Errorf("chunk not found: %d", chunkID)
+	}
+	return &c, nil
+}
+
+func (f *fakeStore) DeleteDocument(ctx context.Context, documentID string) error {
+	if f.softDelete {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.deleted[documentID] = true
+		return nil
+	}
+	return f.PurgeDocument(ctx, documentID)
+}
+
+func (f *fakeStore) RestoreDocument(ctx context.Context, documentID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.deleted, documentID)
+	return nil
+}
+
+func (f *fakeStore) PurgeDocument(ctx context.Context, documentID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.deleted, documentID)
+	for id, c := range f.chunks {
+		if c.DocumentID == documentID {
+			delete(f.chunks, id)
+		}
+	}
+	return nil
+}
+
+func (f *fakeStore) DeleteChunk(ctx context.Context, chunkID uint64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.chunks, chunkID)
+	return nil
+}
+
+func (f *fakeStore) HealthCheck(ctx context.Context) error {
+	if f.unreachable {
+		return fmt.Errorf("connection refused")
+	}
+	return nil
+}
+
+func (f *fakeStore) CreateCollection(ctx context.Context, vectorSize int) error {
+	return nil
+}
+
+// recordingGenerator is a GenerationService that records the chunks it was given
+type recordingGenerator struct {
+	chunks []types.RankedChunk
+	opts   types.GenerationOptions
+	calls  int
+	err    error
+	delay  time.Duration
+}
+
+func (g *recordingGenerator) GenerateResponse(ctx context.Context, query string, chunks []types.RankedChunk) (*types.GeneratedResponse, error) {
+	return g.GenerateWithOptions(ctx, query, chunks, types.GenerationOptions{})
+}
+
+func (g *recordingGenerator) GenerateWithOptions(ctx context.Context, query string, chunks []types.RankedChunk, opts types.GenerationOptions) (*types.GeneratedResponse, error) {
+	g.calls++
+	g.chunks = chunks
+	g.opts = opts
+	time.Sleep(g.delay)
+	if g.err != nil {
+		return nil, g.err
+	}
+	return &types.GeneratedResponse{Response: "answer", Sources: []string{}}, nil
+}
+
+func newTestHandler(store *fakeStore, generator *recordingGenerator) *Handler {
+	return newTestHandlerWithConfig(&config.Config{}, store, generator)
+}
+
+func newTestHandlerWithConfig(cfg *config.Config, store *fakeStore, generator *recordingGenerator) *Handler {
+	chunker := chunk.NewService(1000, 200)
+	return &Handler{
+		ingestService:    ingest.NewService(*chunker, store, types.ChunkingConfig{}),
+		retrieverService: retriever.NewService(store, types.RetrievalConfig{}),
+		rankerService:    ranker.NewService(types.RankingConfig{}),
+		generateService:  generator,
+		vectorStore:      store,
+		config:           cfg,
+		chunker:          chunker,
+	}
+}
+
+// performJSON sends a JSON request to the given handler and returns the recorder
+func performJSON(handler gin.HandlerFunc, method, path string, body any) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Handle(method, path, handler)
+
+	payload, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func testChunks(n int) []types.DocumentChunk {
+	chunks := make([]types.DocumentChunk, n)
+	for i := range chunks {
+		chunks[i] = types.DocumentChunk{
+			ID:         uint64(i + 1),
+			DocumentID: "doc-1",
+			ChunkIndex: i,
+			Content:    fmt.Sprintf("machine learning chunk %d", i),
+		}
+	}
+	return chunks
+}
+
+func TestHealthCheck_ReportsVectorStore(t *testing.T) {
+	store := newFakeStore()
+	handler := newTestHandler(store, &recordingGenerator{})
+
+	w := performJSON(handler.HealthCheck, http.MethodGet, "/health", nil)
+	var response types.HealthCheckResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusOK || response.Services["vector_store"] != "healthy" {
+		t.Errorf("Expected a healthy vector store, got %d: %s", w.Code, w.Body.String())
+	}
+
+	store.unreachable = true
+	w = performJSON(handler.HealthCheck, http.MethodGet, "/health", nil)
+	response = types.HealthCheckResponse{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusServiceUnavailable || response.Status != "unhealthy" || response.Services["vector_store"] != "unhealthy" {
+		t.Errorf("Expected 503 with an unhealthy vector store, got %d: %s", w.Code, w.Body.String())
+	}
+	if response.Services["vector_store_error"] != "connection refused" {
+		t.Errorf("Expected the store's error, got %q", response.Services["vector_store_error"])
+	}
+}
+
+func TestNewHandler_RecreateCollection(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	cfg := &config.Config{
+		VectorStore: types.VectorStoreConfig{Provider: "memory", CollectionName: "documents", PersistPath: path},
+		Embedding:   types.EmbeddingConfig{Provider: "mock", Dimensions: 4},
+		Generation:  types.GenerationConfig{Provider: "mock"},
+	}
+	embedder, _ := embedding.NewMockService(cfg.Embedding)
+	previous, _ := store.NewMemoryStore(cfg.VectorStore, embedder)
+	previous.StoreChunks(context.Background(), testChunks(3))
+	previous.Flush(context.Background())
+
+	cfg.VectorStore.RecreateCollection = true
+	handler, err := NewHandler(cfg)
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	defer handler.Close(context.Background())
+	if chunk, err := handler.vectorStore.GetChunkByID(context.Background(), 1); err == nil {
+		t.Errorf("Expected the recreated collection to be empty, got %+v", chunk)
+	}
+
+	// Pinecone indexes can't be dropped from here
+	cfg.VectorStore = types.VectorStoreConfig{Provider: "pinecone", Host: "docs.svc.pinecone.io", APIKey: "key", CollectionName: "documents", RecreateCollection: true}
+	if _, err := NewHandler(cfg); err == nil || !strings.Contains(err.Error(), "RECREATE_COLLECTION") {
+		t.Errorf("Expected RECREATE_COLLECTION to be rejected for pinecone, got %v", err)
+	}
+}
+
+func TestRAGQuery_SeparatesRetrieveAndContextLimits(t *testing.T) {
+	store := newFakeStore(testChunks(30)...)
+	generator := &recordingGenerator{}
+	handler := newTestHandler(store, generator)
+
+	w := performJSON(handler.RAGQuery, http.MethodPost, "/rag", types.RAGRequest{
+		Query:         "machine learning",
+		RetrieveLimit: 20,
+		ContextLimit:  5,
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if store.searchLimit != 20 {
+		t.Errorf("Expected retrieval limit 20, got %d", store.searchLimit)
+	}
+	if len(generator.chunks) != 5 {
+		t.Errorf("Expected 5 chunks passed to generation, got %d", len(generator.chunks))
+	}
+
+	var response types.RAGResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if len(response.RetrievedChunks) != 20 {
+		t.Errorf("Expected 20 ranked chunks in response, got %d", len(response.RetrievedChunks))
+	}
+}
+
+func TestRAGQuery_RerankTopK(t *testing.T) {
+	store := newFakeStore(testChunks(30)...)
+	generator := &recordingGenerator{}
+	handler := newTestHandler(store, generator)
+
+	w := performJSON(handler.RAGQuery, http.MethodPost, "/rag", types.RAGRequest{
+		Query:         "machine learning",
+		RetrieveLimit: 20,
+		RerankTopK:    3,
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if store.searchLimit != 20 {
+		t.Errorf("Expected retrieval limit 20, got %d", store.searchLimit)
+	}
+	if len(generator.chunks) != 3 {
+		t.Errorf("Expected 3 chunks passed to generation, got %d", len(generator.chunks))
+	}
+	var response types.RAGResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if len(response.RetrievedChunks) != 3 {
+		t.Errorf("Expected 3 ranked chunks in response, got %d", len(response.RetrievedChunks))
+	}
+
+	w = performJSON(handler.RAGQuery, http.MethodPost, "/rag", types.RAGRequest{Query: "machine learning", RerankTopK: -1})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a negative rerank_top_k, got %d", w.Code)
+	}
+}
+
+func TestRAGQuery_OverridesGenerationSettings(t *testing.T) {
+	generator := &recordingGenerator{}
+	cfg := &config.Config{Generation: types.GenerationConfig{Model: "gpt-4o", AllowedModels: []string{"gpt-4o-mini"}}}
+	handler := newTestHandlerWithConfig(cfg, newFakeStore(testChunks(3)...), generator)
+
+	temperature := 1.2
+	w := performJSON(handler.RAGQuery, http.MethodPost, "/rag", types.RAGRequest{
+		Query:       "machine learning",
+		Model:       "gpt-4o-mini",
+		Temperature: &temperature,
+		MaxTokens:   200,
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if opts := generator.opts; opts.Model != "gpt-4o-mini" || opts.Temperature == nil || *opts.Temperature != 1.2 || opts.MaxTokens != 200 {
+		t.Errorf("Expected the request's model, temperature and max tokens, got %+v", opts)
+	}
+
+	for _, req := range []types.RAGRequest{
+		{Query: "machine learning", Temperature: func() *float64 { v := 2.5; return &v }()},
+		{Query: "machine learning", MaxTokens: -1},
+		{Query: "machine learning", MaxTokens: maxRequestTokens + 1},
+		{Query: "machine learning", Model: "o1-pro"},
+	} {
+		if w := performJSON(handler.RAGQuery, http.MethodPost, "/rag", req); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %+v, got %d", req, w.Code)
+		}
+	}
+}
+
+func TestRAGQuery_EmptyRetrievalSkipsGeneration(t *testing.T) {
+	cfg := &config.Config{Retrieval: types.RetrievalConfig{SkipGenerationOnEmpty: true}}
+	generator := &recordingGenerator{}
+	handler := newTestHandlerWithConfig(cfg, newFakeStore(), generator)
+
+	w := performJSON(handler.RAGQuery, http.MethodPost, "/rag", types.RAGRequest{Query: "anything"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if generator.calls != 0 {
+		t.Errorf("Expected no generation calls, got %d", generator.calls)
+	}
+
+	var response types.RAGResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.NoResultsReason != "collection empty" {
+		t.Errorf("Expected reason 'collection empty', got %q", response.NoResultsReason)
+	}
+	if response.GeneratedResponse.Response != generate.NoContextResponse {
+		t.Errorf("Expected fallback response, got %q", response.GeneratedResponse.Response)
+	}
+}
+
+func TestRAGQuery_RateLimitedGenerationDegrades(t *testing.T) {
+	rateLimited := fmt.Errorf("failed to generate response: %w", generate.ErrRateLimited)
+
+	cfg := &config.Config{Generation: types.GenerationConfig{DegradeOnRateLimit: true}}
+	handler := newTestHandlerWithConfig(cfg, newFakeStore(testChunks(3)...), &recordingGenerator{err: rateLimited})
+
+	w := performJSON(handler.RAGQuery, http.MethodPost, "/rag", types.RAGRequest{Query: "chunk", Limit: 3})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response types.RAGResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.GenerationSkippedReason == "" {
+		t.Error("Expected a generation skipped reason")
+	}
+	if len(response.RetrievedChunks) != 3 {
+		t.Errorf("Expected 3 retrieved chunks, got %d", len(response.RetrievedChunks))
+	}
+
+	// Without the toggle the failure is still reported
+	handler = newTestHandler(newFakeStore(testChunks(3)...), &recordingGenerator{err: rateLimited})
+	w = performJSON(handler.RAGQuery, http.MethodPost, "/rag", types.RAGRequest{Query: "chunk", Limit: 3})
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500 when degradation is disabled, got %d", w.Code)
+	}
+}
+
+func TestResolveTenant_RoutesToTenantCollection(t *testing.T) {
+	stores := map[string]*fakeStore{}
+	handler := newTestHandler(newFakeStore(), &recordingGenerator{})
+	tenantRouter, err := store.NewTenantRouter("tenant_{id}", func(ctx context.Context, collection string) (store.VectorStore, error) {
+		stores[collection] = newFakeStore()
+		return stores[collection], nil
+	})
+	if err != nil {
+		t.Fatalf("NewTenantRouter failed: %v", err)
+	}
+	handler.tenantRouter = tenantRouter
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(handler.ResolveTenant)
+	router.POST("/ingest", handler.IngestDocument)
+
+	ingestAs := func(tenantID string) int {
+		payload, _ := json.Marshal(types.IngestRequest{DocumentID: "doc-1", Content: "Tenant data."})
+		req := httptest.NewRequest(http.MethodPost, "/ingest", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		if tenantID != "" {
+			req.Header.Set(tenantHeader, tenantID)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := ingestAs(""); code != http.StatusBadRequest {
+		t.Errorf("missing tenant: expected 400, got %d", code)
+	}
+	if code := ingestAs("bad/tenant"); code != http.StatusBadRequest {
+		t.Errorf("invalid tenant: expected 400, got %d", code)
+	}
+	for _, tenantID := range []string{"acme", "globex"} {
+		if code := ingestAs(tenantID); code != http.StatusOK {
+			t.Fatalf("tenant %s: expected 200, got %d", tenantID, code)
+		}
+	}
+
+	for _, collection := range []string{"tenant_acme", "tenant_globex"} {
+		tenantStore, exists := stores[collection]
+		if !exists {
+			t.Fatalf("expected collection %s to be created", collection)
+		}
+		if len(tenantStore.chunks) == 0 {
+			t.Errorf("expected chunks stored in %s", collection)
+		}
+	}
+	if len(handler.vectorStore.(*fakeStore).chunks) != 0 {
+		t.Error("expected default store to stay empty")
+	}
+
+	// Tenants off the allow-list, and new tenants past the limit, are refused
+	tenantRouter.SetAllowedTenants([]string{"acme", "globex"})
+	if code := ingestAs("initech"); code != http.StatusForbidden {
+		t.Errorf("unknown tenant: expected 403, got %d", code)
+	}
+	tenantRouter.SetAllowedTenants(nil)
+	tenantRouter.SetMaxTenants(2)
+	if code := ingestAs("initech"); code != http.StatusServiceUnavailable {
+		t.Errorf("tenant past the limit: expected 503, got %d", code)
+	}
+}
+
+func TestSoftDeleteRestoreAndPurge(t *testing.T) {
+	store := newFakeStore(testChunks(3)...)
+	store.softDelete = true
+	handler := newTestHandler(store, &recordingGenerator{})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.DELETE("/documents/:id", handler.DeleteDocument)
+	router.POST("/documents/:id/restore", handler.RestoreDocument)
+	router.POST("/search", handler.SearchDocuments)
+
+	do := func(method, path string, body any) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	searchCount := func() int {
+		w := do(http.MethodPost, "/search", types.SearchRequest{Query: "chunk"})
+		var response types.SearchResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return len(response.Results)
+	}
+
+	if w := do(http.MethodDelete, "/documents/doc-1", nil); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 on delete, got %d", w.Code)
+	}
+	if n := searchCount(); n != 0 {
+		t.Errorf("Expected soft-deleted chunks to be hidden, got %d results", n)
+	}
+
+	if w := do(http.MethodPost, "/documents/doc-1/restore", nil); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 on restore, got %d", w.Code)
+	}
+	if n := searchCount(); n != 3 {
+		t.Errorf("Expected 3 results after restore, got %d", n)
+	}
+
+	w := do(http.MethodDelete, "/documents/doc-1?purge=true", nil)
+	if !bytes.Contains(w.Body.Bytes(), []byte(`"purged"`)) {
+		t.Errorf("Expected purged status, got %s", w.Body.String())
+	}
+	if len(store.chunks) != 0 {
+		t.Errorf("Expected purge to remove chunks, %d left", len(store.chunks))
+	}
+
+	if w := do(http.MethodDelete, "/documents/doc-1", nil); w.Code != http.StatusNotFound || !bytes.Contains(w.Body.Bytes(), []byte("document_not_found")) {
+		t.Errorf("Expected 404 for a document that no longer exists, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestGetDocumentContent_ReconstructsByStrategy(t *testing.T) {
+	store := newFakeStore(
+		types.DocumentChunk{ID: 2, DocumentID: "doc-1", ChunkIndex: 1, Content: "and more text here.", ChunkStrategy: "fixed", ChunkOverlap: 9},
+		types.DocumentChunk{ID: 1, DocumentID: "doc-1", ChunkIndex: 0, Content: "First part and more", ChunkStrategy: "fixed", ChunkOverlap: 9},
+		types.DocumentChunk{ID: 3, DocumentID: "doc-2", ChunkIndex: 0, Content: "One sentence."},
+		types.DocumentChunk{ID: 4, DocumentID: "doc-2", ChunkIndex: 1, Content: "Another one."},
+	)
+	handler := newTestHandler(store, &recordingGenerator{})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/documents/:id/content", handler.GetDocumentContent)
+
+	get := func(path string) (*httptest.ResponseRecorder, types.DocumentContentResponse) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var response types.DocumentContentResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w, response
+	}
+
+	if _, response := get("/documents/doc-1/content"); response.Content != "First part and more text here." {
+		t.Errorf("Expected overlap to be trimmed, got %q", response.Content)
+	}
+	if _, response := get("/documents/doc-2/content"); response.Content != "One sentence. Another one." || response.ChunkStrategy != "sentence" {
+		t.Errorf("Expected sentence chunks joined without trimming, got %+v", response)
+	}
+	if w, _ := get("/documents/missing/content"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing document, got %d", w.Code)
+	}
+}
+
+func TestReindexJob_StartPollAndCancel(t *testing.T) {
+	store := newFakeStore(testChunks(3)...)
+	handler := newTestHandler(store, &recordingGenerator{})
+	manager, err := jobs.NewManager("")
+	if err != nil {
+		t.Fatalf("Failed to create job manager: %v", err)
+	}
+	handler.jobManager = manager
+
+	w := performJSON(handler.StartReindex, http.MethodPost, "/jobs/reindex", types.ReindexRequest{DocumentIDs: []string{"doc-1"}})
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d: %s", w.Code, w.Body.String())
+	}
+	var started jobs.Job
+	json.Unmarshal(w.Body.Bytes(), &started)
+	manager.Wait()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/jobs/:id", handler.GetJob)
+	router.DELETE("/jobs/:id", handler.CancelJob)
+	do := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	w = do(http.MethodGet, "/jobs/"+started.ID)
+	var job jobs.Job
+	json.Unmarshal(w.Body.Bytes(), &job)
+	if job.Status != jobs.StatusSucceeded || job.Done != 1 || job.Total != 1 {
+		t.Errorf("Expected a succeeded job with 1/1 done, got %+v", job)
+	}
+
+	if w := do(http.MethodDelete, "/jobs/"+started.ID); w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 cancelling a finished job, got %d", w.Code)
+	}
+	if w := do(http.MethodGet, "/jobs/missing"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown job, got %d", w.Code)
+	}
+}
+
+func TestSearchDocuments_DiagnosticsGated(t *testing.T) {
+	store := newFakeStore(testChunks(3)...)
+	request := types.SearchRequest{Query: "chunk", Diagnostics: true}
+
+	handler := newTestHandler(store, &recordingGenerator{})
+	if w := performJSON(handler.SearchDocuments, http.MethodPost, "/search", request); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 with diagnostics disabled, got %d", w.Code)
+	}
+
+	cfg := &config.Config{Server: config.ServerConfig{SearchDiagnostics: true}}
+	handler = newTestHandlerWithConfig(cfg, store, &recordingGenerator{})
+	w := performJSON(handler.SearchDocuments, http.MethodPost, "/search", request)
+	var response types.SearchResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Diagnostics == nil || response.Diagnostics.ResultCount != 3 || response.Diagnostics.SearchMode != "hnsw" {
+		t.Errorf("Expected diagnostics from the store, got %+v", response.Diagnostics)
+	}
+
+	w = performJSON(handler.SearchDocuments, http.MethodPost, "/search", types.SearchRequest{Query: "chunk"})
+	if bytes.Contains(w.Body.Bytes(), []byte(`"diagnostics"`)) {
+		t.Errorf("Expected no diagnostics unless requested, got %s", w.Body.String())
+	}
+}
+
+func TestSearchDocuments_ResponseMeta(t *testing.T) {
+	store := newFakeStore(testChunks(3)...)
+	request := types.SearchRequest{Query: "chunk"}
+
+	handler := newTestHandler(store, &recordingGenerator{})
+	if w := performJSON(handler.SearchDocuments, http.MethodPost, "/search", request); bytes.Contains(w.Body.Bytes(), []byte(`"meta"`)) {
+		t.Errorf("Expected no meta while disabled, got %s", w.Body.String())
+	}
+
+	cfg := &config.Config{
+		Server:               config.ServerConfig{ResponseMeta: true},
+		Embedding:            types.EmbeddingConfig{Model: "text-embedding-ada-002"},
+		CollectionEmbeddings: map[string]types.EmbeddingConfig{"documents": {Model: "text-embedding-3-large"}},
+	}
+	handler = newTestHandlerWithConfig(cfg, store, &recordingGenerator{})
+	w := performJSON(handler.SearchDocuments, http.MethodPost, "/search", request)
+	var response types.SearchResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	want := types.ResponseMeta{Collection: "documents", EmbeddingModel: "text-embedding-3-large", Distance: "cosine"}
+	if response.Meta == nil || *response.Meta != want {
+		t.Errorf("Expected meta %+v, got %+v", want, response.Meta)
+	}
+}
+
+func TestGetDocumentChunks_CapAndStream(t *testing.T) {
+	chunks := testChunks(5000)
+	store := newFakeStore(chunks...)
+	cfg := &config.Config{Retrieval: types.RetrievalConfig{MaxDocumentChunks: 100}}
+	handler := newTestHandlerWithConfig(cfg, store, &recordingGenerator{})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/documents/:id/chunks", handler.GetDocumentChunks)
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	var response struct {
+		Chunks    []types.DocumentChunk `json:"chunks"`
+		Truncated bool                  `json:"truncated"`
+	}
+	json.Unmarshal(get("/documents/doc-1/chunks").Body.Bytes(), &response)
+	if len(response.Chunks) != 100 || !response.Truncated {
+		t.Errorf("Expected 100 chunks marked truncated, got %d (truncated=%v)", len(response.Chunks), response.Truncated)
+	}
+
+	w := get("/documents/doc-1/chunks?format=jsonl")
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Expected NDJSON content type, got %q", ct)
+	}
+	decoder := json.NewDecoder(w.Body)
+	count := 0
+	for decoder.More() {
+		var chunk types.DocumentChunk
+		if err := decoder.Decode(&chunk); err != nil {
+			t.Fatalf("Invalid JSON line: %v", err)
+		}
+		if chunk.ChunkIndex != count {
+			t.Fatalf("Expected chunk_index %d, got %d", count, chunk.ChunkIndex)
+		}
+		count++
+	}
+	if count != 5000 {
+		t.Errorf("Expected all 5000 chunks streamed, got %d", count)
+	}
+}
+
+func TestGetDocumentChunks_ServedFromCache(t *testing.T) {
+	store := newFakeStore(testChunks(3)...)
+	handler := newTestHandler(store, &recordingGenerator{})
+	handler.retrieverService = retriever.NewService(store, types.RetrievalConfig{ChunkCacheSize: 10})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/documents/:id/chunks", handler.GetDocumentChunks)
+	for range 2 {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/documents/doc-1/chunks", nil))
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"total":3`) {
+			t.Fatalf("Expected the document's 3 chunks, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	if stats, ok := handler.retrieverService.CacheStats(); !ok || stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("Expected the second read to hit the document cache, got %+v", stats)
+	}
+}
+
+func TestNoCache_BypassesCachesForRequest(t *testing.T) {
+	store := newFakeStore(testChunks(3)...)
+	handler := newTestHandler(store, &recordingGenerator{})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(handler.AttachCacheBypass)
+	router.POST("/search", handler.SearchDocuments)
+	search := func(body types.SearchRequest, header string) bool {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, "/search", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		if header != "" {
+			req.Header.Set(noCacheHeader, header)
+		}
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		return store.searchBypassedCache
+	}
+
+	if search(types.SearchRequest{Query: "chunk"}, "") {
+		t.Error("Expected caches to be used by default")
+	}
+	if !search(types.SearchRequest{Query: "chunk", NoCache: true}, "") {
+		t.Error("Expected no_cache to bypass caches")
+	}
+	if !search(types.SearchRequest{Query: "chunk"}, "true") {
+		t.Error("Expected the X-No-Cache header to bypass caches")
+	}
+}
+
+func TestRAGQuery_TimingBreakdown(t *testing.T) {
+	generator := &recordingGenerator{delay: 20 * time.Millisecond}
+	cfg := &config.Config{Server: config.ServerConfig{TimingBreakdown: true}}
+	handler := newTestHandlerWithConfig(cfg, newFakeStore(testChunks(5)...), generator)
+
+	w := performJSON(handler.RAGQuery, http.MethodPost, "/rag", types.RAGRequest{Query: "machine learning"})
+	var response types.RAGResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	timings := response.Timings
+	if timings == nil {
+		t.Fatalf("Expected a timing breakdown, got %s", w.Body.String())
+	}
+	if timings.GenerationMs < 20 {
+		t.Errorf("Expected generation to take at least 20ms, got %.3f", timings.GenerationMs)
+	}
+	stages := timings.RetrievalMs + timings.RankingMs + timings.GenerationMs
+	if stages > timings.TotalMs || timings.TotalMs-stages > 5 {
+		t.Errorf("Expected stages (%.3fms) to roughly add up to the total (%.3fms)", stages, timings.TotalMs)
+	}
+
+	handler = newTestHandler(newFakeStore(testChunks(5)...), &recordingGenerator{})
+	w = performJSON(handler.RAGQuery, http.MethodPost, "/rag", types.RAGRequest{Query: "machine learning"})
+	if bytes.Contains(w.Body.Bytes(), []byte(`"timings"`)) {
+		t.Errorf("Expected no timings when disabled, got %s", w.Body.String())
+	}
+}
+
+func TestRAGQuery_Confidence(t *testing.T) {
+	cfg := &config.Config{Generation: types.GenerationConfig{
+		Confidence: types.ConfidenceConfig{Enabled: true, ScoreWeight: 0.6, CoverageWeight: 0.2, LogProbWeight: 0.2, TargetChunks: 3},
+	}}
+	handler := newTestHandlerWithConfig(cfg, newFakeStore(testChunks(5)...), &recordingGenerator{})
+
+	confidence := func(query string) float64 {
+		w := performJSON(handler.RAGQuery, http.MethodPost, "/rag", types.RAGRequest{Query: query})
+		var response types.RAGResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if response.Confidence == nil {
+			t.Fatalf("Expected a confidence score, got %s", w.Body.String())
+		}
+		return *response.Confidence
+	}
+
+	if got := confidence("machine learning"); got < 0.7 {
+		t.Errorf("Expected high confidence for a well-grounded query, got %v", got)
+	}
+	if got := confidence("medieval pottery glazes"); got > 0.2 {
+		t.Errorf("Expected low confidence for an off-topic query, got %v", got)
+	}
+
+	handler = newTestHandler(newFakeStore(testChunks(5)...), &recordingGenerator{})
+	w := performJSON(handler.RAGQuery, http.MethodPost, "/rag", types.RAGRequest{Query: "machine learning"})
+	if bytes.Contains(w.Body.Bytes(), []byte(`"confidence"`)) {
+		t.Errorf("Expected no confidence when disabled, got %s", w.Body.String())
+	}
+}
+
+func TestRAGQuery_EmptyGenerationIsBadGateway(t *testing.T) {
+	empty := fmt.Errorf("failed to generate response: %w", generate.ErrEmptyResponse)
+	handler := newTestHandler(newFakeStore(testChunks(3)...), &recordingGenerator{err: empty})
+
+	w := performJSON(handler.RAGQuery, http.MethodPost, "/rag", types.RAGRequest{Query: "chunk"})
+	if w.Code != http.StatusBadGateway || !bytes.Contains(w.Body.Bytes(), []byte("empty_generation")) {
+		t.Errorf("Expected 502 empty_generation, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestSearchDocuments_ReturnsSentenceWindows(t *testing.T) {
+	store := newFakeStore()
+	handler := newTestHandler(store, &recordingGenerator{})
+	handler.ingestService = ingest.NewService(*handler.chunker, store, types.ChunkingConfig{Strategy: "sentence", SentenceWindow: 1})
+
+	text := "Alpha comes first. Beta follows it. Gamma rays are energetic. Delta is a river mouth. Epsilon is small."
+	if w := performJSON(handler.IngestDocument, http.MethodPost, "/ingest", types.IngestRequest{DocumentID: "doc-1", Content: text}); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	stored, _ := store.GetChunksByDocumentID(context.Background(), "doc-1")
+	if len(stored) != 5 {
+		t.Fatalf("Expected one chunk per sentence, got %d", len(stored))
+	}
+
+	w := performJSON(handler.SearchDocuments, http.MethodPost, "/search", types.SearchRequest{Query: "gamma"})
+	var response types.SearchResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	found := false
+	for _, result := range response.Results {
+		if result.MatchedText != "Gamma rays are energetic." {
+			continue
+		}
+		found = true
+		if result.Content != "Beta follows it. Gamma rays are energetic. Delta is a river mouth." {
+			t.Errorf("Expected the surrounding window as content, got %q", result.Content)
+		}
+	}
+	if !found {
+		t.Errorf("Expected a result matching the gamma sentence, got %+v", response.Results)
+	}
+}
+
+func TestIngestDirectory_StatusCodes(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(file, []byte("Directory ingestion works."), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	handler := newTestHandler(newFakeStore(), &recordingGenerator{})
+
+	w := performJSON(handler.IngestDirectory, http.MethodPost, "/ingest/directory", types.DirectoryIngestRequest{DirectoryPath: dir})
+	var response types.DirectoryIngestResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusOK || len(response.SuccessfulIngestions) != 1 {
+		t.Errorf("Expected 200 with one ingested file, got %d: %s", w.Code, w.Body.String())
+	}
+
+	cases := []struct {
+		req  types.DirectoryIngestRequest
+		want int
+	}{
+		{types.DirectoryIngestRequest{DirectoryPath: filepath.Join(dir, "missing")}, http.StatusNotFound},
+		{types.DirectoryIngestRequest{DirectoryPath: file}, http.StatusBadRequest},
+		{types.DirectoryIngestRequest{DirectoryPath: dir, FilePattern: "[txt"}, http.StatusBadRequest},
+		{types.DirectoryIngestRequest{DirectoryPath: dir, BatchSize: -1}, http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		if w := performJSON(handler.IngestDirectory, http.MethodPost, "/ingest/directory", tc.req); w.Code != tc.want {
+			t.Errorf("%+v: expected %d, got %d: %s", tc.req, tc.want, w.Code, w.Body.String())
+		}
+	}
+
+	if os.Geteuid() != 0 {
+		locked := filepath.Join(dir, "locked")
+		if err := os.Mkdir(locked, 0o000); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		defer os.Chmod(locked, 0o755)
+		if w := performJSON(handler.IngestDirectory, http.MethodPost, "/ingest/directory", types.DirectoryIngestRequest{DirectoryPath: locked}); w.Code != http.StatusForbidden {
+			t.Errorf("Expected 403 for an unreadable directory, got %d", w.Code)
+		}
+	}
+}
+
+func TestSearchAndRAG_ForwardFilters(t *testing.T) {
+	store := newFakeStore(testChunks(3)...)
+	handler := newTestHandler(store, &recordingGenerator{})
+	filters := map[string]string{"language": "en"}
+
+	performJSON(handler.SearchDocuments, http.MethodPost, "/search", types.SearchRequest{Query: "chunk", Filters: filters})
+	if store.searchFilters["language"] != "en" {
+		t.Errorf("Expected search filters to reach the store, got %v", store.searchFilters)
+	}
+
+	store.searchFilters = nil
+	performJSON(handler.RAGQuery, http.MethodPost, "/rag", types.RAGRequest{Query: "chunk", Filters: filters})
+	if store.searchFilters["language"] != "en" {
+		t.Errorf("Expected RAG filters to reach the store, got %v", store.searchFilters)
+	}
+
+	// An unknown operator is rejected before searching
+	invalid := map[string]string{"source:matches": "docs"}
+	if w := performJSON(handler.SearchDocuments, http.MethodPost, "/search", types.SearchRequest{Query: "chunk", Filters: invalid}); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid search filter, got %d", w.Code)
+	}
+	if w := performJSON(handler.RAGQuery, http.MethodPost, "/rag", types.RAGRequest{Query: "chunk", Filters: invalid}); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid RAG filter, got %d", w.Code)
+	}
+}
+
+func TestSearchDocuments_Facets(t *testing.T) {
+	chunks := testChunks(3)
+	chunks[0].Metadata.Source = "handbook"
+	chunks[1].Metadata.Source = "handbook"
+	chunks[2].Metadata.Source = "wiki"
+	handler := newTestHandler(newFakeStore(chunks...), &recordingGenerator{})
+
+	w := performJSON(handler.SearchDocuments, http.MethodPost, "/search", types.SearchRequest{Query: "chunk", Facets: []string{"source"}})
+	var response types.SearchResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	want := []types.FacetCount{{Value: "handbook", Count: 2}, {Value: "wiki", Count: 1}}
+	if w.Code != http.StatusOK || !reflect.DeepEqual(response.Facets["source"], want) {
+		t.Errorf("Expected source facet counts %v, got %d: %s", want, w.Code, w.Body.String())
+	}
+
+	w = performJSON(handler.SearchDocuments, http.MethodPost, "/search", types.SearchRequest{Query: "chunk"})
+	if strings.Contains(w.Body.String(), `"facets"`) {
+		t.Errorf("Expected no facets unless requested, got %s", w.Body.String())
+	}
+
+	if w := performJSON(handler.SearchDocuments, http.MethodPost, "/search", types.SearchRequest{Query: "chunk", Facets: []string{""}}); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an empty facet field, got %d", w.Code)
+	}
+}
+
+func TestSearchDocuments_RerankTopKTrimsCandidates(t *testing.T) {
+	store := newFakeStore(testChunks(20)...)
+	handler := newTestHandler(store, &recordingGenerator{})
+
+	w := performJSON(handler.SearchDocuments, http.MethodPost, "/search", types.SearchRequest{Query: "chunk", Limit: 20, RerankTopK: 3, Facets: []string{"source"}})
+	var response types.SearchResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusOK || len(response.Results) != 3 || response.Total != 3 {
+		t.Fatalf("Expected the top 3 results, got %d: %s", w.Code, w.Body.String())
+	}
+	if store.searchLimit != 20 {
+		t.Errorf("Expected 20 candidates to be retrieved, got %d", store.searchLimit)
+	}
+	for i := 1; i < len(response.Results); i++ {
+		if response.Results[i].Score > response.Results[i-1].Score {
+			t.Errorf("Expected results in rank order, got %+v", response.Results)
+		}
+	}
+
+	if w := performJSON(handler.SearchDocuments, http.MethodPost, "/search", types.SearchRequest{Query: "chunk", RerankTopK: -1}); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a negative rerank_top_k, got %d", w.Code)
+	}
+}
+
+func TestGetChunk_RejectsInvalidID(t *testing.T) {
+	handler := newTestHandler(newFakeStore(testChunks(1)...), &recordingGenerator{})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/chunks/:id", handler.GetChunk)
+
+	for _, id := range []string{"abc", "-1", "1.5", "18446744073709551616"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/chunks/"+id, nil))
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "invalid chunk id") {
+			t.Errorf("Expected 400 for chunk id %q, got %d: %s", id, w.Code, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/chunks/1", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected 200 for a valid chunk id, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestSearchDocuments_MaxContentLength(t *testing.T) {
+	chunks := testChunks(1)
+	chunks[0].Content = "Straße für Übungen mit Überlänge"
+	handler := newTestHandler(newFakeStore(chunks...), &recordingGenerator{})
+
+	w := performJSON(handler.SearchDocuments, http.MethodPost, "/search", types.SearchRequest{Query: "chunk", MaxContentLength: 12})
+	var response types.SearchResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusOK || len(response.Results) != 1 {
+		t.Fatalf("Expected one result, got %d: %s", w.Code, w.Body.String())
+	}
+	if result := response.Results[0]; result.Content != "Straße fü..." || !result.Truncated {
+		t.Errorf("Expected truncated content, got %q (truncated=%v)", result.Content, result.Truncated)
+	}
+
+	// The chunk endpoint always returns the full content
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/chunks/:id", handler.GetChunk)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/chunks/1", nil))
+	var chunk types.DocumentChunk
+	json.Unmarshal(w.Body.Bytes(), &chunk)
+	if chunk.Content != chunks[0].Content {
+		t.Errorf("Expected the full content from the chunk endpoint, got %q", chunk.Content)
+	}
+
+	if w := performJSON(handler.SearchDocuments, http.MethodPost, "/search", types.SearchRequest{Query: "chunk", MaxContentLength: -1}); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a negative max_content_length, got %d", w.Code)
+	}
+}
+
+func TestSearchDocuments_HybridKeepsVectorRanking(t *testing.T) {
+	// The fake store returns chunks in ID order, standing in for vector similarity
+	chunks := testChunks(3)
+	chunks[0].Content = "unrelated text"
+	chunks[1].Content = "more unrelated text"
+	chunks[2].Content = "machine learning"
+	handler := newTestHandler(newFakeStore(chunks...), &recordingGenerator{})
+
+	ids := func(hybrid bool) []uint64 {
+		w := performJSON(handler.SearchDocuments, http.MethodPost, "/search", types.SearchRequest{Query: "machine learning", Hybrid: hybrid})
+		var response types.SearchResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		var ids []uint64
+		for _, result := range response.Results {
+			ids = append(ids, result.ID)
+		}
+		return ids
+	}
+
+	if got := ids(false); !reflect.DeepEqual(got, []uint64{3, 1, 2}) {
+		t.Errorf("Expected keyword order 3, 1, 2, got %v", got)
+	}
+	// Chunk 1 leads the vector ranking and is second by keywords, so it wins the fusion
+	if got := ids(true); !reflect.DeepEqual(got, []uint64{1, 3, 2}) {
+		t.Errorf("Expected fused order 1, 3, 2, got %v", got)
+	}
+}
+
+func TestSearchDocuments_RerankFalseKeepsVectorScores(t *testing.T) {
+	// Keyword ranking alone would put chunk 3, the only one mentioning Go, first
+	chunks := testChunks(3)
+	chunks[2].Content = "Go machine learning"
+	for i, score := range []float64{0.91, 0.74, 0.42} {
+		chunks[i].VectorScore = score
+	}
+	handler := newTestHandler(newFakeStore(chunks...), &recordingGenerator{})
+
+	search := func(rerank *bool) types.SearchResponse {
+		w := performJSON(handler.SearchDocuments, http.MethodPost, "/search", types.SearchRequest{Query: "go machine learning", Rerank: rerank})
+		var response types.SearchResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response
+	}
+
+	if results := search(nil).Results; len(results) != 3 || results[0].ID != 3 || results[0].VectorScore != 0.42 {
+		t.Errorf("Expected keyword ranking to lead with chunk 3 and keep its vector score, got %+v", results)
+	}
+
+	rerank := false
+	var scores []float64
+	for _, result := range search(&rerank).Results {
+		scores = append(scores, result.Score)
+	}
+	if !reflect.DeepEqual(scores, []float64{0.91, 0.74, 0.42}) {
+		t.Errorf("Expected the vector similarities as scores, got %v", scores)
+	}
+}
+
+func TestSearchAndRAG_MMRSpreadsNearDuplicates(t *testing.T) {
+	// Chunks 1 and 2 are near-duplicates; the store hands back their vectors
+	chunks := testChunks(3)
+	chunks[0].Embedding = []float64{1, 0, 0, 0}
+	chunks[1].Embedding = []float64{1, 0.01, 0, 0}
+	chunks[2].Embedding = []float64{0, 1, 0, 0}
+	generator := &recordingGenerator{}
+	handler := newTestHandler(newFakeStore(chunks...), generator)
+	embedder, _ := embedding.NewMockService(types.EmbeddingConfig{Provider: "mock", Dimensions: 4})
+	handler.retrieverService = handler.retrieverService.WithEmbeddingService(embedder)
+
+	lambda := 0.0
+	w := performJSON(handler.SearchDocuments, http.MethodPost, "/search", types.SearchRequest{Query: "machine learning", MMRLambda: &lambda})
+	var response types.SearchResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	var ids []uint64
+	for _, result := range response.Results {
+		ids = append(ids, result.ID)
+	}
+	if w.Code != http.StatusOK || !reflect.DeepEqual(ids, []uint64{1, 3, 2}) {
+		t.Errorf("Expected the near-duplicate moved last, got %d: %v", w.Code, ids)
+	}
+
+	// RAG picks its context after diversifying
+	w = performJSON(handler.RAGQuery, http.MethodPost, "/rag", types.RAGRequest{Query: "machine learning", ContextLimit: 2, MMRLambda: &lambda})
+	if w.Code != http.StatusOK || len(generator.chunks) != 2 || generator.chunks[1].ID != 3 {
+		t.Errorf("Expected chunks 1 and 3 as context, got %d: %+v", w.Code, generator.chunks)
+	}
+
+	lambda = 2
+	if w := performJSON(handler.SearchDocuments, http.MethodPost, "/search", types.SearchRequest{Query: "machine learning", MMRLambda: &lambda}); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for mmr_lambda above 1, got %d", w.Code)
+	}
+}
+
+func TestSearchDocuments_NegativeQueryDemotesMatches(t *testing.T) {
+	embedder, _ := embedding.NewMockService(types.EmbeddingConfig{Provider: "mock", Dimensions: 4})
+	negative, _ := embedder.GenerateEmbedding(context.Background(), "deep learning")
+
+	// Chunk 1 embeds exactly like the negative query; chunk 2 points the other way
+	chunks := testChunks(2)
+	chunks[0].Embedding = negative
+	chunks[1].Embedding = make([]float64, len(negative))
+	for i, value := range negative {
+		chunks[1].Embedding[i] = -value
+	}
+	handler := newTestHandler(newFakeStore(chunks...), &recordingGenerator{})
+	handler.retrieverService = handler.retrieverService.WithEmbeddingService(embedder)
+
+	ids := func(negativeQuery string) []uint64 {
+		w := performJSON(handler.SearchDocuments, http.MethodPost, "/search", types.SearchRequest{Query: "machine learning", NegativeQuery: negativeQuery})
+		var response types.SearchResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		var ids []uint64
+		for _, result := range response.Results {
+			ids = append(ids, result.ID)
+		}
+		return ids
+	}
+
+	if got := ids(""); !reflect.DeepEqual(got, []uint64{1, 2}) {
+		t.Errorf("Expected keyword order 1, 2, got %v", got)
+	}
+	if got := ids("deep learning"); !reflect.DeepEqual(got, []uint64{2, 1}) {
+		t.Errorf("Expected the negative query to demote chunk 1, got %v", got)
+	}
+}
+
+func TestSearchAndRAG_FlagPartialResults(t *testing.T) {
+	store := newFakeStore(testChunks(3)...)
+	handler := newTestHandler(store, &recordingGenerator{})
+
+	w := performJSON(handler.SearchDocuments, http.MethodPost, "/search", types.SearchRequest{Query: "chunk"})
+	if strings.Contains(w.Body.String(), `"partial"`) {
+		t.Errorf("Expected a complete search not to be flagged, got %s", w.Body.String())
+	}
+
+	store.searchTimesOut = true
+	w = performJSON(handler.SearchDocuments, http.MethodPost, "/search", types.SearchRequest{Query: "chunk"})
+	var search types.SearchResponse
+	json.Unmarshal(w.Body.Bytes(), &search)
+	if w.Code != http.StatusOK || !search.Partial || search.Total != 3 {
+		t.Errorf("Expected partial results instead of an error, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = performJSON(handler.RAGQuery, http.MethodPost, "/rag", types.RAGRequest{Query: "chunk"})
+	var rag types.RAGResponse
+	json.Unmarshal(w.Body.Bytes(), &rag)
+	if w.Code != http.StatusOK || !rag.Partial || len(rag.RetrievedChunks) == 0 {
+		t.Errorf("Expected a partial RAG answer, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// answerModerator flags the fake generator's "answer" and anything containing "forbidden"
+type answerModerator struct{}
+
+func (answerModerator) Moderate(ctx context.Context, text string) (moderation.Result, error) {
+	return moderation.Result{Flagged: text == "answer" || strings.Contains(text, "forbidden")}, nil
+}
+
+func TestModeration_BlocksFlaggedContent(t *testing.T) {
+	store := newFakeStore(testChunks(2)...)
+	cfg := &config.Config{Moderation: types.ModerationConfig{Generation: moderation.ActionReject}}
+	handler := newTestHandlerWithConfig(cfg, store, &recordingGenerator{})
+	handler.moderator = answerModerator{}
+	handler.ingestService.SetModerator(answerModerator{}, moderation.ActionReject)
+
+	w := performJSON(handler.IngestDocument, http.MethodPost, "/ingest", types.IngestRequest{DocumentID: "doc-x", Content: "Something forbidden."})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected flagged ingest to be rejected with 422, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = performJSON(handler.RAGQuery, http.MethodPost, "/rag", types.RAGRequest{Query: "chunk"})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected a flagged answer to be rejected with 422, got %d: %s", w.Code, w.Body.String())
+	}
+
+	cfg.Moderation.Generation = moderation.ActionRedact
+	w = performJSON(handler.RAGQuery, http.MethodPost, "/rag", types.RAGRequest{Query: "chunk"})
+	var response types.RAGResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusOK || response.GeneratedResponse.Response != moderation.RedactedText {
+		t.Errorf("Expected a redacted answer, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// sseEvent is a server-sent event read back from a response body
+type sseEvent struct {
+	name string
+	data string
+}
+
+// parseSSE splits a server-sent event stream into its events
+func parseSSE(body string) []sseEvent {
+	var events []sseEvent
+	for _, block := range strings.Split(strings.TrimSpace(body), "\n\n") {
+		var event sseEvent
+		for _, line := range strings.Split(block, "\n") {
+			if name, ok := strings.CutPrefix(line, "event:"); ok {
+				event.name = name
+			}
+			if data, ok := strings.CutPrefix(line, "data:"); ok {
+				event.data = data
+			}
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestRAGStream_StreamsDeltasThenDone(t *testing.T) {
+	handler := newTestHandler(newFakeStore(testChunks(3)...), &recordingGenerator{})
+	mock, _ := generate.NewMockService(types.GenerationConfig{Provider: "mock"})
+	handler.generateService = mock
+
+	w := performJSON(handler.RAGStream, http.MethodPost, "/rag/stream", types.RAGRequest{Query: "machine learning"})
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream") {
+		t.Fatalf("Expected an event stream, got %d %q: %s", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+
+	events := parseSSE(w.Body.String())
+	var answer strings.Builder
+	for _, event := range events[:len(events)-1] {
+		var delta types.RAGStreamDelta
+		if event.name != "delta" || json.Unmarshal([]byte(event.data), &delta) != nil {
+			t.Fatalf("Expected only delta events before the end, got %+v", event)
+		}
+		answer.WriteString(delta.Delta)
+	}
+	if len(events) < 3 {
+		t.Errorf("Expected the answer in several deltas, got %d events", len(events))
+	}
+
+	last := events[len(events)-1]
+	var done types.RAGResponse
+	if last.name != "done" || json.Unmarshal([]byte(last.data), &done) != nil {
+		t.Fatalf("Expected a final done event, got %+v", last)
+	}
+	if done.GeneratedResponse.Response != answer.String() {
+		t.Errorf("Expected the deltas to make up the answer %q, got %q", done.GeneratedResponse.Response, answer.String())
+	}
+	if len(done.RetrievedChunks) != 3 || done.ProcessingTime == "" {
+		t.Errorf("Expected the retrieved chunks and processing time, got %+v", done)
+	}
+
+	w = performJSON(handler.RAGStream, http.MethodPost, "/rag/stream", types.RAGRequest{Query: "machine learning", ResponseFormat: types.ResponseFormatJSON})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected JSON answers to be rejected with 400, got %d", w.Code)
+	}
+}
+
+// disconnectingStreamer sends one delta, then disconnects the client and tries another
+type disconnectingStreamer struct {
+	recordingGenerator
+	disconnect context.CancelFunc
+	err        error
+}
+
+func (g *disconnectingStreamer) StreamResponse(ctx context.Context, query string, chunks []types.RankedChunk, opts types.GenerationOptions, onDelta func(delta string) error) (*types.GeneratedResponse, error) {
+	if err := onDelta("first"); err != nil {
+		return nil, err
+	}
+	g.disconnect()
+	g.err = onDelta("second")
+	return nil, g.err
+}
+
+func TestRAGStream_StopsWhenClientDisconnects(t *testing.T) {
+	handler := newTestHandler(newFakeStore(testChunks(2)...), &recordingGenerator{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	streamer := &disconnectingStreamer{disconnect: cancel}
+	handler.generateService = streamer
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/rag/stream", handler.RAGStream)
+	payload, _ := json.Marshal(types.RAGRequest{Query: "machine learning"})
+	req := httptest.NewRequest(http.MethodPost, "/rag/stream", bytes.NewReader(payload)).WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if streamer.err == nil {
+		t.Error("Expected deltas after the disconnect to fail")
+	}
+	events := parseSSE(w.Body.String())
+	if len(events) != 1 || events[0].name != "delta" {
+		t.Errorf("Expected only the delta sent before the disconnect, got %+v", events)
+	}
+}
+
+// performUpload posts a multipart form with the file and fields to the handler
+func performUpload(handler gin.HandlerFunc, filename string, data []byte, fields map[string]string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/ingest/file", handler)
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for field, value := range fields {
+		writer.WriteField(field, value)
+	}
+	part, _ := writer.CreateFormFile("file", filename)
+	part.Write(data)
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/ingest/file", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIngestFile(t *testing.T) {
+	store := newFakeStore()
+	handler := newTestHandlerWithConfig(&config.Config{Server: config.ServerConfig{MaxUploadMB: 1}}, store, &recordingGenerator{})
+
+	w := performUpload(handler.IngestFile, "notes.md", []byte("# Notes\n\nUploads are chunked like any other document."), map[string]string{
+		"title":    "Team notes",
+		"tags":     "meetings, weekly",
+		"team":     "search",
+		"strategy": "markdown",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp types.IngestResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if !strings.HasPrefix(resp.DocumentID, "upload_") || resp.ChunksCount != 1 {
+		t.Fatalf("Expected a generated document ID and one chunk, got %+v", resp)
+	}
+
+	chunks, _ := store.GetChunksByDocumentID(context.Background(), resp.DocumentID)
+	metadata := chunks[0].Metadata
+	if metadata.Title != "Team notes" || metadata.Source != "notes.md" || metadata.ContentType != "text/markdown" ||
+		!reflect.DeepEqual(metadata.Tags, []string{"meetings", "weekly"}) || metadata.Custom["team"] != "search" {
+		t.Errorf("Expected the form fields as metadata, got %+v", metadata)
+	}
+	if chunks[0].ChunkStrategy != chunk.StrategyMarkdown {
+		t.Errorf("Expected the markdown strategy from the form, got %q", chunks[0].ChunkStrategy)
+	}
+
+	// A supplied document ID is kept
+	w = performUpload(handler.IngestFile, "notes.txt", []byte("Plain text upload."), map[string]string{"document_id": "notes"})
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"document_id":"notes"`) {
+		t.Errorf("Expected the supplied document ID, got %d: %s", w.Code, w.Body.String())
+	}
+
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	if w := performUpload(handler.IngestFile, "diagram.png", png, nil); w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected 415 for an image, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := performJSON(handler.IngestFile, http.MethodPost, "/ingest/file", map[string]string{"content": "text"}); w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected 415 for a JSON body, got %d", w.Code)
+	}
+	if w := performUpload(handler.IngestFile, "big.txt", bytes.Repeat([]byte("a"), 2<<20), nil); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 above the upload limit, got %d", w.Code)
+	}
+	if w := performUpload(handler.IngestFile, "notes.txt", []byte("text"), map[string]string{"chunk_size": "big"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a bad chunk_size, got %d", w.Code)
+	}
+}
+
+func TestUpdateDocument_ReplacesAllChunks(t *testing.T) {
+	store := newFakeStore(testChunks(3)...)
+	store.softDelete = true
+	handler := newTestHandler(store, &recordingGenerator{})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.PUT("/documents/:id", handler.UpdateDocument)
+
+	put := func(body any) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPut, "/documents/doc-1", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := put(types.UpdateDocumentRequest{Content: "The replacement text.", Metadata: types.Metadata{Title: "v2"}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp types.IngestResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.DocumentID != "doc-1" || resp.ChunksCount != 1 {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+	// Even with soft delete on, the old chunks are removed rather than hidden
+	if len(store.chunks) != 1 {
+		t.Fatalf("Expected only the new chunk to be stored, got %d", len(store.chunks))
+	}
+	for _, c := range store.chunks {
+		if c.Content != "The replacement text." || c.Metadata.Title != "v2" {
+			t.Errorf("Unexpected chunk: %+v", c)
+		}
+	}
+
+	if w := put(map[string]any{"metadata": map[string]string{"title": "v3"}}); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without content, got %d", w.Code)
+	}
+	if w := put(types.UpdateDocumentRequest{Content: "text", ChunkingOptions: types.ChunkingOptions{Strategy: "bogus"}}); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid strategy, got %d", w.Code)
+	}
+	if len(store.chunks) != 1 {
+		t.Errorf("Expected rejected updates to leave the document alone, got %d chunks", len(store.chunks))
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	fake := newFakeStore(append(testChunks(3), types.DocumentChunk{ID: 10, DocumentID: "doc-2", Content: "other"})...)
+	handler := newTestHandler(fake, &recordingGenerator{})
+
+	w := performJSON(handler.GetStats, http.MethodGet, "/stats", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var stats types.CollectionStats
+	json.Unmarshal(w.Body.Bytes(), &stats)
+	if stats.Chunks != 4 || stats.Documents != 2 || stats.Dimensions != 3 || stats.Distance != "cosine" {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	// A store that can't report statistics answers 501
+	handler.retrieverService = retriever.NewService(struct{ store.VectorStore }{fake}, types.RetrievalConfig{})
+	if w := performJSON(handler.GetStats, http.MethodGet, "/stats", nil); w.Code != http.StatusNotImplemented {
+		t.Errorf("Expected 501, got %d", w.Code)
+	}
+}
+
+func TestCheckWebsocketOrigin(t *testing.T) {
+	allowed := []string{"https://app.example.com"}
+	for origin, ok := range map[string]bool{
+		"":                         true,
+		"http://rag.internal:8080": true,
+		"https://app.example.com":  true,
+		"https://evil.example.com": false,
+		"http://rag.internal":      false,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "http://rag.internal:8080/api/v1/ws/ingest", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if err := checkWebsocketOrigin(req, allowed); (err == nil) != ok {
+			t.Errorf("origin %q: expected allowed=%v, got %v", origin, ok, err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Flusher is implemented by services that buffer data which must be written
+// out before the server exits
+type Flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// Close stops background jobs, then flushes and closes every service that
+// supports it. It keeps going after a failure and returns all errors joined;
+// ctx bounds how long shutdown may take.
+func (h *Handler) Close(ctx context.Context) error {
+	var errs []error
+
+	if h.jobManager != nil {
+		if err := h.jobManager.Close(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	services := []struct {
+		name    string
+		service any
+	}{
+		{"generation service", h.generateService},
+		{"audit logger", h.auditLogger},
+		{"vector store", h.vectorStore},
+	}
+	for _, s := range services {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, fmt.Errorf("shutdown deadline reached before closing %s: %w", s.name, err))
+			break
+		}
+		if f, ok := s.service.(Flusher); ok {
+			if err := f.Flush(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("failed to flush %s: %w", s.name, err))
+			}
+		}
+		if c, ok := s.service.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("failed to close %s: %w", s.name, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,72 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-rag/internal/audit"
+	"go-rag/internal/jobs"
+)
+
+// flushingGenerator records whether it was flushed on shutdown
+type flushingGenerator struct {
+	recordingGenerator
+	flushed bool
+}
+
+func (g *flushingGenerator) Flush(ctx context.Context) error {
+	g.flushed = true
+	return nil
+}
+
+// closingAuditLogger records whether it was closed on shutdown
+type closingAuditLogger struct {
+	audit.Logger
+	closed bool
+	err    error
+}
+
+func (l *closingAuditLogger) Close() error {
+	l.closed = true
+	return l.err
+}
+
+func TestClose_FlushesServicesAndInterruptsJobs(t *testing.T) {
+	generator := &flushingGenerator{}
+	logger := &closingAuditLogger{err: errors.New("disk full")}
+	handler := newTestHandler(newFakeStore(), &generator.recordingGenerator)
+	handler.generateService = generator
+	handler.auditLogger = logger
+
+	manager, err := jobs.NewManager("")
+	if err != nil {
+		t.Fatalf("Failed to create job manager: %v", err)
+	}
+	handler.jobManager = manager
+	started := make(chan struct{})
+	job := manager.Start("reindex", func(ctx context.Context, progress func(done, total int)) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err = handler.Close(ctx)
+
+	if err == nil || !errors.Is(err, logger.err) {
+		t.Errorf("Expected the audit logger's close error, got %v", err)
+	}
+	if !generator.flushed {
+		t.Error("Expected the generation service to be flushed")
+	}
+	if !logger.closed {
+		t.Error("Expected the audit logger to be closed")
+	}
+	if polled, _ := manager.Get(job.ID); polled.Status != jobs.StatusInterrupted {
+		t.Errorf("Expected the running job to be interrupted, got %s", polled.Status)
+	}
+}
@@ -0,0 +1,47 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go-rag/internal/embedding"
+	"go-rag/internal/generate"
+	"go-rag/internal/types"
+)
+
+// warmupTimeout bounds how long startup waits on the warmup calls
+const warmupTimeout = 30 * time.Second
+
+// warmUp issues a tiny embedding and generation call so the first real request
+// doesn't pay for connection setup or local model loading
+func warmUp(ctx context.Context, embeddingService embedding.Service, generateService generate.GenerationService) error {
+	var errs []error
+
+	if _, err := embeddingService.GenerateEmbedding(ctx, "warmup"); err != nil {
+		errs = append(errs, fmt.Errorf("embedding warmup failed: %w", err))
+	}
+
+	// Generation skips the provider without context, so pass a one-line chunk
+	chunks := []types.RankedChunk{{DocumentChunk: types.DocumentChunk{DocumentID: "warmup", Content: "OK"}}}
+	if _, err := generateService.GenerateResponse(ctx, "Reply with OK.", chunks); err != nil {
+		errs = append(errs, fmt.Errorf("generation warmup failed: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// runWarmup warms up the providers, logging rather than failing on errors
+func runWarmup(embeddingService embedding.Service, generateService generate.GenerationService) {
+	ctx, cancel := context.WithTimeout(context.Background(), warmupTimeout)
+	defer cancel()
+
+	start := time.Now()
+	if err := warmUp(ctx, embeddingService, generateService); err != nil {
+		log.Printf("Warmup failed after %s: %v", time.Since(start), err)
+		return
+	}
+	log.Printf("Warmup completed in %s", time.Since(start))
+}
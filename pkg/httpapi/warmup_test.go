@@ -0,0 +1,65 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-rag/internal/embedding"
+	"go-rag/internal/types"
+)
+
+// countingEmbedder is an embedding service that counts calls
+type countingEmbedder struct {
+	embedding.Service
+	calls int
+	err   error
+}
+
+func (e *countingEmbedder) GenerateEmbedding(ctx context.Context, text string) ([]float64, error) {
+	e.calls++
+	if e.err != nil {
+		return nil, e.err
+	}
+	return e.Service.GenerateEmbedding(ctx, text)
+}
+
+func newCountingEmbedder(t *testing.T) *countingEmbedder {
+	mock, err := embedding.NewMockService(types.EmbeddingConfig{Dimensions: 8})
+	if err != nil {
+		t.Fatalf("Failed to create mock embedding service: %v", err)
+	}
+	return &countingEmbedder{Service: mock}
+}
+
+func TestWarmUp_CallsEmbeddingAndGeneration(t *testing.T) {
+	embedder := newCountingEmbedder(t)
+	generator := &recordingGenerator{}
+
+	if err := warmUp(context.Background(), embedder, generator); err != nil {
+		t.Fatalf("Expected warmup to succeed, got %v", err)
+	}
+	if embedder.calls != 1 || generator.calls != 1 {
+		t.Errorf("Expected one embedding and one generation call, got %d and %d", embedder.calls, generator.calls)
+	}
+	if len(generator.chunks) == 0 {
+		t.Error("Expected warmup to pass context so generation reaches the provider")
+	}
+}
+
+func TestWarmUp_ReportsEveryFailure(t *testing.T) {
+	embedder := newCountingEmbedder(t)
+	embedder.err = errors.New("connection refused")
+	generator := &recordingGenerator{err: errors.New("model loading")}
+
+	err := warmUp(context.Background(), embedder, generator)
+	if err == nil {
+		t.Fatal("Expected warmup to report failures")
+	}
+	if generator.calls != 1 {
+		t.Error("Expected generation warmup to run after the embedding warmup failed")
+	}
+	if !errors.Is(err, embedder.err) || !errors.Is(err, generator.err) {
+		t.Errorf("Expected both failures in the error, got %v", err)
+	}
+}